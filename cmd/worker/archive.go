@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/archive"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// archiveRepo implements the `archive` sub-command which exports everything known about a repo (all the synced
+// tables, its syncs, their jobs and logs) into a single SQLite file, eg. to share with a consultant or attach to a ticket.
+//
+//	worker archive --out mergestat.db https://github.com/mergestat/mergestat
+func archiveRepo(ctx context.Context, args []string, pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	var opts archive.Options
+
+	var flags = flag.NewFlagSet("archive", flag.ContinueOnError)
+	flags.StringVar(&opts.File, "out", "", "path of the SQLite file to write (default: <repo id>.db)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected the id or url of the repo to archive")
+	}
+	opts.Repo = flags.Arg(0)
+
+	if opts.File == "" {
+		var id string
+		if err := pool.QueryRow(ctx, "SELECT id::TEXT FROM public.repos WHERE id::TEXT = $1 OR repo = $1", opts.Repo).Scan(&id); err != nil {
+			return errors.Wrapf(err, "repo %q not found", opts.Repo)
+		}
+		opts.File = fmt.Sprintf("%s.db", id)
+	}
+
+	return archive.Repo(ctx, pool, logger, opts)
+}
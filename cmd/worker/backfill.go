@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// backfill implements the `backfill` sub-command which enqueues a full re-sync, at low priority,
+// of every enabled repo sync of the given types (eg. after a change to a sync's tables).
+//
+//	worker backfill --types GIT_BLAME,GIT_FILES
+func backfill(ctx context.Context, args []string, pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	var types string
+	var priority int
+
+	var flags = flag.NewFlagSet("backfill", flag.ContinueOnError)
+	flags.StringVar(&types, "types", "", "comma separated list of sync types to re-sync, eg. GIT_BLAME,GIT_FILES")
+	flags.IntVar(&priority, "priority", 100, "queue priority of the re-syncs (higher runs later)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if types == "" {
+		flags.Usage()
+		return errors.New("--types is required")
+	}
+
+	var queries = db.New(pool)
+	for _, syncType := range splitList(types) {
+		var params = db.BackfillRepoSyncsParams{Synctype: syncType, Version: sql.NullInt32{}, Priority: int32(priority)}
+
+		var enqueued, err = queries.BackfillRepoSyncs(ctx, params)
+		if err != nil {
+			return errors.Wrapf(err, "failed to backfill %s syncs", syncType)
+		}
+		logger.Info().Msgf("enqueued %d %s sync(s)", enqueued, syncType)
+	}
+
+	return nil
+}
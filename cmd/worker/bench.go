@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/mergestat/mergestat/internal/syncer"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// bench implements the `bench` sub-command which measures the throughput (rows/sec) and memory of syncs against
+// a synthetic repository. Results can be saved, and compared against the saved results of a previous run to fail
+// on a regression (eg. in CI, before a release).
+//
+//	worker bench --files 500 --commits 2000 --output bench.json
+//	worker bench --files 500 --commits 2000 --baseline bench.json --max-regression 20
+func bench(ctx context.Context, args []string, pool *pgxpool.Pool, embedded *sqlx.DB, logger *zerolog.Logger) error {
+	var opts syncer.BenchOptions
+	var types, output, baseline string
+	var maxRegression float64
+
+	var flags = flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.IntVar(&opts.Files, "files", 200, "number of files of the synthetic repository")
+	flags.IntVar(&opts.Commits, "commits", 500, "number of commits of the synthetic repository")
+	flags.IntVar(&opts.Lines, "lines", 100, "number of lines of each file of the synthetic repository")
+	flags.Int64Var(&opts.Seed, "seed", 1, "seed of the generation of the synthetic repository")
+	flags.IntVar(&opts.CopyRows, "copy-rows", 100000, "number of rows the COPY benchmark writes (0 to skip it)")
+	flags.StringVar(&types, "types", "GIT_COMMITS,GIT_COMMIT_STATS,GIT_BLAME", "comma separated list of sync types to benchmark")
+	flags.StringVar(&output, "output", "", "file to save the results into, as json")
+	flags.StringVar(&baseline, "baseline", "", "file of saved results to compare the throughput against")
+	flags.Float64Var(&maxRegression, "max-regression", 20, "percentage by which throughput may drop below the baseline")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	opts.SyncTypes = splitList(types)
+
+	var results, err = syncer.Bench(ctx, pool, embedded, logger, opts)
+	if err != nil {
+		return err
+	}
+
+	var table = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "BENCHMARK\tROWS\tDURATION\tROWS/SEC\tALLOCATED (MiB)\tPEAK HEAP (MiB)")
+	for _, r := range results {
+		fmt.Fprintf(table, "%s\t%d\t%s\t%.0f\t%.1f\t%.1f\n", r.Name, r.Rows, r.Duration, r.RowsPerSecond(),
+			float64(r.AllocatedBytes)/(1<<20), float64(r.PeakHeapBytes)/(1<<20))
+	}
+	if err = table.Flush(); err != nil {
+		return err
+	}
+
+	if output != "" {
+		var encoded, _ = json.MarshalIndent(results, "", "  ")
+		if err = os.WriteFile(output, encoded, 0o644); err != nil {
+			return errors.Wrapf(err, "failed to save results")
+		}
+	}
+
+	if baseline == "" {
+		return nil
+	}
+
+	var previous []*syncer.BenchResult
+	var encoded []byte
+	if encoded, err = os.ReadFile(baseline); err != nil {
+		return errors.Wrapf(err, "failed to read baseline")
+	}
+	if err = json.Unmarshal(encoded, &previous); err != nil {
+		return errors.Wrapf(err, "failed to parse baseline")
+	}
+
+	var regressions int
+	for _, p := range previous {
+		for _, r := range results {
+			if r.Name != p.Name || p.RowsPerSecond() == 0 {
+				continue
+			}
+
+			var change = (r.RowsPerSecond() - p.RowsPerSecond()) / p.RowsPerSecond() * 100
+			if change < -maxRegression {
+				logger.Error().Msgf("%s regressed: %.0f rows/sec, %.1f%% below the baseline (%.0f rows/sec)", r.Name, r.RowsPerSecond(), -change, p.RowsPerSecond())
+				regressions++
+			} else {
+				logger.Info().Msgf("%s: %.0f rows/sec, %+.1f%% against the baseline", r.Name, r.RowsPerSecond(), change)
+			}
+		}
+	}
+
+	if regressions > 0 {
+		return errors.Errorf("%d benchmark(s) regressed by more than %.0f%%", regressions, maxRegression)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/dbt"
+)
+
+// dbtSources implements the `dbt-sources` sub-command which writes the properties file declaring the synced tables
+// as a dbt source (with their freshness), eg. to bootstrap a dbt project on top of the synced data.
+//
+//	worker dbt-sources --out models/mergestat/sources.yml
+func dbtSources(ctx context.Context, args []string, pool *pgxpool.Pool) (err error) {
+	var out string
+
+	var flags = flag.NewFlagSet("dbt-sources", flag.ContinueOnError)
+	flags.StringVar(&out, "out", "", "path of the properties file to write (default: stdout)")
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	var cfg *dbt.Config
+	if cfg, err = dbt.LoadConfig(ctx, pool); err != nil {
+		return err
+	}
+
+	if out != "" {
+		return dbt.WriteSources(ctx, pool, cfg, out)
+	}
+
+	var content []byte
+	if content, err = dbt.Sources(ctx, pool, cfg); err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(content)
+	return err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/mergestat/mergestat/internal/syncer"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// doctor implements the `doctor` sub-command which runs a single sync end-to-end in the foreground,
+// with verbose output, without touching the sync queue or the production tables.
+//
+//	worker doctor --repo https://github.com/mergestat/mergestat --type GIT_BLAME
+func doctor(ctx context.Context, args []string, pool *pgxpool.Pool, embedded *sqlx.DB, logger *zerolog.Logger) error {
+	var opts syncer.DoctorOptions
+
+	var flags = flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.StringVar(&opts.Repo, "repo", "", "url of the repository to sync (it must already be added)")
+	flags.StringVar(&opts.SyncType, "type", "", "type of sync to run, eg. GIT_BLAME")
+	flags.BoolVar(&opts.Keep, "keep", false, "keep the scratch schema after the sync completes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if opts.Repo == "" || opts.SyncType == "" {
+		flags.Usage()
+		return errors.New("both --repo and --type are required")
+	}
+
+	var verbose = logger.Level(zerolog.DebugLevel)
+	return syncer.Doctor(ctx, pool, embedded, &verbose, opts)
+}
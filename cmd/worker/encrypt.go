@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/syncer"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// columnCipher returns the cipher for the key in COLUMN_ENCRYPTION_KEY (base64 encoded, 32 bytes), or nil if it's not set
+func columnCipher() (*helper.ColumnCipher, error) {
+	var key = os.Getenv("COLUMN_ENCRYPTION_KEY")
+	if key == "" {
+		return nil, nil
+	}
+	return helper.NewColumnCipherFromBase64(key)
+}
+
+// encrypt implements the `encrypt` sub-command which encrypts the values of the columns listed in
+// mergestat.encrypted_columns that were synced before the column was configured to be encrypted.
+//
+//	COLUMN_ENCRYPTION_KEY=$(cat key) worker encrypt --batch-size 5000
+func encrypt(ctx context.Context, args []string, pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	var batchSize int
+
+	var flags = flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	flags.IntVar(&batchSize, "batch-size", 1000, "maximum number of rows encrypted at once")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var c, err = columnCipher()
+	if err != nil {
+		return errors.Wrapf(err, "incorrect value for COLUMN_ENCRYPTION_KEY")
+	}
+	if c == nil {
+		return errors.New("COLUMN_ENCRYPTION_KEY is required")
+	}
+
+	var updated int64
+	if updated, err = syncer.EncryptSynced(ctx, pool, c, logger, batchSize); err != nil {
+		return err
+	}
+
+	logger.Info().Msgf("encrypted %d row(s)", updated)
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// erase implements the `erase` sub-command which pseudonymizes (or deletes) all the data identifying an author,
+// by email (commits, blame, ...) and login (pull requests, issues, ...), for right-to-erasure requests. Syncs apply
+// the erasure to the rows they write from then on (see mergestat.author_erasures).
+//
+//	worker erase --email jane@example.com --login jane-doe
+//	worker erase --email jane@example.com --mode delete
+func erase(ctx context.Context, args []string, pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	var email, login, mode string
+
+	var flags = flag.NewFlagSet("erase", flag.ContinueOnError)
+	flags.StringVar(&email, "email", "", "email of the author to erase")
+	flags.StringVar(&login, "login", "", "login (eg. on GitHub) of the author to erase")
+	flags.StringVar(&mode, "mode", "pseudonymize", "pseudonymize (replace the author with a pseudonym) or delete (delete the rows)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if email == "" && login == "" {
+		flags.Usage()
+		return errors.New("--email or --login is required")
+	}
+
+	mode = strings.ToUpper(mode)
+	if mode != "PSEUDONYMIZE" && mode != "DELETE" {
+		return errors.Errorf("unknown mode %q, expected pseudonymize or delete", mode)
+	}
+
+	var affected int64
+	if err := pool.QueryRow(ctx, "SELECT mergestat.erase_author($1, $2, $3)", email, login, mode).Scan(&affected); err != nil {
+		return errors.Wrapf(err, "failed to erase author")
+	}
+
+	logger.Info().Msgf("erased author (%s): %d row(s) affected", strings.ToLower(mode), affected)
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/export"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// exportAnonymized implements the `export` sub-command which exports an anonymized copy of the synced data
+// (hashed identities, stripped messages and file contents) that can be shared without leaking source code.
+//
+//	worker export --out ./dataset --repos https://github.com/mergestat/mergestat
+func exportAnonymized(ctx context.Context, args []string, pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	var opts export.Options
+	var tables, repos string
+
+	var flags = flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.StringVar(&opts.Dir, "out", "", "directory to write the export into, one csv file per table")
+	flags.StringVar(&tables, "tables", "", "comma separated list of tables to export (default: all synced tables)")
+	flags.StringVar(&repos, "repos", "", "comma separated list of repository urls to export (default: all repositories)")
+	flags.StringVar(&opts.Salt, "salt", "", "salt for hashed values, reuse it to produce linkable exports (default: random)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if opts.Dir == "" {
+		flags.Usage()
+		return errors.New("--out is required")
+	}
+
+	opts.Tables, opts.Repos = splitList(tables), splitList(repos)
+
+	var err error
+	if opts.Cipher, err = columnCipher(); err != nil {
+		return errors.Wrapf(err, "incorrect value for COLUMN_ENCRYPTION_KEY")
+	}
+
+	if opts.Salt == "" {
+		var salt = make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		opts.Salt = hex.EncodeToString(salt)
+	}
+
+	return export.Anonymized(ctx, pool, logger, opts)
+}
+
+// splitList splits a comma separated list, ignoring empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/mergestat/mergestat/internal/grafana"
+	"github.com/rs/zerolog"
+)
+
+// provisionGrafana implements the `grafana` sub-command which writes the canonical Grafana dashboards (queue health,
+// sync freshness and git metrics), and the dashboard provider loading them, into a Grafana provisioning directory.
+//
+//	worker grafana --out /etc/grafana/provisioning --datasource SXQZgpP7z
+func provisionGrafana(args []string, logger *zerolog.Logger) error {
+	var out, datasource, path string
+
+	var flags = flag.NewFlagSet("grafana", flag.ContinueOnError)
+	flags.StringVar(&out, "out", "/etc/grafana/provisioning", "provisioning directory of Grafana")
+	flags.StringVar(&datasource, "datasource", grafana.DefaultDatasource, "uid of the Postgres datasource the dashboards query")
+	flags.StringVar(&path, "path", "", "path the dashboards are loaded from by Grafana, if mounted elsewhere than where they are written")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := grafana.Provision(out, datasource, path); err != nil {
+		return err
+	}
+
+	logger.Info().Msgf("wrote %d dashboard(s) into %s", len(grafana.Dashboards(datasource)), out)
+	return nil
+}
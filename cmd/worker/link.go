@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// link implements the `link` sub-command which declares that a repo is the same logical project as another (canonical)
+// repo, eg. its mirror on another provider or the repo the project was migrated away from (see mergestat.repo_links).
+//
+//	worker link --relation mirror https://gitlab.com/acme/app https://github.com/acme/app
+//	worker link --relation predecessor --migrated-at 2023-03-01 https://bitbucket.org/acme/app https://github.com/acme/app
+//	worker link --remove https://gitlab.com/acme/app
+func link(ctx context.Context, args []string, pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	var relation, migratedAt string
+	var remove bool
+
+	var flags = flag.NewFlagSet("link", flag.ContinueOnError)
+	flags.StringVar(&relation, "relation", "mirror", "mirror (a copy of the canonical repo) or predecessor (where the project lived before)")
+	flags.StringVar(&migratedAt, "migrated-at", "", "for a predecessor, the date (or RFC 3339 time) the project was migrated to the canonical repo")
+	flags.BoolVar(&remove, "remove", false, "remove the link of the repo instead")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if remove {
+		if flags.NArg() != 1 {
+			flags.Usage()
+			return errors.New("expected the id or url of the repo to unlink")
+		}
+
+		tag, err := pool.Exec(ctx, "DELETE FROM mergestat.repo_links WHERE repo_id = mergestat.resolve_repo($1)", flags.Arg(0))
+		if err != nil {
+			return errors.Wrapf(err, "failed to unlink repo")
+		}
+
+		logger.Info().Msgf("removed %d link(s) of %s", tag.RowsAffected(), flags.Arg(0))
+		return nil
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("expected the id or url of the repo to link, and of its canonical repo")
+	}
+
+	relation = strings.ToUpper(relation)
+	if relation != "MIRROR" && relation != "PREDECESSOR" {
+		return errors.Errorf("unknown relation %q, expected mirror or predecessor", relation)
+	}
+
+	var migrated *time.Time
+	if migratedAt != "" {
+		var t, err = time.Parse(time.RFC3339, migratedAt)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", migratedAt); err != nil {
+				return errors.Errorf("invalid --migrated-at %q, expected a date (2006-01-02) or an RFC 3339 time", migratedAt)
+			}
+		}
+		migrated = &t
+	}
+
+	if _, err := pool.Exec(ctx, "SELECT mergestat.link_repos($1, $2, $3, $4)", flags.Arg(0), flags.Arg(1), relation, migrated); err != nil {
+		return errors.Wrapf(err, "failed to link repos")
+	}
+
+	logger.Info().Msgf("linked %s to %s (%s)", flags.Arg(0), flags.Arg(1), strings.ToLower(relation))
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/mergestat/mergestat-lite/extensions"
+	"github.com/mergestat/mergestat-lite/extensions/options"
+	"github.com/mergestat/mergestat-lite/pkg/locator"
+	"github.com/mergestat/mergestat/internal/lite"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"go.riyazali.net/sqlite"
+)
+
+// runLite implements the `lite` sub-command which syncs local repositories into a SQLite database, without Postgres.
+//
+//	worker lite --db mergestat.db --repo ~/src/mergestat --type GIT_COMMITS,GIT_REFS
+func runLite(ctx context.Context, args []string, logger *zerolog.Logger) error {
+	var opts lite.Options
+	var repos, types string
+
+	var flags = flag.NewFlagSet("lite", flag.ContinueOnError)
+	flags.StringVar(&opts.Database, "db", "mergestat.db", "path to the SQLite database")
+	flags.StringVar(&repos, "repo", "", "comma separated paths of local git repositories to add")
+	flags.StringVar(&types, "type", "", "comma separated sync types to run (default: "+strings.Join(lite.SyncTypes(), ",")+")")
+	flags.DurationVar(&opts.Interval, "interval", 0, "if set, re-run the syncs on this interval")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if repos != "" {
+		opts.Repos = strings.Split(repos, ",")
+	}
+	if types != "" {
+		opts.SyncTypes = strings.Split(types, ",")
+	}
+
+	if opts.Database == "" {
+		flags.Usage()
+		return errors.New("--db is required")
+	}
+
+	sqlite.Register(
+		extensions.RegisterFn(
+			options.WithExtraFunctions(),
+			options.WithRepoLocator(locator.CachedLocator(repoLocator())),
+			options.WithLogger(logger),
+		),
+	)
+
+	return lite.Run(ctx, logger, opts)
+}
@@ -5,23 +5,31 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	_ "net/http/pprof"
 	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/mergestat/mergestat/internal/cron"
 	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/dbt"
+	"github.com/mergestat/mergestat/internal/events"
 	"github.com/mergestat/mergestat/internal/helper"
 	"github.com/mergestat/mergestat/internal/jobs/repo"
 	"github.com/mergestat/mergestat/internal/jobs/sync/podman"
+	"github.com/mergestat/mergestat/internal/logship"
+	"github.com/mergestat/mergestat/internal/namespace"
+	"github.com/mergestat/mergestat/internal/notifications"
 	"github.com/mergestat/mergestat/internal/syncer"
 	"github.com/mergestat/mergestat/internal/timeout"
+	"github.com/mergestat/mergestat/internal/tuning"
 	"github.com/mergestat/mergestat/queries"
 	"github.com/mergestat/sqlq/runtime/embed"
 	"github.com/mergestat/sqlq/schema"
@@ -30,6 +38,7 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	_ "github.com/jackc/pgx/v4/stdlib"
@@ -40,7 +49,12 @@ import (
 	"github.com/mergestat/mergestat-lite/extensions/services"
 	"github.com/mergestat/mergestat-lite/pkg/locator"
 	_ "github.com/mergestat/mergestat-lite/pkg/sqlite"
+	"github.com/mergestat/mergestat/internal/dialect"
+	"github.com/mergestat/mergestat/internal/plugins"
+	"github.com/mergestat/mergestat/internal/querypacks"
+	"github.com/mergestat/mergestat/internal/retention"
 	"github.com/mergestat/mergestat/internal/scheduler"
+	"github.com/mergestat/mergestat/internal/search"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/shurcooL/githubv4"
@@ -92,19 +106,49 @@ func logLevelFromEnv() zerolog.Level {
 }
 
 func main() {
-	logger := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(logLevelFromEnv())
+	var output io.Writer = os.Stderr
 	prettyLogs := os.Getenv("PRETTY_LOGS") == "1"
 
 	// if stdout is a terminal or if the PRETTY_LOGS environment variable is set
 	// to 1, use a human-friendly log formatter
 	if fileInfo, _ := os.Stdout.Stat(); (fileInfo.Mode()&os.ModeCharDevice) != 0 || prettyLogs {
-		logger = logger.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.Stamp})
+		output = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.Stamp}
 	}
+
+	// if log sinks are configured (see logship.FromEnv), the (json) log output is shipped to them as well
+	sinks, sinksErr := logship.FromEnv()
+	var shipper *logship.Shipper
+	if len(sinks) > 0 {
+		shipper = logship.New(sinks...)
+		output = zerolog.MultiLevelWriter(output, shipper)
+	}
+
+	logger := zerolog.New(output).With().Timestamp().Logger().Level(logLevelFromEnv())
 	zerolog.DefaultContextLogger = &logger
+	if sinksErr != nil {
+		logger.Fatal().Err(sinksErr).Msg("invalid LOG_SINKS configuration")
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	if shipper != nil {
+		go shipper.Run(ctx)
+		defer func() {
+			var flushCtx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			shipper.Flush(flushCtx)
+		}()
+	}
+
+	// `worker lite` syncs local repositories into a SQLite database, without Postgres
+	if len(os.Args) > 1 && os.Args[1] == "lite" {
+		if err := runLite(ctx, os.Args[2:], &logger); err != nil {
+			logger.Fatal().Err(err).Msg("lite mode run failed")
+		}
+		return
+	}
+
 	var err error
 	concurrency := 1
 	if concurrencyEnv != "" {
@@ -121,8 +165,47 @@ func main() {
 		logger.Err(err).Msgf("could not parse database connection string: %v", err)
 		os.Exit(1)
 	}
+	maxConns := concurrency + 5
+	if maxConnsEnv := os.Getenv("DB_MAX_CONNS"); maxConnsEnv != "" {
+		if maxConns, err = strconv.Atoi(maxConnsEnv); err != nil {
+			logger.Err(err).Msgf("could not parse DB_MAX_CONNS env into an int: %s", maxConnsEnv)
+			maxConns = concurrency + 5
+		}
+	}
+
 	v := u.Query()
-	v.Add("pool_max_conns", strconv.Itoa(concurrency+5))
+	v.Add("pool_max_conns", strconv.Itoa(maxConns))
+	if minConns := os.Getenv("DB_MIN_CONNS"); minConns != "" {
+		v.Add("pool_min_conns", minConns)
+	}
+
+	// session wide timeouts (syncs set their own, per sync type, on the transaction they write in, derived from these)
+	var timeouts = make(map[string]time.Duration)
+	for env, param := range map[string]string{
+		"DB_STATEMENT_TIMEOUT":                   "statement_timeout",
+		"DB_LOCK_TIMEOUT":                        "lock_timeout",
+		"DB_IDLE_IN_TRANSACTION_SESSION_TIMEOUT": "idle_in_transaction_session_timeout",
+	} {
+		if timeoutEnv := os.Getenv(env); timeoutEnv != "" {
+			if timeout, err := time.ParseDuration(timeoutEnv); err != nil {
+				logger.Err(err).Msgf("could not parse %s env into a duration: %s", env, timeoutEnv)
+			} else {
+				v.Add(param, strconv.FormatInt(timeout.Milliseconds(), 10))
+				timeouts[env] = timeout
+			}
+		}
+	}
+	// identifies the worker's connections (eg. in pg_stat_activity), unless the connection string sets a name
+	if v.Get("application_name") == "" {
+		var hostname, _ = os.Hostname()
+		v.Add("application_name", fmt.Sprintf("mergestat-worker:%s:%d", hostname, os.Getpid()))
+	}
+	// synced tables relocated out of public (see namespace.Apply) resolve through the views of mergestat_tables
+	var tableSchema, tablePrefix = os.Getenv("SYNC_SCHEMA"), os.Getenv("SYNC_TABLE_PREFIX")
+	var relocateTables = (tableSchema != "" && tableSchema != "public") || tablePrefix != ""
+	if relocateTables {
+		v.Set("search_path", namespace.SearchPath)
+	}
 	u.RawQuery = v.Encode()
 
 	var pool *pgxpool.Pool
@@ -132,6 +215,33 @@ func main() {
 	}
 	defer pool.Close()
 
+	// detect the (Postgres-compatible) backend, and report the features it lacks
+	var backend *dialect.Dialect
+	if backend, err = dialect.Detect(ctx, pool); err != nil {
+		logger.Err(err).Msgf("could not detect database backend: %v", err)
+		os.Exit(1)
+	}
+	for _, unsupported := range backend.Unsupported() {
+		logger.Warn().Msgf("%s does not support %s", backend.Name, unsupported)
+	}
+
+	// an optional read-only connection (eg. to a replica) used for heavy read queries
+	var replica *pgxpool.Pool
+	if readConnection := os.Getenv("POSTGRES_READ_CONNECTION"); readConnection != "" {
+		var config *pgxpool.Config
+		if config, err = pgxpool.ParseConfig(readConnection); err != nil {
+			logger.Fatal().Err(err).Msgf("Incorrect value for POSTGRES_READ_CONNECTION")
+		}
+		if relocateTables {
+			config.ConnConfig.RuntimeParams["search_path"] = namespace.SearchPath
+		}
+		if replica, err = pgxpool.ConnectConfig(ctx, config); err != nil {
+			logger.Err(err).Msgf("could not connect to read replica: %v", err)
+			os.Exit(1)
+		}
+		defer replica.Close()
+	}
+
 	// create a new sqlq worker to process tasks in background
 	var upstream *sql.DB
 	if upstream, err = sql.Open("pgx", postgresConnection); err != nil {
@@ -139,7 +249,7 @@ func main() {
 	}
 
 	// this sets the max number of db connections to the same number used by the pgxpool above
-	upstream.SetMaxOpenConns(concurrency + 5)
+	upstream.SetMaxOpenConns(maxConns)
 
 	// apply sqlq migrations
 	if err := schema.Apply(upstream); err != nil {
@@ -152,11 +262,15 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := m.Up(); err != nil {
-		if !errors.Is(err, migrate.ErrNoChange) {
-			logger.Err(err).Msgf("could not run migrations: %v", err)
-			os.Exit(1)
-		}
+	if err = migrateAndRelocate(ctx, pool, backend, m, "migrations", tableSchema, tablePrefix, &logger); err != nil {
+		logger.Err(err).Msgf("could not migrate and relocate synced tables: %v", err)
+		os.Exit(1)
+	}
+
+	var tables namespace.Registry
+	if tables, err = namespace.Load(ctx, pool); err != nil {
+		logger.Err(err).Msgf("could not load synced tables: %v", err)
+		os.Exit(1)
 	}
 
 	srcErr, dbErr := m.Close()
@@ -210,16 +324,21 @@ func main() {
 		githubRequestMutex.Unlock()
 	}
 
+	// githubTransport, once the sync worker is set up, returns the transport the GitHub client of the mergestat-lite
+	// module makes its calls through, so that they count against the api budget of the provider (see syncer.ProviderTransport)
+	var githubTransport func(provider uuid.UUID) http.RoundTripper
+
 	githubClientGetter := func() *githubv4.Client {
 		encryptionSecret := os.Getenv("ENCRYPTION_SECRET")
 
 		const fetchToken = `
-			SELECT credentials.token
+			SELECT provider.id, credentials.token
 				FROM (SELECT * FROM mergestat.providers WHERE vendor = 'github') AS provider,
 					  mergestat.fetch_service_auth_credential(provider.id, 'GITHUB_PAT', $1) AS credentials`
 
+		var provider uuid.NullUUID
 		var credentials []byte
-		if err = pool.QueryRow(context.TODO(), fetchToken, encryptionSecret).Scan(&credentials); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		if err = pool.QueryRow(context.TODO(), fetchToken, encryptionSecret).Scan(&provider, &credentials); err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			logger.Err(err).Msgf("error retrieving GitHub PAT from database")
 		}
 
@@ -229,7 +348,12 @@ func main() {
 			credentials = []byte(os.Getenv("GITHUB_TOKEN"))
 		}
 
-		httpClient := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
+		var clientCtx = context.Background()
+		if githubTransport != nil && provider.Valid {
+			clientCtx = context.WithValue(clientCtx, oauth2.HTTPClient, &http.Client{Transport: githubTransport(provider.UUID)})
+		}
+
+		httpClient := oauth2.NewClient(clientCtx, oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: string(credentials)},
 		))
 		// httpClient.Transport = &mutexRoundTripper{}
@@ -260,6 +384,134 @@ func main() {
 		os.Exit(1)
 	}
 
+	// `worker doctor` runs a single sync in the foreground and exits
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		if err = doctor(ctx, os.Args[2:], pool, embedded, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("doctor run failed")
+		}
+		return
+	}
+
+	// `worker bench` measures the throughput of syncs against a synthetic repository and exits
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		if err = bench(ctx, os.Args[2:], pool, embedded, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("bench failed")
+		}
+		return
+	}
+
+	// `worker redact` applies the privacy settings to the rows already synced and exits
+	if len(os.Args) > 1 && os.Args[1] == "redact" {
+		if err = redact(ctx, pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("redact failed")
+		}
+		return
+	}
+
+	// `worker backfill` enqueues a full re-sync of the given sync types and exits
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		if err = backfill(ctx, os.Args[2:], pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("backfill failed")
+		}
+		return
+	}
+
+	// `worker export` writes an anonymized copy of the synced data and exits
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err = exportAnonymized(ctx, os.Args[2:], pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("export failed")
+		}
+		return
+	}
+
+	// `worker purge` deletes a repo and every row synced for it and exits
+	if len(os.Args) > 1 && os.Args[1] == "purge" {
+		if err = purgeRepo(ctx, os.Args[2:], pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("purge failed")
+		}
+		return
+	}
+
+	// `worker archive` exports everything known about a repo into a single SQLite file and exits
+	if len(os.Args) > 1 && os.Args[1] == "archive" {
+		if err = archiveRepo(ctx, os.Args[2:], pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("archive failed")
+		}
+		return
+	}
+
+	// `worker erase` pseudonymizes (or deletes) the data identifying an author and exits
+	if len(os.Args) > 1 && os.Args[1] == "erase" {
+		if err = erase(ctx, os.Args[2:], pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("erase failed")
+		}
+		return
+	}
+
+	// `worker link` declares that a repo is the same project as another (eg. its mirror on another provider) and exits
+	if len(os.Args) > 1 && os.Args[1] == "link" {
+		if err = link(ctx, os.Args[2:], pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("link failed")
+		}
+		return
+	}
+
+	// `worker encrypt` encrypts the values of encrypted columns synced before they were configured and exits
+	if len(os.Args) > 1 && os.Args[1] == "encrypt" {
+		if err = encrypt(ctx, os.Args[2:], pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("encrypt failed")
+		}
+		return
+	}
+
+	// `worker packs` installs (or upgrades) the query packs shipped with the worker and exits
+	if len(os.Args) > 1 && os.Args[1] == "packs" {
+		if err = packs(ctx, os.Args[2:], pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("packs failed")
+		}
+		return
+	}
+
+	// `worker dbt-sources` writes the dbt source declaring the synced tables and exits
+	if len(os.Args) > 1 && os.Args[1] == "dbt-sources" {
+		if err = dbtSources(ctx, os.Args[2:], pool); err != nil {
+			logger.Fatal().Err(err).Msg("dbt-sources failed")
+		}
+		return
+	}
+
+	// `worker grafana` writes the provisioning files of the canonical Grafana dashboards and exits
+	if len(os.Args) > 1 && os.Args[1] == "grafana" {
+		if err = provisionGrafana(os.Args[2:], &logger); err != nil {
+			logger.Fatal().Err(err).Msg("grafana failed")
+		}
+		return
+	}
+
+	// `worker plan` simulates a sync cycle under the current and alternative configurations and exits
+	if len(os.Args) > 1 && os.Args[1] == "plan" {
+		if err = plan(ctx, os.Args[2:], pool, concurrency, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("plan failed")
+		}
+		return
+	}
+
+	// `worker maintenance on|off|status` switches the maintenance mode, pausing (or resuming) all workers, and exits
+	if len(os.Args) > 1 && os.Args[1] == "maintenance" {
+		if err = maintenance(ctx, os.Args[2:], pool, &logger); err != nil {
+			logger.Fatal().Err(err).Msg("maintenance failed")
+		}
+		return
+	}
+
+	// `worker search [--kind code|commits|issues|pulls] [--repo <pattern>] [--tag <tag>] <text>` searches across repos, and exits
+	if len(os.Args) > 1 && os.Args[1] == "search" {
+		if err = searchRepos(ctx, os.Args[2:], pool); err != nil {
+			logger.Fatal().Err(err).Msg("search failed")
+		}
+		return
+	}
+
 	var worker, _ = embed.NewWorker(upstream, embed.WorkerConfig{
 		Concurrency: concurrency,
 	})
@@ -275,6 +527,7 @@ func main() {
 	// either via the database/app or possibly with env vars
 	schedulerInterval := 1
 	syncerInterval := 3
+	cleanupInterval := 60
 
 	if schedulerIntervalStr := os.Getenv("SCHEDULER_INTERVAL_MINUTES"); len(schedulerIntervalStr) != 0 {
 		if schedulerInterval, err = strconv.Atoi(schedulerIntervalStr); err != nil {
@@ -286,9 +539,168 @@ func main() {
 			logger.Err(err).Msgf("Incorrect value for SYNCER_INTERVAL_SECONDS")
 		}
 	}
-	go scheduler.New(&logger, pool).Start(ctx, time.Duration(schedulerInterval)*time.Minute)
+	if cleanupIntervalStr := os.Getenv("QUEUE_CLEANUP_INTERVAL_MINUTES"); len(cleanupIntervalStr) != 0 {
+		if cleanupInterval, err = strconv.Atoi(cleanupIntervalStr); err != nil {
+			logger.Err(err).Msgf("Incorrect value for QUEUE_CLEANUP_INTERVAL_MINUTES")
+		}
+	}
+	go scheduler.New(&logger, pool).WithDialect(backend).Start(ctx, time.Duration(schedulerInterval)*time.Minute)
 	go timeout.New(&logger, pool).Start(ctx, time.Minute)
-	go syncer.New(pool, embedded, &logger, concurrency, time.Duration(syncerInterval)*time.Second).Start(ctx)
+	go retention.New(&logger, pool).WithDialect(backend).Start(ctx, time.Duration(cleanupInterval)*time.Minute)
+	// query packs are installed, upgraded and refreshed on their own schedule (see mergestat.query_packs)
+	queryPacksInterval := 10
+	if queryPacksIntervalStr := os.Getenv("QUERY_PACKS_INTERVAL_MINUTES"); len(queryPacksIntervalStr) != 0 {
+		if queryPacksInterval, err = strconv.Atoi(queryPacksIntervalStr); err != nil {
+			logger.Err(err).Msgf("Incorrect value for QUERY_PACKS_INTERVAL_MINUTES")
+		}
+	}
+	if queryPacksInterval > 0 {
+		go querypacks.New(&logger, pool).Start(ctx, time.Duration(queryPacksInterval)*time.Minute)
+	}
+	// the dbt integration writes the dbt source and runs the dbt Cloud job once a sync cycle completes (see mergestat.dbt_integration)
+	if sourcesPath, cloudToken := os.Getenv("DBT_SOURCES_PATH"), os.Getenv("DBT_CLOUD_API_TOKEN"); sourcesPath != "" || cloudToken != "" {
+		go dbt.New(&logger, pool, sourcesPath, cloudToken).Start(ctx, time.Minute)
+	}
+	// the notifications raised by syncs are relayed to a webhook, if one is configured (see internal/notifications)
+	if webhookURL := os.Getenv("NOTIFICATIONS_WEBHOOK_URL"); webhookURL != "" {
+		go notifications.New(&logger, pool, webhookURL, os.Getenv("NOTIFICATIONS_WEBHOOK_SECRET")).Start(ctx, 30*time.Second)
+	}
+	// generated columns and extra indexes of the synced tables are maintained as configured (see internal/tuning)
+	tuningInterval := 10
+	if tuningIntervalStr := os.Getenv("TABLE_TUNING_INTERVAL_MINUTES"); len(tuningIntervalStr) != 0 {
+		if tuningInterval, err = strconv.Atoi(tuningIntervalStr); err != nil {
+			logger.Err(err).Msgf("Incorrect value for TABLE_TUNING_INTERVAL_MINUTES")
+		}
+	}
+	if tuningInterval > 0 && backend.AdvisoryLocks {
+		go tuning.New(&logger, pool, tables).Start(ctx, time.Duration(tuningInterval)*time.Minute)
+	}
+	var syncWorker = syncer.New(pool, embedded, &logger, concurrency, time.Duration(syncerInterval)*time.Second).WithDialect(backend).WithTableRegistry(tables)
+	if timeout, ok := timeouts["DB_STATEMENT_TIMEOUT"]; ok {
+		syncWorker = syncWorker.WithStatementTimeout(timeout)
+	}
+	if timeout, ok := timeouts["DB_LOCK_TIMEOUT"]; ok {
+		syncWorker = syncWorker.WithLockTimeout(timeout)
+	}
+	if replica != nil {
+		syncWorker = syncWorker.WithReadReplica(replica)
+	}
+	// chaos test mode: the worker randomly kills itself (or drops its database connections) during jobs
+	if killProbability, disconnectProbability := os.Getenv("CHAOS_KILL_PROBABILITY"), os.Getenv("CHAOS_DISCONNECT_PROBABILITY"); killProbability != "" || disconnectProbability != "" {
+		var chaos syncer.ChaosOptions
+		for env, value := range map[string]*float64{"CHAOS_KILL_PROBABILITY": &chaos.KillProbability, "CHAOS_DISCONNECT_PROBABILITY": &chaos.DisconnectProbability} {
+			if s := os.Getenv(env); s != "" {
+				if *value, err = strconv.ParseFloat(s, 64); err != nil {
+					logger.Fatal().Err(err).Msgf("Incorrect value for %s", env)
+				}
+			}
+		}
+		if maxDelay := os.Getenv("CHAOS_MAX_DELAY"); maxDelay != "" {
+			if chaos.MaxDelay, err = time.ParseDuration(maxDelay); err != nil {
+				logger.Fatal().Err(err).Msgf("Incorrect value for CHAOS_MAX_DELAY")
+			}
+		}
+		logger.Warn().Msgf("chaos test mode enabled (kill probability %.2f, disconnect probability %.2f), never use it in production", chaos.KillProbability, chaos.DisconnectProbability)
+		syncWorker = syncWorker.WithChaos(chaos)
+	}
+	// polite mode: low concurrency, paced and capped api calls, for scanning many public repos (eg. for research)
+	if os.Getenv("POLITE_MODE") != "" {
+		var polite = syncer.PoliteOptions{UserAgent: os.Getenv("POLITE_USER_AGENT")}
+		for env, value := range map[string]*int{"POLITE_CONCURRENCY": &polite.Concurrency, "POLITE_MAX_CALLS_PER_HOUR": &polite.MaxCallsPerHour} {
+			if s := os.Getenv(env); s != "" {
+				if *value, err = strconv.Atoi(s); err != nil {
+					logger.Fatal().Err(err).Msgf("Incorrect value for %s", env)
+				}
+			}
+		}
+		if minInterval := os.Getenv("POLITE_MIN_INTERVAL"); minInterval != "" {
+			if polite.MinInterval, err = time.ParseDuration(minInterval); err != nil {
+				logger.Fatal().Err(err).Msgf("Incorrect value for POLITE_MIN_INTERVAL")
+			}
+		}
+		if polite.UserAgent == "" {
+			logger.Warn().Msg("polite mode is enabled without POLITE_USER_AGENT, set it to say who runs the worker and how to reach them")
+		}
+		syncWorker = syncWorker.WithPoliteMode(polite)
+	}
+	// anonymous mode: public repos are synced without any credential (GitHub api calls are limited to 60 per hour)
+	if os.Getenv("ANONYMOUS_MODE") != "" {
+		syncWorker = syncWorker.WithAnonymousAccess()
+	}
+	// GIT_EMBEDDINGS syncs compute embeddings with the OpenAI api (or a compatible server) or a local Ollama server
+	if model := os.Getenv("EMBEDDINGS_MODEL"); model != "" {
+		var embeddings = syncer.EmbeddingOptions{Provider: os.Getenv("EMBEDDINGS_PROVIDER"), URL: os.Getenv("EMBEDDINGS_URL"), Model: model, APIKey: os.Getenv("EMBEDDINGS_API_KEY")}
+		if embeddings.APIKey == "" && (embeddings.Provider == "" || embeddings.Provider == "openai") {
+			embeddings.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+		if batchSize := os.Getenv("EMBEDDINGS_BATCH_SIZE"); batchSize != "" {
+			if embeddings.BatchSize, err = strconv.Atoi(batchSize); err != nil {
+				logger.Fatal().Err(err).Msgf("Incorrect value for EMBEDDINGS_BATCH_SIZE")
+			}
+		}
+		syncWorker = syncWorker.WithEmbeddings(embeddings)
+	}
+	// LLM_SUMMARIES syncs generate summaries with the OpenAI api (or a compatible server) or a local Ollama server
+	if model := os.Getenv("LLM_MODEL"); model != "" {
+		var llm = syncer.LLMOptions{Provider: os.Getenv("LLM_PROVIDER"), URL: os.Getenv("LLM_URL"), Model: model, APIKey: os.Getenv("LLM_API_KEY")}
+		if llm.APIKey == "" && (llm.Provider == "" || llm.Provider == "openai") {
+			llm.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+		if maxTokens := os.Getenv("LLM_MAX_TOKENS"); maxTokens != "" {
+			if llm.MaxTokens, err = strconv.Atoi(maxTokens); err != nil {
+				logger.Fatal().Err(err).Msgf("Incorrect value for LLM_MAX_TOKENS")
+			}
+		}
+		syncWorker = syncWorker.WithLLM(llm)
+	}
+	if cipher, err := columnCipher(); err != nil {
+		logger.Fatal().Err(err).Msgf("Incorrect value for COLUMN_ENCRYPTION_KEY")
+	} else if cipher != nil {
+		syncWorker = syncWorker.WithColumnEncryption(cipher)
+	}
+	if maxWeight := os.Getenv("MAX_WEIGHT"); maxWeight != "" {
+		// eg. with a MAX_WEIGHT of 4 and GIT_BLAME syncs of weight 4, a worker never runs two of them at once
+		var weight, err = strconv.Atoi(maxWeight)
+		if err != nil {
+			logger.Fatal().Err(err).Msgf("Incorrect value for MAX_WEIGHT")
+		}
+		syncWorker = syncWorker.WithMaxWeight(weight)
+	}
+	if logSampling := os.Getenv("SYNC_LOG_SAMPLING"); logSampling != "" {
+		// eg. WARNING=25,INFO=100 is the number of similar logs, by type, a job writes before they're suppressed
+		var limits = make(map[string]int)
+		for _, item := range splitList(logSampling) {
+			var logType, value, _ = strings.Cut(item, "=")
+			var limit, err = strconv.Atoi(value)
+			if err != nil {
+				logger.Fatal().Err(err).Msgf("Incorrect value for SYNC_LOG_SAMPLING: %s", item)
+			}
+			limits[strings.ToUpper(strings.TrimSpace(logType))] = limit
+		}
+		syncWorker = syncWorker.WithLogSampling(limits)
+	}
+	if pluginsConfig := os.Getenv("WORKER_PLUGINS_CONFIG"); pluginsConfig != "" {
+		var workerPlugins map[string]*plugins.Plugin
+		if workerPlugins, err = plugins.Load(pluginsConfig); err != nil {
+			logger.Fatal().Err(err).Msgf("could not load plugins from %s", pluginsConfig)
+		}
+		for syncType, p := range workerPlugins {
+			logger.Info().Msgf("sync type %s is implemented by plugin %s", syncType, p.Command)
+		}
+		syncWorker = syncWorker.WithPlugins(workerPlugins)
+	}
+	githubTransport = syncWorker.ProviderTransport
+	// the github events poller enqueues the syncs of repos that changed upstream as soon as they do (see internal/events)
+	if pollIntervalStr := os.Getenv("GITHUB_EVENTS_POLL_INTERVAL_SECONDS"); len(pollIntervalStr) != 0 {
+		var pollInterval int
+		if pollInterval, err = strconv.Atoi(pollIntervalStr); err != nil || pollInterval <= 0 {
+			logger.Fatal().Err(err).Msgf("Incorrect value for GITHUB_EVENTS_POLL_INTERVAL_SECONDS: %s", pollIntervalStr)
+		}
+		go events.New(&logger, pool).WithTransport(syncWorker.ProviderTransport).Start(ctx, time.Duration(pollInterval)*time.Second)
+	}
+	if backend.SkipLocked {
+		go syncWorker.Start(ctx)
+	}
 
 	// run a basic cron every minute to schedule a repos/auto-import job
 	// these jobs are idempotent, and so, multiple instances can run at same time without conflict
@@ -297,6 +709,38 @@ func main() {
 	// run container sync scheduler every minute
 	go cron.ContainerSync(ctx, 1*time.Minute, upstream)
 
+	// /healthz reports the state of the worker (incl. the maintenance mode), /readyz fails while it's in maintenance.
+	// They're served on HEALTH_ADDR, if set, by a mux of their own: probes don't expose the handlers of DEBUG.
+	if addr := os.Getenv("HEALTH_ADDR"); addr != "" {
+		var health = http.NewServeMux()
+		health.Handle("/healthz", syncWorker.HealthHandler(false))
+		health.Handle("/readyz", syncWorker.HealthHandler(true))
+		go func() {
+			if err := http.ListenAndServe(addr, health); err != nil {
+				logger.Err(err).Msgf("could not start health HTTP handler")
+			}
+		}()
+	}
+
+	// /search serves cross-repo searches to clients with the SEARCH_API_TOKEN, if set (see search.Handler), on
+	// SEARCH_ADDR (:8081 by default) by a mux of its own, so that the token doesn't sit next to the handlers of DEBUG
+	if token := os.Getenv("SEARCH_API_TOKEN"); token != "" {
+		var cipher, _ = columnCipher() // checked above
+		var searches = http.NewServeMux()
+		searches.Handle("/search", search.Handler(pool, cipher, token))
+
+		var addr = os.Getenv("SEARCH_ADDR")
+		if addr == "" {
+			addr = ":8081"
+		}
+		go func() {
+			if err := http.ListenAndServe(addr, searches); err != nil {
+				logger.Err(err).Msgf("could not start search HTTP handler")
+			}
+		}()
+	}
+
+	// /metrics and the pprof handlers (of http.DefaultServeMux) are only served with DEBUG
 	if os.Getenv("DEBUG") != "" {
 		go func() {
 			http.Handle("/metrics", promhttp.Handler())
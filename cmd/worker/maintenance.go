@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// maintenance implements the `maintenance` sub-command which switches the maintenance mode (see mergestat.maintenance_mode):
+// while it's enabled, the scheduler enqueues nothing and workers dequeue nothing, finishing the jobs they're running.
+// With --wait, `on` waits for the jobs running to finish, so that operators can upgrade or restore once it returns.
+//
+//	worker maintenance on --reason "upgrading Postgres" --wait 30m
+//	worker maintenance status
+//	worker maintenance off
+func maintenance(ctx context.Context, args []string, pool *pgxpool.Pool, logger *zerolog.Logger) (err error) {
+	if len(args) == 0 {
+		return errors.New("usage: worker maintenance on|off|status")
+	}
+
+	var reason string
+	var wait time.Duration
+
+	var flags = flag.NewFlagSet("maintenance", flag.ContinueOnError)
+	flags.StringVar(&reason, "reason", "", "reason the maintenance mode is enabled, shown in its status")
+	flags.DurationVar(&wait, "wait", 0, "time to wait for the jobs running to finish, once the maintenance mode is enabled")
+	if err = flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "on":
+		var nullableReason *string
+		if reason != "" {
+			nullableReason = &reason
+		}
+		if _, err = pool.Exec(ctx, "SELECT mergestat.enable_maintenance_mode($1)", nullableReason); err != nil {
+			return errors.Wrapf(err, "failed to enable maintenance mode")
+		}
+		logger.Info().Msg("maintenance mode enabled, no sync is scheduled or dequeued until it's disabled")
+
+		if wait > 0 {
+			if err = drain(ctx, pool, wait, logger); err != nil {
+				return err
+			}
+		}
+
+	case "off":
+		if _, err = pool.Exec(ctx, "SELECT mergestat.disable_maintenance_mode()"); err != nil {
+			return errors.Wrapf(err, "failed to disable maintenance mode")
+		}
+		logger.Info().Msg("maintenance mode disabled, workers resume")
+
+	case "status":
+	default:
+		return errors.Errorf("unknown action %q, expected on, off or status", args[0])
+	}
+
+	return printMaintenanceStatus(ctx, pool)
+}
+
+// drain waits (up to timeout) for the jobs running to finish
+func drain(ctx context.Context, pool *pgxpool.Pool, timeout time.Duration, logger *zerolog.Logger) error {
+	var deadline = time.Now().Add(timeout)
+	for {
+		var running int
+		if err := pool.QueryRow(ctx, "SELECT running_jobs FROM mergestat.maintenance_status").Scan(&running); err != nil {
+			return err
+		}
+		if running == 0 {
+			logger.Info().Msg("all running jobs finished")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("%d job(s) still running after %s", running, timeout)
+		}
+
+		logger.Info().Msgf("waiting for %d running job(s) to finish", running)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func printMaintenanceStatus(ctx context.Context, pool *pgxpool.Pool) error {
+	var enabled, drained bool
+	var reason, enabledBy *string
+	var enabledAt *time.Time
+	var running int
+
+	const status = `SELECT enabled, reason, enabled_at, enabled_by, running_jobs, drained FROM mergestat.maintenance_status`
+	if err := pool.QueryRow(ctx, status).Scan(&enabled, &reason, &enabledAt, &enabledBy, &running, &drained); err != nil {
+		return errors.Wrapf(err, "failed to read maintenance status")
+	}
+
+	var table = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(table, "ENABLED\t%t\n", enabled)
+	if enabled {
+		if reason != nil {
+			fmt.Fprintf(table, "REASON\t%s\n", *reason)
+		}
+		if enabledAt != nil {
+			fmt.Fprintf(table, "SINCE\t%s\n", enabledAt.Format(time.RFC3339))
+		}
+		if enabledBy != nil {
+			fmt.Fprintf(table, "BY\t%s\n", *enabledBy)
+		}
+		fmt.Fprintf(table, "DRAINED\t%t\n", drained)
+	}
+	fmt.Fprintf(table, "RUNNING JOBS\t%d\n", running)
+	return table.Flush()
+}
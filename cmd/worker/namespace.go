@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/dialect"
+	"github.com/mergestat/mergestat/internal/namespace"
+	"github.com/rs/zerolog"
+)
+
+// relocationDrainTimeout is how long a worker about to move the synced tables waits for the jobs running to finish
+const relocationDrainTimeout = 30 * time.Minute
+
+// migrationsPending reports whether the database is behind the migrations in dir (or left dirty by a failed one).
+// Synced tables relocated out of public (see SYNC_SCHEMA) are only moved back when there are migrations to run.
+func migrationsPending(m *migrate.Migrate, dir string) (bool, error) {
+	var version, dirty, err = m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(dir); err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		var prefix, _, ok = strings.Cut(entry.Name(), "_")
+		if !ok || !strings.HasSuffix(entry.Name(), ".up.sql") {
+			continue
+		}
+		if v, err := strconv.ParseUint(prefix, 10, 64); err == nil && uint(v) > version {
+			return true, nil
+		}
+	}
+
+	return dirty, nil
+}
+
+// migrateAndRelocate runs the migrations in dir, moving the synced tables relocated out of public back into it meanwhile, and
+// (re)locates the synced tables into the given schema, with the given prefix. Moving tables locks them (and drops the
+// views resolving their names), so the other workers are paused (see mergestat.maintenance_mode) while tables move,
+// and workers starting at the same time take turns.
+func migrateAndRelocate(ctx context.Context, pool *pgxpool.Pool, backend *dialect.Dialect, m *migrate.Migrate, dir, schema, prefix string, logger *zerolog.Logger) (err error) {
+	var conn *pgxpool.Conn
+	if conn, err = pool.Acquire(ctx); err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if backend.AdvisoryLocks {
+		if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock(hashtext('mergestat.synced_tables'))"); err != nil {
+			return fmt.Errorf("could not lock synced tables: %w", err)
+		}
+		defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock(hashtext('mergestat.synced_tables'))") //nolint:errcheck
+	}
+
+	var paused bool
+	var resume = func() {}
+	defer func() { resume() }()
+
+	var pending bool
+	var relocated int
+	if pending, err = migrationsPending(m, dir); err != nil {
+		return fmt.Errorf("could not check for pending migrations: %w", err)
+	}
+	if relocated, err = namespace.Relocated(ctx, conn); err != nil {
+		return err
+	}
+
+	// migrations reference the synced tables in public, where tables relocated out of it are moved back meanwhile
+	if pending && relocated > 0 {
+		if resume, err = pauseWorkers(ctx, pool, logger); err != nil {
+			return err
+		}
+		paused = true
+
+		var restored int
+		if restored, err = namespace.Restore(ctx, conn); err != nil {
+			return err
+		} else if restored > 0 {
+			logger.Info().Msgf("moved %d synced table(s) back into public to run migrations", restored)
+		}
+	}
+
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("could not run migrations: %w", err)
+	}
+
+	var move bool
+	if move, err = namespace.Pending(ctx, conn, schema, prefix); err != nil || !move {
+		return err
+	}
+
+	if !paused {
+		if resume, err = pauseWorkers(ctx, pool, logger); err != nil {
+			return err
+		}
+	}
+
+	var moved int
+	if moved, err = namespace.Apply(ctx, conn, schema, prefix); err != nil {
+		return err
+	} else if moved > 0 {
+		logger.Info().Msgf("moved %d synced table(s) into schema %q with prefix %q", moved, schema, prefix)
+	}
+
+	return nil
+}
+
+// pauseWorkers enables the maintenance mode (unless it already is) and waits for the jobs running to finish. The
+// returned func disables the maintenance mode, if it enabled it.
+func pauseWorkers(ctx context.Context, pool *pgxpool.Pool, logger *zerolog.Logger) (resume func(), err error) {
+	var enabled bool
+	if err = pool.QueryRow(ctx, "SELECT mergestat.maintenance_mode_enabled()").Scan(&enabled); err != nil {
+		return nil, fmt.Errorf("could not check maintenance mode: %w", err)
+	}
+
+	resume = func() {}
+	if !enabled {
+		if _, err = pool.Exec(ctx, "SELECT mergestat.enable_maintenance_mode($1)", "moving synced tables"); err != nil {
+			return nil, fmt.Errorf("could not enable maintenance mode: %w", err)
+		}
+		logger.Info().Msg("maintenance mode enabled while synced tables are moved")
+
+		resume = func() {
+			if _, err := pool.Exec(context.Background(), "SELECT mergestat.disable_maintenance_mode()"); err != nil {
+				logger.Err(err).Msgf("could not disable maintenance mode: %v", err)
+				return
+			}
+			logger.Info().Msg("maintenance mode disabled, synced tables are in place")
+		}
+	}
+
+	if err = drain(ctx, pool, relocationDrainTimeout, logger); err != nil {
+		resume()
+		return nil, fmt.Errorf("could not pause workers to move synced tables: %w", err)
+	}
+
+	return resume, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/querypacks"
+	"github.com/rs/zerolog"
+)
+
+// packs implements the `packs` sub-command which installs (or upgrades) the query packs shipped with the worker
+// right away, rather than on the next run of the query packs routine, and reports their state.
+//
+//	worker packs --refresh
+func packs(ctx context.Context, args []string, pool *pgxpool.Pool, logger *zerolog.Logger) (err error) {
+	var refresh bool
+
+	var flags = flag.NewFlagSet("packs", flag.ContinueOnError)
+	flags.BoolVar(&refresh, "refresh", false, "refresh the snapshots of every pack, even if not due")
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err = querypacks.New(logger, pool).Sync(ctx, refresh); err != nil {
+		return err
+	}
+
+	const list = `SELECT name, enabled, version, last_refreshed_at, COALESCE(last_error, '') FROM mergestat.query_packs ORDER BY name`
+
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, list); err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, lastError string
+		var enabled bool
+		var version *int
+		var refreshedAt *time.Time
+		if err = rows.Scan(&name, &enabled, &version, &refreshedAt, &lastError); err != nil {
+			return err
+		}
+
+		var event = logger.Info()
+		if lastError != "" {
+			event = logger.Warn().Str("error", lastError)
+		}
+		event.Str("pack", name).Bool("enabled", enabled).Interface("version", version).Interface("refreshed_at", refreshedAt).Msgf("query pack %s", name)
+	}
+	return rows.Err()
+}
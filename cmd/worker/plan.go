@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/planner"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// configurations is a repeatable flag of alternative configurations to simulate
+type configurations []string
+
+func (c *configurations) String() string     { return strings.Join(*c, " ") }
+func (c *configurations) Set(v string) error { *c = append(*c, v); return nil }
+
+// plan implements the `plan` sub-command which simulates a sync cycle of the scheduled syncs, with their durations
+// over the previous cycles, and reports its expected duration, queue contention and api usage under the current
+// configuration and the alternatives given with --try, so that operators can tune concurrency before changing it.
+// An alternative overrides the total slots, the concurrency (and slots borrowed) of type groups, or api budgets:
+//
+//	worker plan --slots 20 --try "GITHUB=2" --try "slots=40,GITHUB=4,borrow:GITHUB=2,budget:GitHub=5000"
+func plan(ctx context.Context, args []string, pool *pgxpool.Pool, concurrency int, logger *zerolog.Logger) error {
+	var historyDays, slots int
+	var tries configurations
+
+	var flags = flag.NewFlagSet("plan", flag.ContinueOnError)
+	flags.IntVar(&historyDays, "history", 14, "number of days of history the durations of syncs are taken from")
+	flags.IntVar(&slots, "slots", concurrency, "total number of syncs the workers run at once (workers times their CONCURRENCY)")
+	flags.Var(&tries, "try", "alternative configuration to simulate, as comma separated slots=N, <GROUP>=N, borrow:<GROUP>=N or budget:<provider>=N (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var p, err = planner.Load(ctx, pool, time.Duration(historyDays)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+	if len(p.Syncs) == 0 {
+		return errors.New("no sync is enabled in the schedule")
+	}
+	if p.Estimated > 0 {
+		logger.Warn().Msgf("%d of %d sync(s) have no history, their duration is estimated", p.Estimated, len(p.Syncs))
+	}
+
+	var names = []string{"current"}
+	var configs = []helper.SyncPlanConfig{{Slots: slots, Groups: p.Groups, APIBudgets: p.APIBudgets}}
+	for _, try := range tries {
+		var config helper.SyncPlanConfig
+		if config, err = override(configs[0], try); err != nil {
+			return errors.Wrapf(err, "invalid configuration %q", try)
+		}
+		names, configs = append(names, try), append(configs, config)
+	}
+
+	var reports = make([]*helper.SyncPlanReport, len(configs))
+	for i := range configs {
+		reports[i] = helper.SimulateSyncCycle(p.Syncs, configs[i])
+	}
+
+	var table = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "CONFIGURATION\tSLOTS\tCYCLE\tMEAN WAIT\tMAX WAIT\tUTILIZATION\tDEFERRED\tSTUCK")
+	for i, r := range reports {
+		fmt.Fprintf(table, "%s\t%d\t%s\t%s\t%s\t%.0f%%\t%d\t%d\n", names[i], configs[i].Slots, round(r.Duration), round(r.MeanWait), round(r.MaxWait),
+			r.Utilization*100, r.Deferred, r.Stuck)
+	}
+	fmt.Fprintln(table)
+
+	fmt.Fprintln(table, "GROUP\tCONFIGURATION\tCONCURRENCY\tSYNCS\tBUSY\tMEAN WAIT\tMAX WAIT\tDONE AFTER")
+	for i, r := range reports {
+		for _, g := range r.Groups {
+			var concurrency = "unlimited"
+			if group, ok := configs[i].Groups[g.Group]; ok {
+				concurrency = fmt.Sprintf("%d (+%d)", group.ConcurrentSyncs, group.MaxBorrowedSyncs)
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", g.Group, names[i], concurrency, g.Syncs, round(g.Busy), round(g.MeanWait), round(g.MaxWait), round(g.Done))
+		}
+	}
+
+	var header bool
+	for i, r := range reports {
+		for provider, calls := range r.APICalls {
+			if !header {
+				fmt.Fprintln(table)
+				fmt.Fprintln(table, "PROVIDER\tCONFIGURATION\tAPI CALLS\tPEAK CALLS/HOUR\tBUDGET/HOUR")
+				header = true
+			}
+			var budget = "none"
+			if b, ok := configs[i].APIBudgets[provider]; ok {
+				budget = strconv.Itoa(b)
+			}
+			fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%s\n", provider, names[i], calls, r.PeakAPICallsPerHour[provider], budget)
+		}
+	}
+
+	return table.Flush()
+}
+
+// override returns a copy of the configuration with the overrides of try applied
+func override(base helper.SyncPlanConfig, try string) (helper.SyncPlanConfig, error) {
+	var config = helper.SyncPlanConfig{Slots: base.Slots, Groups: make(map[string]helper.SyncPlanGroup), APIBudgets: make(map[string]int)}
+	for name, g := range base.Groups {
+		config.Groups[name] = g
+	}
+	for provider, b := range base.APIBudgets {
+		config.APIBudgets[provider] = b
+	}
+
+	for _, setting := range splitList(try) {
+		var key, value, ok = strings.Cut(setting, "=")
+		if !ok {
+			return config, errors.Errorf("expected key=value, got %q", setting)
+		}
+		var n, err = strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return config, errors.Errorf("expected a positive number for %s, got %q", key, value)
+		}
+
+		switch {
+		case key == "slots":
+			config.Slots = n
+		case strings.HasPrefix(key, "borrow:"):
+			var g = config.Groups[strings.TrimPrefix(key, "borrow:")]
+			g.MaxBorrowedSyncs = n
+			config.Groups[strings.TrimPrefix(key, "borrow:")] = g
+		case strings.HasPrefix(key, "budget:"):
+			config.APIBudgets[strings.TrimPrefix(key, "budget:")] = n
+		default:
+			var g = config.Groups[key]
+			g.ConcurrentSyncs = n
+			config.Groups[key] = g
+		}
+	}
+	return config, nil
+}
+
+// round rounds the duration to the second, for display
+func round(d time.Duration) time.Duration { return d.Round(time.Second) }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/purge"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// purgeRepo implements the `purge` sub-command which deletes a repo and every row synced for it, in batches
+// (eg. when offboarding a repo that must not be retained).
+//
+//	worker purge --dry-run https://github.com/mergestat/mergestat
+//	worker purge --batch-size 5000 7e8b4f1c-5c8e-4d0a-9a0e-3f1b2c4d5e6f
+func purgeRepo(ctx context.Context, args []string, pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	var opts purge.Options
+
+	var flags = flag.NewFlagSet("purge", flag.ContinueOnError)
+	flags.IntVar(&opts.BatchSize, "batch-size", 10000, "maximum number of rows deleted at once from a table")
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "only report the number of rows that would be deleted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected the id or url of the repo to purge")
+	}
+	opts.Repo = flags.Arg(0)
+
+	return purge.Repo(ctx, pool, logger, opts)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// redact implements the `redact` sub-command which re-redacts the rows already synced into the public tables,
+// following the current mergestat.privacy_settings (syncs only apply them to the rows they write).
+//
+//	worker redact
+func redact(ctx context.Context, pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	var updated int64
+	if err := pool.QueryRow(ctx, "SELECT mergestat.apply_privacy_settings()").Scan(&updated); err != nil {
+		return err
+	}
+
+	logger.Info().Msgf("redacted %d row(s)", updated)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/search"
+	"github.com/pkg/errors"
+)
+
+// repeatable is a flag that may be given more than once
+type repeatable []string
+
+func (r *repeatable) String() string     { return strings.Join(*r, ",") }
+func (r *repeatable) Set(v string) error { *r = append(*r, v); return nil }
+
+// searchRepos implements the `search` sub-command which searches code, commit messages, issues or pull requests
+// across the synced repos (see search.Search), and prints the matches, grep-like or as JSON:
+//
+//	worker search --kind code --repo "github.com/mergestat/*" --tag go InsecureSkipVerify
+func searchRepos(ctx context.Context, args []string, pool *pgxpool.Pool) (err error) {
+	var kind string
+	var asJSON bool
+	var q search.Query
+
+	var flags = flag.NewFlagSet("search", flag.ContinueOnError)
+	flags.StringVar(&kind, "kind", "code", "what to search: code, commits, issues or pulls")
+	flags.Var((*repeatable)(&q.Repos), "repo", "only search the repos matching the pattern, where * matches any characters (repeatable)")
+	flags.Var((*repeatable)(&q.Tags), "tag", "only search the repos with the tag (repeatable)")
+	flags.IntVar(&q.Limit, "limit", search.DefaultLimit, fmt.Sprintf("maximum number of matches (at most %d)", search.MaxLimit))
+	flags.BoolVar(&asJSON, "json", false, "print the matches as JSON")
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if q.Text = strings.Join(flags.Args(), " "); q.Text == "" {
+		flags.Usage()
+		return errors.New("search text is required")
+	}
+	if q.Kind, err = search.ParseKind(kind); err != nil {
+		return err
+	}
+
+	var c *helper.ColumnCipher
+	if c, err = columnCipher(); err != nil {
+		return errors.Wrapf(err, "incorrect value for COLUMN_ENCRYPTION_KEY")
+	}
+
+	var matches []search.Match
+	if matches, err = search.Search(ctx, pool, c, q); err != nil {
+		return err
+	}
+
+	if asJSON {
+		var enc = json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(matches)
+	}
+
+	for _, m := range matches {
+		switch m.Kind {
+		case search.KindCode:
+			for _, line := range m.Lines {
+				fmt.Printf("%s:%s:%d: %s\n", m.Repo, m.Path, line.Number, line.Text)
+			}
+		case search.KindCommits:
+			fmt.Printf("%s@%s %s\n\t%s\n", m.Repo, m.Commit, m.Title, m.Snippet)
+		default:
+			fmt.Printf("%s#%d [%s] %s %s\n\t%s\n", m.Repo, m.Number, m.State, m.Title, m.URL, m.Snippet)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+// Package archive implements exporting everything known about a repo into a single, self-contained SQLite file.
+package archive
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Options configures an archive (see Repo)
+type Options struct {
+	// Repo is the repo to archive, by id or by url (as stored in public.repos)
+	Repo string
+
+	// File is the path of the SQLite file to write, which must not exist
+	File string
+}
+
+// source is a query for the rows of the repo (its only parameter) written into a table of the archive
+type source struct {
+	table string
+	query string
+}
+
+// Repo writes the repo, the rows of every table of the registry (see mergestat.repo_data_tables), and the history
+// of its syncs (jobs and logs) into a new SQLite file. Tables of the public schema keep their name, the ones of the
+// mergestat schema are prefixed with mergestat_, and archive_info describes the archive. Values are stored as their
+// closest SQLite type: uuids, timestamps (RFC 3339) and json as text. The file can be opened by DuckDB as well
+// (ATTACH 'repo.db' (TYPE sqlite)).
+func Repo(ctx context.Context, pool *pgxpool.Pool, logger *zerolog.Logger, opts Options) (err error) {
+	if _, err = os.Stat(opts.File); err == nil {
+		return errors.Errorf("%s already exists", opts.File)
+	}
+
+	var id uuid.UUID
+	var url string
+	if id, url, err = resolve(ctx, pool, opts.Repo); err != nil {
+		return err
+	}
+
+	var sources = []source{{"repos", "SELECT * FROM public.repos WHERE id = $1"}}
+
+	// relocated tables (see mergestat.synced_tables) are archived under their own name, as if they lived in public
+	const listTables = `
+SELECT d.table_schema, d.table_name, d.column_name, COALESCE(s.table_name, '') FROM mergestat.repo_data_tables d
+    LEFT JOIN mergestat.synced_tables s ON s.schema_name = d.table_schema AND s.physical_name = d.table_name
+    ORDER BY d.table_schema, d.table_name`
+
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, listTables); err != nil {
+		return errors.Wrapf(err, "failed to list repo data tables")
+	}
+	for rows.Next() {
+		var schema, table, column, relocated string
+		if err = rows.Scan(&schema, &table, &column, &relocated); err != nil {
+			rows.Close()
+			return err
+		}
+
+		var name = table
+		if relocated != "" {
+			name = relocated
+		} else if schema != "public" {
+			name = schema + "_" + table
+		}
+		sources = append(sources, source{name, "SELECT * FROM " + pgx.Identifier{schema, table}.Sanitize() + " WHERE " + pgx.Identifier{column}.Sanitize() + " = $1"})
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// the sync history references the syncs of the repo rather than the repo
+	const bySync = " WHERE repo_sync_id IN (SELECT id FROM mergestat.repo_syncs WHERE repo_id = $1)"
+	sources = append(sources,
+		source{"mergestat_repo_sync_queue", "SELECT * FROM mergestat.repo_sync_queue" + bySync},
+		source{"mergestat_repo_sync_queue_archive", "SELECT * FROM mergestat.repo_sync_queue_archive" + bySync},
+		source{"mergestat_repo_sync_logs", "SELECT * FROM mergestat.repo_sync_logs WHERE repo_sync_queue_id IN (SELECT id FROM mergestat.repo_sync_queue" + bySync + ")"},
+	)
+
+	var file *sqlx.DB
+	if file, err = sqlx.Open("sqlite3", opts.File); err != nil {
+		return errors.Wrapf(err, "failed to create archive")
+	}
+	defer file.Close()
+
+	var total int64
+	for _, s := range sources {
+		var count int64
+		if count, err = copyTable(ctx, pool, file, s, id); err != nil {
+			return errors.Wrapf(err, "failed to archive %s", s.table)
+		}
+		if count > 0 {
+			logger.Info().Str("table", s.table).Int64("rows", count).Msgf("archived %d row(s) of %s", count, s.table)
+		}
+		total += count
+	}
+
+	var version string
+	_ = pool.QueryRow(ctx, "SELECT version::TEXT FROM public.schema_migrations").Scan(&version)
+
+	if _, err = file.ExecContext(ctx, "CREATE TABLE archive_info (key TEXT PRIMARY KEY, value TEXT)"); err != nil {
+		return err
+	}
+	for key, value := range map[string]string{
+		"repo": url, "repo_id": id.String(), "archived_at": time.Now().UTC().Format(time.RFC3339), "schema_version": version, "rows": fmt.Sprint(total),
+	} {
+		if _, err = file.ExecContext(ctx, "INSERT INTO archive_info (key, value) VALUES (?, ?)", key, value); err != nil {
+			return err
+		}
+	}
+
+	logger.Info().Msgf("archived %d row(s) of %s into %s", total, url, opts.File)
+	return file.Close()
+}
+
+// resolve returns the id and url of the repo, given either
+func resolve(ctx context.Context, pool *pgxpool.Pool, repo string) (id uuid.UUID, url string, err error) {
+	var row pgx.Row
+	if parsed, parseErr := uuid.Parse(repo); parseErr == nil {
+		row = pool.QueryRow(ctx, "SELECT id, repo FROM public.repos WHERE id = $1", parsed)
+	} else {
+		row = pool.QueryRow(ctx, "SELECT id, repo FROM public.repos WHERE repo = $1", repo)
+	}
+
+	if err = row.Scan(&id, &url); errors.Is(err, pgx.ErrNoRows) {
+		return id, url, errors.Errorf("repo %q not found", repo)
+	}
+	return id, url, err
+}
+
+// copyTable creates the table of the archive after the columns of the query, and copies its rows into it
+func copyTable(ctx context.Context, pool *pgxpool.Pool, file *sqlx.DB, s source, repo uuid.UUID) (_ int64, err error) {
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, s.query, repo); err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var fields = rows.FieldDescriptions()
+	var columns = make([]string, len(fields))
+	var placeholders = make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = quote(string(field.Name)) + " " + sqliteType(field.DataTypeOID)
+		placeholders[i] = "?"
+	}
+
+	if _, err = file.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", quote(s.table), strings.Join(columns, ", "))); err != nil {
+		return 0, err
+	}
+
+	var tx *sqlx.Tx
+	if tx, err = file.BeginTxx(ctx, nil); err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var insert = fmt.Sprintf("INSERT INTO %s VALUES (%s)", quote(s.table), strings.Join(placeholders, ", "))
+
+	var count int64
+	for rows.Next() {
+		var values []interface{}
+		if values, err = rows.Values(); err != nil {
+			return 0, err
+		}
+		for i := range values {
+			if values[i], err = sqliteValue(values[i]); err != nil {
+				return 0, errors.Wrapf(err, "column %s", fields[i].Name)
+			}
+		}
+
+		if _, err = tx.ExecContext(ctx, insert, values...); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+// quote quotes a SQLite identifier
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqliteType returns the SQLite type (affinity) of a column of the given Postgres type
+func sqliteType(oid uint32) string {
+	switch oid {
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.BoolOID:
+		return "INTEGER"
+	case pgtype.Float4OID, pgtype.Float8OID, pgtype.NumericOID:
+		return "REAL"
+	case pgtype.ByteaOID:
+		return "BLOB"
+	default:
+		return "TEXT"
+	}
+}
+
+// sqliteValue returns the value read from Postgres as a value SQLite can store
+func sqliteValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, string, bool, int16, int32, int64, float32, float64, []byte:
+		return v, nil
+	case [16]uint8:
+		return uuid.UUID(v).String(), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return v.String(), nil
+	case map[string]interface{}, []interface{}:
+		var encoded, err = json.Marshal(v)
+		return string(encoded), err
+	case driver.Valuer:
+		var dv, err = v.Value()
+		if err != nil {
+			return nil, err
+		}
+		return sqliteValue(dv)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
@@ -12,6 +12,296 @@ import (
 	"github.com/jackc/pgtype"
 )
 
+// reverts and hotfixes of a repo, each a signal of a change that failed (in production)
+type ChangeFailure struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// REVERT (a commit reverting others) or HOTFIX (a hotfix branch, commit or pull request)
+	Kind string
+	// where the failure was detected: COMMIT or PULL_REQUEST
+	Source string
+	// hash of the revert or hotfix commit (for COMMIT failures)
+	CommitHash sql.NullString
+	// number of the hotfix pull request (for PULL_REQUEST failures)
+	PullRequestNumber sql.NullInt32
+	// hash of the commit reverted (for REVERT failures that state it)
+	RevertedCommitHash sql.NullString
+	// hotfix branch, merged by the commit or head of the pull request
+	Branch sql.NullString
+	// subject of the commit, or title of the pull request
+	Title sql.NullString
+	// time the failure was introduced (the reverted commit was committed) or noticed (the hotfix pull request was opened), if known
+	FailedAt sql.NullTime
+	// time the failure was restored: the revert or hotfix was committed, or the hotfix pull request merged
+	RestoredAt time.Time
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// actions (and reusable workflows) used by the GitHub Actions workflows of a repo
+type CiAction struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the workflow
+	Path string
+	// id of the job using the action
+	Job string
+	// position of the action among the ones the job uses
+	Position int32
+	// reference to the action, as written in the workflow
+	Uses string
+	// REMOTE (an action of another repository), LOCAL (a path of the repository) or DOCKER (a docker:// image)
+	Kind string
+	// action without its ref, eg. actions/checkout
+	Action string
+	// git ref of remote actions (a tag, branch or commit sha)
+	Ref sql.NullString
+	// true if the action is pinned to a full commit sha (or image digest)
+	Pinned bool
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// CI configuration files of a repo
+type CiConfig struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the configuration file
+	Path string
+	// CI provider the file configures, GITHUB_ACTIONS, GITLAB_CI or JENKINS
+	Provider string
+	// name of the workflow, if any
+	Name sql.NullString
+	// events triggering the workflow, eg. push or pull_request
+	Triggers []string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// GitHub Actions jobs that failed and passed on the same commit, or were retried, in the last 90 days
+type CiFlakyJob struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// id of the workflow of the job (see github_actions_workflows.id)
+	WorkflowID int64
+	// name of the workflow of the job
+	WorkflowName sql.NullString
+	// name of the job
+	JobName string
+	// number of completed executions of the job (including retries)
+	Executions int32
+	// number of distinct commits the job completed on
+	Commits int32
+	// number of commits the job both failed and succeeded on
+	FlakyCommits int32
+	// number of workflow runs the job completed in
+	Runs int32
+	// number of workflow runs the job was executed more than once in
+	RetriedRuns int32
+	// share (0 to 1) of the commits the job both failed and succeeded on
+	FlakyCommitRate pgtype.Numeric
+	// share (0 to 1) of the runs the job was retried in
+	RetryRate pgtype.Numeric
+	// the greater of flaky_commit_rate and retry_rate
+	FlakinessScore pgtype.Numeric
+	// timestamp of the latest execution on a flaky commit, or of a retried run
+	LastFlakyAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// jobs of the CI configurations of a repo
+type CiJob struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the configuration file
+	Path string
+	// id of the job
+	Job string
+	// labels of the runners the job runs on (runs-on in GitHub Actions, tags in GitLab CI)
+	RunsOn []string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// weekly durations of the successful runs of GitHub Actions workflows, compared to the preceding four weeks
+type CiWorkflowDuration struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// id of the workflow (see github_actions_workflows.id)
+	WorkflowID int64
+	// name of the workflow
+	WorkflowName sql.NullString
+	// first day (monday) of the week the runs started in
+	Week time.Time
+	// number of successful runs
+	Runs int32
+	// median duration of the runs
+	P50Duration int64
+	// 95th percentile duration of the runs
+	P95Duration int64
+	// median duration of the runs of the preceding four weeks
+	BaselineP50Duration sql.NullInt64
+	// 95th percentile duration of the runs of the preceding four weeks
+	BaselineP95Duration sql.NullInt64
+	// change of the 95th percentile duration compared to the baseline, in percent
+	P95ChangePercent pgtype.Numeric
+	// whether the 95th percentile duration exceeded the baseline by more than the threshold
+	Regressed bool
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// imports of the Go and JavaScript/TypeScript files of a repo (file to imported module), parsed from git_files
+type CodeImport struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the importing file
+	Path string
+	// line of the import
+	Line int32
+	// language of the importing file (Go, JavaScript or TypeScript)
+	Language string
+	// module imported, as written (eg. github.com/jackc/pgx/v4, react or ./utils)
+	Module string
+	// import, require (require() calls) or dynamic (import() calls)
+	Kind string
+	// path (within the repo) of the directory or file imported, for internal imports
+	ResolvedPath sql.NullString
+	// true if the import refers to the repo itself: a relative import, or a package of a Go module of the repo
+	Internal bool
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type CodeImportEdge struct {
+	RepoID   uuid.UUID
+	Path     string
+	Line     int32
+	Language string
+	Module   string
+	Internal bool
+	// repo declaring the module imported
+	TargetRepoID uuid.UUID
+	// module (declared by the target repo) the import refers to
+	TargetModule string
+}
+
+// modules declared by a repo (by go.mod and package.json files), which imports of other repos may refer to
+type CodeModule struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the go.mod or package.json file declaring the module
+	Path string
+	// Go for go.mod files, JavaScript for package.json files
+	Language string
+	// module path (go.mod) or package name (package.json)
+	Name string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type CodeownersRule struct {
+	RepoID uuid.UUID
+	// path of the CODEOWNERS file
+	CodeownersPath string
+	// line of the rule in the CODEOWNERS file (later rules take precedence)
+	LineNo int32
+	// path pattern of the rule
+	Pattern interface{}
+	// owner of the paths matching the pattern: @user, @org/team or an email
+	Owner interface{}
+}
+
+// container images referenced by the Dockerfiles and compose files of a repo
+type ContainerImage struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the file referencing the image
+	Path string
+	// line of the file the image is referenced at
+	Line int32
+	// DOCKERFILE (a FROM instruction) or COMPOSE (a service image)
+	Source string
+	// image reference, as written in the file (with build args substituted)
+	Image string
+	// registry of the image, docker.io if not specified
+	Registry string
+	// repository of the image in the registry, eg. library/golang
+	Repository string
+	// tag of the image, if any
+	Tag sql.NullString
+	// digest the image is pinned to, if any
+	Digest sql.NullString
+	// name of the build stage (FROM ... AS stage) of Dockerfile images, if any
+	Stage sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// npm, PyPI and Go dependencies of a repo (as found by its Syft scan), and how far behind the latest release of their registry they are
+type DependencyLag struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// registry the dependency is published to, npm, pypi or go
+	Registry string
+	// name of the dependency (or path of the Go module)
+	Name string
+	// version of the dependency the repo depends on
+	Version string
+	// time the version the repo depends on was published, NULL if the registry does not know it
+	VersionReleasedAt sql.NullTime
+	// latest (non-prerelease) version of the dependency
+	LatestVersion sql.NullString
+	// time the latest version was published
+	LatestReleasedAt sql.NullTime
+	// number of (non-prerelease) versions published after the version the repo depends on, NULL if unknown
+	VersionsBehind sql.NullInt32
+	// years between the release of the version the repo depends on and that of the latest version, NULL if unknown
+	Libyears sql.NullFloat64
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type DependencyUpdateLagScore struct {
+	RepoID                uuid.UUID
+	Repo                  string
+	Dependencies          int64
+	OutdatedDependencies  int64
+	Libyears              sql.NullInt64
+	LibyearsPerDependency sql.NullFloat64
+	OpenUpdatePrs         int64
+	OldestOpenUpdatePrAge interface{}
+	MedianTimeToMerge     sql.NullInt32
+	MergeRate             sql.NullInt32
+}
+
+// pull requests of a repo opened by dependency update bots (Dependabot or Renovate)
+type DependencyUpdatePr struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// number of the pull request (see github_pull_requests.number)
+	Number int32
+	// bot that opened the pull request: dependabot or renovate
+	Bot string
+	// name of the dependency updated, as stated by the title of the pull request (NULL for grouped updates)
+	Dependency sql.NullString
+	// version the dependency is updated from, if stated
+	FromVersion sql.NullString
+	// version the dependency is updated to, if stated
+	ToVersion sql.NullString
+	// state of the pull request: OPEN, CLOSED or MERGED
+	State sql.NullString
+	// time the pull request was opened
+	CreatedAt sql.NullTime
+	// time the pull request was closed (or merged)
+	ClosedAt sql.NullTime
+	// time the pull request was merged
+	MergedAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 // git blame of all lines in all files of a repo
 type GitBlame struct {
 	// foreign key for public.repos.id
@@ -32,6 +322,34 @@ type GitBlame struct {
 	MergestatSyncedAt time.Time
 }
 
+// git blame of all lines of a repo at a point in time (a ref, or the last commit before a date)
+type GitBlameSnapshot struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// ref the blame was taken at (HEAD if only a date was given)
+	Ref string
+	// date the blame was taken as of, the revision is the last commit of the ref before it (null if none was given)
+	AsOf sql.NullTime
+	// hash of the commit the blame was taken at
+	Revision string
+	// email of the author who last modified the line
+	AuthorEmail sql.NullString
+	// name of the author who last modified the line
+	AuthorName sql.NullString
+	// timestamp of when the line was last modified
+	AuthorWhen sql.NullTime
+	// hash of the commit the line was last modified in
+	CommitHash sql.NullString
+	// line number
+	LineNo int32
+	// content of the line
+	Line sql.NullString
+	// path of the file
+	Path string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 type GitBranch struct {
 	// foreign key for public.repos.id
 	RepoID   uuid.UUID
@@ -52,6 +370,69 @@ type GitBranch struct {
 	MergestatSyncedAt time.Time
 }
 
+// age, activity and divergence from the default branch of the branches of a repo, refreshed on each GIT_REFS sync
+type GitBranchStat struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// name of the branch, without the remote prefix
+	Name string
+	// hash of the commit the branch points to
+	Hash string
+	// true if this is the default branch of the repo
+	IsDefault bool
+	// author date of the oldest commit of the branch not in the default branch, null if there is none
+	FirstCommitAt sql.NullTime
+	// committer date of the commit the branch points to
+	LastCommitAt sql.NullTime
+	// author email of the commit the branch points to
+	LastCommitAuthorEmail sql.NullString
+	// number of commits of the branch not in the default branch
+	Ahead int32
+	// number of commits of the default branch not in the branch
+	Behind int32
+	// true if every commit of the branch is in the default branch
+	Merged bool
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// bus factor of a repo and its directories, one set of rows per GIT_BUS_FACTOR sync (kept for trending)
+type GitBusFactor struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// time the metrics were computed, shared by all the rows of a sync
+	ComputedAt time.Time
+	// directory the metric covers, empty for the whole repo
+	Directory string
+	// what contributions are counted, lines (from git_file_ownership) or commits (from git_commit_stats)
+	Metric string
+	// percentage of the contributions the authors must cover
+	Coverage float64
+	// smallest number of authors covering the given percentage of the contributions
+	BusFactor int32
+	// emails of the authors counted in the bus factor, largest contributor first
+	Authors []string
+	// number of authors with contributions to the directory
+	TotalAuthors int32
+	// number of contributions (lines or commits) to the directory
+	Total int64
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type GitBusFactorLatest struct {
+	RepoID            uuid.UUID
+	ComputedAt        time.Time
+	Directory         string
+	Metric            string
+	Coverage          float64
+	BusFactor         int32
+	Authors           []string
+	TotalAuthors      int32
+	Total             int64
+	MergestatSyncedAt time.Time
+}
+
 // git commit history of a repo
 type GitCommit struct {
 	// foreign key for public.repos.id
@@ -59,17 +440,17 @@ type GitCommit struct {
 	// hash of the commit
 	Hash string
 	// message of the commit
-	Message string
+	Message sql.NullString
 	// name of the author of the the modification
-	AuthorName string
+	AuthorName sql.NullString
 	// email of the author of the modification
-	AuthorEmail string
+	AuthorEmail sql.NullString
 	// timestamp of when the modifcation was authored
 	AuthorWhen time.Time
 	// name of the author who committed the modification
-	CommitterName string
+	CommitterName sql.NullString
 	// email of the author who committed the modification
-	CommitterEmail string
+	CommitterEmail sql.NullString
 	// timestamp of when the commit was made
 	CommitterWhen time.Time
 	// the number of parents of the commit
@@ -78,6 +459,97 @@ type GitCommit struct {
 	MergestatSyncedAt time.Time
 }
 
+// conventional commit structure of the commit messages of a repo (commits not following the convention are omitted)
+type GitCommitConvention struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// hash of the commit
+	CommitHash string
+	// type of the change, lower cased, eg. feat or fix
+	Type string
+	// scope of the change, if any, eg. parser
+	Scope sql.NullString
+	// true if the commit is marked as a breaking change (! or a BREAKING CHANGE footer)
+	Breaking bool
+	// description of the change, from the first line of the message
+	Description string
+	// ticket references found in the message, eg. #123 or ABC-123
+	TicketRefs []string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type GitCommitLeadTime struct {
+	RepoID            uuid.UUID
+	CommitHash        string
+	PullRequestNumber int32
+	AuthorWhen        sql.NullTime
+	MergedAt          sql.NullTime
+	LeadTime          int32
+}
+
+// message quality and size metrics of the commits of a repo
+type GitCommitMetric struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// hash of the commit
+	CommitHash string
+	// number of characters of the (trimmed) commit message
+	MessageLength int32
+	// number of characters of the first line of the commit message
+	SubjectLength int32
+	// true if the subject is non-empty, at most 72 characters, and does not end with a period
+	SubjectConforms bool
+	// true if the message has a body, separated from the subject by a blank line
+	HasBody bool
+	// number of files the commit changes
+	FilesChanged int32
+	// number of lines the commit adds
+	Additions int32
+	// number of lines the commit removes
+	Deletions int32
+	// size of the commit by lines changed: XS (< 10), S (< 50), M (< 250), L (< 1000) or XL
+	SizeBucket string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// unified diff of the files changed by the commits of a repo, truncated per file and per commit
+type GitCommitPatch struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// hash of the commit
+	CommitHash string
+	// path of the file changed
+	FilePath string
+	// path of the file before the commit (differs from file_path if it was renamed)
+	OldFilePath sql.NullString
+	// unified diff of the file, possibly truncated (empty for binary files)
+	Patch string
+	// size (in bytes) of the whole unified diff of the file
+	Size int32
+	// true if the patch holds only part of the unified diff (see the patchMaxFileBytes and patchMaxCommitBytes sync settings)
+	Truncated bool
+	// true if the file is binary
+	IsBinary bool
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// commits of a repo and the pull requests they belong to
+type GitCommitPullRequest struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// hash of the commit
+	CommitHash string
+	// number of the pull request
+	PullRequestNumber int32
+	// PR_COMMIT for commits of the pull request (from github_pull_request_commits), MERGE_COMMIT for the merge commit of the pull request and SQUASH_COMMIT for the commit a pull request was squashed into (both from git_commits)
+	Association string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 // git commit stats of a repo
 type GitCommitStat struct {
 	// foreign key for public.repos.id
@@ -98,6 +570,28 @@ type GitCommitStat struct {
 	NewFileMode string
 }
 
+// embeddings of the commit messages and README/doc files of a repo, for semantic search (eg. ORDER BY embedding <=> $1::vector with pgvector) and clustering
+type GitEmbedding struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// what is embedded: commit_message or file
+	Kind string
+	// hash of the commit (commit_message), or path of the file (file)
+	Key string
+	// model the embedding was computed with, embeddings of different models must not be compared
+	Model string
+	// SHA-256 of the text embedded, so that only texts that changed are embedded again
+	ContentHash string
+	// number of dimensions of the embedding
+	Dimensions int32
+	// embedding of the text (a pgvector vector, or REAL[] without pgvector)
+	Embedding []float32
+	// timestamp when the embedding was computed
+	EmbeddedAt time.Time
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 // git files (content and paths) of a repo
 type GitFile struct {
 	// foreign key for public.repos.id
@@ -110,6 +604,128 @@ type GitFile struct {
 	Contents sql.NullString
 	// timestamp when record was synced into the MergeStat database
 	MergestatSyncedAt time.Time
+	// SHA-256 (hex encoded) of the contents of the file, binary files included (NULL if synced before content hashes were recorded)
+	ContentHash sql.NullString
+}
+
+// files added, modified or deleted since the previous GIT_FILES sync of a repo, so that consumers can process deltas rather than all of git_files
+type GitFileChange struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// id of the sync job (in mergestat.repo_sync_queue) that observed the change, increasing from one sync to the next
+	SyncID int64
+	// path of the file
+	Path string
+	// added, modified or deleted (files whose previous content hash is unknown are never reported as modified)
+	Change string
+	// content hash of the file as of the previous sync (NULL if added)
+	OldContentHash sql.NullString
+	// content hash of the file as of the sync (NULL if deleted)
+	NewContentHash sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// per file age histogram of the lines of git_blame (code decay), maintained by GIT_BLAME syncs
+type GitFileLineAge struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the file
+	Path string
+	// number of lines of the file
+	Lines int32
+	// percentage (0 to 100) of the lines of the file last modified more than a year before the sync
+	OlderThan1y float64
+	// percentage (0 to 100) of the lines of the file last modified more than 2 years before the sync
+	OlderThan2y float64
+	// percentage (0 to 100) of the lines of the file last modified more than 3 years before the sync
+	OlderThan3y float64
+	// median age (in days) of the lines of the file, by the author date of the commit that last modified them
+	MedianAgeDays int32
+	// author date of the oldest line of the file
+	OldestLineAt sql.NullTime
+	// author date of the newest line of the file
+	NewestLineAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// per file, per author rollup of git_blame, maintained by GIT_BLAME syncs
+type GitFileOwnership struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the file
+	Path string
+	// email of the author the lines are attributed to
+	AuthorEmail string
+	// name of the author the lines are attributed to
+	AuthorName sql.NullString
+	// number of lines of the file attributed to the author
+	Lines int32
+	// number of lines of the file
+	TotalLines int32
+	// percentage (0 to 100) of the lines of the file attributed to the author
+	Ownership float64
+	// author date of the most recent commit of the author still present in the file
+	LastTouchedAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type GitFileOwnershipSnapshot struct {
+	RepoID        uuid.UUID
+	Ref           string
+	AsOf          sql.NullTime
+	Revision      string
+	Path          string
+	AuthorEmail   sql.NullString
+	AuthorName    interface{}
+	Lines         int64
+	TotalLines    int64
+	Ownership     int32
+	LastTouchedAt interface{}
+}
+
+// binary files, and files above a size threshold (see the largeFileThreshold sync setting), at the HEAD of a repo
+type GitLargeFile struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the file
+	Path string
+	// size of the file in bytes
+	Size int64
+	// hash of the blob of the file
+	BlobHash string
+	// true if the file is detected as binary
+	IsBinary bool
+	// true if the file is above the size threshold
+	Large bool
+	// hash of the most recent commit modifying the file
+	LastCommitHash sql.NullString
+	// committer date of the most recent commit modifying the file
+	LastCommitAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// bare mirrors (every ref) of repos, maintained by GIT_MIRROR syncs for offline analysis
+type GitMirror struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the mirror, on the storage of the worker (under GIT_MIRROR_PATH)
+	Path string
+	// hash of the commit HEAD of the mirror points to
+	HeadCommitHash sql.NullString
+	// number of refs of the mirror
+	Refs int32
+	// size of the mirror on disk in bytes
+	Size int64
+	// timestamp when the mirror was cloned
+	CreatedAt time.Time
+	// timestamp when the mirror was last fetched into
+	FetchedAt time.Time
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
 }
 
 // git refs of a repo
@@ -133,6 +749,54 @@ type GitRef struct {
 	MergestatSyncedAt time.Time
 }
 
+// releases (tags) of a repo, with a summary of their changes
+type GitRelease struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// name of the tag of the release
+	Tag string
+	// hash of the commit the tag points to
+	CommitHash string
+	// commit time of the tagged commit
+	ReleasedAt time.Time
+	// tag of the previous release
+	PreviousTag sql.NullString
+	// number of commits since the previous release
+	CommitCount int32
+	// number of pull requests merged since the previous release
+	PullRequestCount int32
+	// number of feat changes (conventional commits and pull request titles)
+	FeatureCount int32
+	// number of fix changes (conventional commits and pull request titles)
+	FixCount int32
+	// number of breaking changes
+	BreakingCount int32
+	// median time from a change being authored (or its pull request opened) to its release
+	MedianLeadTime    sql.NullInt64
+	MergestatSyncedAt time.Time
+}
+
+// changelog of each release of a repo: the commits and merged pull requests since the previous release
+type GitReleaseChange struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// tag of the release the change shipped in
+	Tag string
+	// COMMIT or PULL_REQUEST
+	Source            string
+	CommitHash        sql.NullString
+	PullRequestNumber sql.NullInt32
+	// conventional commit type of the change (from GIT_COMMIT_CONVENTIONS, or the pull request title)
+	Type     sql.NullString
+	Scope    sql.NullString
+	Breaking bool
+	// commit subject or pull request title
+	Description sql.NullString
+	// time from the change being authored (or its pull request opened) to its release
+	LeadTime          sql.NullInt64
+	MergestatSyncedAt time.Time
+}
+
 // table of git repo remotes
 type GitRemote struct {
 	// foreign key for public.repos.id
@@ -145,6 +809,30 @@ type GitRemote struct {
 	MergestatSyncedAt time.Time
 }
 
+// symbols defined in the files of a repo at HEAD, as extracted by universal-ctags
+type GitSymbol struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the file defining the symbol
+	Path string
+	// name of the symbol
+	Name string
+	// kind of the symbol, as named by ctags for the language (eg. func, struct, method, class, variable)
+	Kind string
+	// line of the file the symbol is defined at
+	Line int32
+	// language of the file, as detected by ctags
+	Language string
+	// name of the symbol enclosing the symbol (eg. the class of a method), if any
+	Scope sql.NullString
+	// kind of the enclosing symbol, if any
+	ScopeKind sql.NullString
+	// signature of functions and methods, for the languages ctags reports it for
+	Signature sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 type GitTag struct {
 	// foreign key for public.repos.id
 	RepoID   uuid.UUID
@@ -165,6 +853,100 @@ type GitTag struct {
 	MergestatSyncedAt time.Time
 }
 
+type GithubActionsFlakyTest struct {
+	RepoID       uuid.UUID
+	WorkflowID   sql.NullInt64
+	Suite        string
+	Classname    string
+	Name         string
+	Commits      int64
+	FlakyCommits int64
+	FlakyRate    int32
+}
+
+type GithubActionsMonthlyUsage struct {
+	RepoID          uuid.UUID
+	Repo            string
+	Month           time.Time
+	RunnerOs        string
+	Runs            int64
+	Jobs            int64
+	DurationMs      int64
+	BillableMinutes int64
+}
+
+type GithubActionsTeamMonthlyUsage struct {
+	Org             string
+	Team            string
+	Month           time.Time
+	RunnerOs        string
+	Repos           int64
+	Runs            int64
+	Jobs            int64
+	BillableMinutes int64
+}
+
+type GithubActionsTestCase struct {
+	RepoID          uuid.UUID
+	RunID           int64
+	WorkflowID      sql.NullInt64
+	WorkflowName    sql.NullString
+	HeadBranch      sql.NullString
+	CommitHash      interface{}
+	RunAttempt      sql.NullInt32
+	RunCreatedAt    sql.NullTime
+	Suite           string
+	Classname       string
+	Name            string
+	Status          string
+	DurationSeconds float64
+	Message         sql.NullString
+}
+
+// results of the test cases of the JUnit XML reports uploaded as artifacts by GitHub Actions workflow runs
+type GithubActionsTestResult struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// id of the workflow run (see github_actions_workflow_runs.id)
+	RunID int64
+	// id of the artifact the report was found in
+	ArtifactID int64
+	// name of the artifact the report was found in
+	ArtifactName string
+	// path of the report in the artifact
+	File string
+	// name of the (innermost named) test suite of the test case
+	Suite string
+	// class name of the test case, as reported by the test runner
+	Classname string
+	// name of the test case
+	Name string
+	// result of the test case: passed, failed, error or skipped
+	Status string
+	// duration of the test case in seconds
+	DurationSeconds float64
+	// message of the failure, error or skip, truncated to 4 KiB
+	Message sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// completed GitHub Actions workflow runs the artifacts of which were scanned for JUnit reports (including runs without any), so that they are only scanned once
+type GithubActionsTestResultRun struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// id of the workflow run (see github_actions_workflow_runs.id)
+	RunID int64
+	// number of artifacts of the run that were downloaded as JUnit reports
+	Artifacts int32
+	// number of test cases found in the reports of the run
+	TestCases int32
+	// time the artifacts of the run were scanned
+	ScannedAt time.Time
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 type GithubActionsWorkflow struct {
 	RepoID            uuid.UUID
 	ID                int64
@@ -180,6 +962,24 @@ type GithubActionsWorkflow struct {
 	MergestatSyncedAt time.Time
 }
 
+// billable time of the jobs of completed GitHub Actions workflow runs, by runner operating system
+type GithubActionsWorkflowJobUsage struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// id of the workflow run (see github_actions_workflow_runs.id)
+	RunID int64
+	// id of the job (see github_actions_workflow_run_jobs.id)
+	JobID int64
+	// operating system of the GitHub hosted runner, eg. UBUNTU, WINDOWS or MACOS
+	RunnerOs string
+	// billable time of the job in milliseconds
+	DurationMs int64
+	// minutes GitHub bills for the job: rounded up to the minute and multiplied by the rate of the runner operating system
+	BillableMinutes int64
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 type GithubActionsWorkflowRun struct {
 	RepoID            uuid.UUID
 	ID                int64
@@ -238,6 +1038,68 @@ type GithubActionsWorkflowRunJob struct {
 	MergestatSyncedAt time.Time
 }
 
+// timing and billable time of completed GitHub Actions workflow runs
+type GithubActionsWorkflowRunUsage struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// id of the workflow run (see github_actions_workflow_runs.id)
+	RunID int64
+	// wall clock duration of the run in milliseconds
+	RunDurationMs sql.NullInt64
+	// time the jobs of the run spent on GitHub hosted runners in milliseconds (0 for self-hosted runners and public repos)
+	BillableMs int64
+	// minutes GitHub bills for the run: each job rounded up to the minute and multiplied by the rate of its runner operating system
+	BillableMinutes int64
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// upstream of a fork, and how far its default branch diverged from that of the upstream, one row per GITHUB_FORK_DRIFT sync (kept for trending)
+type GithubForkDrift struct {
+	// foreign key for public.repos.id (of the fork)
+	RepoID uuid.UUID
+	// time the drift was computed
+	ComputedAt time.Time
+	// owner of the repo the fork was forked from (its parent)
+	UpstreamOwner string
+	// name of the repo the fork was forked from
+	UpstreamName string
+	// id of the upstream in public.repos, if it's synced too
+	UpstreamRepoID uuid.NullUUID
+	// default branch of the fork
+	DefaultBranch string
+	// default branch of the upstream
+	UpstreamDefaultBranch string
+	// status of the fork versus its upstream: identical, ahead, behind or diverged, NULL if they could not be compared (eg. unrelated histories)
+	Status sql.NullString
+	// number of commits of the fork's default branch not in the upstream's
+	AheadBy sql.NullInt32
+	// number of commits of the upstream's default branch not in the fork's
+	BehindBy sql.NullInt32
+	// hash of the latest commit the two branches share
+	MergeBaseHash sql.NullString
+	// time the latest commit the two branches share was committed, ie. since when they diverged
+	MergeBaseCommittedAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type GithubForkDriftLatest struct {
+	RepoID                uuid.UUID
+	ComputedAt            time.Time
+	UpstreamOwner         string
+	UpstreamName          string
+	UpstreamRepoID        uuid.NullUUID
+	DefaultBranch         string
+	UpstreamDefaultBranch string
+	Status                sql.NullString
+	AheadBy               sql.NullInt32
+	BehindBy              sql.NullInt32
+	MergeBaseHash         sql.NullString
+	MergeBaseCommittedAt  sql.NullTime
+	MergestatSyncedAt     time.Time
+}
+
 // issues of a GitHub repo
 type GithubIssue struct {
 	// foreign key for public.repos.id
@@ -290,6 +1152,244 @@ type GithubIssue struct {
 	MergestatSyncedAt time.Time
 }
 
+// comments of GitHub issues and pull requests (including pull request review comments)
+type GithubIssueComment struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// GitHub id of the comment
+	ID int64
+	// ISSUE_COMMENT for comments of the conversation of an issue or pull request, REVIEW_COMMENT for comments on the diff of a pull request
+	Kind string
+	// number of the issue or pull request
+	IssueNumber int32
+	// whether the comment belongs to a pull request
+	IsPullRequest bool
+	// login of the author of the comment
+	AuthorLogin sql.NullString
+	// relationship of the author to the repo, eg. MEMBER or CONTRIBUTOR
+	AuthorAssociation sql.NullString
+	// body of the comment
+	Body sql.NullString
+	// path of the file a review comment is on
+	Path sql.NullString
+	// id of the review comment a review comment replies to
+	InReplyToID sql.NullInt64
+	// timestamp when the comment was created
+	CreatedAt sql.NullTime
+	// timestamp when the comment was last updated
+	UpdatedAt sql.NullTime
+	// url of the comment
+	Url sql.NullString
+	// total number of reactions to the comment
+	ReactionsTotal int32
+	// number of reactions to the comment by content, eg. {"+1": 2, "heart": 1}
+	Reactions pgtype.JSONB
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// periods GitHub issues and pull requests spent with a label (eg. in a triage state)
+type GithubIssueLabelDuration struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// number of the issue or pull request
+	IssueNumber int32
+	// whether the item is a pull request
+	IsPullRequest bool
+	// name of the label
+	Label string
+	// timestamp when the label was added
+	LabeledAt time.Time
+	// timestamp when the label was removed, NULL if it still is labeled
+	UnlabeledAt sql.NullTime
+	// time spent with the label, until it was removed, the item closed or (if neither) the time of the sync
+	Duration int64
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// labels added to and removed from GitHub issues and pull requests
+type GithubIssueLabelEvent struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// GitHub id of the event
+	ID int64
+	// number of the issue or pull request
+	IssueNumber int32
+	// whether the event belongs to a pull request
+	IsPullRequest bool
+	// labeled or unlabeled
+	Event string
+	// name of the label
+	Label string
+	// login of the user who added or removed the label
+	ActorLogin sql.NullString
+	// timestamp of the event
+	CreatedAt time.Time
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// rollup of the reactions to GitHub issues and pull requests (reactions to their comments are in github_issue_comments)
+type GithubIssueReaction struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// number of the issue or pull request
+	IssueNumber int32
+	// whether the item is a pull request
+	IsPullRequest bool
+	// total number of reactions to the item
+	ReactionsTotal int32
+	// number of reactions to the item by content, eg. {"+1": 2, "heart": 1}
+	Reactions pgtype.JSONB
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// time to the first response (and, for pull requests, to the first review) of GitHub issues and pull requests
+type GithubIssueResponseTime struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// number of the issue or pull request
+	IssueNumber int32
+	// whether the item is a pull request
+	IsPullRequest bool
+	// login of the author of the item
+	AuthorLogin sql.NullString
+	// timestamp when the item was created
+	CreatedAt sql.NullTime
+	// timestamp of the first comment (or review) by someone other than the author (and other than a bot), NULL if none yet
+	FirstResponseAt sql.NullTime
+	// login of the author of the first response
+	FirstResponderLogin sql.NullString
+	// time from the item being created to the first response
+	TimeToFirstResponse sql.NullInt64
+	// timestamp of the first review of a pull request by someone other than the author, NULL if none yet
+	FirstReviewAt sql.NullTime
+	// time from the pull request being created to the first review
+	TimeToFirstReview sql.NullInt64
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// audit log events of GitHub Enterprise Cloud organizations (append-only)
+type GithubOrgAuditLog struct {
+	// login of the organization
+	Org string
+	// GitHub id of the event
+	DocumentID string
+	// action performed, eg. repo.create, org.add_member or git.push
+	Action sql.NullString
+	// category of the action (the part of action before the first dot), eg. repo, org or git
+	Category sql.NullString
+	// login of the user who performed the action
+	Actor sql.NullString
+	// login of the user affected by the action, if any
+	UserLogin sql.NullString
+	// repo (owner/name) affected by the action, if any
+	Repo sql.NullString
+	// timestamp of the event
+	CreatedAt sql.NullTime
+	// the event, as returned by the audit log api
+	Entry pgtype.JSONB
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// GitHub projects (Projects v2) linked to a repo
+type GithubProject struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// GraphQL node id of the project
+	ProjectID string
+	// number of the project (unique within its owner)
+	Number sql.NullInt32
+	// title of the project
+	Title sql.NullString
+	// url of the project
+	Url sql.NullString
+	// whether the project is closed
+	Closed sql.NullBool
+	// timestamp when the project was created
+	CreatedAt sql.NullTime
+	// timestamp when the project was last updated
+	UpdatedAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// fields (built-in and custom) of GitHub projects
+type GithubProjectField struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// GraphQL node id of the project
+	ProjectID string
+	// GraphQL node id of the field
+	FieldID string
+	// name of the field, eg. Status
+	Name string
+	// data type of the field, eg. SINGLE_SELECT, ITERATION, TEXT, NUMBER or DATE
+	DataType sql.NullString
+	// options of a single select field, or iterations of an iteration field
+	Options pgtype.JSONB
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// items of GitHub projects, and the issue, pull request or draft issue they hold
+type GithubProjectItem struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// GraphQL node id of the project
+	ProjectID string
+	// GraphQL node id of the item
+	ItemID string
+	// type of the content of the item: ISSUE, PULL_REQUEST, DRAFT_ISSUE or REDACTED
+	Type sql.NullString
+	// repository (owner/name) of the issue or pull request, items of a project may belong to any repo
+	ContentRepository sql.NullString
+	// number of the issue or pull request, joins with github_issues.number and github_pull_requests.number
+	ContentNumber sql.NullInt32
+	// url of the issue or pull request
+	ContentUrl sql.NullString
+	// title of the content of the item
+	Title sql.NullString
+	// whether the item is archived
+	IsArchived sql.NullBool
+	// timestamp when the item was added to the project
+	CreatedAt sql.NullTime
+	// timestamp when the item was last updated
+	UpdatedAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// values of the fields (status, iteration, custom fields...) of GitHub project items
+type GithubProjectItemFieldValue struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// GraphQL node id of the project
+	ProjectID string
+	// GraphQL node id of the item
+	ItemID string
+	// name of the field, eg. Status
+	FieldName string
+	// type of the value: SINGLE_SELECT, ITERATION, TEXT, NUMBER or DATE
+	FieldType string
+	// value of a text field, name of the option of a single select field or title of the iteration of an iteration field
+	TextValue sql.NullString
+	// value of a number field
+	NumberValue sql.NullFloat64
+	// value of a date field or start date of the iteration of an iteration field
+	DateValue sql.NullTime
+	// duration, in days, of the iteration of an iteration field
+	IterationDuration sql.NullInt32
+	// timestamp when the value was last updated
+	UpdatedAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 // GitHub Workflow Run Jobs
 type GithubPullRequest struct {
 	// foreign key for public.repos.id
@@ -451,6 +1551,28 @@ type GithubPullRequestReview struct {
 	MergestatSyncedAt time.Time
 }
 
+// summary of a large pull request generated by an LLM, from its description, size and commits
+type GithubPullRequestSummary struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// number of the pull request
+	PrNumber int32
+	// model the summary was requested from, as configured
+	Model string
+	// model (version) that generated the summary, as reported by the endpoint
+	ModelVersion sql.NullString
+	// version of the prompt the summary was generated with, summaries are generated again when it changes
+	PromptVersion int32
+	// summary of the pull request, generated (and not reviewed), it may be inaccurate
+	Summary string
+	// SHA-256 of the prompt, so that the summary is only generated again when its inputs change
+	InputHash string
+	// timestamp when the summary was generated
+	GeneratedAt time.Time
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 // info/metadata of a GitHub repo
 type GithubRepoInfo struct {
 	// foreign key for public.repos.id
@@ -514,6 +1636,111 @@ type GithubRepoInfo struct {
 	MirrorUrl                    sql.NullString
 }
 
+// GitHub teams with access to a repo
+type GithubRepoTeam struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// GitHub id of the team
+	TeamID int64
+	// login of the organization the team belongs to
+	Org string
+	// slug of the team, as used in CODEOWNERS (@org/slug)
+	Slug string
+	Name sql.NullString
+	// permission of the team on the repo (pull, triage, push, maintain or admin)
+	Permission sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// members of the GitHub teams with access to a repo
+type GithubRepoTeamMember struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// GitHub id of the team
+	TeamID int64
+	// login of the member
+	Login string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// weekly rollup of the pull request reviews of each reviewer of a GitHub repo
+type GithubReviewerWeeklyLoad struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// login of the reviewer
+	ReviewerLogin string
+	// first day (monday) of the week the reviews were submitted in
+	Week time.Time
+	// number of reviews submitted
+	Reviews int32
+	// number of distinct pull requests reviewed
+	PullRequestsReviewed int32
+	// number of reviews that approved
+	Approvals int32
+	// number of reviews that requested changes
+	ChangesRequested int32
+	// number of comments left with the reviews
+	ReviewComments int32
+	// review depth: average number of comments per review
+	CommentsPerReview pgtype.Numeric
+	// average time from a pull request being opened to the reviewer's first review of it
+	AvgResponseLatency sql.NullInt64
+	// median time from a pull request being opened to the reviewer's first review of it
+	MedianResponseLatency sql.NullInt64
+	// share (0 to 1) of the repo's reviews of the week submitted by the reviewer
+	ShareOfReviews pgtype.Numeric
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// self-hosted GitHub Actions runners available to a repo, registered to the repo or to its organization
+type GithubRunner struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// REPO if the runner is registered to the repo, ORG if to its organization
+	Scope string
+	// login of the owner (user or organization) of the repo
+	Owner string
+	// GitHub id of the runner
+	RunnerID int64
+	// name of the runner
+	Name sql.NullString
+	// operating system of the runner
+	Os sql.NullString
+	// online or offline
+	Status sql.NullString
+	// whether the runner was executing a job
+	Busy sql.NullBool
+	// labels of the runner, that jobs select runners by
+	Labels []string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// status of the self-hosted GitHub Actions runners available to a repo, as observed by each sync
+type GithubRunnerSnapshot struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// REPO if the runner is registered to the repo, ORG if to its organization
+	Scope string
+	// GitHub id of the runner
+	RunnerID int64
+	// timestamp of the sync that observed the runner
+	ObservedAt time.Time
+	// name of the runner
+	Name sql.NullString
+	// online or offline
+	Status sql.NullString
+	// whether the runner was executing a job
+	Busy sql.NullBool
+	// labels of the runner
+	Labels []string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 // stargazers of a GitHub repo
 type GithubStargazer struct {
 	// foreign key for public.repos.id
@@ -657,40 +1884,315 @@ type GrypeRepoVulnerability struct {
 	Path interface{}
 }
 
-type MergestatContainerImage struct {
-	ID          uuid.UUID
-	Name        string
-	Type        string
-	Url         string
-	Version     string
-	Parameters  pgtype.JSONB
-	Description sql.NullString
-	Queue       string
+// incidents of the PagerDuty or Opsgenie services a repo is mapped to
+type Incident struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// incident management tool the incident was imported from: PAGERDUTY or OPSGENIE
+	Provider string
+	// id of the incident in the provider
+	IncidentID string
+	// human readable number of the incident, eg. 1234
+	Number sql.NullString
+	// title of the incident
+	Title sql.NullString
+	// status of the incident: OPEN, ACKNOWLEDGED or RESOLVED (including closed incidents)
+	Status string
+	// urgency (PagerDuty, eg. high) or priority (Opsgenie, eg. P1) of the incident
+	Severity sql.NullString
+	// id of the (first impacted) service of the incident
+	ServiceID sql.NullString
+	// name of the service of the incident, if reported by the provider
+	ServiceName sql.NullString
+	// url of the incident in the provider
+	Url sql.NullString
+	// time the incident was opened
+	CreatedAt time.Time
+	// time the incident was resolved, NULL while it's not
+	ResolvedAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// issue keys referenced by the commits and pull requests of a repo
+type IssueKeyLink struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// where the key was found: COMMIT (message) or PULL_REQUEST (title or body)
+	Source string
+	// hash of the commit referencing the issue (for COMMIT links)
+	CommitHash sql.NullString
+	// number of the pull request referencing the issue (for PULL_REQUEST links)
+	PullRequestNumber sql.NullInt32
+	// the issue key, eg. ABC-123
+	IssueKey string
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// Jira issues referenced in public.issue_key_links (when the JIRA_URL of the Jira instance is configured on the worker)
+type JiraIssue struct {
+	// key of the issue, eg. ABC-123
+	IssueKey string
+	// summary (title) of the issue
+	Summary sql.NullString
+	// name of the status of the issue
+	Status sql.NullString
+	// name of the type of the issue
+	IssueType sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// policy setting the interval of syncs by the activity (latest push) of their repo
+type MergestatAdaptiveSchedule struct {
+	ID bool
+	// whether the policy is applied at all, the adaptive intervals of syncs are cleared when it is disabled
+	Enabled bool
+	// repos pushed to within this period are active
+	ActiveWithin int64
+	// sync interval of the syncs of active repos
+	ActiveInterval int64
+	// sync interval of the syncs of repos neither active nor dormant (and of repos whose activity is unknown)
+	DefaultInterval int64
+	// repos not pushed to for this long are dormant
+	DormantAfter int64
+	// sync interval of the syncs of dormant repos
+	DormantInterval int64
+	// sync types that detect pushes (eg. GITHUB_REPO_METADATA, which syncs pushed_at), never scheduled less often than default_interval
+	ProbeSyncTypes []string
+	// the policy is applied at most this often, rather than on every tick of the scheduler, as the activity of repos changes slowly
+	ApplyEvery int64
+	// timestamp when the policy was last applied
+	LastAppliedAt sql.NullTime
+}
+
+// authors whose data was erased (right-to-erasure requests), also applied by every sync to the rows it writes
+type MergestatAuthorErasure struct {
+	ID int32
+	// email of the author, hashed with mergestat.privacy_hash (the email itself is not retained)
+	EmailHash sql.NullString
+	// login (eg. on GitHub) of the author, hashed with mergestat.privacy_hash
+	LoginHash sql.NullString
+	// PSEUDONYMIZE (replace the identity, and the name next to it, with a stable pseudonym) or DELETE (delete the rows)
+	Mode string
+	// number of rows pseudonymized or deleted by the erasure
+	RowsAffected int64
+	// timestamp when the erasure was applied
+	ErasedAt time.Time
+}
+
+type MergestatContainerImage struct {
+	ID          uuid.UUID
+	Name        string
+	Type        string
+	Url         string
+	Version     string
+	Parameters  pgtype.JSONB
+	Description sql.NullString
+	Queue       string
+}
+
+type MergestatContainerImageType struct {
+	Name        string
+	DisplayName string
+	Description sql.NullString
+}
+
+type MergestatContainerSync struct {
+	ID         uuid.UUID
+	RepoID     uuid.UUID
+	ImageID    uuid.UUID
+	Parameters pgtype.JSONB
+}
+
+type MergestatContainerSyncExecution struct {
+	SyncID    uuid.UUID
+	JobID     uuid.UUID
+	CreatedAt sql.NullTime
+}
+
+type MergestatContainerSyncSchedule struct {
+	ID        uuid.UUID
+	SyncID    uuid.UUID
+	CreatedAt sql.NullTime
+}
+
+// retention policies for rows of synced tables, enforced periodically by mergestat.enforce_data_retention()
+type MergestatDataRetentionPolicy struct {
+	ID uuid.UUID
+	// name of the table (in the public schema) the policy applies to
+	TableName string
+	// repo the policy applies to, NULL for the default policy of the table (a per-repo policy takes precedence over the default)
+	RepoID uuid.NullUUID
+	// column of the table that determines the age of a row
+	TimestampColumn string
+	// rows older than this are removed, NULL to keep rows forever
+	MaxAge sql.NullInt64
+	// if false the policy is not enforced, a disabled per-repo policy keeps all rows of the repo
+	Enabled   bool
+	CreatedAt time.Time
+}
+
+// coordination of dbt with the syncs: the dbt source written, and the dbt Cloud job run, once a sync cycle completes
+type MergestatDbtIntegration struct {
+	ID bool
+	// whether the integration runs at all
+	Enabled bool
+	// name of the dbt source declaring the synced tables, as used in source() by dbt models
+	SourceName string
+	// dbt warns about a synced table when its rows were all synced longer ago than this (freshness)
+	WarnAfter int64
+	// dbt errors about a synced table when its rows were all synced longer ago than this (freshness)
+	ErrorAfter int64
+	// base url of the dbt Cloud API
+	CloudUrl string
+	// id of the dbt Cloud account of the job
+	CloudAccountID sql.NullInt64
+	// id of the dbt Cloud job run after each sync cycle, NULL to not run any
+	CloudJobID sql.NullInt64
+	// timestamp when the last sync cycle completed
+	LastCycleAt sql.NullTime
+	// id of the dbt Cloud run triggered after the last sync cycle
+	LastRunID sql.NullInt64
+	// error of the integration after the last sync cycle, NULL if it succeeded
+	LastError sql.NullString
+}
+
+// repos flagged as dormant by the lifecycle policy
+type MergestatDormantRepo struct {
+	RepoID uuid.UUID
+	// ARCHIVED if the repo is archived upstream, INACTIVE if it had no commits for the inactive_after period of the policy
+	Reason string
+	// timestamp of the latest commit of the repo, NULL if it has no synced commits
+	LastCommitAt sql.NullTime
+	// timestamp when the repo was flagged as dormant
+	FlaggedAt time.Time
+	// timestamp when the syncs of the repo were downgraded, NULL if they were not
+	DowngradedAt sql.NullTime
+}
+
+// text columns of the public tables the worker encrypts at rest (AES-256-GCM, with the key in COLUMN_ENCRYPTION_KEY) as rows are synced
+type MergestatEncryptedColumn struct {
+	// name of the table, in the public schema
+	TableName string
+	// name of the (text) column, whose values are stored prefixed with enc:v1:, identity columns (email, login, *_email, *_login) can't be encrypted
+	ColumnName string
+	// timestamp when the column was configured to be encrypted, rows synced before are encrypted by `worker encrypt`
+	CreatedAt time.Time
+}
+
+// file metadata for explore experience
+type MergestatExploreFileMetadatum struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path to the file
+	Path string
+	// hash based reference to last commit
+	LastCommitHash sql.NullString
+	// message of the commit
+	LastCommitMessage sql.NullString
+	// name of the author of the the modification
+	LastCommitAuthorName sql.NullString
+	// email of the author of the modification
+	LastCommitAuthorEmail sql.NullString
+	// timestamp of when the modifcation was authored
+	LastCommitAuthorWhen sql.NullTime
+	// name of the author who committed the modification
+	LastCommitCommitterName sql.NullString
+	// email of the author who committed the modification
+	LastCommitCommitterEmail sql.NullString
+	// timestamp of when the commit was made
+	LastCommitCommitterWhen sql.NullTime
+	// the number of parents of the commit
+	LastCommitParents sql.NullInt32
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// repo metadata for explore experience
+type MergestatExploreRepoMetadatum struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// hash based reference to last commit
+	LastCommitHash sql.NullString
+	// message of the commit
+	LastCommitMessage sql.NullString
+	// name of the author of the the modification
+	LastCommitAuthorName sql.NullString
+	// email of the author of the modification
+	LastCommitAuthorEmail sql.NullString
+	// timestamp of when the modifcation was authored
+	LastCommitAuthorWhen sql.NullTime
+	// name of the author who committed the modification
+	LastCommitCommitterName sql.NullString
+	// email of the author who committed the modification
+	LastCommitCommitterEmail sql.NullString
+	// timestamp of when the commit was made
+	LastCommitCommitterWhen sql.NullTime
+	// the number of parents of the commit
+	LastCommitParents sql.NullInt32
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
 }
 
-type MergestatContainerImageType struct {
-	Name        string
-	DisplayName string
-	Description sql.NullString
+// computed columns of the synced tables, added (or dropped) by the workers after migrations: plain columns kept up to date by a trigger, and backfilled in batches
+type MergestatGeneratedColumn struct {
+	// name of the synced table the column is added to
+	TableName string
+	// name of the generated column
+	ColumnName string
+	// type of the generated column, eg. TIMESTAMP
+	Type string
+	// expression the column is generated with, of the other columns of the row, it must be immutable (eg. date_trunc('month', author_when AT TIME ZONE 'UTC'))
+	Expression string
+	// if true the column is added, if false it is dropped (the table is only locked briefly, the rows are then backfilled in batches of pages)
+	Enabled bool
+	// definition (type and expression) of the column as added and backfilled by a worker, null if it is not added
+	AppliedDefinition sql.NullString
+	// timestamp when the column was last added (or dropped)
+	AppliedAt sql.NullTime
+	// error of the last attempt to add (or drop) the column, if it failed
+	LastError sql.NullString
+	// timestamp when the generated column was configured
+	CreatedAt time.Time
 }
 
-type MergestatContainerSync struct {
-	ID         uuid.UUID
-	RepoID     uuid.UUID
-	ImageID    uuid.UUID
-	Parameters pgtype.JSONB
+// position up to which the audit log of each organization was synced
+type MergestatGithubAuditLogCheckpoint struct {
+	// login of the organization
+	Org string
+	// timestamp of the latest synced event, the next sync resumes from it
+	LastEventAt time.Time
+	// timestamp when the checkpoint last moved
+	UpdatedAt time.Time
 }
 
-type MergestatContainerSyncExecution struct {
-	SyncID    uuid.UUID
-	JobID     uuid.UUID
-	CreatedAt sql.NullTime
+// state of the polling of the GitHub Events API, by owner of synced repos (see mergestat.github_event_sync_types)
+type MergestatGithubEventPoll struct {
+	Provider uuid.UUID
+	// organization whose events are polled
+	Owner string
+	// etag of the latest response, requests with it do not count against the rate limit when there are no new events
+	Etag sql.NullString
+	// id of the latest event handled
+	LastEventID sql.NullInt64
+	// seconds between polls, as requested by GitHub (X-Poll-Interval)
+	PollInterval int32
+	// timestamp of the latest poll
+	PolledAt sql.NullTime
+	// number of events handled
+	EventsSeen int64
+	// error of the latest poll, NULL if it succeeded
+	LastError sql.NullString
 }
 
-type MergestatContainerSyncSchedule struct {
-	ID        uuid.UUID
-	SyncID    uuid.UUID
-	CreatedAt sql.NullTime
+// syncs enqueued (with a high priority) when an event of the type is seen on a repo
+type MergestatGithubEventSyncType struct {
+	// type of the event, eg. PushEvent (see https://docs.github.com/en/rest/using-the-rest-api/github-event-types)
+	EventType string
+	// type of the sync to enqueue, if enabled for the repo
+	SyncType string
 }
 
 type MergestatLatestRepoSync struct {
@@ -702,6 +2204,86 @@ type MergestatLatestRepoSync struct {
 	DoneAt     sql.NullTime
 }
 
+type MergestatLatestRepoSyncBatch struct {
+	RepoSyncQueueID int64
+	RepoSyncID      uuid.UUID
+	RepoID          uuid.UUID
+	SyncType        string
+	HandlerVersion  int32
+	CodeVersion     string
+	CreatedAt       time.Time
+}
+
+// switch pausing all the workers (no job is dequeued nor enqueued while it is enabled), eg. for a maintenance window of the database
+type MergestatMaintenanceMode struct {
+	ID bool
+	// whether the maintenance mode is enabled
+	Enabled bool
+	// why the maintenance mode is enabled, reported by the health endpoints of the workers
+	Reason sql.NullString
+	// timestamp when the maintenance mode was enabled
+	EnabledAt sql.NullTime
+	// database user who enabled the maintenance mode
+	EnabledBy sql.NullString
+}
+
+type MergestatMaintenanceStatus struct {
+	Enabled     bool
+	Reason      sql.NullString
+	EnabledAt   sql.NullTime
+	EnabledBy   sql.NullString
+	RunningJobs int64
+	Drained     interface{}
+}
+
+// notifications raised by syncs, relayed by the worker to the webhook at NOTIFICATIONS_WEBHOOK_URL (if set) or by integrations listening on the mergestat_notifications channel
+type MergestatNotification struct {
+	ID int64
+	// kind of the notification, eg. CI_DURATION_REGRESSION
+	Kind string
+	// repo the notification is about, if any
+	RepoID uuid.NullUUID
+	// identifies the notification among the ones of its kind and repo, so that it is only raised once
+	Key string
+	// human readable summary of the notification
+	Subject string
+	// details of the notification
+	Payload   pgtype.JSONB
+	CreatedAt time.Time
+	// timestamp when the notification was acknowledged (eg. relayed), NULL if it was not yet
+	AcknowledgedAt sql.NullTime
+}
+
+// synced tables owned by mergestat (created in public by migrations), other tables of the database are never moved nor rewritten
+type MergestatOwnedTable struct {
+	// name of the table, as created by the migrations (and referenced by syncs)
+	TableName string
+}
+
+// columns of the synced tables owned by mergestat, in the schema (and under the name) the tables live
+type MergestatOwnedTableColumn struct {
+	TableName    string
+	TableSchema  string
+	PhysicalName string
+	ColumnName   string
+	DataType     string
+	UdtName      string
+}
+
+// deployment wide data minimization settings, applied by every sync as rows are written (a single row)
+type MergestatPrivacySetting struct {
+	ID bool
+	// KEEP, HASH (replace with a salted sha256 hash, prefixed with sha256:) or DROP (replace with an empty string) email columns
+	AuthorEmails string
+	// KEEP or DROP (keep only the subject line of) message columns
+	CommitMessageBodies string
+	// KEEP or DROP (replace with null) contents columns, and empty patch columns
+	FileContents string
+	// salt of hashed emails, so that hashes are consistent across tables but not reversible with a dictionary of known emails
+	HashSalt  string
+	UpdatedAt time.Time
+}
+
 type MergestatProvider struct {
 	ID          uuid.UUID
 	Name        string
@@ -711,6 +2293,31 @@ type MergestatProvider struct {
 	Description sql.NullString
 }
 
+// api call budgets of providers, api-backed syncs are deferred while the budget of their provider is exhausted
+type MergestatProviderApiBudget struct {
+	// provider (and so credential) the budget applies to
+	ProviderID uuid.UUID
+	// maximum number of api calls the workers make per (clock) hour, NULL for no maximum
+	MaxCallsPerHour sql.NullInt32
+	// api calls of the rate limit of the credential left for other consumers: syncs are deferred while the remaining rate limit (as last reported by the api) is below this, until the rate limit resets
+	ReservedRemaining int32
+	CreatedAt         time.Time
+}
+
+// api calls made by the workers with the credential of each provider, by hour
+type MergestatProviderApiUsage struct {
+	ProviderID uuid.UUID
+	// start of the (clock) hour the calls were made in
+	Hour time.Time
+	// number of api calls made
+	Calls int64
+	// remaining rate limit of the credential, as last reported by the api
+	LastRemaining sql.NullInt32
+	// when the rate limit of the credential resets, as last reported by the api
+	RateLimitResetAt sql.NullTime
+	UpdatedAt        time.Time
+}
+
 type MergestatQueryHistory struct {
 	ID    uuid.UUID
 	RunAt sql.NullTime
@@ -718,6 +2325,89 @@ type MergestatQueryHistory struct {
 	Query string
 }
 
+// query packs (libraries of views, eg. DORA metrics) shipped with the worker, and their installation in the database
+type MergestatQueryPack struct {
+	// name of the pack, installed in the pack_<name> schema
+	Name string
+	// description of the pack
+	Description sql.NullString
+	// whether the pack is installed, its schema (and everything depending on it) is dropped when it is disabled
+	Enabled bool
+	// installed version of the pack (NULL when not installed), the pack is reinstalled when a worker ships a newer version
+	Version sql.NullInt32
+	// interval the views of the pack are refreshed into snapshot tables (<view>_snapshot) at, NULL to never refresh them
+	RefreshInterval sql.NullInt64
+	// timestamp when the installed version of the pack was installed
+	InstalledAt sql.NullTime
+	// timestamp when the snapshot tables of the pack were last refreshed
+	LastRefreshedAt sql.NullTime
+	// error of the last install or refresh of the pack, NULL if it succeeded
+	LastError sql.NullString
+}
+
+type MergestatQueueCycleEtum struct {
+	TypeGroup       string
+	Queued          int64
+	Running         int64
+	EstimatedDoneAt interface{}
+}
+
+type MergestatQueueEtum struct {
+	JobID              int64
+	RepoSyncID         uuid.UUID
+	Status             string
+	TypeGroup          string
+	EstimatedRemaining interface{}
+	EstimatedStartAt   interface{}
+	EstimatedDoneAt    interface{}
+}
+
+type MergestatRepoActivity struct {
+	RepoID       uuid.UUID
+	LastPushedAt interface{}
+}
+
+type MergestatRepoDataTable struct {
+	TableSchema  string
+	TableName    string
+	ColumnName   string
+	IsForeignKey bool
+}
+
+// groups of repos (eg. the repos of a project or platform), whose syncs are scheduled together
+type MergestatRepoGroup struct {
+	ID uuid.UUID
+	// unique name of the group
+	Name        string
+	Description sql.NullString
+	// if set, every repo with this tag is a member of the group (in addition to explicit members)
+	Tag sql.NullString
+	// if set, the enabled syncs of the repos in the group are enqueued on this interval (eg. 1 hour)
+	SyncInterval sql.NullInt64
+	// timestamp of when the syncs of the group were last enqueued
+	LastEnqueuedAt sql.NullTime
+	CreatedAt      time.Time
+}
+
+// explicit membership of repos in groups
+type MergestatRepoGroupMember struct {
+	GroupID uuid.UUID
+	RepoID  uuid.UUID
+}
+
+type MergestatRepoGroupRepo struct {
+	GroupID uuid.UUID
+	RepoID  uuid.UUID
+}
+
+type MergestatRepoIdentity struct {
+	RepoID          uuid.UUID
+	CanonicalRepoID uuid.UUID
+	// relation of the repo to its canonical repo (MIRROR or PREDECESSOR), NULL for canonical (and unlinked) repos
+	Relation   sql.NullString
+	MigratedAt sql.NullTime
+}
+
 // Table for "dynamic" repo imports - regularly loading from a GitHub org for example
 type MergestatRepoImport struct {
 	ID                  uuid.UUID
@@ -738,14 +2428,110 @@ type MergestatRepoImportType struct {
 	Description string
 }
 
+// policy for flagging dormant repos (and downgrading the schedule of their syncs)
+type MergestatRepoLifecyclePolicy struct {
+	ID bool
+	// whether the policy is applied at all
+	Enabled bool
+	// repos without commits for this long are dormant
+	InactiveAfter int64
+	// whether repos archived upstream (see github_repo_info.is_archived) are dormant
+	IncludeArchived bool
+	// whether the syncs of dormant repos are downgraded to downgraded_sync_interval (and restored once they are active again)
+	AutoDowngrade bool
+	// sync interval of the syncs of dormant repos
+	DowngradedSyncInterval int64
+	// timestamp when the policy was last applied
+	LastAppliedAt sql.NullTime
+}
+
+// repos that are the same logical project as another (canonical) repo, eg. on another provider
+type MergestatRepoLink struct {
+	// foreign key for public.repos.id of the linked repo
+	RepoID uuid.UUID
+	// foreign key for public.repos.id of the repo the project is identified by (itself never linked to another)
+	CanonicalRepoID uuid.UUID
+	// MIRROR (a copy of the canonical repo, eg. on GitLab) or PREDECESSOR (where the project lived before it was migrated to the canonical repo)
+	Relation string
+	// for a PREDECESSOR, when the project was migrated to the canonical repo: the data of the predecessor is used before, that of the canonical repo after
+	MigratedAt sql.NullTime
+	// timestamp when the link was declared
+	CreatedAt time.Time
+}
+
+// rules REPO_POLICIES syncs check repos against
+type MergestatRepoPolicyRule struct {
+	// name of the rule, eg. has-license
+	Name string
+	// description of the rule
+	Description sql.NullString
+	// FILE_EXISTS (a file matching pattern exists), FILE_ABSENT (no file matches pattern), BRANCH_NAMES (every branch name matches pattern) or ACTIONS_PINNED (every workflow action matching pattern is pinned to a commit sha, requires CI_INVENTORY)
+	Kind string
+	// regular expression matched against file paths or branch names
+	Pattern string
+	// only enabled rules are checked
+	Enabled   bool
+	CreatedAt time.Time
+	// severity of a failure of the rule, LOW, MEDIUM, HIGH or CRITICAL
+	Severity string
+}
+
+// repos purged, with every row produced for them, by `worker purge`
+type MergestatRepoPurge struct {
+	// id the purged repo had (it no longer references public.repos)
+	RepoID uuid.UUID
+	// url of the purged repo
+	Repo string
+	// number of rows deleted across all the tables of mergestat.repo_data_tables, and sync logs
+	RowsDeleted int64
+	// timestamp when the purge completed
+	PurgedAt time.Time
+}
+
+// measured sizes of repos, and the size tier their syncs are run with
+type MergestatRepoSize struct {
+	RepoID uuid.UUID
+	// size (in bytes) of the last clone of the repo
+	CloneBytes int64
+	// size tier of the repo (small, medium, large or huge), which sets how it's cloned, its batch sizes and timeouts. The sizeTier repo setting overrides it
+	Tier string
+	// timestamp of when the clone of the repo was measured
+	MeasuredAt time.Time
+}
+
 type MergestatRepoSync struct {
-	RepoID                       uuid.UUID
-	SyncType                     string
+	RepoID   uuid.UUID
+	SyncType string
+	// JSON settings for the repo sync, set {"dryRun": true} to write into mergestat_staging instead of public
 	Settings                     pgtype.JSONB
 	ID                           uuid.UUID
 	ScheduleEnabled              bool
 	Priority                     int32
 	LastCompletedRepoSyncQueueID sql.NullInt64
+	// checksum (HEAD sha or provider content hash) of the source at the last successful sync, with a hash of its settings
+	LastCompletedChecksum sql.NullString
+	// if set, the scheduler enqueues the sync at most once per interval (eg. 1 month), otherwise as soon as the previous run completes
+	SyncInterval sql.NullInt64
+	// interval set by the adaptive schedule (see mergestat.apply_adaptive_schedule), used when sync_interval is not set
+	AdaptiveSyncInterval sql.NullInt64
+}
+
+// provenance of the rows written by each successful sync job
+type MergestatRepoSyncBatch struct {
+	// id of the job in mergestat.repo_sync_queue (jobs are eventually removed from the queue, batches are kept)
+	RepoSyncQueueID int64
+	// foreign key for mergestat.repo_syncs.id
+	RepoSyncID uuid.UUID
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// type of the sync
+	SyncType string
+	// version of the sync handler (see mergestat.repo_sync_versions)
+	HandlerVersion int32
+	// version of the worker code, the vcs revision the worker was built from (suffixed with -dirty if modified) or unknown
+	CodeVersion string
+	// timestamp when the batch was written
+	CreatedAt time.Time
 }
 
 type MergestatRepoSyncLog struct {
@@ -754,6 +2540,8 @@ type MergestatRepoSyncLog struct {
 	LogType         string
 	Message         string
 	RepoSyncQueueID int64
+	// structured fields of the log (eg. table, rows, path, error, error_code, duration_ms), NULL if it has none
+	Details pgtype.JSONB
 }
 
 type MergestatRepoSyncLogType struct {
@@ -771,6 +2559,37 @@ type MergestatRepoSyncQueue struct {
 	LastKeepAlive sql.NullTime
 	Priority      int32
 	TypeGroup     string
+	// settings of the job, overriding the settings of its repo sync for this run only (eg. the blameRef of a GIT_BLAME sync)
+	Parameters pgtype.JSONB
+}
+
+// archive of repo sync jobs (and their logs) removed by mergestat.repo_sync_queue_cleanup()
+type MergestatRepoSyncQueueArchive struct {
+	// id of the job in mergestat.repo_sync_queue
+	ID         int64
+	CreatedAt  time.Time
+	RepoSyncID uuid.UUID
+	Status     string
+	StartedAt  sql.NullTime
+	DoneAt     sql.NullTime
+	Priority   int32
+	TypeGroup  string
+	// outcome of the job: SUCCESS or ERROR
+	Outcome string
+	// array of the job logs, each with created_at, log_type and message
+	Logs pgtype.JSONB
+	// timestamp of when the job was archived
+	ArchivedAt time.Time
+}
+
+// retention policies for finished repo sync jobs (and their logs), by outcome
+type MergestatRepoSyncQueueRetentionPolicy struct {
+	// outcome of the job the policy applies to: SUCCESS or ERROR (a job with at least one ERROR log)
+	Outcome string
+	// number of days to keep a finished job for
+	RetentionDays int32
+	// if true, jobs (and their logs) are copied into mergestat.repo_sync_queue_archive before they are deleted
+	Archive bool
 }
 
 type MergestatRepoSyncQueueStatusType struct {
@@ -784,11 +2603,27 @@ type MergestatRepoSyncType struct {
 	ShortName   string
 	Priority    int32
 	TypeGroup   string
+	// if true, the tables written by the sync are analyzed after a successful sync
+	AnalyzeAfterSync bool
+	// if true, the indexes of the tables written by the sync are rebuilt after a successful sync
+	ReindexAfterSync bool
+	// whether syncs of the type call the api of the repo's provider (and so are subject to its api budget)
+	UsesProviderApi bool
+	// cost of a sync of the type on the worker running it, workers cap the total weight of the syncs they run at once (MAX_WEIGHT)
+	Weight int32
 }
 
 type MergestatRepoSyncTypeGroup struct {
 	Group           sql.NullString
 	ConcurrentSyncs sql.NullInt32
+	// if true, the slots of the group can be used by other groups when it has no queued syncs
+	LendIdleSlots bool
+	// maximum number of syncs of the group that can run in slots borrowed from idle groups (0 to never borrow)
+	MaxBorrowedSyncs int32
+	// queued syncs of the group gain one level of priority for every interval they wait, NULL to never age
+	PriorityAgingInterval sql.NullInt64
+	// maximum number of levels of priority queued syncs of the group gain by waiting (0 to never age)
+	PriorityAgingMaxBoost int32
 }
 
 // @name labels
@@ -804,6 +2639,31 @@ type MergestatRepoSyncTypeLabelAssociation struct {
 	RepoSyncType string
 }
 
+// version of the sync handler that performed the last successful sync of each repo sync
+type MergestatRepoSyncVersion struct {
+	// foreign key for mergestat.repo_syncs.id
+	RepoSyncID uuid.UUID
+	// version of the handler, repo syncs without a recorded version were performed by version 1
+	Version int32
+	// timestamp of the last successful sync
+	UpdatedAt time.Time
+}
+
+// Table to save explores
+type MergestatSavedExplore struct {
+	ID uuid.UUID
+	// explore creator
+	CreatedBy sql.NullString
+	// timestamp when explore was created
+	CreatedAt sql.NullTime
+	// explore name
+	Name sql.NullString
+	// explore description
+	Description sql.NullString
+	// explore metadata
+	Metadata pgtype.JSONB
+}
+
 // Table to save queries
 type MergestatSavedQuery struct {
 	ID uuid.UUID
@@ -833,6 +2693,28 @@ type MergestatSchemaIntrospection struct {
 	ColumnDescription string
 }
 
+// search indexes of text columns of the synced tables, created (or dropped) by the workers once a sync writing into the table completes
+type MergestatSearchIndex struct {
+	// name of the table (of the public schema) the column belongs to
+	TableName string
+	// name of the text column indexed
+	ColumnName string
+	// tsvector for a GIN index over to_tsvector(config, column), used by full-text search (@@), or trgm for a GIN index with pg_trgm operators, used by LIKE, ILIKE and regular expressions
+	Method string
+	// text search configuration of tsvector indexes, queries must use the same one to use the index, eg. to_tsvector('simple', message) @@ websearch_to_tsquery('simple', 'fix race')
+	Config interface{}
+	// if true the index is created, if false it is dropped
+	Enabled bool
+	// name of the index, once created
+	IndexName sql.NullString
+	// timestamp when the index was last created (or dropped)
+	IndexedAt sql.NullTime
+	// error of the last attempt to create (or drop) the index, if it failed
+	LastError sql.NullString
+	// timestamp when the search index was configured
+	CreatedAt time.Time
+}
+
 type MergestatServiceAuthCredential struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
@@ -849,6 +2731,119 @@ type MergestatServiceAuthCredentialType struct {
 	Description string
 }
 
+// WASM (WASI) modules that transform or filter the rows of a table before a sync writes them
+type MergestatSyncTransform struct {
+	ID uuid.UUID
+	// name of the transform, eg. redact-emails
+	Name string
+	// type of the syncs the transform applies to
+	SyncType string
+	// repo the transform applies to, null for all repos
+	RepoID uuid.NullUUID
+	// table whose rows are transformed, eg. git_commits
+	TableName string
+	// the compiled WASM module, unless it is fetched from module_url
+	Module []byte
+	// url (eg. an object storage url) the WASM module is fetched from, unless it is stored in module
+	ModuleUrl sql.NullString
+	// transforms of the same table are applied in ascending position
+	Position int32
+	// only enabled transforms are applied
+	Enabled   bool
+	CreatedAt time.Time
+}
+
+type MergestatSyncVariable struct {
+	RepoID uuid.UUID
+	Key    interface{}
+	Value  []byte
+}
+
+// synced tables relocated out of public, and where they live (tables not listed are in public, under their own name)
+type MergestatSyncedTable struct {
+	// name of the table, as created by the migrations (and referenced by syncs)
+	TableName string
+	// schema the table was moved into
+	SchemaName string
+	// name of the table in its schema, ie. its name with the configured prefix
+	PhysicalName string
+	// timestamp when the table was moved
+	RelocatedAt time.Time
+}
+
+// extra indexes of the synced tables, created (or dropped) concurrently by the workers after migrations
+type MergestatTableIndex struct {
+	// name of the synced table the index is created on
+	TableName string
+	// name of the index, unique by table (the name of the index itself is derived from it and its definition)
+	Name string
+	// index method: btree, hash, gin, gist or brin
+	Method string
+	// columns (or expressions) indexed, as in CREATE INDEX, eg. repo_id, author_when DESC
+	Columns string
+	// predicate of a partial index, eg. merged_at IS NOT NULL
+	Predicate sql.NullString
+	// if true the index is created, if false it is dropped
+	Enabled bool
+	// name of the index, once created
+	IndexName sql.NullString
+	// timestamp when the index was last created (or dropped)
+	IndexedAt sql.NullTime
+	// error of the last attempt to create (or drop) the index, if it failed
+	LastError sql.NullString
+	// timestamp when the index was configured
+	CreatedAt time.Time
+}
+
+// versions of synced tables populated in parallel with the active version, see mergestat.cut_over_table_version()
+type MergestatTableVersion struct {
+	// name of the table (in the public schema), the version is public.<table_name>_v<version> until it is cut over
+	TableName string
+	// version of the table, tables without an ACTIVE version are at version 1
+	Version int32
+	// sync type writing the table, the version is populated after each of its syncs
+	SyncType string
+	// function (taking the id of a repo and returning the number of rows written) replacing the rows of the repo in the version
+	PopulateFunction string
+	// POPULATING until cut over, ACTIVE once cut over and RETIRED once a newer version is cut over
+	Status    string
+	CreatedAt time.Time
+	// timestamp of the cut over
+	CutOverAt sql.NullTime
+}
+
+// teams repos (and paths within repos) are owned by
+type MergestatTeam struct {
+	ID          uuid.UUID
+	Name        string
+	Description sql.NullString
+	// if set (with github_slug), the GitHub team the team is enriched from
+	GithubOrg sql.NullString
+	// slug of the GitHub team the team is enriched from
+	GithubSlug sql.NullString
+	CreatedAt  time.Time
+}
+
+type MergestatTeamOwnership struct {
+	TeamID  uuid.UUID
+	RepoID  uuid.UUID
+	Pattern sql.NullString
+	Source  interface{}
+}
+
+// paths (CODEOWNERS style patterns) within repos owned by a team
+type MergestatTeamPathPattern struct {
+	TeamID  uuid.UUID
+	RepoID  uuid.UUID
+	Pattern string
+}
+
+// repos explicitly owned by a team
+type MergestatTeamRepo struct {
+	TeamID uuid.UUID
+	RepoID uuid.UUID
+}
+
 type MergestatUserMgmtPgUser struct {
 	Rolname        interface{}
 	Rolsuper       sql.NullBool
@@ -920,6 +2915,60 @@ type OssfScorecardRepoScore struct {
 	ScorecardVersion interface{}
 }
 
+type ProjectGitCommit struct {
+	// id of the canonical repo of the project (see mergestat.repo_links)
+	CanonicalRepoID uuid.UUID
+	// id of the repo the commit was synced from
+	SourceRepoID   uuid.UUID
+	Hash           string
+	Message        sql.NullString
+	AuthorName     sql.NullString
+	AuthorEmail    sql.NullString
+	AuthorWhen     time.Time
+	CommitterName  sql.NullString
+	CommitterEmail sql.NullString
+	CommitterWhen  time.Time
+	Parents        int32
+}
+
+// packages a repo publishes to npm, PyPI or the Go module proxy, as reported by the registry
+type RegistryPackage struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// registry the package is published to, npm, pypi or go
+	Registry string
+	// name of the package (or path of the Go module)
+	Name string
+	// path of the manifest declaring the package, eg. package.json
+	ManifestPath string
+	// version the registry considers the latest
+	LatestVersion sql.NullString
+	// time the most recent version was published
+	LatestReleasedAt sql.NullTime
+	// number of published versions
+	VersionCount int32
+	// number of downloads in the last month, null if the registry does not report it
+	DownloadsLastMonth sql.NullInt64
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// published versions of the packages in registry_packages
+type RegistryPackageVersion struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// registry the package is published to, npm, pypi or go
+	Registry string
+	// name of the package (or path of the Go module)
+	Name string
+	// published version
+	Version string
+	// time the version was published
+	ReleasedAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 // git repositories to track
 type Repo struct {
 	// MergeStat identifier for the repo
@@ -930,7 +2979,7 @@ type Repo struct {
 	Ref sql.NullString
 	// timestamp of when the MergeStat repo entry was created
 	CreatedAt time.Time
-	// JSON settings for the repo
+	// JSON settings for the repo. Set exclusiveSyncs to true to never run more than one sync (of any type) at a time for the repo, so that it is only cloned once at a time
 	Settings pgtype.JSONB
 	// array of tags for the repo for topics in GitHub as well as tags added in MergeStat
 	Tags pgtype.JSONB
@@ -939,6 +2988,51 @@ type Repo struct {
 	Provider     uuid.UUID
 }
 
+// result of checking a repo against each enabled rule in mergestat.repo_policy_rules
+type RepoPolicyResult struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// name of the rule, see mergestat.repo_policy_rules
+	Rule string
+	// true if the repo complies with the rule
+	Passed bool
+	// what failed the rule, eg. the offending file paths or branch names
+	Details sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+	// severity of the rule, see mergestat.repo_policy_rules.severity
+	Severity sql.NullString
+}
+
+type RepoSummariesLatest struct {
+	RepoID       uuid.UUID
+	Repo         string
+	Summary      string
+	Model        string
+	ModelVersion sql.NullString
+	GeneratedAt  time.Time
+}
+
+// summary of a repo generated by an LLM, from its README and recent commits, for portfolio overviews
+type RepoSummary struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// model the summary was requested from, as configured
+	Model string
+	// model (version) that generated the summary, as reported by the endpoint, eg. gpt-4o-mini-2024-07-18
+	ModelVersion sql.NullString
+	// version of the prompt the summary was generated with, summaries are generated again when it changes
+	PromptVersion int32
+	// summary of the repo, generated (and not reviewed), it may be inaccurate
+	Summary string
+	// SHA-256 of the prompt, so that the summary is only generated again when its inputs change
+	InputHash string
+	// timestamp when the summary was generated
+	GeneratedAt time.Time
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
 // MergeStat internal table to track schema migrations
 type SchemaMigration struct {
 	Version int64
@@ -952,6 +3046,96 @@ type SchemaMigrationsHistory struct {
 	AppliedAt time.Time
 }
 
+// open issues reported by SonarQube for the project of a repo
+type SonarqubeIssue struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// key of the SonarQube project the repo is mapped to
+	ProjectKey string
+	// key of the issue in SonarQube
+	IssueKey string
+	// rule that raised the issue, eg. go:S1192
+	Rule string
+	// type of the issue: BUG, VULNERABILITY or CODE_SMELL
+	Type sql.NullString
+	// severity of the issue: BLOCKER, CRITICAL, MAJOR, MINOR or INFO
+	Severity sql.NullString
+	// status of the issue, eg. OPEN, CONFIRMED or REOPENED
+	Status sql.NullString
+	// path of the file of the issue in the repo, NULL for issues of the project
+	Path sql.NullString
+	// line of the issue in the file
+	Line sql.NullInt32
+	// description of the issue
+	Message sql.NullString
+	// estimated effort to fix the issue, eg. 10min
+	Effort sql.NullString
+	// tags of the issue
+	Tags []string
+	// time the issue was first reported
+	CreatedAt sql.NullTime
+	// time the issue was last updated
+	UpdatedAt sql.NullTime
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// metrics of the SonarQube project of a repo (eg. coverage, bugs or ncloc), one set of rows per SONARQUBE sync (kept for trending)
+type SonarqubeMeasure struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// time the metrics were imported, shared by all the rows of a sync
+	ComputedAt time.Time
+	// key of the SonarQube project the repo is mapped to
+	ProjectKey string
+	// key of the metric, eg. coverage
+	Metric string
+	// value of the metric, as reported by SonarQube (numeric for most metrics, eg. 81.5, or a rating, eg. 1.0 for A)
+	Value sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type SonarqubeMeasuresLatest struct {
+	RepoID            uuid.UUID
+	ComputedAt        time.Time
+	ProjectKey        string
+	Metric            string
+	Value             sql.NullString
+	MergestatSyncedAt time.Time
+}
+
+// quality gate status of the SonarQube project of a repo, one row per SONARQUBE sync (kept for trending)
+type SonarqubeQualityGate struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// time the status was imported
+	ComputedAt time.Time
+	// key of the SonarQube project the repo is mapped to
+	ProjectKey string
+	// status of the quality gate: OK, WARN, ERROR or NONE (no gate or no analysis)
+	Status string
+	// conditions of the quality gate, with their thresholds, actual values and statuses
+	Conditions pgtype.JSONB
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type StaleBranch struct {
+	RepoID                uuid.UUID
+	Name                  string
+	Hash                  string
+	IsDefault             bool
+	FirstCommitAt         sql.NullTime
+	LastCommitAt          sql.NullTime
+	LastCommitAuthorEmail sql.NullString
+	Ahead                 int32
+	Behind                int32
+	Merged                bool
+	MergestatSyncedAt     time.Time
+	DaysInactive          int32
+}
+
 type SyftRepoArtifact struct {
 	// foreign key for public.repos.id
 	RepoID uuid.UUID
@@ -989,6 +3173,79 @@ type SyftRepoScan struct {
 	MergestatSyncedAt time.Time
 }
 
+// module calls of the Terraform files of a repo
+type TerraformModule struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the .tf file
+	Path string
+	// line of the file the module block starts at
+	Line int32
+	// name of the module call
+	Name string
+	// source of the module, eg. terraform-aws-modules/vpc/aws or ./modules/network
+	Source sql.NullString
+	// version constraint of the module, if any
+	Version sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// provider requirements of the Terraform files of a repo
+type TerraformProvider struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// path of the .tf file
+	Path string
+	// line of the file the requirement is declared at
+	Line int32
+	// local name of the provider, eg. aws
+	Name string
+	// source address of the provider, eg. hashicorp/aws
+	Source sql.NullString
+	// version constraint of the provider, if any
+	Version sql.NullString
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+// number of test and source files (and their lines) of a repo and its directories, one set of rows per TEST_RATIOS sync (kept for trending)
+type TestRatio struct {
+	// foreign key for public.repos.id
+	RepoID uuid.UUID
+	// time the ratios were computed, shared by all the rows of a sync
+	ComputedAt time.Time
+	// directory the ratios cover, empty for the whole repo
+	Directory string
+	// number of source files that are not tests
+	SourceFiles int32
+	// number of lines of the source files that are not tests
+	SourceLines int64
+	// number of test files, by the naming conventions of their language (eg. _test.go, *.spec.ts, test_*.py) or in a test directory (eg. tests/)
+	TestFiles int32
+	// number of lines of the test files
+	TestLines int64
+	// test files per source file, NULL without source files
+	FileRatio sql.NullFloat64
+	// test lines per source line, NULL without source lines
+	LineRatio sql.NullFloat64
+	// timestamp when record was synced into the MergeStat database
+	MergestatSyncedAt time.Time
+}
+
+type TestRatiosLatest struct {
+	RepoID            uuid.UUID
+	ComputedAt        time.Time
+	Directory         string
+	SourceFiles       int32
+	SourceLines       int64
+	TestFiles         int32
+	TestLines         int64
+	FileRatio         sql.NullFloat64
+	LineRatio         sql.NullFloat64
+	MergestatSyncedAt time.Time
+}
+
 // Trivy repo scans
 type TrivyRepoScan struct {
 	// foreign key for public.repos.id
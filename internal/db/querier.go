@@ -11,32 +11,51 @@ import (
 )
 
 type Querier interface {
+	// returns -1 if the adaptive schedule is disabled (or being applied by another worker)
+	ApplyAdaptiveSchedule(ctx context.Context) (int32, error)
+	// returns -1 if the lifecycle policy is disabled (or being applied by another worker)
+	ApplyRepoLifecyclePolicy(ctx context.Context) (int32, error)
+	// enqueues a full re-sync of the repo syncs of the type last synced by an older handler version (or all of them, if version is null)
+	BackfillRepoSyncs(ctx context.Context, arg BackfillRepoSyncsParams) (int32, error)
 	CheckRunningImps(ctx context.Context) (int64, error)
 	CleanOldJobs(ctx context.Context, dollar_1 int32) error
 	CleanOldRepoSyncQueue(ctx context.Context, dollar_1 int32) error
+	// returns -1 if another worker is already running the cleanup
+	CleanRepoSyncQueueByPolicy(ctx context.Context) (int32, error)
 	DeleteGitHubRepoInfo(ctx context.Context, repoID uuid.UUID) error
 	DeleteRemovedRepos(ctx context.Context, arg DeleteRemovedReposParams) error
-	DequeueSyncJob(ctx context.Context) (DequeueSyncJobRow, error)
+	DequeueSyncJob(ctx context.Context, maxWeight int32) (DequeueSyncJobRow, error)
 	EnableContainerSync(ctx context.Context, arg EnableContainerSyncParams) error
+	// returns -1 if another worker is already enforcing the retention policies
+	EnforceDataRetention(ctx context.Context) (int64, error)
 	// We use a CTE here to retrieve all the repo_sync_jobs that were previously enqueued, to make sure that we *do not* re-enqueue anything new until the previously enqueued jobs are *completed*.
 	// This allows us to make sure all repo syncs complete before we reschedule a new batch.
 	// We have now also added a concept of type groups which allows us to apply this same logic but by each group type which is where the PARTITION BY clause comes into play
 	EnqueueAllSyncs(ctx context.Context) error
+	EnqueueRepoGroupSyncs(ctx context.Context) (int32, error)
+	EnqueueRepoSyncOfType(ctx context.Context, arg EnqueueRepoSyncOfTypeParams) error
 	FetchContainerSync(ctx context.Context, id uuid.UUID) (FetchContainerSyncRow, error)
 	FetchGitHubToken(ctx context.Context, pgpSymDecrypt string) (string, error)
 	FetchImportJob(ctx context.Context, id uuid.UUID) (FetchImportJobRow, error)
+	GetMaintenanceMode(ctx context.Context) (GetMaintenanceModeRow, error)
 	GetRepoById(ctx context.Context, id uuid.UUID) (Repo, error)
+	GetRepoByURL(ctx context.Context, repo string) (Repo, error)
 	GetRepoIDsFromRepoImport(ctx context.Context, arg GetRepoIDsFromRepoImportParams) ([]uuid.UUID, error)
 	GetRepoImportByID(ctx context.Context, id uuid.UUID) (MergestatRepoImport, error)
 	GetRepoUrlFromImport(ctx context.Context, importid uuid.UUID) ([]string, error)
+	GetSyncTypeMaintenance(ctx context.Context, synctype string) (GetSyncTypeMaintenanceRow, error)
 	InsertGitHubRepoInfo(ctx context.Context, arg InsertGitHubRepoInfoParams) error
 	InsertNewDefaultSync(ctx context.Context, arg InsertNewDefaultSyncParams) error
+	InsertSyncBatch(ctx context.Context, arg InsertSyncBatchParams) error
 	InsertSyncJobLog(ctx context.Context, arg InsertSyncJobLogParams) error
 	ListRepoImportsDueForImport(ctx context.Context) ([]ListRepoImportsDueForImportRow, error)
 	MarkRepoImportAsUpdated(ctx context.Context, id uuid.UUID) error
 	MarkSyncsAsTimedOut(ctx context.Context) ([]int64, error)
+	RecordAPIUsage(ctx context.Context, arg RecordAPIUsageParams) error
 	SetLatestKeepAliveForJob(ctx context.Context, id int64) error
+	SetSyncChecksum(ctx context.Context, arg SetSyncChecksumParams) error
 	SetSyncJobStatus(ctx context.Context, arg SetSyncJobStatusParams) error
+	SetSyncVersion(ctx context.Context, arg SetSyncVersionParams) error
 	UpdateImportStatus(ctx context.Context, arg UpdateImportStatusParams) error
 	UpsertRepo(ctx context.Context, arg UpsertRepoParams) error
 	UpsertWorkflowRunJobs(ctx context.Context, arg UpsertWorkflowRunJobsParams) error
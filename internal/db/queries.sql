@@ -45,33 +45,69 @@ running AS (
         INNER JOIN mergestat.repo_sync_type_groups rstg ON rsq.type_group = rstg.group
         WHERE status = 'RUNNING'
 ),
+idle_slots AS (
+        -- slots of idle groups (without any queued job) that lend them, which are available to other groups
+        SELECT COALESCE(SUM(GREATEST(rstg.concurrent_syncs - (SELECT COUNT(*) FROM running WHERE running.group = rstg.group), 0)), 0) AS available
+        FROM mergestat.repo_sync_type_groups rstg
+        WHERE rstg.lend_idle_slots
+        AND NOT EXISTS (SELECT 1 FROM mergestat.repo_sync_queue rsq WHERE rsq.type_group = rstg.group AND rsq.status = 'QUEUED')
+),
+borrowed AS (
+        -- number of running syncs, by group, that are beyond the group's own concurrency (i.e. using borrowed slots)
+        SELECT rstg.group, GREATEST((SELECT COUNT(*) FROM running WHERE running.group = rstg.group) - rstg.concurrent_syncs, 0) AS count
+        FROM mergestat.repo_sync_type_groups rstg
+),
 dequeued AS (
    UPDATE mergestat.repo_sync_queue SET status = 'RUNNING'
    WHERE id IN (   
         SELECT rsq.id
         FROM mergestat.repo_sync_queue rsq
         INNER JOIN mergestat.repo_sync_type_groups rstg ON rsq.type_group = rstg.group
+        INNER JOIN mergestat.repo_syncs rs ON rsq.repo_sync_id = rs.id
+        INNER JOIN repos r ON rs.repo_id = r.id
         WHERE status = 'QUEUED'
-        AND rstg.concurrent_syncs > (SELECT COUNT(*) FROM running WHERE running.group = rstg.group)
-        ORDER BY rsq.priority ASC, rsq.created_at ASC, rsq.id ASC LIMIT 1 FOR UPDATE SKIP LOCKED
-   ) RETURNING id, created_at, status, repo_sync_id
+        -- nothing is dequeued while the maintenance mode is enabled (see mergestat.maintenance_mode)
+        AND NOT mergestat.maintenance_mode_enabled()
+        AND (
+            rstg.concurrent_syncs > (SELECT COUNT(*) FROM running WHERE running.group = rstg.group)
+            -- or the group may (and can) borrow a slot from an idle group
+            OR (
+                rstg.max_borrowed_syncs > (SELECT count FROM borrowed WHERE borrowed.group = rstg.group)
+                AND (SELECT available FROM idle_slots) > (SELECT SUM(count) FROM borrowed)
+            )
+        )
+        -- repos with the exclusiveSyncs setting only run one sync (of any type) at a time, and dequeues (of any group)
+        -- serialize on them (see mergestat.try_exclusive_repo_sync)
+        AND CASE WHEN COALESCE((r.settings->>'exclusiveSyncs')::BOOLEAN, FALSE) THEN mergestat.try_exclusive_repo_sync(rs.repo_id) ELSE TRUE END
+        -- syncs that call the provider's api are deferred while its api budget is exhausted (see mergestat.provider_api_budgets)
+        AND NOT (
+            EXISTS (SELECT 1 FROM mergestat.repo_sync_types rst WHERE rst.type = rs.sync_type AND rst.uses_provider_api)
+            AND mergestat.provider_api_budget_exhausted(r.provider)
+        )
+        -- the weight of the sync type must fit in the weight the worker has left (see mergestat.repo_sync_types.weight)
+        AND (SELECT rst.weight FROM mergestat.repo_sync_types rst WHERE rst.type = rs.sync_type) <= @max_weight::INTEGER
+        -- jobs gain priority the longer they wait (see mergestat.repo_sync_queue_effective_priority), so that lower priority ones aren't starved
+        ORDER BY mergestat.repo_sync_queue_effective_priority(rsq) ASC, rsq.created_at ASC, rsq.id ASC LIMIT 1 FOR UPDATE OF rsq, rstg SKIP LOCKED
+   ) RETURNING id, created_at, status, repo_sync_id, parameters
 )
 SELECT
     dequeued.*,
     repo_syncs.*,
     repos.repo,
     repos.ref,
-    repos.settings AS repo_settings
+    repos.settings AS repo_settings,
+    repo_sync_types.weight
 FROM dequeued
 JOIN mergestat.repo_syncs ON mergestat.repo_syncs.id = dequeued.repo_sync_id
+JOIN mergestat.repo_sync_types ON mergestat.repo_sync_types.type = mergestat.repo_syncs.sync_type
 JOIN repos ON repos.id = mergestat.repo_syncs.repo_id
 ;
 
 -- name: DeleteGitHubRepoInfo :exec
-DELETE FROM public.github_repo_info WHERE repo_id = $1;
+DELETE FROM github_repo_info WHERE repo_id = $1;
 
 -- name: InsertGitHubRepoInfo :exec
-INSERT INTO public.github_repo_info (
+INSERT INTO github_repo_info (
     repo_id, owner, name,
     created_at, default_branch_name, description, size, fork_count, homepage_url,
     is_archived, is_disabled, mirror_url, is_private, total_issues_count, latest_release_author,
@@ -84,11 +120,34 @@ INSERT INTO public.github_repo_info (
 );
 
 -- name: InsertSyncJobLog :exec
-INSERT INTO mergestat.repo_sync_logs (log_type, message, repo_sync_queue_id) VALUES ($1, $2, $3);
+INSERT INTO mergestat.repo_sync_logs (log_type, message, repo_sync_queue_id, details) VALUES ($1, $2, $3, $4);
 
 -- name: SetSyncJobStatus :exec
 SELECT mergestat.set_sync_job_status(@Status::TEXT, @ID::BIGINT);
 
+-- name: SetSyncChecksum :exec
+UPDATE mergestat.repo_syncs SET last_completed_checksum = @checksum::TEXT WHERE id = @id::UUID;
+
+-- name: SetSyncVersion :exec
+INSERT INTO mergestat.repo_sync_versions (repo_sync_id, version) VALUES (@repoSyncID::UUID, @version::INTEGER)
+ON CONFLICT (repo_sync_id) DO UPDATE SET version = excluded.version, updated_at = now();
+
+-- name: RecordAPIUsage :exec
+INSERT INTO mergestat.provider_api_usage (provider_id, hour, calls, last_remaining, rate_limit_reset_at)
+VALUES (@providerID::UUID, date_trunc('hour', now()), @calls::INTEGER, sqlc.narg('lastRemaining')::INTEGER, sqlc.narg('resetAt')::TIMESTAMPTZ)
+ON CONFLICT (provider_id, hour) DO UPDATE SET calls = provider_api_usage.calls + excluded.calls,
+    last_remaining = COALESCE(excluded.last_remaining, provider_api_usage.last_remaining),
+    rate_limit_reset_at = COALESCE(excluded.rate_limit_reset_at, provider_api_usage.rate_limit_reset_at), updated_at = now();
+
+-- name: InsertSyncBatch :exec
+INSERT INTO mergestat.repo_sync_batches (repo_sync_queue_id, repo_sync_id, repo_id, sync_type, handler_version, code_version)
+VALUES (@repoSyncQueueID::BIGINT, @repoSyncID::UUID, @repoID::UUID, @syncType::TEXT, @handlerVersion::INTEGER, @codeVersion::TEXT)
+ON CONFLICT (repo_sync_queue_id) DO NOTHING;
+
+-- enqueues a full re-sync of the repo syncs of the type last synced by an older handler version (or all of them, if version is null)
+-- name: BackfillRepoSyncs :one
+SELECT mergestat.backfill_repo_syncs(@syncType::TEXT, sqlc.narg('version')::INTEGER, @priority::INTEGER)::INTEGER AS enqueued;
+
 -- name: FetchGitHubToken :one
 SELECT pgp_sym_decrypt(credentials, $1) FROM mergestat.service_auth_credentials WHERE type = 'GITHUB_PAT' ORDER BY created_at DESC LIMIT 1;
 
@@ -117,6 +176,9 @@ FROM mergestat.repo_syncs rs
 INNER JOIN mergestat.repo_sync_types AS rst ON rs.sync_type = rst.type
 WHERE schedule_enabled
     AND id NOT IN (SELECT repo_sync_id FROM mergestat.repo_sync_queue WHERE status = 'RUNNING' OR status = 'QUEUED')
+    AND (COALESCE(rs.sync_interval, rs.adaptive_sync_interval) IS NULL OR NOT EXISTS (
+        SELECT 1 FROM mergestat.repo_sync_queue q WHERE q.repo_sync_id = rs.id AND q.created_at > now() - COALESCE(rs.sync_interval, rs.adaptive_sync_interval)
+    ))
     AND NOT EXISTS (
         SELECT rq.done_at
         FROM ranked_queue rq
@@ -127,6 +189,28 @@ WHERE schedule_enabled
 ORDER BY rs.priority, rs.sync_type desc
 ;
 
+-- name: EnqueueRepoGroupSyncs :one
+SELECT mergestat.enqueue_repo_group_syncs()::INTEGER AS enqueued;
+
+-- returns -1 if the lifecycle policy is disabled (or being applied by another worker)
+-- name: ApplyRepoLifecyclePolicy :one
+SELECT COALESCE(mergestat.apply_repo_lifecycle_policy(), -1)::INTEGER AS repos;
+
+-- returns -1 if the adaptive schedule is disabled (or being applied by another worker)
+-- name: ApplyAdaptiveSchedule :one
+SELECT COALESCE(mergestat.apply_adaptive_schedule(), -1)::INTEGER AS syncs;
+
+-- name: GetMaintenanceMode :one
+SELECT enabled, reason, enabled_at FROM mergestat.maintenance_mode;
+
+-- name: EnqueueRepoSyncOfType :exec
+INSERT INTO mergestat.repo_sync_queue (repo_sync_id, status, priority, type_group)
+SELECT rs.id, 'QUEUED', rs.priority, rst.type_group
+FROM mergestat.repo_syncs rs
+INNER JOIN mergestat.repo_sync_types AS rst ON rs.sync_type = rst.type
+WHERE rs.repo_id = @repoID::UUID AND rs.sync_type = @syncType::TEXT AND rs.schedule_enabled
+    AND rs.id NOT IN (SELECT repo_sync_id FROM mergestat.repo_sync_queue WHERE status = 'RUNNING' OR status = 'QUEUED');
+
 -- name: SetLatestKeepAliveForJob :exec
 UPDATE mergestat.repo_sync_queue SET last_keep_alive = now() WHERE id = $1;
 
@@ -150,6 +234,14 @@ DELETE FROM public.repos WHERE repo_import_id = $1::uuid AND NOT(repo = ANY($2::
 -- name: CleanOldRepoSyncQueue :exec
 SELECT mergestat.simple_repo_sync_queue_cleanup($1::INTEGER);
 
+-- returns -1 if another worker is already running the cleanup
+-- name: CleanRepoSyncQueueByPolicy :one
+SELECT COALESCE(mergestat.repo_sync_queue_cleanup(), -1)::INTEGER AS rows_deleted;
+
+-- returns -1 if another worker is already enforcing the retention policies
+-- name: EnforceDataRetention :one
+SELECT COALESCE(mergestat.enforce_data_retention(), -1)::BIGINT AS rows_deleted;
+
 -- name: CleanOldJobs :exec
 SELECT mergestat.simple_sqlq_cleanup($1::INTEGER);
 
@@ -161,6 +253,9 @@ SELECT id FROM public.repos WHERE repo_import_id = @importID::uuid AND repo = AN
 SELECT repo FROM public.repos WHERE repo_import_id = @importID::uuid
 ;
 
+-- name: GetSyncTypeMaintenance :one
+SELECT analyze_after_sync, reindex_after_sync FROM mergestat.repo_sync_types WHERE type = @syncType::TEXT;
+
 -- name: InsertNewDefaultSync :exec
 INSERT INTO mergestat.repo_syncs (repo_id, sync_type, priority, schedule_enabled)
 SELECT @repoID::uuid, type, priority, true
@@ -169,8 +264,7 @@ WHERE type = @syncType::text
 ON CONFLICT DO NOTHING;
 
 -- name: UpsertWorkflowsInPublic :exec
-WITH t AS (
-  INSERT INTO public.github_actions_workflows(
+INSERT INTO github_actions_workflows(
 	repo_id, 
 	id,
 	workflow_node_id,
@@ -207,18 +301,10 @@ WITH t AS (
       updated_at=EXCLUDED.updated_at,
       url=EXCLUDED.url,
       html_url=EXCLUDED.html_url,
-      badge_url=EXCLUDED.badge_url
-  RETURNING xmax::text
-) 
-SELECT
-    COUNT(*) AS all_rows,
-    SUM(CASE WHEN xmax::int = 0 THEN 1 ELSE 0 END) AS ins,
-    SUM(CASE WHEN xmax::int > 0 THEN 1 ELSE 0 END) AS upd
-FROM t;
+      badge_url=EXCLUDED.badge_url;
 
 -- name: UpsertWorkflowRuns :exec
-WITH t AS(
-	INSERT INTO public.github_actions_workflow_runs(
+INSERT INTO github_actions_workflow_runs(
 	repo_id,
 	id,
 	workflow_run_node_id,
@@ -308,18 +394,10 @@ WITH t AS(
 		head_commit=EXCLUDED.head_commit,
 		workflow_url=EXCLUDED.workflow_url,
 		repository_url=EXCLUDED.repository_url,
-		head_repository_url=EXCLUDED.head_repository_url
-  RETURNING xmax::text
-)
-SELECT
-    COUNT(*) AS all_rows,
-    SUM(CASE WHEN xmax::int = 0 THEN 1 ELSE 0 END) AS ins,
-    SUM(CASE WHEN xmax::int > 0 THEN 1 ELSE 0 END) AS upd
-FROM t;
+		head_repository_url=EXCLUDED.head_repository_url;
 
 -- name: UpsertWorkflowRunJobs :exec
-WITH t AS (
-	INSERT INTO public.github_actions_workflow_run_jobs (
+INSERT INTO github_actions_workflow_run_jobs (
 		repo_id,
 		id,
 		run_id,
@@ -386,19 +464,15 @@ WITH t AS (
 			runner_id=EXCLUDED.runner_id,
 			runner_name=EXCLUDED.runner_name,
 			runner_group_id=EXCLUDED.runner_group_id,
-			runner_group_name=EXCLUDED.runner_group_name
-		RETURNING xmax::text
-)
-SELECT
-    COUNT(*) AS all_rows,
-    SUM(CASE WHEN xmax::int = 0 THEN 1 ELSE 0 END) AS ins,
-    SUM(CASE WHEN xmax::int > 0 THEN 1 ELSE 0 END) AS upd
-FROM t;
+			runner_group_name=EXCLUDED.runner_group_name;
 
 
 -- name: GetRepoById :one
 SELECT * FROM public.repos WHERE id = @id;
 
+-- name: GetRepoByURL :one
+SELECT * FROM public.repos WHERE repo = @repo LIMIT 1;
+
 -- name: FetchContainerSync :one
 SELECT sync.id, sync.repo_id,
     image.type AS image_type, image.url AS image_url, image.version AS image_version,
@@ -14,6 +14,48 @@ import (
 	"github.com/jackc/pgtype"
 )
 
+const applyAdaptiveSchedule = `-- name: ApplyAdaptiveSchedule :one
+SELECT COALESCE(mergestat.apply_adaptive_schedule(), -1)::INTEGER AS syncs
+`
+
+// returns -1 if the adaptive schedule is disabled (or being applied by another worker)
+func (q *Queries) ApplyAdaptiveSchedule(ctx context.Context) (int32, error) {
+	row := q.db.QueryRow(ctx, applyAdaptiveSchedule)
+	var syncs int32
+	err := row.Scan(&syncs)
+	return syncs, err
+}
+
+const applyRepoLifecyclePolicy = `-- name: ApplyRepoLifecyclePolicy :one
+SELECT COALESCE(mergestat.apply_repo_lifecycle_policy(), -1)::INTEGER AS repos
+`
+
+// returns -1 if the lifecycle policy is disabled (or being applied by another worker)
+func (q *Queries) ApplyRepoLifecyclePolicy(ctx context.Context) (int32, error) {
+	row := q.db.QueryRow(ctx, applyRepoLifecyclePolicy)
+	var repos int32
+	err := row.Scan(&repos)
+	return repos, err
+}
+
+const backfillRepoSyncs = `-- name: BackfillRepoSyncs :one
+SELECT mergestat.backfill_repo_syncs($1::TEXT, $2::INTEGER, $3::INTEGER)::INTEGER AS enqueued
+`
+
+type BackfillRepoSyncsParams struct {
+	Synctype string
+	Version  sql.NullInt32
+	Priority int32
+}
+
+// enqueues a full re-sync of the repo syncs of the type last synced by an older handler version (or all of them, if version is null)
+func (q *Queries) BackfillRepoSyncs(ctx context.Context, arg BackfillRepoSyncsParams) (int32, error) {
+	row := q.db.QueryRow(ctx, backfillRepoSyncs, arg.Synctype, arg.Version, arg.Priority)
+	var enqueued int32
+	err := row.Scan(&enqueued)
+	return enqueued, err
+}
+
 const checkRunningImps = `-- name: CheckRunningImps :one
 SELECT COUNT(*) FROM mergestat.repo_imports WHERE import_status = 'RUNNING'
 `
@@ -43,8 +85,20 @@ func (q *Queries) CleanOldRepoSyncQueue(ctx context.Context, dollar_1 int32) err
 	return err
 }
 
+const cleanRepoSyncQueueByPolicy = `-- name: CleanRepoSyncQueueByPolicy :one
+SELECT COALESCE(mergestat.repo_sync_queue_cleanup(), -1)::INTEGER AS rows_deleted
+`
+
+// returns -1 if another worker is already running the cleanup
+func (q *Queries) CleanRepoSyncQueueByPolicy(ctx context.Context) (int32, error) {
+	row := q.db.QueryRow(ctx, cleanRepoSyncQueueByPolicy)
+	var rows_deleted int32
+	err := row.Scan(&rows_deleted)
+	return rows_deleted, err
+}
+
 const deleteGitHubRepoInfo = `-- name: DeleteGitHubRepoInfo :exec
-DELETE FROM public.github_repo_info WHERE repo_id = $1
+DELETE FROM github_repo_info WHERE repo_id = $1
 `
 
 func (q *Queries) DeleteGitHubRepoInfo(ctx context.Context, repoID uuid.UUID) error {
@@ -76,25 +130,61 @@ running AS (
         INNER JOIN mergestat.repo_sync_type_groups rstg ON rsq.type_group = rstg.group
         WHERE status = 'RUNNING'
 ),
+idle_slots AS (
+        -- slots of idle groups (without any queued job) that lend them, which are available to other groups
+        SELECT COALESCE(SUM(GREATEST(rstg.concurrent_syncs - (SELECT COUNT(*) FROM running WHERE running.group = rstg.group), 0)), 0) AS available
+        FROM mergestat.repo_sync_type_groups rstg
+        WHERE rstg.lend_idle_slots
+        AND NOT EXISTS (SELECT 1 FROM mergestat.repo_sync_queue rsq WHERE rsq.type_group = rstg.group AND rsq.status = 'QUEUED')
+),
+borrowed AS (
+        -- number of running syncs, by group, that are beyond the group's own concurrency (i.e. using borrowed slots)
+        SELECT rstg.group, GREATEST((SELECT COUNT(*) FROM running WHERE running.group = rstg.group) - rstg.concurrent_syncs, 0) AS count
+        FROM mergestat.repo_sync_type_groups rstg
+),
 dequeued AS (
    UPDATE mergestat.repo_sync_queue SET status = 'RUNNING'
    WHERE id IN (   
         SELECT rsq.id
         FROM mergestat.repo_sync_queue rsq
         INNER JOIN mergestat.repo_sync_type_groups rstg ON rsq.type_group = rstg.group
+        INNER JOIN mergestat.repo_syncs rs ON rsq.repo_sync_id = rs.id
+        INNER JOIN repos r ON rs.repo_id = r.id
         WHERE status = 'QUEUED'
-        AND rstg.concurrent_syncs > (SELECT COUNT(*) FROM running WHERE running.group = rstg.group)
-        ORDER BY rsq.priority ASC, rsq.created_at ASC, rsq.id ASC LIMIT 1 FOR UPDATE SKIP LOCKED
-   ) RETURNING id, created_at, status, repo_sync_id
+        -- nothing is dequeued while the maintenance mode is enabled (see mergestat.maintenance_mode)
+        AND NOT mergestat.maintenance_mode_enabled()
+        AND (
+            rstg.concurrent_syncs > (SELECT COUNT(*) FROM running WHERE running.group = rstg.group)
+            -- or the group may (and can) borrow a slot from an idle group
+            OR (
+                rstg.max_borrowed_syncs > (SELECT count FROM borrowed WHERE borrowed.group = rstg.group)
+                AND (SELECT available FROM idle_slots) > (SELECT SUM(count) FROM borrowed)
+            )
+        )
+        -- repos with the exclusiveSyncs setting only run one sync (of any type) at a time, and dequeues (of any group)
+        -- serialize on them (see mergestat.try_exclusive_repo_sync)
+        AND CASE WHEN COALESCE((r.settings->>'exclusiveSyncs')::BOOLEAN, FALSE) THEN mergestat.try_exclusive_repo_sync(rs.repo_id) ELSE TRUE END
+        -- syncs that call the provider's api are deferred while its api budget is exhausted (see mergestat.provider_api_budgets)
+        AND NOT (
+            EXISTS (SELECT 1 FROM mergestat.repo_sync_types rst WHERE rst.type = rs.sync_type AND rst.uses_provider_api)
+            AND mergestat.provider_api_budget_exhausted(r.provider)
+        )
+        -- the weight of the sync type must fit in the weight the worker has left (see mergestat.repo_sync_types.weight)
+        AND (SELECT rst.weight FROM mergestat.repo_sync_types rst WHERE rst.type = rs.sync_type) <= $1::INTEGER
+        -- jobs gain priority the longer they wait (see mergestat.repo_sync_queue_effective_priority), so that lower priority ones aren't starved
+        ORDER BY mergestat.repo_sync_queue_effective_priority(rsq) ASC, rsq.created_at ASC, rsq.id ASC LIMIT 1 FOR UPDATE OF rsq, rstg SKIP LOCKED
+   ) RETURNING id, created_at, status, repo_sync_id, parameters
 )
 SELECT
-    dequeued.id, dequeued.created_at, dequeued.status, dequeued.repo_sync_id,
-    repo_syncs.repo_id, repo_syncs.sync_type, repo_syncs.settings, repo_syncs.id, repo_syncs.schedule_enabled, repo_syncs.priority, repo_syncs.last_completed_repo_sync_queue_id,
+    dequeued.id, dequeued.created_at, dequeued.status, dequeued.repo_sync_id, dequeued.parameters,
+    repo_syncs.repo_id, repo_syncs.sync_type, repo_syncs.settings, repo_syncs.id, repo_syncs.schedule_enabled, repo_syncs.priority, repo_syncs.last_completed_repo_sync_queue_id, repo_syncs.last_completed_checksum, repo_syncs.sync_interval, repo_syncs.adaptive_sync_interval,
     repos.repo,
     repos.ref,
-    repos.settings AS repo_settings
+    repos.settings AS repo_settings,
+    repo_sync_types.weight
 FROM dequeued
 JOIN mergestat.repo_syncs ON mergestat.repo_syncs.id = dequeued.repo_sync_id
+JOIN mergestat.repo_sync_types ON mergestat.repo_sync_types.type = mergestat.repo_syncs.sync_type
 JOIN repos ON repos.id = mergestat.repo_syncs.repo_id
 `
 
@@ -103,6 +193,7 @@ type DequeueSyncJobRow struct {
 	CreatedAt                    time.Time
 	Status                       string
 	RepoSyncID                   uuid.UUID
+	Parameters                   pgtype.JSONB
 	RepoID                       uuid.UUID
 	SyncType                     string
 	Settings                     pgtype.JSONB
@@ -110,19 +201,24 @@ type DequeueSyncJobRow struct {
 	ScheduleEnabled              bool
 	Priority                     int32
 	LastCompletedRepoSyncQueueID sql.NullInt64
+	LastCompletedChecksum        sql.NullString
+	SyncInterval                 sql.NullInt64
+	AdaptiveSyncInterval         sql.NullInt64
 	Repo                         string
 	Ref                          sql.NullString
 	RepoSettings                 pgtype.JSONB
+	Weight                       int32
 }
 
-func (q *Queries) DequeueSyncJob(ctx context.Context) (DequeueSyncJobRow, error) {
-	row := q.db.QueryRow(ctx, dequeueSyncJob)
+func (q *Queries) DequeueSyncJob(ctx context.Context, maxWeight int32) (DequeueSyncJobRow, error) {
+	row := q.db.QueryRow(ctx, dequeueSyncJob, maxWeight)
 	var i DequeueSyncJobRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.Status,
 		&i.RepoSyncID,
+		&i.Parameters,
 		&i.RepoID,
 		&i.SyncType,
 		&i.Settings,
@@ -130,9 +226,13 @@ func (q *Queries) DequeueSyncJob(ctx context.Context) (DequeueSyncJobRow, error)
 		&i.ScheduleEnabled,
 		&i.Priority,
 		&i.LastCompletedRepoSyncQueueID,
+		&i.LastCompletedChecksum,
+		&i.SyncInterval,
+		&i.AdaptiveSyncInterval,
 		&i.Repo,
 		&i.Ref,
 		&i.RepoSettings,
+		&i.Weight,
 	)
 	return i, err
 }
@@ -151,6 +251,18 @@ func (q *Queries) EnableContainerSync(ctx context.Context, arg EnableContainerSy
 	return err
 }
 
+const enforceDataRetention = `-- name: EnforceDataRetention :one
+SELECT COALESCE(mergestat.enforce_data_retention(), -1)::BIGINT AS rows_deleted
+`
+
+// returns -1 if another worker is already enforcing the retention policies
+func (q *Queries) EnforceDataRetention(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, enforceDataRetention)
+	var rows_deleted int64
+	err := row.Scan(&rows_deleted)
+	return rows_deleted, err
+}
+
 const enqueueAllSyncs = `-- name: EnqueueAllSyncs :exec
 WITH ranked_queue AS (
     SELECT
@@ -173,6 +285,9 @@ FROM mergestat.repo_syncs rs
 INNER JOIN mergestat.repo_sync_types AS rst ON rs.sync_type = rst.type
 WHERE schedule_enabled
     AND id NOT IN (SELECT repo_sync_id FROM mergestat.repo_sync_queue WHERE status = 'RUNNING' OR status = 'QUEUED')
+    AND (COALESCE(rs.sync_interval, rs.adaptive_sync_interval) IS NULL OR NOT EXISTS (
+        SELECT 1 FROM mergestat.repo_sync_queue q WHERE q.repo_sync_id = rs.id AND q.created_at > now() - COALESCE(rs.sync_interval, rs.adaptive_sync_interval)
+    ))
     AND NOT EXISTS (
         SELECT rq.done_at
         FROM ranked_queue rq
@@ -191,6 +306,36 @@ func (q *Queries) EnqueueAllSyncs(ctx context.Context) error {
 	return err
 }
 
+const enqueueRepoGroupSyncs = `-- name: EnqueueRepoGroupSyncs :one
+SELECT mergestat.enqueue_repo_group_syncs()::INTEGER AS enqueued
+`
+
+func (q *Queries) EnqueueRepoGroupSyncs(ctx context.Context) (int32, error) {
+	row := q.db.QueryRow(ctx, enqueueRepoGroupSyncs)
+	var enqueued int32
+	err := row.Scan(&enqueued)
+	return enqueued, err
+}
+
+const enqueueRepoSyncOfType = `-- name: EnqueueRepoSyncOfType :exec
+INSERT INTO mergestat.repo_sync_queue (repo_sync_id, status, priority, type_group)
+SELECT rs.id, 'QUEUED', rs.priority, rst.type_group
+FROM mergestat.repo_syncs rs
+INNER JOIN mergestat.repo_sync_types AS rst ON rs.sync_type = rst.type
+WHERE rs.repo_id = $1::UUID AND rs.sync_type = $2::TEXT AND rs.schedule_enabled
+    AND rs.id NOT IN (SELECT repo_sync_id FROM mergestat.repo_sync_queue WHERE status = 'RUNNING' OR status = 'QUEUED')
+`
+
+type EnqueueRepoSyncOfTypeParams struct {
+	Repoid   uuid.UUID
+	Synctype string
+}
+
+func (q *Queries) EnqueueRepoSyncOfType(ctx context.Context, arg EnqueueRepoSyncOfTypeParams) error {
+	_, err := q.db.Exec(ctx, enqueueRepoSyncOfType, arg.Repoid, arg.Synctype)
+	return err
+}
+
 const fetchContainerSync = `-- name: FetchContainerSync :one
 SELECT sync.id, sync.repo_id,
     image.type AS image_type, image.url AS image_url, image.version AS image_version,
@@ -266,6 +411,23 @@ func (q *Queries) FetchImportJob(ctx context.Context, id uuid.UUID) (FetchImport
 	return i, err
 }
 
+const getMaintenanceMode = `-- name: GetMaintenanceMode :one
+SELECT enabled, reason, enabled_at FROM mergestat.maintenance_mode
+`
+
+type GetMaintenanceModeRow struct {
+	Enabled   bool
+	Reason    sql.NullString
+	EnabledAt sql.NullTime
+}
+
+func (q *Queries) GetMaintenanceMode(ctx context.Context) (GetMaintenanceModeRow, error) {
+	row := q.db.QueryRow(ctx, getMaintenanceMode)
+	var i GetMaintenanceModeRow
+	err := row.Scan(&i.Enabled, &i.Reason, &i.EnabledAt)
+	return i, err
+}
+
 const getRepoById = `-- name: GetRepoById :one
 SELECT id, repo, ref, created_at, settings, tags, repo_import_id, provider FROM public.repos WHERE id = $1
 `
@@ -286,6 +448,26 @@ func (q *Queries) GetRepoById(ctx context.Context, id uuid.UUID) (Repo, error) {
 	return i, err
 }
 
+const getRepoByURL = `-- name: GetRepoByURL :one
+SELECT id, repo, ref, created_at, settings, tags, repo_import_id, provider FROM public.repos WHERE repo = $1 LIMIT 1
+`
+
+func (q *Queries) GetRepoByURL(ctx context.Context, repo string) (Repo, error) {
+	row := q.db.QueryRow(ctx, getRepoByURL, repo)
+	var i Repo
+	err := row.Scan(
+		&i.ID,
+		&i.Repo,
+		&i.Ref,
+		&i.CreatedAt,
+		&i.Settings,
+		&i.Tags,
+		&i.RepoImportID,
+		&i.Provider,
+	)
+	return i, err
+}
+
 const getRepoIDsFromRepoImport = `-- name: GetRepoIDsFromRepoImport :many
 SELECT id FROM public.repos WHERE repo_import_id = $1::uuid AND repo = ANY($2::TEXT[])
 `
@@ -362,8 +544,24 @@ func (q *Queries) GetRepoUrlFromImport(ctx context.Context, importid uuid.UUID)
 	return items, nil
 }
 
+const getSyncTypeMaintenance = `-- name: GetSyncTypeMaintenance :one
+SELECT analyze_after_sync, reindex_after_sync FROM mergestat.repo_sync_types WHERE type = $1::TEXT
+`
+
+type GetSyncTypeMaintenanceRow struct {
+	AnalyzeAfterSync bool
+	ReindexAfterSync bool
+}
+
+func (q *Queries) GetSyncTypeMaintenance(ctx context.Context, synctype string) (GetSyncTypeMaintenanceRow, error) {
+	row := q.db.QueryRow(ctx, getSyncTypeMaintenance, synctype)
+	var i GetSyncTypeMaintenanceRow
+	err := row.Scan(&i.AnalyzeAfterSync, &i.ReindexAfterSync)
+	return i, err
+}
+
 const insertGitHubRepoInfo = `-- name: InsertGitHubRepoInfo :exec
-INSERT INTO public.github_repo_info (
+INSERT INTO github_repo_info (
     repo_id, owner, name,
     created_at, default_branch_name, description, size, fork_count, homepage_url,
     is_archived, is_disabled, mirror_url, is_private, total_issues_count, latest_release_author,
@@ -461,18 +659,51 @@ func (q *Queries) InsertNewDefaultSync(ctx context.Context, arg InsertNewDefault
 	return err
 }
 
+const insertSyncBatch = `-- name: InsertSyncBatch :exec
+INSERT INTO mergestat.repo_sync_batches (repo_sync_queue_id, repo_sync_id, repo_id, sync_type, handler_version, code_version)
+VALUES ($1::BIGINT, $2::UUID, $3::UUID, $4::TEXT, $5::INTEGER, $6::TEXT)
+ON CONFLICT (repo_sync_queue_id) DO NOTHING
+`
+
+type InsertSyncBatchParams struct {
+	Reposyncqueueid int64
+	Reposyncid      uuid.UUID
+	Repoid          uuid.UUID
+	Synctype        string
+	Handlerversion  int32
+	Codeversion     string
+}
+
+func (q *Queries) InsertSyncBatch(ctx context.Context, arg InsertSyncBatchParams) error {
+	_, err := q.db.Exec(ctx, insertSyncBatch,
+		arg.Reposyncqueueid,
+		arg.Reposyncid,
+		arg.Repoid,
+		arg.Synctype,
+		arg.Handlerversion,
+		arg.Codeversion,
+	)
+	return err
+}
+
 const insertSyncJobLog = `-- name: InsertSyncJobLog :exec
-INSERT INTO mergestat.repo_sync_logs (log_type, message, repo_sync_queue_id) VALUES ($1, $2, $3)
+INSERT INTO mergestat.repo_sync_logs (log_type, message, repo_sync_queue_id, details) VALUES ($1, $2, $3, $4)
 `
 
 type InsertSyncJobLogParams struct {
 	LogType         string
 	Message         string
 	RepoSyncQueueID int64
+	Details         pgtype.JSONB
 }
 
 func (q *Queries) InsertSyncJobLog(ctx context.Context, arg InsertSyncJobLogParams) error {
-	_, err := q.db.Exec(ctx, insertSyncJobLog, arg.LogType, arg.Message, arg.RepoSyncQueueID)
+	_, err := q.db.Exec(ctx, insertSyncJobLog,
+		arg.LogType,
+		arg.Message,
+		arg.RepoSyncQueueID,
+		arg.Details,
+	)
 	return err
 }
 
@@ -548,7 +779,7 @@ WITH timed_out_sync_jobs AS (
         (last_keep_alive < now() - '10 minutes'::interval)
         OR
         (last_keep_alive IS NULL AND started_at < now() - '10 minutes'::interval)) -- if worker crashed before last_keep_alive was first set
-    RETURNING id, created_at, repo_sync_id, status, started_at, done_at, last_keep_alive, priority, type_group
+    RETURNING id, created_at, repo_sync_id, status, started_at, done_at, last_keep_alive, priority, type_group, parameters
 )
 INSERT INTO mergestat.repo_sync_logs (repo_sync_queue_id, log_type, message)
 SELECT id, 'ERROR', 'No response from job within reasonable interval. Timing out.' FROM timed_out_sync_jobs
@@ -575,6 +806,31 @@ func (q *Queries) MarkSyncsAsTimedOut(ctx context.Context) ([]int64, error) {
 	return items, nil
 }
 
+const recordAPIUsage = `-- name: RecordAPIUsage :exec
+INSERT INTO mergestat.provider_api_usage (provider_id, hour, calls, last_remaining, rate_limit_reset_at)
+VALUES ($1::UUID, date_trunc('hour', now()), $2::INTEGER, $3::INTEGER, $4::TIMESTAMPTZ)
+ON CONFLICT (provider_id, hour) DO UPDATE SET calls = provider_api_usage.calls + excluded.calls,
+    last_remaining = COALESCE(excluded.last_remaining, provider_api_usage.last_remaining),
+    rate_limit_reset_at = COALESCE(excluded.rate_limit_reset_at, provider_api_usage.rate_limit_reset_at), updated_at = now()
+`
+
+type RecordAPIUsageParams struct {
+	Providerid    uuid.UUID
+	Calls         int32
+	LastRemaining sql.NullInt32
+	ResetAt       sql.NullTime
+}
+
+func (q *Queries) RecordAPIUsage(ctx context.Context, arg RecordAPIUsageParams) error {
+	_, err := q.db.Exec(ctx, recordAPIUsage,
+		arg.Providerid,
+		arg.Calls,
+		arg.LastRemaining,
+		arg.ResetAt,
+	)
+	return err
+}
+
 const setLatestKeepAliveForJob = `-- name: SetLatestKeepAliveForJob :exec
 UPDATE mergestat.repo_sync_queue SET last_keep_alive = now() WHERE id = $1
 `
@@ -584,6 +840,20 @@ func (q *Queries) SetLatestKeepAliveForJob(ctx context.Context, id int64) error
 	return err
 }
 
+const setSyncChecksum = `-- name: SetSyncChecksum :exec
+UPDATE mergestat.repo_syncs SET last_completed_checksum = $1::TEXT WHERE id = $2::UUID
+`
+
+type SetSyncChecksumParams struct {
+	Checksum string
+	ID       uuid.UUID
+}
+
+func (q *Queries) SetSyncChecksum(ctx context.Context, arg SetSyncChecksumParams) error {
+	_, err := q.db.Exec(ctx, setSyncChecksum, arg.Checksum, arg.ID)
+	return err
+}
+
 const setSyncJobStatus = `-- name: SetSyncJobStatus :exec
 SELECT mergestat.set_sync_job_status($1::TEXT, $2::BIGINT)
 `
@@ -598,6 +868,21 @@ func (q *Queries) SetSyncJobStatus(ctx context.Context, arg SetSyncJobStatusPara
 	return err
 }
 
+const setSyncVersion = `-- name: SetSyncVersion :exec
+INSERT INTO mergestat.repo_sync_versions (repo_sync_id, version) VALUES ($1::UUID, $2::INTEGER)
+ON CONFLICT (repo_sync_id) DO UPDATE SET version = excluded.version, updated_at = now()
+`
+
+type SetSyncVersionParams struct {
+	Reposyncid uuid.UUID
+	Version    int32
+}
+
+func (q *Queries) SetSyncVersion(ctx context.Context, arg SetSyncVersionParams) error {
+	_, err := q.db.Exec(ctx, setSyncVersion, arg.Reposyncid, arg.Version)
+	return err
+}
+
 const updateImportStatus = `-- name: UpdateImportStatus :exec
 UPDATE mergestat.repo_imports SET import_status = $1::TEXT, import_error = $2::TEXT WHERE id = $3
 `
@@ -638,8 +923,7 @@ func (q *Queries) UpsertRepo(ctx context.Context, arg UpsertRepoParams) error {
 }
 
 const upsertWorkflowRunJobs = `-- name: UpsertWorkflowRunJobs :exec
-WITH t AS (
-	INSERT INTO public.github_actions_workflow_run_jobs (
+INSERT INTO github_actions_workflow_run_jobs (
 		repo_id,
 		id,
 		run_id,
@@ -707,13 +991,6 @@ WITH t AS (
 			runner_name=EXCLUDED.runner_name,
 			runner_group_id=EXCLUDED.runner_group_id,
 			runner_group_name=EXCLUDED.runner_group_name
-		RETURNING xmax::text
-)
-SELECT
-    COUNT(*) AS all_rows,
-    SUM(CASE WHEN xmax::int = 0 THEN 1 ELSE 0 END) AS ins,
-    SUM(CASE WHEN xmax::int > 0 THEN 1 ELSE 0 END) AS upd
-FROM t
 `
 
 type UpsertWorkflowRunJobsParams struct {
@@ -774,8 +1051,7 @@ func (q *Queries) UpsertWorkflowRunJobs(ctx context.Context, arg UpsertWorkflowR
 }
 
 const upsertWorkflowRuns = `-- name: UpsertWorkflowRuns :exec
-WITH t AS(
-	INSERT INTO public.github_actions_workflow_runs(
+INSERT INTO github_actions_workflow_runs(
 	repo_id,
 	id,
 	workflow_run_node_id,
@@ -866,13 +1142,6 @@ WITH t AS(
 		workflow_url=EXCLUDED.workflow_url,
 		repository_url=EXCLUDED.repository_url,
 		head_repository_url=EXCLUDED.head_repository_url
-  RETURNING xmax::text
-)
-SELECT
-    COUNT(*) AS all_rows,
-    SUM(CASE WHEN xmax::int = 0 THEN 1 ELSE 0 END) AS ins,
-    SUM(CASE WHEN xmax::int > 0 THEN 1 ELSE 0 END) AS upd
-FROM t
 `
 
 type UpsertWorkflowRunsParams struct {
@@ -949,8 +1218,7 @@ func (q *Queries) UpsertWorkflowRuns(ctx context.Context, arg UpsertWorkflowRuns
 }
 
 const upsertWorkflowsInPublic = `-- name: UpsertWorkflowsInPublic :exec
-WITH t AS (
-  INSERT INTO public.github_actions_workflows(
+INSERT INTO github_actions_workflows(
 	repo_id, 
 	id,
 	workflow_node_id,
@@ -988,13 +1256,6 @@ WITH t AS (
       url=EXCLUDED.url,
       html_url=EXCLUDED.html_url,
       badge_url=EXCLUDED.badge_url
-  RETURNING xmax::text
-) 
-SELECT
-    COUNT(*) AS all_rows,
-    SUM(CASE WHEN xmax::int = 0 THEN 1 ELSE 0 END) AS ins,
-    SUM(CASE WHEN xmax::int > 0 THEN 1 ELSE 0 END) AS upd
-FROM t
 `
 
 type UpsertWorkflowsInPublicParams struct {
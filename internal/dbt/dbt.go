@@ -0,0 +1,274 @@
+// Package dbt implements the coordination of dbt with the syncs: once a sync cycle completes, the dbt source
+// declaring the synced tables (with their freshness) is written, and the configured dbt Cloud job is run, so that
+// transformation pipelines run on fresh data rather than on a schedule of their own (see mergestat.dbt_integration).
+package dbt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// loadedAtField is the column of synced tables holding when a row was synced
+const loadedAtField = "_mergestat_synced_at"
+
+// Config is the configuration of the integration (see mergestat.dbt_integration)
+type Config struct {
+	SourceName string
+	WarnAfter  time.Duration
+	ErrorAfter time.Duration
+
+	CloudURL       string
+	CloudAccountID int64
+	CloudJobID     int64
+}
+
+// integration periodically checks whether a sync cycle completed, and if so, writes the dbt source to SourcesPath
+// and runs the dbt Cloud job (with CloudToken)
+type integration struct {
+	logger *zerolog.Logger
+	pool   *pgxpool.Pool
+
+	// SourcesPath is the path the properties file of the dbt source is written to (eg. models/mergestat/sources.yml), if set
+	SourcesPath string
+
+	// CloudToken is the token of the dbt Cloud API, required to run the configured job
+	CloudToken string
+
+	// transport, if set, is the http transport api calls are made through (eg. to a fake api, in tests)
+	transport http.RoundTripper
+}
+
+func New(logger *zerolog.Logger, pool *pgxpool.Pool, sourcesPath, cloudToken string) *integration {
+	return &integration{
+		logger:      logger,
+		pool:        pool,
+		SourcesPath: sourcesPath,
+		CloudToken:  cloudToken,
+	}
+}
+
+// WithTransport sets the http transport the api calls are made through
+func (i *integration) WithTransport(transport http.RoundTripper) *integration {
+	i.transport = transport
+	return i
+}
+
+func (i *integration) Start(ctx context.Context, interval time.Duration) {
+	i.logger.Info().Msg("starting dbt integration routine")
+	exec := func() {
+		var cfg, completed, err = i.cycle(ctx)
+		if err != nil {
+			i.logger.Err(err).Msg("encountered error checking for completed sync cycles")
+			return
+		}
+		if !completed {
+			return
+		}
+
+		var runID int64
+		if runID, err = i.run(ctx, cfg); err != nil {
+			i.logger.Err(err).Msg("encountered error running dbt integration")
+		}
+
+		var lastError *string
+		if err != nil {
+			var message = err.Error()
+			lastError = &message
+		}
+		const record = `UPDATE mergestat.dbt_integration SET last_run_id = COALESCE(NULLIF($1::BIGINT, 0), last_run_id), last_error = $2`
+		if _, err = i.pool.Exec(ctx, record, runID, lastError); err != nil {
+			i.logger.Err(err).Msg("failed to record dbt integration run")
+		}
+	}
+	exec()
+
+	for {
+		select {
+		case <-ctx.Done():
+			i.logger.Info().Msg("stopping dbt integration routine")
+			return
+		case <-time.After(interval):
+			exec()
+		}
+	}
+}
+
+// cycle reports whether a sync cycle completed since the last one: the queue is drained, and syncs finished since.
+// The cycle is claimed (by pushing last_cycle_at), so that it's handled by a single worker.
+func (i *integration) cycle(ctx context.Context) (cfg *Config, completed bool, err error) {
+	const claim = `
+UPDATE mergestat.dbt_integration SET last_cycle_at = now()
+WHERE enabled
+    AND NOT EXISTS (SELECT 1 FROM mergestat.repo_sync_queue WHERE status IN ('QUEUED', 'RUNNING'))
+    AND EXISTS (SELECT 1 FROM mergestat.repo_sync_queue WHERE status = 'DONE' AND done_at > COALESCE(last_cycle_at, '-infinity'))
+RETURNING ` + configColumns
+
+	if cfg, err = scanConfig(i.pool.QueryRow(ctx, claim)); errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	return cfg, err == nil, err
+}
+
+// run writes the dbt source and runs the dbt Cloud job, as configured, and returns the id of the dbt Cloud run
+func (i *integration) run(ctx context.Context, cfg *Config) (runID int64, err error) {
+	if i.SourcesPath != "" {
+		if err = WriteSources(ctx, i.pool, cfg, i.SourcesPath); err != nil {
+			return 0, errors.Wrapf(err, "failed to write dbt source")
+		}
+		i.logger.Info().Msgf("sync cycle completed, wrote dbt source %s to %s", cfg.SourceName, i.SourcesPath)
+	}
+
+	if cfg.CloudJobID != 0 {
+		if i.CloudToken == "" {
+			return 0, errors.New("in order to run the dbt Cloud job, DBT_CLOUD_API_TOKEN must be set")
+		}
+		if runID, err = i.runJob(ctx, cfg); err != nil {
+			return 0, errors.Wrapf(err, "failed to run dbt Cloud job %d", cfg.CloudJobID)
+		}
+		i.logger.Info().Msgf("sync cycle completed, triggered run %d of dbt Cloud job %d", runID, cfg.CloudJobID)
+	}
+
+	return runID, nil
+}
+
+// runJob triggers a run of the dbt Cloud job (see https://docs.getdbt.com/dbt-cloud/api-v2#/operations/Trigger%20Job%20Run)
+func (i *integration) runJob(ctx context.Context, cfg *Config) (_ int64, err error) {
+	var url = fmt.Sprintf("%s/api/v2/accounts/%d/jobs/%d/run/", strings.TrimSuffix(cfg.CloudURL, "/"), cfg.CloudAccountID, cfg.CloudJobID)
+	var body, _ = json.Marshal(map[string]string{"cause": "Triggered by MergeStat after a sync cycle completed"})
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body)); err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Token "+i.CloudToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	var transport = i.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	var resp *http.Response
+	if resp, err = (&http.Client{Transport: transport, Timeout: time.Minute}).Do(req); err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var run struct {
+		Data struct {
+			ID int64 `json:"id"`
+		} `json:"data"`
+		Status struct {
+			UserMessage string `json:"user_message"`
+		} `json:"status"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&run)
+
+	if resp.StatusCode >= 300 {
+		return 0, errors.Errorf("unexpected status %s: %s", resp.Status, run.Status.UserMessage)
+	}
+	return run.Data.ID, nil
+}
+
+const configColumns = `source_name, EXTRACT(EPOCH FROM warn_after)::BIGINT, EXTRACT(EPOCH FROM error_after)::BIGINT,
+    cloud_url, COALESCE(cloud_account_id, 0), COALESCE(cloud_job_id, 0)`
+
+func scanConfig(row pgx.Row) (*Config, error) {
+	var cfg Config
+	var warnAfter, errorAfter int64
+	if err := row.Scan(&cfg.SourceName, &warnAfter, &errorAfter, &cfg.CloudURL, &cfg.CloudAccountID, &cfg.CloudJobID); err != nil {
+		return nil, err
+	}
+	cfg.WarnAfter, cfg.ErrorAfter = time.Duration(warnAfter)*time.Second, time.Duration(errorAfter)*time.Second
+	return &cfg, nil
+}
+
+// LoadConfig returns the configuration of the integration, enabled or not
+func LoadConfig(ctx context.Context, pool *pgxpool.Pool) (*Config, error) {
+	return scanConfig(pool.QueryRow(ctx, "SELECT "+configColumns+" FROM mergestat.dbt_integration"))
+}
+
+// Sources renders the properties file declaring the tables (and views) of the public schema as a dbt source,
+// documented with their comments. Tables with a _mergestat_synced_at column have their freshness checked by dbt.
+// Synced tables relocated out of public (see mergestat.synced_tables) are declared under their own name, in a source
+// of their schema (see helper.DBTSources).
+func Sources(ctx context.Context, pool *pgxpool.Pool, cfg *Config) (_ []byte, err error) {
+	const listColumns = `
+SELECT n.nspname, COALESCE(s.table_name, c.relname), c.relname, COALESCE(obj_description(c.oid, 'pg_class'), ''),
+       a.attname, COALESCE(col_description(c.oid, a.attnum), '')
+    FROM pg_catalog.pg_class c
+    INNER JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+    INNER JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+    LEFT JOIN mergestat.synced_tables s ON s.schema_name = n.nspname AND s.physical_name = c.relname
+    WHERE (n.nspname = 'public' OR s.table_name IS NOT NULL) AND c.relkind IN ('r', 'p', 'v', 'm') AND c.relname <> 'schema_migrations'
+    ORDER BY n.nspname <> 'public', n.nspname, 2, a.attnum`
+
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, listColumns); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []helper.DBTTable
+	for rows.Next() {
+		var schema, table, identifier, description, column, columnDescription string
+		if err = rows.Scan(&schema, &table, &identifier, &description, &column, &columnDescription); err != nil {
+			return nil, err
+		}
+
+		if len(tables) == 0 || tables[len(tables)-1].Schema != schema || tables[len(tables)-1].Name != table {
+			tables = append(tables, helper.DBTTable{Name: table, Description: description, Schema: schema, Identifier: identifier})
+		}
+		var t = &tables[len(tables)-1]
+		if column == loadedAtField {
+			t.LoadedAtField = loadedAtField
+		}
+		t.Columns = append(t.Columns, helper.DBTColumn{Name: column, Description: columnDescription})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return helper.DBTSources(cfg.SourceName, "public", tables, cfg.WarnAfter, cfg.ErrorAfter)
+}
+
+// WriteSources writes the properties file of the dbt source (see Sources) to path, replacing it at once
+func WriteSources(ctx context.Context, pool *pgxpool.Pool, cfg *Config, path string) (err error) {
+	var content []byte
+	if content, err = Sources(ctx, pool, cfg); err != nil {
+		return err
+	}
+
+	var tmp *os.File
+	if tmp, err = os.CreateTemp(filepath.Dir(path), ".sources-*.yml"); err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,133 @@
+// Package dialect describes the features of the Postgres-compatible backend the worker is connected to,
+// so that behaviors relying on features a backend lacks can be gated (or fail with a clear error).
+package dialect
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Dialect is a Postgres-compatible backend and the (non-standard) features it supports
+type Dialect struct {
+	// Name of the backend, eg. postgres
+	Name string
+
+	// AdvisoryLocks is set if pg_try_advisory_xact_lock() and friends are supported (used by queue cleanup)
+	AdvisoryLocks bool
+
+	// SkipLocked is set if SELECT ... FOR UPDATE SKIP LOCKED is supported (required to dequeue jobs)
+	SkipLocked bool
+
+	// SystemColumns is set if the xmin and ctid system columns are available (used to encrypt the values written by statements)
+	SystemColumns bool
+
+	// PGCrypto is set if pgp_sym_encrypt() and pgp_sym_decrypt() are available (used to store credentials)
+	PGCrypto bool
+
+	// WALFunctions is set if pg_current_wal_lsn() and pg_last_wal_replay_lsn() are available (used with read replicas)
+	WALFunctions bool
+
+	// TemporaryTables is set if temporary tables are supported (used by full-refresh syncs)
+	TemporaryTables bool
+
+	// ReindexConcurrently is set if REINDEX TABLE CONCURRENTLY is supported
+	ReindexConcurrently bool
+
+	// StatActivity is set if pg_stat_activity and the max_connections setting are available (used to detect load)
+	StatActivity bool
+
+	// TextSearch is set if GIN indexes over to_tsvector() and pg_trgm are supported (used by search indexes)
+	TextSearch bool
+}
+
+// Postgres is the reference dialect, supporting every feature
+var Postgres = &Dialect{
+	Name:                "postgres",
+	AdvisoryLocks:       true,
+	SkipLocked:          true,
+	SystemColumns:       true,
+	PGCrypto:            true,
+	WALFunctions:        true,
+	TemporaryTables:     true,
+	ReindexConcurrently: true,
+	StatActivity:        true,
+	TextSearch:          true,
+}
+
+// Aurora is Amazon Aurora PostgreSQL, whose storage layer doesn't expose WAL positions
+var Aurora = &Dialect{
+	Name:                "aurora",
+	AdvisoryLocks:       true,
+	SkipLocked:          true,
+	SystemColumns:       true,
+	PGCrypto:            true,
+	WALFunctions:        false,
+	TemporaryTables:     true,
+	ReindexConcurrently: true,
+	StatActivity:        true,
+	TextSearch:          true,
+}
+
+// CockroachDB speaks the Postgres wire protocol, but lacks most of its system functions and columns
+var CockroachDB = &Dialect{
+	Name:                "cockroachdb",
+	AdvisoryLocks:       false,
+	SkipLocked:          true,
+	SystemColumns:       false,
+	PGCrypto:            false,
+	WALFunctions:        false,
+	TemporaryTables:     false,
+	ReindexConcurrently: false,
+	StatActivity:        false,
+	TextSearch:          false,
+}
+
+// Detect returns the dialect of the backend the pool is connected to, defaulting to Postgres
+func Detect(ctx context.Context, pool *pgxpool.Pool) (_ *Dialect, err error) {
+	var version string
+	if err = pool.QueryRow(ctx, "SELECT version()").Scan(&version); err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(version, "CockroachDB") {
+		return CockroachDB, nil
+	}
+
+	var aurora bool
+	const isAurora = "SELECT EXISTS (SELECT 1 FROM pg_proc WHERE proname = 'aurora_version')"
+	if err = pool.QueryRow(ctx, isAurora).Scan(&aurora); err != nil {
+		return nil, err
+	}
+
+	if aurora {
+		return Aurora, nil
+	}
+
+	return Postgres, nil
+}
+
+// Unsupported returns a description of each feature the dialect lacks, and what it's used for
+func (d *Dialect) Unsupported() []string {
+	var unsupported []string
+	for _, f := range []struct {
+		supported   bool
+		description string
+	}{
+		{d.AdvisoryLocks, "advisory locks: queue cleanup by retention policy (jobs are removed after REPO_SYNC_QUEUE_RETENTION_DAYS, 30 by default), data retention policies and table tuning are disabled"},
+		{d.SkipLocked, "FOR UPDATE SKIP LOCKED: jobs can't be dequeued"},
+		{d.SystemColumns, "the xmin and ctid system columns: syncs writing into encrypted columns fail"},
+		{d.PGCrypto, "pgcrypto: stored credentials can't be read"},
+		{d.WALFunctions, "WAL position functions: read replicas are not used"},
+		{d.TemporaryTables, "temporary tables: GIT_BLAME, GIT_FILES, GIT_COMMIT_PATCHES, GIT_SYMBOLS, RELEASE_CHANGELOGS, GITHUB_ISSUE_RESPONSE_TIMES and CI_DURATION_REGRESSIONS syncs, and the repo lifecycle policy, are disabled"},
+		{d.ReindexConcurrently, "REINDEX CONCURRENTLY: indexes are not rebuilt after syncs"},
+		{d.StatActivity, "pg_stat_activity: concurrency is only adapted to connection wait times"},
+		{d.TextSearch, "GIN indexes over to_tsvector() and pg_trgm: search indexes are not maintained"},
+	} {
+		if !f.supported {
+			unsupported = append(unsupported, f.description)
+		}
+	}
+	return unsupported
+}
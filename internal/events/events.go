@@ -0,0 +1,369 @@
+// Package events implements the polling of the GitHub Events API, which enqueues the syncs of the repos that
+// changed (eg. on a push, or a new pull request) as soon as the change is seen, rather than on their schedule.
+package events
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/rs/zerolog"
+	"golang.org/x/oauth2"
+)
+
+// maxPages bounds the pages of events fetched by a poll (GitHub keeps the latest 300 events of an organization)
+const maxPages = 3
+
+// poller periodically polls the events of the owners of synced GitHub repos, and enqueues the syncs the events make
+// stale (see mergestat.github_event_sync_types) with the highest priority. The events of organizations are those the
+// authenticated user receives of them (private repos included), the events of users (or of organizations the
+// authenticated user isn't a member of) those of each of their synced repos.
+type poller struct {
+	logger *zerolog.Logger
+	pool   *pgxpool.Pool
+	db     *db.Queries
+
+	// transport, if set, returns the http transport the api calls of a provider are made through, so that they count
+	// against its api budget (see syncer.ProviderTransport)
+	transport func(provider uuid.UUID) http.RoundTripper
+
+	// logins are the logins of the authenticated users, by provider, and organizations whether the owners are
+	// organizations (the authenticated user is a member of), by provider and owner
+	logins        map[uuid.UUID]string
+	organizations map[string]bool
+
+	// etags are the etags of the latest responses of the events of repos, by repo
+	etags map[string]string
+}
+
+func New(logger *zerolog.Logger, pool *pgxpool.Pool) *poller {
+	return &poller{
+		logger:        logger,
+		pool:          pool,
+		db:            db.New(pool),
+		logins:        make(map[uuid.UUID]string),
+		organizations: make(map[string]bool),
+		etags:         make(map[string]string),
+	}
+}
+
+// WithTransport sets the function returning the http transport the api calls of a provider are made through
+func (p *poller) WithTransport(transport func(provider uuid.UUID) http.RoundTripper) *poller {
+	p.transport = transport
+	return p
+}
+
+// owner is an owner of synced repos whose events are due to be polled (see mergestat.github_event_polls)
+type owner struct {
+	Provider    uuid.UUID
+	Name        string
+	ETag        string
+	LastEventID int64
+}
+
+func (p *poller) Start(ctx context.Context, interval time.Duration) {
+	p.logger.Info().Msg("starting github events poller")
+	exec := func() {
+		var owners, err = p.due(ctx)
+		if err != nil {
+			p.logger.Err(err).Msg("encountered error listing github event polls")
+			return
+		}
+
+		for _, o := range owners {
+			if err = p.poll(ctx, o); err != nil {
+				p.logger.Err(err).Msgf("encountered error polling github events of %s", o.Name)
+			}
+		}
+	}
+	exec()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Info().Msg("stopping github events poller")
+			return
+		case <-time.After(interval):
+			exec()
+		}
+	}
+}
+
+// due registers the owners of the synced GitHub repos, and claims the ones due to be polled
+func (p *poller) due(ctx context.Context) (_ []*owner, err error) {
+	const register = `
+INSERT INTO mergestat.github_event_polls (provider, owner)
+SELECT DISTINCT r.provider, lower(split_part(regexp_replace(r.repo, '^https?://github\.com/', ''), '/', 1))
+    FROM public.repos r INNER JOIN mergestat.providers pr ON pr.id = r.provider
+    WHERE pr.vendor = 'github' AND r.repo ~ '^https?://github\.com/[^/]+/'
+ON CONFLICT DO NOTHING`
+
+	if _, err = p.pool.Exec(ctx, register); err != nil {
+		return nil, err
+	}
+
+	// polls are claimed (by pushing polled_at) so that the workers polling concurrently don't poll the same owner
+	const claim = `
+UPDATE mergestat.github_event_polls SET polled_at = now()
+WHERE (provider, owner) IN (
+    SELECT provider, owner FROM mergestat.github_event_polls
+        WHERE polled_at IS NULL OR polled_at + make_interval(secs => poll_interval) <= now()
+        FOR UPDATE SKIP LOCKED
+) RETURNING provider, owner, COALESCE(etag, ''), COALESCE(last_event_id, 0)`
+
+	var rows pgx.Rows
+	if rows, err = p.pool.Query(ctx, claim); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var owners []*owner
+	for rows.Next() {
+		var o owner
+		if err = rows.Scan(&o.Provider, &o.Name, &o.ETag, &o.LastEventID); err != nil {
+			return nil, err
+		}
+		owners = append(owners, &o)
+	}
+	return owners, rows.Err()
+}
+
+// poll fetches the events of the owner since the latest one handled, and enqueues the syncs they make stale
+func (p *poller) poll(ctx context.Context, o *owner) (err error) {
+	defer func() {
+		var lastError *string
+		if err != nil {
+			var message = err.Error()
+			lastError = &message
+		}
+		const update = `UPDATE mergestat.github_event_polls SET last_error = $3 WHERE provider = $1 AND owner = $2`
+		if _, updateErr := p.pool.Exec(context.Background(), update, o.Provider, o.Name, lastError); updateErr != nil {
+			p.logger.Err(updateErr).Msgf("failed to record github events poll of %s", o.Name)
+		}
+	}()
+
+	var token string
+	if _, token, err = p.db.FetchCredential(ctx, o.Provider); err != nil {
+		return err
+	}
+
+	var base = http.DefaultTransport
+	if p.transport != nil {
+		base = p.transport(o.Provider)
+	}
+	var client = github.NewClient(&http.Client{Transport: &oauth2.Transport{Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}), Base: base}})
+
+	var organization bool
+	if organization, err = p.isOrganization(ctx, client, o); err != nil {
+		return err
+	}
+
+	var events []*github.Event
+	var etag = o.ETag
+	var pollInterval int
+	if organization {
+		var path = fmt.Sprintf("users/%s/events/orgs/%s", p.logins[o.Provider], o.Name)
+		var notModified bool
+		if events, etag, pollInterval, notModified, err = p.fetch(ctx, client, path, o.ETag, o.LastEventID); err != nil {
+			return err
+		}
+		if notModified {
+			return p.record(ctx, o, o.ETag, o.LastEventID, 0)
+		}
+	} else {
+		var repos []string
+		if repos, err = p.repos(ctx, o); err != nil {
+			return err
+		}
+		for _, repo := range repos {
+			var batch []*github.Event
+			var repoETag string
+			var notModified bool
+			if batch, repoETag, pollInterval, notModified, err = p.fetch(ctx, client, fmt.Sprintf("repos/%s/events", repo), p.etags[repo], o.LastEventID); err != nil {
+				return fmt.Errorf("%s: %w", repo, err)
+			}
+			if !notModified {
+				p.etags[repo] = repoETag
+				events = append(events, batch...)
+			}
+		}
+	}
+
+	var latest = o.LastEventID
+	for _, e := range events {
+		if id, _ := strconv.ParseInt(e.GetID(), 10, 64); id > latest {
+			latest = id
+		}
+	}
+
+	var enqueued int
+	if o.LastEventID != 0 {
+		if enqueued, err = p.enqueue(ctx, o, events); err != nil {
+			return err
+		}
+	} else {
+		events = nil
+	}
+
+	if err = p.record(ctx, o, etag, latest, len(events)); err != nil {
+		return err
+	}
+
+	if pollInterval > 0 {
+		if _, err = p.pool.Exec(ctx, "UPDATE mergestat.github_event_polls SET poll_interval = $3 WHERE provider = $1 AND owner = $2", o.Provider, o.Name, pollInterval); err != nil {
+			return err
+		}
+	}
+
+	if enqueued > 0 {
+		p.logger.Info().Msgf("enqueued %d sync(s) for %d new github event(s) of %s", enqueued, len(events), o.Name)
+	}
+	return nil
+}
+
+// fetch fetches the events at the given path since the given one, a page of events at a time. It reports whether
+// nothing changed since the response with the given etag, and returns the etag and poll interval of the response.
+func (p *poller) fetch(ctx context.Context, client *github.Client, path, etag string, lastEventID int64) (events []*github.Event, _ string, pollInterval int, notModified bool, err error) {
+	var latestETag string
+	for page := 1; page <= maxPages; page++ {
+		var req *http.Request
+		if req, err = client.NewRequest(http.MethodGet, fmt.Sprintf("%s?per_page=100&page=%d", path, page), nil); err != nil {
+			return nil, "", 0, false, err
+		}
+		// a conditional request for the first page doesn't count against the rate limit if nothing changed
+		if page == 1 && etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		var batch []*github.Event
+		var resp *github.Response
+		resp, err = client.Do(ctx, req, &batch)
+		if resp != nil && resp.StatusCode == http.StatusNotModified {
+			return nil, etag, 0, true, nil
+		}
+		if err != nil {
+			return nil, "", 0, false, err
+		}
+
+		if page == 1 {
+			latestETag = resp.Header.Get("ETag")
+			pollInterval, _ = strconv.Atoi(resp.Header.Get("X-Poll-Interval"))
+		}
+
+		var seen bool
+		for _, e := range batch {
+			if id, _ := strconv.ParseInt(e.GetID(), 10, 64); id > lastEventID {
+				events = append(events, e)
+			} else {
+				seen = true
+			}
+		}
+
+		// the first poll only sets the starting point, there's no telling which of the older events were synced
+		if seen || lastEventID == 0 || resp.NextPage == 0 {
+			break
+		}
+	}
+	return events, latestETag, pollInterval, false, nil
+}
+
+// isOrganization reports whether the owner is an organization whose events the authenticated user receives (the
+// events of an organization are only listed to its members), loading the login of the authenticated user
+func (p *poller) isOrganization(ctx context.Context, client *github.Client, o *owner) (bool, error) {
+	var key = o.Provider.String() + "/" + o.Name
+	if organization, known := p.organizations[key]; known {
+		return organization, nil
+	}
+
+	if _, known := p.logins[o.Provider]; !known {
+		var user, _, err = client.Users.Get(ctx, "")
+		if err != nil {
+			return false, err
+		}
+		p.logins[o.Provider] = user.GetLogin()
+	}
+
+	var user, _, err = client.Users.Get(ctx, o.Name)
+	if err != nil {
+		return false, err
+	}
+
+	var organization = user.GetType() == "Organization"
+	if organization {
+		var member bool
+		if member, _, err = client.Organizations.IsMember(ctx, o.Name, p.logins[o.Provider]); err != nil {
+			return false, err
+		}
+		organization = member
+	}
+
+	p.organizations[key] = organization
+	return organization, nil
+}
+
+// repos returns the full names (owner/name) of the synced repos of the owner
+func (p *poller) repos(ctx context.Context, o *owner) (_ []string, err error) {
+	const query = `
+SELECT DISTINCT regexp_replace(regexp_replace(r.repo, '^https?://github\.com/', ''), '(\.git)?/*$', '') FROM public.repos r
+    WHERE r.provider = $1 AND lower(split_part(regexp_replace(r.repo, '^https?://github\.com/', ''), '/', 1)) = $2`
+
+	var rows pgx.Rows
+	if rows, err = p.pool.Query(ctx, query, o.Provider, o.Name); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var repos []string
+	for rows.Next() {
+		var repo string
+		if err = rows.Scan(&repo); err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	return repos, rows.Err()
+}
+
+// enqueue enqueues the syncs made stale by the events, once per repo and type of event, and returns the number of syncs enqueued
+func (p *poller) enqueue(ctx context.Context, o *owner, events []*github.Event) (int, error) {
+	type key struct{ repo, eventType string }
+	var seen = make(map[key]bool)
+
+	const enqueue = `
+SELECT COALESCE(SUM(mergestat.enqueue_github_event_syncs(r.id, $3)), 0)::INTEGER FROM public.repos r
+    WHERE r.provider = $1 AND lower(regexp_replace(r.repo, '^https?://github\.com/', '')) = lower($2)`
+
+	var total int
+	for _, e := range events {
+		var k = key{repo: strings.TrimSuffix(e.GetRepo().GetName(), ".git"), eventType: e.GetType()}
+		if k.repo == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		var enqueued int
+		if err := p.pool.QueryRow(ctx, enqueue, o.Provider, k.repo, k.eventType).Scan(&enqueued); err != nil {
+			return total, err
+		}
+		total += enqueued
+	}
+	return total, nil
+}
+
+// record saves the state of the poll of the owner: the etag of the response, the latest event handled and the number of events handled
+func (p *poller) record(ctx context.Context, o *owner, etag string, latest int64, events int) error {
+	const update = `
+UPDATE mergestat.github_event_polls SET etag = NULLIF($3, ''), last_event_id = NULLIF(GREATEST(COALESCE(last_event_id, 0), $4), 0), events_seen = events_seen + $5
+    WHERE provider = $1 AND owner = $2`
+
+	var _, err = p.pool.Exec(ctx, update, o.Provider, o.Name, etag, latest, events)
+	return err
+}
@@ -0,0 +1,200 @@
+// Package export implements exporting the synced data out of the database.
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/namespace"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Options configures an anonymized export (see Anonymized)
+type Options struct {
+	// Dir is the directory the export is written into, one csv file per table
+	Dir string
+
+	// Tables, if set, limits the export to the given tables (by name, even if relocated out of the public schema)
+	Tables []string
+
+	// Repos, if set, limits the export to the given repositories (by url, as stored in public.repos)
+	Repos []string
+
+	// Salt is prepended to every hashed value. Exports with the same salt hash equal values the same way.
+	Salt string
+
+	// Cipher decrypts the values of the encrypted columns (see mergestat.encrypted_columns) before they're anonymized,
+	// so that equal values hash the same way. It's required if any column is encrypted.
+	Cipher *helper.ColumnCipher
+}
+
+// Anonymized exports the synced tables (the tables with a repo_id column, in public or relocated, and public.repos)
+// as csv files, with identities (emails, names, urls...) hashed, free text (messages, file contents, patches...)
+// stripped and paths hashed segment by segment. Ids, commit hashes, timestamps and numbers are kept as-is, so the
+// structure of the data (and the relations between tables) is preserved, and any other column is hashed. See
+// helper.AnonymizeRuleFor for the rules by column.
+func Anonymized(ctx context.Context, pool *pgxpool.Pool, logger *zerolog.Logger, opts Options) (err error) {
+	const listTables = `
+SELECT c.table_name::TEXT FROM information_schema.columns c
+	JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+	WHERE c.table_schema = 'public' AND c.column_name = 'repo_id' AND t.table_type = 'BASE TABLE'
+UNION SELECT table_name FROM mergestat.synced_tables
+	ORDER BY 1`
+
+	// tables are listed (and exported) under their own name, wherever they live
+	var registry namespace.Registry
+	if registry, err = namespace.Load(ctx, pool); err != nil {
+		return errors.Wrapf(err, "failed to load relocated tables")
+	}
+
+	var encrypted helper.EncryptedColumns
+	if encrypted, err = helper.CollectEncryptedColumns(pool.Query(ctx, helper.ListEncryptedColumns)); err != nil {
+		return errors.Wrapf(err, "failed to list encrypted columns")
+	}
+
+	var tables []string
+	if len(opts.Tables) > 0 {
+		tables = opts.Tables
+	} else {
+		if tables, err = helper.CollectStrings(pool.Query(ctx, listTables)); err != nil {
+			return errors.Wrapf(err, "failed to list tables")
+		}
+		tables = append([]string{"repos"}, tables...)
+	}
+
+	var repos []string
+	if len(opts.Repos) > 0 {
+		if repos, err = helper.CollectStrings(pool.Query(ctx, "SELECT id::TEXT FROM public.repos WHERE repo = ANY($1)", opts.Repos)); err != nil {
+			return errors.Wrapf(err, "failed to fetch repositories")
+		}
+
+		if len(repos) != len(opts.Repos) {
+			return errors.Errorf("found %d of the %d repositories, make sure they're added to mergestat first", len(repos), len(opts.Repos))
+		}
+	}
+
+	if err = os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		var count int64
+		if count, err = exportTable(ctx, pool, registry.Table(table), table, repos, encrypted, opts); err != nil {
+			return errors.Wrapf(err, "failed to export %s", table)
+		}
+		logger.Info().Str("table", table).Int64("rows", count).Msgf("exported %d row(s) of %s", count, table)
+	}
+
+	return nil
+}
+
+// exportTable writes the (anonymized) rows of the table (where it lives), for the given repos (or for all repos if
+// none are given), into <dir>/<table>.csv and returns the number of rows written
+func exportTable(ctx context.Context, pool *pgxpool.Pool, id pgx.Identifier, table string, repos []string, encrypted helper.EncryptedColumns, opts Options) (_ int64, err error) {
+	var column = "repo_id"
+	if table == "repos" {
+		column = "id"
+	}
+
+	var query = "SELECT * FROM " + id.Sanitize()
+	var args []interface{}
+	if len(repos) > 0 {
+		query += " WHERE " + pgx.Identifier{column}.Sanitize() + "::TEXT = ANY($1)"
+		args = append(args, repos)
+	}
+
+	var file *os.File
+	if file, err = os.Create(filepath.Join(opts.Dir, table+".csv")); err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, query, args...); err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var fields = rows.FieldDescriptions()
+	var header = make([]string, len(fields))
+	var rules = make([]helper.AnonymizeRule, len(fields))
+	for i, field := range fields {
+		header[i] = string(field.Name)
+		rules[i] = helper.AnonymizeRuleFor(header[i], field.DataTypeOID)
+	}
+
+	var w = csv.NewWriter(file)
+	if err = w.Write(header); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for rows.Next() {
+		var values []interface{}
+		if values, err = rows.Values(); err != nil {
+			return 0, err
+		}
+
+		var record = make([]string, len(values))
+		for i, value := range values {
+			if record[i], err = format(value); err != nil {
+				return 0, errors.Wrapf(err, "column %s", header[i])
+			}
+			if record[i], err = encrypted.Decrypt(opts.Cipher, table, header[i], record[i]); err != nil {
+				return 0, errors.Wrapf(err, "column %s", header[i])
+			}
+			record[i] = helper.AnonymizeValue(rules[i], record[i], opts.Salt)
+		}
+
+		if err = w.Write(record); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return 0, err
+	}
+
+	return count, file.Close()
+}
+
+// format returns the text representation of a value read from the database. Json values are dropped,
+// as there's no telling what they contain.
+func format(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case [16]uint8:
+		return uuid.UUID(v).String(), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case map[string]interface{}, []interface{}:
+		return "", nil
+	case driver.Valuer:
+		var dv, err = v.Value()
+		if err != nil || dv == nil {
+			return "", err
+		}
+		return format(dv)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
@@ -0,0 +1,236 @@
+// Package fakegithub is a deterministic, in-process fake of the subset of the GitHub REST and GraphQL apis
+// the syncs use, for testing them (and validating new providers) against recorded responses.
+//
+// Responses are registered by method and path (see Server.Handle), and served in pages. Every response carries
+// the X-RateLimit-* headers of a simulated rate limit (see Server.SetRateLimit): once it's exhausted, requests
+// fail the way GitHub fails them until the limit resets. Clients are pointed at the fake with Server.Transport,
+// which redirects the requests made to api.github.com.
+package fakegithub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// RateLimit is the simulated rate limit of the api
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// route are the pages of the response of a method and path, or the error it fails with
+type route struct {
+	pages   []json.RawMessage
+	status  int
+	message string
+}
+
+// graphQLFixture is the data of the response to the GraphQL queries that contain a string
+type graphQLFixture struct {
+	contains string
+	data     json.RawMessage
+}
+
+// Server is a fake GitHub api server
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	routes  map[string]*route
+	graphQL []graphQLFixture
+	rate    RateLimit
+	calls   []string
+}
+
+// New starts a fake GitHub api server, with an (effectively) unlimited rate limit, that's closed once the test completes
+func New(t testing.TB) *Server {
+	var s = &Server{
+		routes: make(map[string]*route),
+		rate:   RateLimit{Limit: 5000, Remaining: 5000, Reset: time.Now().Add(time.Hour)},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Handle registers the response of a method and path (eg. GET /repos/mergestat/mergestat/issues). Each of the
+// given bodies (encoded as json) is a page of the response: the page query parameter (or the after cursor) selects
+// it, and every page but the last links to the next one.
+func (s *Server) Handle(method, path string, pages ...interface{}) {
+	var r = &route{status: http.StatusOK}
+	for _, page := range pages {
+		var encoded, err = json.Marshal(page)
+		if err != nil {
+			panic(fmt.Sprintf("fakegithub: could not encode page of %s %s: %v", method, path, err))
+		}
+		r.pages = append(r.pages, encoded)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[method+" "+path] = r
+}
+
+// Fail registers the error a method and path fails with (eg. a 403 for an endpoint the token has no access to)
+func (s *Server) Fail(method, path string, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[method+" "+path] = &route{status: status, message: message}
+}
+
+// GraphQL registers the data of the response to the GraphQL queries that contain the given string
+// (eg. the name of a field only the query of one sync selects). The first matching fixture is served.
+func (s *Server) GraphQL(contains string, data interface{}) {
+	var encoded, err = json.Marshal(data)
+	if err != nil {
+		panic(fmt.Sprintf("fakegithub: could not encode graphql data: %v", err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.graphQL = append(s.graphQL, graphQLFixture{contains: contains, data: encoded})
+}
+
+// SetRateLimit sets the simulated rate limit, which every request (including the failing ones) uses up
+func (s *Server) SetRateLimit(rate RateLimit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rate = rate
+}
+
+// Calls returns the requests the server received, as method and path (eg. GET /repos/mergestat/mergestat/issues?page=2)
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+// Transport returns an http.RoundTripper that sends the requests made to api.github.com to the fake
+func (s *Server) Transport() http.RoundTripper {
+	var target, _ = url.Parse(s.URL)
+	return &redirect{target: target, base: http.DefaultTransport}
+}
+
+func (s *Server) serve(rw http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, req.Method+" "+req.URL.RequestURI())
+
+	if !time.Now().Before(s.rate.Reset) {
+		s.rate.Remaining, s.rate.Reset = s.rate.Limit, time.Now().Add(time.Hour)
+	}
+
+	var exhausted = s.rate.Remaining <= 0
+	if !exhausted {
+		s.rate.Remaining--
+	}
+
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.rate.Limit))
+	rw.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.rate.Remaining))
+	rw.Header().Set("X-RateLimit-Used", strconv.Itoa(s.rate.Limit-s.rate.Remaining))
+	rw.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.rate.Reset.Unix(), 10))
+	rw.Header().Set("X-RateLimit-Resource", "core")
+
+	if exhausted {
+		writeError(rw, http.StatusForbidden, "API rate limit exceeded for user. (But here's the good news: Authenticated requests get a higher rate limit.)")
+		return
+	}
+
+	if req.Method == http.MethodPost && req.URL.Path == "/graphql" {
+		s.serveGraphQL(rw, req)
+		return
+	}
+
+	var r, ok = s.routes[req.Method+" "+req.URL.Path]
+	if !ok {
+		writeError(rw, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	if r.status >= 300 {
+		writeError(rw, r.status, r.message)
+		return
+	}
+
+	var page = 1
+	for _, param := range []string{"page", "after"} {
+		if n, err := strconv.Atoi(req.URL.Query().Get(param)); err == nil && n > 0 {
+			page = n
+		}
+	}
+
+	if page > len(r.pages) {
+		_, _ = rw.Write([]byte("[]"))
+		return
+	}
+
+	if page < len(r.pages) {
+		rw.Header().Set("Link", link(req, page+1, "next")+", "+link(req, len(r.pages), "last"))
+	}
+	_, _ = rw.Write(r.pages[page-1])
+}
+
+func (s *Server) serveGraphQL(rw http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(rw, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+
+	for _, fixture := range s.graphQL {
+		if strings.Contains(body.Query, fixture.contains) {
+			_, _ = fmt.Fprintf(rw, `{"data":%s}`, fixture.data)
+			return
+		}
+	}
+
+	// like GitHub, GraphQL errors are reported with a 200
+	var encoded, _ = json.Marshal(map[string]interface{}{"errors": []map[string]string{{"message": "fakegithub: no fixture matches the query"}}})
+	_, _ = rw.Write(encoded)
+}
+
+// link returns the Link header entry of a page of the request, with both a page number and an after cursor
+// (as the endpoints paginated with cursors, eg. the audit log, do)
+func link(req *http.Request, page int, rel string) string {
+	var u = url.URL{Scheme: "https", Host: "api.github.com", Path: req.URL.Path}
+	var query = req.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("after", strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+}
+
+func writeError(rw http.ResponseWriter, status int, message string) {
+	rw.WriteHeader(status)
+	var encoded, _ = json.Marshal(map[string]string{"message": message, "documentation_url": "https://docs.github.com/rest"})
+	_, _ = rw.Write(encoded)
+}
+
+// redirect is an http.RoundTripper that sends the requests made to api.github.com to the target
+type redirect struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (r *redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != "api.github.com" {
+		return r.base.RoundTrip(req)
+	}
+
+	var redirected = req.Clone(req.Context())
+	redirected.URL.Scheme, redirected.URL.Host, redirected.Host = r.target.Scheme, r.target.Host, r.target.Host
+	return r.base.RoundTrip(redirected)
+}
@@ -0,0 +1,309 @@
+// Package grafana implements the canonical Grafana dashboards of MergeStat (queue health, sync freshness and git
+// metrics), bound to the schema of the database, and their provisioning files, so that new installs get observability
+// and analytics out of the box.
+package grafana
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// DefaultDatasource is the uid of the Postgres datasource of the provisioning files shipped in scripts/grafana
+const DefaultDatasource = "SXQZgpP7z"
+
+// Dashboard is a Grafana dashboard (see https://grafana.com/docs/grafana/latest/dashboards/build-dashboards/view-dashboard-json-model/)
+type Dashboard struct {
+	UID           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description,omitempty"`
+	Tags          []string   `json:"tags"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Editable      bool       `json:"editable"`
+	Refresh       string     `json:"refresh,omitempty"`
+	Time          timeRange  `json:"time"`
+	Templating    templating `json:"templating"`
+	Panels        []*panel   `json:"panels"`
+}
+
+type timeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type templating struct {
+	List []variable `json:"list"`
+}
+
+type variable struct {
+	Name       string      `json:"name"`
+	Label      string      `json:"label"`
+	Type       string      `json:"type"`
+	Datasource *datasource `json:"datasource"`
+	Query      string      `json:"query"`
+	Definition string      `json:"definition"`
+	Multi      bool        `json:"multi"`
+	IncludeAll bool        `json:"includeAll"`
+	Refresh    int         `json:"refresh"`
+	Current    interface{} `json:"current"`
+}
+
+type datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type gridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type target struct {
+	RefID      string      `json:"refId"`
+	Datasource *datasource `json:"datasource"`
+	Format     string      `json:"format"`
+	RawQuery   bool        `json:"rawQuery"`
+	RawSQL     string      `json:"rawSql"`
+}
+
+type panel struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	GridPos     gridPos                `json:"gridPos"`
+	Datasource  *datasource            `json:"datasource"`
+	Targets     []target               `json:"targets"`
+	FieldConfig map[string]interface{} `json:"fieldConfig"`
+	Options     map[string]interface{} `json:"options,omitempty"`
+}
+
+// builder lays out the panels of a dashboard left to right, in rows of 24 columns
+type builder struct {
+	ds        *datasource
+	panels    []*panel
+	x, y, row int
+}
+
+// add appends a panel of the given type, width and height, whose only query is sql
+func (b *builder) add(kind, title, description, sql string, w, h int, unit string) {
+	if b.x+w > 24 {
+		b.x, b.y, b.row = 0, b.y+b.row, 0
+	}
+
+	var format = "table"
+	if kind == "timeseries" {
+		format = "time_series"
+	}
+
+	var p = &panel{
+		ID: len(b.panels) + 1, Type: kind, Title: title, Description: description,
+		GridPos:     gridPos{H: h, W: w, X: b.x, Y: b.y},
+		Datasource:  b.ds,
+		Targets:     []target{{RefID: "A", Datasource: b.ds, Format: format, RawQuery: true, RawSQL: sql}},
+		FieldConfig: map[string]interface{}{"defaults": map[string]interface{}{}, "overrides": []interface{}{}},
+	}
+	if unit != "" {
+		p.FieldConfig["defaults"] = map[string]interface{}{"unit": unit}
+	}
+	if kind == "stat" {
+		p.Options = map[string]interface{}{"reduceOptions": map[string]interface{}{"calcs": []string{"lastNotNull"}, "fields": "", "values": false}}
+	}
+
+	b.panels = append(b.panels, p)
+	b.x += w
+	if h > b.row {
+		b.row = h
+	}
+}
+
+// Dashboards returns the canonical dashboards, querying the Postgres datasource of the given uid
+func Dashboards(datasourceUID string) []*Dashboard {
+	var ds = &datasource{Type: "postgres", UID: datasourceUID}
+	return []*Dashboard{queueHealth(ds), syncFreshness(ds), gitMetrics(ds)}
+}
+
+func dashboard(uid, title, description, from string, b *builder) *Dashboard {
+	return &Dashboard{
+		UID: uid, Title: title, Description: description,
+		Tags:          []string{"mergestat"},
+		SchemaVersion: 36,
+		Editable:      true,
+		Refresh:       "5m",
+		Time:          timeRange{From: from, To: "now"},
+		Templating:    templating{List: []variable{}},
+		Panels:        b.panels,
+	}
+}
+
+func queueHealth(ds *datasource) *Dashboard {
+	var b = &builder{ds: ds}
+
+	b.add("stat", "Queued", "jobs waiting in the queue",
+		`SELECT COUNT(*) AS queued FROM mergestat.repo_sync_queue WHERE status = 'QUEUED'`, 6, 4, "")
+	b.add("stat", "Running", "jobs being run by a worker",
+		`SELECT COUNT(*) AS running FROM mergestat.repo_sync_queue WHERE status = 'RUNNING'`, 6, 4, "")
+	b.add("stat", "Failed", "jobs done with errors, over the time range",
+		`SELECT COUNT(*) AS failed FROM mergestat.repo_sync_queue q
+WHERE q.status = 'DONE' AND $__timeFilter(q.done_at) AND mergestat.repo_sync_queue_has_error(q)`, 6, 4, "")
+	b.add("stat", "Oldest queued job", "time the oldest queued job has been waiting for",
+		`SELECT COALESCE(EXTRACT(EPOCH FROM now() - MIN(created_at)), 0) AS waiting FROM mergestat.repo_sync_queue WHERE status = 'QUEUED'`, 6, 4, "s")
+
+	b.add("timeseries", "Jobs done", "jobs done per hour, with and without errors",
+		`SELECT date_trunc('hour', done_at) AS time, COUNT(*) FILTER (WHERE NOT has_error) AS succeeded, COUNT(*) FILTER (WHERE has_error) AS failed
+FROM (
+    SELECT q.done_at, mergestat.repo_sync_queue_has_error(q) AS has_error FROM mergestat.repo_sync_queue q
+    WHERE q.status = 'DONE' AND $__timeFilter(q.done_at)
+) jobs
+GROUP BY 1 ORDER BY 1`, 12, 8, "")
+	b.add("timeseries", "Wait and run times", "median time jobs waited in the queue, and ran for, per hour",
+		`SELECT date_trunc('hour', done_at) AS time,
+    percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM started_at - created_at)) AS waited,
+    percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM done_at - started_at)) AS ran
+FROM mergestat.repo_sync_queue
+WHERE status = 'DONE' AND $__timeFilter(done_at)
+GROUP BY 1 ORDER BY 1`, 12, 8, "s")
+
+	b.add("table", "Failures by sync type", "jobs done with errors over the time range, by sync type",
+		`SELECT rs.sync_type AS "sync type", COUNT(*) AS failures, MAX(q.done_at) AS "last failure"
+FROM mergestat.repo_sync_queue q
+INNER JOIN mergestat.repo_syncs rs ON rs.id = q.repo_sync_id
+WHERE q.status = 'DONE' AND $__timeFilter(q.done_at) AND mergestat.repo_sync_queue_has_error(q)
+GROUP BY 1 ORDER BY 2 DESC`, 12, 8, "")
+	b.add("table", "Running jobs", "jobs being run, the longest running first",
+		`SELECT r.repo, rs.sync_type AS "sync type", q.started_at AS "started at", EXTRACT(EPOCH FROM now() - q.started_at) AS "running for"
+FROM mergestat.repo_sync_queue q
+INNER JOIN mergestat.repo_syncs rs ON rs.id = q.repo_sync_id
+INNER JOIN public.repos r ON r.id = rs.repo_id
+WHERE q.status = 'RUNNING'
+ORDER BY q.started_at LIMIT 100`, 12, 8, "")
+
+	return dashboard("mergestat-queue-health", "MergeStat / Queue health", "health of the queue of sync jobs", "now-24h", b)
+}
+
+func syncFreshness(ds *datasource) *Dashboard {
+	var b = &builder{ds: ds}
+
+	const lastSynced = `
+FROM mergestat.repo_syncs rs
+LEFT JOIN mergestat.repo_sync_queue q ON q.id = rs.last_completed_repo_sync_queue_id
+WHERE rs.schedule_enabled`
+
+	b.add("stat", "Scheduled syncs", "syncs enabled in the schedule",
+		`SELECT COUNT(*) AS syncs`+lastSynced, 6, 4, "")
+	b.add("stat", "Never synced", "scheduled syncs that never completed",
+		`SELECT COUNT(*) FILTER (WHERE q.done_at IS NULL) AS never`+lastSynced, 6, 4, "")
+	b.add("stat", "Synced in the last day", "share of the scheduled syncs completed in the last 24 hours",
+		`SELECT COUNT(*) FILTER (WHERE q.done_at > now() - INTERVAL '1 day')::NUMERIC / NULLIF(COUNT(*), 0) AS fresh`+lastSynced, 6, 4, "percentunit")
+	b.add("stat", "Median age", "median time since the scheduled syncs last completed",
+		`SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM now() - q.done_at)) AS age`+lastSynced, 6, 4, "s")
+
+	b.add("timeseries", "Syncs completed", "syncs completed per day",
+		`SELECT date_trunc('day', done_at) AS time, COUNT(*) AS completed FROM mergestat.repo_sync_queue
+WHERE status = 'DONE' AND $__timeFilter(done_at)
+GROUP BY 1 ORDER BY 1`, 24, 8, "")
+
+	b.add("table", "Freshness by sync type", "age of the data of the scheduled syncs, by sync type",
+		`SELECT rs.sync_type AS "sync type", COUNT(*) AS syncs, COUNT(*) FILTER (WHERE q.done_at IS NULL) AS "never synced",
+    percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM now() - q.done_at)) AS "median age",
+    EXTRACT(EPOCH FROM now() - MIN(q.done_at)) AS "max age"`+lastSynced+`
+GROUP BY 1 ORDER BY 4 DESC NULLS FIRST`, 12, 10, "s")
+	b.add("table", "Stalest syncs", "scheduled syncs whose data is the oldest (never synced first)",
+		`SELECT r.repo, rs.sync_type AS "sync type", q.done_at AS "last synced at", EXTRACT(EPOCH FROM now() - q.done_at) AS age
+FROM mergestat.repo_syncs rs
+INNER JOIN public.repos r ON r.id = rs.repo_id
+LEFT JOIN mergestat.repo_sync_queue q ON q.id = rs.last_completed_repo_sync_queue_id
+WHERE rs.schedule_enabled
+ORDER BY q.done_at NULLS FIRST LIMIT 100`, 12, 10, "")
+
+	return dashboard("mergestat-sync-freshness", "MergeStat / Sync freshness", "age of the synced data, by sync", "now-7d", b)
+}
+
+// gitMetrics references the synced tables by their own name, so that they resolve through the search_path of the
+// datasource when relocated (see namespace.SearchPath)
+func gitMetrics(ds *datasource) *Dashboard {
+	var b = &builder{ds: ds}
+
+	const inRepos = `repo_id::TEXT IN ($repo)`
+
+	b.add("stat", "Commits", "commits authored over the time range (merge commits excluded)",
+		`SELECT COUNT(*) AS commits FROM git_commits WHERE $__timeFilter(author_when) AND parents < 2 AND `+inRepos, 6, 4, "")
+	b.add("stat", "Authors", "distinct authors over the time range",
+		`SELECT COUNT(DISTINCT author_email) AS authors FROM git_commits WHERE $__timeFilter(author_when) AND parents < 2 AND `+inRepos, 6, 4, "")
+	b.add("stat", "Pull requests merged", "pull requests merged over the time range",
+		`SELECT COUNT(*) AS merged FROM github_pull_requests WHERE merged AND $__timeFilter(merged_at) AND `+inRepos, 6, 4, "")
+	b.add("stat", "Median time to merge", "median time pull requests merged over the time range were open for",
+		`SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM merged_at - created_at)) AS "time to merge"
+FROM github_pull_requests WHERE merged AND $__timeFilter(merged_at) AND `+inRepos, 6, 4, "s")
+
+	b.add("timeseries", "Commits per week", "commits and distinct authors per week",
+		`SELECT date_trunc('week', author_when) AS time, COUNT(*) AS commits, COUNT(DISTINCT author_email) AS authors
+FROM git_commits WHERE $__timeFilter(author_when) AND parents < 2 AND `+inRepos+`
+GROUP BY 1 ORDER BY 1`, 12, 8, "")
+	b.add("timeseries", "Pull requests per week", "pull requests opened and merged per week",
+		`SELECT week AS time, SUM(opened) AS opened, SUM(merged) AS merged FROM (
+    SELECT date_trunc('week', created_at) AS week, 1 AS opened, 0 AS merged FROM github_pull_requests WHERE $__timeFilter(created_at) AND `+inRepos+`
+    UNION ALL
+    SELECT date_trunc('week', merged_at), 0, 1 FROM github_pull_requests WHERE merged AND $__timeFilter(merged_at) AND `+inRepos+`
+) prs
+GROUP BY 1 ORDER BY 1`, 12, 8, "")
+
+	b.add("table", "Top authors", "authors with the most commits over the time range",
+		`SELECT author_name AS author, author_email AS email, COUNT(*) AS commits, COUNT(DISTINCT repo_id) AS repos, MAX(author_when) AS "last commit"
+FROM git_commits WHERE $__timeFilter(author_when) AND parents < 2 AND `+inRepos+`
+GROUP BY 1, 2 ORDER BY 3 DESC LIMIT 50`, 12, 10, "")
+	b.add("table", "Most active repos", "repos with the most commits over the time range",
+		`SELECT r.repo, COUNT(*) AS commits, COUNT(DISTINCT c.author_email) AS authors, MAX(c.author_when) AS "last commit"
+FROM git_commits c INNER JOIN public.repos r ON r.id = c.repo_id
+WHERE $__timeFilter(c.author_when) AND c.parents < 2 AND c.`+inRepos+`
+GROUP BY 1 ORDER BY 2 DESC LIMIT 50`, 12, 10, "")
+
+	var d = dashboard("mergestat-git-metrics", "MergeStat / Git metrics", "activity of the synced repos", "now-1y", b)
+	d.Templating.List = append(d.Templating.List, variable{
+		Name: "repo", Label: "Repo", Type: "query", Datasource: ds,
+		Query:      `SELECT id::TEXT AS __value, repo AS __text FROM public.repos ORDER BY repo`,
+		Definition: `SELECT id::TEXT AS __value, repo AS __text FROM public.repos ORDER BY repo`,
+		Multi:      true, IncludeAll: true, Refresh: 1,
+		Current: map[string]interface{}{"selected": true, "text": []string{"All"}, "value": []string{"$__all"}},
+	})
+	return d
+}
+
+// Provision writes the dashboards, and the dashboard provider loading them, into the provisioning directory of
+// Grafana (eg. /etc/grafana/provisioning): the provider to dashboards/mergestat.yaml, and the dashboards to
+// dashboards/mergestat/<uid>.json, the path the provider loads them from being dashboardsPath.
+func Provision(dir, datasourceUID, dashboardsPath string) (err error) {
+	var providers = filepath.Join(dir, "dashboards")
+	var dashboards = filepath.Join(providers, "mergestat")
+	if err = os.MkdirAll(dashboards, 0o755); err != nil {
+		return err
+	}
+
+	for _, d := range Dashboards(datasourceUID) {
+		var content []byte
+		if content, err = json.MarshalIndent(d, "", "  "); err != nil {
+			return err
+		}
+		if err = os.WriteFile(filepath.Join(dashboards, d.UID+".json"), content, 0o644); err != nil {
+			return err
+		}
+	}
+
+	if dashboardsPath == "" {
+		dashboardsPath = dashboards
+	}
+
+	var provider = `apiVersion: 1
+
+providers:
+  - name: 'MergeStat (canonical)'
+    type: file
+    folder: 'MergeStat'
+    allowUiUpdates: true
+    options:
+      path: ` + dashboardsPath + `
+`
+	return os.WriteFile(filepath.Join(providers, "mergestat.yaml"), []byte(provider), 0o644)
+}
@@ -0,0 +1,27 @@
+package helper
+
+import "strings"
+
+// runnerMinuteMultipliers are the rates GitHub bills the minutes of its hosted runners at, by operating system
+// (as reported by the workflow run timing api), relative to linux runners
+var runnerMinuteMultipliers = map[string]int64{
+	"UBUNTU":  1,
+	"WINDOWS": 2,
+	"MACOS":   10,
+}
+
+// BillableMinutes returns the minutes GitHub bills a job of the given duration that ran on a hosted runner of
+// the operating system for: each job is rounded up to the whole minute, then multiplied by the rate of the
+// operating system. Unknown operating systems are billed at the linux rate.
+func BillableMinutes(runnerOS string, durationMS int64) int64 {
+	if durationMS <= 0 {
+		return 0
+	}
+
+	var multiplier, ok = runnerMinuteMultipliers[strings.ToUpper(runnerOS)]
+	if !ok {
+		multiplier = 1
+	}
+
+	return (durationMS + 59_999) / 60_000 * multiplier
+}
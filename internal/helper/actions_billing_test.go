@@ -0,0 +1,29 @@
+package helper
+
+import "testing"
+
+func TestBillableMinutes(t *testing.T) {
+	type testArgs struct {
+		description string
+		runnerOS    string
+		durationMS  int64
+		want        int64
+	}
+
+	tests := []testArgs{
+		{description: "no duration", runnerOS: "UBUNTU", durationMS: 0, want: 0},
+		{description: "rounded up to the minute", runnerOS: "UBUNTU", durationMS: 1, want: 1},
+		{description: "exact minutes", runnerOS: "UBUNTU", durationMS: 120_000, want: 2},
+		{description: "windows rate", runnerOS: "WINDOWS", durationMS: 61_000, want: 4},
+		{description: "macos rate", runnerOS: "macos", durationMS: 30_000, want: 10},
+		{description: "unknown os at linux rate", runnerOS: "SOLARIS", durationMS: 90_000, want: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if got := BillableMinutes(test.runnerOS, test.durationMS); got != test.want {
+				t.Fatalf("expected %d, got %d", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+
+	"github.com/jackc/pgtype"
+)
+
+// AnonymizeRule is how the values of a column are treated in an anonymized export
+type AnonymizeRule int
+
+const (
+	// AnonymizeKeep exports the values as-is
+	AnonymizeKeep AnonymizeRule = iota
+
+	// AnonymizeHash replaces the values with a salted hash, so that equal values remain equal
+	AnonymizeHash
+
+	// AnonymizePath hashes each segment of a path, preserving its depth and file extension
+	AnonymizePath
+
+	// AnonymizeStrip removes the values
+	AnonymizeStrip
+)
+
+var (
+	// columns holding free text (messages, descriptions, file contents, patches, code...) or derived from it
+	stripColumns = []string{"message", "body", "title", "contents", "description", "log", "subject", "line", "text", "summary",
+		"notes", "changelog", "patch", "signature", "embedding", "entry", "prompt"}
+
+	// columns holding file paths
+	pathColumns = []string{"path", "file_path", "directory", "filename", "dir"}
+
+	// columns holding identities, urls and names
+	hashColumns = []string{"email", "name", "login", "author", "committer", "user", "username", "owner", "repo", "url", "ref", "branch", "tag", "node_id", "source", "image", "repository"}
+
+	// text columns known to only hold commit hashes, or values of a small set (types, states...), that are kept as-is
+	keepColumns = []string{"hash", "sha", "type", "kind", "status", "state", "conclusion", "severity", "language", "extension", "mode", "provider", "model"}
+
+	// keepTypes are the (oids of the) types whose values are kept as-is: numbers, booleans, timestamps and ids
+	keepTypes = map[uint32]bool{
+		pgtype.Int2OID: true, pgtype.Int4OID: true, pgtype.Int8OID: true, pgtype.Float4OID: true, pgtype.Float8OID: true,
+		pgtype.NumericOID: true, pgtype.BoolOID: true, pgtype.DateOID: true, pgtype.TimestampOID: true,
+		pgtype.TimestamptzOID: true, pgtype.IntervalOID: true, pgtype.UUIDOID: true, pgtype.OIDOID: true,
+	}
+)
+
+// matchesColumn reports whether the column is one of the names, or ends in _<name>
+func matchesColumn(column string, names []string) bool {
+	for _, name := range names {
+		if column == name || strings.HasSuffix(column, "_"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnonymizeRuleFor returns how the values of the column, given its name and the oid of its type, are treated in an
+// anonymized export. It's an allowlist: numbers, timestamps, ids and the text columns known to be safe are kept,
+// free text is stripped, paths and identities are hashed, and so is every other (text, array, json...) column.
+func AnonymizeRuleFor(column string, typeOID uint32) AnonymizeRule {
+	column = strings.ToLower(column)
+	switch {
+	case keepTypes[typeOID]:
+		return AnonymizeKeep
+	case matchesColumn(column, stripColumns):
+		return AnonymizeStrip
+	case matchesColumn(column, pathColumns):
+		return AnonymizePath
+	case matchesColumn(column, hashColumns):
+		return AnonymizeHash
+	case matchesColumn(column, keepColumns) && (typeOID == pgtype.TextOID || typeOID == pgtype.VarcharOID):
+		return AnonymizeKeep
+	default:
+		return AnonymizeHash
+	}
+}
+
+// anonymizeToken returns the first n hex characters of the salted hash of the value
+func anonymizeToken(value, salt string, n int) string {
+	var sum = sha256.Sum256([]byte(salt + value))
+	return hex.EncodeToString(sum[:])[:n]
+}
+
+// AnonymizeValue applies the rule to the value. Hashed emails remain (syntactically) emails
+// and hashed paths keep their depth and file extensions.
+func AnonymizeValue(rule AnonymizeRule, value, salt string) string {
+	if value == "" {
+		return value
+	}
+
+	switch rule {
+	case AnonymizeStrip:
+		return ""
+	case AnonymizeHash:
+		if local, domain, found := strings.Cut(value, "@"); found && local != "" && domain != "" && !strings.ContainsAny(value, " /:") {
+			return anonymizeToken(strings.ToLower(value), salt, 16) + "@anonymized.invalid"
+		}
+		return anonymizeToken(value, salt, 16)
+	case AnonymizePath:
+		var segments = strings.Split(value, "/")
+		for i, segment := range segments {
+			if segment == "" || segment == "." || segment == ".." {
+				continue
+			}
+
+			var ext = path.Ext(segment)
+			if ext == segment || len(ext) > 10 {
+				ext = ""
+			}
+			segments[i] = anonymizeToken(segment, salt, 12) + ext
+		}
+		return strings.Join(segments, "/")
+	default:
+		return value
+	}
+}
@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgtype"
+)
+
+func TestAnonymizeRuleFor(t *testing.T) {
+	var tests = []struct {
+		column string
+		oid    uint32
+		want   AnonymizeRule
+	}{
+		{"hash", pgtype.TextOID, AnonymizeKeep},
+		{"additions", pgtype.Int4OID, AnonymizeKeep},
+		{"author_when", pgtype.TimestamptzOID, AnonymizeKeep},
+		{"line", pgtype.Int4OID, AnonymizeKeep},
+		{"author_email", pgtype.TextOID, AnonymizeHash},
+		{"committer_name", pgtype.TextOID, AnonymizeHash},
+		{"repo", pgtype.TextOID, AnonymizeHash},
+		{"html_url", pgtype.TextOID, AnonymizeHash},
+		{"message", pgtype.TextOID, AnonymizeStrip},
+		{"contents", pgtype.TextOID, AnonymizeStrip},
+		{"line", pgtype.TextOID, AnonymizeStrip},
+		{"patch", pgtype.TextOID, AnonymizeStrip},
+		{"signature", pgtype.TextOID, AnonymizeStrip},
+		{"path", pgtype.TextOID, AnonymizePath},
+		{"file_path", pgtype.TextOID, AnonymizePath},
+
+		// columns that aren't known to be safe are hashed
+		{"authors", pgtype.TextArrayOID, AnonymizeHash},
+		{"key", pgtype.TextOID, AnonymizeHash},
+		{"hash", pgtype.TextArrayOID, AnonymizeHash},
+		{"unknown", 0, AnonymizeHash},
+	}
+
+	for _, test := range tests {
+		if got := AnonymizeRuleFor(test.column, test.oid); got != test.want {
+			t.Fatalf("%s (%d): expected rule %d, got %d", test.column, test.oid, test.want, got)
+		}
+	}
+}
+
+func TestAnonymizeValue(t *testing.T) {
+	var email = AnonymizeValue(AnonymizeHash, "Jane@Example.com", "salt")
+	if !regexp.MustCompile(`^[0-9a-f]{16}@anonymized\.invalid$`).MatchString(email) {
+		t.Fatalf("unexpected hashed email %q", email)
+	}
+
+	if email != AnonymizeValue(AnonymizeHash, "jane@example.com", "salt") {
+		t.Fatalf("expected emails to be hashed case insensitively")
+	}
+
+	if AnonymizeValue(AnonymizeHash, "jane", "salt") == AnonymizeValue(AnonymizeHash, "jane", "pepper") {
+		t.Fatalf("expected hashes to depend on the salt")
+	}
+
+	var p = AnonymizeValue(AnonymizePath, "internal/syncer/git_blame.go", "salt")
+	if segments := strings.Split(p, "/"); len(segments) != 3 || !strings.HasSuffix(segments[2], ".go") || strings.Contains(p, "syncer") {
+		t.Fatalf("unexpected anonymized path %q", p)
+	}
+
+	if got := AnonymizeValue(AnonymizeStrip, "fix: secret project", "salt"); got != "" {
+		t.Fatalf("expected stripped value, got %q", got)
+	}
+
+	if got := AnonymizeValue(AnonymizeKeep, "3", "salt"); got != "3" {
+		t.Fatalf("expected value to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,39 @@
+package helper
+
+import "sort"
+
+// BusFactor returns the smallest set of authors that together account for at least coverage percent (0 to 100)
+// of the given contributions (eg. lines or commits, keyed by author), largest contributors first.
+// Ties are broken by author, so the result is stable.
+func BusFactor(contributions map[string]int64, coverage float64) []string {
+	var total int64
+	var authors = make([]string, 0, len(contributions))
+	for author, n := range contributions {
+		if n <= 0 {
+			continue
+		}
+		total += n
+		authors = append(authors, author)
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	sort.Slice(authors, func(i, j int) bool {
+		if a, b := contributions[authors[i]], contributions[authors[j]]; a != b {
+			return a > b
+		}
+		return authors[i] < authors[j]
+	})
+
+	var covered int64
+	for i, author := range authors {
+		covered += contributions[author]
+		if float64(covered)*100 >= coverage*float64(total) {
+			return authors[:i+1]
+		}
+	}
+
+	return authors
+}
@@ -0,0 +1,56 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBusFactor(t *testing.T) {
+	type testArgs struct {
+		description   string
+		contributions map[string]int64
+		coverage      float64
+		want          []string
+	}
+
+	tests := []testArgs{
+		{
+			description:   "no contributions",
+			contributions: map[string]int64{},
+			coverage:      50,
+			want:          nil,
+		},
+		{
+			description:   "single author",
+			contributions: map[string]int64{"a": 10},
+			coverage:      50,
+			want:          []string{"a"},
+		},
+		{
+			description:   "largest contributor covers the threshold",
+			contributions: map[string]int64{"a": 60, "b": 30, "c": 10},
+			coverage:      50,
+			want:          []string{"a"},
+		},
+		{
+			description:   "threshold reached exactly",
+			contributions: map[string]int64{"a": 40, "b": 40, "c": 20},
+			coverage:      80,
+			want:          []string{"a", "b"},
+		},
+		{
+			description:   "full coverage requires every author",
+			contributions: map[string]int64{"a": 5, "b": 3, "c": 1, "d": 0},
+			coverage:      100,
+			want:          []string{"a", "b", "c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if got := BusFactor(test.contributions, test.coverage); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// revertedCommitPattern matches the line git revert adds to the message of a revert, eg. This reverts commit 1a2b3c4.
+	revertedCommitPattern = regexp.MustCompile(`(?i)\bThis reverts commit ([0-9a-f]{7,40})\b`)
+
+	// hotfixPattern matches hotfix as a word in a title or message, eg. [HOTFIX] or hot-fix:
+	hotfixPattern = regexp.MustCompile(`(?i)(^|[^a-z0-9])hot[-_ ]?fix(es)?([^a-z0-9]|$)`)
+
+	// mergedBranchPattern matches the messages of the merge commits of git and of GitHub pull requests, eg.
+	// Merge branch 'hotfix/login' or Merge pull request #12 from acme/hotfix/login
+	mergedBranchPattern = regexp.MustCompile(`^Merge (?:remote-tracking )?branch '([^']+)'|^Merge pull request #\d+ from [^/\s]+/(\S+)`)
+)
+
+// ParseRevert reports whether the commit message is that of a revert (as written by git revert, or by the revert
+// button of GitHub: Revert "..."), and returns the (possibly abbreviated) hashes of the commits it reverts, if stated
+func ParseRevert(message string) (reverted []string, ok bool) {
+	for _, m := range revertedCommitPattern.FindAllStringSubmatch(message, -1) {
+		reverted = append(reverted, strings.ToLower(m[1]))
+	}
+	return reverted, len(reverted) > 0 || strings.HasPrefix(message, `Revert "`)
+}
+
+// IsHotfixBranch reports whether a segment of the branch name starts with hotfix (or hot-fix, hot_fix), eg.
+// hotfix/login, hotfix-1.2.3 or alice/hotfix_login
+func IsHotfixBranch(name string) bool {
+	for _, segment := range strings.Split(strings.ToLower(name), "/") {
+		for _, prefix := range []string{"hotfix", "hot-fix", "hot_fix"} {
+			if rest := strings.TrimPrefix(segment, prefix); rest != segment && (rest == "" || strings.ContainsRune("-_.", rune(rest[0])) || rest[0] >= '0' && rest[0] <= '9') {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsHotfixTitle reports whether the title (of a pull request, or the subject of a commit) mentions a hotfix
+func IsHotfixTitle(title string) bool {
+	return hotfixPattern.MatchString(title)
+}
+
+// MergedBranch returns the branch a merge commit merged, according to its message, or an empty string
+func MergedBranch(message string) string {
+	var m = mergedBranchPattern.FindStringSubmatch(message)
+	if m == nil {
+		return ""
+	}
+	if m[1] != "" {
+		return m[1]
+	}
+	return m[2]
+}
@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRevert(t *testing.T) {
+	for _, tt := range []struct {
+		message  string
+		reverted []string
+		ok       bool
+	}{
+		{"Revert \"Add login\"\n\nThis reverts commit 1A2B3C4D5E6F7A8B9C0D1E2F3A4B5C6D7E8F9A0B.", []string{"1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b"}, true},
+		{"Revert \"Add login (#12)\" (#13)", nil, true},
+		{"Roll back bad deploy\n\nThis reverts commit abc1234.\nThis reverts commit def5678.", []string{"abc1234", "def5678"}, true},
+		{"Add revert button", nil, false},
+	} {
+		if reverted, ok := ParseRevert(tt.message); ok != tt.ok || !reflect.DeepEqual(reverted, tt.reverted) {
+			t.Errorf("ParseRevert(%q) = %v, %t, want %v, %t", tt.message, reverted, ok, tt.reverted, tt.ok)
+		}
+	}
+}
+
+func TestIsHotfixBranch(t *testing.T) {
+	for name, want := range map[string]bool{
+		"hotfix/login": true, "hotfix-1.2.3": true, "alice/hot_fix_login": true, "HOTFIX": true, "hotfix2": true,
+		"hotfixes-docs": false, "feature/hotfix-button": true, "fix/login": false, "main": false,
+	} {
+		if got := IsHotfixBranch(name); got != want {
+			t.Errorf("IsHotfixBranch(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestIsHotfixTitle(t *testing.T) {
+	for title, want := range map[string]bool{
+		"[HOTFIX] Fix login": true, "Hot-fix: null pointer": true, "hotfixes for 1.2": true, "Add photofixture": false, "Fix login": false,
+	} {
+		if got := IsHotfixTitle(title); got != want {
+			t.Errorf("IsHotfixTitle(%q) = %t, want %t", title, got, want)
+		}
+	}
+}
+
+func TestMergedBranch(t *testing.T) {
+	for message, want := range map[string]string{
+		"Merge pull request #12 from acme/hotfix/login\n\nFix login": "hotfix/login",
+		"Merge branch 'hotfix-1.2.3' into main":                      "hotfix-1.2.3",
+		"Merge remote-tracking branch 'origin/main'":                 "origin/main",
+		"Fix login": "",
+	} {
+		if got := MergedBranch(message); got != want {
+			t.Errorf("MergedBranch(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package helper
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ActionReference is the action a workflow step (or job) uses, eg. actions/checkout@v3
+type ActionReference struct {
+	// Uses is the reference as written in the workflow
+	Uses string
+
+	// Kind is REMOTE (an action in another repository), LOCAL (a path in the same repository) or DOCKER (a docker:// image)
+	Kind string
+
+	// Action is the action without its ref, eg. actions/checkout or github/codeql-action/init
+	Action string
+
+	// Ref is the git ref (tag, branch or commit sha) of remote actions
+	Ref string
+
+	// Pinned is true if the ref is a full commit sha
+	Pinned bool
+}
+
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ParseActionReference parses the value of a uses: key of a workflow
+func ParseActionReference(uses string) *ActionReference {
+	var ref = &ActionReference{Uses: uses}
+	switch {
+	case strings.HasPrefix(uses, "./"):
+		ref.Kind, ref.Action = "LOCAL", uses
+	case strings.HasPrefix(uses, "docker://"):
+		ref.Kind, ref.Action = "DOCKER", strings.TrimPrefix(uses, "docker://")
+		ref.Pinned = strings.Contains(ref.Action, "@sha256:")
+	default:
+		ref.Kind = "REMOTE"
+		ref.Action, ref.Ref, _ = strings.Cut(uses, "@")
+		ref.Pinned = commitSHAPattern.MatchString(ref.Ref)
+	}
+	return ref
+}
+
+// CIJob is a job of a CI configuration
+type CIJob struct {
+	// ID is the key of the job in the configuration
+	ID string
+
+	// RunsOn are the labels of the runners the job runs on
+	RunsOn []string
+
+	// Actions are the actions (or reusable workflow) the job uses, in order
+	Actions []*ActionReference
+}
+
+// CIConfig is a parsed CI configuration file
+type CIConfig struct {
+	Name     string
+	Triggers []string
+	Jobs     []*CIJob
+}
+
+// stringsOf returns the strings of a scalar or sequence yaml value
+func stringsOf(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			result = append(result, stringsOf(item)...)
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// sortedKeys returns the (string) keys of a yaml mapping, sorted
+func sortedKeys(m map[interface{}]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, fmt.Sprint(k))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseGitHubWorkflow parses a GitHub Actions workflow file (.github/workflows/*.yml)
+func ParseGitHubWorkflow(contents string) (*CIConfig, error) {
+	var doc map[interface{}]interface{}
+	if err := yaml.Unmarshal([]byte(contents), &doc); err != nil {
+		return nil, err
+	}
+
+	var config = &CIConfig{}
+	config.Name, _ = doc["name"].(string)
+
+	// on is a boolean in YAML 1.1, so the key may have been decoded as true
+	var on, found = doc["on"]
+	if !found {
+		on = doc[true]
+	}
+	if triggers, ok := on.(map[interface{}]interface{}); ok {
+		config.Triggers = sortedKeys(triggers)
+	} else {
+		config.Triggers = stringsOf(on)
+	}
+
+	var jobs, _ = doc["jobs"].(map[interface{}]interface{})
+	for _, id := range sortedKeys(jobs) {
+		var job, _ = jobs[id].(map[interface{}]interface{})
+		var j = &CIJob{ID: id}
+
+		switch runsOn := job["runs-on"].(type) {
+		case map[interface{}]interface{}:
+			j.RunsOn = append(stringsOf(runsOn["group"]), stringsOf(runsOn["labels"])...)
+		default:
+			j.RunsOn = stringsOf(runsOn)
+		}
+
+		// a job calling a reusable workflow
+		if uses, ok := job["uses"].(string); ok {
+			j.Actions = append(j.Actions, ParseActionReference(uses))
+		}
+
+		var steps, _ = job["steps"].([]interface{})
+		for _, s := range steps {
+			var step, _ = s.(map[interface{}]interface{})
+			if uses, ok := step["uses"].(string); ok {
+				j.Actions = append(j.Actions, ParseActionReference(uses))
+			}
+		}
+
+		config.Jobs = append(config.Jobs, j)
+	}
+
+	return config, nil
+}
+
+// gitlabReservedKeys are the top level keys of a .gitlab-ci.yml that aren't jobs
+var gitlabReservedKeys = map[string]bool{"default": true, "include": true, "stages": true, "variables": true,
+	"workflow": true, "image": true, "services": true, "cache": true, "before_script": true, "after_script": true}
+
+// ParseGitLabCI parses a GitLab CI configuration (.gitlab-ci.yml). Runner labels are the tags of each job.
+func ParseGitLabCI(contents string) (*CIConfig, error) {
+	var doc map[interface{}]interface{}
+	if err := yaml.Unmarshal([]byte(contents), &doc); err != nil {
+		return nil, err
+	}
+
+	var config = &CIConfig{}
+	for _, id := range sortedKeys(doc) {
+		// hidden jobs (templates) start with a dot
+		if gitlabReservedKeys[id] || strings.HasPrefix(id, ".") {
+			continue
+		}
+
+		var job, ok = doc[id].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		config.Jobs = append(config.Jobs, &CIJob{ID: id, RunsOn: stringsOf(job["tags"])})
+	}
+
+	return config, nil
+}
@@ -0,0 +1,77 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGitHubWorkflow(t *testing.T) {
+	const workflow = `name: CI
+on:
+  push:
+    branches: [main]
+  pull_request:
+jobs:
+  test:
+    runs-on: [self-hosted, linux]
+    steps:
+      - uses: actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab
+      - uses: actions/setup-go@v4
+      - run: go test ./...
+      - uses: ./.github/actions/lint
+  release:
+    uses: org/workflows/.github/workflows/release.yml@main
+`
+
+	config, err := ParseGitHubWorkflow(workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Name != "CI" || !reflect.DeepEqual(config.Triggers, []string{"pull_request", "push"}) {
+		t.Fatalf("unexpected name or triggers: %+v", config)
+	}
+
+	if len(config.Jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(config.Jobs))
+	}
+
+	var release, test = config.Jobs[0], config.Jobs[1]
+	if !reflect.DeepEqual(test.RunsOn, []string{"self-hosted", "linux"}) || len(test.Actions) != 3 {
+		t.Fatalf("unexpected test job: %+v", test)
+	}
+
+	var want = []*ActionReference{
+		{Uses: "actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab", Kind: "REMOTE", Action: "actions/checkout", Ref: "8e5e7e5ab8b370d6c329ec480221332ada57f0ab", Pinned: true},
+		{Uses: "actions/setup-go@v4", Kind: "REMOTE", Action: "actions/setup-go", Ref: "v4"},
+		{Uses: "./.github/actions/lint", Kind: "LOCAL", Action: "./.github/actions/lint"},
+	}
+	if !reflect.DeepEqual(test.Actions, want) {
+		t.Fatalf("unexpected actions: %+v", test.Actions)
+	}
+
+	if len(release.Actions) != 1 || release.Actions[0].Ref != "main" {
+		t.Fatalf("unexpected release job: %+v", release)
+	}
+}
+
+func TestParseGitLabCI(t *testing.T) {
+	const ci = `stages: [test]
+.template:
+  tags: [docker]
+test:
+  stage: test
+  tags: [docker, linux]
+  script: make test
+`
+
+	config, err := ParseGitLabCI(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want = []*CIJob{{ID: "test", RunsOn: []string{"docker", "linux"}}}
+	if !reflect.DeepEqual(config.Jobs, want) {
+		t.Fatalf("unexpected jobs: %+v", config.Jobs)
+	}
+}
@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ImageReference is a container image reference, eg. docker.io/library/golang:1.19@sha256:...
+type ImageReference struct {
+	// Image is the reference as written in the file
+	Image string
+
+	// Registry is the host of the registry, docker.io if not specified
+	Registry string
+
+	// Repository is the path of the image in the registry, eg. library/golang
+	Repository string
+
+	// Tag is the tag of the image, if any
+	Tag string
+
+	// Digest is the digest the image is pinned to, if any
+	Digest string
+}
+
+// ParseImageReference splits an image reference into its parts, following the rules docker applies to short names
+func ParseImageReference(image string) *ImageReference {
+	var ref = &ImageReference{Image: image, Registry: "docker.io"}
+
+	var name = image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+	}
+
+	// a colon after the last slash separates the tag (any other colon is part of the registry host)
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+
+	if i := strings.Index(name, "/"); i >= 0 && strings.ContainsAny(name[:i], ".:") || strings.HasPrefix(name, "localhost/") {
+		ref.Registry, name = name[:i], name[i+1:]
+	}
+
+	if ref.Registry == "docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	ref.Repository = name
+	return ref
+}
+
+// DockerfileImage is a base image referenced by a FROM instruction of a Dockerfile
+type DockerfileImage struct {
+	*ImageReference
+
+	// Line is the (1-based) line number of the FROM instruction
+	Line int
+
+	// Stage is the name the build stage is given (FROM ... AS stage), if any
+	Stage string
+}
+
+var argPattern = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}?`)
+
+// ParseDockerfile returns the base images of the Dockerfile. References to earlier build stages and scratch are
+// skipped, and ARG defaults are substituted into image names.
+func ParseDockerfile(contents string) []*DockerfileImage {
+	var images []*DockerfileImage
+	var args = make(map[string]string)
+	var stages = make(map[string]bool)
+
+	var lines = strings.Split(contents, "\n")
+	for i := 0; i < len(lines); i++ {
+		var lineNo = i + 1
+		var line = strings.TrimSpace(lines[i])
+
+		// join continued lines
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(lines[i])
+		}
+
+		var fields = strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "ARG":
+			if name, value, ok := strings.Cut(fields[1], "="); ok {
+				args[name] = strings.Trim(value, `"'`)
+			}
+		case "FROM":
+			fields = fields[1:]
+			for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+				fields = fields[1:] // eg. --platform=linux/amd64
+			}
+			if len(fields) == 0 {
+				continue
+			}
+
+			var image = argPattern.ReplaceAllStringFunc(fields[0], func(s string) string {
+				var m = argPattern.FindStringSubmatch(s)
+				if v, ok := args[m[1]]; ok {
+					return v
+				}
+				return m[2]
+			})
+
+			var stage string
+			if len(fields) >= 3 && strings.EqualFold(fields[1], "AS") {
+				stage = fields[2]
+			}
+
+			if !stages[strings.ToLower(image)] && image != "scratch" && image != "" {
+				images = append(images, &DockerfileImage{ImageReference: ParseImageReference(image), Line: lineNo, Stage: stage})
+			}
+
+			if stage != "" {
+				stages[strings.ToLower(stage)] = true
+			}
+		}
+	}
+
+	return images
+}
+
+var composeImagePattern = regexp.MustCompile(`^\s*image:\s*["']?([^"'\s#]+)`)
+
+// ParseComposeImages returns the images referenced by the services of a docker compose file, with their line numbers
+func ParseComposeImages(contents string) map[int]*ImageReference {
+	var images = make(map[int]*ImageReference)
+	for i, line := range strings.Split(contents, "\n") {
+		if m := composeImagePattern.FindStringSubmatch(line); m != nil {
+			images[i+1] = ParseImageReference(m[1])
+		}
+	}
+	return images
+}
@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImageReference(t *testing.T) {
+	type testArgs struct {
+		image string
+		want  *ImageReference
+	}
+
+	tests := []testArgs{
+		{image: "golang", want: &ImageReference{Image: "golang", Registry: "docker.io", Repository: "library/golang"}},
+		{image: "golang:1.19-alpine", want: &ImageReference{Image: "golang:1.19-alpine", Registry: "docker.io", Repository: "library/golang", Tag: "1.19-alpine"}},
+		{image: "bitnami/redis:7", want: &ImageReference{Image: "bitnami/redis:7", Registry: "docker.io", Repository: "bitnami/redis", Tag: "7"}},
+		{image: "ghcr.io/org/app@sha256:abc", want: &ImageReference{Image: "ghcr.io/org/app@sha256:abc", Registry: "ghcr.io", Repository: "org/app", Digest: "sha256:abc"}},
+		{image: "localhost:5000/app:dev", want: &ImageReference{Image: "localhost:5000/app:dev", Registry: "localhost:5000", Repository: "app", Tag: "dev"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			if got := ParseImageReference(test.image); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("expected %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParseDockerfile(t *testing.T) {
+	const dockerfile = `ARG GO_VERSION=1.19
+FROM --platform=linux/amd64 golang:${GO_VERSION} AS builder
+RUN go build ./...
+
+FROM builder AS test
+FROM scratch
+FROM \
+  gcr.io/distroless/static@sha256:abc
+`
+
+	var got = ParseDockerfile(dockerfile)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(got))
+	}
+
+	if got[0].Image != "golang:1.19" || got[0].Stage != "builder" || got[0].Line != 2 {
+		t.Fatalf("unexpected first image: %+v", got[0])
+	}
+
+	if got[1].Repository != "distroless/static" || got[1].Digest != "sha256:abc" || got[1].Line != 7 {
+		t.Fatalf("unexpected second image: %+v", got[1])
+	}
+}
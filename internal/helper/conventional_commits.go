@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ConventionalCommit is the structure of a commit message following the Conventional Commits specification
+// (see https://www.conventionalcommits.org/en/v1.0.0/)
+type ConventionalCommit struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+	TicketRefs  []string
+}
+
+var (
+	// conventionalHeader matches the first line of a conventional commit, eg. feat(parser)!: add arrays
+	conventionalHeader = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()\r\n]*)\))?(!)?: (.+)$`)
+
+	// breakingFooter matches the footer announcing a breaking change
+	breakingFooter = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+
+	// ticketRef matches GitHub style (#123) and Jira style (ABC-123) ticket references
+	ticketRef = regexp.MustCompile(`(?:^|[^\w/])(#[0-9]+|[A-Z][A-Z0-9]+-[0-9]+)\b`)
+)
+
+// ParseConventionalCommit parses a commit message into its conventional commit structure.
+// It returns false if the message doesn't follow the Conventional Commits specification.
+func ParseConventionalCommit(message string) (*ConventionalCommit, bool) {
+	var header, body, _ = strings.Cut(message, "\n")
+
+	var match = conventionalHeader.FindStringSubmatch(strings.TrimSpace(header))
+	if match == nil {
+		return nil, false
+	}
+
+	var commit = &ConventionalCommit{
+		Type:        strings.ToLower(match[1]),
+		Scope:       strings.TrimSpace(match[2]),
+		Breaking:    match[3] == "!" || breakingFooter.MatchString(body),
+		Description: strings.TrimSpace(match[4]),
+	}
+
+	var seen = make(map[string]struct{})
+	for _, ref := range ticketRef.FindAllStringSubmatch(message, -1) {
+		if _, ok := seen[ref[1]]; !ok {
+			seen[ref[1]] = struct{}{}
+			commit.TicketRefs = append(commit.TicketRefs, ref[1])
+		}
+	}
+
+	return commit, true
+}
@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	type testArgs struct {
+		description string
+		message     string
+		want        *ConventionalCommit
+		wantOk      bool
+	}
+
+	tests := []testArgs{
+		{
+			description: "type and description",
+			message:     "fix: handle empty repos",
+			want:        &ConventionalCommit{Type: "fix", Description: "handle empty repos"},
+			wantOk:      true,
+		},
+		{
+			description: "scope, breaking marker and ticket refs",
+			message:     "feat(api)!: drop v1 endpoints\n\nCloses #42, refs ABC-123",
+			want:        &ConventionalCommit{Type: "feat", Scope: "api", Breaking: true, Description: "drop v1 endpoints", TicketRefs: []string{"#42", "ABC-123"}},
+			wantOk:      true,
+		},
+		{
+			description: "breaking change footer",
+			message:     "Refactor(db): rename columns\n\nBREAKING CHANGE: repo_id is now id",
+			want:        &ConventionalCommit{Type: "refactor", Scope: "db", Breaking: true, Description: "rename columns"},
+			wantOk:      true,
+		},
+		{
+			description: "not a conventional commit",
+			message:     "Merge pull request #12 from mergestat/branch",
+			want:        nil,
+			wantOk:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, ok := ParseConventionalCommit(tt.message)
+			if ok != tt.wantOk {
+				t.Fatalf("ParseConventionalCommit() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseConventionalCommit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package helper
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// Symbol is a symbol (function, type, variable...) defined in a file, as reported by universal-ctags
+type Symbol struct {
+	Name     string
+	Kind     string
+	Path     string
+	Line     int
+	Language string
+
+	// Scope and ScopeKind are the enclosing symbol (eg. the type of a method) and its kind, if any
+	Scope     string
+	ScopeKind string
+
+	// Signature is the parameters (and result) of functions and methods, for the languages it's reported for
+	Signature string
+}
+
+// CtagsArgs are the arguments universal-ctags is run with, over the current directory, so that its output is
+// read by ReadCtags: every file, recursively (vcs and dependency directories excluded), as JSON lines on stdout
+var CtagsArgs = []string{
+	"--output-format=json", "--fields=+nKlS", "--fields=-P",
+	"--exclude=.git", "--exclude=node_modules", "--exclude=vendor",
+	"-R", "-f", "-", ".",
+}
+
+// ctagsTag is a line of the JSON output of universal-ctags (see https://docs.ctags.io/en/latest/man/ctags-json-output.5.html)
+type ctagsTag struct {
+	Type      string `json:"_type"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Kind      string `json:"kind"`
+	Language  string `json:"language"`
+	Scope     string `json:"scope"`
+	ScopeKind string `json:"scopeKind"`
+	Signature string `json:"signature"`
+}
+
+// ReadCtags reads the JSON output of universal-ctags (see CtagsArgs) and calls fn with each symbol, stopping at the
+// first error fn returns. Lines that aren't tags (eg. pseudo tags) are skipped, and so are malformed ones.
+func ReadCtags(r io.Reader, fn func(*Symbol) error) error {
+	var scanner = bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var tag ctagsTag
+		if err := json.Unmarshal(scanner.Bytes(), &tag); err != nil || tag.Type != "tag" || tag.Name == "" {
+			continue
+		}
+
+		var symbol = &Symbol{
+			Name:      tag.Name,
+			Kind:      tag.Kind,
+			Path:      strings.TrimPrefix(tag.Path, "./"),
+			Line:      tag.Line,
+			Language:  tag.Language,
+			Scope:     tag.Scope,
+			ScopeKind: tag.ScopeKind,
+			Signature: tag.Signature,
+		}
+		if err := fn(symbol); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,40 @@
+package helper
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const ctagsOutput = `{"_type": "ptag", "name": "JSON_OUTPUT_VERSION", "path": "0.0", "pattern": "in development"}
+{"_type": "tag", "name": "worker", "path": "./internal/syncer/syncer.go", "line": 82, "language": "Go", "kind": "struct", "scope": "syncer", "scopeKind": "package"}
+{"_type": "tag", "name": "Start", "path": "./internal/syncer/syncer.go", "line": 160, "language": "Go", "kind": "methodSpec", "scope": "worker", "scopeKind": "struct", "signature": "(ctx context.Context)"}
+not json
+{"_type": "tag", "name": "main", "path": "cmd/worker/main.go", "line": 12, "language": "Go", "kind": "func"}
+`
+
+func TestReadCtags(t *testing.T) {
+	var symbols []Symbol
+	if err := ReadCtags(strings.NewReader(ctagsOutput), func(s *Symbol) error {
+		symbols = append(symbols, *s)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var want = []Symbol{
+		{Name: "worker", Kind: "struct", Path: "internal/syncer/syncer.go", Line: 82, Language: "Go", Scope: "syncer", ScopeKind: "package"},
+		{Name: "Start", Kind: "methodSpec", Path: "internal/syncer/syncer.go", Line: 160, Language: "Go", Scope: "worker", ScopeKind: "struct", Signature: "(ctx context.Context)"},
+		{Name: "main", Kind: "func", Path: "cmd/worker/main.go", Line: 12, Language: "Go"},
+	}
+	if !reflect.DeepEqual(symbols, want) {
+		t.Errorf("ReadCtags() = %+v, want %+v", symbols, want)
+	}
+
+	var stop = errors.New("stop")
+	var calls int
+	if err := ReadCtags(strings.NewReader(ctagsOutput), func(*Symbol) error { calls++; return stop }); !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("ReadCtags() = %v after %d call(s), want it to stop at the first error", err, calls)
+	}
+}
@@ -0,0 +1,113 @@
+package helper
+
+import (
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DBTTable is a synced table (or view), described as a table of a dbt source
+type DBTTable struct {
+	Name        string
+	Description string
+
+	// Schema is the schema the table lives in, if it's not the schema of the source (eg. when relocated, see
+	// mergestat.synced_tables), and Identifier its name there, if it's not Name
+	Schema     string
+	Identifier string
+
+	// LoadedAtField is the column holding when a row was synced (eg. _mergestat_synced_at), empty if there's none.
+	// Only the tables with one have their freshness checked.
+	LoadedAtField string
+
+	Columns []DBTColumn
+}
+
+// DBTColumn is a column of a DBTTable
+type DBTColumn struct {
+	Name        string
+	Description string
+}
+
+// DBTFreshnessThreshold is a freshness threshold (warn_after or error_after) of a dbt source
+type DBTFreshnessThreshold struct {
+	Count  int64  `yaml:"count"`
+	Period string `yaml:"period"`
+}
+
+// NewDBTFreshnessThreshold returns the threshold of the given duration, in the largest period (day, hour or minute)
+// it is a whole number of (rounded up to the minute)
+func NewDBTFreshnessThreshold(d time.Duration) DBTFreshnessThreshold {
+	var minutes = int64((d + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+
+	switch {
+	case minutes%(24*60) == 0:
+		return DBTFreshnessThreshold{Count: minutes / (24 * 60), Period: "day"}
+	case minutes%60 == 0:
+		return DBTFreshnessThreshold{Count: minutes / 60, Period: "hour"}
+	default:
+		return DBTFreshnessThreshold{Count: minutes, Period: "minute"}
+	}
+}
+
+// DBTSources renders the properties file (sources.yml) declaring the tables of the schema as a dbt source, with
+// their freshness thresholds, so that dbt models can select from them (with source()) and dbt can check their freshness
+// (see https://docs.getdbt.com/reference/source-properties). A dbt source lives in a single schema, so the tables
+// living in another schema are declared as a source of their own, named <name>_<schema>.
+func DBTSources(name, schema string, tables []DBTTable, warnAfter, errorAfter time.Duration) ([]byte, error) {
+	type freshness struct {
+		WarnAfter  DBTFreshnessThreshold `yaml:"warn_after"`
+		ErrorAfter DBTFreshnessThreshold `yaml:"error_after"`
+	}
+	type column struct {
+		Name        string `yaml:"name"`
+		Description string `yaml:"description,omitempty"`
+	}
+	type table struct {
+		Name          string     `yaml:"name"`
+		Identifier    string     `yaml:"identifier,omitempty"`
+		Description   string     `yaml:"description,omitempty"`
+		LoadedAtField string     `yaml:"loaded_at_field,omitempty"`
+		Freshness     *freshness `yaml:"freshness,omitempty"`
+		Columns       []column   `yaml:"columns,omitempty"`
+	}
+	type source struct {
+		Name   string  `yaml:"name"`
+		Schema string  `yaml:"schema"`
+		Tables []table `yaml:"tables"`
+	}
+
+	var threshold = &freshness{WarnAfter: NewDBTFreshnessThreshold(warnAfter), ErrorAfter: NewDBTFreshnessThreshold(errorAfter)}
+
+	var sources = []*source{{Name: name, Schema: schema, Tables: make([]table, 0, len(tables))}}
+	var bySchema = map[string]*source{schema: sources[0]}
+	for _, t := range tables {
+		var s = bySchema[schema]
+		if t.Schema != "" && t.Schema != schema {
+			if s = bySchema[t.Schema]; s == nil {
+				s = &source{Name: name + "_" + t.Schema, Schema: t.Schema}
+				sources, bySchema[t.Schema] = append(sources, s), s
+			}
+		}
+
+		var out = table{Name: t.Name, Description: t.Description, LoadedAtField: t.LoadedAtField}
+		if t.Identifier != t.Name {
+			out.Identifier = t.Identifier
+		}
+		if t.LoadedAtField != "" {
+			out.Freshness = threshold
+		}
+		for _, c := range t.Columns {
+			out.Columns = append(out.Columns, column{Name: c.Name, Description: c.Description})
+		}
+		s.Tables = append(s.Tables, out)
+	}
+
+	return yaml.Marshal(struct {
+		Version int       `yaml:"version"`
+		Sources []*source `yaml:"sources"`
+	}{Version: 2, Sources: sources})
+}
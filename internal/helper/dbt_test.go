@@ -0,0 +1,99 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDBTFreshnessThreshold(t *testing.T) {
+	type testArgs struct {
+		description string
+		duration    time.Duration
+		want        DBTFreshnessThreshold
+	}
+
+	tests := []testArgs{
+		{description: "whole days", duration: 48 * time.Hour, want: DBTFreshnessThreshold{Count: 2, Period: "day"}},
+		{description: "whole hours", duration: 12 * time.Hour, want: DBTFreshnessThreshold{Count: 12, Period: "hour"}},
+		{description: "minutes", duration: 90 * time.Minute, want: DBTFreshnessThreshold{Count: 90, Period: "minute"}},
+		{description: "rounded up to the minute", duration: 30 * time.Second, want: DBTFreshnessThreshold{Count: 1, Period: "minute"}},
+		{description: "zero", duration: 0, want: DBTFreshnessThreshold{Count: 1, Period: "minute"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if got := NewDBTFreshnessThreshold(test.duration); got != test.want {
+				t.Fatalf("expected %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestDBTSources(t *testing.T) {
+	var tables = []DBTTable{
+		{Name: "git_commits", Description: "git commit history of a repo", LoadedAtField: "_mergestat_synced_at",
+			Columns: []DBTColumn{{Name: "hash", Description: "hash of the commit"}}},
+		{Name: "git_commit_lead_times"},
+	}
+
+	var out, err = DBTSources("mergestat", "public", tables, 12*time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `version: 2
+sources:
+- name: mergestat
+  schema: public
+  tables:
+  - name: git_commits
+    description: git commit history of a repo
+    loaded_at_field: _mergestat_synced_at
+    freshness:
+      warn_after:
+        count: 12
+        period: hour
+      error_after:
+        count: 1
+        period: day
+    columns:
+    - name: hash
+      description: hash of the commit
+  - name: git_commit_lead_times
+`
+	if got := string(out); strings.TrimSpace(got) != strings.TrimSpace(want) {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestDBTSourcesRelocated(t *testing.T) {
+	var tables = []DBTTable{
+		{Name: "repos"},
+		{Name: "git_commits", Schema: "mergestat_data", Identifier: "ms_git_commits"},
+		{Name: "git_refs", Schema: "mergestat_data", Identifier: "ms_git_refs"},
+	}
+
+	var out, err = DBTSources("mergestat", "public", tables, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `version: 2
+sources:
+- name: mergestat
+  schema: public
+  tables:
+  - name: repos
+- name: mergestat_mergestat_data
+  schema: mergestat_data
+  tables:
+  - name: git_commits
+    identifier: ms_git_commits
+  - name: git_refs
+    identifier: ms_git_refs
+`
+	if got := string(out); strings.TrimSpace(got) != strings.TrimSpace(want) {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,60 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DependencyUpdate is the update of a dependency proposed by a pull request of a dependency bot
+type DependencyUpdate struct {
+	// Bot is dependabot or renovate
+	Bot string
+
+	// Dependency is the name of the dependency, eg. lodash or github.com/pkg/errors, empty for grouped updates
+	Dependency string
+
+	// From and To are the versions updated from (if stated) and to
+	From, To string
+}
+
+var (
+	// dependabotTitlePattern matches the titles of Dependabot pull requests, eg. Bump lodash from 4.17.20 to 4.17.21
+	// or chore(deps): bump lodash from 4.17.20 to 4.17.21 in /web
+	dependabotTitlePattern = regexp.MustCompile(`(?i)\bbump (\S+) from (\S+) to (\S+)`)
+
+	// renovateTitlePattern matches the titles of Renovate pull requests, eg. Update dependency lodash to v4.17.21 or
+	// fix(deps): update module github.com/pkg/errors to v0.9.1
+	renovateTitlePattern = regexp.MustCompile(`(?i)\bupdate (?:dependency|module|(?:[a-z]+ )?(?:crate|package|image|gem|action)) (\S+) to (\S+)`)
+)
+
+// ParseDependencyUpdate returns the update proposed by a pull request, if it was opened by Dependabot or Renovate
+// (according to its author or head branch, eg. dependabot/npm_and_yarn/lodash-4.17.21 or renovate/lodash-4.x)
+func ParseDependencyUpdate(author, branch, title string) (DependencyUpdate, bool) {
+	var update DependencyUpdate
+
+	switch author, branch = strings.ToLower(author), strings.ToLower(branch); {
+	case strings.Contains(author, "dependabot"), strings.HasPrefix(branch, "dependabot/"):
+		update.Bot = "dependabot"
+	case strings.Contains(author, "renovate"), strings.HasPrefix(branch, "renovate/"):
+		update.Bot = "renovate"
+	default:
+		return update, false
+	}
+
+	if m := dependabotTitlePattern.FindStringSubmatch(title); m != nil {
+		update.Dependency, update.From, update.To = m[1], m[2], m[3]
+	} else if m = renovateTitlePattern.FindStringSubmatch(title); m != nil {
+		update.Dependency, update.To = m[1], m[2]
+	}
+
+	return update, true
+}
+
+// prereleasePattern matches the prerelease versions of semver (eg. 1.2.0-rc.1) and of PEP 440 (eg. 1.2.0rc1, 1.2.0.dev3)
+var prereleasePattern = regexp.MustCompile(`(?i)-|\d(a|b|rc|alpha|beta|pre|dev)\d*$|\.dev\d*$`)
+
+// IsPrerelease reports whether the version is a prerelease, which dependencies aren't expected to be updated to.
+// Go pseudo-versions (eg. v0.0.0-20230101000000-abcdef123456) are prereleases too.
+func IsPrerelease(version string) bool {
+	return prereleasePattern.MatchString(strings.TrimPrefix(version, "v"))
+}
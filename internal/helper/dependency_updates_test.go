@@ -0,0 +1,36 @@
+package helper
+
+import "testing"
+
+func TestParseDependencyUpdate(t *testing.T) {
+	for _, tt := range []struct {
+		author, branch, title string
+		want                  DependencyUpdate
+		ok                    bool
+	}{
+		{"dependabot[bot]", "dependabot/npm_and_yarn/lodash-4.17.21", "Bump lodash from 4.17.20 to 4.17.21",
+			DependencyUpdate{Bot: "dependabot", Dependency: "lodash", From: "4.17.20", To: "4.17.21"}, true},
+		{"app/dependabot", "", "chore(deps): bump golang.org/x/net from 0.7.0 to 0.17.0 in /tools",
+			DependencyUpdate{Bot: "dependabot", Dependency: "golang.org/x/net", From: "0.7.0", To: "0.17.0"}, true},
+		{"renovate[bot]", "renovate/github.com-pkg-errors-0.x", "fix(deps): update module github.com/pkg/errors to v0.9.1",
+			DependencyUpdate{Bot: "renovate", Dependency: "github.com/pkg/errors", To: "v0.9.1"}, true},
+		{"mergestat-bot", "renovate/docker-node-20.x", "Update node Docker image to v20",
+			DependencyUpdate{Bot: "renovate"}, true},
+		{"alice", "feature/bump", "Bump version from 1.0 to 1.1", DependencyUpdate{}, false},
+	} {
+		if got, ok := ParseDependencyUpdate(tt.author, tt.branch, tt.title); ok != tt.ok || got != tt.want {
+			t.Errorf("ParseDependencyUpdate(%q) = %+v, %t, want %+v, %t", tt.title, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsPrerelease(t *testing.T) {
+	for version, want := range map[string]bool{
+		"4.17.21": false, "v1.2.3": false, "1.2.0-rc.1": true, "2.0.0b1": true, "1.2.0rc1": true, "3.0.0.dev3": true,
+		"v0.0.0-20230101000000-abcdef123456": true, "2023.7.22": false,
+	} {
+		if got := IsPrerelease(version); got != want {
+			t.Errorf("IsPrerelease(%q) = %t, want %t", version, got, want)
+		}
+	}
+}
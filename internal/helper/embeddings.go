@@ -0,0 +1,29 @@
+package helper
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultEmbeddingPaths are the globs of the files GIT_EMBEDDINGS syncs embed, unless the sync configures others:
+// READMEs and documentation
+var DefaultEmbeddingPaths = []string{"**/README*", "**/readme*", "docs/**", "doc/**", "**/*.md", "**/*.rst", "**/*.adoc"}
+
+// EmbeddingText returns the text to embed: the text with surrounding whitespace removed, cut to at most maxBytes
+// (on a character boundary) to fit the input limit of the model. A maxBytes of zero (or less) doesn't cut the text.
+func EmbeddingText(text string, maxBytes int) string {
+	return cutText(strings.TrimSpace(text), maxBytes)
+}
+
+// cutText returns the text cut to at most maxBytes, on a character boundary (or as-is, with a maxBytes of zero)
+func cutText(text string, maxBytes int) string {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return text
+	}
+
+	var end = maxBytes
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return strings.TrimSpace(text[:end])
+}
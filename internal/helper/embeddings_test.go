@@ -0,0 +1,36 @@
+package helper
+
+import "testing"
+
+func TestEmbeddingText(t *testing.T) {
+	for _, tt := range []struct {
+		text     string
+		maxBytes int
+		want     string
+	}{
+		{"  fix: race in watcher\n\n", 0, "fix: race in watcher"},
+		{"fix: race in watcher", 9, "fix: race"},
+		{"héllo", 2, "h"}, // é is 2 bytes, it's not cut in half
+		{"héllo", 3, "hé"},
+		{"short", 100, "short"},
+	} {
+		if got := EmbeddingText(tt.text, tt.maxBytes); got != tt.want {
+			t.Errorf("EmbeddingText(%q, %d) = %q, want %q", tt.text, tt.maxBytes, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultEmbeddingPaths(t *testing.T) {
+	for path, want := range map[string]bool{
+		"README.md":              true,
+		"pkg/parser/README":      true,
+		"docs/setup/install.txt": true,
+		"CHANGELOG.md":           true,
+		"docs.go":                false,
+		"internal/syncer/run.go": false,
+	} {
+		if got := MatchAnyGlob(DefaultEmbeddingPaths, path); got != want {
+			t.Errorf("MatchAnyGlob(DefaultEmbeddingPaths, %q) = %v, want %v", path, got, want)
+		}
+	}
+}
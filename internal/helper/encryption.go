@@ -0,0 +1,145 @@
+package helper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// encryptedPrefix marks (and versions) the values encrypted by a ColumnCipher
+const encryptedPrefix = "enc:v1:"
+
+// ColumnCipher encrypts the values of synced columns at rest, with AES-256-GCM and a key only the worker holds
+type ColumnCipher struct {
+	aead cipher.AEAD
+}
+
+// NewColumnCipher returns a cipher for the given key, which must be 32 bytes long (AES-256)
+func NewColumnCipher(key []byte) (*ColumnCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("expected a key of 32 bytes, got %d", len(key))
+	}
+
+	var block, err = aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var aead cipher.AEAD
+	if aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+
+	return &ColumnCipher{aead: aead}, nil
+}
+
+// NewColumnCipherFromBase64 returns a cipher for the given base64 encoded key (eg. from `openssl rand -base64 32`)
+func NewColumnCipherFromBase64(key string) (*ColumnCipher, error) {
+	var decoded, err = base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return NewColumnCipher(decoded)
+}
+
+// IsEncrypted reports whether the value was encrypted with the key of the cipher. The prefix alone doesn't tell,
+// as plain values may start with it too: the value must also authenticate with the key.
+func (c *ColumnCipher) IsEncrypted(value string) bool {
+	var _, err = c.open(value)
+	return err == nil
+}
+
+// Encrypt returns the value encrypted, as text (prefixed with enc:v1:). Encrypting the same value twice gives
+// different results. Every value is encrypted, even one that looks encrypted already.
+func (c *ColumnCipher) Encrypt(value string) (string, error) {
+	var nonce = make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	var sealed = c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plain value of an encrypted value. Values without the prefix are returned as-is, values with it
+// that don't authenticate (eg. encrypted with another key) are an error.
+func (c *ColumnCipher) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	return c.open(value)
+}
+
+// open decrypts (and authenticates) a value prefixed with enc:v1:
+func (c *ColumnCipher) open(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return "", errors.New("invalid encrypted value: no prefix")
+	}
+
+	var sealed, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+
+	var size = c.aead.NonceSize()
+	if len(sealed) < size {
+		return "", errors.New("invalid encrypted value: too short")
+	}
+
+	var plain []byte
+	if plain, err = c.aead.Open(nil, sealed[:size], sealed[size:], nil); err != nil {
+		return "", fmt.Errorf("could not decrypt value (wrong key?): %w", err)
+	}
+	return string(plain), nil
+}
+
+// ErrNoEncryptionKey is returned when reading (or writing) a value of an encrypted column without a key
+var ErrNoEncryptionKey = errors.New("columns are configured to be encrypted (see mergestat.encrypted_columns), but COLUMN_ENCRYPTION_KEY is not set")
+
+// ListEncryptedColumns lists the columns configured to be encrypted, see CollectEncryptedColumns
+const ListEncryptedColumns = "SELECT table_name, column_name FROM mergestat.encrypted_columns"
+
+// EncryptedColumns are the columns encrypted at rest (see mergestat.encrypted_columns), by table
+type EncryptedColumns map[string]map[string]bool
+
+// CollectEncryptedColumns reads the rows of ListEncryptedColumns
+func CollectEncryptedColumns(rows pgx.Rows, err error) (EncryptedColumns, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns = make(EncryptedColumns)
+	for rows.Next() {
+		var table, column string
+		if err = rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		if columns[table] == nil {
+			columns[table] = make(map[string]bool)
+		}
+		columns[table][column] = true
+	}
+	return columns, rows.Err()
+}
+
+// Decrypt returns the plain value of a value read from the column of the table, with the cipher (which may be nil).
+// Values of columns that aren't encrypted, and the plain values synced before their column was, are returned as-is.
+func (e EncryptedColumns) Decrypt(c *ColumnCipher, table, column, value string) (string, error) {
+	if !e[table][column] {
+		return value, nil
+	}
+	if c == nil {
+		if strings.HasPrefix(value, encryptedPrefix) {
+			return "", ErrNoEncryptionKey
+		}
+		return value, nil
+	}
+	return c.Decrypt(value)
+}
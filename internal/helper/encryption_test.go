@@ -0,0 +1,80 @@
+package helper
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestColumnCipher(t *testing.T) {
+	var c, err = NewColumnCipher(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values = []string{"", "fix: a bug\n\nwith a long body", "ünïcode ✓"}
+	for _, value := range values {
+		var encrypted, err = c.Encrypt(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.IsEncrypted(encrypted) {
+			t.Fatalf("%q: expected an encrypted value, got %q", value, encrypted)
+		}
+		if again, _ := c.Encrypt(encrypted); again == encrypted {
+			t.Fatalf("%q: expected an encrypted value to be encrypted again", value)
+		}
+
+		var decrypted string
+		if decrypted, err = c.Decrypt(encrypted); err != nil {
+			t.Fatal(err)
+		}
+		if decrypted != value {
+			t.Fatalf("expected %q, got %q", value, decrypted)
+		}
+	}
+
+	if plain, _ := c.Decrypt("not encrypted"); plain != "not encrypted" {
+		t.Fatalf("expected a plain value to be left as-is, got %q", plain)
+	}
+
+	// a plain value with the prefix is not taken for an encrypted one
+	var prefixed = "enc:v1:c2VjcmV0"
+	if c.IsEncrypted(prefixed) {
+		t.Fatalf("expected %q not to be taken for an encrypted value", prefixed)
+	}
+	if encrypted, _ := c.Encrypt(prefixed); encrypted == prefixed {
+		t.Fatalf("expected %q to be encrypted", prefixed)
+	}
+
+	var other, _ = NewColumnCipher(bytes.Repeat([]byte{8}, 32))
+	var encrypted, _ = c.Encrypt("secret")
+	if _, err = other.Decrypt(encrypted); err == nil {
+		t.Fatal("expected decrypting with another key to fail")
+	}
+	if other.IsEncrypted(encrypted) {
+		t.Fatal("expected a value encrypted with another key not to authenticate")
+	}
+
+	if _, err = NewColumnCipher([]byte("short")); err == nil {
+		t.Fatal("expected a short key to be rejected")
+	}
+}
+
+func TestEncryptedColumnsDecrypt(t *testing.T) {
+	var c, _ = NewColumnCipher(bytes.Repeat([]byte{7}, 32))
+	var encrypted, _ = c.Encrypt("fix: a bug")
+	var columns = EncryptedColumns{"git_commits": {"message": true}}
+
+	if plain, err := columns.Decrypt(c, "git_commits", "message", encrypted); err != nil || plain != "fix: a bug" {
+		t.Fatalf("expected the value to be decrypted, got %q (%v)", plain, err)
+	}
+	if plain, err := columns.Decrypt(c, "git_commits", "message", "synced before"); err != nil || plain != "synced before" {
+		t.Fatalf("expected a plain value to be left as-is, got %q (%v)", plain, err)
+	}
+	if value, err := columns.Decrypt(nil, "git_commits", "hash", "enc:v1:c2VjcmV0"); err != nil || value != "enc:v1:c2VjcmV0" {
+		t.Fatalf("expected the value of a plain column to be left as-is, got %q (%v)", value, err)
+	}
+	if _, err := columns.Decrypt(nil, "git_commits", "message", encrypted); err != ErrNoEncryptionKey {
+		t.Fatalf("expected ErrNoEncryptionKey, got %v", err)
+	}
+}
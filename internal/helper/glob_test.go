@@ -0,0 +1,39 @@
+package helper
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"src/**", "src/main.go", true},
+		{"src/**", "src/auth/crypto/keys.go", true},
+		{"src/**", "docs/src/readme.md", false},
+		{"src/**", "src", true},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "internal/auth/token.go", true},
+		{"**/*.go", "internal/auth/token.rs", false},
+		{"internal/**/auth/*.go", "internal/auth/token.go", true},
+		{"internal/**/auth/*.go", "internal/a/b/auth/token.go", true},
+		{"internal/**/auth/*.go", "internal/a/b/auth/sub/token.go", false},
+		{"*.md", "README.md", true},
+		{"*.md", "docs/README.md", false},
+		{"/config/*.yaml", "config/app.yaml", true},
+		{"[", "[", false},
+	} {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.match {
+			t.Errorf("MatchGlob(%q, %q) = %t, want %t", tt.pattern, tt.name, got, tt.match)
+		}
+	}
+}
+
+func TestMatchAnyGlob(t *testing.T) {
+	var patterns = []string{"src/security/**", "**/*.pem"}
+	if !MatchAnyGlob(patterns, "certs/server.pem") || MatchAnyGlob(patterns, "src/app/main.go") {
+		t.Errorf("MatchAnyGlob(%v) matched unexpectedly", patterns)
+	}
+	if MatchAnyGlob(nil, "main.go") {
+		t.Errorf("MatchAnyGlob without patterns should match nothing")
+	}
+}
@@ -0,0 +1,162 @@
+package helper
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Import is a module (or package) imported by a source file
+type Import struct {
+	Line   int
+	Module string
+
+	// Kind is how the module is imported: import (incl. export ... from in JS/TS), require or dynamic (import())
+	Kind string
+}
+
+// ImportLanguage returns the language whose imports are parsed (see ParseImports) of the file at p, if any
+func ImportLanguage(p string) string {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".go":
+		return "Go"
+	case ".js", ".jsx", ".mjs", ".cjs":
+		return "JavaScript"
+	case ".ts", ".tsx", ".mts", ".cts":
+		return "TypeScript"
+	default:
+		return ""
+	}
+}
+
+// ParseImports returns the imports of a source file in the given language (see ImportLanguage), in order
+func ParseImports(language, contents string) []Import {
+	switch language {
+	case "Go":
+		return parseGoImports(contents)
+	case "JavaScript", "TypeScript":
+		return parseJSImports(contents)
+	default:
+		return nil
+	}
+}
+
+// parseGoImports parses the import declarations of a Go file, none if it doesn't parse
+func parseGoImports(contents string) []Import {
+	var fset = token.NewFileSet()
+	var file, err = parser.ParseFile(fset, "", contents, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+
+	var imports = make([]Import, 0, len(file.Imports))
+	for _, spec := range file.Imports {
+		var module, err = strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		imports = append(imports, Import{Line: fset.Position(spec.Pos()).Line, Module: module, Kind: "import"})
+	}
+	return imports
+}
+
+// jsImportPatterns match the imports of JS/TS files, their first group being the module imported
+var jsImportPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	// import x from 'm', import { x } from 'm', export * from 'm', import type { T } from 'm'
+	{"import", regexp.MustCompile(`\b(?:import|export)\s[^'";]*?\bfrom\s*['"]([^'"\n]+)['"]`)},
+	// import 'm' (side effects only)
+	{"import", regexp.MustCompile(`\bimport\s*['"]([^'"\n]+)['"]`)},
+	// require('m')
+	{"require", regexp.MustCompile(`\brequire\s*\(\s*['"]([^'"\n]+)['"]\s*\)`)},
+	// import('m')
+	{"dynamic", regexp.MustCompile(`\bimport\s*\(\s*['"]([^'"\n]+)['"]\s*\)`)},
+}
+
+// jsCommentPattern matches the comments of JS/TS files
+var jsCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+
+// parseJSImports parses the import declarations, require() and import() calls of a JS/TS file. Comments are ignored,
+// but imports are matched textually, so that those in strings (or template literals) are reported as well.
+func parseJSImports(contents string) []Import {
+	// comments are blanked out (keeping their newlines), so that the line numbers of matches are preserved
+	contents = jsCommentPattern.ReplaceAllStringFunc(contents, func(comment string) string {
+		return strings.Map(func(r rune) rune {
+			if r == '\n' {
+				return r
+			}
+			return ' '
+		}, comment)
+	})
+
+	var imports []Import
+	for _, p := range jsImportPatterns {
+		for _, m := range p.pattern.FindAllStringSubmatchIndex(contents, -1) {
+			var line = strings.Count(contents[:m[2]], "\n") + 1
+			imports = append(imports, Import{Line: line, Module: contents[m[2]:m[3]], Kind: p.kind})
+		}
+	}
+
+	sort.SliceStable(imports, func(i, j int) bool { return imports[i].Line < imports[j].Line })
+	return imports
+}
+
+// GoModulePath returns the module path declared by a go.mod file, empty if there's none
+func GoModulePath(gomod string) string {
+	for _, line := range strings.Split(gomod, "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// ResolveImport returns the path (within the repo) of the directory or file an import of the file at p refers to,
+// if it's internal to the repo: relative imports of JS/TS files, and imports of Go packages of the modules of the
+// repo (given as the directories of their go.mod files, mapped to their module path). The resolved path of JS/TS
+// imports is as written, ie. without the extension (or /index file) the module resolution would add.
+func ResolveImport(p, language, module string, goModules map[string]string) (resolved string, internal bool) {
+	switch language {
+	case "Go":
+		var best string
+		var bestDir string
+		for dir, modulePath := range goModules {
+			if (module == modulePath || strings.HasPrefix(module, modulePath+"/")) && len(modulePath) > len(best) {
+				best, bestDir = modulePath, dir
+			}
+		}
+		if best == "" {
+			return "", false
+		}
+		return path.Join(bestDir, strings.TrimPrefix(module, best)), true
+	case "JavaScript", "TypeScript":
+		if !strings.HasPrefix(module, "./") && !strings.HasPrefix(module, "../") && module != "." && module != ".." {
+			return "", false
+		}
+		var resolved = path.Join(path.Dir(p), module)
+		if strings.HasPrefix(resolved, "../") || resolved == ".." {
+			return "", false // outside of the repo
+		}
+		return resolved, true
+	default:
+		return "", false
+	}
+}
+
+// PackageJSONName returns the name of the package declared by a package.json file, empty if there's none
+func PackageJSONName(contents string) string {
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(contents), &pkg); err != nil {
+		return ""
+	}
+	return pkg.Name
+}
@@ -0,0 +1,96 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImportsGo(t *testing.T) {
+	const contents = `package main
+
+import "fmt"
+
+import (
+	"context"
+	pgx "github.com/jackc/pgx/v4"
+	_ "github.com/mergestat/mergestat/internal/db"
+)
+
+func main() { fmt.Println(context.Background(), pgx.Identifier{}) }
+`
+	var want = []Import{
+		{Line: 3, Module: "fmt", Kind: "import"},
+		{Line: 6, Module: "context", Kind: "import"},
+		{Line: 7, Module: "github.com/jackc/pgx/v4", Kind: "import"},
+		{Line: 8, Module: "github.com/mergestat/mergestat/internal/db", Kind: "import"},
+	}
+	if got := ParseImports(ImportLanguage("cmd/main.go"), contents); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseImports() = %+v, want %+v", got, want)
+	}
+
+	if got := ParseImports("Go", "not go"); len(got) != 0 {
+		t.Errorf("ParseImports() of an invalid file = %+v, want none", got)
+	}
+}
+
+func TestParseImportsJS(t *testing.T) {
+	const contents = `import React from 'react'
+import {
+  useState,
+  useEffect,
+} from "react"
+import './styles.css'
+import type { Repo } from '../types'
+export * from './utils'
+// import ignored from 'commented-out'
+/* const alsoIgnored = require('block-comment') */
+const lodash = require('lodash')
+const page = await import('./pages/home')
+`
+	var want = []Import{
+		{Line: 1, Module: "react", Kind: "import"},
+		{Line: 5, Module: "react", Kind: "import"},
+		{Line: 6, Module: "./styles.css", Kind: "import"},
+		{Line: 7, Module: "../types", Kind: "import"},
+		{Line: 8, Module: "./utils", Kind: "import"},
+		{Line: 11, Module: "lodash", Kind: "require"},
+		{Line: 12, Module: "./pages/home", Kind: "dynamic"},
+	}
+	if got := ParseImports(ImportLanguage("ui/src/app.tsx"), contents); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseImports() = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolveImport(t *testing.T) {
+	var modules = map[string]string{
+		".":      "github.com/mergestat/mergestat",
+		"pkg/sq": "github.com/mergestat/mergestat/pkg/sq",
+	}
+
+	for _, tt := range []struct {
+		path, language, module string
+		resolved               string
+		internal               bool
+	}{
+		{"cmd/worker/main.go", "Go", "github.com/mergestat/mergestat/internal/db", "internal/db", true},
+		{"cmd/worker/main.go", "Go", "github.com/mergestat/mergestat/pkg/sq/parser", "pkg/sq/parser", true},
+		{"cmd/worker/main.go", "Go", "github.com/mergestat/mergestat-lite", "", false},
+		{"cmd/worker/main.go", "Go", "fmt", "", false},
+		{"ui/src/app.tsx", "TypeScript", "./pages/home", "ui/src/pages/home", true},
+		{"ui/src/app.tsx", "TypeScript", "../../lib", "lib", true},
+		{"ui/src/app.tsx", "TypeScript", "../../../outside", "", false},
+		{"ui/src/app.tsx", "TypeScript", "react", "", false},
+	} {
+		if resolved, internal := ResolveImport(tt.path, tt.language, tt.module, modules); resolved != tt.resolved || internal != tt.internal {
+			t.Errorf("ResolveImport(%q, %q) = %q, %t, want %q, %t", tt.path, tt.module, resolved, internal, tt.resolved, tt.internal)
+		}
+	}
+
+	if got := GoModulePath("// comment\nmodule github.com/mergestat/mergestat\n\ngo 1.19\n"); got != "github.com/mergestat/mergestat" {
+		t.Errorf("GoModulePath() = %q", got)
+	}
+
+	if got := PackageJSONName(`{"name": "@mergestat/ui", "version": "1.0.0"}`); got != "@mergestat/ui" {
+		t.Errorf("PackageJSONName() = %q", got)
+	}
+}
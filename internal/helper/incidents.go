@@ -0,0 +1,39 @@
+package helper
+
+import (
+	"strings"
+	"time"
+)
+
+// IncidentStatus normalizes the status of a PagerDuty (triggered, acknowledged or resolved) or Opsgenie (open,
+// resolved or closed) incident to OPEN, ACKNOWLEDGED or RESOLVED. Unknown statuses are considered open.
+func IncidentStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "acknowledged":
+		return "ACKNOWLEDGED"
+	case "resolved", "closed":
+		return "RESOLVED"
+	default:
+		return "OPEN"
+	}
+}
+
+// TimeWindow is a range of time, from (inclusive) Since to (exclusive) Until
+type TimeWindow struct {
+	Since, Until time.Time
+}
+
+// SplitTimeRange splits the range from since to until into consecutive windows of at most max each (eg. as apis
+// such as PagerDuty's limit the range of a search), in order. It returns nothing if the range is empty.
+func SplitTimeRange(since, until time.Time, max time.Duration) []TimeWindow {
+	var windows []TimeWindow
+	for since.Before(until) {
+		var end = since.Add(max)
+		if end.After(until) {
+			end = until
+		}
+		windows = append(windows, TimeWindow{Since: since, Until: end})
+		since = end
+	}
+	return windows
+}
@@ -0,0 +1,36 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIncidentStatus(t *testing.T) {
+	for status, want := range map[string]string{
+		"triggered": "OPEN", "open": "OPEN", "acknowledged": "ACKNOWLEDGED", "resolved": "RESOLVED", "closed": "RESOLVED", "": "OPEN",
+	} {
+		if got := IncidentStatus(status); got != want {
+			t.Errorf("IncidentStatus(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestSplitTimeRange(t *testing.T) {
+	var since = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var day = 24 * time.Hour
+
+	var windows = SplitTimeRange(since, since.Add(10*day), 4*day)
+	if len(windows) != 3 {
+		t.Fatalf("SplitTimeRange() = %+v, want 3 windows", windows)
+	}
+	if !windows[0].Since.Equal(since) || !windows[1].Since.Equal(windows[0].Until) || !windows[2].Until.Equal(since.Add(10*day)) {
+		t.Errorf("SplitTimeRange() = %+v, want consecutive windows covering the range", windows)
+	}
+	if got := windows[2].Until.Sub(windows[2].Since); got != 2*day {
+		t.Errorf("last window spans %s, want %s", got, 2*day)
+	}
+
+	if windows = SplitTimeRange(since, since, day); len(windows) != 0 {
+		t.Errorf("SplitTimeRange() of an empty range = %+v", windows)
+	}
+}
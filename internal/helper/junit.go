@@ -0,0 +1,106 @@
+package helper
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTestMessageBytes is the length the failure (or error) messages of test cases are truncated to
+const maxTestMessageBytes = 4096
+
+// TestCase is the result of a test case of a JUnit XML report
+type TestCase struct {
+	Suite     string
+	ClassName string
+	Name      string
+
+	// Status is passed, failed, error or skipped
+	Status string
+
+	// Duration is in seconds
+	Duration float64
+
+	// Message is the message of the failure, error or skip (falling back to its text), truncated to 4 KiB
+	Message string
+}
+
+// junitSuite is a <testsuite> (or <testsuites>) element, which may nest suites
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []junitCase  `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitOutcome `xml:"failure"`
+	Error     *junitOutcome `xml:"error"`
+	Skipped   *junitOutcome `xml:"skipped"`
+}
+
+type junitOutcome struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ParseJUnit parses the test cases of a JUnit XML report, whose root is either <testsuites> or <testsuite>
+// (as written by most test runners, eg. Maven Surefire, pytest, jest-junit or gotestsum)
+func ParseJUnit(r io.Reader) ([]TestCase, error) {
+	var root junitSuite
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, err
+	}
+
+	var cases []TestCase
+	var walk func(s junitSuite, suite string)
+	walk = func(s junitSuite, suite string) {
+		if s.Name != "" {
+			suite = s.Name
+		}
+		for _, c := range s.Cases {
+			var tc = TestCase{Suite: suite, ClassName: c.ClassName, Name: c.Name, Status: "passed"}
+			tc.Duration, _ = strconv.ParseFloat(strings.TrimSpace(strings.ReplaceAll(c.Time, ",", "")), 64)
+
+			var outcome *junitOutcome
+			switch {
+			case c.Failure != nil:
+				tc.Status, outcome = "failed", c.Failure
+			case c.Error != nil:
+				tc.Status, outcome = "error", c.Error
+			case c.Skipped != nil:
+				tc.Status, outcome = "skipped", c.Skipped
+			}
+			if outcome != nil {
+				tc.Message = truncateMessage(strings.TrimSpace(outcome.Message))
+				if tc.Message == "" {
+					tc.Message = truncateMessage(strings.TrimSpace(outcome.Text))
+				}
+			}
+
+			cases = append(cases, tc)
+		}
+		for _, nested := range s.Suites {
+			walk(nested, suite)
+		}
+	}
+	walk(root, "")
+
+	return cases, nil
+}
+
+// truncateMessage truncates s to maxTestMessageBytes, on a rune boundary
+func truncateMessage(s string) string {
+	if len(s) <= maxTestMessageBytes {
+		return s
+	}
+	var cut = maxTestMessageBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
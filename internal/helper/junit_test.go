@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJUnit(t *testing.T) {
+	const report = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="jest tests">
+  <testsuite name="api" tests="2">
+    <testcase classname="api.repos" name="lists repos" time="0.120"/>
+    <testcase classname="api.repos" name="imports repos" time="1,204.5">
+      <failure message="expected 2 repos, got 1">stack trace</failure>
+    </testcase>
+    <testsuite name="api.auth">
+      <testcase classname="api.auth" name="rejects expired tokens" time="0.01">
+        <error>connection refused</error>
+      </testcase>
+    </testsuite>
+  </testsuite>
+  <testsuite name="ui">
+    <testcase classname="ui.table" name="sorts columns">
+      <skipped message="flaky on CI"/>
+    </testcase>
+  </testsuite>
+</testsuites>`
+
+	var cases, err = ParseJUnit(strings.NewReader(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want = []TestCase{
+		{Suite: "api", ClassName: "api.repos", Name: "lists repos", Status: "passed", Duration: 0.12},
+		{Suite: "api", ClassName: "api.repos", Name: "imports repos", Status: "failed", Duration: 1204.5, Message: "expected 2 repos, got 1"},
+		{Suite: "api.auth", ClassName: "api.auth", Name: "rejects expired tokens", Status: "error", Duration: 0.01, Message: "connection refused"},
+		{Suite: "ui", ClassName: "ui.table", Name: "sorts columns", Status: "skipped", Message: "flaky on CI"},
+	}
+	if !reflect.DeepEqual(cases, want) {
+		t.Errorf("ParseJUnit() = %+v, want %+v", cases, want)
+	}
+
+	// a single suite as root, as written by Maven Surefire
+	if cases, err = ParseJUnit(strings.NewReader(`<testsuite name="com.acme.RepoTest"><testcase name="saves" classname="com.acme.RepoTest" time="0.5"/></testsuite>`)); err != nil {
+		t.Fatal(err)
+	} else if len(cases) != 1 || cases[0].Suite != "com.acme.RepoTest" || cases[0].Status != "passed" {
+		t.Errorf("ParseJUnit() of a single suite = %+v", cases)
+	}
+
+	if _, err = ParseJUnit(strings.NewReader("not xml")); err == nil {
+		t.Errorf("ParseJUnit() of an invalid report should fail")
+	}
+}
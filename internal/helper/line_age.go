@@ -0,0 +1,78 @@
+package helper
+
+import "time"
+
+// year is the length of a year the age of lines is measured in
+const year = 365 * 24 * time.Hour
+
+// LineAges is the age histogram of the lines of a file, by the author date of the commit that last modified them
+type LineAges struct {
+	Lines int
+
+	// OlderThan1Y, OlderThan2Y and OlderThan3Y are the number of lines last modified more than 1, 2 and 3 years ago
+	OlderThan1Y, OlderThan2Y, OlderThan3Y int
+
+	// MedianAge is the median age of the lines
+	MedianAge time.Duration
+
+	Oldest, Newest time.Time
+}
+
+// Share returns the percentage (0 to 100) of the lines among the given number
+func (a *LineAges) Share(lines int) float64 {
+	if a.Lines == 0 {
+		return 0
+	}
+	return float64(lines) * 100 / float64(a.Lines)
+}
+
+// ComputeLineAges returns the age histogram, as of now, of lines last modified at the given times
+func ComputeLineAges(modified []time.Time, now time.Time) LineAges {
+	var ages = LineAges{Lines: len(modified)}
+	if len(modified) == 0 {
+		return ages
+	}
+
+	// counting the lines of each age (in days) gives the median without sorting the (possibly many) lines
+	var days = make(map[int]int)
+	var maxDays int
+	for _, when := range modified {
+		var age = now.Sub(when)
+		if age < 0 {
+			age = 0
+		}
+		switch {
+		case age > 3*year:
+			ages.OlderThan3Y++
+			fallthrough
+		case age > 2*year:
+			ages.OlderThan2Y++
+			fallthrough
+		case age > year:
+			ages.OlderThan1Y++
+		}
+
+		var d = int(age / (24 * time.Hour))
+		days[d]++
+		if d > maxDays {
+			maxDays = d
+		}
+
+		if ages.Oldest.IsZero() || when.Before(ages.Oldest) {
+			ages.Oldest = when
+		}
+		if when.After(ages.Newest) {
+			ages.Newest = when
+		}
+	}
+
+	var seen int
+	for d := 0; d <= maxDays; d++ {
+		if seen += days[d]; seen*2 >= len(modified) {
+			ages.MedianAge = time.Duration(d) * 24 * time.Hour
+			break
+		}
+	}
+
+	return ages
+}
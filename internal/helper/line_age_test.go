@@ -0,0 +1,29 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeLineAges(t *testing.T) {
+	var now = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	var daysAgo = func(d int) time.Time { return now.Add(-time.Duration(d) * 24 * time.Hour) }
+
+	var ages = ComputeLineAges([]time.Time{daysAgo(10), daysAgo(10), daysAgo(400), daysAgo(800), daysAgo(1200), daysAgo(1500)}, now)
+	if ages.Lines != 6 || ages.OlderThan1Y != 4 || ages.OlderThan2Y != 3 || ages.OlderThan3Y != 2 {
+		t.Errorf("unexpected histogram: %+v", ages)
+	}
+	if ages.MedianAge != 400*24*time.Hour {
+		t.Errorf("median age = %s, want 400 days", ages.MedianAge)
+	}
+	if !ages.Oldest.Equal(daysAgo(1500)) || !ages.Newest.Equal(daysAgo(10)) {
+		t.Errorf("unexpected oldest/newest: %s, %s", ages.Oldest, ages.Newest)
+	}
+	if share := ages.Share(ages.OlderThan2Y); share != 50 {
+		t.Errorf("share older than 2 years = %.1f, want 50", share)
+	}
+
+	if empty := ComputeLineAges(nil, now); empty.Lines != 0 || empty.Share(0) != 0 {
+		t.Errorf("unexpected histogram of no lines: %+v", empty)
+	}
+}
@@ -0,0 +1,83 @@
+package helper
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// FilePatch is the unified diff of a file changed by a commit, possibly truncated
+type FilePatch struct {
+	Path, OldPath string
+	Patch         string
+
+	// Size is the size (in bytes) of the whole patch, and Truncated is set if Patch holds only part of it
+	Size      int
+	Truncated bool
+	Binary    bool
+}
+
+// PatchBuilder accumulates the patches of the files changed by a commit, truncating the patch of each file at
+// maxFileBytes, and the patches of the commit at maxCommitBytes in total (a cap of 0 or less is no cap)
+type PatchBuilder struct {
+	maxFile, maxCommit int
+
+	used  int
+	files []*FilePatch
+	patch strings.Builder
+}
+
+func NewPatchBuilder(maxFileBytes, maxCommitBytes int) *PatchBuilder {
+	return &PatchBuilder{maxFile: maxFileBytes, maxCommit: maxCommitBytes}
+}
+
+// File starts the patch of a changed file, the patch of a binary file is left empty
+func (b *PatchBuilder) File(oldPath, path string, binary bool) {
+	b.flush()
+	b.files = append(b.files, &FilePatch{Path: path, OldPath: oldPath, Binary: binary})
+	if !binary {
+		b.Write("--- a/" + oldPath + "\n+++ b/" + path + "\n")
+	}
+}
+
+// Write appends s to the patch of the current file, as far as the caps allow
+func (b *PatchBuilder) Write(s string) {
+	if len(b.files) == 0 {
+		return
+	}
+	var f = b.files[len(b.files)-1]
+	f.Size += len(s)
+	if f.Truncated {
+		return
+	}
+
+	var room = len(s)
+	if b.maxFile > 0 && b.maxFile-b.patch.Len() < room {
+		room = b.maxFile - b.patch.Len()
+	}
+	if b.maxCommit > 0 && b.maxCommit-b.used < room {
+		room = b.maxCommit - b.used
+	}
+	if room < len(s) {
+		// never cut a rune in half
+		for room > 0 && !utf8.RuneStart(s[room]) {
+			room--
+		}
+		s, f.Truncated = s[:room], true
+	}
+
+	b.patch.WriteString(s)
+	b.used += len(s)
+}
+
+func (b *PatchBuilder) flush() {
+	if len(b.files) > 0 {
+		b.files[len(b.files)-1].Patch = b.patch.String()
+	}
+	b.patch.Reset()
+}
+
+// Files returns the patches of the files changed by the commit
+func (b *PatchBuilder) Files() []*FilePatch {
+	b.flush()
+	return b.files
+}
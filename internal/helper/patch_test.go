@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatchBuilder(t *testing.T) {
+	var b = NewPatchBuilder(40, 70)
+
+	// the header (22 bytes) and hunk header (12 bytes) leave 6 bytes for the lines, which ends in the middle of é
+	b.File("a.go", "a.go", false)
+	b.Write("@@ -1 +1 @@\n")
+	b.Write("-abcdé\n+world\n")
+
+	b.File("logo.png", "logo.png", true)
+
+	// the commit cap leaves 31 bytes for the file, its header (26 bytes) and part of its hunk header
+	b.File("old.go", "new.go", false)
+	b.Write("@@ -1 +1 @@\n")
+
+	var files = b.Files()
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+
+	if f := files[0]; !f.Truncated || f.Patch != "--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-abcd" || f.Size != 49 {
+		t.Errorf("unexpected patch of a.go: %+v", f)
+	}
+	if f := files[1]; !f.Binary || f.Patch != "" || f.Truncated {
+		t.Errorf("unexpected patch of logo.png: %+v", f)
+	}
+	if f := files[2]; !f.Truncated || f.OldPath != "old.go" || f.Patch != "--- a/old.go\n+++ b/new.go\n@@ -1" {
+		t.Errorf("unexpected patch of new.go: %+v", f)
+	}
+}
+
+func TestPatchBuilderWithoutCaps(t *testing.T) {
+	var b = NewPatchBuilder(0, 0)
+	b.File("a", "a", false)
+	b.Write(strings.Repeat("+x\n", 1000))
+
+	if f := b.Files()[0]; f.Truncated || len(f.Patch) != f.Size {
+		t.Errorf("patch shouldn't be truncated: %d of %d bytes", len(f.Patch), f.Size)
+	}
+}
@@ -0,0 +1,86 @@
+package helper
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// RepoImportKind is the kind of file a repo with no remote (eg. from a decommissioned server) is imported from
+type RepoImportKind string
+
+const (
+	// RepoImportBundle is a git bundle (git bundle create repo.bundle --all)
+	RepoImportBundle RepoImportKind = "bundle"
+
+	// RepoImportFastExport is a fast-export stream (git fast-export --all > repo.fast-export), optionally gzipped
+	RepoImportFastExport RepoImportKind = "fast-export"
+)
+
+// repoImportExtensions maps the extensions of the files repos are imported from to their kind
+var repoImportExtensions = map[string]RepoImportKind{
+	".bundle":      RepoImportBundle,
+	".fast-export": RepoImportFastExport,
+	".fi":          RepoImportFastExport,
+}
+
+// RepoImportSource returns the kind and path of the file the repo with the given url is imported from, if its url is
+// the absolute path (or file:// url) of a git bundle or a fast-export stream, eg. /imports/legacy/app.bundle or
+// file:///imports/legacy/app.fast-export.gz. It returns an empty kind for any other url, which is cloned.
+//
+// Files are only imported from within the root directory (see REPO_IMPORT_DIR), with symbolic links resolved: an
+// import from anywhere else (or from anywhere, without a root) is an error, rather than a read of the worker's files.
+func RepoImportSource(url, root string) (RepoImportKind, string, error) {
+	var path = strings.TrimPrefix(url, "file://")
+	if !filepath.IsAbs(path) {
+		return "", "", nil
+	}
+
+	var ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(path, ".gz")))
+	var kind, ok = repoImportExtensions[ext]
+	if !ok || (kind == RepoImportBundle && strings.HasSuffix(path, ".gz")) {
+		return "", "", nil
+	}
+
+	if root == "" {
+		return "", "", fmt.Errorf("repos can't be imported from files unless REPO_IMPORT_DIR is set")
+	}
+
+	path = filepath.Clean(path)
+	if !withinDir(resolveSymlinks(path), resolveSymlinks(root)) {
+		return "", "", fmt.Errorf("%s is not within the import directory (REPO_IMPORT_DIR)", path)
+	}
+
+	return kind, path, nil
+}
+
+// resolveSymlinks returns the path with its symbolic links resolved, or cleaned if it doesn't exist (yet)
+func resolveSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// withinDir reports whether the (absolute, clean) path is within the directory
+func withinDir(path, dir string) bool {
+	var rel, err = filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// DefaultImportBranch returns the branch HEAD of an imported repo points to, given its branches (as refs/heads/...):
+// main or master if it has them, its first branch otherwise, or an empty string if it has none.
+func DefaultImportBranch(branches []string) string {
+	for _, preferred := range []string{"refs/heads/main", "refs/heads/master"} {
+		for _, b := range branches {
+			if b == preferred {
+				return b
+			}
+		}
+	}
+
+	if len(branches) > 0 {
+		return branches[0]
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package helper
+
+import "testing"
+
+func TestRepoImportSource(t *testing.T) {
+	for _, tt := range []struct {
+		url  string
+		kind RepoImportKind
+		path string
+	}{
+		{"/imports/app.bundle", RepoImportBundle, "/imports/app.bundle"},
+		{"file:///imports/legacy/app.BUNDLE", RepoImportBundle, "/imports/legacy/app.BUNDLE"},
+		{"/imports/app.fast-export", RepoImportFastExport, "/imports/app.fast-export"},
+		{"file:///imports/app.fi.gz", RepoImportFastExport, "/imports/app.fi.gz"},
+		{"/imports/app.bundle.gz", "", ""},
+		{"imports/app.bundle", "", ""},
+		{"https://example.com/app.bundle", "", ""},
+		{"https://github.com/mergestat/mergestat", "", ""},
+		{"file:///srv/git/app.git", "", ""},
+	} {
+		if kind, path, err := RepoImportSource(tt.url, "/imports"); err != nil || kind != tt.kind || path != tt.path {
+			t.Errorf("RepoImportSource(%q) = %q, %q, %v, want %q, %q", tt.url, kind, path, err, tt.kind, tt.path)
+		}
+	}
+}
+
+func TestRepoImportSourceOutsideRoot(t *testing.T) {
+	for _, tt := range []struct {
+		url  string
+		root string
+	}{
+		{"/etc/app.bundle", "/imports"},
+		{"/imports/../etc/app.bundle", "/imports"},
+		{"file:///imports-other/app.fast-export", "/imports"},
+		{"/imports.bundle", "/imports"},
+		{"/imports/app.bundle", ""},
+	} {
+		if kind, path, err := RepoImportSource(tt.url, tt.root); err == nil {
+			t.Errorf("RepoImportSource(%q, %q) = %q, %q, want an error", tt.url, tt.root, kind, path)
+		}
+	}
+}
+
+func TestDefaultImportBranch(t *testing.T) {
+	for _, tt := range []struct {
+		branches []string
+		want     string
+	}{
+		{[]string{"refs/heads/develop", "refs/heads/master", "refs/heads/main"}, "refs/heads/main"},
+		{[]string{"refs/heads/develop", "refs/heads/master"}, "refs/heads/master"},
+		{[]string{"refs/heads/trunk", "refs/heads/develop"}, "refs/heads/trunk"},
+		{nil, ""},
+	} {
+		if got := DefaultImportBranch(tt.branches); got != tt.want {
+			t.Errorf("DefaultImportBranch(%v) = %q, want %q", tt.branches, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package helper
+
+import (
+	"strings"
+	"time"
+)
+
+// RepoSizeTier is the size class of a repo, which the strategy of its syncs is chosen by (see SyncStrategyFor)
+type RepoSizeTier string
+
+const (
+	RepoSizeSmall  RepoSizeTier = "small"
+	RepoSizeMedium RepoSizeTier = "medium"
+	RepoSizeLarge  RepoSizeTier = "large"
+	RepoSizeHuge   RepoSizeTier = "huge"
+)
+
+// repoSizeTiers are the tiers, by their (exclusive) upper bound in bytes
+var repoSizeTiers = []struct {
+	tier RepoSizeTier
+	max  int64
+}{
+	{RepoSizeSmall, 50 << 20},
+	{RepoSizeMedium, 1 << 30},
+	{RepoSizeLarge, 5 << 30},
+}
+
+// ClassifyRepoSize returns the tier of a repo of the given size (in bytes). The size is the largest known of the repo,
+// eg. its disk usage as reported by GitHub or the size of its last clone. A repo of unknown size (0) is medium.
+func ClassifyRepoSize(size int64) RepoSizeTier {
+	if size <= 0 {
+		return RepoSizeMedium
+	}
+	for _, t := range repoSizeTiers {
+		if size < t.max {
+			return t.tier
+		}
+	}
+	return RepoSizeHuge
+}
+
+// ParseRepoSizeTier returns the tier of the given name, or false if there is none (eg. to override the tier of a repo)
+func ParseRepoSizeTier(name string) (RepoSizeTier, bool) {
+	switch tier := RepoSizeTier(strings.ToLower(strings.TrimSpace(name))); tier {
+	case RepoSizeSmall, RepoSizeMedium, RepoSizeLarge, RepoSizeHuge:
+		return tier, true
+	}
+	return "", false
+}
+
+// SyncStrategy is how the syncs of a repo are run, depending on its size
+type SyncStrategy struct {
+	Tier RepoSizeTier
+
+	// NoTags, if set, doesn't fetch the tags when cloning the repo for the syncs that don't read its refs
+	NoTags bool
+
+	// Shallow, if set, clones only the commit at HEAD for the syncs that only read the files at HEAD
+	Shallow bool
+
+	// CloneTimeout is the time cloning the repo may take
+	CloneTimeout time.Duration
+
+	// BatchSize is the number of rows copied to the database at once
+	BatchSize int
+
+	// TimeoutFactor multiplies the statement timeout of the transactions of syncs
+	TimeoutFactor int
+}
+
+// SyncStrategyFor returns the strategy of the syncs of repos of the given tier
+func SyncStrategyFor(tier RepoSizeTier) SyncStrategy {
+	switch tier {
+	case RepoSizeSmall:
+		return SyncStrategy{Tier: tier, CloneTimeout: 10 * time.Minute, BatchSize: 100, TimeoutFactor: 1}
+	case RepoSizeLarge:
+		return SyncStrategy{Tier: tier, NoTags: true, Shallow: true, CloneTimeout: time.Hour, BatchSize: 1000, TimeoutFactor: 2}
+	case RepoSizeHuge:
+		return SyncStrategy{Tier: tier, NoTags: true, Shallow: true, CloneTimeout: 3 * time.Hour, BatchSize: 5000, TimeoutFactor: 4}
+	default:
+		return SyncStrategy{Tier: RepoSizeMedium, CloneTimeout: 30 * time.Minute, BatchSize: 500, TimeoutFactor: 1}
+	}
+}
@@ -0,0 +1,47 @@
+package helper
+
+import "testing"
+
+func TestClassifyRepoSize(t *testing.T) {
+	for _, tt := range []struct {
+		size int64
+		tier RepoSizeTier
+	}{
+		{0, RepoSizeMedium},
+		{-1, RepoSizeMedium},
+		{1 << 20, RepoSizeSmall},
+		{50 << 20, RepoSizeMedium},
+		{(1 << 30) - 1, RepoSizeMedium},
+		{1 << 30, RepoSizeLarge},
+		{5 << 30, RepoSizeHuge},
+		{40 << 30, RepoSizeHuge},
+	} {
+		if got := ClassifyRepoSize(tt.size); got != tt.tier {
+			t.Errorf("ClassifyRepoSize(%d) = %s, want %s", tt.size, got, tt.tier)
+		}
+	}
+}
+
+func TestParseRepoSizeTier(t *testing.T) {
+	if tier, ok := ParseRepoSizeTier(" Huge "); !ok || tier != RepoSizeHuge {
+		t.Errorf("ParseRepoSizeTier(Huge) = %s, %t", tier, ok)
+	}
+	if _, ok := ParseRepoSizeTier("enormous"); ok {
+		t.Errorf("ParseRepoSizeTier(enormous) should fail")
+	}
+}
+
+func TestSyncStrategyFor(t *testing.T) {
+	if s := SyncStrategyFor("unknown"); s.Tier != RepoSizeMedium {
+		t.Errorf("strategy of an unknown tier should be medium, got %s", s.Tier)
+	}
+
+	var previous = SyncStrategyFor(RepoSizeSmall)
+	for _, tier := range []RepoSizeTier{RepoSizeMedium, RepoSizeLarge, RepoSizeHuge} {
+		var s = SyncStrategyFor(tier)
+		if s.CloneTimeout < previous.CloneTimeout || s.BatchSize < previous.BatchSize || s.TimeoutFactor < previous.TimeoutFactor {
+			t.Errorf("strategy of %s should be at least as lenient as %s", tier, previous.Tier)
+		}
+		previous = s
+	}
+}
@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// SearchIndexMethod is how a text column is indexed for search (see mergestat.search_indexes)
+type SearchIndexMethod string
+
+const (
+	// SearchIndexTSVector is a GIN index over to_tsvector(config, column), for full-text search,
+	// eg. WHERE to_tsvector('simple', message) @@ websearch_to_tsquery('simple', 'fix race')
+	SearchIndexTSVector SearchIndexMethod = "tsvector"
+
+	// SearchIndexTrigram is a GIN index with pg_trgm operators, for LIKE, ILIKE and regular expression matches,
+	// eg. WHERE contents ILIKE '%InsecureSkipVerify%'
+	SearchIndexTrigram SearchIndexMethod = "trgm"
+)
+
+// SearchIndexPrefix is the prefix of the names of the search indexes, so that they're told apart from others
+const SearchIndexPrefix = "idx_search_"
+
+// SearchIndex is a search index of a column of a synced table
+type SearchIndex struct {
+	Table  string
+	Column string
+	Method SearchIndexMethod
+
+	// Config is the text search configuration of tsvector indexes (eg. simple or english)
+	Config string
+}
+
+// Name returns the name of the index. It's derived from the whole definition, so that an index is rebuilt
+// (under a new name) when its method or configuration changes. Names longer than Postgres allows are shortened.
+func (s SearchIndex) Name() string {
+	var name = SearchIndexPrefix + s.Table + "_" + s.Column + "_" + string(s.Method)
+	if s.Method == SearchIndexTSVector {
+		name += "_" + s.Config
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, name)
+
+	// identifiers are truncated to 63 bytes, the (truncated) name is suffixed with a hash to keep it unique
+	const maxIdentifier = 63
+	if len(name) > maxIdentifier {
+		var h uint32 = 2166136261 // FNV-1a
+		for i := 0; i < len(name); i++ {
+			h = (h ^ uint32(name[i])) * 16777619
+		}
+		name = fmt.Sprintf("%s_%08x", name[:maxIdentifier-9], h)
+	}
+	return name
+}
+
+// Definition returns the CREATE INDEX statement of the index on the given table (ie. the table where it lives),
+// built concurrently so that syncs and queries of the table aren't blocked
+func (s SearchIndex) Definition(table pgx.Identifier) (string, error) {
+	var name = pgx.Identifier{s.Name()}.Sanitize()
+	var column = pgx.Identifier{s.Column}.Sanitize()
+
+	switch s.Method {
+	case SearchIndexTSVector:
+		if s.Config == "" {
+			return "", fmt.Errorf("search index of %s.%s has no text search configuration", s.Table, s.Column)
+		}
+		var config = "'" + strings.ReplaceAll(s.Config, "'", "''") + "'"
+		return fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s USING GIN (to_tsvector(%s, %s))", name, table.Sanitize(), config, column), nil
+	case SearchIndexTrigram:
+		return fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s USING GIN (%s gin_trgm_ops)", name, table.Sanitize(), column), nil
+	default:
+		return "", fmt.Errorf("unknown search index method %q", s.Method)
+	}
+}
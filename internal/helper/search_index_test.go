@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func TestSearchIndexName(t *testing.T) {
+	for _, tt := range []struct {
+		index SearchIndex
+		name  string
+	}{
+		{SearchIndex{Table: "git_commits", Column: "message", Method: SearchIndexTSVector, Config: "simple"}, "idx_search_git_commits_message_tsvector_simple"},
+		{SearchIndex{Table: "git_files", Column: "contents", Method: SearchIndexTrigram, Config: "simple"}, "idx_search_git_files_contents_trgm"},
+		{SearchIndex{Table: "github_issues", Column: "body", Method: SearchIndexTSVector, Config: "public.My-Config"}, "idx_search_github_issues_body_tsvector_public_my_config"},
+	} {
+		if got := tt.index.Name(); got != tt.name {
+			t.Errorf("Name() = %q, want %q", got, tt.name)
+		}
+	}
+
+	var long = SearchIndex{Table: "github_pull_request_reviews", Column: "body", Method: SearchIndexTSVector, Config: "english_with_stop_words"}
+	var other = long
+	other.Config = "english_with_stop_words_2"
+	if name := long.Name(); len(name) != 63 || !strings.HasPrefix(name, SearchIndexPrefix) {
+		t.Errorf("Name() = %q, want a name of 63 bytes prefixed with %s", name, SearchIndexPrefix)
+	}
+	if long.Name() == other.Name() {
+		t.Errorf("truncated names of different indexes should differ, both are %q", long.Name())
+	}
+}
+
+func TestSearchIndexDefinition(t *testing.T) {
+	var def, err = SearchIndex{Table: "git_commits", Column: "message", Method: SearchIndexTSVector, Config: "it's"}.Definition(pgx.Identifier{"public", "git_commits"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `CREATE INDEX CONCURRENTLY IF NOT EXISTS "idx_search_git_commits_message_tsvector_it_s" ON "public"."git_commits" USING GIN (to_tsvector('it''s', "message"))`
+	if def != want {
+		t.Errorf("Definition() = %s, want %s", def, want)
+	}
+
+	if def, err = (SearchIndex{Table: "git_files", Column: "contents", Method: SearchIndexTrigram}).Definition(pgx.Identifier{"mergestat_data", "ms_git_files"}); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasSuffix(def, `ON "mergestat_data"."ms_git_files" USING GIN ("contents" gin_trgm_ops)`) {
+		t.Errorf("Definition() = %s, want a pg_trgm index of the relocated table", def)
+	}
+
+	if _, err = (SearchIndex{Table: "git_files", Column: "contents", Method: "btree"}).Definition(pgx.Identifier{"git_files"}); err == nil {
+		t.Errorf("Definition() of an unknown method should fail")
+	}
+}
@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSearchLineBytes is the length lines returned by SearchLines are truncated to, so that minified files
+// (a single, very long line) don't flood the results
+const maxSearchLineBytes = 240
+
+// SearchLine is a line of a file matching a search
+type SearchLine struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+}
+
+// SearchLines returns (up to max) lines of the contents containing the term, ignoring case, with their 1-based number.
+// Lines are trimmed of surrounding whitespace and truncated (on a rune boundary) if longer than 240 bytes.
+func SearchLines(contents, term string, max int) []SearchLine {
+	if term == "" || max <= 0 {
+		return nil
+	}
+
+	var lines []SearchLine
+	var needle = strings.ToLower(term)
+	for number := 1; contents != "" && len(lines) < max; number++ {
+		var line string
+		line, contents, _ = strings.Cut(contents, "\n")
+
+		if !strings.Contains(strings.ToLower(line), needle) {
+			continue
+		}
+
+		line = strings.TrimSpace(line)
+		if len(line) > maxSearchLineBytes {
+			var cut = maxSearchLineBytes
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			line = line[:cut] + "…"
+		}
+		lines = append(lines, SearchLine{Number: number, Text: line})
+	}
+	return lines
+}
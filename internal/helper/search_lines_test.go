@@ -0,0 +1,33 @@
+package helper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchLines(t *testing.T) {
+	const contents = "package main\n\nfunc main() {\n\ttls := &tls.Config{InsecureSkipVerify: true}\n\t_ = tls\n}\n// insecureSkipVerify is set above\n"
+
+	var got = SearchLines(contents, "InsecureSkipVerify", 10)
+	var want = []SearchLine{
+		{Number: 4, Text: "tls := &tls.Config{InsecureSkipVerify: true}"},
+		{Number: 7, Text: "// insecureSkipVerify is set above"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchLines() = %v, want %v", got, want)
+	}
+
+	if got = SearchLines(contents, "tls", 1); len(got) != 1 || got[0].Number != 4 {
+		t.Errorf("SearchLines() with max 1 = %v, want only line 4", got)
+	}
+
+	if got = SearchLines(contents, "", 10); got != nil {
+		t.Errorf("SearchLines() of an empty term = %v, want none", got)
+	}
+
+	var long = strings.Repeat("é", 200) + "needle"
+	if got = SearchLines(long, "needle", 1); len(got) != 1 || !strings.HasSuffix(got[0].Text, "…") || len(got[0].Text) > 240+len("…") {
+		t.Errorf("SearchLines() of a long line = %v, want it truncated", got)
+	}
+}
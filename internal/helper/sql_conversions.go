@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
 )
 
 // JSONNullInt64 is a helper for marshalling sql.NullInt64 values into JSON
@@ -109,3 +110,22 @@ func Int64ToSqlNullInt64(v *int64) sql.NullInt64 {
 
 	return sqlNullInt64
 }
+
+// CollectStrings reads all rows, each containing a single text column, into a slice
+func CollectStrings(rows pgx.Rows, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var s string
+		if err = rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommitSubject returns the subject (first line) of a commit message
+func CommitSubject(message string) string {
+	var subject, _, _ = strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(subject)
+}
+
+// RepoSummaryPrompt returns the prompt a repo is summarized with, from the subjects of its recent commits and its
+// README, cut to at most maxBytes (the README is what's cut, it comes last)
+func RepoSummaryPrompt(repo, readme string, commits []string, maxBytes int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Repository: %s\n", repo)
+
+	if len(commits) > 0 {
+		b.WriteString("\nRecent commits:\n")
+		for _, c := range commits {
+			if subject := CommitSubject(c); subject != "" {
+				fmt.Fprintf(&b, "- %s\n", subject)
+			}
+		}
+	}
+
+	if readme = strings.TrimSpace(readme); readme != "" {
+		fmt.Fprintf(&b, "\nREADME:\n%s\n", readme)
+	}
+
+	return cutText(strings.TrimSpace(b.String()), maxBytes)
+}
+
+// PullRequestSummaryPrompt returns the prompt a pull request is summarized with, from its title, size, the subjects
+// of its commits and its description, cut to at most maxBytes (the description is what's cut, it comes last)
+func PullRequestSummaryPrompt(title, body string, additions, deletions, changedFiles int, commits []string, maxBytes int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Pull request: %s\n", strings.TrimSpace(title))
+	fmt.Fprintf(&b, "Size: %d file(s) changed, %d addition(s), %d deletion(s)\n", changedFiles, additions, deletions)
+
+	if len(commits) > 0 {
+		b.WriteString("\nCommits:\n")
+		for _, c := range commits {
+			if subject := CommitSubject(c); subject != "" {
+				fmt.Fprintf(&b, "- %s\n", subject)
+			}
+		}
+	}
+
+	if body = strings.TrimSpace(body); body != "" {
+		fmt.Fprintf(&b, "\nDescription:\n%s\n", body)
+	}
+
+	return cutText(strings.TrimSpace(b.String()), maxBytes)
+}
@@ -0,0 +1,43 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepoSummaryPrompt(t *testing.T) {
+	var prompt = RepoSummaryPrompt("github.com/mergestat/mergestat", "# MergeStat\n\nSQL for your git repos.",
+		[]string{"fix: race in watcher\n\nlong description", "", "feat: add polite mode"}, 0)
+
+	const want = `Repository: github.com/mergestat/mergestat
+
+Recent commits:
+- fix: race in watcher
+- feat: add polite mode
+
+README:
+# MergeStat
+
+SQL for your git repos.`
+	if prompt != want {
+		t.Errorf("RepoSummaryPrompt() = %q, want %q", prompt, want)
+	}
+
+	// the README is cut first, as it comes last
+	if cut := RepoSummaryPrompt("github.com/mergestat/mergestat", strings.Repeat("docs ", 1000), []string{"fix: race"}, 100); len(cut) > 100 || !strings.Contains(cut, "- fix: race") {
+		t.Errorf("RepoSummaryPrompt() = %q, want at most 100 bytes keeping the commits", cut)
+	}
+}
+
+func TestPullRequestSummaryPrompt(t *testing.T) {
+	var prompt = PullRequestSummaryPrompt("Rewrite the scheduler", "", 1200, 300, 14, []string{"refactor: split queue"}, 0)
+
+	const want = `Pull request: Rewrite the scheduler
+Size: 14 file(s) changed, 1200 addition(s), 300 deletion(s)
+
+Commits:
+- refactor: split queue`
+	if prompt != want {
+		t.Errorf("PullRequestSummaryPrompt() = %q, want %q", prompt, want)
+	}
+}
@@ -0,0 +1,246 @@
+package helper
+
+import (
+	"sort"
+	"time"
+)
+
+// PlannedSync is a sync of a simulated sync cycle (see SimulateSyncCycle)
+type PlannedSync struct {
+	Repo     string
+	Group    string
+	Priority int
+
+	// Exclusive is true if the repo only runs one sync (of any type) at a time (the exclusiveSyncs repo setting)
+	Exclusive bool
+
+	// Duration is the expected duration of the sync, eg. its median duration over the previous cycles
+	Duration time.Duration
+
+	// Provider is the provider whose api the sync calls, empty if it doesn't call any
+	Provider string
+
+	// APICalls is the expected number of api calls of the sync
+	APICalls int
+}
+
+// SyncPlanGroup is the concurrency of a type group (see mergestat.repo_sync_type_groups)
+type SyncPlanGroup struct {
+	ConcurrentSyncs  int
+	MaxBorrowedSyncs int
+}
+
+// SyncPlanConfig is a configuration of the workers a sync cycle is simulated with
+type SyncPlanConfig struct {
+	// Slots is the total number of syncs the workers run at once (workers times their CONCURRENCY)
+	Slots int
+
+	// Groups is the concurrency of each type group, syncs of groups not in it are not limited
+	Groups map[string]SyncPlanGroup
+
+	// APIBudgets is the maximum number of api calls per hour of each provider, providers not in it are not limited
+	APIBudgets map[string]int
+}
+
+// SyncPlanGroupReport is the simulated cycle of the syncs of a type group
+type SyncPlanGroupReport struct {
+	Group string
+	Syncs int
+
+	// Busy is the total duration of the syncs of the group
+	Busy time.Duration
+
+	// MeanWait and MaxWait are the time syncs of the group waited in the queue before they started
+	MeanWait, MaxWait time.Duration
+
+	// Done is when the last sync of the group completed, since the start of the cycle
+	Done time.Duration
+}
+
+// SyncPlanReport is the outcome of a simulated sync cycle
+type SyncPlanReport struct {
+	// Duration is the time it takes for every sync of the cycle to complete
+	Duration time.Duration
+
+	// MeanWait and MaxWait are the time syncs waited in the queue before they started
+	MeanWait, MaxWait time.Duration
+
+	// Utilization is the share of the slots busy over the cycle
+	Utilization float64
+
+	Groups []SyncPlanGroupReport
+
+	// APICalls and PeakAPICallsPerHour are the api calls of the cycle by provider: in total, and in its busiest hour
+	APICalls            map[string]int
+	PeakAPICallsPerHour map[string]int
+
+	// Deferred is the number of syncs that were held back (at least once) by an exhausted api budget
+	Deferred int
+
+	// Stuck is the number of syncs that could never start (eg. their group has a concurrency of 0)
+	Stuck int
+}
+
+// SimulateSyncCycle simulates a cycle of the given syncs, all queued at once, dequeued as the workers do: by priority
+// (lowest first) then in order, as long as a slot is free, their type group has room (or may borrow an idle slot),
+// their repo isn't running an exclusive sync, and the api budget of their provider isn't exhausted for the hour.
+func SimulateSyncCycle(syncs []PlannedSync, config SyncPlanConfig) *SyncPlanReport {
+	type job struct {
+		*PlannedSync
+		start, end time.Duration
+		deferred   bool
+	}
+
+	var pending = make([]*job, len(syncs))
+	for i := range syncs {
+		pending[i] = &job{PlannedSync: &syncs[i]}
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].Priority < pending[j].Priority })
+
+	var slots = config.Slots
+	if slots <= 0 {
+		slots = 1
+	}
+
+	var running []*job
+	var runningByGroup = make(map[string]int)
+	var runningRepos = make(map[string]int)
+	var calls = make(map[string]map[int64]int) // provider -> hour -> calls
+	var done []*job
+
+	// room returns whether a sync of the group can start, possibly borrowing an idle slot of another group
+	var room = func(group string) bool {
+		var g, limited = config.Groups[group]
+		if !limited || runningByGroup[group] < g.ConcurrentSyncs {
+			return true
+		}
+
+		var idle, borrowed int
+		for name, other := range config.Groups {
+			if free := other.ConcurrentSyncs - runningByGroup[name]; free > 0 {
+				idle += free
+			} else {
+				borrowed -= free
+			}
+		}
+		return runningByGroup[group]-g.ConcurrentSyncs < g.MaxBorrowedSyncs && idle > borrowed
+	}
+
+	var exhausted = func(provider string, now time.Duration) bool {
+		var budget, limited = config.APIBudgets[provider]
+		return provider != "" && limited && calls[provider][int64(now/time.Hour)] >= budget
+	}
+
+	var now time.Duration
+	for len(pending) > 0 || len(running) > 0 {
+		var held bool
+		for i := 0; i < len(pending) && len(running) < slots; {
+			var j = pending[i]
+			switch {
+			case !room(j.Group), j.Exclusive && runningRepos[j.Repo] > 0:
+				i++
+				continue
+			case exhausted(j.Provider, now):
+				j.deferred, held = true, true
+				i++
+				continue
+			}
+
+			j.start, j.end = now, now+j.Duration
+			running = append(running, j)
+			runningByGroup[j.Group]++
+			runningRepos[j.Repo]++
+			pending = append(pending[:i], pending[i+1:]...)
+		}
+
+		// advance to the next completion, or to the next hour if a budget held syncs back (and nothing runs)
+		var next = time.Duration(-1)
+		for _, j := range running {
+			if next < 0 || j.end < next {
+				next = j.end
+			}
+		}
+		if held && (next < 0 || next > (now/time.Hour+1)*time.Hour) {
+			next = (now/time.Hour + 1) * time.Hour
+		}
+		if next < 0 {
+			break // the pending syncs can never start (eg. their group has a concurrency of 0)
+		}
+		now = next
+
+		var still = running[:0]
+		for _, j := range running {
+			if j.end > now {
+				still = append(still, j)
+				continue
+			}
+			runningByGroup[j.Group]--
+			runningRepos[j.Repo]--
+			// the calls are recorded against the budget once the sync completes, as the workers do
+			if j.Provider != "" {
+				if calls[j.Provider] == nil {
+					calls[j.Provider] = make(map[int64]int)
+				}
+				calls[j.Provider][int64(j.end/time.Hour)] += j.APICalls
+			}
+			done = append(done, j)
+		}
+		running = still
+	}
+
+	var report = &SyncPlanReport{APICalls: make(map[string]int), PeakAPICallsPerHour: make(map[string]int), Stuck: len(pending)}
+	var groups = make(map[string]*SyncPlanGroupReport)
+	var busy, wait time.Duration
+	for _, j := range done {
+		var g = groups[j.Group]
+		if g == nil {
+			g = &SyncPlanGroupReport{Group: j.Group}
+			groups[j.Group] = g
+		}
+		g.Syncs++
+		g.Busy += j.Duration
+		g.MeanWait += j.start
+		if j.start > g.MaxWait {
+			g.MaxWait = j.start
+		}
+		if j.end > g.Done {
+			g.Done = j.end
+		}
+
+		busy += j.Duration
+		wait += j.start
+		if j.start > report.MaxWait {
+			report.MaxWait = j.start
+		}
+		if j.end > report.Duration {
+			report.Duration = j.end
+		}
+		if j.deferred {
+			report.Deferred++
+		}
+	}
+
+	if len(done) > 0 {
+		report.MeanWait = wait / time.Duration(len(done))
+	}
+	if report.Duration > 0 {
+		report.Utilization = float64(busy) / (float64(report.Duration) * float64(slots))
+	}
+
+	for _, g := range groups {
+		g.MeanWait /= time.Duration(g.Syncs)
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool { return report.Groups[i].Group < report.Groups[j].Group })
+
+	for provider, hours := range calls {
+		for _, n := range hours {
+			report.APICalls[provider] += n
+			if n > report.PeakAPICallsPerHour[provider] {
+				report.PeakAPICallsPerHour[provider] = n
+			}
+		}
+	}
+
+	return report
+}
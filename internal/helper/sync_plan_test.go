@@ -0,0 +1,76 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulateSyncCycle(t *testing.T) {
+	var minutes = func(n int) time.Duration { return time.Duration(n) * time.Minute }
+
+	t.Run("slots", func(t *testing.T) {
+		var syncs = []PlannedSync{{Group: "DEFAULT", Duration: minutes(10)}, {Group: "DEFAULT", Duration: minutes(10)}, {Group: "DEFAULT", Duration: minutes(10)}}
+
+		var report = SimulateSyncCycle(syncs, SyncPlanConfig{Slots: 2})
+		if report.Duration != minutes(20) || report.MaxWait != minutes(10) {
+			t.Fatalf("expected a 20m cycle with a 10m max wait, got %s and %s", report.Duration, report.MaxWait)
+		}
+		if report.Utilization != 0.75 {
+			t.Fatalf("expected a 0.75 utilization, got %f", report.Utilization)
+		}
+	})
+
+	t.Run("group concurrency", func(t *testing.T) {
+		var syncs = []PlannedSync{{Group: "GITHUB", Duration: minutes(5)}, {Group: "GITHUB", Duration: minutes(5)}, {Group: "DEFAULT", Duration: minutes(5)}}
+		var groups = map[string]SyncPlanGroup{"GITHUB": {ConcurrentSyncs: 1}, "DEFAULT": {ConcurrentSyncs: 10}}
+
+		var report = SimulateSyncCycle(syncs, SyncPlanConfig{Slots: 10, Groups: groups})
+		if report.Duration != minutes(10) {
+			t.Fatalf("expected a 10m cycle, got %s", report.Duration)
+		}
+		if len(report.Groups) != 2 || report.Groups[1].Group != "GITHUB" || report.Groups[1].MaxWait != minutes(5) {
+			t.Fatalf("expected github syncs to wait 5m, got %+v", report.Groups)
+		}
+
+		// with borrowing, the second github sync runs in an idle default slot
+		groups["GITHUB"] = SyncPlanGroup{ConcurrentSyncs: 1, MaxBorrowedSyncs: 1}
+		if report = SimulateSyncCycle(syncs, SyncPlanConfig{Slots: 10, Groups: groups}); report.Duration != minutes(5) {
+			t.Fatalf("expected a 5m cycle when borrowing, got %s", report.Duration)
+		}
+	})
+
+	t.Run("priority and exclusive repos", func(t *testing.T) {
+		var syncs = []PlannedSync{
+			{Repo: "a", Exclusive: true, Priority: 2, Group: "DEFAULT", Duration: minutes(1)},
+			{Repo: "a", Exclusive: true, Priority: 1, Group: "DEFAULT", Duration: minutes(3)},
+		}
+
+		var report = SimulateSyncCycle(syncs, SyncPlanConfig{Slots: 2})
+		if report.Duration != minutes(4) || report.MaxWait != minutes(3) {
+			t.Fatalf("expected the syncs of the exclusive repo to run one after the other, got %s and %s", report.Duration, report.MaxWait)
+		}
+	})
+
+	t.Run("api budget", func(t *testing.T) {
+		var syncs = []PlannedSync{
+			{Group: "GITHUB", Provider: "github", APICalls: 100, Duration: minutes(10)},
+			{Group: "GITHUB", Provider: "github", APICalls: 100, Duration: minutes(10)},
+		}
+
+		var report = SimulateSyncCycle(syncs, SyncPlanConfig{Slots: 1, APIBudgets: map[string]int{"github": 100}})
+		if report.Duration != minutes(70) || report.Deferred != 1 {
+			t.Fatalf("expected the second sync to be deferred to the next hour, got a %s cycle and %d deferred", report.Duration, report.Deferred)
+		}
+		if report.APICalls["github"] != 200 || report.PeakAPICallsPerHour["github"] != 100 {
+			t.Fatalf("expected 200 calls, 100 in the peak hour, got %v and %v", report.APICalls, report.PeakAPICallsPerHour)
+		}
+	})
+
+	t.Run("stuck", func(t *testing.T) {
+		var syncs = []PlannedSync{{Group: "NONE", Duration: minutes(1)}}
+		var groups = map[string]SyncPlanGroup{"NONE": {ConcurrentSyncs: 0}}
+		if report := SimulateSyncCycle(syncs, SyncPlanConfig{Slots: 1, Groups: groups}); report.Stuck != 1 {
+			t.Fatalf("expected 1 stuck sync, got %d", report.Stuck)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package helper
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// TableIndexPrefix is the prefix of the names of the indexes configured in mergestat.table_indexes, so that they're
+// told apart from the indexes created by migrations
+const TableIndexPrefix = "idx_tuning_"
+
+// tableIndexMethods are the index methods a table index may use
+var tableIndexMethods = map[string]bool{"btree": true, "hash": true, "gin": true, "gist": true, "brin": true}
+
+// TableIndex is an extra index of a synced table (see mergestat.table_indexes)
+type TableIndex struct {
+	Table string
+	Name  string
+
+	// Method is the index method (eg. btree or brin)
+	Method string
+
+	// Columns are the columns (or expressions) indexed, as in CREATE INDEX
+	Columns string
+
+	// Predicate, if set, makes a partial index of the rows matching it
+	Predicate string
+}
+
+// IndexName returns the name of the index. It's suffixed with a hash of the whole definition, so that an index is
+// rebuilt (under a new name) when its definition changes. Names longer than Postgres allows are shortened.
+func (i TableIndex) IndexName() string {
+	var h = fnv.New32a()
+	_, _ = h.Write([]byte(i.Method + "\x00" + i.Columns + "\x00" + i.Predicate))
+	var suffix = fmt.Sprintf("_%08x", h.Sum32())
+
+	var name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, TableIndexPrefix+i.Table+"_"+i.Name)
+
+	const maxIdentifier = 63
+	if len(name)+len(suffix) > maxIdentifier {
+		name = name[:maxIdentifier-len(suffix)]
+	}
+	return name + suffix
+}
+
+// Definition returns the CREATE INDEX statement of the index on the given table (ie. the table where it lives),
+// built concurrently so that syncs and queries of the table aren't blocked
+func (i TableIndex) Definition(table pgx.Identifier) (string, error) {
+	var method = strings.ToLower(i.Method)
+	if !tableIndexMethods[method] {
+		return "", fmt.Errorf("unknown index method %q", i.Method)
+	}
+	if strings.TrimSpace(i.Columns) == "" {
+		return "", fmt.Errorf("index %s of %s has no columns", i.Name, i.Table)
+	}
+
+	var stmt = fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s USING %s (%s)",
+		pgx.Identifier{i.IndexName()}.Sanitize(), table.Sanitize(), method, i.Columns)
+	if strings.TrimSpace(i.Predicate) != "" {
+		stmt += " WHERE " + i.Predicate
+	}
+	return stmt, nil
+}
+
+// GeneratedColumn is a computed column of a synced table (see mergestat.generated_columns). Rather than a stored
+// generated column, whose addition rewrites the table under an exclusive lock, it's a plain column kept up to date by
+// a trigger, and backfilled in batches.
+type GeneratedColumn struct {
+	Table      string
+	Column     string
+	Type       string
+	Expression string
+}
+
+// Definition returns the definition of the column: its type and the expression it's computed with. It's what's
+// recorded once the column is added, so that the column is added again when its definition changes.
+func (c GeneratedColumn) Definition() (string, error) {
+	if strings.TrimSpace(c.Type) == "" || strings.TrimSpace(c.Expression) == "" {
+		return "", fmt.Errorf("generated column %s of %s requires a type and an expression", c.Column, c.Table)
+	}
+	return fmt.Sprintf("%s AS (%s)", strings.TrimSpace(c.Type), strings.TrimSpace(c.Expression)), nil
+}
+
+// TriggerName returns the name of the trigger computing the column (and of its function, in the mergestat schema).
+// Like the names of indexes, names longer than Postgres allows are shortened, and suffixed with a hash.
+func (c GeneratedColumn) TriggerName() string {
+	var h = fnv.New32a()
+	_, _ = h.Write([]byte(c.Table + "\x00" + c.Column))
+	var suffix = fmt.Sprintf("_%08x", h.Sum32())
+
+	var name = "tuning_" + c.Table + "_" + c.Column
+	const maxIdentifier = 63
+	if len(name)+len(suffix) > maxIdentifier {
+		name = name[:maxIdentifier-len(suffix)]
+	}
+	return name + suffix
+}
+
+// TriggerFunction returns the CREATE FUNCTION statement of the function of the trigger computing the column, from
+// the other columns of the row
+func (c GeneratedColumn) TriggerFunction() (string, error) {
+	if _, err := c.Definition(); err != nil {
+		return "", err
+	}
+	if strings.Contains(c.Expression, "$tuning$") {
+		return "", fmt.Errorf("expression of generated column %s of %s can't contain $tuning$", c.Column, c.Table)
+	}
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s() RETURNS TRIGGER AS $tuning$
+BEGIN
+    SELECT (%s)::%s INTO NEW.%s FROM (SELECT NEW.*) AS r;
+    RETURN NEW;
+END;
+$tuning$ LANGUAGE plpgsql`, pgx.Identifier{"mergestat", c.TriggerName()}.Sanitize(), strings.TrimSpace(c.Expression),
+		strings.TrimSpace(c.Type), pgx.Identifier{c.Column}.Sanitize()), nil
+}
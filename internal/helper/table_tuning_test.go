@@ -0,0 +1,79 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func TestTableIndexName(t *testing.T) {
+	var index = TableIndex{Table: "git_commits", Name: "Author Month", Method: "btree", Columns: "repo_id, author_month"}
+	var name = index.IndexName()
+	if !strings.HasPrefix(name, "idx_tuning_git_commits_author_month_") || len(name) != len("idx_tuning_git_commits_author_month_")+8 {
+		t.Errorf("IndexName() = %q, want the sanitized name suffixed with a hash", name)
+	}
+
+	var changed = index
+	changed.Predicate = "author_month IS NOT NULL"
+	if changed.IndexName() == name {
+		t.Errorf("IndexName() should change with the definition, both are %q", name)
+	}
+
+	var long = TableIndex{Table: "github_pull_request_reviews", Name: "submitted_at_by_author_and_state", Method: "btree", Columns: "submitted_at"}
+	if name := long.IndexName(); len(name) != 63 || !strings.HasPrefix(name, TableIndexPrefix) {
+		t.Errorf("IndexName() = %q, want a name of 63 bytes prefixed with %s", name, TableIndexPrefix)
+	}
+}
+
+func TestTableIndexDefinition(t *testing.T) {
+	var index = TableIndex{Table: "github_pull_requests", Name: "merged_at", Method: "BTREE", Columns: "repo_id, merged_at", Predicate: "merged_at IS NOT NULL"}
+	var def, err = index.Definition(pgx.Identifier{"mergestat_data", "ms_github_pull_requests"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = `CREATE INDEX CONCURRENTLY IF NOT EXISTS "` + index.IndexName() + `" ON "mergestat_data"."ms_github_pull_requests" USING btree (repo_id, merged_at) WHERE merged_at IS NOT NULL`
+	if def != want {
+		t.Errorf("Definition() = %s, want %s", def, want)
+	}
+
+	if _, err = (TableIndex{Table: "git_commits", Name: "x", Method: "rum", Columns: "message"}).Definition(pgx.Identifier{"git_commits"}); err == nil {
+		t.Errorf("Definition() of an unknown method should fail")
+	}
+	if _, err = (TableIndex{Table: "git_commits", Name: "x", Method: "btree"}).Definition(pgx.Identifier{"git_commits"}); err == nil {
+		t.Errorf("Definition() without columns should fail")
+	}
+}
+
+func TestGeneratedColumnDefinition(t *testing.T) {
+	var def, err = GeneratedColumn{Table: "git_commits", Column: "author_month", Type: "TIMESTAMP", Expression: "date_trunc('month', author_when AT TIME ZONE 'UTC')"}.Definition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "TIMESTAMP AS (date_trunc('month', author_when AT TIME ZONE 'UTC'))"
+	if def != want {
+		t.Errorf("Definition() = %s, want %s", def, want)
+	}
+
+	if _, err = (GeneratedColumn{Table: "git_commits", Column: "author_month", Type: "TIMESTAMP"}).Definition(); err == nil {
+		t.Errorf("Definition() without an expression should fail")
+	}
+}
+
+func TestGeneratedColumnTriggerFunction(t *testing.T) {
+	var c = GeneratedColumn{Table: "git_commits", Column: "author_month", Type: "TIMESTAMP", Expression: "date_trunc('month', author_when AT TIME ZONE 'UTC')"}
+	var fn, err = c.TriggerFunction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(fn, `"mergestat"."`+c.TriggerName()+`"()`) || !strings.Contains(fn, `INTO NEW."author_month" FROM (SELECT NEW.*) AS r`) {
+		t.Errorf("TriggerFunction() = %s", fn)
+	}
+
+	if name := (GeneratedColumn{Table: strings.Repeat("t", 40), Column: strings.Repeat("c", 40)}).TriggerName(); len(name) > 63 {
+		t.Errorf("TriggerName() = %s, longer than Postgres allows", name)
+	}
+	if _, err = (GeneratedColumn{Table: "git_commits", Column: "x", Type: "TEXT", Expression: "$tuning$"}).TriggerFunction(); err == nil {
+		t.Errorf("TriggerFunction() of an expression closing its body should fail")
+	}
+}
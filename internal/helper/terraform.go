@@ -0,0 +1,118 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TerraformModule is a module call (module "name" { ... }) of a Terraform file
+type TerraformModule struct {
+	Name    string
+	Source  string
+	Version string
+	Line    int
+}
+
+// TerraformProvider is a provider requirement (in a required_providers block, or the version of a legacy provider block)
+type TerraformProvider struct {
+	Name    string
+	Source  string
+	Version string
+	Line    int
+}
+
+var (
+	tfModuleBlock       = regexp.MustCompile(`^module\s+"([^"]+)"\s*\{`)
+	tfProviderBlock     = regexp.MustCompile(`^provider\s+"([^"]+)"\s*\{`)
+	tfRequiredProviders = regexp.MustCompile(`^required_providers\s*\{`)
+	tfProviderObject    = regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*=\s*\{`)
+	tfProviderString    = regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*=\s*"([^"]*)"`)
+	tfAttribute         = regexp.MustCompile(`(source|version)\s*=\s*"([^"]*)"`)
+)
+
+// tfFrame is an open block while parsing a Terraform file
+type tfFrame struct {
+	kind     string
+	module   *TerraformModule
+	provider *TerraformProvider
+}
+
+// ParseTerraform returns the module calls and provider requirements of a Terraform (.tf) file.
+// It's a line based parser that understands the usual formatting (as produced by terraform fmt), not all of HCL.
+func ParseTerraform(contents string) (modules []*TerraformModule, providers []*TerraformProvider) {
+	var stack []*tfFrame
+	var top = func() *tfFrame {
+		if len(stack) == 0 {
+			return &tfFrame{}
+		}
+		return stack[len(stack)-1]
+	}
+
+	for i, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		var opens, closes = strings.Count(line, "{"), strings.Count(line, "}")
+
+		var frame *tfFrame
+		switch parent := top(); {
+		case len(stack) == 0 && tfModuleBlock.MatchString(line):
+			var m = &TerraformModule{Name: tfModuleBlock.FindStringSubmatch(line)[1], Line: i + 1}
+			modules = append(modules, m)
+			frame = &tfFrame{kind: "module", module: m}
+		case len(stack) == 0 && tfProviderBlock.MatchString(line):
+			frame = &tfFrame{kind: "provider", provider: &TerraformProvider{Name: tfProviderBlock.FindStringSubmatch(line)[1], Line: i + 1}}
+		case parent.kind == "terraform" && tfRequiredProviders.MatchString(line):
+			frame = &tfFrame{kind: "required_providers"}
+		case len(stack) == 0 && strings.HasPrefix(line, "terraform") && opens > 0:
+			frame = &tfFrame{kind: "terraform"}
+		case parent.kind == "required_providers" && tfProviderObject.MatchString(line):
+			var p = &TerraformProvider{Name: tfProviderObject.FindStringSubmatch(line)[1], Line: i + 1}
+			providers = append(providers, p)
+			frame = &tfFrame{kind: "required_provider", provider: p}
+		case parent.kind == "required_providers" && tfProviderString.MatchString(line):
+			var m = tfProviderString.FindStringSubmatch(line)
+			providers = append(providers, &TerraformProvider{Name: m[1], Version: m[2], Line: i + 1})
+		}
+
+		if frame != nil && opens > 0 {
+			stack = append(stack, frame)
+			opens--
+		}
+		for ; opens > 0; opens-- {
+			stack = append(stack, &tfFrame{kind: "block"})
+		}
+
+		// attributes apply to the innermost block (or to the block opened and closed on the same line)
+		var target = top()
+		if frame != nil {
+			target = frame
+		}
+		for _, m := range tfAttribute.FindAllStringSubmatch(line, -1) {
+			switch {
+			case target.module != nil && m[1] == "source":
+				target.module.Source = m[2]
+			case target.module != nil && m[1] == "version":
+				target.module.Version = m[2]
+			case target.provider != nil && m[1] == "source":
+				target.provider.Source = m[2]
+			case target.provider != nil && m[1] == "version":
+				target.provider.Version = m[2]
+			}
+		}
+
+		for ; closes > 0 && len(stack) > 0; closes-- {
+			var closed = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			// legacy provider blocks are only requirements if they constrain the version
+			if closed.kind == "provider" && closed.provider.Version != "" {
+				providers = append(providers, closed.provider)
+			}
+		}
+	}
+
+	return modules, providers
+}
@@ -0,0 +1,67 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTerraform(t *testing.T) {
+	const tf = `terraform {
+  required_version = ">= 1.0"
+
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+    random = { source = "hashicorp/random", version = ">= 3.1" }
+    null = "~> 2.1"
+  }
+}
+
+# module "disabled" {
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "3.14.0"
+
+  tags = {
+    source = "not a module source"
+  }
+}
+
+module "local" {
+  source = "./modules/local"
+}
+
+provider "google" {
+  version = "~> 3.0"
+}
+
+provider "aws" {
+  region = "us-east-1"
+}
+`
+
+	modules, providers := ParseTerraform(tf)
+
+	var wantModules = []*TerraformModule{
+		{Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "3.14.0", Line: 15},
+		{Name: "local", Source: "./modules/local", Line: 24},
+	}
+	if !reflect.DeepEqual(modules, wantModules) {
+		t.Fatalf("unexpected modules: %+v", modules)
+	}
+
+	var wantProviders = []*TerraformProvider{
+		{Name: "aws", Source: "hashicorp/aws", Version: "~> 4.0", Line: 5},
+		{Name: "random", Source: "hashicorp/random", Version: ">= 3.1", Line: 9},
+		{Name: "null", Version: "~> 2.1", Line: 10},
+		{Name: "google", Version: "~> 3.0", Line: 28},
+	}
+	if !reflect.DeepEqual(providers, wantProviders) {
+		for _, p := range providers {
+			t.Logf("%+v", p)
+		}
+		t.Fatalf("unexpected providers")
+	}
+}
@@ -0,0 +1,144 @@
+package helper
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// sourceLanguages are the languages whose files are counted by ComputeTestRatios, by extension
+var sourceLanguages = map[string]string{
+	".go": "Go", ".py": "Python", ".rb": "Ruby", ".rs": "Rust", ".php": "PHP", ".cs": "C#", ".swift": "Swift",
+	".java": "Java", ".kt": "Kotlin", ".scala": "Scala", ".ex": "Elixir", ".exs": "Elixir",
+	".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript", ".mts": "TypeScript", ".cts": "TypeScript",
+	".c": "C", ".h": "C", ".cc": "C++", ".cpp": "C++", ".hpp": "C++",
+}
+
+// testDirectories are the directories whose source files are all tests, whatever the language
+var testDirectories = map[string]bool{"test": true, "tests": true, "__tests__": true, "spec": true, "specs": true, "testdata": true}
+
+// ClassifySourceFile returns the language of a source file (empty if it isn't one), and whether it's a test,
+// according to the conventions of the language (eg. _test.go, test_*.py, *.spec.ts or *Test.java) or because
+// it's in a test directory (eg. tests/, __tests__/ or src/test/ of Maven and Gradle projects)
+func ClassifySourceFile(p string) (language string, test bool) {
+	var ext = strings.ToLower(path.Ext(p))
+	if language = sourceLanguages[ext]; language == "" {
+		return "", false
+	}
+
+	var base = path.Base(p)
+	var name = strings.TrimSuffix(base, path.Ext(base))
+
+	switch language {
+	case "Go":
+		test = strings.HasSuffix(name, "_test")
+	case "Python":
+		test = strings.HasPrefix(name, "test_") || strings.HasSuffix(name, "_test") || name == "conftest"
+	case "Ruby", "Elixir":
+		test = strings.HasSuffix(name, "_spec") || strings.HasSuffix(name, "_test")
+	case "JavaScript", "TypeScript":
+		var lower = strings.ToLower(name)
+		test = strings.HasSuffix(lower, ".test") || strings.HasSuffix(lower, ".spec") || strings.HasSuffix(lower, ".e2e")
+	case "Java", "Kotlin", "Scala", "C#", "PHP", "Swift":
+		test = strings.HasSuffix(name, "Test") || strings.HasSuffix(name, "Tests") || strings.HasSuffix(name, "Spec") || strings.HasSuffix(name, "IT")
+	case "C", "C++":
+		test = strings.HasSuffix(name, "_test") || strings.HasSuffix(name, "_unittest") || strings.HasPrefix(name, "test_")
+	}
+
+	if !test {
+		for _, dir := range strings.Split(path.Dir(p), "/") {
+			if testDirectories[dir] {
+				test = true
+				break
+			}
+		}
+	}
+
+	return language, test
+}
+
+// SourceFile is a file of a repo, with its number of lines
+type SourceFile struct {
+	Path  string
+	Lines int
+}
+
+// TestRatio is the number of source and test files (and their lines) under a directory of a repo
+type TestRatio struct {
+	// Directory is the path of the directory, empty for the whole repo
+	Directory string
+
+	SourceFiles, SourceLines int
+	TestFiles, TestLines     int
+}
+
+// FileRatio returns the ratio of test files to (non-test) source files, and false if there's no source file
+func (r TestRatio) FileRatio() (float64, bool) {
+	if r.SourceFiles == 0 {
+		return 0, false
+	}
+	return float64(r.TestFiles) / float64(r.SourceFiles), true
+}
+
+// LineRatio returns the ratio of lines of test files to lines of (non-test) source files, and false if there's none
+func (r TestRatio) LineRatio() (float64, bool) {
+	if r.SourceLines == 0 {
+		return 0, false
+	}
+	return float64(r.TestLines) / float64(r.SourceLines), true
+}
+
+// ComputeTestRatios counts the source and test files (see ClassifySourceFile) of the repo, and of each of its
+// directories up to the given depth (eg. 2 for cmd and cmd/worker), so that test files in a tests/ directory are
+// counted with the sources they test. Files in vendored dependencies are skipped. Directories are sorted by path.
+func ComputeTestRatios(files []SourceFile, depth int) []TestRatio {
+	var ratios = make(map[string]*TestRatio)
+	var add = func(dir string, lines int, test bool) {
+		var r = ratios[dir]
+		if r == nil {
+			r = &TestRatio{Directory: dir}
+			ratios[dir] = r
+		}
+		if test {
+			r.TestFiles, r.TestLines = r.TestFiles+1, r.TestLines+lines
+		} else {
+			r.SourceFiles, r.SourceLines = r.SourceFiles+1, r.SourceLines+lines
+		}
+	}
+
+	for _, f := range files {
+		var dirs = strings.Split(path.Dir(f.Path), "/")
+		if containsAny(dirs, "vendor", "node_modules", "third_party") {
+			continue
+		}
+
+		var language, test = ClassifySourceFile(f.Path)
+		if language == "" {
+			continue
+		}
+
+		add("", f.Lines, test)
+		for i := 1; i <= depth && i <= len(dirs) && dirs[0] != "."; i++ {
+			add(strings.Join(dirs[:i], "/"), f.Lines, test)
+		}
+	}
+
+	var result = make([]TestRatio, 0, len(ratios))
+	for _, r := range ratios {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Directory < result[j].Directory })
+	return result
+}
+
+func containsAny(values []string, any ...string) bool {
+	for _, v := range values {
+		for _, a := range any {
+			if v == a {
+				return true
+			}
+		}
+	}
+	return false
+}
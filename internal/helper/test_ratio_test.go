@@ -0,0 +1,63 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifySourceFile(t *testing.T) {
+	for _, tt := range []struct {
+		path     string
+		language string
+		test     bool
+	}{
+		{"internal/syncer/syncer.go", "Go", false},
+		{"internal/syncer/e2e_test.go", "Go", true},
+		{"app/models.py", "Python", false},
+		{"app/test_models.py", "Python", true},
+		{"ui/src/App.tsx", "TypeScript", false},
+		{"ui/src/App.test.tsx", "TypeScript", true},
+		{"ui/src/__tests__/render.js", "JavaScript", true},
+		{"src/main/java/com/acme/Repo.java", "Java", false},
+		{"src/test/java/com/acme/RepoFixtures.java", "Java", true},
+		{"src/main/java/com/acme/RepoTest.java", "Java", true},
+		{"lib/repo_spec.rb", "Ruby", true},
+		{"README.md", "", false},
+	} {
+		if language, test := ClassifySourceFile(tt.path); language != tt.language || test != tt.test {
+			t.Errorf("ClassifySourceFile(%q) = %q, %t, want %q, %t", tt.path, language, test, tt.language, tt.test)
+		}
+	}
+}
+
+func TestComputeTestRatios(t *testing.T) {
+	var files = []SourceFile{
+		{"main.go", 10},
+		{"cmd/worker/main.go", 100},
+		{"internal/syncer/syncer.go", 300},
+		{"internal/syncer/syncer_test.go", 150},
+		{"internal/helper/helper_test.go", 50},
+		{"vendor/github.com/pkg/errors/errors.go", 1000},
+		{"docs/README.md", 20},
+	}
+
+	var want = []TestRatio{
+		{Directory: "", SourceFiles: 3, SourceLines: 410, TestFiles: 2, TestLines: 200},
+		{Directory: "cmd", SourceFiles: 1, SourceLines: 100},
+		{Directory: "internal", SourceFiles: 1, SourceLines: 300, TestFiles: 2, TestLines: 200},
+	}
+	if got := ComputeTestRatios(files, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeTestRatios() = %+v, want %+v", got, want)
+	}
+
+	var ratios = ComputeTestRatios(files, 2)
+	if len(ratios) != 6 || ratios[5].Directory != "internal/syncer" {
+		t.Fatalf("ComputeTestRatios() at depth 2 = %+v", ratios)
+	}
+	if ratio, ok := ratios[5].LineRatio(); !ok || ratio != 0.5 {
+		t.Errorf("LineRatio() = %v, %t, want 0.5", ratio, ok)
+	}
+	if _, ok := ratios[4].FileRatio(); ok {
+		t.Errorf("FileRatio() of %s without source files should be undefined", ratios[4].Directory)
+	}
+}
@@ -0,0 +1,214 @@
+// Package lite implements "lite mode": a single-binary deployment, without Postgres, for individual developers who
+// want to analyze a handful of repositories. The control plane (repos, sync runs and their logs) and the synced data
+// all live in a local SQLite database, and syncs are executed with the mergestat-lite table-valued functions.
+package lite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Options configures a lite mode run
+type Options struct {
+	// Database is the path to the SQLite database file (created if it doesn't exist)
+	Database string
+
+	// Repos are the paths of the (local) git repositories to sync, added to the database if not already there
+	Repos []string
+
+	// SyncTypes are the sync types to run for every repo, eg. GIT_COMMITS (defaults to all supported sync types)
+	SyncTypes []string
+
+	// Interval, if set, re-runs the syncs on the given interval until the context is canceled
+	Interval time.Duration
+}
+
+// syncs maps the sync types supported in lite mode to the statements that (re-)populate their table for a repo.
+// Each statement takes the repo path as its only parameter.
+var syncs = map[string]struct {
+	table  string
+	insert string
+}{
+	"GIT_COMMITS": {"git_commits", `INSERT INTO git_commits
+		SELECT ?1, hash, message, author_name, author_email, author_when, committer_name, committer_email, committer_when, parents
+		FROM commits(?1)`},
+	"GIT_REFS": {"git_refs", `INSERT INTO git_refs
+		SELECT ?1, full_name, name, hash, remote, target, type, (CASE type WHEN 'tag' THEN COALESCE(COMMIT_FROM_TAG(tag), hash) END)
+		FROM refs(?1)`},
+	"GIT_FILES": {"git_files", `INSERT INTO git_files
+		SELECT ?1, path, executable, contents FROM files(?1)`},
+	"GIT_COMMIT_STATS": {"git_commit_stats", `INSERT INTO git_commit_stats
+		SELECT ?1, commits.hash, stats.file_path, stats.additions, stats.deletions, stats.old_file_mode, stats.new_file_mode
+		FROM commits(?1), stats(?1, commits.hash) AS stats`},
+}
+
+// SyncTypes returns the sync types supported in lite mode
+func SyncTypes() []string {
+	return []string{"GIT_COMMITS", "GIT_REFS", "GIT_FILES", "GIT_COMMIT_STATS"}
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS repos (repo TEXT PRIMARY KEY, created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP);
+
+CREATE TABLE IF NOT EXISTS sync_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	repo TEXT NOT NULL REFERENCES repos(repo) ON DELETE CASCADE,
+	sync_type TEXT NOT NULL,
+	started_at DATETIME NOT NULL,
+	done_at DATETIME,
+	status TEXT NOT NULL,
+	rows_written INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS sync_logs (
+	run_id INTEGER NOT NULL REFERENCES sync_runs(id) ON DELETE CASCADE,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	log_type TEXT NOT NULL,
+	message TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS git_commits (
+	repo TEXT NOT NULL, hash TEXT NOT NULL, message TEXT, author_name TEXT, author_email TEXT, author_when DATETIME,
+	committer_name TEXT, committer_email TEXT, committer_when DATETIME, parents INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_git_commits_repo ON git_commits(repo);
+
+CREATE TABLE IF NOT EXISTS git_refs (
+	repo TEXT NOT NULL, full_name TEXT NOT NULL, name TEXT, hash TEXT, remote TEXT, target TEXT, type TEXT, tag_commit_hash TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_git_refs_repo ON git_refs(repo);
+
+CREATE TABLE IF NOT EXISTS git_files (repo TEXT NOT NULL, path TEXT NOT NULL, executable BOOLEAN, contents TEXT);
+CREATE INDEX IF NOT EXISTS idx_git_files_repo ON git_files(repo);
+
+CREATE TABLE IF NOT EXISTS git_commit_stats (
+	repo TEXT NOT NULL, commit_hash TEXT NOT NULL, file_path TEXT NOT NULL, additions INTEGER, deletions INTEGER,
+	old_file_mode TEXT, new_file_mode TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_git_commit_stats_repo ON git_commit_stats(repo);
+`
+
+// Run executes the lite mode syncs (once, or on opts.Interval) against the SQLite database at opts.Database
+func Run(ctx context.Context, logger *zerolog.Logger, opts Options) (err error) {
+	if len(opts.SyncTypes) == 0 {
+		opts.SyncTypes = SyncTypes()
+	}
+
+	for _, syncType := range opts.SyncTypes {
+		if _, ok := syncs[syncType]; !ok {
+			return errors.Errorf("sync type %s is not supported in lite mode (supported: %v)", syncType, SyncTypes())
+		}
+	}
+
+	var db *sqlx.DB
+	if db, err = sqlx.Open("sqlite3", opts.Database); err != nil {
+		return errors.Wrapf(err, "failed to open %s", opts.Database)
+	}
+	defer db.Close()
+
+	// SQLite only allows a single writer at a time
+	db.SetMaxOpenConns(1)
+
+	if _, err = db.ExecContext(ctx, schema); err != nil {
+		return errors.Wrapf(err, "failed to create schema")
+	}
+
+	for _, repo := range opts.Repos {
+		if _, err = db.ExecContext(ctx, "INSERT OR IGNORE INTO repos (repo) VALUES (?)", repo); err != nil {
+			return errors.Wrapf(err, "failed to add repo %s", repo)
+		}
+	}
+
+	for {
+		if err = runAll(ctx, logger, db, opts.SyncTypes); err != nil {
+			return err
+		}
+
+		if opts.Interval <= 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+// runAll runs every sync type for every repo in the database. A failing sync is recorded (and logged), but doesn't stop the others.
+func runAll(ctx context.Context, logger *zerolog.Logger, db *sqlx.DB, syncTypes []string) (err error) {
+	var repos []string
+	if err = db.SelectContext(ctx, &repos, "SELECT repo FROM repos ORDER BY repo"); err != nil {
+		return errors.Wrapf(err, "failed to list repos")
+	}
+
+	for _, repo := range repos {
+		for _, syncType := range syncTypes {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			var start = time.Now()
+			var rows int64
+			if rows, err = runSync(ctx, db, repo, syncType); err != nil {
+				logger.Err(err).Str("repo", repo).Msgf("sync %s failed", syncType)
+				continue
+			}
+
+			logger.Info().Str("repo", repo).Msgf("sync %s wrote %d row(s) in %s", syncType, rows, time.Since(start))
+		}
+	}
+
+	return nil
+}
+
+// runSync replaces the rows of the sync type's table for the repo, recording the run (and its outcome) in sync_runs
+func runSync(ctx context.Context, db *sqlx.DB, repo, syncType string) (rows int64, err error) {
+	var sync = syncs[syncType]
+
+	res, err := db.ExecContext(ctx, "INSERT INTO sync_runs (repo, sync_type, started_at, status) VALUES (?, ?, ?, 'RUNNING')", repo, syncType, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	var runID int64
+	if runID, err = res.LastInsertId(); err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		var status = "SUCCESS"
+		if err != nil {
+			status = "ERROR"
+			_, _ = db.ExecContext(context.Background(), "INSERT INTO sync_logs (run_id, log_type, message) VALUES (?, 'ERROR', ?)", runID, err.Error())
+		}
+		_, _ = db.ExecContext(context.Background(), "UPDATE sync_runs SET done_at = ?, status = ?, rows_written = ? WHERE id = ?",
+			time.Now(), status, rows, runID)
+	}()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE repo = ?", sync.table), repo); err != nil {
+		return 0, err
+	}
+
+	if res, err = tx.ExecContext(ctx, sync.insert, repo); err != nil {
+		return 0, err
+	}
+
+	if rows, err = res.RowsAffected(); err != nil {
+		return 0, err
+	}
+
+	return rows, tx.Commit()
+}
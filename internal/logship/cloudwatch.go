@@ -0,0 +1,140 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// cloudWatchBatchSize and cloudWatchBatchBytes are the limits of a PutLogEvents request
+	// (the size of an event is the size of its message plus 26 bytes)
+	cloudWatchBatchSize  = 10000
+	cloudWatchBatchBytes = 1048576
+)
+
+// cloudWatch ships log lines to a log stream of CloudWatch Logs (which must exist), with PutLogEvents
+// requests signed with the AWS credentials of the environment
+type cloudWatch struct {
+	region, group, stream           string
+	accessKey, secretKey, sessToken string
+	client                          *http.Client
+}
+
+func cloudWatchFromEnv() (Sink, error) {
+	var env, err = requireEnv("CLOUDWATCH_LOG_GROUP", "CLOUDWATCH_LOG_STREAM", "AWS_REGION", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudWatch{
+		group: env[0], stream: env[1], region: env[2],
+		accessKey: env[3], secretKey: env[4], sessToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (c *cloudWatch) Name() string { return "cloudwatch" }
+
+func (c *cloudWatch) Ship(ctx context.Context, entries []Entry) error {
+	type event struct {
+		Timestamp int64  `json:"timestamp"`
+		Message   string `json:"message"`
+	}
+
+	// the events of a request must be in chronological order
+	entries = append([]Entry(nil), entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+
+	for len(entries) > 0 {
+		var events []event
+		var size int
+		for len(entries) > 0 && len(events) < cloudWatchBatchSize && size+len(entries[0].Line)+26 <= cloudWatchBatchBytes {
+			events = append(events, event{Timestamp: entries[0].Time.UnixMilli(), Message: string(entries[0].Line)})
+			size, entries = size+len(entries[0].Line)+26, entries[1:]
+		}
+
+		// a single line larger than a request is dropped
+		if len(events) == 0 {
+			entries = entries[1:]
+			continue
+		}
+
+		var body, err = json.Marshal(map[string]interface{}{"logGroupName": c.group, "logStreamName": c.stream, "logEvents": events})
+		if err != nil {
+			return err
+		}
+
+		if err := c.put(ctx, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put sends a PutLogEvents request, signed with AWS Signature Version 4
+func (c *cloudWatch) put(ctx context.Context, body []byte) error {
+	var host = "logs." + c.region + ".amazonaws.com"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	var now = time.Now().UTC()
+	var amzDate, date = now.Format("20060102T150405Z"), now.Format("20060102")
+
+	var headers = map[string]string{
+		"content-type": "application/x-amz-json-1.1",
+		"host":         host,
+		"x-amz-date":   amzDate,
+		"x-amz-target": "Logs_20140328.PutLogEvents",
+	}
+	if c.sessToken != "" {
+		headers["x-amz-security-token"] = c.sessToken
+	}
+
+	var names = make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+		if name != "host" {
+			req.Header.Set(name, headers[name])
+		}
+	}
+	var signedHeaders = strings.Join(names, ";")
+
+	var payloadHash = sha256.Sum256(body)
+	var canonicalRequest = strings.Join([]string{http.MethodPost, "/", "", canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payloadHash[:])}, "\n")
+
+	var scope = date + "/" + c.region + "/logs/aws4_request"
+	var requestHash = sha256.Sum256([]byte(canonicalRequest))
+	var stringToSign = strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	var key = []byte("AWS4" + c.secretKey)
+	for _, part := range []string{date, c.region, "logs", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	var signature = hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.accessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return send(c.client, req)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	var h = hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,83 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+)
+
+// datadogBatchSize is the maximum number of logs the Datadog intake accepts per request
+const datadogBatchSize = 1000
+
+// datadog ships log lines to the http logs intake of Datadog. Lines are json, so Datadog parses their fields
+// (eg. job-id) into attributes.
+type datadog struct {
+	url      string
+	apiKey   string
+	hostname string
+	client   *http.Client
+}
+
+func datadogFromEnv() (Sink, error) {
+	var env, err = requireEnv("DD_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	var site = os.Getenv("DD_SITE")
+	if site == "" {
+		site = "datadoghq.com"
+	}
+
+	var hostname, _ = os.Hostname()
+	return &datadog{
+		url:      "https://http-intake.logs." + site + "/api/v2/logs",
+		apiKey:   env[0],
+		hostname: hostname,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (d *datadog) Name() string { return "datadog" }
+
+func (d *datadog) Ship(ctx context.Context, entries []Entry) error {
+	type log struct {
+		Message  string `json:"message"`
+		Status   string `json:"status"`
+		Service  string `json:"service"`
+		Source   string `json:"ddsource"`
+		Hostname string `json:"hostname,omitempty"`
+	}
+
+	for start := 0; start < len(entries); start += datadogBatchSize {
+		var end = start + datadogBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		var logs = make([]log, 0, end-start)
+		for _, e := range entries[start:end] {
+			logs = append(logs, log{Message: string(e.Line), Status: e.Level, Service: "mergestat-worker", Source: "mergestat", Hostname: d.hostname})
+		}
+
+		var body, err = json.Marshal(logs)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("DD-API-KEY", d.apiKey)
+
+		if err := send(d.client, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
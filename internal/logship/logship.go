@@ -0,0 +1,171 @@
+// Package logship ships the worker's (json) log output to external log systems, so that it can be searched
+// alongside the logs of other services instead of (only) in the container's output.
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// flushInterval is how often buffered log lines are shipped
+	flushInterval = 5 * time.Second
+
+	// flushSize is the number of buffered log lines that triggers shipping before the interval elapses
+	flushSize = 1000
+
+	// maxPending is the number of log lines buffered while sinks are unavailable, the oldest lines are dropped past it
+	maxPending = 50000
+)
+
+// Entry is a single line of log output
+type Entry struct {
+	Time  time.Time
+	Level string
+	Line  []byte
+}
+
+// Sink is an external log system log lines are shipped to
+type Sink interface {
+	// Name is the name of the sink, as listed in LOG_SINKS
+	Name() string
+
+	// Ship sends the entries, in order, to the sink
+	Ship(ctx context.Context, entries []Entry) error
+}
+
+// Shipper is a zerolog writer that buffers the log lines it's given, and ships them to its sinks in the background
+// (see Run). Failing to ship logs never blocks nor fails the worker: failures are reported on stderr and the lines
+// are dropped.
+type Shipper struct {
+	sinks []Sink
+
+	mu      sync.Mutex
+	pending []Entry
+	full    chan struct{}
+}
+
+// New returns a shipper of log lines to the given sinks
+func New(sinks ...Sink) *Shipper {
+	return &Shipper{sinks: sinks, full: make(chan struct{}, 1)}
+}
+
+// Write buffers a line of (json) log output
+func (s *Shipper) Write(p []byte) (int, error) {
+	var fields struct {
+		Time  string `json:"time"`
+		Level string `json:"level"`
+	}
+	_ = json.Unmarshal(p, &fields)
+
+	var entry = Entry{Time: time.Now(), Level: fields.Level, Line: append([]byte(nil), strings.TrimSpace(string(p))...)}
+	if t, err := time.Parse(time.RFC3339Nano, fields.Time); err == nil {
+		entry.Time = t
+	}
+	if entry.Level == "" {
+		entry.Level = "info"
+	}
+
+	s.mu.Lock()
+	if s.pending = append(s.pending, entry); len(s.pending) > maxPending {
+		s.pending = s.pending[len(s.pending)-maxPending:]
+	}
+	var full = len(s.pending) >= flushSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Flush ships all buffered log lines to every sink
+func (s *Shipper) Flush(ctx context.Context) {
+	s.mu.Lock()
+	var entries = s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	for _, sink := range s.sinks {
+		if err := sink.Ship(ctx, entries); err != nil {
+			fmt.Fprintf(os.Stderr, "could not ship %d log line(s) to %s: %v\n", len(entries), sink.Name(), err)
+		}
+	}
+}
+
+// Run ships the buffered log lines every flushInterval (or sooner, when the buffer fills up) until the context
+// is done, then ships them one last time
+func (s *Shipper) Run(ctx context.Context) {
+	var ticker = time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			var shutdown, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+			s.Flush(shutdown)
+			cancel()
+			return
+		case <-ticker.C:
+			s.Flush(ctx)
+		case <-s.full:
+			s.Flush(ctx)
+		}
+	}
+}
+
+// FromEnv returns the sinks listed (comma separated) in LOG_SINKS, configured from their own environment variables:
+//
+//	loki:       LOKI_URL, and optionally LOKI_TENANT_ID, LOKI_USERNAME and LOKI_PASSWORD
+//	datadog:    DD_API_KEY, and optionally DD_SITE (defaults to datadoghq.com)
+//	cloudwatch: CLOUDWATCH_LOG_GROUP, CLOUDWATCH_LOG_STREAM, AWS_REGION and the AWS_ACCESS_KEY_ID,
+//	            AWS_SECRET_ACCESS_KEY (and optionally AWS_SESSION_TOKEN) credentials
+func FromEnv() ([]Sink, error) {
+	var sinks []Sink
+	for _, name := range strings.Split(os.Getenv("LOG_SINKS"), ",") {
+		var sink Sink
+		var err error
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+			continue
+		case "loki":
+			sink, err = lokiFromEnv()
+		case "datadog":
+			sink, err = datadogFromEnv()
+		case "cloudwatch":
+			sink, err = cloudWatchFromEnv()
+		default:
+			err = fmt.Errorf("unknown log sink")
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("log sink %s: %w", name, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// requireEnv returns the values of the given environment variables, or an error naming the first one that's not set
+func requireEnv(names ...string) ([]string, error) {
+	var values = make([]string, len(names))
+	for i, name := range names {
+		if values[i] = os.Getenv(name); values[i] == "" {
+			return nil, fmt.Errorf("%s is required", name)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,99 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loki ships log lines to the push api of Grafana Loki, in one stream per level
+type loki struct {
+	url                string
+	tenant             string
+	username, password string
+	client             *http.Client
+}
+
+func lokiFromEnv() (Sink, error) {
+	var env, err = requireEnv("LOKI_URL")
+	if err != nil {
+		return nil, err
+	}
+
+	return &loki{
+		url:      strings.TrimSuffix(env[0], "/") + "/loki/api/v1/push",
+		tenant:   os.Getenv("LOKI_TENANT_ID"),
+		username: os.Getenv("LOKI_USERNAME"),
+		password: os.Getenv("LOKI_PASSWORD"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (l *loki) Name() string { return "loki" }
+
+func (l *loki) Ship(ctx context.Context, entries []Entry) error {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	// labels are kept to a minimum (as Loki indexes them), the job's fields are part of the line
+	var streams = make(map[string]*stream)
+	var order []string
+	for _, e := range entries {
+		var s, ok = streams[e.Level]
+		if !ok {
+			s = &stream{Stream: map[string]string{"service": "mergestat-worker", "level": e.Level}}
+			streams[e.Level], order = s, append(order, e.Level)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), string(e.Line)})
+	}
+
+	var push struct {
+		Streams []*stream `json:"streams"`
+	}
+	for _, level := range order {
+		push.Streams = append(push.Streams, streams[level])
+	}
+
+	var body, err = json.Marshal(push)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.tenant != "" {
+		req.Header.Set("X-Scope-OrgID", l.tenant)
+	}
+	if l.username != "" {
+		req.SetBasicAuth(l.username, l.password)
+	}
+
+	return send(l.client, req)
+}
+
+// send executes the request, returning an error if it doesn't succeed
+func send(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var msg, _ = io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -38,6 +38,51 @@ func (m *MockQuerier) EXPECT() *MockQuerierMockRecorder {
 	return m.recorder
 }
 
+// ApplyAdaptiveSchedule mocks base method.
+func (m *MockQuerier) ApplyAdaptiveSchedule(ctx context.Context) (int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyAdaptiveSchedule", ctx)
+	ret0, _ := ret[0].(int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyAdaptiveSchedule indicates an expected call of ApplyAdaptiveSchedule.
+func (mr *MockQuerierMockRecorder) ApplyAdaptiveSchedule(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyAdaptiveSchedule", reflect.TypeOf((*MockQuerier)(nil).ApplyAdaptiveSchedule), ctx)
+}
+
+// ApplyRepoLifecyclePolicy mocks base method.
+func (m *MockQuerier) ApplyRepoLifecyclePolicy(ctx context.Context) (int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyRepoLifecyclePolicy", ctx)
+	ret0, _ := ret[0].(int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyRepoLifecyclePolicy indicates an expected call of ApplyRepoLifecyclePolicy.
+func (mr *MockQuerierMockRecorder) ApplyRepoLifecyclePolicy(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyRepoLifecyclePolicy", reflect.TypeOf((*MockQuerier)(nil).ApplyRepoLifecyclePolicy), ctx)
+}
+
+// BackfillRepoSyncs mocks base method.
+func (m *MockQuerier) BackfillRepoSyncs(ctx context.Context, arg db.BackfillRepoSyncsParams) (int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BackfillRepoSyncs", ctx, arg)
+	ret0, _ := ret[0].(int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BackfillRepoSyncs indicates an expected call of BackfillRepoSyncs.
+func (mr *MockQuerierMockRecorder) BackfillRepoSyncs(ctx, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BackfillRepoSyncs", reflect.TypeOf((*MockQuerier)(nil).BackfillRepoSyncs), ctx, arg)
+}
+
 // CheckRunningImps mocks base method.
 func (m *MockQuerier) CheckRunningImps(ctx context.Context) (int64, error) {
 	m.ctrl.T.Helper()
@@ -81,6 +126,21 @@ func (mr *MockQuerierMockRecorder) CleanOldRepoSyncQueue(ctx, dollar_1 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanOldRepoSyncQueue", reflect.TypeOf((*MockQuerier)(nil).CleanOldRepoSyncQueue), ctx, dollar_1)
 }
 
+// CleanRepoSyncQueueByPolicy mocks base method.
+func (m *MockQuerier) CleanRepoSyncQueueByPolicy(ctx context.Context) (int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CleanRepoSyncQueueByPolicy", ctx)
+	ret0, _ := ret[0].(int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CleanRepoSyncQueueByPolicy indicates an expected call of CleanRepoSyncQueueByPolicy.
+func (mr *MockQuerierMockRecorder) CleanRepoSyncQueueByPolicy(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanRepoSyncQueueByPolicy", reflect.TypeOf((*MockQuerier)(nil).CleanRepoSyncQueueByPolicy), ctx)
+}
+
 // DeleteGitHubRepoInfo mocks base method.
 func (m *MockQuerier) DeleteGitHubRepoInfo(ctx context.Context, repoID uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -110,18 +170,18 @@ func (mr *MockQuerierMockRecorder) DeleteRemovedRepos(ctx, arg interface{}) *gom
 }
 
 // DequeueSyncJob mocks base method.
-func (m *MockQuerier) DequeueSyncJob(ctx context.Context) (db.DequeueSyncJobRow, error) {
+func (m *MockQuerier) DequeueSyncJob(ctx context.Context, maxWeight int32) (db.DequeueSyncJobRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DequeueSyncJob", ctx)
+	ret := m.ctrl.Call(m, "DequeueSyncJob", ctx, maxWeight)
 	ret0, _ := ret[0].(db.DequeueSyncJobRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DequeueSyncJob indicates an expected call of DequeueSyncJob.
-func (mr *MockQuerierMockRecorder) DequeueSyncJob(ctx interface{}) *gomock.Call {
+func (mr *MockQuerierMockRecorder) DequeueSyncJob(ctx, maxWeight interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DequeueSyncJob", reflect.TypeOf((*MockQuerier)(nil).DequeueSyncJob), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DequeueSyncJob", reflect.TypeOf((*MockQuerier)(nil).DequeueSyncJob), ctx, maxWeight)
 }
 
 // EnableContainerSync mocks base method.
@@ -138,6 +198,21 @@ func (mr *MockQuerierMockRecorder) EnableContainerSync(ctx, arg interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableContainerSync", reflect.TypeOf((*MockQuerier)(nil).EnableContainerSync), ctx, arg)
 }
 
+// EnforceDataRetention mocks base method.
+func (m *MockQuerier) EnforceDataRetention(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnforceDataRetention", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnforceDataRetention indicates an expected call of EnforceDataRetention.
+func (mr *MockQuerierMockRecorder) EnforceDataRetention(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnforceDataRetention", reflect.TypeOf((*MockQuerier)(nil).EnforceDataRetention), ctx)
+}
+
 // EnqueueAllSyncs mocks base method.
 func (m *MockQuerier) EnqueueAllSyncs(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -152,6 +227,35 @@ func (mr *MockQuerierMockRecorder) EnqueueAllSyncs(ctx interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueAllSyncs", reflect.TypeOf((*MockQuerier)(nil).EnqueueAllSyncs), ctx)
 }
 
+// EnqueueRepoGroupSyncs mocks base method.
+func (m *MockQuerier) EnqueueRepoGroupSyncs(ctx context.Context) (int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueRepoGroupSyncs", ctx)
+	ret0, _ := ret[0].(int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueRepoGroupSyncs indicates an expected call of EnqueueRepoGroupSyncs.
+func (mr *MockQuerierMockRecorder) EnqueueRepoGroupSyncs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueRepoGroupSyncs", reflect.TypeOf((*MockQuerier)(nil).EnqueueRepoGroupSyncs), ctx)
+}
+
+// EnqueueRepoSyncOfType mocks base method.
+func (m *MockQuerier) EnqueueRepoSyncOfType(ctx context.Context, arg db.EnqueueRepoSyncOfTypeParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueRepoSyncOfType", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnqueueRepoSyncOfType indicates an expected call of EnqueueRepoSyncOfType.
+func (mr *MockQuerierMockRecorder) EnqueueRepoSyncOfType(ctx, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueRepoSyncOfType", reflect.TypeOf((*MockQuerier)(nil).EnqueueRepoSyncOfType), ctx, arg)
+}
+
 // FetchContainerSync mocks base method.
 func (m *MockQuerier) FetchContainerSync(ctx context.Context, id uuid.UUID) (db.FetchContainerSyncRow, error) {
 	m.ctrl.T.Helper()
@@ -197,6 +301,21 @@ func (mr *MockQuerierMockRecorder) FetchImportJob(ctx, id interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchImportJob", reflect.TypeOf((*MockQuerier)(nil).FetchImportJob), ctx, id)
 }
 
+// GetMaintenanceMode mocks base method.
+func (m *MockQuerier) GetMaintenanceMode(ctx context.Context) (db.GetMaintenanceModeRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMaintenanceMode", ctx)
+	ret0, _ := ret[0].(db.GetMaintenanceModeRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMaintenanceMode indicates an expected call of GetMaintenanceMode.
+func (mr *MockQuerierMockRecorder) GetMaintenanceMode(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaintenanceMode", reflect.TypeOf((*MockQuerier)(nil).GetMaintenanceMode), ctx)
+}
+
 // GetRepoById mocks base method.
 func (m *MockQuerier) GetRepoById(ctx context.Context, id uuid.UUID) (db.Repo, error) {
 	m.ctrl.T.Helper()
@@ -212,6 +331,21 @@ func (mr *MockQuerierMockRecorder) GetRepoById(ctx, id interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepoById", reflect.TypeOf((*MockQuerier)(nil).GetRepoById), ctx, id)
 }
 
+// GetRepoByURL mocks base method.
+func (m *MockQuerier) GetRepoByURL(ctx context.Context, repo string) (db.Repo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRepoByURL", ctx, repo)
+	ret0, _ := ret[0].(db.Repo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRepoByURL indicates an expected call of GetRepoByURL.
+func (mr *MockQuerierMockRecorder) GetRepoByURL(ctx, repo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepoByURL", reflect.TypeOf((*MockQuerier)(nil).GetRepoByURL), ctx, repo)
+}
+
 // GetRepoIDsFromRepoImport mocks base method.
 func (m *MockQuerier) GetRepoIDsFromRepoImport(ctx context.Context, arg db.GetRepoIDsFromRepoImportParams) ([]uuid.UUID, error) {
 	m.ctrl.T.Helper()
@@ -257,6 +391,21 @@ func (mr *MockQuerierMockRecorder) GetRepoUrlFromImport(ctx, importid interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepoUrlFromImport", reflect.TypeOf((*MockQuerier)(nil).GetRepoUrlFromImport), ctx, importid)
 }
 
+// GetSyncTypeMaintenance mocks base method.
+func (m *MockQuerier) GetSyncTypeMaintenance(ctx context.Context, synctype string) (db.GetSyncTypeMaintenanceRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSyncTypeMaintenance", ctx, synctype)
+	ret0, _ := ret[0].(db.GetSyncTypeMaintenanceRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSyncTypeMaintenance indicates an expected call of GetSyncTypeMaintenance.
+func (mr *MockQuerierMockRecorder) GetSyncTypeMaintenance(ctx, synctype interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSyncTypeMaintenance", reflect.TypeOf((*MockQuerier)(nil).GetSyncTypeMaintenance), ctx, synctype)
+}
+
 // InsertGitHubRepoInfo mocks base method.
 func (m *MockQuerier) InsertGitHubRepoInfo(ctx context.Context, arg db.InsertGitHubRepoInfoParams) error {
 	m.ctrl.T.Helper()
@@ -285,6 +434,20 @@ func (mr *MockQuerierMockRecorder) InsertNewDefaultSync(ctx, arg interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertNewDefaultSync", reflect.TypeOf((*MockQuerier)(nil).InsertNewDefaultSync), ctx, arg)
 }
 
+// InsertSyncBatch mocks base method.
+func (m *MockQuerier) InsertSyncBatch(ctx context.Context, arg db.InsertSyncBatchParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertSyncBatch", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertSyncBatch indicates an expected call of InsertSyncBatch.
+func (mr *MockQuerierMockRecorder) InsertSyncBatch(ctx, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertSyncBatch", reflect.TypeOf((*MockQuerier)(nil).InsertSyncBatch), ctx, arg)
+}
+
 // InsertSyncJobLog mocks base method.
 func (m *MockQuerier) InsertSyncJobLog(ctx context.Context, arg db.InsertSyncJobLogParams) error {
 	m.ctrl.T.Helper()
@@ -343,6 +506,20 @@ func (mr *MockQuerierMockRecorder) MarkSyncsAsTimedOut(ctx interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkSyncsAsTimedOut", reflect.TypeOf((*MockQuerier)(nil).MarkSyncsAsTimedOut), ctx)
 }
 
+// RecordAPIUsage mocks base method.
+func (m *MockQuerier) RecordAPIUsage(ctx context.Context, arg db.RecordAPIUsageParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAPIUsage", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAPIUsage indicates an expected call of RecordAPIUsage.
+func (mr *MockQuerierMockRecorder) RecordAPIUsage(ctx, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAPIUsage", reflect.TypeOf((*MockQuerier)(nil).RecordAPIUsage), ctx, arg)
+}
+
 // SetLatestKeepAliveForJob mocks base method.
 func (m *MockQuerier) SetLatestKeepAliveForJob(ctx context.Context, id int64) error {
 	m.ctrl.T.Helper()
@@ -357,6 +534,20 @@ func (mr *MockQuerierMockRecorder) SetLatestKeepAliveForJob(ctx, id interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLatestKeepAliveForJob", reflect.TypeOf((*MockQuerier)(nil).SetLatestKeepAliveForJob), ctx, id)
 }
 
+// SetSyncChecksum mocks base method.
+func (m *MockQuerier) SetSyncChecksum(ctx context.Context, arg db.SetSyncChecksumParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSyncChecksum", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetSyncChecksum indicates an expected call of SetSyncChecksum.
+func (mr *MockQuerierMockRecorder) SetSyncChecksum(ctx, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSyncChecksum", reflect.TypeOf((*MockQuerier)(nil).SetSyncChecksum), ctx, arg)
+}
+
 // SetSyncJobStatus mocks base method.
 func (m *MockQuerier) SetSyncJobStatus(ctx context.Context, arg db.SetSyncJobStatusParams) error {
 	m.ctrl.T.Helper()
@@ -371,6 +562,20 @@ func (mr *MockQuerierMockRecorder) SetSyncJobStatus(ctx, arg interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSyncJobStatus", reflect.TypeOf((*MockQuerier)(nil).SetSyncJobStatus), ctx, arg)
 }
 
+// SetSyncVersion mocks base method.
+func (m *MockQuerier) SetSyncVersion(ctx context.Context, arg db.SetSyncVersionParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSyncVersion", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetSyncVersion indicates an expected call of SetSyncVersion.
+func (mr *MockQuerierMockRecorder) SetSyncVersion(ctx, arg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSyncVersion", reflect.TypeOf((*MockQuerier)(nil).SetSyncVersion), ctx, arg)
+}
+
 // UpdateImportStatus mocks base method.
 func (m *MockQuerier) UpdateImportStatus(ctx context.Context, arg db.UpdateImportStatusParams) error {
 	m.ctrl.T.Helper()
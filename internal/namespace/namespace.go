@@ -0,0 +1,116 @@
+// Package namespace relocates the synced tables mergestat owns (see mergestat.owned_tables) out of the public schema
+// (into a schema of their own, and/or with a prefix), so that mergestat can share a database with other applications,
+// and resolves the names of the tables syncs write into to where they live (see mergestat.synced_tables).
+//
+// Migrations create (and alter) the tables in public, so the tables are moved back before migrations run, and
+// relocated once they're done. Moving tables locks them (and drops the views resolving their names), so workers
+// are paused meanwhile. The unqualified names of relocated tables resolve to (updatable) views of the same
+// name in the mergestat_tables schema, which the workers put first in their search_path: syncs keep referencing
+// the tables by their own name, only COPY (which doesn't write through views) has to target the table itself.
+// Tools reading the tables (grafana dashboards, dbt, exports, ...) expect them in public, and should be given
+// the same search_path.
+package namespace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// SearchPath is the search_path the unqualified names of relocated tables resolve with
+const SearchPath = "mergestat_tables,public"
+
+// Conn is what tables are moved on, eg. a pool, or the connection holding the lock serializing the workers moving them
+type Conn interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Relocated returns the number of tables relocated out of public. It's 0 on a database migrations haven't created
+// mergestat.synced_tables in yet.
+func Relocated(ctx context.Context, conn Conn) (n int, err error) {
+	const relocated = "SELECT CASE WHEN to_regclass('mergestat.synced_tables') IS NULL THEN 0 ELSE (SELECT COUNT(*) FROM mergestat.synced_tables) END"
+	if err = conn.QueryRow(ctx, relocated).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count relocated tables: %w", err)
+	}
+	return n, nil
+}
+
+// Pending reports whether Apply has tables to move for the given schema and prefix. Moving tables locks them, so
+// workers are paused meanwhile.
+func Pending(ctx context.Context, conn Conn, schema, prefix string) (pending bool, err error) {
+	if err = conn.QueryRow(ctx, "SELECT mergestat.table_namespace_pending($1, $2)", schema, prefix).Scan(&pending); err != nil {
+		return false, fmt.Errorf("check relocated tables: %w", err)
+	}
+	return pending, nil
+}
+
+// Restore moves the relocated tables back into public, before migrations run. It's a no-op on a database
+// migrations haven't created mergestat.restore_table_namespace in yet.
+func Restore(ctx context.Context, conn Conn) (n int, err error) {
+	var exists bool
+	if err = conn.QueryRow(ctx, "SELECT to_regproc('mergestat.restore_table_namespace') IS NOT NULL").Scan(&exists); err != nil || !exists {
+		return 0, err
+	}
+
+	if err = conn.QueryRow(ctx, "SELECT mergestat.restore_table_namespace()").Scan(&n); err != nil {
+		return 0, fmt.Errorf("restore tables: %w", err)
+	}
+	return n, nil
+}
+
+// Apply moves the owned tables (see mergestat.owned_tables) into the given schema, with the given prefix, and returns
+// the number of tables moved. With the public schema and no prefix, it moves back the tables relocated before (if any).
+func Apply(ctx context.Context, conn Conn, schema, prefix string) (n int, err error) {
+	if err = conn.QueryRow(ctx, "SELECT mergestat.apply_table_namespace($1, $2)", schema, prefix).Scan(&n); err != nil {
+		return 0, fmt.Errorf("relocate tables: %w", err)
+	}
+	return n, nil
+}
+
+// Registry maps the (unqualified) names of the relocated tables to where they live
+type Registry map[string]pgx.Identifier
+
+// Load returns the registry of the relocated tables
+func Load(ctx context.Context, pool *pgxpool.Pool) (_ Registry, err error) {
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, "SELECT table_name, schema_name, physical_name FROM mergestat.synced_tables"); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var registry = make(Registry)
+	for rows.Next() {
+		var table, schema, name string
+		if err = rows.Scan(&table, &schema, &name); err != nil {
+			return nil, err
+		}
+		registry[table] = pgx.Identifier{schema, name}
+	}
+	return registry, rows.Err()
+}
+
+// Identifier returns the identifier of the given table where it lives, if it's relocated, or as given otherwise.
+// Only unqualified names (and names qualified with public) resolve; the registry may be nil.
+func (r Registry) Identifier(table pgx.Identifier) pgx.Identifier {
+	var name string
+	switch {
+	case len(table) == 1:
+		name = table[0]
+	case len(table) == 2 && table[0] == "public":
+		name = table[1]
+	default:
+		return table
+	}
+
+	if id, ok := r[name]; ok {
+		return id
+	}
+	return table
+}
+
+// Table is like Identifier, for the name of a table
+func (r Registry) Table(name string) pgx.Identifier {
+	return r.Identifier(pgx.Identifier{name})
+}
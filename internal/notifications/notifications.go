@@ -0,0 +1,171 @@
+// Package notifications relays the notifications raised by syncs (see mergestat.notifications), eg. of CI duration
+// regressions, to a webhook: each is POSTed once, as json, and acknowledged once the webhook accepted it.
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// batchSize is the number of notifications claimed (and relayed) at once
+const batchSize = 50
+
+// relay periodically relays the notifications that weren't yet to the webhook, oldest first
+type relay struct {
+	logger *zerolog.Logger
+	pool   *pgxpool.Pool
+	client *http.Client
+
+	// url is the url of the webhook, and secret, if set, the key the requests are signed with
+	url    string
+	secret string
+}
+
+func New(logger *zerolog.Logger, pool *pgxpool.Pool, url, secret string) *relay {
+	return &relay{
+		logger: logger,
+		pool:   pool,
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    url,
+		secret: secret,
+	}
+}
+
+func (r *relay) Start(ctx context.Context, interval time.Duration) {
+	r.logger.Info().Msg("starting notifications relay")
+	exec := func() {
+		if relayed, err := r.Relay(ctx); err != nil {
+			r.logger.Err(err).Msgf("encountered error relaying notifications (%d relayed)", relayed)
+		} else if relayed > 0 {
+			r.logger.Info().Msgf("relayed %d notification(s)", relayed)
+		}
+	}
+	exec()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info().Msg("stopping notifications relay")
+			return
+		case <-time.After(interval):
+			exec()
+		}
+	}
+}
+
+// notification is a notification as it's POSTed to the webhook
+type notification struct {
+	ID        int64           `json:"id"`
+	Kind      string          `json:"kind"`
+	RepoID    *uuid.UUID      `json:"repo_id"`
+	Key       string          `json:"key"`
+	Subject   string          `json:"subject"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Relay relays the notifications that weren't yet, a batch at a time, and returns the number relayed. The batch is
+// claimed (so that the workers relaying concurrently don't relay the same notifications), and a notification is
+// acknowledged once the webhook accepted it: relaying stops at the first one it didn't, to be attempted again.
+func (r *relay) Relay(ctx context.Context) (relayed int, err error) {
+	const claim = `
+SELECT id, kind, repo_id, key, subject, payload, created_at FROM mergestat.notifications
+WHERE acknowledged_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`
+
+	for {
+		var tx pgx.Tx
+		if tx, err = r.pool.Begin(ctx); err != nil {
+			return relayed, err
+		}
+
+		var batch []*notification
+		if batch, err = claimed(ctx, tx, claim); err != nil {
+			_ = tx.Rollback(ctx)
+			return relayed, err
+		}
+
+		var sent int
+		for _, n := range batch {
+			if err = r.send(ctx, n); err != nil {
+				err = fmt.Errorf("notification %d: %w", n.ID, err)
+				break
+			}
+			if _, err = tx.Exec(ctx, "UPDATE mergestat.notifications SET acknowledged_at = now() WHERE id = $1", n.ID); err != nil {
+				break
+			}
+			sent++
+		}
+
+		// the notifications relayed before a failure are acknowledged regardless
+		if commitErr := tx.Commit(ctx); commitErr != nil {
+			return relayed, commitErr
+		}
+		relayed += sent
+		if err != nil || len(batch) < batchSize {
+			return relayed, err
+		}
+	}
+}
+
+// claimed returns the notifications claimed by the given query
+func claimed(ctx context.Context, tx pgx.Tx, claim string) (_ []*notification, err error) {
+	var rows pgx.Rows
+	if rows, err = tx.Query(ctx, claim, batchSize); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []*notification
+	for rows.Next() {
+		var n notification
+		if err = rows.Scan(&n.ID, &n.Kind, &n.RepoID, &n.Key, &n.Subject, &n.Payload, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		batch = append(batch, &n)
+	}
+	return batch, rows.Err()
+}
+
+// send POSTs the notification to the webhook, signed (with HMAC-SHA256, in X-Mergestat-Signature) if a secret is set
+func (r *relay) send(ctx context.Context, n *notification) error {
+	var body, err = json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.secret != "" {
+		var mac = hmac.New(sha256.New, []byte(r.secret))
+		mac.Write(body)
+		req.Header.Set("X-Mergestat-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	var resp *http.Response
+	if resp, err = r.client.Do(req); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Package planner implements the planning of the capacity of the workers: it loads the scheduled syncs, with their
+// durations and api calls in the previous cycles, and the current configuration (type group concurrency and api
+// budgets), to simulate a sync cycle (see helper.SimulateSyncCycle) under different configurations.
+package planner
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/pkg/errors"
+)
+
+// defaultDuration is the expected duration of the syncs of types that never ran over the history
+const defaultDuration = time.Minute
+
+// Plan is the input of a simulation: the scheduled syncs, and the configuration currently in place
+type Plan struct {
+	Syncs []helper.PlannedSync
+
+	// Groups and APIBudgets are the current concurrency of type groups, and api budgets of providers (by name)
+	Groups     map[string]helper.SyncPlanGroup
+	APIBudgets map[string]int
+
+	// Estimated is the number of syncs without history, whose duration is the median of their type (or a default)
+	Estimated int
+}
+
+// Load returns the syncs enabled in the schedule, with their median duration, and the average api calls per sync
+// of their provider, over the given history
+func Load(ctx context.Context, pool *pgxpool.Pool, history time.Duration) (_ *Plan, err error) {
+	var plan = &Plan{Groups: make(map[string]helper.SyncPlanGroup), APIBudgets: make(map[string]int)}
+	var days = int(math.Ceil(history.Hours() / 24))
+
+	// api calls are recorded by provider and hour, the calls of a sync are the average over the syncs that call the api
+	const listAPICalls = `
+SELECT p.name, SUM(u.calls)::FLOAT8 / NULLIF((
+    SELECT COUNT(*) FROM mergestat.repo_sync_queue q
+    INNER JOIN mergestat.repo_syncs rs ON rs.id = q.repo_sync_id
+    INNER JOIN mergestat.repo_sync_types rst ON rst.type = rs.sync_type
+    INNER JOIN public.repos r ON r.id = rs.repo_id
+    WHERE rst.uses_provider_api AND r.provider = u.provider_id AND q.status = 'DONE' AND q.done_at > now() - make_interval(days => $1)
+), 0)
+FROM mergestat.provider_api_usage u INNER JOIN mergestat.providers p ON p.id = u.provider_id
+WHERE u.hour > now() - make_interval(days => $1)
+GROUP BY p.name, u.provider_id`
+
+	var calls = make(map[string]float64)
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, listAPICalls, days); err != nil {
+		return nil, errors.Wrapf(err, "failed to load api usage")
+	}
+	for rows.Next() {
+		var provider string
+		var perSync *float64
+		if err = rows.Scan(&provider, &perSync); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if perSync != nil {
+			calls[provider] = *perSync
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	const listSyncs = `
+WITH durations AS (
+    SELECT repo_sync_id, percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM done_at - started_at)) AS seconds
+    FROM mergestat.repo_sync_queue
+    WHERE status = 'DONE' AND started_at IS NOT NULL AND done_at > now() - make_interval(days => $1)
+    GROUP BY repo_sync_id
+), type_durations AS (
+    SELECT rs.sync_type, percentile_cont(0.5) WITHIN GROUP (ORDER BY d.seconds) AS seconds
+    FROM durations d INNER JOIN mergestat.repo_syncs rs ON rs.id = d.repo_sync_id
+    GROUP BY rs.sync_type
+)
+SELECT r.repo, rst.type_group, rs.priority, COALESCE((r.settings->>'exclusiveSyncs')::BOOLEAN, FALSE),
+    d.seconds, td.seconds, CASE WHEN rst.uses_provider_api THEN COALESCE(p.name, '') ELSE '' END
+FROM mergestat.repo_syncs rs
+INNER JOIN mergestat.repo_sync_types rst ON rst.type = rs.sync_type
+INNER JOIN public.repos r ON r.id = rs.repo_id
+LEFT JOIN mergestat.providers p ON p.id = r.provider
+LEFT JOIN durations d ON d.repo_sync_id = rs.id
+LEFT JOIN type_durations td ON td.sync_type = rs.sync_type
+WHERE rs.schedule_enabled`
+
+	if rows, err = pool.Query(ctx, listSyncs, days); err != nil {
+		return nil, errors.Wrapf(err, "failed to load scheduled syncs")
+	}
+	for rows.Next() {
+		var s helper.PlannedSync
+		var seconds, typeSeconds *float64
+		if err = rows.Scan(&s.Repo, &s.Group, &s.Priority, &s.Exclusive, &seconds, &typeSeconds, &s.Provider); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		switch {
+		case seconds != nil:
+			s.Duration = time.Duration(*seconds * float64(time.Second))
+		case typeSeconds != nil:
+			s.Duration, plan.Estimated = time.Duration(*typeSeconds*float64(time.Second)), plan.Estimated+1
+		default:
+			s.Duration, plan.Estimated = defaultDuration, plan.Estimated+1
+		}
+		s.APICalls = int(math.Round(calls[s.Provider]))
+
+		plan.Syncs = append(plan.Syncs, s)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if rows, err = pool.Query(ctx, `SELECT "group", COALESCE(concurrent_syncs, 0), max_borrowed_syncs FROM mergestat.repo_sync_type_groups`); err != nil {
+		return nil, errors.Wrapf(err, "failed to load type groups")
+	}
+	for rows.Next() {
+		var name string
+		var group helper.SyncPlanGroup
+		if err = rows.Scan(&name, &group.ConcurrentSyncs, &group.MaxBorrowedSyncs); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		plan.Groups[name] = group
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	const listBudgets = `
+SELECT p.name, b.max_calls_per_hour FROM mergestat.provider_api_budgets b
+INNER JOIN mergestat.providers p ON p.id = b.provider_id
+WHERE b.max_calls_per_hour IS NOT NULL`
+
+	if rows, err = pool.Query(ctx, listBudgets); err != nil {
+		return nil, errors.Wrapf(err, "failed to load api budgets")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var provider string
+		var budget int
+		if err = rows.Scan(&provider, &budget); err != nil {
+			return nil, err
+		}
+		plan.APIBudgets[provider] = budget
+	}
+
+	return plan, rows.Err()
+}
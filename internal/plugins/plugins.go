@@ -0,0 +1,327 @@
+// Package plugins runs syncs implemented by external binaries, so that new sync types can be added without
+// rebuilding the worker. Plugins are hashicorp/go-plugin plugins, served over gRPC (see proto/plugin.proto):
+// a plugin binary implements Syncer, and calls Serve from its main function. A plugin is started once per job,
+// it's sent the job (a Request) and streams back Messages, with the logs and rows of the sync.
+package plugins
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/plugin.proto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/mergestat/mergestat/internal/plugins/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Plugin is an external binary implementing a sync type
+type Plugin struct {
+	// SyncType is the type of sync the plugin implements (it must be registered in mergestat.repo_sync_types)
+	SyncType string `json:"syncType"`
+
+	// Command is the path of the binary to execute
+	Command string `json:"command"`
+
+	// Args are the arguments the binary is executed with
+	Args []string `json:"args,omitempty"`
+
+	// Env are the environment variables (KEY=VALUE) the binary is executed with, in addition to those of
+	// baseEnv (which they take precedence over). The rest of the environment of the worker (its database url,
+	// encryption keys, provider tokens...) isn't passed to plugins.
+	Env []string `json:"env,omitempty"`
+
+	// Clone, if set, clones the repository before executing the plugin and sends its path in the request
+	Clone bool `json:"clone,omitempty"`
+
+	// Tables are the tables the plugin is allowed to write rows into
+	Tables []string `json:"tables"`
+}
+
+// Load reads the plugins configuration (a JSON array of plugins) at path
+func Load(path string) (_ map[string]*Plugin, err error) {
+	var contents []byte
+	if contents, err = os.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	var list []*Plugin
+	if err = json.Unmarshal(contents, &list); err != nil {
+		return nil, fmt.Errorf("invalid plugins configuration: %w", err)
+	}
+
+	var plugins = make(map[string]*Plugin, len(list))
+	for _, p := range list {
+		if p.SyncType == "" || p.Command == "" {
+			return nil, fmt.Errorf("invalid plugins configuration: syncType and command are required")
+		}
+		if _, dup := plugins[p.SyncType]; dup {
+			return nil, fmt.Errorf("invalid plugins configuration: more than one plugin for %s", p.SyncType)
+		}
+		plugins[p.SyncType] = p
+	}
+
+	return plugins, nil
+}
+
+// Allows reports whether the plugin may write into the given table
+func (p *Plugin) Allows(table string) bool {
+	for _, t := range p.Tables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// Request is the job a plugin executes
+type Request struct {
+	RepoID   uuid.UUID       `json:"repoId"`
+	Repo     string          `json:"repo"`
+	Ref      string          `json:"ref,omitempty"`
+	SyncType string          `json:"syncType"`
+	Settings json.RawMessage `json:"settings,omitempty"`
+
+	// Path is the path of the clone of the repository, if the plugin asks for one
+	Path string `json:"path,omitempty"`
+}
+
+// Message is a log or rows a plugin sends
+type Message struct {
+	// Type is either "log" or "rows"
+	Type string `json:"type"`
+
+	// Level (INFO, WARN or ERROR) and Message are set for logs, Details optionally holds their structured fields
+	Level   string                 `json:"level,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	// Table, Columns and Rows are set for rows. All the rows of a table must be sent before Sync returns, they
+	// replace the rows of the repo in the table once it returns successfully. Values are JSON-like: nil, booleans,
+	// numbers, strings, and maps and slices of them.
+	Table   string          `json:"table,omitempty"`
+	Columns []string        `json:"columns,omitempty"`
+	Rows    [][]interface{} `json:"rows,omitempty"`
+}
+
+// pluginName is the name the Syncer is dispensed under
+const pluginName = "syncer"
+
+// Handshake is the handshake the worker and its plugins agree on. It's not a security measure, it keeps plugin
+// binaries (and other binaries) from being executed the wrong way by mistake.
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "MERGESTAT_PLUGIN",
+	MagicCookieValue: "syncer",
+}
+
+// Syncer is implemented by plugins to execute the syncs of their sync type: Sync executes the job, calling send
+// with each of its logs and rows
+type Syncer interface {
+	Sync(ctx context.Context, req *Request, send func(*Message) error) error
+}
+
+// Serve serves the syncer, it's called by the main function of plugin binaries
+func Serve(s Syncer) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         plugin.PluginSet{pluginName: &SyncerPlugin{Impl: s}},
+		GRPCServer:      plugin.DefaultGRPCServer,
+	})
+}
+
+// baseEnv are the variables of the environment of the worker plugins are executed with (if set)
+var baseEnv = []string{"PATH", "HOME", "TMPDIR", "LANG", "TZ"}
+
+// minPort and maxPort are the range of ports plugins may listen on (where they don't listen on a unix socket)
+const minPort, maxPort = 10000, 25000
+
+// environment returns the environment the plugin is executed with: the variables of baseEnv, its Env, then the
+// variables of the handshake go-plugin sets (so that they aren't overridden)
+func (p *Plugin) environment() []string {
+	var env []string
+	for _, key := range baseEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	env = append(env, p.Env...)
+	return append(env,
+		fmt.Sprintf("%s=%s", Handshake.MagicCookieKey, Handshake.MagicCookieValue),
+		fmt.Sprintf("PLUGIN_MIN_PORT=%d", minPort),
+		fmt.Sprintf("PLUGIN_MAX_PORT=%d", maxPort),
+		fmt.Sprintf("PLUGIN_PROTOCOL_VERSIONS=%d", Handshake.ProtocolVersion),
+	)
+}
+
+// Run executes the plugin for the request, calling handle with each message the plugin sends
+func (p *Plugin) Run(ctx context.Context, req *Request, handle func(*Message) error) (err error) {
+	// go-plugin adds the whole environment of the worker to that of the command: the plugin is executed through
+	// env -i instead, with nothing but its environment
+	var env string
+	if env, err = exec.LookPath("env"); err != nil {
+		return fmt.Errorf("start plugin %s: %w", p.Command, err)
+	}
+	var args = append(append([]string{"-i"}, p.environment()...), p.Command)
+	var cmd = exec.Command(env, append(args, p.Args...)...)
+	cmd.Dir = req.Path
+
+	var stderr lockedBuffer
+	var client = plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          plugin.PluginSet{pluginName: &SyncerPlugin{}},
+		Cmd:              cmd,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		Logger:           hclog.NewNullLogger(),
+		Stderr:           &stderr,
+		MinPort:          minPort,
+		MaxPort:          maxPort,
+	})
+	defer client.Kill()
+
+	var rpc plugin.ClientProtocol
+	if rpc, err = client.Client(); err != nil {
+		return stderr.wrap(fmt.Errorf("start plugin %s: %w", p.Command, err))
+	}
+
+	var raw interface{}
+	if raw, err = rpc.Dispense(pluginName); err != nil {
+		return fmt.Errorf("start plugin %s: %w", p.Command, err)
+	}
+
+	if err = raw.(Syncer).Sync(ctx, req, handle); err != nil {
+		return stderr.wrap(fmt.Errorf("plugin %s: %w", p.Command, err))
+	}
+	return nil
+}
+
+// lockedBuffer is the buffer the stderr of a plugin is written into (by go-plugin, as it's written)
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// wrap returns the error with what the plugin wrote on stderr (if anything)
+func (b *lockedBuffer) wrap(err error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if stderr := bytes.TrimSpace(b.buf.Bytes()); len(stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, stderr)
+	}
+	return err
+}
+
+// SyncerPlugin is the plugin.GRPCPlugin serving (and dispensing clients of) a Syncer
+type SyncerPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+
+	// Impl is the syncer served, in plugin binaries
+	Impl Syncer
+}
+
+func (p *SyncerPlugin) GRPCServer(_ *plugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterSyncerServer(s, &grpcServer{impl: p.Impl})
+	return nil
+}
+
+func (p *SyncerPlugin) GRPCClient(_ context.Context, _ *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &grpcClient{client: proto.NewSyncerClient(c)}, nil
+}
+
+// grpcClient is the Syncer of a plugin, in the worker
+type grpcClient struct {
+	client proto.SyncerClient
+}
+
+func (c *grpcClient) Sync(ctx context.Context, req *Request, send func(*Message) error) (err error) {
+	var in = &proto.SyncRequest{RepoId: req.RepoID.String(), Repo: req.Repo, Ref: req.Ref, SyncType: req.SyncType, Settings: req.Settings, Path: req.Path}
+
+	var stream proto.Syncer_SyncClient
+	if stream, err = c.client.Sync(ctx, in); err != nil {
+		return err
+	}
+
+	for {
+		var msg *proto.SyncMessage
+		if msg, err = stream.Recv(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var m = &Message{}
+		switch v := msg.Message.(type) {
+		case *proto.SyncMessage_Log:
+			m.Type, m.Level, m.Message = "log", v.Log.Level, v.Log.Message
+			if v.Log.Details != nil {
+				m.Details = v.Log.Details.AsMap()
+			}
+		case *proto.SyncMessage_Rows:
+			m.Type, m.Table, m.Columns = "rows", v.Rows.Table, v.Rows.Columns
+			for _, row := range v.Rows.Rows {
+				m.Rows = append(m.Rows, row.AsSlice())
+			}
+		default:
+			return fmt.Errorf("unknown plugin message: %T", msg.Message)
+		}
+
+		if err = send(m); err != nil {
+			return err
+		}
+	}
+}
+
+// grpcServer serves a Syncer, in a plugin binary
+type grpcServer struct {
+	proto.UnimplementedSyncerServer
+	impl Syncer
+}
+
+func (s *grpcServer) Sync(in *proto.SyncRequest, stream proto.Syncer_SyncServer) (err error) {
+	var req = &Request{Repo: in.Repo, Ref: in.Ref, SyncType: in.SyncType, Settings: in.Settings, Path: in.Path}
+	if req.RepoID, err = uuid.Parse(in.RepoId); err != nil {
+		return fmt.Errorf("invalid repo id: %w", err)
+	}
+
+	return s.impl.Sync(stream.Context(), req, func(m *Message) (err error) {
+		var msg = &proto.SyncMessage{}
+		switch m.Type {
+		case "log":
+			var log = &proto.Log{Level: m.Level, Message: m.Message}
+			if m.Details != nil {
+				if log.Details, err = structpb.NewStruct(m.Details); err != nil {
+					return fmt.Errorf("log details: %w", err)
+				}
+			}
+			msg.Message = &proto.SyncMessage_Log{Log: log}
+		case "rows":
+			var rows = &proto.Rows{Table: m.Table, Columns: m.Columns, Rows: make([]*structpb.ListValue, len(m.Rows))}
+			for i, row := range m.Rows {
+				if rows.Rows[i], err = structpb.NewList(row); err != nil {
+					return fmt.Errorf("row of %s: %w", m.Table, err)
+				}
+			}
+			msg.Message = &proto.SyncMessage_Rows{Rows: rows}
+		default:
+			return fmt.Errorf("unknown plugin message type: %q", m.Type)
+		}
+		return stream.Send(msg)
+	})
+}
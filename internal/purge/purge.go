@@ -0,0 +1,238 @@
+// Package purge implements deleting a repo along with every row synced for it.
+package purge
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Options configures a purge (see Repo)
+type Options struct {
+	// Repo is the repo to purge, by id or by url (as stored in public.repos)
+	Repo string
+
+	// BatchSize is the maximum number of rows deleted at once (by a single statement) from a table
+	BatchSize int
+
+	// DryRun, if set, only reports the number of rows the purge would delete
+	DryRun bool
+}
+
+// dataTable is a table of mergestat.repo_data_tables, with the column referencing the repo
+type dataTable struct {
+	Schema, Table, Column string
+}
+
+func (t dataTable) String() string { return t.Schema + "." + t.Table }
+
+// Repo deletes the repo and every row produced for it: its sync logs, then the rows of every table of the registry
+// (see mergestat.repo_data_tables), one batch at a time so that no transaction locks (or cascades over) all the
+// rows of a large repo, and finally the repo itself (its syncs go along). The syncs of the repo are unscheduled,
+// and their queued jobs deleted, first (see unschedule), and the purge is refused while one of them is running.
+func Repo(ctx context.Context, pool *pgxpool.Pool, logger *zerolog.Logger, opts Options) (err error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10000
+	}
+
+	var id uuid.UUID
+	var url string
+	if id, url, err = resolve(ctx, pool, opts.Repo); err != nil {
+		return err
+	}
+	logger.Info().Msgf("purging %s (%s)", url, id)
+
+	var tables []dataTable
+	if tables, err = listTables(ctx, pool); err != nil {
+		return errors.Wrapf(err, "failed to list repo data tables")
+	}
+
+	if opts.DryRun {
+		var total int64
+		for _, t := range tables {
+			var count int64
+			var query = "SELECT COUNT(*) FROM " + pgx.Identifier{t.Schema, t.Table}.Sanitize() + " WHERE " + pgx.Identifier{t.Column}.Sanitize() + " = $1"
+			if err = pool.QueryRow(ctx, query, id).Scan(&count); err != nil {
+				return errors.Wrapf(err, "failed to count rows of %s", t)
+			}
+			if count > 0 {
+				logger.Info().Str("table", t.String()).Int64("rows", count).Msgf("would delete %d row(s) from %s", count, t)
+			}
+			total += count
+		}
+		logger.Info().Msgf("would delete %d row(s) in total (and the repo's sync logs)", total)
+		return nil
+	}
+
+	if err = unschedule(ctx, pool, id); err != nil {
+		return err
+	}
+
+	var total int64
+	var deleted int64
+	if deleted, err = inBatches(ctx, logger, "sync logs", opts.BatchSize, func() (int, error) {
+		var n int
+		err := pool.QueryRow(ctx, "SELECT mergestat.purge_repo_sync_logs($1, $2)", id, opts.BatchSize).Scan(&n)
+		return n, err
+	}); err != nil {
+		return errors.Wrapf(err, "failed to delete sync logs")
+	}
+	total += deleted
+
+	for _, t := range tables {
+		var t = t
+		if deleted, err = inBatches(ctx, logger, t.String(), opts.BatchSize, func() (int, error) {
+			var n int
+			err := pool.QueryRow(ctx, "SELECT mergestat.purge_repo_rows($1, $2, $3, $4, $5)", id, t.Schema, t.Table, t.Column, opts.BatchSize).Scan(&n)
+			return n, err
+		}); err != nil {
+			return errors.Wrapf(err, "failed to delete rows of %s", t)
+		}
+		total += deleted
+	}
+
+	var tx pgx.Tx
+	if tx, err = pool.Begin(ctx); err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(context.Background()) }()
+
+	if _, err = tx.Exec(ctx, "DELETE FROM public.repos WHERE id = $1", id); err != nil {
+		return errors.Wrapf(err, "failed to delete repo")
+	}
+	if _, err = tx.Exec(ctx, "INSERT INTO mergestat.repo_purges (repo_id, repo, rows_deleted) VALUES ($1, $2, $3)", id, url, total+1); err != nil {
+		return errors.Wrapf(err, "failed to record purge")
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	logger.Info().Int64("rows", total+1).Msgf("purged %s: deleted %d row(s) in total", url, total+1)
+	return nil
+}
+
+// resolve returns the id and url of the repo, given either its id or its url
+func resolve(ctx context.Context, pool *pgxpool.Pool, repo string) (id uuid.UUID, url string, err error) {
+	const byId = `SELECT id, repo FROM public.repos WHERE id = $1`
+	const byUrl = `SELECT id, repo FROM public.repos WHERE repo = $1`
+
+	var row pgx.Row
+	if parsed, parseErr := uuid.Parse(repo); parseErr == nil {
+		row = pool.QueryRow(ctx, byId, parsed)
+	} else {
+		row = pool.QueryRow(ctx, byUrl, repo)
+	}
+
+	if err = row.Scan(&id, &url); errors.Is(err, pgx.ErrNoRows) {
+		return id, url, errors.Errorf("repo %q not found", repo)
+	}
+	return id, url, err
+}
+
+// unschedule disables the syncs of the repo and deletes their queued jobs, so that no job writes rows back while the
+// repo is purged, and returns an error if one of them is running. The syncs are locked while it does, which blocks
+// enqueuing new jobs (inserting a job locks its sync, to check the reference), and a job being dequeued concurrently
+// is either skipped (it's locked by the delete) or seen as running once it's dequeued.
+func unschedule(ctx context.Context, pool *pgxpool.Pool, id uuid.UUID) (err error) {
+	var tx pgx.Tx
+	if tx, err = pool.Begin(ctx); err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(context.Background()) }()
+
+	const lock = `SELECT id FROM mergestat.repo_syncs WHERE repo_id = $1 FOR UPDATE`
+	if _, err = tx.Exec(ctx, lock, id); err != nil {
+		return errors.Wrapf(err, "failed to lock syncs")
+	}
+
+	const disable = `UPDATE mergestat.repo_syncs SET schedule_enabled = FALSE WHERE repo_id = $1`
+	if _, err = tx.Exec(ctx, disable, id); err != nil {
+		return errors.Wrapf(err, "failed to disable syncs")
+	}
+
+	const dequeue = `
+DELETE FROM mergestat.repo_sync_queue q USING mergestat.repo_syncs rs
+    WHERE rs.id = q.repo_sync_id AND rs.repo_id = $1 AND q.status = 'QUEUED'`
+	if _, err = tx.Exec(ctx, dequeue, id); err != nil {
+		return errors.Wrapf(err, "failed to delete queued syncs")
+	}
+
+	const running = `
+SELECT COUNT(*) FROM mergestat.repo_sync_queue q
+    INNER JOIN mergestat.repo_syncs rs ON rs.id = q.repo_sync_id
+    WHERE rs.repo_id = $1 AND q.status = 'RUNNING'`
+
+	var jobs int
+	if err = tx.QueryRow(ctx, running, id).Scan(&jobs); err != nil {
+		return errors.Wrapf(err, "failed to check for running syncs")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if jobs > 0 {
+		return errors.Errorf("%d sync(s) of the repo are running, retry once they're done (its syncs are no longer scheduled)", jobs)
+	}
+	return nil
+}
+
+// listTables returns the tables of mergestat.repo_data_tables, but the repos and their syncs, which are deleted
+// (with their queued jobs) along with the repo itself
+func listTables(ctx context.Context, pool *pgxpool.Pool) (_ []dataTable, err error) {
+	const query = `
+SELECT table_schema, table_name, column_name FROM mergestat.repo_data_tables
+    WHERE NOT (table_schema = 'mergestat' AND table_name = 'repo_syncs')
+    ORDER BY table_schema, table_name`
+
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, query); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []dataTable
+	for rows.Next() {
+		var t dataTable
+		if err = rows.Scan(&t.Schema, &t.Table, &t.Column); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// inBatches calls batch (which deletes up to size rows) until it deletes less than size rows,
+// logging the progress, and returns the number of rows deleted
+func inBatches(ctx context.Context, logger *zerolog.Logger, name string, size int, batch func() (int, error)) (int64, error) {
+	var total int64
+	var start = time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		var n, err = batch()
+		if err != nil {
+			return total, err
+		}
+		total += int64(n)
+
+		if n > 0 {
+			logger.Info().Str("table", name).Int64("rows", total).Msgf("deleted %d row(s) from %s", total, name)
+		}
+		if n < size {
+			break
+		}
+	}
+
+	if total > 0 {
+		logger.Info().Str("table", name).Int64("rows", total).Msgf("done with %s: deleted %d row(s) in %s", name, total, time.Since(start))
+	}
+	return total, nil
+}
@@ -0,0 +1,79 @@
+-- name: dora
+-- version: 3
+-- description: DORA metrics (deployment frequency, lead time for changes, change failure rate, time to restore) by repo and week, with releases as deployments and incidents (if imported) or reverts and hotfixes as failures
+-- materialize: weekly
+
+-- releases, as deployments. A release with fixes and no features is a fix deployment: it restores a failure
+-- introduced by a previous deployment.
+CREATE VIEW deployments AS
+SELECT r.repo_id, r.tag, r.released_at,
+    r.fix_count > 0 AND r.feature_count = 0 AS is_fix,
+    LAG(r.released_at) OVER (PARTITION BY r.repo_id ORDER BY r.released_at) AS previous_released_at
+FROM public.git_releases r;
+
+COMMENT ON VIEW deployments IS 'releases of each repo, as deployments (fix deployments restore a failure)';
+
+-- the source of the failures of each repo: its incidents if imported (see the INCIDENTS sync), or else its reverts
+-- and hotfixes if detected (see the CHANGE_FAILURES sync), or else its fix deployments
+CREATE VIEW failure_sources AS
+SELECT r.id AS repo_id,
+    CASE WHEN EXISTS (SELECT 1 FROM public.incidents i WHERE i.repo_id = r.id) THEN 'incidents'
+        WHEN EXISTS (SELECT 1 FROM public.change_failures cf WHERE cf.repo_id = r.id) THEN 'change_failures'
+        ELSE 'fix_deployments'
+    END AS source
+FROM public.repos r;
+
+COMMENT ON VIEW failure_sources IS 'source of the change failures of each repo: incidents, change_failures (reverts and hotfixes) or fix_deployments';
+
+-- the failures of each repo, from its failure source
+CREATE VIEW failures AS
+SELECT i.repo_id, i.created_at AS failed_at, i.resolved_at AS restored_at
+FROM public.incidents i
+INNER JOIN failure_sources s ON s.repo_id = i.repo_id AND s.source = 'incidents'
+UNION ALL
+SELECT cf.repo_id, cf.failed_at, cf.restored_at
+FROM public.change_failures cf
+INNER JOIN failure_sources s ON s.repo_id = cf.repo_id AND s.source = 'change_failures';
+
+COMMENT ON VIEW failures IS 'incidents, or reverts and hotfixes, of each repo (as its failure source has it), with when they were introduced (if known) and restored';
+
+CREATE VIEW weekly AS
+WITH deploys AS (
+    SELECT repo_id, date_trunc('week', released_at) AS week,
+        COUNT(*) AS deployments,
+        COUNT(*) FILTER (WHERE is_fix) AS fix_deployments,
+        AVG(released_at - previous_released_at) FILTER (WHERE is_fix) AS time_to_restore
+    FROM deployments
+    GROUP BY 1, 2
+), lead_times AS (
+    SELECT repo_id, date_trunc('week', merged_at) AS week,
+        percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM lead_time)) * INTERVAL '1 second' AS median_lead_time
+    FROM public.git_commit_lead_times
+    GROUP BY 1, 2
+), failed AS (
+    SELECT repo_id, date_trunc('week', COALESCE(failed_at, restored_at)) AS week,
+        COUNT(*) AS failures,
+        AVG(restored_at - failed_at) AS time_to_restore
+    FROM failures
+    GROUP BY 1, 2
+), weeks AS (
+    SELECT repo_id, week FROM deploys
+    UNION SELECT repo_id, week FROM lead_times
+    UNION SELECT repo_id, week FROM failed
+)
+SELECT w.repo_id, w.week,
+    COALESCE(d.deployments, 0) AS deployments,
+    l.median_lead_time AS median_lead_time,
+    CASE WHEN s.source = 'fix_deployments'
+        THEN d.fix_deployments::NUMERIC / NULLIF(d.deployments, 0)
+        ELSE LEAST(COALESCE(f.failures, 0)::NUMERIC / NULLIF(d.deployments, 0), 1)
+    END AS change_failure_rate,
+    CASE WHEN s.source = 'fix_deployments' THEN d.time_to_restore ELSE f.time_to_restore END AS time_to_restore,
+    s.source AS failure_source
+FROM weeks w
+INNER JOIN failure_sources s ON s.repo_id = w.repo_id
+LEFT JOIN deploys d ON d.repo_id = w.repo_id AND d.week = w.week
+LEFT JOIN lead_times l ON l.repo_id = w.repo_id AND l.week = w.week
+LEFT JOIN failed f ON f.repo_id = w.repo_id AND f.week = w.week;
+
+COMMENT ON VIEW weekly IS 'DORA metrics of each repo by week: deployments, median lead time of the merged commits, change failure rate and mean time to restore, from the failures of the repo (failures per deployment, capped at 1) or else from its fix deployments (see failure_sources)';
@@ -0,0 +1,25 @@
+-- name: hotspots
+-- version: 1
+-- description: files changed the most (churn, commits, authors) over the last 90 days
+-- materialize: files
+
+CREATE VIEW files AS
+SELECT s.repo_id, s.file_path,
+    COUNT(DISTINCT s.commit_hash) AS commits,
+    COUNT(DISTINCT c.author_email) AS authors,
+    SUM(s.additions + s.deletions) AS churn,
+    MAX(c.author_when) AS last_changed_at
+FROM public.git_commit_stats s
+INNER JOIN public.git_commits c ON c.repo_id = s.repo_id AND c.hash = s.commit_hash
+WHERE c.author_when > now() - INTERVAL '90 days' AND c.parents < 2
+GROUP BY s.repo_id, s.file_path;
+
+COMMENT ON VIEW files IS 'churn, commits and authors of each file over the last 90 days (merge commits excluded)';
+
+CREATE VIEW top_files AS
+SELECT * FROM (
+    SELECT files.*, rank() OVER (PARTITION BY repo_id ORDER BY churn DESC, commits DESC) AS rank FROM files
+) ranked
+WHERE rank <= 50;
+
+COMMENT ON VIEW top_files IS 'the 50 files of each repo with the most churn over the last 90 days';
@@ -0,0 +1,29 @@
+-- name: review_health
+-- version: 1
+-- description: pull request review health (time to first review, reviewers, merges without review) by repo and week
+-- materialize: weekly
+
+CREATE VIEW pull_requests AS
+SELECT pr.repo_id, pr.number, pr.author_login, pr.created_at, pr.merged, pr.merged_at,
+    MIN(rv.created_at) FILTER (WHERE rv.author_login IS DISTINCT FROM pr.author_login) AS first_reviewed_at,
+    COUNT(rv.id) FILTER (WHERE rv.author_login IS DISTINCT FROM pr.author_login) AS reviews,
+    COUNT(DISTINCT rv.author_login) FILTER (WHERE rv.author_login IS DISTINCT FROM pr.author_login) AS reviewers
+FROM public.github_pull_requests pr
+LEFT JOIN public.github_pull_request_reviews rv ON rv.repo_id = pr.repo_id AND rv.pr_number = pr.number
+GROUP BY pr.repo_id, pr.number, pr.author_login, pr.created_at, pr.merged, pr.merged_at;
+
+COMMENT ON VIEW pull_requests IS 'pull requests with their reviews by someone else than the author';
+
+CREATE VIEW weekly AS
+SELECT repo_id, date_trunc('week', created_at) AS week,
+    COUNT(*) AS opened,
+    COUNT(*) FILTER (WHERE merged) AS merged,
+    percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM first_reviewed_at - created_at)) * INTERVAL '1 second' AS median_time_to_first_review,
+    percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM merged_at - created_at)) * INTERVAL '1 second' AS median_time_to_merge,
+    AVG(reviewers) AS average_reviewers,
+    COUNT(*) FILTER (WHERE merged AND reviews = 0)::NUMERIC / NULLIF(COUNT(*) FILTER (WHERE merged), 0) AS merged_without_review_rate
+FROM pull_requests
+WHERE created_at IS NOT NULL
+GROUP BY 1, 2;
+
+COMMENT ON VIEW weekly IS 'review health of each repo by week of the pull requests creation';
@@ -0,0 +1,16 @@
+-- name: security_posture
+-- version: 1
+-- description: security posture of each repo (vulnerabilities by severity, leaked secrets, failed policies)
+-- materialize: repos
+
+CREATE VIEW repos AS
+SELECT r.id AS repo_id, r.repo,
+    (SELECT COUNT(*) FROM public.trivy_repo_vulnerabilities v WHERE v.repo_id = r.id AND upper(v.vulnerability_severity) = 'CRITICAL') AS trivy_critical,
+    (SELECT COUNT(*) FROM public.trivy_repo_vulnerabilities v WHERE v.repo_id = r.id AND upper(v.vulnerability_severity) = 'HIGH') AS trivy_high,
+    (SELECT COUNT(*) FROM public.grype_repo_vulnerabilities v WHERE v.repo_id = r.id AND upper(v.severity) = 'CRITICAL') AS grype_critical,
+    (SELECT COUNT(*) FROM public.grype_repo_vulnerabilities v WHERE v.repo_id = r.id AND upper(v.severity) = 'HIGH') AS grype_high,
+    (SELECT COUNT(*) FROM public.gitleaks_repo_detections d WHERE d.repo_id = r.id) AS leaked_secrets,
+    (SELECT COUNT(*) FROM public.repo_policy_results p WHERE p.repo_id = r.id AND NOT p.passed) AS failed_policies
+FROM public.repos r;
+
+COMMENT ON VIEW repos IS 'critical and high vulnerabilities (trivy, grype), leaked secrets (gitleaks) and failed policies of each repo';
@@ -0,0 +1,250 @@
+// Package querypacks implements the maintained libraries of SQL views (query packs, eg. DORA metrics) shipped with
+// the worker: each pack is installed in its own schema (pack_<name>), reinstalled when the worker ships a newer
+// version of it, and its views are optionally refreshed into snapshot tables on a schedule (see mergestat.query_packs).
+package querypacks
+
+import (
+	"context"
+	"embed"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+//go:embed packs/*.sql
+var files embed.FS
+
+// Pack is a query pack, as shipped with the worker. The header of its file (comments of the form `-- key: value`)
+// holds its name, version, description and the views to materialize.
+type Pack struct {
+	Name        string
+	Version     int
+	Description string
+
+	// Materialize are the views of the pack refreshed into snapshot tables (<view>_snapshot)
+	Materialize []string
+
+	// SQL creates the views of the pack, with the schema of the pack first in the search_path
+	SQL string
+}
+
+// Schema returns the schema the pack is installed in
+func (p *Pack) Schema() string { return "pack_" + p.Name }
+
+// Packs returns the query packs shipped with the worker, by name
+func Packs() ([]*Pack, error) {
+	var entries, err = files.ReadDir("packs")
+	if err != nil {
+		return nil, err
+	}
+
+	var packs []*Pack
+	for _, e := range entries {
+		var content []byte
+		if content, err = files.ReadFile(path.Join("packs", e.Name())); err != nil {
+			return nil, err
+		}
+
+		var pack *Pack
+		if pack, err = parse(string(content)); err != nil {
+			return nil, errors.Wrapf(err, "invalid query pack %s", e.Name())
+		}
+		packs = append(packs, pack)
+	}
+
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Name < packs[j].Name })
+	return packs, nil
+}
+
+// parse reads the header of a pack, which ends at its first line not of the form `-- key: value`
+func parse(content string) (_ *Pack, err error) {
+	var pack = &Pack{SQL: content}
+	for _, line := range strings.Split(content, "\n") {
+		var key, value, ok = strings.Cut(strings.TrimPrefix(line, "--"), ":")
+		if !ok || !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "name":
+			pack.Name = value
+		case "version":
+			if pack.Version, err = strconv.Atoi(value); err != nil || pack.Version <= 0 {
+				return nil, errors.Errorf("invalid version %q", value)
+			}
+		case "description":
+			pack.Description = value
+		case "materialize":
+			for _, view := range strings.Split(value, ",") {
+				if view = strings.TrimSpace(view); view != "" {
+					pack.Materialize = append(pack.Materialize, view)
+				}
+			}
+		}
+	}
+
+	if pack.Name == "" || pack.Version == 0 {
+		return nil, errors.New("name and version are required")
+	}
+	return pack, nil
+}
+
+// installer periodically installs, upgrades and refreshes the query packs shipped with the worker
+type installer struct {
+	logger *zerolog.Logger
+	pool   *pgxpool.Pool
+}
+
+func New(logger *zerolog.Logger, pool *pgxpool.Pool) *installer {
+	return &installer{
+		logger: logger,
+		pool:   pool,
+	}
+}
+
+func (i *installer) Start(ctx context.Context, interval time.Duration) {
+	i.logger.Info().Msg("starting query packs routine")
+	exec := func() {
+		if err := i.Sync(ctx, false); err != nil {
+			i.logger.Err(err).Msg("encountered error syncing query packs")
+		}
+	}
+	exec()
+
+	for {
+		select {
+		case <-ctx.Done():
+			i.logger.Info().Msg("stopping query packs routine")
+			return
+		case <-time.After(interval):
+			exec()
+		}
+	}
+}
+
+// Sync registers the packs shipped with the worker in mergestat.query_packs, then installs (or upgrades) the enabled
+// ones, uninstalls the disabled ones, and refreshes the snapshots of the ones due (or of all of them, if refresh is set).
+// Each pack is handled with its row locked, and skipped if another worker is handling it.
+func (i *installer) Sync(ctx context.Context, refresh bool) error {
+	var packs, err = Packs()
+	if err != nil {
+		return err
+	}
+
+	for _, pack := range packs {
+		const register = `INSERT INTO mergestat.query_packs (name, description) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET description = excluded.description`
+		if _, err = i.pool.Exec(ctx, register, pack.Name, pack.Description); err != nil {
+			return errors.Wrapf(err, "failed to register query pack %s", pack.Name)
+		}
+
+		if err = i.sync(ctx, pack, refresh); err != nil {
+			i.logger.Err(err).Msgf("failed to sync query pack %s", pack.Name)
+
+			const record = `UPDATE mergestat.query_packs SET last_error = $2 WHERE name = $1`
+			if _, recordErr := i.pool.Exec(ctx, record, pack.Name, err.Error()); recordErr != nil {
+				return recordErr
+			}
+		}
+	}
+	return nil
+}
+
+// sync installs, upgrades, uninstalls or refreshes the pack, as its row in mergestat.query_packs requires
+func (i *installer) sync(ctx context.Context, pack *Pack, refresh bool) (err error) {
+	var tx pgx.Tx
+	if tx, err = i.pool.Begin(ctx); err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	const lock = `
+SELECT enabled, version, refresh_interval IS NOT NULL AND (last_refreshed_at IS NULL OR last_refreshed_at + refresh_interval <= now())
+    FROM mergestat.query_packs WHERE name = $1 FOR UPDATE SKIP LOCKED`
+
+	var enabled, due bool
+	var version *int
+	if err = tx.QueryRow(ctx, lock, pack.Name).Scan(&enabled, &version, &due); errors.Is(err, pgx.ErrNoRows) {
+		return nil // another worker is handling the pack
+	} else if err != nil {
+		return err
+	}
+
+	var schema = pgx.Identifier{pack.Schema()}.Sanitize()
+
+	switch {
+	case !enabled && version != nil:
+		if _, err = tx.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE"); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, "UPDATE mergestat.query_packs SET version = NULL, installed_at = NULL, last_refreshed_at = NULL, last_error = NULL WHERE name = $1", pack.Name); err != nil {
+			return err
+		}
+		i.logger.Info().Msgf("uninstalled query pack %s", pack.Name)
+		return tx.Commit(ctx)
+
+	case !enabled:
+		return nil
+
+	// a pack installed by a worker shipping a newer version of it is left as is
+	case version == nil || *version < pack.Version:
+		if err = install(ctx, tx, pack); err != nil {
+			return errors.Wrapf(err, "failed to install version %d", pack.Version)
+		}
+		if _, err = tx.Exec(ctx, "UPDATE mergestat.query_packs SET version = $2, installed_at = now(), last_refreshed_at = NULL, last_error = NULL WHERE name = $1", pack.Name, pack.Version); err != nil {
+			return err
+		}
+		i.logger.Info().Msgf("installed version %d of query pack %s", pack.Version, pack.Name)
+
+		// the snapshots of the previous version are gone with its schema
+		due = due || refresh || version != nil
+	}
+
+	if (due || refresh) && len(pack.Materialize) > 0 {
+		for _, view := range pack.Materialize {
+			var snapshot = pgx.Identifier{pack.Schema(), view + "_snapshot"}.Sanitize()
+			if _, err = tx.Exec(ctx, "DROP TABLE IF EXISTS "+snapshot); err != nil {
+				return err
+			}
+			if _, err = tx.Exec(ctx, "CREATE TABLE "+snapshot+" AS SELECT now() AS refreshed_at, v.* FROM "+pgx.Identifier{pack.Schema(), view}.Sanitize()+" v"); err != nil {
+				return errors.Wrapf(err, "failed to refresh %s", view)
+			}
+		}
+		if _, err = tx.Exec(ctx, "UPDATE mergestat.query_packs SET last_refreshed_at = now(), last_error = NULL WHERE name = $1", pack.Name); err != nil {
+			return err
+		}
+		i.logger.Info().Msgf("refreshed %d snapshot(s) of query pack %s", len(pack.Materialize), pack.Name)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// install (re)creates the schema of the pack, and the views of the pack in it
+func install(ctx context.Context, tx pgx.Tx, pack *Pack) (err error) {
+	var schema = pgx.Identifier{pack.Schema()}.Sanitize()
+	for _, stmt := range []string{
+		"DROP SCHEMA IF EXISTS " + schema + " CASCADE",
+		"CREATE SCHEMA " + schema,
+		"SET LOCAL search_path TO " + schema + ", public",
+		pack.SQL, // without arguments, the statements are sent at once (simple protocol)
+		"COMMENT ON SCHEMA " + schema + " IS " + quoteLiteral(pack.Description+" (query pack, version "+strconv.Itoa(pack.Version)+")"),
+		"RESET search_path",
+	} {
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quoteLiteral quotes a Postgres string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,107 @@
+package retention
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/dialect"
+	"github.com/rs/zerolog"
+)
+
+// retention periodically removes finished repo sync jobs (and their logs) from the queue,
+// according to the policies in mergestat.repo_sync_queue_retention_policies, and rows of
+// synced tables according to the policies in mergestat.data_retention_policies
+type retention struct {
+	logger *zerolog.Logger
+	pool   *pgxpool.Pool
+	db     *db.Queries
+
+	// advisoryLocks is set if the policies are enforced: only one worker at a time enforces them (by way of an
+	// advisory lock), where advisory locks aren't supported the queue is cleaned up by retention period only
+	advisoryLocks bool
+}
+
+func New(logger *zerolog.Logger, pool *pgxpool.Pool) *retention {
+	return &retention{
+		logger:        logger,
+		pool:          pool,
+		db:            db.New(pool),
+		advisoryLocks: true,
+	}
+}
+
+// WithDialect configures the routine for the given (Postgres-compatible) backend
+func (s *retention) WithDialect(d *dialect.Dialect) *retention {
+	s.advisoryLocks = d.AdvisoryLocks
+	return s
+}
+
+func (s *retention) Start(ctx context.Context, interval time.Duration) {
+	s.logger.Info().Msg("starting queue cleanup routine")
+	exec := func() {
+		// REPO_SYNC_QUEUE_RETENTION_DAYS, if set, overrides the retention policies with a single
+		// retention period for all jobs (and allows for REPO_SYNC_QUEUE_RETENTION_DAYS=-1 to skip the cleanup).
+		// Without advisory locks, the retention period (30 days by default) is the only one applied.
+		retentionPeriodDays := 30
+		if days := os.Getenv("REPO_SYNC_QUEUE_RETENTION_DAYS"); days != "" || !s.advisoryLocks {
+			if days != "" {
+				var err error
+				if retentionPeriodDays, err = strconv.Atoi(days); err != nil {
+					// the queue isn't cleaned up rather than by a period that wasn't meant, the data retention policies still apply
+					s.logger.Err(err).Msgf("could not parse REPO_SYNC_QUEUE_RETENTION_DAYS env, skipping queue cleanup: %v", err)
+					retentionPeriodDays = -1
+				}
+			}
+
+			if retentionPeriodDays > 0 {
+				if err := s.db.CleanOldRepoSyncQueue(ctx, int32(retentionPeriodDays)); err != nil {
+					s.logger.Err(err).Msg("encountered error cleaning queue logs")
+				} else {
+					s.logger.Info().Msgf("successfully removed repo sync jobs older than %d days", retentionPeriodDays)
+				}
+			}
+		} else {
+			if deleted, err := s.db.CleanRepoSyncQueueByPolicy(ctx); err != nil {
+				s.logger.Err(err).Msg("encountered error cleaning queue logs")
+			} else if deleted < 0 {
+				s.logger.Debug().Msg("queue cleanup is already running on another worker, skipping")
+			} else {
+				s.logger.Info().Msgf("successfully removed %d repo sync job(s) past their retention period", deleted)
+			}
+		}
+
+		if retentionPeriodDays > 0 {
+			if err := s.db.CleanOldJobs(ctx, int32(retentionPeriodDays)); err != nil {
+				s.logger.Err(err).Msg("encountered error cleaning sqlq logs")
+			} else {
+				s.logger.Info().Msgf("successfully removed sqlq jobs older than %d days", retentionPeriodDays)
+			}
+		}
+
+		if !s.advisoryLocks {
+			return
+		}
+		if deleted, err := s.db.EnforceDataRetention(ctx); err != nil {
+			s.logger.Err(err).Msg("encountered error enforcing data retention policies")
+		} else if deleted < 0 {
+			s.logger.Debug().Msg("data retention is already being enforced by another worker, skipping")
+		} else if deleted > 0 {
+			s.logger.Info().Msgf("successfully removed %d synced row(s) past their retention period", deleted)
+		}
+	}
+	exec()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info().Msg("stopping queue cleanup routine")
+			return
+		case <-time.After(interval):
+			exec()
+		}
+	}
+}
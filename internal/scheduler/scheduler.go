@@ -2,12 +2,11 @@ package scheduler
 
 import (
 	"context"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/dialect"
 	"github.com/rs/zerolog"
 )
 
@@ -15,48 +14,63 @@ type scheduler struct {
 	logger *zerolog.Logger
 	pool   *pgxpool.Pool
 	db     *db.Queries
+
+	// lifecyclePolicy is set if the repo lifecycle policy is applied (it's not where temporary tables aren't supported)
+	lifecyclePolicy bool
 }
 
 func New(logger *zerolog.Logger, pool *pgxpool.Pool) *scheduler {
 	return &scheduler{
-		logger: logger,
-		pool:   pool,
-		db:     db.New(pool),
+		logger:          logger,
+		pool:            pool,
+		db:              db.New(pool),
+		lifecyclePolicy: true,
 	}
 }
 
+// WithDialect configures the scheduler for the given (Postgres-compatible) backend
+func (s *scheduler) WithDialect(d *dialect.Dialect) *scheduler {
+	s.lifecyclePolicy = d.TemporaryTables
+	return s
+}
+
 func (s *scheduler) Start(ctx context.Context, interval time.Duration) {
 	s.logger.Info().Msg("starting scheduler")
 	exec := func() {
+		// nothing is enqueued while the maintenance mode is enabled, syncs due resume once it's disabled
+		if mode, err := s.db.GetMaintenanceMode(ctx); err != nil {
+			s.logger.Err(err).Msg("encountered error checking maintenance mode")
+		} else if mode.Enabled {
+			s.logger.Info().Msg("maintenance mode enabled, skipping scheduling")
+			return
+		}
+
+		// intervals are adapted to the activity of repos before syncs due to run are enqueued
+		if syncs, err := s.db.ApplyAdaptiveSchedule(ctx); err != nil {
+			s.logger.Err(err).Msg("encountered error applying adaptive schedule")
+		} else if syncs > 0 {
+			s.logger.Info().Msgf("applied adaptive schedule, interval of %d sync(s) changed", syncs)
+		}
+
 		if err := s.db.EnqueueAllSyncs(ctx); err != nil {
 			s.logger.Err(err).Msg("encountered error during scheduler execution")
 		} else {
 			s.logger.Info().Msg("re-scheduling all completed syncs to run again")
 		}
 
-		// TODO(patrickdevivo) this should probably be lifted up into a config/param
-		// of the scheduler, which is passed into New and defined by the caller
-		retentionPeriodDays := 30
-		if days := os.Getenv("REPO_SYNC_QUEUE_RETENTION_DAYS"); days != "" {
-			var err error
-			if retentionPeriodDays, err = strconv.Atoi(days); err != nil {
-				s.logger.Err(err).Msgf("could not parse REPO_SYNC_QUEUE_RETENTION_DAYS env: %v", err)
-			}
+		if enqueued, err := s.db.EnqueueRepoGroupSyncs(ctx); err != nil {
+			s.logger.Err(err).Msg("encountered error scheduling repo group syncs")
+		} else if enqueued > 0 {
+			s.logger.Info().Msgf("scheduled %d sync(s) of repo groups due to run", enqueued)
 		}
 
-		// allows for REPO_SYNC_QUEUE_RETENTION_DAYS=-1 to skip the cleanup
-		if retentionPeriodDays > 0 {
-			if err := s.db.CleanOldRepoSyncQueue(ctx, int32(retentionPeriodDays)); err != nil {
-				s.logger.Err(err).Msg("encountered error cleaning queue logs")
-			} else {
-				s.logger.Info().Msgf("successfully removed repo sync jobs older than %d days", retentionPeriodDays)
-			}
-
-			if err := s.db.CleanOldJobs(ctx, int32(retentionPeriodDays)); err != nil {
-				s.logger.Err(err).Msg("encountered error cleaning sqlq logs")
-			} else {
-				s.logger.Info().Msgf("successfully removed sqlq jobs older than %d days", retentionPeriodDays)
-			}
+		if !s.lifecyclePolicy {
+			return
+		}
+		if repos, err := s.db.ApplyRepoLifecyclePolicy(ctx); err != nil {
+			s.logger.Err(err).Msg("encountered error applying repo lifecycle policy")
+		} else if repos > 0 {
+			s.logger.Info().Msgf("applied repo lifecycle policy, %d repo(s) flagged or unflagged as dormant", repos)
 		}
 	}
 	exec()
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// Handler serves searches over http, authenticated with the bearer token, eg.
+//
+//	GET /search?q=InsecureSkipVerify&kind=code&repo=github.com/mergestat/*&tag=go&limit=20
+//
+// The repo and tag parameters may be repeated. Matches are returned as JSON: {"matches": [...]}. Encrypted values
+// of matches are decrypted with the cipher (which may be nil).
+func Handler(pool *pgxpool.Pool, c *helper.ColumnCipher, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var bearer = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "only GET is supported")
+			return
+		}
+
+		var params = r.URL.Query()
+		var q = Query{Text: params.Get("q"), Repos: params["repo"], Tags: params["tag"]}
+
+		var err error
+		if q.Kind, err = ParseKind(params.Get("kind")); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if limit := params.Get("limit"); limit != "" {
+			if q.Limit, err = strconv.Atoi(limit); err != nil {
+				writeError(w, http.StatusBadRequest, "limit must be a number")
+				return
+			}
+		}
+		if strings.TrimSpace(q.Text) == "" {
+			writeError(w, http.StatusBadRequest, "the q parameter is required")
+			return
+		}
+
+		var matches []Match
+		if matches, err = Search(r.Context(), pool, c, q); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(struct {
+			Matches []Match `json:"matches"`
+		}{matches})
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{message})
+}
@@ -0,0 +1,243 @@
+// Package search implements the search of code, commit messages, issues and pull requests across the synced repos,
+// filtered by repo and tag. Searches are served by the search indexes, when enabled (see mergestat.search_indexes):
+// code is matched with ILIKE (a trgm index on git_files.contents), and text with to_tsvector() @@ websearch_to_tsquery()
+// in the configuration of the tsvector index of the column (simple if there's none). Encrypted columns (see
+// mergestat.encrypted_columns) can't be searched, as the database only sees their ciphertext.
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/pkg/errors"
+)
+
+// Kind is what a search runs over
+type Kind string
+
+const (
+	KindCode    Kind = "code"
+	KindCommits Kind = "commits"
+	KindIssues  Kind = "issues"
+	KindPulls   Kind = "pulls"
+)
+
+const (
+	// DefaultLimit and MaxLimit are the default and maximum number of matches of a search
+	DefaultLimit = 50
+	MaxLimit     = 500
+
+	// maxLinesPerFile is the number of matching lines returned per file of a code search
+	maxLinesPerFile = 5
+)
+
+// Query is a search
+type Query struct {
+	Text string
+	Kind Kind
+
+	// Repos restricts the search to the repos matching any of the patterns, where * matches any characters (eg. github.com/mergestat/*)
+	Repos []string
+
+	// Tags restricts the search to the repos with any of the tags
+	Tags []string
+
+	Limit int
+}
+
+// Match is a match of a search
+type Match struct {
+	Kind Kind   `json:"kind"`
+	Repo string `json:"repo"`
+
+	// Path and Lines are the file and its matching lines, of code searches
+	Path  string              `json:"path,omitempty"`
+	Lines []helper.SearchLine `json:"lines,omitempty"`
+
+	// Commit is the hash of the matching commit, of commit searches
+	Commit string `json:"commit,omitempty"`
+
+	// Number, State and URL are those of the matching issue or pull request
+	Number int    `json:"number,omitempty"`
+	State  string `json:"state,omitempty"`
+	URL    string `json:"url,omitempty"`
+
+	// Title is the title of the issue or pull request, or the first line of the commit message
+	Title string `json:"title,omitempty"`
+
+	// Snippet is an excerpt of the matching text (with the matches in **), of text searches
+	Snippet string `json:"snippet,omitempty"`
+
+	// Rank is the relevance of text matches (higher is better)
+	Rank float32 `json:"rank,omitempty"`
+}
+
+// ParseKind parses the kind of a search, defaulting to code
+func ParseKind(s string) (Kind, error) {
+	switch k := Kind(strings.ToLower(s)); k {
+	case "":
+		return KindCode, nil
+	case KindCode, KindCommits, KindIssues, KindPulls:
+		return k, nil
+	default:
+		return "", errors.Errorf("unknown search kind %q (must be one of code, commits, issues or pulls)", s)
+	}
+}
+
+// Search runs the query, and returns its matches: the most relevant first for text searches, by repo and path for code.
+// The synced tables are referenced by their own name, so that they resolve through the search_path of the pool when
+// relocated (see namespace.SearchPath). The encrypted values of matches are decrypted with the cipher (which may be nil).
+func Search(ctx context.Context, pool *pgxpool.Pool, c *helper.ColumnCipher, q Query) (_ []Match, err error) {
+	if strings.TrimSpace(q.Text) == "" {
+		return nil, errors.New("search text is required")
+	}
+	if q.Kind, err = ParseKind(string(q.Kind)); err != nil {
+		return nil, err
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+
+	var patterns = make([]string, 0, len(q.Repos))
+	for _, repo := range q.Repos {
+		patterns = append(patterns, likePattern(repo, "*"))
+	}
+	var tags = q.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	var encrypted helper.EncryptedColumns
+	if encrypted, err = helper.CollectEncryptedColumns(pool.Query(ctx, helper.ListEncryptedColumns)); err != nil {
+		return nil, err
+	}
+	var searchable = func(table, column string) error {
+		if encrypted[table][column] {
+			return errors.Errorf("%s.%s is encrypted (see mergestat.encrypted_columns), and can't be searched", table, column)
+		}
+		return nil
+	}
+
+	// repos filters the repos of the search by pattern ($2) and tag ($3), the search text being $1 and the limit $4
+	const repos = `(cardinality($2::TEXT[]) = 0 OR r.repo ILIKE ANY($2::TEXT[])) AND (cardinality($3::TEXT[]) = 0 OR r.tags ?| $3::TEXT[])`
+
+	switch q.Kind {
+	case KindCode:
+		if err = searchable("git_files", "contents"); err != nil {
+			return nil, err
+		}
+		var sql = `
+SELECT r.repo, f.path, f.contents FROM git_files f INNER JOIN public.repos r ON r.id = f.repo_id
+WHERE f.contents ILIKE '%' || $1 || '%' AND ` + repos + `
+ORDER BY r.repo, f.path LIMIT $4`
+		return query(ctx, pool, q.Kind, sql, func(rows pgx.Rows) (m Match, err error) {
+			var contents string
+			if err = rows.Scan(&m.Repo, &m.Path, &contents); err != nil {
+				return m, err
+			}
+			m.Lines = helper.SearchLines(contents, q.Text, maxLinesPerFile)
+			return m, nil
+		}, likePattern(q.Text, ""), patterns, tags, q.Limit)
+
+	case KindCommits:
+		if err = searchable("git_commits", "message"); err != nil {
+			return nil, err
+		}
+		var config string
+		if config, err = textSearchConfig(ctx, pool, "git_commits", "message"); err != nil {
+			return nil, err
+		}
+		var sql = fmt.Sprintf(`
+WITH q AS (SELECT websearch_to_tsquery(%[1]s, $1) AS query)
+SELECT r.repo, c.hash, split_part(c.message, E'\n', 1), ts_headline(%[1]s, c.message, q.query, %[2]s),
+    ts_rank(to_tsvector(%[1]s, c.message), q.query)
+FROM git_commits c INNER JOIN public.repos r ON r.id = c.repo_id, q
+WHERE to_tsvector(%[1]s, c.message) @@ q.query AND `+repos+`
+ORDER BY 5 DESC, c.author_when DESC LIMIT $4`, config, headlineOptions)
+		return query(ctx, pool, q.Kind, sql, func(rows pgx.Rows) (m Match, err error) {
+			err = rows.Scan(&m.Repo, &m.Commit, &m.Title, &m.Snippet, &m.Rank)
+			return m, err
+		}, q.Text, patterns, tags, q.Limit)
+
+	default: // issues and pull requests
+		var table = "github_issues"
+		if q.Kind == KindPulls {
+			table = "github_pull_requests"
+		}
+		if err = searchable(table, "body"); err != nil {
+			return nil, err
+		}
+		var config string
+		if config, err = textSearchConfig(ctx, pool, table, "body"); err != nil {
+			return nil, err
+		}
+		var sql = fmt.Sprintf(`
+WITH q AS (SELECT websearch_to_tsquery(%[1]s, $1) AS query)
+SELECT r.repo, i.number, COALESCE(i.state, ''), COALESCE(i.url, ''), COALESCE(i.title, ''),
+    ts_headline(%[1]s, i.body, q.query, %[2]s), ts_rank(to_tsvector(%[1]s, i.body), q.query)
+FROM %[3]s i INNER JOIN public.repos r ON r.id = i.repo_id, q
+WHERE to_tsvector(%[1]s, i.body) @@ q.query AND `+repos+`
+ORDER BY 7 DESC, i.number DESC LIMIT $4`, config, headlineOptions, pgx.Identifier{table}.Sanitize())
+		return query(ctx, pool, q.Kind, sql, func(rows pgx.Rows) (m Match, err error) {
+			if err = rows.Scan(&m.Repo, &m.Number, &m.State, &m.URL, &m.Title, &m.Snippet, &m.Rank); err != nil {
+				return m, err
+			}
+			for column, value := range map[string]*string{"state": &m.State, "url": &m.URL, "title": &m.Title} {
+				if *value, err = encrypted.Decrypt(c, table, column, *value); err != nil {
+					return m, errors.Wrapf(err, "%s.%s", table, column)
+				}
+			}
+			return m, nil
+		}, q.Text, patterns, tags, q.Limit)
+	}
+}
+
+// headlineOptions are the options of ts_headline() snippets of text matches
+const headlineOptions = `'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=**, StopSel=**'`
+
+func query(ctx context.Context, pool *pgxpool.Pool, kind Kind, sql string, scan func(pgx.Rows) (Match, error), args ...interface{}) (_ []Match, err error) {
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, sql, args...); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches = make([]Match, 0)
+	for rows.Next() {
+		var m Match
+		if m, err = scan(rows); err != nil {
+			return nil, err
+		}
+		m.Kind = kind
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// textSearchConfig returns the configuration of the tsvector index of the column (as a quoted literal), as queries
+// must use the configuration of the index to be served by it
+func textSearchConfig(ctx context.Context, pool *pgxpool.Pool, table, column string) (string, error) {
+	const get = `SELECT config::TEXT FROM mergestat.search_indexes WHERE table_name = $1 AND column_name = $2 AND method = 'tsvector'`
+
+	var config = "simple"
+	if err := pool.QueryRow(ctx, get, table, column).Scan(&config); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+	return "'" + strings.ReplaceAll(config, "'", "''") + "'", nil
+}
+
+// likePattern escapes the LIKE wildcards of s, then turns the occurrences of wildcard (if any) into %
+func likePattern(s, wildcard string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	if wildcard != "" {
+		s = strings.ReplaceAll(s, wildcard, "%")
+	}
+	return s
+}
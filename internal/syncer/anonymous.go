@@ -0,0 +1,52 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/mergestat/mergestat/internal/db"
+	"golang.org/x/oauth2"
+)
+
+// WithAnonymousAccess lets the syncs that work on public repos without a credential (eg. GITHUB_REPO_METADATA) call
+// the GitHub api anonymously when no credential is configured, instead of failing. Anonymous calls are limited to 60
+// per hour (per ip), which suits a few public repos (or, with polite mode, a slow scan of many).
+func (w *worker) WithAnonymousAccess() *worker {
+	w.anonymous = true
+	return w
+}
+
+// githubToken returns the GitHub token of the job's provider. Without one it fails with errGitHubTokenRequired,
+// unless anonymous access is allowed (see WithAnonymousAccess), in which case it returns an empty token.
+func (w *worker) githubToken(ctx context.Context, j *db.DequeueSyncJobRow) (_ string, err error) {
+	var token string
+	if _, token, err = w.fetchCredentials(ctx, j); err != nil {
+		return "", err
+	}
+
+	if len(token) > 0 {
+		return token, nil
+	}
+
+	if !w.anonymous {
+		return "", errGitHubTokenRequired
+	}
+
+	if err = w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf("no GitHub credential is configured, calling the api anonymously for %s", j.Repo),
+	}}); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+// githubHTTPClient returns the client GitHub api calls are made with, authenticated with the token unless it's empty.
+// Either way, the calls go through the client of the context (see trackAPIUsage).
+func githubHTTPClient(ctx context.Context, token string) *http.Client {
+	if len(token) <= 0 {
+		return oauth2.NewClient(ctx, nil)
+	}
+	return oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+}
@@ -0,0 +1,178 @@
+package syncer
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mergestat/mergestat/internal/db"
+	"golang.org/x/oauth2"
+)
+
+// apiUsage is an http.RoundTripper that counts the api calls made through it, and keeps track of the
+// remaining rate limit (and when it resets) last reported by the api (see mergestat.provider_api_budgets)
+type apiUsage struct {
+	base http.RoundTripper
+
+	mu        sync.Mutex
+	calls     int32
+	remaining sql.NullInt32
+	resetAt   sql.NullTime
+}
+
+func (u *apiUsage) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp, err = u.base.RoundTrip(req)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.calls++
+	if resp == nil {
+		return resp, err
+	}
+
+	// GitHub reports the rate limit in X-RateLimit-* headers, GitLab in RateLimit-* headers
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if remaining, perr := strconv.Atoi(resp.Header.Get(prefix + "Remaining")); perr == nil {
+			u.remaining = sql.NullInt32{Int32: int32(remaining), Valid: true}
+		}
+		if reset, perr := strconv.ParseInt(resp.Header.Get(prefix+"Reset"), 10, 64); perr == nil {
+			u.resetAt = sql.NullTime{Time: time.Unix(reset, 0), Valid: true}
+		}
+	}
+
+	return resp, err
+}
+
+// WithTransport sets the http transport the api calls of syncs are made through (eg. to a fake api, in tests)
+func (w *worker) WithTransport(transport http.RoundTripper) *worker {
+	w.transport = transport
+	return w
+}
+
+// apiTransport returns the transport the api calls of the worker are made through, politely in polite mode
+func (w *worker) apiTransport() http.RoundTripper {
+	var base = w.transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if w.polite != nil {
+		base = &politeTransport{polite: w.polite, base: base}
+	}
+	return base
+}
+
+// trackAPIUsage returns a context that the api clients (created with oauth2.NewClient) of the job count their calls
+// through, and a function that records the calls made against the api budget of the repo's provider
+func (w *worker) trackAPIUsage(ctx context.Context, j *db.DequeueSyncJobRow) (context.Context, func()) {
+	var usage = &apiUsage{base: w.apiTransport()}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: usage})
+
+	var repo, err = w.db.GetRepoById(ctx, j.RepoID)
+	if err != nil {
+		w.loggerForJob(j).Warn().AnErr("error", err).Msg("could not look up provider of repo, not tracking api usage")
+		return ctx, func() {}
+	}
+
+	return ctx, func() {
+		usage.mu.Lock()
+		defer usage.mu.Unlock()
+
+		if usage.calls == 0 {
+			return
+		}
+
+		var params = db.RecordAPIUsageParams{
+			Providerid:    repo.Provider,
+			Calls:         usage.calls,
+			LastRemaining: usage.remaining,
+			ResetAt:       usage.resetAt,
+		}
+
+		// the job's context may be done by now (eg. on shutdown), the calls were made regardless
+		if err := w.db.RecordAPIUsage(context.Background(), params); err != nil {
+			w.loggerForJob(j).Err(err).Msg("could not record api usage")
+		}
+	}
+}
+
+// providerUsageInterval is the interval the api calls made through provider transports are recorded on
+const providerUsageInterval = 30 * time.Second
+
+// providerUsage is the usage of the api clients of each provider that aren't created for a job: their calls are
+// counted in memory, and recorded against the api budget of the provider periodically (see recordProviderUsage)
+type providerUsage struct {
+	mu     sync.Mutex
+	usages map[uuid.UUID]*apiUsage
+}
+
+// ProviderTransport returns the transport of the api clients of the given provider that aren't created for a job
+// (eg. the GitHub client of the mergestat-lite module, see options.WithGitHubClientGetter): like the clients of
+// jobs, their calls are made politely in polite mode, and recorded against the api budget of the provider
+func (w *worker) ProviderTransport(provider uuid.UUID) http.RoundTripper {
+	w.providerUsage.mu.Lock()
+	defer w.providerUsage.mu.Unlock()
+
+	if w.providerUsage.usages == nil {
+		w.providerUsage.usages = make(map[uuid.UUID]*apiUsage)
+	}
+	var usage, ok = w.providerUsage.usages[provider]
+	if !ok {
+		usage = &apiUsage{base: w.apiTransport()}
+		w.providerUsage.usages[provider] = usage
+	}
+	return usage
+}
+
+// recordProviderUsage records the calls made through provider transports every providerUsageInterval,
+// and once more when the context is done
+func (w *worker) recordProviderUsage(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			w.flushProviderUsage(context.Background())
+			return
+		case <-time.After(providerUsageInterval):
+			w.flushProviderUsage(ctx)
+		}
+	}
+}
+
+// flushProviderUsage records the calls made through provider transports since they were last recorded
+func (w *worker) flushProviderUsage(ctx context.Context) {
+	w.providerUsage.mu.Lock()
+	var providers = make(map[uuid.UUID]*apiUsage, len(w.providerUsage.usages))
+	for provider, usage := range w.providerUsage.usages {
+		providers[provider] = usage
+	}
+	w.providerUsage.mu.Unlock()
+
+	for provider, usage := range providers {
+		usage.mu.Lock()
+		var params = db.RecordAPIUsageParams{
+			Providerid:    provider,
+			Calls:         usage.calls,
+			LastRemaining: usage.remaining,
+			ResetAt:       usage.resetAt,
+		}
+		usage.calls = 0
+		usage.mu.Unlock()
+
+		if params.Calls == 0 {
+			continue
+		}
+
+		if err := w.db.RecordAPIUsage(ctx, params); err != nil {
+			w.logger.Err(err).Msg("could not record api usage")
+
+			// the calls are recorded with the next ones
+			usage.mu.Lock()
+			usage.calls += params.Calls
+			usage.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package syncer
+
+import (
+	"context"
+	"database/sql"
+	"runtime/debug"
+
+	"github.com/google/uuid"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// backfillPriority is the queue priority of backfill jobs, so that they run after regularly scheduled syncs
+const backfillPriority = 100
+
+// syncTypeVersions are the versions of the sync handlers whose output changed (because of a schema or logic change)
+// since they were introduced, every other handler is at version 1. Bumping the version of a type schedules a full
+// re-sync of every repo last synced by an older version, the next time a worker starts.
+var syncTypeVersions = map[string]int32{
+	syncTypeGitRefs:          2, // branch stats (public.git_branch_stats)
+	syncTypeGitBlame:         3, // file ownership (public.git_file_ownership), line ages (public.git_file_line_ages)
+	syncTypeGitFiles:         2, // content hashes (git_files.content_hash) and file changes (public.git_file_changes)
+	syncTypeGitHubRepoIssues: 3, // label events (public.github_issue_label_events)
+	syncTypeGitHubRepoPRs:    3, // label events (public.github_issue_label_events)
+}
+
+// handlerVersion returns the version of the handler of the sync type
+func handlerVersion(syncType string) int32 {
+	if version, ok := syncTypeVersions[syncType]; ok {
+		return version
+	}
+	return 1
+}
+
+// buildVersion is the version of the worker code set at build time (see the Makefile), with
+// -ldflags "-X github.com/mergestat/mergestat/internal/syncer.buildVersion=..."
+var buildVersion string
+
+// codeVersion is the version of the worker code: the version set at build time or else the vcs revision it was built
+// from (suffixed with -dirty if the working tree was modified) or, if the binary wasn't built from a repository, unknown
+var codeVersion = func() string {
+	if buildVersion != "" {
+		return buildVersion
+	}
+
+	var info, ok = debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	var revision, dirty string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				dirty = "-dirty"
+			}
+		}
+	}
+
+	if revision == "" {
+		return "unknown"
+	}
+	return revision + dirty
+}()
+
+// recordVersion records the version of the handler that successfully synced the job's repo sync,
+// and the provenance of the batch of rows the job wrote (see mergestat.repo_sync_batches)
+func (w *worker) recordVersion(ctx context.Context, j *db.DequeueSyncJobRow) {
+	// jobs that weren't dequeued (eg. the ones the doctor runs) don't belong to a repo sync
+	if j.RepoSyncID == uuid.Nil {
+		return
+	}
+
+	var version = handlerVersion(j.SyncType)
+
+	if err := w.db.SetSyncVersion(ctx, db.SetSyncVersionParams{Reposyncid: j.RepoSyncID, Version: version}); err != nil {
+		w.loggerForJob(j).Err(err).Msg("could not record version of sync handler")
+	}
+
+	var batch = db.InsertSyncBatchParams{
+		Reposyncqueueid: j.ID,
+		Reposyncid:      j.RepoSyncID,
+		Repoid:          j.RepoID,
+		Synctype:        j.SyncType,
+		Handlerversion:  version,
+		Codeversion:     codeVersion,
+	}
+
+	if err := w.db.InsertSyncBatch(ctx, batch); err != nil {
+		w.loggerForJob(j).Err(err).Msg("could not record provenance of sync batch")
+	}
+}
+
+// scheduleBackfills enqueues a full re-sync of the repo syncs last synced by an older version of their handler
+func (w *worker) scheduleBackfills(ctx context.Context) {
+	if !w.dialect.AdvisoryLocks {
+		w.logger.Warn().Msgf("backfills are not supported by %s, skipping", w.dialect.Name)
+		return
+	}
+
+	for syncType, version := range syncTypeVersions {
+		var params = db.BackfillRepoSyncsParams{
+			Synctype: syncType,
+			Version:  sql.NullInt32{Int32: version, Valid: true},
+			Priority: backfillPriority,
+		}
+
+		if enqueued, err := w.db.BackfillRepoSyncs(ctx, params); err != nil {
+			w.logger.Err(err).Msgf("could not schedule backfill of %s syncs", syncType)
+		} else if enqueued > 0 {
+			w.logger.Info().Msgf("scheduled backfill of %d %s sync(s) to handler version %d", enqueued, syncType, version)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// BenchOptions configures a benchmark of the syncs (see Bench)
+type BenchOptions struct {
+	// Files, Commits and Lines are the size of the synthetic repository: the number of files, of commits
+	// (the first adds every file, the following ones each change a few) and of lines per file
+	Files, Commits, Lines int
+
+	// Seed seeds the generation of the synthetic repository, so that runs with the same options sync the same repository
+	Seed int64
+
+	// SyncTypes are the syncs to benchmark, eg. GIT_BLAME
+	SyncTypes []string
+
+	// CopyRows is the number of synthetic rows the COPY benchmark writes (none if zero)
+	CopyRows int
+}
+
+// BenchResult is the measurement of a single benchmark
+type BenchResult struct {
+	Name     string        `json:"name"`
+	Rows     int64         `json:"rows"`
+	Duration time.Duration `json:"duration"`
+
+	// AllocatedBytes is the memory the worker allocated, and PeakHeapBytes the largest its heap grew, during the
+	// benchmark. The memory of the processes a sync runs (eg. git blame) isn't accounted for.
+	AllocatedBytes uint64 `json:"allocatedBytes"`
+	PeakHeapBytes  uint64 `json:"peakHeapBytes"`
+}
+
+// RowsPerSecond is the throughput of the benchmark
+func (r *BenchResult) RowsPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Rows) / r.Duration.Seconds()
+}
+
+// Bench generates a synthetic repository, then measures the syncs of the given types against it, one after the
+// other, and the throughput of a bulk COPY of synthetic rows. Like Doctor, rows are written into a throwaway
+// scratch schema and nothing is enqueued. The synthetic repository is added to public.repos for the duration
+// of the benchmark.
+func Bench(ctx context.Context, pool *pgxpool.Pool, mergestat *sqlx.DB, logger *zerolog.Logger, opts BenchOptions) (_ []*BenchResult, err error) {
+	var dir string
+	if dir, err = os.MkdirTemp(os.Getenv("GIT_CLONE_PATH"), "mergestat-bench-*"); err != nil {
+		return nil, errors.Wrapf(err, "failed to create directory of synthetic repository")
+	}
+	defer os.RemoveAll(dir)
+
+	var start = time.Now()
+	if err = generateRepo(dir, opts); err != nil {
+		return nil, errors.Wrapf(err, "failed to generate synthetic repository")
+	}
+	logger.Info().Msgf("generated synthetic repository with %d file(s) of %d line(s) and %d commit(s) in %s", opts.Files, opts.Lines, opts.Commits, time.Since(start))
+
+	const addRepo = `
+WITH provider AS (
+    INSERT INTO mergestat.providers (name, vendor, settings) VALUES ('mergestat-bench-' || gen_random_uuid(), 'git', '{}') RETURNING id
+)
+INSERT INTO public.repos (repo, provider) SELECT $1, id FROM provider RETURNING id, provider`
+
+	var repo, provider uuid.UUID
+	if err = pool.QueryRow(ctx, addRepo, dir).Scan(&repo, &provider); err != nil {
+		return nil, errors.Wrapf(err, "failed to add synthetic repository")
+	}
+	defer func() {
+		// the repo is removed along with its provider
+		if _, delErr := pool.Exec(context.Background(), "DELETE FROM mergestat.providers WHERE id = $1", provider); delErr != nil {
+			logger.Err(delErr).Msg("failed to remove synthetic repository")
+		}
+	}()
+
+	var scratch = fmt.Sprintf("mergestat_bench_%d", time.Now().Unix())
+	if err = cloneTables(ctx, pool, scratch); err != nil {
+		return nil, errors.Wrapf(err, "failed to create scratch schema")
+	}
+	defer func() {
+		if _, dropErr := pool.Exec(context.Background(), "DROP SCHEMA "+pgx.Identifier{scratch}.Sanitize()+" CASCADE"); dropErr != nil {
+			logger.Err(dropErr).Msgf("failed to drop scratch schema %s", scratch)
+		}
+	}()
+
+	var scratchPool *pgxpool.Pool
+	if scratchPool, err = connectWithSchema(ctx, pool, scratch, 5); err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to database")
+	}
+	defer scratchPool.Close()
+
+	// sync logs would only add noise to the output
+	var quiet = logger.Level(zerolog.WarnLevel)
+	var w = New(scratchPool, mergestat, &quiet, 1, 0)
+	w.localLogs = true
+
+	var results []*BenchResult
+	for _, syncType := range opts.SyncTypes {
+		var job = &db.DequeueSyncJobRow{
+			CreatedAt: time.Now(),
+			Status:    "RUNNING",
+			RepoID:    repo,
+			SyncType:  syncType,
+			// every run must sync, regardless of the previous ones
+			Settings: pgtype.JSONB{Bytes: []byte(`{"disableChangeDetection": true}`), Status: pgtype.Present},
+			Repo:     dir,
+		}
+
+		var before, after int64
+		if before, err = totalRows(ctx, scratchPool, scratch, repo); err != nil {
+			return nil, errors.Wrapf(err, "failed to count synced rows")
+		}
+
+		var result *BenchResult
+		if result, err = measure(syncType, func() error { return w.handle(ctx, job) }); err != nil {
+			return nil, errors.Wrapf(err, "%s sync failed", syncType)
+		}
+
+		if after, err = totalRows(ctx, scratchPool, scratch, repo); err != nil {
+			return nil, errors.Wrapf(err, "failed to count synced rows")
+		}
+		result.Rows = after - before
+
+		results = append(results, result)
+	}
+
+	if opts.CopyRows > 0 {
+		var rows = syntheticCommitStats(repo, opts.CopyRows)
+		var result *BenchResult
+		if result, err = measure("COPY", func() error {
+			_, err := scratchPool.CopyFrom(ctx, pgx.Identifier{"git_commit_stats"}, []string{"repo_id", "commit_hash", "file_path", "additions", "deletions"}, pgx.CopyFromRows(rows))
+			return err
+		}); err != nil {
+			return nil, errors.Wrapf(err, "copy failed")
+		}
+		result.Rows = int64(len(rows))
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// measure runs fn, measuring its duration and the memory the worker allocates meanwhile
+func measure(name string, fn func() error) (*BenchResult, error) {
+	runtime.GC()
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	// the peak of the heap is sampled, as the runtime only reports its current size
+	var peak = before.HeapAlloc
+	var done = make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		var ticker = time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+
+		var stats runtime.MemStats
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if runtime.ReadMemStats(&stats); stats.HeapAlloc > peak {
+					peak = stats.HeapAlloc
+				}
+			}
+		}
+	}()
+
+	var start = time.Now()
+	var err = fn()
+	var duration = time.Since(start)
+
+	close(done)
+	wg.Wait()
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	if after.HeapAlloc > peak {
+		peak = after.HeapAlloc
+	}
+
+	return &BenchResult{Name: name, Duration: duration, AllocatedBytes: after.TotalAlloc - before.TotalAlloc, PeakHeapBytes: peak}, err
+}
+
+// totalRows returns the number of rows for the repo across every table of the schema
+func totalRows(ctx context.Context, pool *pgxpool.Pool, schema string, repo uuid.UUID) (int64, error) {
+	var counts, err = countRows(ctx, pool, schema, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, c := range counts {
+		total += c.Rows
+	}
+	return total, nil
+}
+
+// benchWords are the words the lines of the synthetic repository are made of
+var benchWords = strings.Fields("func return value error context table rows sync repo commit blame file line author index query copy batch")
+
+// generateRepo creates, in dir, a repository with opts.Files files of opts.Lines lines each and opts.Commits commits
+// by a handful of authors: the first commit adds every file, the following ones each rewrite some lines of a few files
+func generateRepo(dir string, opts BenchOptions) error {
+	var random = rand.New(rand.NewSource(opts.Seed))
+	var line = func() string {
+		var words = make([]string, 4+random.Intn(8))
+		for i := range words {
+			words[i] = benchWords[random.Intn(len(benchWords))]
+		}
+		return strings.Join(words, " ")
+	}
+
+	var repo, err = git.PlainInit(dir, false)
+	if err != nil {
+		return err
+	}
+
+	var worktree *git.Worktree
+	if worktree, err = repo.Worktree(); err != nil {
+		return err
+	}
+
+	var files = make([][]string, opts.Files)
+	var write = func(i int) error {
+		var path = fmt.Sprintf("pkg%d/file%d.go", i%10, i)
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(strings.Join(files[i], "\n")+"\n"), 0o644); err != nil {
+			return err
+		}
+		_, err := worktree.Add(path)
+		return err
+	}
+
+	var when = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for c := 0; c < opts.Commits; c++ {
+		if c == 0 {
+			for i := range files {
+				files[i] = make([]string, opts.Lines)
+				for l := range files[i] {
+					files[i][l] = line()
+				}
+				if err = write(i); err != nil {
+					return err
+				}
+			}
+		} else if opts.Files > 0 && opts.Lines > 0 {
+			for n := 1 + random.Intn(3); n > 0; n-- {
+				var i = random.Intn(opts.Files)
+				for changes := 1 + random.Intn(opts.Lines/10+1); changes > 0; changes-- {
+					files[i][random.Intn(opts.Lines)] = line()
+				}
+				if err = write(i); err != nil {
+					return err
+				}
+			}
+		}
+
+		var author = random.Intn(8)
+		var signature = &object.Signature{Name: fmt.Sprintf("Author %d", author), Email: fmt.Sprintf("author%d@example.com", author), When: when}
+		if _, err = worktree.Commit(fmt.Sprintf("commit %d: %s", c, line()), &git.CommitOptions{Author: signature, AllowEmptyCommits: true}); err != nil {
+			return err
+		}
+		when = when.Add(time.Duration(1+random.Intn(240)) * time.Minute)
+	}
+
+	return nil
+}
+
+// syntheticCommitStats returns n rows of git_commit_stats for the repo
+func syntheticCommitStats(repo uuid.UUID, n int) [][]interface{} {
+	var rows = make([][]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		rows = append(rows, []interface{}{repo, fmt.Sprintf("%040x", i/10), fmt.Sprintf("pkg%d/file%d.go", i%10, i), i % 100, i % 30})
+	}
+	return rows
+}
@@ -0,0 +1,223 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// changeFailuresColumns are the columns of change_failures a sync writes
+var changeFailuresColumns = []string{"repo_id", "kind", "source", "commit_hash", "pull_request_number", "reverted_commit_hash", "branch", "title", "failed_at", "restored_at"}
+
+// pullRequestReferencePattern matches the references to pull requests in commit messages, eg. (#12) of squash merges
+var pullRequestReferencePattern = regexp.MustCompile(`#(\d+)\b`)
+
+// changeFailures detects the reverts and hotfixes of the (already synced) commits and pull requests of the repo
+func (w *worker) changeFailures(ctx context.Context, j *db.DequeueSyncJobRow) (_ [][]interface{}, err error) {
+	var failures [][]interface{}
+
+	// hotfix pull requests first, so that their commits aren't counted again
+	const selectPRs = `
+SELECT number, branch, title, created_at, merged_at, labeled FROM (
+    SELECT number, COALESCE(head_ref_name, '') AS branch, COALESCE(title, '') AS title, created_at, merged_at,
+        EXISTS (SELECT 1 FROM jsonb_array_elements(labels) l WHERE COALESCE(l->>'name', l#>>'{}') ~* 'hot[-_ ]?fix') AS labeled
+    FROM github_pull_requests WHERE repo_id = $1 AND merged_at IS NOT NULL
+) prs
+WHERE branch ~* 'hot[-_]?fix' OR title ~* 'hot[-_ ]?fix' OR labeled`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, selectPRs, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query pull requests: %w", err)
+	}
+
+	var hotfixPRs = make(map[string]bool)
+	var hotfixBranches = make(map[string]bool)
+	for rows.Next() {
+		var number int32
+		var branch, title string
+		var createdAt *time.Time
+		var mergedAt time.Time
+		var labeled bool
+		if err = rows.Scan(&number, &branch, &title, &createdAt, &mergedAt, &labeled); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		// the query only narrows down the candidates, eg. it matches photofix too
+		if !labeled && !helper.IsHotfixBranch(branch) && !helper.IsHotfixTitle(title) {
+			continue
+		}
+
+		hotfixPRs[fmt.Sprint(number)] = true
+		if branch != "" {
+			hotfixBranches[branch] = true
+		}
+		failures = append(failures, []interface{}{j.RepoID, "HOTFIX", "PULL_REQUEST", nil, number, nil, nullIfEmpty(branch), title, createdAt, mergedAt})
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	const selectCommits = "SELECT hash, COALESCE(message, ''), committer_when, parents FROM git_commits WHERE repo_id = $1"
+	if rows, err = w.pool.Query(ctx, selectCommits, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query commits: %w", err)
+	}
+	defer rows.Close()
+
+	type revert struct {
+		hash, title string
+		reverted    []string
+		committedAt time.Time
+	}
+	var reverts []revert
+	var committed = make(map[string]time.Time) // hash -> committer_when, to date the reverted commits
+
+	for rows.Next() {
+		var hash, message string
+		var committedAt time.Time
+		var parents int32
+		if err = rows.Scan(&hash, &message, &committedAt, &parents); err != nil {
+			return nil, err
+		}
+		committed[hash] = committedAt
+
+		var subject, _, _ = strings.Cut(message, "\n")
+		if reverted, ok := helper.ParseRevert(message); ok {
+			reverts = append(reverts, revert{hash: hash, title: subject, reverted: reverted, committedAt: committedAt})
+			continue
+		}
+
+		var branch string
+		if parents > 1 {
+			branch = helper.MergedBranch(message)
+		}
+		if !helper.IsHotfixBranch(branch) && !helper.IsHotfixTitle(subject) {
+			continue
+		}
+
+		// skip the (merge or squash) commits of the hotfix pull requests
+		var ofPR = hotfixBranches[branch]
+		for _, m := range pullRequestReferencePattern.FindAllStringSubmatch(subject, -1) {
+			ofPR = ofPR || hotfixPRs[m[1]]
+		}
+		if ofPR {
+			continue
+		}
+
+		failures = append(failures, []interface{}{j.RepoID, "HOTFIX", "COMMIT", hash, nil, nil, nullIfEmpty(branch), subject, nil, committedAt})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, r := range reverts {
+		if len(r.reverted) == 0 {
+			failures = append(failures, []interface{}{j.RepoID, "REVERT", "COMMIT", r.hash, nil, nil, nil, r.title, nil, r.committedAt})
+			continue
+		}
+		for _, reverted := range r.reverted {
+			var hash, failedAt = resolveCommit(committed, reverted)
+			failures = append(failures, []interface{}{j.RepoID, "REVERT", "COMMIT", r.hash, nil, hash, nil, r.title, failedAt, r.committedAt})
+		}
+	}
+
+	return failures, nil
+}
+
+// resolveCommit resolves a (possibly abbreviated) hash against the commits of the repo, returning the full hash
+// and the time of the commit, or the hash as is and nil if it isn't found
+func resolveCommit(committed map[string]time.Time, hash string) (string, interface{}) {
+	if at, ok := committed[hash]; ok {
+		return hash, at
+	}
+	for full, at := range committed {
+		if strings.HasPrefix(full, hash) {
+			return full, at
+		}
+	}
+	return hash, nil
+}
+
+func (w *worker) handleChangeFailures(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var failures [][]interface{}
+	if failures, err = w.changeFailures(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("found %d revert(s) and hotfix(es)", len(failures))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM change_failures WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from change_failures", r.RowsAffected()),
+		Details:         rowDetails("removed", "change_failures", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"change_failures"}, changeFailuresColumns, pgx.CopyFromRows(failures)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into change_failures", len(failures)),
+		Details:         rowDetails("inserted", "change_failures", int64(len(failures))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "change_failures", len(failures))
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package syncer
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// ChaosOptions configures the chaos test mode of the worker (see WithChaos), which makes jobs fail the way they
+// do when a worker crashes or loses its database, so that the timeout and requeue paths are exercised
+type ChaosOptions struct {
+	// KillProbability is the probability, per job, that the worker kills itself (with SIGKILL) while running it
+	KillProbability float64
+
+	// DisconnectProbability is the probability, per job, that the worker's connections to the database are
+	// terminated (by the database, see pg_terminate_backend) while running it
+	DisconnectProbability float64
+
+	// MaxDelay bounds the (uniformly random) time after the start of a job at which the chaos strikes
+	MaxDelay time.Duration
+}
+
+// WithChaos enables the chaos test mode. It must never be enabled in production.
+func (w *worker) WithChaos(opts ChaosOptions) *worker {
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 30 * time.Second
+	}
+	w.chaos = &opts
+	return w
+}
+
+// injectChaos, in chaos test mode, randomly schedules the worker's death (or the loss of its database connections)
+// during the job. The returned function cancels it, and must be called once the job ends.
+func (w *worker) injectChaos(j *db.DequeueSyncJobRow) func() {
+	if w.chaos == nil {
+		return func() {}
+	}
+
+	var roll = rand.Float64()
+	var kill = roll < w.chaos.KillProbability
+	if !kill && roll >= w.chaos.KillProbability+w.chaos.DisconnectProbability {
+		return func() {}
+	}
+
+	var delay = time.Duration(rand.Int63n(int64(w.chaos.MaxDelay)))
+	var timer = time.AfterFunc(delay, func() {
+		if kill {
+			w.loggerForJob(j).Warn().Msgf("chaos: killing the worker %s into job %d", delay, j.ID)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = p.Kill()
+			}
+			return
+		}
+
+		w.loggerForJob(j).Warn().Msgf("chaos: dropping the database connections of the worker %s into job %d", delay, j.ID)
+
+		// the worker's connections share its application_name, the one issuing the query survives
+		const terminate = `
+SELECT COUNT(pg_terminate_backend(pid)) FROM pg_stat_activity
+    WHERE application_name = current_setting('application_name') AND application_name <> '' AND pid <> pg_backend_pid()`
+
+		var terminated int
+		if err := w.pool.QueryRow(context.Background(), terminate).Scan(&terminated); err != nil {
+			w.loggerForJob(j).Err(err).Msg("chaos: could not drop database connections")
+			return
+		}
+		w.loggerForJob(j).Warn().Msgf("chaos: dropped %d database connection(s)", terminated)
+	})
+
+	return func() { timer.Stop() }
+}
@@ -0,0 +1,159 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/google/go-github/v50/github"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// checksum returns a value identifying the state of the source the job syncs from, without performing the sync.
+// For git based syncs this is the sha of the remote HEAD (or of all remote refs), and for GitHub API based syncs it's
+// derived from the most recently updated issue or pull request. If two successful syncs produce the same checksum,
+// the second has nothing new to sync. An empty checksum means change detection isn't supported for the sync type.
+// As the output of a sync depends on its settings too, a hash of them is part of the checksum.
+func (w *worker) checksum(ctx context.Context, j *db.DequeueSyncJobRow) (string, error) {
+	var sum, err = w.sourceChecksum(ctx, j)
+	if err != nil || sum == "" {
+		return sum, err
+	}
+
+	// the settings are hashed as decoded, so that their formatting (or the order of their keys) doesn't matter
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return "", err
+	}
+	var encoded []byte
+	if encoded, err = json.Marshal(settings); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:settings:%x", sum, sha256.Sum256(encoded)), nil
+}
+
+// sourceChecksum returns the checksum of the state of the source the job syncs from (see checksum)
+func (w *worker) sourceChecksum(ctx context.Context, j *db.DequeueSyncJobRow) (string, error) {
+	switch j.SyncType {
+	case syncTypeGitCommits, syncTypeGitCommitStats, syncTypeGitFiles, syncTypeGitBlame, syncTypeGitCommitPatches, syncTypeGitSymbols:
+		return w.remoteChecksum(ctx, j, false)
+	case syncTypeGitRefs:
+		return w.remoteChecksum(ctx, j, true)
+	case syncTypeGitHubRepoIssues:
+		return w.githubChecksum(ctx, j, false)
+	case syncTypeGitHubRepoPRs, syncTypeGitHubPRReviews, syncTypeGitHubPRCommits, syncTypeGitHubPRsAndCommits:
+		return w.githubChecksum(ctx, j, true)
+	default:
+		return "", nil
+	}
+}
+
+// remoteChecksum lists the refs advertised by the repository's remote (like git ls-remote) and returns
+// the sha HEAD points to or, if allRefs is set, a hash of every advertised ref.
+func (w *worker) remoteChecksum(ctx context.Context, j *db.DequeueSyncJobRow, allRefs bool) (_ string, err error) {
+	var repo db.Repo
+	if repo, err = w.db.GetRepoById(ctx, j.RepoID); err != nil {
+		return "", err
+	}
+
+	// an imported repo changes only if the file it's imported from is replaced
+	var kind helper.RepoImportKind
+	var source string
+	if kind, source, err = helper.RepoImportSource(repo.Repo, os.Getenv("REPO_IMPORT_DIR")); err != nil {
+		return "", err
+	} else if kind != "" {
+		return importChecksum(source)
+	}
+
+	var endpoint *transport.Endpoint
+	var auth transport.AuthMethod
+	if endpoint, auth, err = w.authForRepo(ctx, repo); err != nil {
+		return "", err
+	}
+
+	var remote = git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{endpoint.String()}})
+
+	var refs []*plumbing.Reference
+	if refs, err = remote.ListContext(ctx, &git.ListOptions{Auth: auth}); err != nil {
+		return "", err
+	}
+
+	if allRefs {
+		var lines = make([]string, 0, len(refs))
+		for _, ref := range refs {
+			lines = append(lines, ref.String())
+		}
+		sort.Strings(lines)
+		return fmt.Sprintf("refs:%x", sha256.Sum256([]byte(fmt.Sprint(lines)))), nil
+	}
+
+	var resolved = make(map[plumbing.ReferenceName]*plumbing.Reference, len(refs))
+	for _, ref := range refs {
+		resolved[ref.Name()] = ref
+	}
+
+	var head, found = resolved[plumbing.HEAD]
+	if found && head.Type() == plumbing.SymbolicReference {
+		head, found = resolved[head.Target()]
+	}
+
+	if !found {
+		return "", fmt.Errorf("remote did not advertise HEAD")
+	}
+
+	return "head:" + head.Hash().String(), nil
+}
+
+// githubChecksum fetches the most recently updated issue (or pull request, if pulls is set) of the repository
+// and returns a checksum built from its number and the time it was last updated.
+func (w *worker) githubChecksum(ctx context.Context, j *db.DequeueSyncJobRow, pulls bool) (_ string, err error) {
+	var token string
+	if token, err = w.githubToken(ctx, j); err != nil {
+		return "", err
+	}
+
+	var owner, name string
+	if owner, name, err = helper.GetRepoOwnerAndRepoName(j.Repo); err != nil {
+		return "", err
+	}
+
+	var client = github.NewClient(githubHTTPClient(ctx, token))
+
+	var number int
+	var updatedAt time.Time
+	if pulls {
+		var opts = &github.PullRequestListOptions{State: "all", Sort: "updated", Direction: "desc", ListOptions: github.ListOptions{PerPage: 1}}
+
+		var prs []*github.PullRequest
+		if prs, _, err = client.PullRequests.List(ctx, owner, name, opts); err != nil {
+			return "", err
+		}
+
+		if len(prs) > 0 {
+			number, updatedAt = prs[0].GetNumber(), prs[0].GetUpdatedAt().Time
+		}
+	} else {
+		var opts = &github.IssueListByRepoOptions{State: "all", Sort: "updated", Direction: "desc", ListOptions: github.ListOptions{PerPage: 1}}
+
+		var issues []*github.Issue
+		if issues, _, err = client.Issues.ListByRepo(ctx, owner, name, opts); err != nil {
+			return "", err
+		}
+
+		if len(issues) > 0 {
+			number, updatedAt = issues[0].GetNumber(), issues[0].GetUpdatedAt().Time
+		}
+	}
+
+	return fmt.Sprintf("updated:%d:%s", number, updatedAt.UTC().Format(time.RFC3339)), nil
+}
@@ -0,0 +1,27 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// defaultDurationRegressionThreshold is the percentage by which a workflow's p95 duration must exceed its baseline
+// to be a regression, unless set in the sync settings
+const defaultDurationRegressionThreshold = 25
+
+// handleCIDurationRegressions derives the weekly p50/p95 durations of the repo's workflows, and records (and notifies of)
+// the weeks a workflow's p95 duration regressed beyond the threshold compared to the preceding four weeks
+func (w *worker) handleCIDurationRegressions(ctx context.Context, j *db.DequeueSyncJobRow) error {
+	var settings, err = settingsForJob(j)
+	if err != nil {
+		return err
+	}
+
+	var threshold = settings.DurationRegressionThreshold
+	if threshold <= 0 {
+		threshold = defaultDurationRegressionThreshold
+	}
+
+	return w.handleDerived(ctx, j, "derive_ci_duration_regressions", "ci_workflow_durations", threshold)
+}
@@ -0,0 +1,12 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// handleCIFlakyJobs derives the flaky GitHub Actions jobs of the repo from the synced workflow runs and jobs
+func (w *worker) handleCIFlakyJobs(ctx context.Context, j *db.DequeueSyncJobRow) error {
+	return w.handleDerived(ctx, j, "derive_ci_flaky_jobs", "ci_flaky_jobs")
+}
@@ -0,0 +1,165 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+var (
+	// ciConfigsColumns are the columns of ci_configs a sync writes
+	ciConfigsColumns = []string{"repo_id", "path", "provider", "name", "triggers"}
+
+	// ciJobsColumns are the columns of ci_jobs a sync writes
+	ciJobsColumns = []string{"repo_id", "path", "job", "runs_on"}
+
+	// ciActionsColumns are the columns of ci_actions a sync writes
+	ciActionsColumns = []string{"repo_id", "path", "job", "position", "uses", "kind", "action", "ref", "pinned"}
+)
+
+// ciProvider returns the CI provider the file at p configures, or an empty string if it isn't a CI configuration
+func ciProvider(p string) string {
+	switch {
+	case strings.HasPrefix(p, ".github/workflows/") && (strings.HasSuffix(p, ".yml") || strings.HasSuffix(p, ".yaml")):
+		return "GITHUB_ACTIONS"
+	case p == ".gitlab-ci.yml":
+		return "GITLAB_CI"
+	case path.Base(p) == "Jenkinsfile":
+		return "JENKINS"
+	default:
+		return ""
+	}
+}
+
+// ciInventory is the result of parsing the CI configurations of a repo
+type ciInventory struct {
+	configs, jobs, actions [][]interface{}
+}
+
+// ciInventory parses the (already synced) CI configuration files of the repo. Files that fail to parse are
+// reported as warnings and skipped.
+func (w *worker) ciInventory(ctx context.Context, j *db.DequeueSyncJobRow) (_ *ciInventory, err error) {
+	const query = `
+SELECT path, COALESCE(contents, '') FROM git_files
+	WHERE repo_id = $1 AND (path LIKE '.github/workflows/%' OR path = '.gitlab-ci.yml' OR path ~ '(^|/)Jenkinsfile$')`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, query, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query files: %w", err)
+	}
+	defer rows.Close()
+
+	type file struct{ path, contents, provider string }
+
+	var files []file
+	for rows.Next() {
+		var f file
+		if err = rows.Scan(&f.path, &f.contents); err != nil {
+			return nil, err
+		}
+		if f.provider = ciProvider(f.path); f.provider != "" {
+			files = append(files, f)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var inventory ciInventory
+	for _, f := range files {
+		var config, err = &helper.CIConfig{}, error(nil)
+		switch f.provider {
+		case "GITHUB_ACTIONS":
+			config, err = helper.ParseGitHubWorkflow(f.contents)
+		case "GITLAB_CI":
+			config, err = helper.ParseGitLabCI(f.contents)
+		}
+
+		if err != nil {
+			w.warnForJob(ctx, j, fmt.Sprintf("could not parse %s: %v", f.path, err), logDetails{"path": f.path, "error": err.Error()})
+			continue
+		}
+
+		var triggers = config.Triggers
+		if triggers == nil {
+			triggers = []string{}
+		}
+		inventory.configs = append(inventory.configs, []interface{}{j.RepoID, f.path, f.provider, nullIfEmpty(config.Name), triggers})
+
+		for _, job := range config.Jobs {
+			var runsOn = job.RunsOn
+			if runsOn == nil {
+				runsOn = []string{}
+			}
+			inventory.jobs = append(inventory.jobs, []interface{}{j.RepoID, f.path, job.ID, runsOn})
+
+			for i, action := range job.Actions {
+				inventory.actions = append(inventory.actions, []interface{}{j.RepoID, f.path, job.ID, i,
+					action.Uses, action.Kind, action.Action, nullIfEmpty(action.Ref), action.Pinned})
+			}
+		}
+	}
+
+	return &inventory, nil
+}
+
+func (w *worker) handleCIInventory(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var inventory *ciInventory
+	if inventory, err = w.ciInventory(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("found %d CI configuration(s) with %d job(s)", len(inventory.configs), len(inventory.jobs))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	if err = w.replaceRows(ctx, tx, j, "ci_configs", ciConfigsColumns, inventory.configs); err != nil {
+		return err
+	}
+
+	if err = w.replaceRows(ctx, tx, j, "ci_jobs", ciJobsColumns, inventory.jobs); err != nil {
+		return err
+	}
+
+	if err = w.replaceRows(ctx, tx, j, "ci_actions", ciActionsColumns, inventory.actions); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
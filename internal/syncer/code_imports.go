@@ -0,0 +1,167 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// codeImportsColumns and codeModulesColumns are the columns of code_imports and code_modules a sync writes
+var (
+	codeImportsColumns = []string{"repo_id", "path", "line", "language", "module", "kind", "resolved_path", "internal"}
+	codeModulesColumns = []string{"repo_id", "path", "language", "name"}
+)
+
+// codeImports parses the imports of the (already synced) Go and JS/TS files of the repo, and the modules it declares
+func (w *worker) codeImports(ctx context.Context, j *db.DequeueSyncJobRow) (imports, modules [][]interface{}, err error) {
+	const query = `
+SELECT path, contents FROM git_files
+	WHERE repo_id = $1 AND contents IS NOT NULL
+	    AND path ~* '\.(go|[cm]?[jt]sx?)$|(^|/)(go\.mod|package\.json)$' AND path !~ '(^|/)(vendor|node_modules)/'`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, query, j.RepoID); err != nil {
+		return nil, nil, fmt.Errorf("query files: %w", err)
+	}
+	defer rows.Close()
+
+	type source struct{ path, language, contents string }
+	var sources []source
+	var goModules = make(map[string]string) // directory of the go.mod -> module path
+
+	for rows.Next() {
+		var p, contents string
+		if err = rows.Scan(&p, &contents); err != nil {
+			return nil, nil, err
+		}
+
+		switch path.Base(p) {
+		case "go.mod":
+			if name := helper.GoModulePath(contents); name != "" {
+				goModules[path.Dir(p)] = name
+				modules = append(modules, []interface{}{j.RepoID, p, "Go", name})
+			}
+		case "package.json":
+			if name := helper.PackageJSONName(contents); name != "" {
+				modules = append(modules, []interface{}{j.RepoID, p, "JavaScript", name})
+			}
+		default:
+			if language := helper.ImportLanguage(p); language != "" {
+				sources = append(sources, source{path: p, language: language, contents: contents})
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// imports are resolved once the modules of the repo are all known
+	for _, s := range sources {
+		type key struct {
+			line   int
+			module string
+		}
+		var seen = make(map[key]bool)
+		for _, imp := range helper.ParseImports(s.language, s.contents) {
+			if seen[key{imp.Line, imp.Module}] {
+				continue
+			}
+			seen[key{imp.Line, imp.Module}] = true
+
+			var resolved, internal = helper.ResolveImport(s.path, s.language, imp.Module, goModules)
+			imports = append(imports, []interface{}{j.RepoID, s.path, imp.Line, s.language, imp.Module, imp.Kind, nullIfEmpty(resolved), internal})
+		}
+	}
+
+	return imports, modules, nil
+}
+
+func (w *worker) handleCodeImports(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var imports, modules [][]interface{}
+	if imports, modules, err = w.codeImports(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("found %d import(s) and %d module(s)", len(imports), len(modules))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	for _, t := range []struct {
+		table   string
+		columns []string
+		rows    [][]interface{}
+	}{
+		{"code_imports", codeImportsColumns, imports},
+		{"code_modules", codeModulesColumns, modules},
+	} {
+		r, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE repo_id = $1;", t.table), j.RepoID)
+		if err != nil {
+			return fmt.Errorf("exec delete: %w", err)
+		}
+
+		if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+			Type:            SyncLogTypeInfo,
+			RepoSyncQueueID: j.ID,
+			Message:         fmt.Sprintf("removed %d row(s) from %s", r.RowsAffected(), t.table),
+			Details:         rowDetails("removed", t.table, r.RowsAffected()),
+		}}); err != nil {
+			return err
+		}
+
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{t.table}, t.columns, pgx.CopyFromRows(t.rows)); err != nil {
+			return fmt.Errorf("tx copy from: %w", err)
+		}
+
+		if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+			Type:            SyncLogTypeInfo,
+			RepoSyncQueueID: j.ID,
+			Message:         fmt.Sprintf("inserted %d row(s) into %s", len(t.rows), t.table),
+			Details:         rowDetails("inserted", t.table, int64(len(t.rows))),
+		}}); err != nil {
+			return err
+		}
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "code_imports", len(imports))
+
+	return nil
+}
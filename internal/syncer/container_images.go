@@ -0,0 +1,156 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// containerImagesColumns are the columns of container_images a sync writes
+var containerImagesColumns = []string{"repo_id", "path", "line", "source", "image", "registry", "repository", "tag", "digest", "stage"}
+
+// composeFilePattern matches the names of docker compose files, eg. docker-compose.yml or compose.prod.yaml
+var composeFilePattern = regexp.MustCompile(`^(docker-)?compose[^/]*\.ya?ml$`)
+
+// isDockerfile reports whether the file at p is a Dockerfile, eg. Dockerfile, api.Dockerfile or Dockerfile.dev
+func isDockerfile(p string) bool {
+	var name = strings.ToLower(path.Base(p))
+	return name == "dockerfile" || strings.HasSuffix(name, ".dockerfile") || strings.HasPrefix(name, "dockerfile.")
+}
+
+// nullIfEmpty returns nil for an empty string, so that it's stored as NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// containerImages parses the images referenced by the (already synced) Dockerfiles and compose files of the repo
+func (w *worker) containerImages(ctx context.Context, j *db.DequeueSyncJobRow) (_ [][]interface{}, err error) {
+	const query = `
+SELECT path, contents FROM git_files
+	WHERE repo_id = $1 AND contents IS NOT NULL AND path ~* '(^|/)([^/]*\.)?dockerfile(\.[^/]*)?$|(^|/)(docker-)?compose[^/]*\.ya?ml$'`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, query, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query files: %w", err)
+	}
+	defer rows.Close()
+
+	var inputs [][]interface{}
+	for rows.Next() {
+		var p, contents string
+		if err = rows.Scan(&p, &contents); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case isDockerfile(p):
+			for _, image := range helper.ParseDockerfile(contents) {
+				inputs = append(inputs, []interface{}{j.RepoID, p, image.Line, "DOCKERFILE", image.Image, image.Registry,
+					image.Repository, nullIfEmpty(image.Tag), nullIfEmpty(image.Digest), nullIfEmpty(image.Stage)})
+			}
+		case composeFilePattern.MatchString(strings.ToLower(path.Base(p))):
+			var images = helper.ParseComposeImages(contents)
+
+			var lines = make([]int, 0, len(images))
+			for line := range images {
+				lines = append(lines, line)
+			}
+			sort.Ints(lines)
+
+			for _, line := range lines {
+				var image = images[line]
+				inputs = append(inputs, []interface{}{j.RepoID, p, line, "COMPOSE", image.Image, image.Registry,
+					image.Repository, nullIfEmpty(image.Tag), nullIfEmpty(image.Digest), nil})
+			}
+		}
+	}
+
+	return inputs, rows.Err()
+}
+
+func (w *worker) handleContainerImages(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var inputs [][]interface{}
+	if inputs, err = w.containerImages(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("found %d container image reference(s)", len(inputs))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM container_images WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from container_images", r.RowsAffected()),
+		Details:         rowDetails("removed", "container_images", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"container_images"}, containerImagesColumns, pgx.CopyFromRows(inputs)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into container_images", len(inputs)),
+		Details:         rowDetails("inserted", "container_images", int64(len(inputs))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "container_images", len(inputs))
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/vendors/registry/client"
+)
+
+// maxDependencyLookups is the most dependencies whose registry a single sync looks up, the others are left out
+const maxDependencyLookups = 1000
+
+var (
+	// dependencyUpdatePRsColumns are the columns of dependency_update_prs a sync writes
+	dependencyUpdatePRsColumns = []string{"repo_id", "number", "bot", "dependency", "from_version", "to_version", "state", "created_at", "closed_at", "merged_at"}
+
+	// dependencyLagColumns are the columns of dependency_lag a sync writes
+	dependencyLagColumns = []string{"repo_id", "registry", "name", "version", "version_released_at", "latest_version", "latest_released_at", "versions_behind", "libyears"}
+)
+
+// syftRegistries maps the types of the artifacts of Syft scans to the registries they're published to
+var syftRegistries = map[string]string{"npm": "npm", "python": "pypi", "go-module": "go"}
+
+// dependencyUpdatePRs lists the (already synced) pull requests of the repo opened by dependency update bots
+func (w *worker) dependencyUpdatePRs(ctx context.Context, j *db.DequeueSyncJobRow) (_ [][]interface{}, err error) {
+	const query = `
+SELECT number, COALESCE(author_login, ''), COALESCE(head_ref_name, ''), COALESCE(title, ''), created_at, closed_at, merged_at
+FROM github_pull_requests WHERE repo_id = $1 AND number IS NOT NULL`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, query, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query pull requests: %w", err)
+	}
+	defer rows.Close()
+
+	var inputs [][]interface{}
+	for rows.Next() {
+		var number int32
+		var author, branch, title string
+		var createdAt, closedAt, mergedAt *time.Time
+		if err = rows.Scan(&number, &author, &branch, &title, &createdAt, &closedAt, &mergedAt); err != nil {
+			return nil, err
+		}
+
+		var update, ok = helper.ParseDependencyUpdate(author, branch, title)
+		if !ok {
+			continue
+		}
+
+		var state = "OPEN"
+		if mergedAt != nil {
+			state = "MERGED"
+		} else if closedAt != nil {
+			state = "CLOSED"
+		}
+
+		inputs = append(inputs, []interface{}{j.RepoID, number, update.Bot, nullIfEmpty(update.Dependency), nullIfEmpty(update.From),
+			nullIfEmpty(update.To), state, createdAt, closedAt, mergedAt})
+	}
+
+	return inputs, rows.Err()
+}
+
+// dependency is a dependency of the repo, as found by its Syft scan
+type dependency struct {
+	registry, name, version string
+}
+
+// dependencies lists the npm, PyPI and Go dependencies of the repo found by its (already synced) Syft scan
+func (w *worker) dependencies(ctx context.Context, j *db.DequeueSyncJobRow) (_ []dependency, err error) {
+	const query = `
+SELECT DISTINCT type, name, version FROM syft_repo_artifacts
+WHERE repo_id = $1 AND type IN ('npm', 'python', 'go-module') AND name IS NOT NULL AND COALESCE(version, '') <> ''`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, query, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var deps []dependency
+	for rows.Next() {
+		var typ, name, version string
+		if err = rows.Scan(&typ, &name, &version); err != nil {
+			return nil, err
+		}
+		deps = append(deps, dependency{registry: syftRegistries[typ], name: name, version: version})
+	}
+
+	return deps, rows.Err()
+}
+
+// dependencyLag compares the version of the dependency to the (non-prerelease) versions published in its registry
+func dependencyLag(j *db.DequeueSyncJobRow, dep dependency, pkg *client.Package) []interface{} {
+	var current, latest *client.Version
+	for _, v := range pkg.Versions {
+		if v.Version == dep.version {
+			current = v
+		}
+		if !helper.IsPrerelease(v.Version) && (latest == nil || v.ReleasedAt.After(latest.ReleasedAt)) {
+			latest = v
+		}
+	}
+
+	var row = []interface{}{j.RepoID, dep.registry, dep.name, dep.version, nil, nil, nil, nil, nil}
+	if latest != nil {
+		row[5], row[6] = latest.Version, nullIfZero(latest.ReleasedAt)
+	}
+	if current == nil || current.ReleasedAt.IsZero() || latest == nil {
+		return row
+	}
+	row[4] = current.ReleasedAt
+
+	var behind int
+	for _, v := range pkg.Versions {
+		if !helper.IsPrerelease(v.Version) && v.ReleasedAt.After(current.ReleasedAt) {
+			behind++
+		}
+	}
+	row[7] = behind
+
+	var libyears float64
+	if latest.ReleasedAt.After(current.ReleasedAt) {
+		libyears = latest.ReleasedAt.Sub(current.ReleasedAt).Hours() / (24 * 365.25)
+	}
+	row[8] = libyears
+
+	return row
+}
+
+func (w *worker) handleDependencyUpdateLag(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var prInputs [][]interface{}
+	if prInputs, err = w.dependencyUpdatePRs(ctx, j); err != nil {
+		return err
+	}
+
+	var deps []dependency
+	if deps, err = w.dependencies(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("found %d dependency update pull request(s) and %d dependencies", len(prInputs), len(deps))
+
+	if len(deps) > maxDependencyLookups {
+		w.warnForJob(ctx, j, fmt.Sprintf("the repo has %d dependencies, only the first %d are looked up", len(deps), maxDependencyLookups),
+			logDetails{"dependencies": len(deps), "limit": maxDependencyLookups})
+		deps = deps[:maxDependencyLookups]
+	}
+
+	var registry = client.New(&http.Client{Timeout: time.Minute})
+
+	// the same package may be depended on in more than one version (eg. by different lock files)
+	var packages = make(map[dependency]*client.Package)
+	var lagInputs [][]interface{}
+	for _, dep := range deps {
+		var key = dependency{registry: dep.registry, name: dep.name}
+		var pkg, seen = packages[key]
+		if !seen {
+			if pkg, err = registry.Lookup(ctx, dep.registry, dep.name); err != nil {
+				// private (or unpublished) packages aren't found, they're left out
+				if !errors.Is(err, client.ErrNotFound) {
+					w.warnForJob(ctx, j, fmt.Sprintf("could not fetch %s package %s: %v", dep.registry, dep.name, err),
+						logDetails{"registry": dep.registry, "package": dep.name, "error": err.Error()})
+				}
+				pkg = nil
+			}
+			packages[key] = pkg
+		}
+
+		if pkg != nil {
+			lagInputs = append(lagInputs, dependencyLag(j, dep, pkg))
+		}
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	if err = w.replaceRows(ctx, tx, j, "dependency_update_prs", dependencyUpdatePRsColumns, prInputs); err != nil {
+		return err
+	}
+
+	if err = w.replaceRows(ctx, tx, j, "dependency_lag", dependencyLagColumns, lagInputs); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,126 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// followUpSyncs are the syncs (derived from, or driven by, the data of others) enqueued for a repo once one of their sources completes
+var followUpSyncs = map[string][]string{
+	syncTypeGitCommits:          {syncTypeGitCommitPullRequests, syncTypeChangeFailures, syncTypeGitEmbeddings},
+	syncTypeGitRefs:             {syncTypeReleaseChangelogs, syncTypeRepoPolicies},
+	syncTypeGitFiles:            {syncTypeRepoPolicies, syncTypeContainerImages, syncTypeTerraformInventory, syncTypeCIInventory, syncTypeCodeImports, syncTypeTestRatios, syncTypeGitEmbeddings, syncTypeLLMSummaries},
+	syncTypeGitHubRepoIssues:    {syncTypeGitHubIssueResponseTimes},
+	syncTypeGitHubRepoPRs:       {syncTypeReleaseChangelogs, syncTypeGitCommitPullRequests, syncTypeGitHubIssueResponseTimes, syncTypeGitHubReviewLoad, syncTypeChangeFailures, syncTypeDependencyUpdateLag, syncTypeLLMSummaries},
+	syncTypeGitHubPRReviews:     {syncTypeGitHubIssueResponseTimes, syncTypeGitHubReviewLoad},
+	syncTypeGitHubPRCommits:     {syncTypeGitCommitPullRequests},
+	syncTypeCIInventory:         {syncTypeRepoPolicies},
+	syncTypeSyftRepoScan:        {syncTypeDependencyUpdateLag},
+	syncTypeGitHubActions:       {syncTypeGitHubActionsUsage, syncTypeGitHubActionsTestResults, syncTypeCIFlakyJobs, syncTypeCIDurationRegressions},
+	syncTypeGitHubPRsAndCommits: {syncTypeReleaseChangelogs, syncTypeGitCommitPullRequests, syncTypeGitHubIssueResponseTimes, syncTypeGitHubReviewLoad, syncTypeChangeFailures, syncTypeDependencyUpdateLag, syncTypeLLMSummaries},
+}
+
+// enqueueFollowUps enqueues the follow-up syncs of the job's sync type (if the repo has them enabled)
+func (w *worker) enqueueFollowUps(ctx context.Context, j *db.DequeueSyncJobRow) {
+	// jobs that weren't dequeued (eg. the ones the doctor runs) leave the queue alone
+	if j.RepoSyncID == uuid.Nil {
+		return
+	}
+
+	for _, syncType := range followUpSyncs[j.SyncType] {
+		if err := w.db.EnqueueRepoSyncOfType(ctx, db.EnqueueRepoSyncOfTypeParams{Repoid: j.RepoID, Synctype: syncType}); err != nil {
+			w.loggerForJob(j).Err(err).Msgf("could not enqueue follow-up %s sync", syncType)
+		}
+	}
+}
+
+// handleDerived executes a sync whose rows are derived, entirely in the database, by the given function from
+// previously synced tables. The function takes the id of the repo (followed by args, if any) and returns the number of
+// rows it wrote into table.
+func (w *worker) handleDerived(ctx context.Context, j *db.DequeueSyncJobRow, function, table string, args ...interface{}) (err error) {
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	var rows int
+	var params = []string{"$1"}
+	for i := range args {
+		params = append(params, fmt.Sprintf("$%d", i+2))
+	}
+
+	var query = "SELECT " + pgx.Identifier{"mergestat", function}.Sanitize() + "(" + strings.Join(params, ", ") + ")"
+	if err = tx.QueryRow(ctx, query, append([]interface{}{j.RepoID}, args...)...).Scan(&rows); err != nil {
+		return fmt.Errorf("%s: %w", function, err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into %s", rows, table),
+		Details:         rowDetails("inserted", table, int64(rows)),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// replaceRows deletes the rows of the job's repo from table and copies the given ones in their place
+func (w *worker) replaceRows(ctx context.Context, tx pgx.Tx, j *db.DequeueSyncJobRow, table string, columns []string, inputs [][]interface{}) error {
+	r, err := tx.Exec(ctx, "DELETE FROM "+pgx.Identifier{table}.Sanitize()+" WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from %s", r.RowsAffected(), table),
+		Details:         rowDetails("removed", table, r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(inputs)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	return w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into %s", len(inputs), table),
+		Details:         rowDetails("inserted", table, int64(len(inputs))),
+	}})
+}
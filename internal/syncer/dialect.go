@@ -0,0 +1,35 @@
+package syncer
+
+import (
+	"fmt"
+
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/dialect"
+)
+
+// WithDialect configures the worker for the given (Postgres-compatible) backend,
+// disabling the behaviors that rely on features it doesn't support
+func (w *worker) WithDialect(d *dialect.Dialect) *worker {
+	w.dialect = d
+	w.limiter.statActivity = d.StatActivity
+	return w
+}
+
+// checkDialect returns an error if the job's sync type relies on a feature the backend doesn't support
+func (w *worker) checkDialect(j *db.DequeueSyncJobRow) error {
+	var missing string
+	switch j.SyncType {
+	// the load tables of these syncs, and the functions deriving the rows of the others, are temporary tables
+	case syncTypeGitBlame, syncTypeGitFiles, syncTypeGitCommitPatches, syncTypeGitSymbols,
+		syncTypeReleaseChangelogs, syncTypeGitHubIssueResponseTimes, syncTypeCIDurationRegressions:
+		if !w.dialect.TemporaryTables {
+			missing = "temporary tables"
+		}
+	}
+
+	if missing != "" {
+		return fmt.Errorf("%s syncs require %s, which is not supported by %s", j.SyncType, missing, w.dialect.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// DoctorOptions configures a single, foreground sync execution (see Doctor)
+type DoctorOptions struct {
+	// Repo is the url of the repository, as it's stored in public.repos
+	Repo string
+
+	// SyncType is the type of sync to execute, eg. GIT_BLAME
+	SyncType string
+
+	// Keep, if set, leaves the scratch schema in place after the sync completes
+	// so that the synced rows can be inspected
+	Keep bool
+}
+
+// Doctor runs a single sync end-to-end in the foreground, to help debug credential, clone and parse issues.
+// The sync is never enqueued: rows are written into a throwaway scratch schema (that shadows public through
+// the connection's search_path) and sync logs are sent to the given logger instead of mergestat.repo_sync_logs.
+func Doctor(ctx context.Context, pool *pgxpool.Pool, mergestat *sqlx.DB, logger *zerolog.Logger, opts DoctorOptions) (err error) {
+	var repo db.Repo
+	if repo, err = db.New(pool).GetRepoByURL(ctx, opts.Repo); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.Errorf("repository %s not found, make sure it's added to mergestat first", opts.Repo)
+		}
+		return errors.Wrapf(err, "failed to fetch repository")
+	}
+
+	var scratch = fmt.Sprintf("mergestat_doctor_%d", time.Now().Unix())
+	if err = cloneTables(ctx, pool, scratch); err != nil {
+		return errors.Wrapf(err, "failed to create scratch schema")
+	}
+	logger.Info().Msgf("created scratch schema %s", scratch)
+
+	defer func() {
+		if opts.Keep {
+			logger.Info().Msgf("keeping scratch schema %s, drop it with: DROP SCHEMA %s CASCADE", scratch, scratch)
+			return
+		}
+
+		if _, dropErr := pool.Exec(context.Background(), "DROP SCHEMA "+pgx.Identifier{scratch}.Sanitize()+" CASCADE"); dropErr != nil {
+			logger.Err(dropErr).Msgf("failed to drop scratch schema %s", scratch)
+		}
+	}()
+
+	var scratchPool *pgxpool.Pool
+	if scratchPool, err = connectWithSchema(ctx, pool, scratch, 5); err != nil {
+		return errors.Wrapf(err, "failed to connect to database")
+	}
+	defer scratchPool.Close()
+
+	var w = New(scratchPool, mergestat, logger, 1, 0)
+	w.localLogs = true
+
+	var job = &db.DequeueSyncJobRow{
+		CreatedAt:    time.Now(),
+		Status:       "RUNNING",
+		RepoID:       repo.ID,
+		SyncType:     opts.SyncType,
+		Settings:     pgtype.JSONB{Status: pgtype.Null},
+		Repo:         repo.Repo,
+		Ref:          repo.Ref,
+		RepoSettings: repo.Settings,
+	}
+
+	var start = time.Now()
+	if err = w.handle(ctx, job); err != nil {
+		logger.Err(err).Msgf("sync %s failed after %s", opts.SyncType, time.Since(start))
+		return err
+	}
+	logger.Info().Msgf("sync %s finished successfully in %s", opts.SyncType, time.Since(start))
+
+	var counts []tableRowCount
+	if counts, err = countRows(ctx, scratchPool, scratch, repo.ID); err != nil {
+		return errors.Wrapf(err, "failed to count synced rows")
+	}
+	logRowCounts(logger, scratch, counts)
+
+	return nil
+}
@@ -0,0 +1,98 @@
+//go:build integration
+
+package syncer
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/testharness"
+	"github.com/rs/zerolog"
+)
+
+// fixtureCommits are the commits of the repository the syncs are run against: at HEAD, README.md has 2 lines
+// and main.go has 3
+var fixtureCommits = []testharness.Commit{
+	{Message: "initial commit", Files: map[string]*string{
+		"README.md": testharness.Content("hello\nworld\n"),
+		"main.go":   testharness.Content("package main\n"),
+	}},
+	{Message: "feat: add main", AuthorName: "Jane Doe", AuthorEmail: "jane@example.com", Files: map[string]*string{
+		"main.go": testharness.Content("package main\n\nfunc main() {}\n"),
+	}},
+	{Message: "chore: remove notes", Files: map[string]*string{
+		"NOTES.md": testharness.Content("todo\n"),
+	}},
+	{Message: "fix: remove notes", Files: map[string]*string{
+		"NOTES.md": nil,
+	}},
+}
+
+// runSync enqueues, dequeues and handles a sync of the given type for the repo, the way the worker's exec loop does,
+// and returns the error the sync failed with
+func runSync(t *testing.T, w *worker, repo uuid.UUID, syncType string) error {
+	t.Helper()
+	var ctx = context.Background()
+
+	if _, err := w.pool.Exec(ctx, "INSERT INTO mergestat.repo_syncs (repo_id, sync_type, schedule_enabled) VALUES ($1, $2, TRUE) ON CONFLICT DO NOTHING", repo, syncType); err != nil {
+		t.Fatalf("could not add %s sync: %v", syncType, err)
+	}
+	if err := w.db.EnqueueRepoSyncOfType(ctx, db.EnqueueRepoSyncOfTypeParams{Repoid: repo, Synctype: syncType}); err != nil {
+		t.Fatalf("could not enqueue %s sync: %v", syncType, err)
+	}
+
+	var j, err = w.db.DequeueSyncJob(ctx, math.MaxInt32)
+	if err != nil {
+		t.Fatalf("could not dequeue %s sync: %v", syncType, err)
+	}
+
+	return w.handle(ctx, &j)
+}
+
+func TestSyncsEndToEnd(t *testing.T) {
+	var database = testharness.Postgres(t)
+	var repo = database.AddRepo(t, testharness.Repo(t, fixtureCommits...))
+
+	var logger = zerolog.New(zerolog.NewTestWriter(t))
+	var w = New(database.Pool, nil, &logger, 1, time.Second)
+
+	var tests = []struct {
+		syncType string
+		table    string
+		rows     int64
+	}{
+		{syncTypeGitCommits, "git_commits", 4},
+		{syncTypeGitCommitStats, "git_commit_stats", 5},
+		{syncTypeGitBlame, "git_blame", 5},
+		{syncTypeGitCommitConventions, "git_commit_conventions", 3}, // the initial commit isn't conventional
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.syncType, func(t *testing.T) {
+			// syncs replace the repo's rows, so running one again must leave the same rows
+			for run := 1; run <= 2; run++ {
+				if err := runSync(t, w, repo, tt.syncType); err != nil {
+					t.Fatalf("run %d: sync failed: %v", run, err)
+				}
+
+				if rows := database.Count(t, tt.table, repo); rows != tt.rows {
+					t.Fatalf("run %d: expected %d row(s) in %s, found %d", run, tt.rows, tt.table, rows)
+				}
+			}
+		})
+	}
+
+	t.Run("sync logs", func(t *testing.T) {
+		var errors int64
+		if err := database.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM mergestat.repo_sync_logs WHERE log_type = 'ERROR'").Scan(&errors); err != nil {
+			t.Fatal(err)
+		}
+		if errors > 0 {
+			t.Fatalf("expected no error logs, found %d", errors)
+		}
+	})
+}
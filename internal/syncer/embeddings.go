@@ -0,0 +1,186 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmbeddingOptions configures the model GIT_EMBEDDINGS syncs compute embeddings with (see WithEmbeddings)
+type EmbeddingOptions struct {
+	// Provider is the api the model is served with: openai (the OpenAI api, or any server compatible with its
+	// embeddings endpoint, eg. vLLM or LocalAI) or ollama (a local Ollama server)
+	Provider string
+
+	// URL is the base url of the api, defaults to https://api.openai.com/v1 (openai) or http://localhost:11434 (ollama)
+	URL string
+
+	// Model is the name of the model, eg. text-embedding-3-small or nomic-embed-text
+	Model string
+
+	// APIKey, if set, authenticates the calls (as a bearer token)
+	APIKey string
+
+	// BatchSize is the number of texts embedded per call
+	BatchSize int
+
+	// MaxInputBytes is the size texts are cut to before they're embedded, to fit the input limit of the model
+	MaxInputBytes int
+}
+
+// errEmbeddingsNotConfigured is returned by GIT_EMBEDDINGS syncs run by a worker without an embedding model
+var errEmbeddingsNotConfigured = errors.New("in order to run this syncer, an embedding model must be configured (see the EMBEDDINGS_PROVIDER and EMBEDDINGS_MODEL env vars)")
+
+// modelAPIRetries is the number of times a call to a model rate limited (or failing on the side of the provider) is retried
+const modelAPIRetries = 3
+
+// WithEmbeddings sets the model GIT_EMBEDDINGS syncs compute embeddings with. Options left zero take defaults.
+func (w *worker) WithEmbeddings(opts EmbeddingOptions) *worker {
+	if opts.Provider == "" {
+		opts.Provider = "openai"
+	}
+	if opts.URL == "" {
+		switch opts.Provider {
+		case "openai":
+			opts.URL = "https://api.openai.com/v1"
+		case "ollama":
+			opts.URL = "http://localhost:11434"
+		}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 64
+	}
+	if opts.MaxInputBytes <= 0 {
+		opts.MaxInputBytes = 16 << 10
+	}
+
+	w.embeddings = &opts
+	return w
+}
+
+// embeddingClient calls the api of the configured embedding model
+type embeddingClient struct {
+	opts   *EmbeddingOptions
+	client *http.Client
+}
+
+func newEmbeddingClient(opts *EmbeddingOptions) (*embeddingClient, error) {
+	if opts == nil || opts.Model == "" {
+		return nil, errEmbeddingsNotConfigured
+	}
+	if opts.Provider != "openai" && opts.Provider != "ollama" {
+		return nil, fmt.Errorf("unknown embeddings provider %q, expected openai or ollama", opts.Provider)
+	}
+	return &embeddingClient{opts: opts, client: &http.Client{Timeout: 2 * time.Minute}}, nil
+}
+
+// embed returns the embeddings of the inputs, in order, computed BatchSize inputs per call
+func (c *embeddingClient) embed(ctx context.Context, inputs []string) (_ [][]float32, err error) {
+	var embeddings = make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += c.opts.BatchSize {
+		var end = start + c.opts.BatchSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+
+		var batch [][]float32
+		if batch, err = c.embedBatch(ctx, inputs[start:end]); err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("embeddings api returned %d embedding(s) for %d input(s)", len(batch), end-start)
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	return embeddings, nil
+}
+
+func (c *embeddingClient) embedBatch(ctx context.Context, inputs []string) ([][]float32, error) {
+	var endpoint = strings.TrimSuffix(c.opts.URL, "/") + "/embeddings"
+	if c.opts.Provider == "ollama" {
+		endpoint = strings.TrimSuffix(c.opts.URL, "/") + "/api/embed"
+	}
+
+	var body, err = json.Marshal(map[string]interface{}{"model": c.opts.Model, "input": inputs})
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		// openai
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+
+		// ollama
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err = postModelAPI(ctx, c.client, endpoint, c.opts.APIKey, body, &response); err != nil {
+		return nil, err
+	}
+
+	if c.opts.Provider == "ollama" {
+		return response.Embeddings, nil
+	}
+
+	sort.Slice(response.Data, func(i, j int) bool { return response.Data[i].Index < response.Data[j].Index })
+	var embeddings = make([][]float32, len(response.Data))
+	for i, d := range response.Data {
+		embeddings[i] = d.Embedding
+	}
+	return embeddings, nil
+}
+
+// postModelAPI decodes the response of the call to the api of a model (eg. of embeddings, see embeddingClient),
+// retrying (after the Retry-After of the response, if any) calls that are rate limited or fail on the side of the provider
+func postModelAPI(ctx context.Context, client *http.Client, endpoint, apiKey string, body []byte, result interface{}) error {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(result)
+			_ = resp.Body.Close()
+			return err
+		}
+
+		var message, _ = io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		_ = resp.Body.Close()
+
+		var retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		if !retryable || attempt >= modelAPIRetries {
+			return fmt.Errorf("%s: %s: %s", endpoint, resp.Status, strings.TrimSpace(string(message)))
+		}
+
+		var delay = time.Duration(attempt+1) * 5 * time.Second
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/namespace"
+	"github.com/rs/zerolog"
+)
+
+// WithColumnEncryption sets the cipher the columns listed in mergestat.encrypted_columns are encrypted with, as rows
+// are synced (COPY'd, or written by statements, see processWritten), and decrypted with, as syncs read them back
+// (see decrypter). Without it, syncs writing into (or reading) an encrypted column fail rather than write plain values.
+func (w *worker) WithColumnEncryption(c *helper.ColumnCipher) *worker {
+	w.cipher = c
+	return w
+}
+
+// encryptedColumns returns the columns to encrypt (see mergestat.encrypted_columns), by table
+func encryptedColumns(ctx context.Context, pool *pgxpool.Pool) (helper.EncryptedColumns, error) {
+	return helper.CollectEncryptedColumns(pool.Query(ctx, helper.ListEncryptedColumns))
+}
+
+// decrypter decrypts the values syncs read from the (already synced) encrypted columns
+type decrypter struct {
+	encrypted helper.EncryptedColumns
+	cipher    *helper.ColumnCipher
+}
+
+// decrypter returns the decrypter of the columns currently encrypted
+func (w *worker) decrypter(ctx context.Context) (_ *decrypter, err error) {
+	var d = &decrypter{cipher: w.cipher}
+	if d.encrypted, err = encryptedColumns(ctx, w.pool); err != nil {
+		return nil, fmt.Errorf("list encrypted columns: %w", err)
+	}
+	return d, nil
+}
+
+// decrypt decrypts, in place, a value read from the column of the table
+func (d *decrypter) decrypt(table, column string, value *string) (err error) {
+	if *value, err = d.encrypted.Decrypt(d.cipher, table, column, *value); err != nil {
+		return fmt.Errorf("decrypt %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// encryptValue returns the (text) value encrypted, or as-is if it's null
+func encryptValue(c *helper.ColumnCipher, value interface{}) (interface{}, error) {
+	var s, ok = textValue(value)
+	if !ok {
+		return value, nil
+	}
+	return c.Encrypt(s)
+}
+
+// EncryptSynced encrypts the values of the columns listed in mergestat.encrypted_columns that were synced before
+// the column was configured to be encrypted, batchSize rows at a time, and returns the number of rows updated.
+// Values with the prefix of encrypted values are only left as-is if they authenticate with the key.
+func EncryptSynced(ctx context.Context, pool *pgxpool.Pool, c *helper.ColumnCipher, logger *zerolog.Logger, batchSize int) (total int64, err error) {
+	var columns map[string]map[string]bool
+	if columns, err = encryptedColumns(ctx, pool); err != nil {
+		return 0, err
+	}
+
+	// rows are addressed by ctid, which the views of relocated tables don't have
+	var tables namespace.Registry
+	if tables, err = namespace.Load(ctx, pool); err != nil {
+		return 0, err
+	}
+
+	for table, names := range columns {
+		for column := range names {
+			var identifier = tables.Identifier(pgx.Identifier{"public", table}).Sanitize()
+			var col = pgx.Identifier{column}.Sanitize()
+			var query = fmt.Sprintf("SELECT ctid::TEXT, %s FROM %s WHERE %s IS NOT NULL AND %s NOT LIKE 'enc:v1:%%' LIMIT $1", col, identifier, col, col)
+			var update = fmt.Sprintf("UPDATE %s SET %s = $2 WHERE ctid = $1::TID", identifier, col)
+
+			var updated int64
+			if updated, err = encryptPrefixed(ctx, pool, c, identifier, col, update, batchSize); err != nil {
+				return total, fmt.Errorf("%s.%s: %w", table, column, err)
+			}
+
+			for {
+				var batch [][2]string
+				if batch, err = collectPairs(pool.Query(ctx, query, batchSize)); err != nil {
+					return total, fmt.Errorf("%s.%s: %w", table, column, err)
+				}
+
+				var b = &pgx.Batch{}
+				for _, row := range batch {
+					var encrypted string
+					if encrypted, err = c.Encrypt(row[1]); err != nil {
+						return total, err
+					}
+					b.Queue(update, row[0], encrypted)
+				}
+				if len(batch) > 0 {
+					if err = pool.SendBatch(ctx, b).Close(); err != nil {
+						return total, fmt.Errorf("%s.%s: %w", table, column, err)
+					}
+				}
+
+				updated += int64(len(batch))
+				if len(batch) < batchSize {
+					break
+				}
+				logger.Info().Msgf("encrypted %d row(s) of %s.%s", updated, table, column)
+			}
+
+			logger.Info().Str("table", table).Int64("rows", updated).Msgf("encrypted %d row(s) of %s.%s", updated, table, column)
+			total += updated
+		}
+	}
+
+	return total, nil
+}
+
+// encryptPrefixed encrypts the (plain) values of the column that have the prefix of encrypted values, but don't
+// authenticate with the key, and returns the number of rows updated
+func encryptPrefixed(ctx context.Context, pool *pgxpool.Pool, c *helper.ColumnCipher, identifier, col, update string, batchSize int) (updated int64, err error) {
+	var rows pgx.Rows
+	if rows, err = pool.Query(ctx, fmt.Sprintf("SELECT ctid::TEXT, %s FROM %s WHERE %s LIKE 'enc:v1:%%'", col, identifier, col)); err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var b = &pgx.Batch{}
+	var send = func() error {
+		if b.Len() == 0 {
+			return nil
+		}
+		if err := pool.SendBatch(ctx, b).Close(); err != nil {
+			return err
+		}
+		updated, b = updated+int64(b.Len()), &pgx.Batch{}
+		return nil
+	}
+
+	for rows.Next() {
+		var ctid, value string
+		if err = rows.Scan(&ctid, &value); err != nil {
+			return updated, err
+		}
+		if c.IsEncrypted(value) {
+			continue
+		}
+
+		var encrypted string
+		if encrypted, err = c.Encrypt(value); err != nil {
+			return updated, err
+		}
+		b.Queue(update, ctid, encrypted)
+		if b.Len() >= batchSize {
+			if err = send(); err != nil {
+				return updated, err
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return updated, err
+	}
+	return updated, send()
+}
+
+// collectPairs scans the rows of two text columns
+func collectPairs(rows pgx.Rows, err error) (_ [][2]string, _ error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs [][2]string
+	for rows.Next() {
+		var pair [2]string
+		if err = rows.Scan(&pair[0], &pair[1]); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, rows.Err()
+}
+
+// errNoEncryptionKey is returned by syncs writing into (or reading) an encrypted column while the worker has no key
+var errNoEncryptionKey = helper.ErrNoEncryptionKey
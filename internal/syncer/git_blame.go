@@ -2,6 +2,7 @@ package syncer
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -24,7 +25,7 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-func (w *worker) sendBatchBlameLines(ctx context.Context, blameTmpPath string, tx pgx.Tx, j *db.DequeueSyncJobRow) (int, error) {
+func (w *worker) sendBatchBlameLines(ctx context.Context, blameTmpPath string, tx copier, table pgx.Identifier, j *db.DequeueSyncJobRow) (int, error) {
 	var (
 		f   *os.File
 		err error
@@ -39,14 +40,14 @@ func (w *worker) sendBatchBlameLines(ctx context.Context, blameTmpPath string, t
 	var (
 		// Create a new JSON decoder for the file
 		decoder       = json.NewDecoder(f)
-		inputs        = make([][]interface{}, 0, 100)
+		inputs        = make([][]interface{}, 0, syncStrategy(ctx).BatchSize)
 		insertedLines = 0
 		isEOF         = false
 	)
 
 	// Using a double loop to walk through json file  until the len of inputs
 	// is equal to the capacity or is OEF, either will break inner loop and the outer
-	// loop copies batches of 100 or less items untils EOF is reached
+	// loop copies batches (sized by the size of the repo) untils EOF is reached
 	for {
 
 		for {
@@ -87,7 +88,7 @@ func (w *worker) sendBatchBlameLines(ctx context.Context, blameTmpPath string, t
 			}
 		}
 
-		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"git_blame"}, []string{"repo_id", "author_email", "author_name", "author_when", "commit_hash", "line_no", "line", "path"}, pgx.CopyFromRows(inputs)); err != nil {
+		if _, err := tx.CopyFrom(ctx, table, gitBlameColumns, pgx.CopyFromRows(inputs)); err != nil {
 			return 0, fmt.Errorf("tx copy from: %w", err)
 		}
 
@@ -105,6 +106,9 @@ func (w *worker) sendBatchBlameLines(ctx context.Context, blameTmpPath string, t
 	return insertedLines, nil
 }
 
+// gitBlameColumns are the columns of git_blame a sync writes
+var gitBlameColumns = []string{"repo_id", "author_email", "author_name", "author_when", "commit_hash", "line_no", "line", "path"}
+
 type blameLine struct {
 	AuthorEmail *string
 	AuthorName  *string
@@ -136,10 +140,34 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 		}
 	}()
 
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	// a blame at a point in time needs the tags (blameRef may be one), and is taken on a checkout of it
+	var snapshot = settings.BlameRef != "" || settings.BlameAsOf != ""
+	if snapshot {
+		ctx = withTags(ctx)
+	}
+
+	// a snapshot only holds lines, which blameAggregatesOnly doesn't retain
+	if snapshot && settings.BlameAggregatesOnly {
+		return errors.New("blameRef and blameAsOf can't be used with blameAggregatesOnly")
+	}
+
 	if err = w.clone(ctx, tmpPath, j); err != nil {
 		return fmt.Errorf("git clone: %w", err)
 	}
 
+	var at *blameSnapshot
+	if snapshot {
+		if at, err = checkoutBlameSnapshot(tmpPath, settings); err != nil {
+			return fmt.Errorf("blame snapshot: %w", err)
+		}
+		l.Info().Msgf("blaming %s at %s (%s)", j.Repo, at.Ref, at.Revision)
+	}
+
 	iter, err := lstree.Exec(ctx, tmpPath, "HEAD", lstree.WithRecurse(true))
 	if err != nil {
 		return fmt.Errorf("git ls-tree error: %w", err)
@@ -168,10 +196,26 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 
 	encoder := json.NewEncoder(file)
 
+	// expectedLines is the total number of lines in all files that were blamed successfully,
+	// used to detect blame output that was silently truncated
+	var expectedLines int
+
+	// ownership aggregates the blamed lines into the git_file_ownership rollup as files are blamed
+	var ownership fileOwnership
+
+	// lineAges aggregates the blamed lines into the git_file_line_ages histograms as files are blamed
+	var lineAges fileLineAges
+
+	// with blamePaths set, only the matching files are blamed (and the blame of the others removed)
+	var skipped int
 	for _, o := range objects {
 		if o.Type != "blob" {
 			continue
 		}
+		if len(settings.BlamePaths) > 0 && !helper.MatchAnyGlob(settings.BlamePaths, o.Path) {
+			skipped++
+			continue
+		}
 
 		// skip running git blame on binary files
 		// first detect if a file is binary or not
@@ -182,6 +226,8 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 			// indicate that we're detecting unexpected behavior
 			if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeWarn, RepoSyncQueueID: j.ID,
 				Message: fmt.Sprintf(LogFormatErrorWarningMessage, "error opening file in repo", err),
+				Details: logDetails{"path": o.Path, "error": err.Error()},
+				Kind:    "error opening file in repo",
 			}}); err != nil {
 				return fmt.Errorf("send batch log messages: %w", err)
 			}
@@ -199,6 +245,8 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 				// indicate that we're detecting unexpected behavior
 				if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeWarn, RepoSyncQueueID: j.ID,
 					Message: fmt.Sprintf(LogFormatErrorWarningMessage, "error reading file in repo", err),
+					Details: logDetails{"path": o.Path, "error": err.Error()},
+					Kind:    "error reading file in repo",
 				}}); err != nil {
 					return fmt.Errorf("send batch log messages: %w", err)
 				}
@@ -228,6 +276,8 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 			// indicate that we're detecting unexpected behavior
 			if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeWarn, RepoSyncQueueID: j.ID,
 				Message: fmt.Sprintf(LogFormatErrorWarningMessage, "error blaming file in repo", err),
+				Details: logDetails{"path": o.Path, "error": err.Error()},
+				Kind:    "error blaming file in repo",
 			}}); err != nil {
 				return fmt.Errorf("send batch log messages: %w", err)
 			}
@@ -235,6 +285,20 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 			continue
 		}
 
+		ownership.add(j.RepoID, o.Path, res)
+		lineAges.add(j.RepoID, o.Path, res)
+
+		// with blameAggregatesOnly set, the lines (and their content) are never written, not even to the temporary file
+		if settings.BlameAggregatesOnly {
+			continue
+		}
+
+		if lines, err := countLines(fullPath); err != nil {
+			w.logger.Warn().AnErr("error", err).Str("repo", j.Repo).Msgf("error counting lines of file: %s, %v", fullPath, err)
+		} else {
+			expectedLines += lines
+		}
+
 		for lineIdx, blame := range res {
 			lineNo := lineIdx + 1
 			blameline := &blameLine{
@@ -254,8 +318,33 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 		}
 	}
 
+	if skipped > 0 {
+		if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+			Message: fmt.Sprintf("skipped %d file(s) not matching blamePaths %s", skipped, strings.Join(settings.BlamePaths, ", ")),
+		}}); err != nil {
+			return fmt.Errorf("send batch log messages: %w", err)
+		}
+	}
+
+	var load *loadTable
+	if load, err = w.newLoadTable(ctx, j, "git_blame"); err != nil {
+		return err
+	}
+	defer func() {
+		if err := load.close(context.Background()); err != nil {
+			w.logger.Err(err).Msgf("could not drop load table")
+		}
+	}()
+
+	// rows are copied into the (unlogged) load table outside of the transaction, and only moved
+	// into git_blame within it, to keep the transaction (and the window without any rows) short
+	var blamedLines int
+	if blamedLines, err = w.sendBatchBlameLines(ctx, file.Name(), load.Copier(), load.Identifier(), j); err != nil {
+		return fmt.Errorf("send batch blamed lines: %w", err)
+	}
+
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTxOn(ctx, load.Conn(), j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -266,6 +355,34 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 		}
 	}()
 
+	// the blame at a point in time replaces its snapshot, and leaves git_blame (and git_file_ownership) as they are
+	if at != nil {
+		var removed, inserted int64
+		if removed, inserted, err = w.replaceBlameSnapshot(ctx, tx, load, j.RepoID.String(), at); err != nil {
+			return err
+		}
+
+		if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+			Type:            SyncLogTypeInfo,
+			RepoSyncQueueID: j.ID,
+			Message:         fmt.Sprintf("replaced %d row(s) with %d row(s) in git_blame_snapshots at %s (%s)", removed, inserted, at.Ref, at.Revision),
+			Details:         rowDetails("inserted", "git_blame_snapshots", inserted),
+		}}); err != nil {
+			return err
+		}
+
+		if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+			return fmt.Errorf("update status done: %w", err)
+		}
+		if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+			Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+		}}); err != nil {
+			return fmt.Errorf("send batch log messages: %w", err)
+		}
+		return tx.Commit(ctx)
+	}
+
+	// with blameAggregatesOnly set, nothing was loaded: the lines of the repo are only removed
 	r, err := tx.Exec(ctx, "DELETE FROM git_blame WHERE repo_id = $1;", j.RepoID.String())
 	if err != nil {
 		return fmt.Errorf("exec delete: %w", err)
@@ -275,12 +392,13 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from git_blame", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_blame", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
-	var blamedLines int
-	if blamedLines, err = w.sendBatchBlameLines(ctx, file.Name(), tx, j); err != nil {
-		return fmt.Errorf("send batch blamed lines: %w", err)
+
+	if _, err = load.swap(ctx, tx, gitBlameColumns); err != nil {
+		return err
 	}
 
 	l.Info().Msgf("sent batch of %d blamed lines", blamedLines)
@@ -289,10 +407,19 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into git_blame", blamedLines),
+		Details:         rowDetails("inserted", "git_blame", int64(blamedLines)),
 	}}); err != nil {
 		return err
 	}
 
+	if err := w.sendBatchGitFileOwnership(ctx, tx, j, &ownership); err != nil {
+		return fmt.Errorf("send batch file ownership: %w", err)
+	}
+
+	if err := w.sendBatchGitFileLineAges(ctx, tx, j, &lineAges); err != nil {
+		return fmt.Errorf("send batch file line ages: %w", err)
+	}
+
 	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
 		return fmt.Errorf("update status done: %w", err)
 	}
@@ -304,7 +431,48 @@ func (w *worker) handleGitBlame(ctx context.Context, j *db.DequeueSyncJobRow) er
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	err = tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "git_blame", blamedLines)
+	if blamedLines != expectedLines {
+		w.warnForJob(ctx, j, fmt.Sprintf("git blame returned %d line(s) but the blamed files contain %d line(s)", blamedLines, expectedLines),
+			logDetails{"table": "git_blame", "expected_rows": expectedLines, "rows": blamedLines})
+	}
+
+	return nil
+}
+
+// countLines returns the number of lines in the file at path, the way git counts them
+// (ie. a last line without a trailing newline still counts)
+func countLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var lines, size int
+	var last byte
+	var buf = make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			size, last = size+n, buf[n-1]
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	if size > 0 && last != '\n' {
+		lines++
+	}
 
-	return err
+	return lines, nil
 }
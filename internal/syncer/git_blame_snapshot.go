@@ -0,0 +1,101 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// blameSnapshot is the point in time a GIT_BLAME sync blames a repo at (see the blameRef and blameAsOf settings)
+type blameSnapshot struct {
+	Ref      string
+	AsOf     *time.Time
+	Revision string
+}
+
+// parseAsOf parses the blameAsOf setting, a date (2006-01-02, midnight UTC) or a timestamp (RFC 3339)
+func parseAsOf(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// checkoutBlameSnapshot resolves the point in time of the settings in the clone at path, and checks it out
+func checkoutBlameSnapshot(path string, settings *syncSettings) (_ *blameSnapshot, err error) {
+	var snapshot = &blameSnapshot{Ref: settings.BlameRef}
+	if snapshot.Ref == "" {
+		snapshot.Ref = "HEAD"
+	}
+	if settings.BlameAsOf != "" {
+		var asOf time.Time
+		if asOf, err = parseAsOf(settings.BlameAsOf); err != nil {
+			return nil, fmt.Errorf("invalid blameAsOf %q: %w", settings.BlameAsOf, err)
+		}
+		snapshot.AsOf = &asOf
+	}
+
+	var repo *git.Repository
+	if repo, err = git.PlainOpen(path); err != nil {
+		return nil, err
+	}
+
+	var hash *plumbing.Hash
+	if hash, err = repo.ResolveRevision(plumbing.Revision(snapshot.Ref)); err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", snapshot.Ref, err)
+	}
+
+	// the revision as of a date is the last commit of the ref before it
+	if snapshot.AsOf != nil {
+		var iter, err = repo.Log(&git.LogOptions{From: *hash, Until: snapshot.AsOf})
+		if err != nil {
+			return nil, err
+		}
+		defer iter.Close()
+
+		var c, nextErr = iter.Next()
+		if errors.Is(nextErr, io.EOF) {
+			return nil, fmt.Errorf("no commit of %s before %s", snapshot.Ref, snapshot.AsOf.Format(time.RFC3339))
+		} else if nextErr != nil {
+			return nil, nextErr
+		}
+		hash = &c.Hash
+	}
+
+	var wt *git.Worktree
+	if wt, err = repo.Worktree(); err != nil {
+		return nil, err
+	}
+	if err = wt.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+		return nil, fmt.Errorf("checkout %s: %w", hash, err)
+	}
+
+	snapshot.Revision = hash.String()
+	return snapshot, nil
+}
+
+// replaceBlameSnapshot replaces the rows of the snapshot of the job's repo in git_blame_snapshots with the loaded rows
+func (w *worker) replaceBlameSnapshot(ctx context.Context, tx pgx.Tx, load *loadTable, repoID string, snapshot *blameSnapshot) (removed, inserted int64, err error) {
+	const remove = `DELETE FROM git_blame_snapshots WHERE repo_id = $1 AND ref = $2 AND as_of IS NOT DISTINCT FROM $3`
+
+	var r pgconn.CommandTag
+	if r, err = tx.Exec(ctx, remove, repoID, snapshot.Ref, snapshot.AsOf); err != nil {
+		return 0, 0, fmt.Errorf("exec delete: %w", err)
+	}
+	removed = r.RowsAffected()
+
+	var insert = `INSERT INTO git_blame_snapshots (ref, as_of, revision, repo_id, author_email, author_name, author_when, commit_hash, line_no, line, path)
+SELECT $1, $2, $3, repo_id, author_email, author_name, author_when, commit_hash, line_no, line, path FROM ` + load.Identifier().Sanitize()
+	if r, err = tx.Exec(ctx, insert, snapshot.Ref, snapshot.AsOf, snapshot.Revision); err != nil {
+		return 0, 0, fmt.Errorf("insert snapshot: %w", err)
+	}
+
+	return removed, r.RowsAffected(), nil
+}
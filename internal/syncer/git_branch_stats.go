@@ -0,0 +1,196 @@
+package syncer
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// gitBranchStatsColumns are the columns of git_branch_stats a refs sync writes
+var gitBranchStatsColumns = []string{"repo_id", "name", "hash", "is_default", "first_commit_at", "last_commit_at",
+	"last_commit_author_email", "ahead", "behind", "merged"}
+
+// paint flags of the commits walked by aheadBehind: reachable from the default branch, from the branch, or both
+const (
+	paintBase = 1 << iota
+	paintBranch
+
+	paintBoth = paintBase | paintBranch
+)
+
+// commitQueue is a queue of commits, the latest (by committer date) first
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int            { return len(q) }
+func (q commitQueue) Less(i, j int) bool  { return q[i].Committer.When.After(q[j].Committer.When) }
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() interface{} {
+	var old = *q
+	var c = old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// aheadBehind counts the commits of the branch that aren't on the default branch (ahead), and those of the default
+// branch that aren't on the branch (behind), and returns the earliest authored of the former. Like git's merge-base,
+// the histories are walked from both tips at once, latest commits first, down to the commits they have in common:
+// the walk is bounded by how far they diverged, rather than by the whole history.
+func aheadBehind(base, branch *object.Commit) (ahead, behind int, first *time.Time, err error) {
+	var flags = map[plumbing.Hash]int{base.Hash: paintBase}
+	flags[branch.Hash] |= paintBranch
+
+	// the walk stops once every queued commit is on both histories (as are all of their ancestors): uncommon counts
+	// the queued commits that aren't
+	var queue = &commitQueue{}
+	var queued = make(map[plumbing.Hash]bool)
+	var uncommon int
+	var push = func(c *object.Commit) {
+		heap.Push(queue, c)
+		queued[c.Hash] = true
+		if flags[c.Hash] != paintBoth {
+			uncommon++
+		}
+	}
+
+	push(base)
+	if branch.Hash != base.Hash {
+		push(branch)
+	}
+
+	for uncommon > 0 {
+		var c = heap.Pop(queue).(*object.Commit)
+		var f = flags[c.Hash]
+		delete(queued, c.Hash)
+
+		switch f {
+		case paintBase:
+			behind++
+			uncommon--
+		case paintBranch:
+			ahead++
+			uncommon--
+			if when := c.Author.When; first == nil || when.Before(*first) {
+				first = &when
+			}
+		}
+
+		if err = c.Parents().ForEach(func(parent *object.Commit) error {
+			var previous, seen = flags[parent.Hash]
+			if previous|f == previous {
+				return nil
+			}
+			flags[parent.Hash] = previous | f
+			switch {
+			case !seen:
+				push(parent)
+			case queued[parent.Hash] && previous|f == paintBoth:
+				uncommon--
+			}
+			return nil
+		}); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	return ahead, behind, first, nil
+}
+
+// branchStats computes, for every remote branch of the repository cloned at path, its age, last activity
+// and how far it diverged from the default branch (the branch HEAD points to). Branches whose stats can't be
+// computed are skipped, and reported in the returned warnings.
+func branchStats(ctx context.Context, path string, j *db.DequeueSyncJobRow) (_ [][]interface{}, warnings []string, err error) {
+	var repo *git.Repository
+	if repo, err = git.PlainOpen(path); err != nil {
+		return nil, nil, err
+	}
+
+	var head *plumbing.Reference
+	if head, err = repo.Head(); err != nil {
+		return nil, nil, fmt.Errorf("resolve HEAD: %w", err)
+	}
+
+	var base *object.Commit
+	if base, err = repo.CommitObject(head.Hash()); err != nil {
+		return nil, nil, fmt.Errorf("read HEAD commit: %w", err)
+	}
+
+	var refs storer.ReferenceIter
+	if refs, err = repo.References(); err != nil {
+		return nil, nil, err
+	}
+	defer refs.Close()
+
+	var inputs [][]interface{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if !ref.Name().IsRemote() || ref.Type() != plumbing.HashReference {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// strip the remote from the name, eg. origin/main => main
+		var name = ref.Name().Short()
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+
+		tip, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not read tip of branch %s: %v", name, err))
+			return nil
+		}
+
+		ahead, behind, first, err := aheadBehind(base, tip)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not compare branch %s with the default branch: %v", name, err))
+			return nil
+		}
+
+		inputs = append(inputs, []interface{}{j.RepoID, name, ref.Hash().String(), ref.Hash() == head.Hash(), first,
+			tip.Committer.When, tip.Author.Email, ahead, behind, ahead == 0})
+
+		return nil
+	})
+
+	return inputs, warnings, err
+}
+
+// sendBatchGitBranchStats replaces the branch stats of the repo with the given ones
+func (w *worker) sendBatchGitBranchStats(ctx context.Context, tx pgx.Tx, j *db.DequeueSyncJobRow, inputs [][]interface{}) error {
+	r, err := tx.Exec(ctx, "DELETE FROM git_branch_stats WHERE repo_id = $1;", j.RepoID.String())
+	if err != nil {
+		return err
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from git_branch_stats", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_branch_stats", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"git_branch_stats"}, gitBranchStatsColumns, pgx.CopyFromRows(inputs)); err != nil {
+		return err
+	}
+
+	return w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into git_branch_stats", len(inputs)),
+		Details:         rowDetails("inserted", "git_branch_stats", int64(len(inputs))),
+	}})
+}
@@ -0,0 +1,204 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+const (
+	// defaultBusFactorCoverage is the percentage of contributions the authors must cover, unless set in the sync settings
+	defaultBusFactorCoverage = 50
+
+	// defaultBusFactorDepth is the number of directory levels metrics are computed for, unless set in the sync settings
+	defaultBusFactorDepth = 2
+)
+
+// gitBusFactorColumns are the columns of git_bus_factor a sync writes
+var gitBusFactorColumns = []string{"repo_id", "computed_at", "directory", "metric", "coverage", "bus_factor", "authors", "total_authors", "total"}
+
+// directories returns the directories (up to depth levels deep) the file at path contributes to, including the root ("")
+func directories(path string, depth int) []string {
+	var dirs = []string{""}
+
+	var parts = strings.Split(path, "/")
+	for i := 1; i < len(parts) && i <= depth; i++ {
+		dirs = append(dirs, strings.Join(parts[:i], "/"))
+	}
+
+	return dirs
+}
+
+// contributions is the number of contributions of each author to each directory
+type contributions map[string]map[string]int64
+
+func (c contributions) add(dir, author string, n int64) {
+	if c[dir] == nil {
+		c[dir] = make(map[string]int64)
+	}
+	c[dir][author] += n
+}
+
+// lineContributions counts the lines attributed to each author, from the git_file_ownership rollup
+func (w *worker) lineContributions(ctx context.Context, j *db.DequeueSyncJobRow, depth int) (_ contributions, err error) {
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, "SELECT path, author_email, lines FROM git_file_ownership WHERE repo_id = $1", j.RepoID); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result = make(contributions)
+	for rows.Next() {
+		var path, author string
+		var lines int64
+		if err = rows.Scan(&path, &author, &lines); err != nil {
+			return nil, err
+		}
+
+		for _, dir := range directories(path, depth) {
+			result.add(dir, author, lines)
+		}
+	}
+
+	return result, rows.Err()
+}
+
+// commitContributions counts the commits of each author touching each directory, from git_commit_stats
+func (w *worker) commitContributions(ctx context.Context, j *db.DequeueSyncJobRow, depth int) (_ contributions, err error) {
+	const query = `
+SELECT s.commit_hash, c.author_email, s.file_path FROM git_commit_stats s
+	INNER JOIN git_commits c ON c.repo_id = s.repo_id AND c.hash = s.commit_hash
+WHERE s.repo_id = $1`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, query, j.RepoID); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// a commit touching many files of a directory only counts once for it
+	var seen = make(map[[2]string]struct{})
+	var result = make(contributions)
+	for rows.Next() {
+		var hash, author, path string
+		if err = rows.Scan(&hash, &author, &path); err != nil {
+			return nil, err
+		}
+
+		for _, dir := range directories(path, depth) {
+			if _, ok := seen[[2]string{hash, dir}]; ok {
+				continue
+			}
+			seen[[2]string{hash, dir}] = struct{}{}
+			result.add(dir, author, 1)
+		}
+	}
+
+	return result, rows.Err()
+}
+
+func (w *worker) handleGitBusFactor(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	var coverage, depth = settings.BusFactorCoverage, settings.BusFactorDepth
+	if coverage <= 0 || coverage > 100 {
+		coverage = defaultBusFactorCoverage
+	}
+	if depth <= 0 {
+		depth = defaultBusFactorDepth
+	}
+
+	var metrics = make(map[string]contributions)
+	if metrics["lines"], err = w.lineContributions(ctx, j, depth); err != nil {
+		return fmt.Errorf("line contributions: %w", err)
+	}
+	if metrics["commits"], err = w.commitContributions(ctx, j, depth); err != nil {
+		return fmt.Errorf("commit contributions: %w", err)
+	}
+
+	var computedAt = time.Now()
+	var inputs [][]interface{}
+	for _, metric := range []string{"lines", "commits"} {
+		var dirs = make([]string, 0, len(metrics[metric]))
+		for dir := range metrics[metric] {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+
+		for _, dir := range dirs {
+			var authors = metrics[metric][dir]
+
+			var total int64
+			for _, n := range authors {
+				total += n
+			}
+
+			var covering = helper.BusFactor(authors, coverage)
+			if covering == nil {
+				covering = []string{}
+			}
+
+			inputs = append(inputs, []interface{}{j.RepoID, computedAt, dir, metric, coverage, len(covering), covering, len(authors), total})
+		}
+	}
+
+	l.Info().Msgf("computed bus factor of %d directories", len(inputs))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	// previous rows are kept, so that the metrics can be trended over time
+	if _, err = tx.CopyFrom(ctx, pgx.Identifier{"git_bus_factor"}, gitBusFactorColumns, pgx.CopyFromRows(inputs)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into git_bus_factor", len(inputs)),
+		Details:         rowDetails("inserted", "git_bus_factor", int64(len(inputs))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
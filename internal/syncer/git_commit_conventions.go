@@ -0,0 +1,126 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// gitCommitConventionsColumns are the columns of git_commit_conventions a sync writes
+var gitCommitConventionsColumns = []string{"repo_id", "commit_hash", "type", "scope", "breaking", "description", "ticket_refs"}
+
+// parseCommitConventions parses the messages of the (already synced) commits of the repo
+func (w *worker) parseCommitConventions(ctx context.Context, j *db.DequeueSyncJobRow) (_ [][]interface{}, err error) {
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, "SELECT hash, COALESCE(message, '') FROM git_commits WHERE repo_id = $1", j.RepoID); err != nil {
+		return nil, fmt.Errorf("query commits: %w", err)
+	}
+	defer rows.Close()
+
+	var inputs [][]interface{}
+	for rows.Next() {
+		var hash, message string
+		if err = rows.Scan(&hash, &message); err != nil {
+			return nil, err
+		}
+
+		commit, ok := helper.ParseConventionalCommit(message)
+		if !ok {
+			continue
+		}
+
+		var scope interface{}
+		if commit.Scope != "" {
+			scope = commit.Scope
+		}
+
+		var refs = commit.TicketRefs
+		if refs == nil {
+			refs = []string{}
+		}
+
+		inputs = append(inputs, []interface{}{j.RepoID, hash, commit.Type, scope, commit.Breaking, commit.Description, refs})
+	}
+
+	return inputs, rows.Err()
+}
+
+func (w *worker) handleGitCommitConventions(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var inputs [][]interface{}
+	if inputs, err = w.parseCommitConventions(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("parsed %d conventional commit(s)", len(inputs))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM git_commit_conventions WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from git_commit_conventions", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_commit_conventions", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"git_commit_conventions"}, gitCommitConventionsColumns, pgx.CopyFromRows(inputs)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into git_commit_conventions", len(inputs)),
+		Details:         rowDetails("inserted", "git_commit_conventions", int64(len(inputs))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "git_commit_conventions", len(inputs))
+
+	return nil
+}
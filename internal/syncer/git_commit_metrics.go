@@ -0,0 +1,148 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// maxSubjectLength is the longest a commit subject line can be and still conform
+const maxSubjectLength = 72
+
+// gitCommitMetricsColumns are the columns of git_commit_metrics a sync writes
+var gitCommitMetricsColumns = []string{"repo_id", "commit_hash", "message_length", "subject_length", "subject_conforms",
+	"has_body", "files_changed", "additions", "deletions", "size_bucket"}
+
+// sizeBucket classifies a commit by the number of lines it changes
+func sizeBucket(changed int) string {
+	switch {
+	case changed < 10:
+		return "XS"
+	case changed < 50:
+		return "S"
+	case changed < 250:
+		return "M"
+	case changed < 1000:
+		return "L"
+	default:
+		return "XL"
+	}
+}
+
+// commitMetrics computes the metrics of the (already synced) commits of the repo
+func (w *worker) commitMetrics(ctx context.Context, j *db.DequeueSyncJobRow) (_ [][]interface{}, err error) {
+	const query = `
+SELECT c.hash, COALESCE(c.message, ''), COUNT(s.file_path), COALESCE(SUM(s.additions), 0), COALESCE(SUM(s.deletions), 0)
+	FROM git_commits c LEFT JOIN git_commit_stats s ON s.repo_id = c.repo_id AND s.commit_hash = c.hash
+WHERE c.repo_id = $1
+GROUP BY c.hash, c.message`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, query, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query commits: %w", err)
+	}
+	defer rows.Close()
+
+	var inputs [][]interface{}
+	for rows.Next() {
+		var hash, message string
+		var files, additions, deletions int
+		if err = rows.Scan(&hash, &message, &files, &additions, &deletions); err != nil {
+			return nil, err
+		}
+
+		message = strings.TrimSpace(message)
+		var subject, body, _ = strings.Cut(message, "\n")
+		subject = strings.TrimSpace(subject)
+
+		var subjectLength = utf8.RuneCountInString(subject)
+		var conforms = subjectLength > 0 && subjectLength <= maxSubjectLength && !strings.HasSuffix(subject, ".")
+		var hasBody = strings.HasPrefix(body, "\n") && strings.TrimSpace(body) != ""
+
+		inputs = append(inputs, []interface{}{j.RepoID, hash, utf8.RuneCountInString(message), subjectLength, conforms,
+			hasBody, files, additions, deletions, sizeBucket(additions + deletions)})
+	}
+
+	return inputs, rows.Err()
+}
+
+func (w *worker) handleGitCommitMetrics(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var inputs [][]interface{}
+	if inputs, err = w.commitMetrics(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("computed metrics of %d commit(s)", len(inputs))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM git_commit_metrics WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from git_commit_metrics", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_commit_metrics", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"git_commit_metrics"}, gitCommitMetricsColumns, pgx.CopyFromRows(inputs)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into git_commit_metrics", len(inputs)),
+		Details:         rowDetails("inserted", "git_commit_metrics", int64(len(inputs))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "git_commit_metrics", len(inputs))
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v4"
+	libgit2 "github.com/libgit2/git2go/v33"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+const (
+	// defaultPatchMaxFileBytes and defaultPatchMaxCommitBytes cap the patches stored, unless set in the sync settings
+	defaultPatchMaxFileBytes   = 64 << 10
+	defaultPatchMaxCommitBytes = 1 << 20
+
+	// patchMaxFileBytesLimit is the largest patch of a file stored, whatever the settings, as patches are full-text indexed
+	patchMaxFileBytesLimit = 1 << 20
+
+	// patchBatchSize is the number of file patches copied into the load table at once
+	patchBatchSize = 500
+)
+
+// gitCommitPatchesColumns are the columns of git_commit_patches a sync writes
+var gitCommitPatchesColumns = []string{"repo_id", "commit_hash", "file_path", "old_file_path", "patch", "size", "truncated", "is_binary"}
+
+// commitPatches returns the patches of the files changed by the commit (against its first parent)
+func commitPatches(repo *libgit2.Repository, c *libgit2.Commit, maxFileBytes, maxCommitBytes int) (_ []*helper.FilePatch, err error) {
+	var toTree, fromTree *libgit2.Tree
+	if toTree, err = c.Tree(); err != nil {
+		return nil, err
+	}
+	defer toTree.Free()
+
+	// root commits are diffed against the empty tree (a nil old tree)
+	if parent := c.Parent(0); parent != nil {
+		defer parent.Free()
+		if fromTree, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+		defer fromTree.Free()
+	}
+
+	var diffOpts libgit2.DiffOptions
+	if diffOpts, err = libgit2.DefaultDiffOptions(); err != nil {
+		return nil, err
+	}
+
+	var diff *libgit2.Diff
+	if diff, err = repo.DiffTreeToTree(fromTree, toTree, &diffOpts); err != nil {
+		return nil, err
+	}
+	defer diff.Free() //nolint:errcheck
+
+	var findOpts libgit2.DiffFindOptions
+	if findOpts, err = libgit2.DefaultDiffFindOptions(); err != nil {
+		return nil, err
+	}
+	if err = diff.FindSimilar(&findOpts); err != nil {
+		return nil, err
+	}
+
+	var patches = helper.NewPatchBuilder(maxFileBytes, maxCommitBytes)
+	err = diff.ForEach(func(delta libgit2.DiffDelta, _ float64) (libgit2.DiffForEachHunkCallback, error) {
+		patches.File(delta.OldFile.Path, delta.NewFile.Path, delta.Flags&libgit2.DiffFlagBinary != 0)
+
+		return func(hunk libgit2.DiffHunk) (libgit2.DiffForEachLineCallback, error) {
+			patches.Write(hunk.Header)
+
+			return func(line libgit2.DiffLine) error {
+				switch line.Origin {
+				case libgit2.DiffLineContext:
+					patches.Write(" " + line.Content)
+				case libgit2.DiffLineAddition:
+					patches.Write("+" + line.Content)
+				case libgit2.DiffLineDeletion:
+					patches.Write("-" + line.Content)
+				default: // eg. the "no newline at end of file" marker
+					patches.Write(line.Content)
+				}
+				return nil
+			}, nil
+		}, nil
+	}, libgit2.DiffDetailLines)
+	if err != nil {
+		return nil, err
+	}
+
+	return patches.Files(), nil
+}
+
+func (w *worker) handleGitCommitPatches(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+	var maxFileBytes, maxCommitBytes = defaultPatchMaxFileBytes, defaultPatchMaxCommitBytes
+	if settings.PatchMaxFileBytes > 0 && settings.PatchMaxFileBytes <= patchMaxFileBytesLimit {
+		maxFileBytes = settings.PatchMaxFileBytes
+	} else if settings.PatchMaxFileBytes > patchMaxFileBytesLimit {
+		maxFileBytes = patchMaxFileBytesLimit
+	}
+	if settings.PatchMaxCommitBytes > 0 {
+		maxCommitBytes = settings.PatchMaxCommitBytes
+	}
+
+	tmpPath, cleanup, err := helper.CreateTempDir(os.Getenv("GIT_CLONE_PATH"), fmt.Sprintf("mergestat-repo-%s-*", j.RepoID.String()))
+	if err != nil {
+		return fmt.Errorf("temp dir: %w", err)
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			l.Err(err).Msgf("error cleaning up repo at: %s, %v", tmpPath, err)
+		}
+	}()
+
+	if err = w.clone(ctx, tmpPath, j); err != nil {
+		return fmt.Errorf("git clone: %w", err)
+	}
+
+	var load *loadTable
+	if load, err = w.newLoadTable(ctx, j, "git_commit_patches"); err != nil {
+		return err
+	}
+	defer func() {
+		if err := load.close(context.Background()); err != nil {
+			w.logger.Err(err).Msgf("could not drop load table")
+		}
+	}()
+
+	var repo *libgit2.Repository
+	if repo, err = libgit2.OpenRepository(tmpPath); err != nil {
+		return err
+	}
+	defer repo.Free()
+
+	var walk *libgit2.RevWalk
+	if walk, err = repo.Walk(); err != nil {
+		return err
+	}
+	defer walk.Free()
+
+	if err = walk.PushHead(); err != nil {
+		return err
+	}
+
+	// patches are copied into the load table in batches as commits are walked, so that they're never all in memory
+	var inputs = make([][]interface{}, 0, patchBatchSize)
+	var loaded, truncated int
+	var flush = func() error {
+		if _, err := load.Copier().CopyFrom(ctx, load.Identifier(), gitCommitPatchesColumns, pgx.CopyFromRows(inputs)); err != nil {
+			return fmt.Errorf("copy patches: %w", err)
+		}
+		loaded, inputs = loaded+len(inputs), inputs[:0]
+		return nil
+	}
+
+	var walkErr error
+	if err = walk.Iterate(func(c *libgit2.Commit) bool {
+		defer c.Free()
+
+		var files []*helper.FilePatch
+		if files, walkErr = commitPatches(repo, c, maxFileBytes, maxCommitBytes); walkErr != nil {
+			walkErr = fmt.Errorf("diff commit %s: %w", c.Id(), walkErr)
+			return false
+		}
+
+		for _, f := range files {
+			if f.Truncated {
+				truncated++
+			}
+			inputs = append(inputs, []interface{}{j.RepoID, c.Id().String(), f.Path, f.OldPath, f.Patch, f.Size, f.Truncated, f.Binary})
+			if len(inputs) == cap(inputs) {
+				if walkErr = flush(); walkErr != nil {
+					return false
+				}
+			}
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+	if walkErr != nil {
+		return walkErr
+	}
+	if err = flush(); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("loaded %d patch(es), %d truncated", loaded, truncated)
+
+	var tx pgx.Tx
+	if tx, err = w.beginTxOn(ctx, load.Conn(), j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM git_commit_patches WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from git_commit_patches", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_commit_patches", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err = load.swap(ctx, tx, gitCommitPatchesColumns); err != nil {
+		return err
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into git_commit_patches (%d truncated)", loaded, truncated),
+		Details:         rowDetails("inserted", "git_commit_patches", int64(loaded)),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "git_commit_patches", loaded)
+
+	return nil
+}
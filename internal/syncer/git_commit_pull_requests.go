@@ -0,0 +1,12 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// handleGitCommitPullRequests derives which commits of the repo belong to which pull request, from its commits and pull requests
+func (w *worker) handleGitCommitPullRequests(ctx context.Context, j *db.DequeueSyncJobRow) error {
+	return w.handleDerived(ctx, j, "derive_commit_pull_requests", "git_commit_pull_requests")
+}
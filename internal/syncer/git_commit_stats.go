@@ -194,7 +194,7 @@ func (w *worker) handleGitCommitStats(ctx context.Context, j *db.DequeueSyncJobR
 	}
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return err
 	}
 	defer func() {
@@ -214,6 +214,7 @@ func (w *worker) handleGitCommitStats(ctx context.Context, j *db.DequeueSyncJobR
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from git_commit_stats", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_commit_stats", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -228,6 +229,7 @@ func (w *worker) handleGitCommitStats(ctx context.Context, j *db.DequeueSyncJobR
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into git_commit_stats", len(stats)),
+		Details:         rowDetails("inserted", "git_commit_stats", int64(len(stats))),
 	}}); err != nil {
 		return err
 	}
@@ -243,7 +245,11 @@ func (w *worker) handleGitCommitStats(ctx context.Context, j *db.DequeueSyncJobR
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	err = tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
 
-	return err
+	w.reconcileRowCount(ctx, j, "git_commit_stats", len(stats))
+
+	return nil
 }
@@ -194,7 +194,7 @@ func (w *worker) handleGitCommits(ctx context.Context, j *db.DequeueSyncJobRow)
 	}
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return err
 	}
 	defer func() {
@@ -214,6 +214,7 @@ func (w *worker) handleGitCommits(ctx context.Context, j *db.DequeueSyncJobRow)
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from git_commits", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_commits", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -228,6 +229,7 @@ func (w *worker) handleGitCommits(ctx context.Context, j *db.DequeueSyncJobRow)
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into git_commits", insertedCommits),
+		Details:         rowDetails("inserted", "git_commits", int64(insertedCommits)),
 	}}); err != nil {
 		return err
 	}
@@ -243,7 +245,11 @@ func (w *worker) handleGitCommits(ctx context.Context, j *db.DequeueSyncJobRow)
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	err = tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "git_commits", insertedCommits)
 
-	return err
+	return nil
 }
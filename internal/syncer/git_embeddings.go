@@ -0,0 +1,241 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+const (
+	// embeddingKindCommitMessage and embeddingKindFile are the kinds of texts GIT_EMBEDDINGS syncs embed
+	embeddingKindCommitMessage = "commit_message"
+	embeddingKindFile          = "file"
+
+	// defaultEmbeddingMaxItems is the most texts a GIT_EMBEDDINGS sync embeds, the others are embedded by the next syncs
+	defaultEmbeddingMaxItems = 5000
+)
+
+// upsertEmbedding records the embedding of a text; the cast lets the column be a pgvector vector or an array
+const upsertEmbedding = `
+INSERT INTO git_embeddings (repo_id, kind, key, model, content_hash, dimensions, embedding)
+VALUES ($1, $2, $3, $4, $5, $6, $7::REAL[])
+ON CONFLICT (repo_id, kind, key, model) DO UPDATE
+    SET content_hash = excluded.content_hash, dimensions = excluded.dimensions, embedding = excluded.embedding,
+        embedded_at = now(), _mergestat_synced_at = now()
+`
+
+// embeddingText is a text of the repo to embed, identified by its kind and key (a commit hash or a file path)
+type embeddingText struct {
+	kind, key, text, hash string
+}
+
+// embeddingTexts returns the texts of the repo to embed: the messages of its (already synced) commits, and the
+// contents of its (already synced) files matching the paths
+func (w *worker) embeddingTexts(ctx context.Context, j *db.DequeueSyncJobRow, paths []string, maxBytes int) (_ []*embeddingText, err error) {
+	var texts []*embeddingText
+	var add = func(kind, key, text string) {
+		if text = helper.EmbeddingText(text, maxBytes); text != "" {
+			var sum = sha256.Sum256([]byte(text))
+			texts = append(texts, &embeddingText{kind: kind, key: key, text: text, hash: hex.EncodeToString(sum[:])})
+		}
+	}
+
+	var d *decrypter
+	if d, err = w.decrypter(ctx); err != nil {
+		return nil, err
+	}
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, "SELECT hash, COALESCE(message, '') FROM git_commits WHERE repo_id = $1 ORDER BY committer_when DESC", j.RepoID); err != nil {
+		return nil, fmt.Errorf("query commits: %w", err)
+	}
+	for rows.Next() {
+		var hash, message string
+		if err = rows.Scan(&hash, &message); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if err = d.decrypt("git_commits", "message", &message); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		add(embeddingKindCommitMessage, hash, message)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if rows, err = w.pool.Query(ctx, "SELECT path, contents FROM git_files WHERE repo_id = $1 AND contents IS NOT NULL ORDER BY path", j.RepoID); err != nil {
+		return nil, fmt.Errorf("query files: %w", err)
+	}
+	for rows.Next() {
+		var path, contents string
+		if err = rows.Scan(&path, &contents); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if helper.MatchAnyGlob(paths, path) {
+			if err = d.decrypt("git_files", "contents", &contents); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			add(embeddingKindFile, path, contents)
+		}
+	}
+	rows.Close()
+
+	return texts, rows.Err()
+}
+
+func (w *worker) handleGitEmbeddings(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var client *embeddingClient
+	if client, err = newEmbeddingClient(w.embeddings); err != nil {
+		return err
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	var paths, maxItems = settings.EmbeddingPaths, settings.EmbeddingMaxItems
+	if len(paths) == 0 {
+		paths = helper.DefaultEmbeddingPaths
+	}
+	if maxItems <= 0 {
+		maxItems = defaultEmbeddingMaxItems
+	}
+
+	var texts []*embeddingText
+	if texts, err = w.embeddingTexts(ctx, j, paths, w.embeddings.MaxInputBytes); err != nil {
+		return err
+	}
+
+	// existing are the hashes of the texts already embedded with the model, by kind and key
+	var existing = make(map[[2]string]string)
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, "SELECT kind, key, content_hash FROM git_embeddings WHERE repo_id = $1 AND model = $2", j.RepoID, w.embeddings.Model); err != nil {
+		return fmt.Errorf("query embeddings: %w", err)
+	}
+	for rows.Next() {
+		var kind, key, hash string
+		if err = rows.Scan(&kind, &key, &hash); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[[2]string{kind, key}] = hash
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// only the texts that changed since they were embedded are embedded again (calls to the model aren't free)
+	var pending []*embeddingText
+	var removedKinds, removedKeys []string
+	var current = make(map[[2]string]bool, len(texts))
+	for _, t := range texts {
+		current[[2]string{t.kind, t.key}] = true
+		if existing[[2]string{t.kind, t.key}] != t.hash {
+			pending = append(pending, t)
+		}
+	}
+	for k := range existing {
+		if !current[k] {
+			removedKinds, removedKeys = append(removedKinds, k[0]), append(removedKeys, k[1])
+		}
+	}
+
+	if len(pending) > maxItems {
+		w.warnForJob(ctx, j, fmt.Sprintf("%d text(s) to embed, only the first %d are embedded by this sync (see embeddingMaxItems)", len(pending), maxItems),
+			logDetails{"pending": len(pending), "maxItems": maxItems})
+		pending = pending[:maxItems]
+	}
+
+	var inputs = make([]string, len(pending))
+	for i, t := range pending {
+		inputs[i] = t.text
+	}
+
+	var embeddings [][]float32
+	if embeddings, err = client.embed(ctx, inputs); err != nil {
+		return fmt.Errorf("embed: %w", err)
+	}
+
+	l.Info().Msgf("embedded %d text(s) with %s", len(embeddings), w.embeddings.Model)
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	// embeddings of commits (or files) no longer in the repo are removed
+	const remove = `
+DELETE FROM git_embeddings WHERE repo_id = $1 AND model = $2 AND (kind, key) IN (SELECT * FROM unnest($3::TEXT[], $4::TEXT[]))`
+	r, err := tx.Exec(ctx, remove, j.RepoID, w.embeddings.Model, removedKinds, removedKeys)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from git_embeddings", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_embeddings", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	var batch = &pgx.Batch{}
+	for i, t := range pending {
+		batch.Queue(upsertEmbedding, j.RepoID, t.kind, t.key, w.embeddings.Model, t.hash, len(embeddings[i]), embeddings[i])
+	}
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("upsert embeddings: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("upserted %d row(s) into git_embeddings", len(pending)),
+		Details:         rowDetails("upserted", "git_embeddings", int64(len(pending))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,68 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/gitutils/blame"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// gitFileLineAgesColumns are the columns of git_file_line_ages a blame sync writes
+var gitFileLineAgesColumns = []string{"repo_id", "path", "lines", "older_than_1y", "older_than_2y", "older_than_3y", "median_age_days", "oldest_line_at", "newest_line_at"}
+
+// fileLineAges aggregates blamed lines into per file age histograms, as files are blamed (see helper.ComputeLineAges)
+type fileLineAges struct {
+	now  time.Time
+	rows [][]interface{}
+}
+
+// add aggregates the blame of the file at path
+func (f *fileLineAges) add(repoID interface{}, path string, lines []*blame.Blame) {
+	if len(lines) == 0 {
+		return
+	}
+	if f.now.IsZero() {
+		f.now = time.Now()
+	}
+
+	var modified = make([]time.Time, len(lines))
+	for i, l := range lines {
+		modified[i] = l.Author.When
+	}
+
+	var ages = helper.ComputeLineAges(modified, f.now)
+	f.rows = append(f.rows, []interface{}{repoID, path, ages.Lines, ages.Share(ages.OlderThan1Y), ages.Share(ages.OlderThan2Y),
+		ages.Share(ages.OlderThan3Y), int(ages.MedianAge / (24 * time.Hour)), ages.Oldest, ages.Newest})
+}
+
+// sendBatchGitFileLineAges replaces the line ages of the repo with the aggregated ones
+func (w *worker) sendBatchGitFileLineAges(ctx context.Context, tx pgx.Tx, j *db.DequeueSyncJobRow, f *fileLineAges) error {
+	r, err := tx.Exec(ctx, "DELETE FROM git_file_line_ages WHERE repo_id = $1;", j.RepoID.String())
+	if err != nil {
+		return err
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from git_file_line_ages", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_file_line_ages", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"git_file_line_ages"}, gitFileLineAgesColumns, pgx.CopyFromRows(f.rows)); err != nil {
+		return err
+	}
+
+	return w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into git_file_line_ages", len(f.rows)),
+		Details:         rowDetails("inserted", "git_file_line_ages", int64(len(f.rows))),
+	}})
+}
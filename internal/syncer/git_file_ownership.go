@@ -0,0 +1,84 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/gitutils/blame"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// gitFileOwnershipColumns are the columns of git_file_ownership a blame sync writes
+var gitFileOwnershipColumns = []string{"repo_id", "path", "author_email", "author_name", "lines", "total_lines", "ownership", "last_touched_at"}
+
+// authorShare is the part of a file attributed to a single author
+type authorShare struct {
+	name        string
+	lines       int
+	lastTouched time.Time
+}
+
+// fileOwnership aggregates blamed lines into per file, per author ownership, as files are blamed,
+// so that the rollup doesn't have to be computed in SQL over the (very large) git_blame table
+type fileOwnership struct {
+	rows [][]interface{}
+}
+
+// add aggregates the blame of the file at path
+func (f *fileOwnership) add(repoID interface{}, path string, lines []*blame.Blame) {
+	if len(lines) == 0 {
+		return
+	}
+
+	var order []string
+	var shares = make(map[string]*authorShare)
+	for _, l := range lines {
+		share, ok := shares[l.Author.Email]
+		if !ok {
+			share = &authorShare{name: l.Author.Name}
+			shares[l.Author.Email] = share
+			order = append(order, l.Author.Email)
+		}
+
+		share.lines++
+		if l.Author.When.After(share.lastTouched) {
+			share.lastTouched = l.Author.When
+		}
+	}
+
+	for _, email := range order {
+		var share = shares[email]
+		var ownership = float64(share.lines) * 100 / float64(len(lines))
+		f.rows = append(f.rows, []interface{}{repoID, path, email, share.name, share.lines, len(lines), ownership, share.lastTouched})
+	}
+}
+
+// sendBatchGitFileOwnership replaces the file ownership rollup of the repo with the aggregated one
+func (w *worker) sendBatchGitFileOwnership(ctx context.Context, tx pgx.Tx, j *db.DequeueSyncJobRow, f *fileOwnership) error {
+	r, err := tx.Exec(ctx, "DELETE FROM git_file_ownership WHERE repo_id = $1;", j.RepoID.String())
+	if err != nil {
+		return err
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from git_file_ownership", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_file_ownership", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"git_file_ownership"}, gitFileOwnershipColumns, pgx.CopyFromRows(f.rows)); err != nil {
+		return err
+	}
+
+	return w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into git_file_ownership", len(f.rows)),
+		Details:         rowDetails("inserted", "git_file_ownership", int64(len(f.rows))),
+	}})
+}
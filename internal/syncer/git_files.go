@@ -2,7 +2,9 @@ package syncer
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
@@ -15,7 +17,7 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-func (w *worker) sendBatchFiles(ctx context.Context, tx pgx.Tx, j *db.DequeueSyncJobRow, batch []*file) error {
+func (w *worker) sendBatchFiles(ctx context.Context, tx copier, table pgx.Identifier, j *db.DequeueSyncJobRow, batch []*file) error {
 	inputs := make([][]interface{}, 0, len(batch))
 	for _, c := range batch {
 		var repoID uuid.UUID
@@ -30,16 +32,25 @@ func (w *worker) sendBatchFiles(ctx context.Context, tx pgx.Tx, j *db.DequeueSyn
 		} else {
 			contents = nil
 		}
-		input := []interface{}{repoID, c.Path.String, c.Executable.Bool, contents}
+		// the hash is of the raw contents, so that changes to binary files (whose contents aren't stored) are detected
+		var contentHash interface{}
+		if c.Contents.Valid {
+			var sum = sha256.Sum256([]byte(c.Contents.String))
+			contentHash = hex.EncodeToString(sum[:])
+		}
+		input := []interface{}{repoID, c.Path.String, c.Executable.Bool, contents, contentHash}
 		inputs = append(inputs, input)
 	}
 
-	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"git_files"}, []string{"repo_id", "path", "executable", "contents"}, pgx.CopyFromRows(inputs)); err != nil {
+	if _, err := tx.CopyFrom(ctx, table, gitFilesColumns, pgx.CopyFromRows(inputs)); err != nil {
 		return fmt.Errorf("tx copy from: %w", err)
 	}
 	return nil
 }
 
+// gitFilesColumns are the columns of git_files a sync writes
+var gitFilesColumns = []string{"repo_id", "path", "executable", "contents", "content_hash"}
+
 type file struct {
 	Path       sql.NullString `json:"path"`
 	Executable sql.NullBool   `json:"executable"`
@@ -82,8 +93,24 @@ func (w *worker) handleGitFiles(ctx context.Context, j *db.DequeueSyncJobRow) er
 		return fmt.Errorf("mergestat query files: %w", err)
 	}
 
+	var load *loadTable
+	if load, err = w.newLoadTable(ctx, j, "git_files"); err != nil {
+		return err
+	}
+	defer func() {
+		if err := load.close(context.Background()); err != nil {
+			w.logger.Err(err).Msgf("could not drop load table")
+		}
+	}()
+
+	// rows are copied into the (unlogged) load table outside of the transaction, and only moved
+	// into git_files within it, to keep the transaction (and the window without any rows) short
+	if err := w.sendBatchFiles(ctx, load.Copier(), load.Identifier(), j, files); err != nil {
+		return fmt.Errorf("send batch files: %w", err)
+	}
+
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTxOn(ctx, load.Conn(), j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -94,6 +121,21 @@ func (w *worker) handleGitFiles(ctx context.Context, j *db.DequeueSyncJobRow) er
 		}
 	}()
 
+	// changes since the previous sync are recorded before its rows are removed
+	var changes int64
+	if changes, err = recordFileChanges(ctx, tx, load, j); err != nil {
+		return fmt.Errorf("record file changes: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("recorded %d file change(s) since the previous sync into git_file_changes", changes),
+		Details:         rowDetails("inserted", "git_file_changes", changes),
+	}}); err != nil {
+		return err
+	}
+
 	r, err := tx.Exec(ctx, "DELETE FROM git_files WHERE repo_id = $1;", j.RepoID.String())
 	if err != nil {
 		return fmt.Errorf("exec delete: %w", err)
@@ -103,12 +145,13 @@ func (w *worker) handleGitFiles(ctx context.Context, j *db.DequeueSyncJobRow) er
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from git_files", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_files", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
 
-	if err := w.sendBatchFiles(ctx, tx, j, files); err != nil {
-		return fmt.Errorf("send batch files: %w", err)
+	if _, err = load.swap(ctx, tx, gitFilesColumns); err != nil {
+		return err
 	}
 
 	l.Info().Msgf("sent batch of %d files", len(files))
@@ -117,6 +160,7 @@ func (w *worker) handleGitFiles(ctx context.Context, j *db.DequeueSyncJobRow) er
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into git_files", len(files)),
+		Details:         rowDetails("inserted", "git_files", int64(len(files))),
 	}}); err != nil {
 		return err
 	}
@@ -132,7 +176,33 @@ func (w *worker) handleGitFiles(ctx context.Context, j *db.DequeueSyncJobRow) er
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	err = tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "git_files", len(files))
 
-	return err
+	return nil
+}
+
+// recordFileChanges records the files added, modified (or whose mode changed) and deleted since the previous sync, by
+// comparing the rows of the repo in git_files with those loaded. Files whose previous content hash is unknown (synced
+// before hashes were recorded) are only reported if added or deleted.
+func recordFileChanges(ctx context.Context, tx pgx.Tx, load *loadTable, j *db.DequeueSyncJobRow) (int64, error) {
+	var stmt = fmt.Sprintf(`
+INSERT INTO git_file_changes (repo_id, sync_id, path, change, old_content_hash, new_content_hash)
+SELECT $1, $2, COALESCE(n.path, o.path),
+    CASE WHEN o.path IS NULL THEN 'added' WHEN n.path IS NULL THEN 'deleted' ELSE 'modified' END,
+    o.content_hash, n.content_hash
+FROM (SELECT path, executable, content_hash FROM git_files WHERE repo_id = $1) o
+FULL OUTER JOIN (SELECT path, executable, content_hash FROM %s) n ON n.path = o.path
+WHERE o.path IS NULL OR n.path IS NULL
+    OR (o.content_hash IS NOT NULL AND (o.content_hash IS DISTINCT FROM n.content_hash OR o.executable <> n.executable))`,
+		load.Identifier().Sanitize())
+
+	r, err := tx.Exec(ctx, stmt, j.RepoID, j.ID)
+	if err != nil {
+		return 0, err
+	}
+	return r.RowsAffected(), nil
 }
@@ -0,0 +1,267 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-enry/go-enry/v2"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// defaultLargeFileThreshold is the size above which a file is recorded, unless set in the sync settings
+const defaultLargeFileThreshold = 1 << 20
+
+// gitLargeFilesColumns are the columns of git_large_files a sync writes
+var gitLargeFilesColumns = []string{"repo_id", "path", "size", "blob_hash", "is_binary", "large", "last_commit_hash", "last_commit_at"}
+
+// largeFile is a binary or large file found at HEAD
+type largeFile struct {
+	path       string
+	size       int64
+	hash       plumbing.Hash
+	binary     bool
+	large      bool
+	lastCommit *object.Commit
+}
+
+// isBinaryBlob reports whether the blob is binary, looking at its first 8kb like the GIT_BLAME sync does
+func isBinaryBlob(f *object.File) (bool, error) {
+	r, err := f.Reader()
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	var buffer = make([]byte, 8000)
+	n, err := io.ReadFull(r, buffer)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, err
+	}
+
+	return enry.IsBinary(buffer[:n]), nil
+}
+
+// findLargeFiles lists the binary files, and the files above threshold, at the HEAD of the repository at path
+func findLargeFiles(ctx context.Context, path string, threshold int64) (_ []*largeFile, err error) {
+	var repo *git.Repository
+	if repo, err = git.PlainOpen(path); err != nil {
+		return nil, err
+	}
+
+	var head *plumbing.Reference
+	if head, err = repo.Head(); err != nil {
+		return nil, fmt.Errorf("resolve HEAD: %w", err)
+	}
+
+	var commit *object.Commit
+	if commit, err = repo.CommitObject(head.Hash()); err != nil {
+		return nil, err
+	}
+
+	var tree *object.Tree
+	if tree, err = commit.Tree(); err != nil {
+		return nil, err
+	}
+
+	var files []*largeFile
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		binary, err := isBinaryBlob(f)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", f.Name, err)
+		}
+
+		if large := f.Size > threshold; binary || large {
+			files = append(files, &largeFile{path: f.Name, size: f.Size, hash: f.Hash, binary: binary, large: large})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, lastModifyingCommits(ctx, repo, head.Hash(), files)
+}
+
+// lastModifyingCommits walks the history from the given commit (newest first) and sets the last commit of each
+// file to the first one whose version of the file differs from its first parent's
+func lastModifyingCommits(ctx context.Context, repo *git.Repository, from plumbing.Hash, files []*largeFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	iter, err := repo.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	var pending = len(files)
+	err = iter.ForEach(func(c *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		tree, err := c.Tree()
+		if err != nil {
+			return err
+		}
+
+		var parentTree *object.Tree
+		if parent, err := c.Parent(0); err == nil {
+			if parentTree, err = parent.Tree(); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, object.ErrParentNotFound) {
+			return err
+		}
+
+		for _, f := range files {
+			if f.lastCommit != nil {
+				continue
+			}
+
+			entry, err := tree.FindEntry(f.path)
+			if err != nil {
+				continue // not in this commit, it's modified by a later one
+			}
+
+			if parentTree != nil {
+				if previous, err := parentTree.FindEntry(f.path); err == nil && previous.Hash == entry.Hash {
+					continue
+				}
+			}
+
+			f.lastCommit = c
+			pending--
+		}
+
+		if pending == 0 {
+			return storer.ErrStop
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (w *worker) handleGitLargeFiles(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	var threshold = settings.LargeFileThreshold
+	if threshold <= 0 {
+		threshold = defaultLargeFileThreshold
+	}
+
+	tmpPath, cleanup, err := helper.CreateTempDir(os.Getenv("GIT_CLONE_PATH"), fmt.Sprintf("mergestat-repo-%s-*", j.RepoID.String()))
+	if err != nil {
+		return fmt.Errorf("temp dir: %w", err)
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			l.Err(err).Msgf("error cleaning up repo at: %s, %v", tmpPath, err)
+		}
+	}()
+
+	if err = w.clone(ctx, tmpPath, j); err != nil {
+		return fmt.Errorf("git clone: %w", err)
+	}
+
+	var files []*largeFile
+	if files, err = findLargeFiles(ctx, tmpPath, threshold); err != nil {
+		return fmt.Errorf("find large files: %w", err)
+	}
+
+	l.Info().Msgf("found %d binary or large file(s)", len(files))
+
+	var inputs = make([][]interface{}, 0, len(files))
+	for _, f := range files {
+		var lastHash, lastAt interface{}
+		if f.lastCommit != nil {
+			lastHash, lastAt = f.lastCommit.Hash.String(), f.lastCommit.Committer.When
+		}
+		inputs = append(inputs, []interface{}{j.RepoID, f.path, f.size, f.hash.String(), f.binary, f.large, lastHash, lastAt})
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM git_large_files WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from git_large_files", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_large_files", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"git_large_files"}, gitLargeFilesColumns, pgx.CopyFromRows(inputs)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into git_large_files", len(inputs)),
+		Details:         rowDetails("inserted", "git_large_files", int64(len(inputs))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "git_large_files", len(inputs))
+
+	return nil
+}
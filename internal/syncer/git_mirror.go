@@ -0,0 +1,265 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// errMirrorPathRequired is returned by GIT_MIRROR syncs when the worker has no storage location for mirrors
+var errMirrorPathRequired = errors.New("in order to run this syncer, GIT_MIRROR_PATH must be set to the directory mirrors are stored in")
+
+// mirrorPath returns the path of the (bare) mirror of the repo, under GIT_MIRROR_PATH
+func mirrorPath(root string, repo uuid.UUID) string {
+	return filepath.Join(root, repo.String()+".git")
+}
+
+// updateMirror creates the bare mirror of the repository at path, or fetches into it if it exists. Every ref is
+// mirrored, and refs deleted upstream are pruned. It reports whether the mirror was created.
+func updateMirror(ctx context.Context, path string, endpoint *transport.Endpoint, auth transport.AuthMethod) (_ *git.Repository, created bool, err error) {
+	var repo *git.Repository
+	if repo, err = git.PlainOpen(path); errors.Is(err, git.ErrRepositoryNotExists) {
+		if repo, err = git.PlainCloneContext(ctx, path, true, &git.CloneOptions{URL: endpoint.String(), Auth: auth, Mirror: true}); err != nil {
+			_ = os.RemoveAll(path)
+			return nil, false, fmt.Errorf("clone mirror: %w", err)
+		}
+		return repo, true, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("open mirror: %w", err)
+	}
+
+	// the remote of a mirror fetches +refs/*:refs/* (see git.CloneOptions.Mirror)
+	var opts = &git.FetchOptions{RemoteName: git.DefaultRemoteName, RemoteURL: endpoint.String(), Auth: auth, Force: true, Tags: git.AllTags}
+	if err = repo.FetchContext(ctx, opts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, false, fmt.Errorf("fetch mirror: %w", err)
+	}
+
+	if err = pruneRefs(ctx, repo, auth); err != nil {
+		return nil, false, fmt.Errorf("prune mirror: %w", err)
+	}
+
+	return repo, false, nil
+}
+
+// pruneRefs deletes the refs of the mirror that no longer exist upstream (go-git doesn't prune on fetch)
+func pruneRefs(ctx context.Context, repo *git.Repository, auth transport.AuthMethod) error {
+	var remote, err = repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return err
+	}
+
+	var upstream []*plumbing.Reference
+	if upstream, err = remote.ListContext(ctx, &git.ListOptions{Auth: auth}); err != nil {
+		return err
+	}
+
+	var exists = make(map[plumbing.ReferenceName]bool, len(upstream))
+	for _, ref := range upstream {
+		exists[ref.Name()] = true
+	}
+
+	var iter storer.ReferenceIter
+	if iter, err = repo.References(); err != nil {
+		return err
+	}
+
+	var stale []plumbing.ReferenceName
+	_ = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() != plumbing.HEAD && !exists[ref.Name()] {
+			stale = append(stale, ref.Name())
+		}
+		return nil
+	})
+
+	for _, name := range stale {
+		if err = repo.Storer.RemoveReference(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dirSize returns the total size of the files under path
+func dirSize(path string) (size int64, err error) {
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		var info fs.FileInfo
+		if info, err = d.Info(); err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// pruneMirrors removes the mirrors of the repos that are no longer in public.repos, and returns their number
+func (w *worker) pruneMirrors(ctx context.Context, root string) (int, error) {
+	var entries, err = os.ReadDir(root)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []uuid.UUID
+	for _, e := range entries {
+		if id, parseErr := uuid.Parse(strings.TrimSuffix(e.Name(), ".git")); parseErr == nil && e.IsDir() && strings.HasSuffix(e.Name(), ".git") {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, "SELECT m.id FROM unnest($1::UUID[]) AS m(id) WHERE NOT EXISTS (SELECT 1 FROM public.repos r WHERE r.id = m.id)", ids); err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var pruned int
+	for rows.Next() {
+		var id uuid.UUID
+		if err = rows.Scan(&id); err != nil {
+			return pruned, err
+		}
+		if err = os.RemoveAll(mirrorPath(root, id)); err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, rows.Err()
+}
+
+// handleGitMirror maintains a full bare mirror of the repo in GIT_MIRROR_PATH, and records it in git_mirrors
+func (w *worker) handleGitMirror(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var root = os.Getenv("GIT_MIRROR_PATH")
+	if root == "" {
+		return errMirrorPathRequired
+	}
+	if err = os.MkdirAll(root, 0o755); err != nil {
+		return fmt.Errorf("mirror path: %w", err)
+	}
+
+	var repo db.Repo
+	if repo, err = w.db.GetRepoById(ctx, j.RepoID); err != nil {
+		return err
+	}
+
+	var endpoint *transport.Endpoint
+	var auth transport.AuthMethod
+	if endpoint, auth, err = w.authForRepo(ctx, repo); err != nil {
+		return err
+	}
+
+	var path = mirrorPath(root, j.RepoID)
+	var mirror *git.Repository
+	var created bool
+	if mirror, created, err = updateMirror(ctx, path, endpoint, auth); err != nil {
+		return err
+	}
+
+	var message = "fetched into mirror at " + path
+	if created {
+		message = "created mirror at " + path
+	}
+	if err = w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID, Message: message}}); err != nil {
+		return err
+	}
+
+	var refs int
+	var iter, _ = mirror.References()
+	if iter != nil {
+		_ = iter.ForEach(func(*plumbing.Reference) error { refs++; return nil })
+	}
+
+	var head interface{}
+	if ref, headErr := mirror.Head(); headErr == nil {
+		head = ref.Hash().String()
+	}
+
+	var size int64
+	if size, err = dirSize(path); err != nil {
+		return fmt.Errorf("mirror size: %w", err)
+	}
+
+	l.Info().Msgf("mirror has %d ref(s) and takes %d byte(s)", refs, size)
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	const upsert = `
+INSERT INTO git_mirrors (repo_id, path, head_commit_hash, refs, size, created_at, fetched_at)
+VALUES ($1, $2, $3, $4, $5, now(), now())
+ON CONFLICT (repo_id) DO UPDATE SET path = excluded.path, head_commit_hash = excluded.head_commit_hash, refs = excluded.refs,
+    size = excluded.size, created_at = CASE WHEN $6 THEN excluded.created_at ELSE git_mirrors.created_at END,
+    fetched_at = excluded.fetched_at, _mergestat_synced_at = now()`
+
+	if _, err = tx.Exec(ctx, upsert, j.RepoID, path, head, refs, size, created); err != nil {
+		return fmt.Errorf("exec upsert: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         "upserted 1 row(s) into git_mirrors",
+		Details:         rowDetails("upserted", "git_mirrors", 1),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// the mirrors of removed repos are pruned as a side effect of any mirror sync
+	if pruned, err := w.pruneMirrors(ctx, root); err != nil {
+		l.Warn().Err(err).Msg("failed to prune mirrors of removed repos")
+	} else if pruned > 0 {
+		l.Info().Msgf("pruned %d mirror(s) of removed repos", pruned)
+	}
+
+	return nil
+}
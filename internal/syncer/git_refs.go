@@ -107,8 +107,27 @@ func (w *worker) handleGitRefs(ctx context.Context, j *db.DequeueSyncJobRow) err
 
 	l.Info().Msgf("retrieved refs: %d", len(refs))
 
+	// branch stats are a bonus of the refs: if they can't be computed, the refs are synced regardless (and the stats
+	// of the previous sync are kept)
+	var stats [][]interface{}
+	var warnings []string
+	var statsFailed bool
+	if stats, warnings, err = branchStats(ctx, tmpPath, j); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("branch stats: %w", err)
+		}
+		warnings = append(warnings, fmt.Sprintf("could not compute branch stats: %v", err))
+		statsFailed, err = true, nil
+	}
+	for _, warning := range warnings {
+		l.Warn().Msg(warning)
+		if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeWarn, RepoSyncQueueID: j.ID, Message: warning}}); err != nil {
+			return err
+		}
+	}
+
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return err
 	}
 	defer func() {
@@ -128,6 +147,7 @@ func (w *worker) handleGitRefs(ctx context.Context, j *db.DequeueSyncJobRow) err
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from git_refs", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_refs", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -142,10 +162,17 @@ func (w *worker) handleGitRefs(ctx context.Context, j *db.DequeueSyncJobRow) err
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into git_refs", len(refs)),
+		Details:         rowDetails("inserted", "git_refs", int64(len(refs))),
 	}}); err != nil {
 		return err
 	}
 
+	if !statsFailed {
+		if err := w.sendBatchGitBranchStats(ctx, tx, j, stats); err != nil {
+			return err
+		}
+	}
+
 	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
 		return err
 	}
@@ -157,7 +184,11 @@ func (w *worker) handleGitRefs(ctx context.Context, j *db.DequeueSyncJobRow) err
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	err = tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "git_refs", len(refs))
 
-	return err
+	return nil
 }
@@ -37,7 +37,7 @@ func (w *worker) handleGitRemotes(ctx context.Context, j *db.DequeueSyncJobRow)
 	}
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return err
 	}
 	defer func() {
@@ -57,6 +57,7 @@ func (w *worker) handleGitRemotes(ctx context.Context, j *db.DequeueSyncJobRow)
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from git_remotes", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_remotes", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -86,6 +87,7 @@ func (w *worker) handleGitRemotes(ctx context.Context, j *db.DequeueSyncJobRow)
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into git_remotes", len(remotes)),
+		Details:         rowDetails("inserted", "git_remotes", int64(len(remotes))),
 	}}); err != nil {
 		return err
 	}
@@ -0,0 +1,165 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// symbolBatchSize is the number of symbols copied into the load table at once
+const symbolBatchSize = 1000
+
+// gitSymbolsColumns are the columns of git_symbols a sync writes
+var gitSymbolsColumns = []string{"repo_id", "path", "name", "kind", "line", "language", "scope", "scope_kind", "signature"}
+
+// handleGitSymbols executes `ctags` (universal-ctags) over the files of a repo at HEAD,
+// and inserts the symbols it reports into git_symbols
+func (w *worker) handleGitSymbols(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	tmpPath, cleanup, err := helper.CreateTempDir(os.Getenv("GIT_CLONE_PATH"), fmt.Sprintf("mergestat-repo-%s-*", j.RepoID.String()))
+	if err != nil {
+		return fmt.Errorf("temp dir: %w", err)
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			l.Err(err).Msgf("error cleaning up repo at: %s, %v", tmpPath, err)
+		}
+	}()
+
+	if err = w.clone(ctx, tmpPath, j); err != nil {
+		return fmt.Errorf("git clone: %w", err)
+	}
+
+	var load *loadTable
+	if load, err = w.newLoadTable(ctx, j, "git_symbols"); err != nil {
+		return err
+	}
+	defer func() {
+		if err := load.close(context.Background()); err != nil {
+			w.logger.Err(err).Msgf("could not drop load table")
+		}
+	}()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ctags", helper.CtagsArgs...)
+	cmd.Dir, cmd.Stderr = tmpPath, &stderr
+
+	var stdout, _ = cmd.StdoutPipe()
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("running ctags (universal-ctags must be installed): %w", err)
+	}
+
+	// symbols are copied into the load table in batches as ctags reports them, so that they're never all in memory
+	var inputs = make([][]interface{}, 0, symbolBatchSize)
+	var loaded int
+	var flush = func() error {
+		if _, err := load.Copier().CopyFrom(ctx, load.Identifier(), gitSymbolsColumns, pgx.CopyFromRows(inputs)); err != nil {
+			return fmt.Errorf("copy symbols: %w", err)
+		}
+		loaded, inputs = loaded+len(inputs), inputs[:0]
+		return nil
+	}
+
+	var readErr = helper.ReadCtags(stdout, func(s *helper.Symbol) error {
+		inputs = append(inputs, []interface{}{j.RepoID, cleanText(s.Path), cleanText(s.Name), s.Kind, s.Line, s.Language,
+			nullIfEmpty(cleanText(s.Scope)), nullIfEmpty(s.ScopeKind), nullIfEmpty(cleanText(s.Signature))})
+		if len(inputs) == cap(inputs) {
+			return flush()
+		}
+		return nil
+	})
+	if readErr != nil {
+		_ = cmd.Process.Kill()
+	}
+	if err = cmd.Wait(); err != nil && readErr == nil {
+		w.logger.Warn().AnErr("error", err).Str("stderr", stderr.String()).Msgf("error running ctags")
+		return fmt.Errorf("running ctags: %w", err)
+	}
+	if readErr != nil {
+		return readErr
+	}
+	if err = flush(); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("loaded %d symbol(s)", loaded)
+
+	var tx pgx.Tx
+	if tx, err = w.beginTxOn(ctx, load.Conn(), j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM git_symbols WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from git_symbols", r.RowsAffected()),
+		Details:         rowDetails("removed", "git_symbols", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err = load.swap(ctx, tx, gitSymbolsColumns); err != nil {
+		return err
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into git_symbols", loaded),
+		Details:         rowDetails("inserted", "git_symbols", int64(loaded)),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "git_symbols", loaded)
+
+	return nil
+}
+
+// cleanText makes text reported by ctags (eg. from files in other encodings) storable in a TEXT column
+func cleanText(s string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(s, "�"), "\u0000", "")
+}
@@ -36,7 +36,7 @@ func (w *worker) handleGithubActions(ctx context.Context, j *db.DequeueSyncJobRo
 
 	var tx pgx.Tx
 
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 
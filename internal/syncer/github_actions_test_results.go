@@ -0,0 +1,270 @@
+package syncer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/queries"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// maxTestResultRunsPerSync is the most workflow runs the artifacts of which are scanned by a single sync,
+	// the remaining runs are picked up by the next syncs
+	maxTestResultRunsPerSync = 200
+
+	// maxJUnitArtifactBytes is the size above which an artifact isn't downloaded
+	maxJUnitArtifactBytes = 50 << 20
+)
+
+// defaultJUnitArtifacts are the globs of the (lowercased) names of the artifacts that are downloaded as JUnit reports,
+// unless set in the sync settings
+var defaultJUnitArtifacts = []string{"*junit*", "*test-result*", "*test-report*", "*test_result*", "*test_report*"}
+
+// githubActionsTestResultsColumns are the columns of github_actions_test_results a sync writes
+var githubActionsTestResultsColumns = []string{"repo_id", "run_id", "artifact_id", "artifact_name", "file", "suite", "classname", "name", "status", "duration_seconds", "message"}
+
+// selectRunsWithoutTestResults lists the completed workflow runs of a repo the artifacts of which weren't scanned yet,
+// most recent first. Artifacts expire, so recent runs are scanned first.
+const selectRunsWithoutTestResults = `
+SELECT wr.id FROM github_actions_workflow_runs wr
+WHERE wr.repo_id = $1 AND wr.status = 'completed'
+  AND NOT EXISTS (SELECT 1 FROM github_actions_test_result_runs r WHERE r.repo_id = wr.repo_id AND r.run_id = wr.id)
+ORDER BY wr.created_at DESC
+LIMIT $2
+`
+
+func (w *worker) handleGitHubActionsTestResults(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	var patterns = settings.JUnitArtifacts
+	if len(patterns) == 0 {
+		patterns = defaultJUnitArtifacts
+	}
+
+	var ghToken string
+	if _, ghToken, err = w.fetchCredentials(ctx, j); err != nil {
+		return err
+	}
+
+	if len(ghToken) <= 0 {
+		return errGitHubTokenRequired
+	}
+
+	var owner, name string
+	if owner, name, err = helper.GetRepoOwnerAndRepoName(j.Repo); err != nil {
+		return err
+	}
+
+	var runIDs []int64
+	if runIDs, err = w.runsWithoutTestResults(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("scanning artifacts of %d workflow run(s)", len(runIDs))
+
+	var client = github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken})))
+
+	// artifacts are downloaded from the (pre-signed) url GitHub redirects to, without the token
+	var download = &http.Client{Timeout: 5 * time.Minute}
+
+	var scannedAt = time.Now()
+	var runRows, resultRows [][]interface{}
+	for _, runID := range runIDs {
+		var artifacts []*github.Artifact
+		var opts = &github.ListOptions{PerPage: 100}
+		for {
+			list, resp, err := client.Actions.ListWorkflowRunArtifacts(ctx, owner, name, runID, opts)
+			if err != nil {
+				return fmt.Errorf("list artifacts of workflow run %d: %w", runID, err)
+			}
+			helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+			artifacts = append(artifacts, list.Artifacts...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+
+		var downloaded, cases int
+		for _, artifact := range artifacts {
+			if artifact.GetExpired() || !helper.MatchAnyGlob(patterns, strings.ToLower(artifact.GetName())) {
+				continue
+			}
+			if artifact.GetSizeInBytes() > maxJUnitArtifactBytes {
+				l.Warn().Msgf("skipping artifact %s of workflow run %d of %d bytes", artifact.GetName(), runID, artifact.GetSizeInBytes())
+				continue
+			}
+
+			u, resp, err := client.Actions.DownloadArtifact(ctx, owner, name, artifact.GetID(), false)
+			if err != nil {
+				return fmt.Errorf("download artifact %d of workflow run %d: %w", artifact.GetID(), runID, err)
+			}
+			helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+			var reports map[string][]helper.TestCase
+			if reports, err = w.junitReports(ctx, download, u.String()); err != nil {
+				// a single malformed (or vanished) artifact shouldn't fail the sync
+				l.Warn().AnErr("error", err).Msgf("could not read artifact %s of workflow run %d", artifact.GetName(), runID)
+				continue
+			}
+
+			downloaded++
+			for file, tcs := range reports {
+				for _, tc := range tcs {
+					resultRows = append(resultRows, []interface{}{j.RepoID, runID, artifact.GetID(), artifact.GetName(), file,
+						tc.Suite, tc.ClassName, tc.Name, tc.Status, tc.Duration, nullIfEmpty(tc.Message)})
+				}
+				cases += len(tcs)
+			}
+		}
+
+		runRows = append(runRows, []interface{}{j.RepoID, runID, downloaded, cases, scannedAt})
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("rollback transaction: %v", err)
+			}
+		}
+	}()
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"github_actions_test_results"}, githubActionsTestResultsColumns, pgx.CopyFromRows(resultRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"github_actions_test_result_runs"}, []string{"repo_id", "run_id", "artifacts", "test_cases", "scanned_at"}, pgx.CopyFromRows(runRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_actions_test_results", len(resultRows)),
+		Details:         rowDetails("inserted", "github_actions_test_results", int64(len(resultRows))),
+	}, {
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_actions_test_result_runs", len(runRows)),
+		Details:         rowDetails("inserted", "github_actions_test_result_runs", int64(len(runRows))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// runsWithoutTestResults returns the ids of (at most maxTestResultRunsPerSync of) the repo's runs the artifacts of which weren't scanned yet
+func (w *worker) runsWithoutTestResults(ctx context.Context, j *db.DequeueSyncJobRow) ([]int64, error) {
+	rows, err := w.pool.Query(ctx, selectRunsWithoutTestResults, j.RepoID, maxTestResultRunsPerSync)
+	if err != nil {
+		return nil, fmt.Errorf("list workflow runs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan workflow run: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// junitReports downloads the (zip) archive of an artifact and parses the test cases of the XML files in it, by path
+func (w *worker) junitReports(ctx context.Context, client *http.Client, url string) (map[string][]helper.TestCase, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status downloading artifact: %s", resp.Status)
+	}
+
+	var contents []byte
+	if contents, err = io.ReadAll(io.LimitReader(resp.Body, maxJUnitArtifactBytes+1)); err != nil {
+		return nil, err
+	} else if len(contents) > maxJUnitArtifactBytes {
+		return nil, fmt.Errorf("artifact larger than %d bytes", maxJUnitArtifactBytes)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+	if err != nil {
+		return nil, fmt.Errorf("open artifact: %w", err)
+	}
+
+	var reports = make(map[string][]helper.TestCase)
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(f.Name), ".xml") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", f.Name, err)
+		}
+		cases, err := helper.ParseJUnit(io.LimitReader(rc, maxJUnitArtifactBytes))
+		rc.Close()
+		if err != nil {
+			// other xml files may be uploaded alongside the reports (eg. coverage), skip the ones that don't parse
+			w.logger.Debug().AnErr("error", err).Msgf("skipping %s, not a JUnit report", f.Name)
+			continue
+		}
+		if len(cases) > 0 {
+			reports[f.Name] = cases
+		}
+	}
+
+	return reports, nil
+}
@@ -0,0 +1,160 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/queries"
+	"golang.org/x/oauth2"
+)
+
+// maxUsageRunsPerSync is the most workflow runs the timing of which is retrieved by a single sync,
+// the remaining runs are picked up by the next syncs
+const maxUsageRunsPerSync = 1000
+
+// selectRunsWithoutUsage lists the completed workflow runs of a repo the usage of which wasn't retrieved yet,
+// most recent first. The timing of a completed run doesn't change, so it's only retrieved once.
+const selectRunsWithoutUsage = `
+SELECT wr.id FROM github_actions_workflow_runs wr
+WHERE wr.repo_id = $1 AND wr.status = 'completed'
+  AND NOT EXISTS (SELECT 1 FROM github_actions_workflow_run_usage u WHERE u.repo_id = wr.repo_id AND u.run_id = wr.id)
+ORDER BY wr.created_at DESC
+LIMIT $2
+`
+
+func (w *worker) handleGitHubActionsUsage(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var ghToken string
+	if _, ghToken, err = w.fetchCredentials(ctx, j); err != nil {
+		return err
+	}
+
+	if len(ghToken) <= 0 {
+		return errGitHubTokenRequired
+	}
+
+	var owner, name string
+	if owner, name, err = helper.GetRepoOwnerAndRepoName(j.Repo); err != nil {
+		return err
+	}
+
+	var runIDs []int64
+	if runIDs, err = w.runsWithoutUsage(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("retrieving usage of %d workflow run(s)", len(runIDs))
+
+	var client = github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken})))
+
+	var runRows, jobRows [][]interface{}
+	for _, runID := range runIDs {
+		usage, resp, err := client.Actions.GetWorkflowRunUsageByID(ctx, owner, name, runID)
+		if err != nil {
+			return fmt.Errorf("get usage of workflow run %d: %w", runID, err)
+		}
+		helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+		var billable github.WorkflowRunBillMap
+		if usage.Billable != nil {
+			billable = *usage.Billable
+		}
+
+		var billableMS, billableMinutes int64
+		for runnerOS, bill := range billable {
+			billableMS += bill.GetTotalMS()
+			for _, job := range bill.JobRuns {
+				var minutes = helper.BillableMinutes(runnerOS, job.GetDurationMS())
+				billableMinutes += minutes
+				jobRows = append(jobRows, []interface{}{j.RepoID, runID, int64(job.GetJobID()), runnerOS, job.GetDurationMS(), minutes})
+			}
+		}
+
+		var runDuration interface{}
+		if usage.RunDurationMS != nil {
+			runDuration = usage.GetRunDurationMS()
+		}
+
+		runRows = append(runRows, []interface{}{j.RepoID, runID, runDuration, billableMS, billableMinutes})
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("rollback transaction: %v", err)
+			}
+		}
+	}()
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"github_actions_workflow_run_usage"}, []string{"repo_id", "run_id", "run_duration_ms", "billable_ms", "billable_minutes"}, pgx.CopyFromRows(runRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"github_actions_workflow_job_usage"}, []string{"repo_id", "run_id", "job_id", "runner_os", "duration_ms", "billable_minutes"}, pgx.CopyFromRows(jobRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_actions_workflow_run_usage", len(runRows)),
+		Details:         rowDetails("inserted", "github_actions_workflow_run_usage", int64(len(runRows))),
+	}, {
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_actions_workflow_job_usage", len(jobRows)),
+		Details:         rowDetails("inserted", "github_actions_workflow_job_usage", int64(len(jobRows))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// runsWithoutUsage returns the ids of (at most maxUsageRunsPerSync of) the repo's runs the usage of which wasn't retrieved yet
+func (w *worker) runsWithoutUsage(ctx context.Context, j *db.DequeueSyncJobRow) ([]int64, error) {
+	rows, err := w.pool.Query(ctx, selectRunsWithoutUsage, j.RepoID, maxUsageRunsPerSync)
+	if err != nil {
+		return nil, fmt.Errorf("list workflow runs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan workflow run: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,68 @@
+//go:build integration
+
+package syncer
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/mergestat/mergestat/internal/fakegithub"
+	"github.com/mergestat/mergestat/internal/testharness"
+	"github.com/rs/zerolog"
+)
+
+func runner(id int64, name, status string, labels ...string) map[string]interface{} {
+	var runnerLabels []map[string]string
+	for _, label := range labels {
+		runnerLabels = append(runnerLabels, map[string]string{"name": label})
+	}
+	return map[string]interface{}{"id": id, "name": name, "os": "linux", "status": status, "busy": false, "labels": runnerLabels}
+}
+
+func TestGitHubSyncsEndToEnd(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "fake-token")
+
+	var database = testharness.Postgres(t)
+	var api = fakegithub.New(t)
+
+	var logger = zerolog.New(zerolog.NewTestWriter(t))
+	var w = New(database.Pool, nil, &logger, 1, time.Second).WithTransport(api.Transport())
+
+	t.Run("GITHUB_RUNNERS", func(t *testing.T) {
+		var repo = database.AddRepo(t, "https://github.com/mergestat/fixture")
+
+		// the repo's runners span two pages, and the token may not list the organization's
+		api.Handle(http.MethodGet, "/repos/mergestat/fixture/actions/runners",
+			map[string]interface{}{"total_count": 3, "runners": []interface{}{runner(1, "runner-1", "online", "self-hosted", "linux")}},
+			map[string]interface{}{"total_count": 3, "runners": []interface{}{runner(2, "runner-2", "offline"), runner(3, "runner-3", "online", "gpu")}},
+		)
+		api.Fail(http.MethodGet, "/orgs/mergestat/actions/runners", http.StatusForbidden, "Resource not accessible by integration")
+
+		if err := runSync(t, w, repo, syncTypeGitHubRunners); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+
+		if rows := database.Count(t, "github_runners", repo); rows != 3 {
+			t.Fatalf("expected 3 row(s) in github_runners, found %d", rows)
+		}
+		if rows := database.Count(t, "github_runner_snapshots", repo); rows != 3 {
+			t.Fatalf("expected 3 row(s) in github_runner_snapshots, found %d", rows)
+		}
+	})
+
+	t.Run("rate limit exceeded", func(t *testing.T) {
+		var repo = database.AddRepo(t, "https://github.com/mergestat/limited")
+		api.Handle(http.MethodGet, "/repos/mergestat/limited/actions/runners", map[string]interface{}{"total_count": 0, "runners": []interface{}{}})
+		api.SetRateLimit(fakegithub.RateLimit{Limit: 5000, Remaining: 0, Reset: time.Now().Add(time.Hour)})
+
+		var err = runSync(t, w, repo, syncTypeGitHubRunners)
+
+		var rateLimitErr *github.RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("expected the sync to fail with a rate limit error, got: %v", err)
+		}
+	})
+}
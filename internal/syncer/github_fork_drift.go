@@ -0,0 +1,126 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/queries"
+)
+
+// insertForkDrift records the drift of a fork, linking its upstream to the repo it's synced as (if it is)
+const insertForkDrift = `
+INSERT INTO github_fork_drift (repo_id, computed_at, upstream_owner, upstream_name, upstream_repo_id, default_branch,
+    upstream_default_branch, status, ahead_by, behind_by, merge_base_hash, merge_base_committed_at)
+VALUES ($1, $2, $3, $4, (SELECT id FROM repos WHERE lower(repo) IN (lower($5), lower($5 || '.git')) ORDER BY created_at LIMIT 1), $6, $7, $8, $9, $10, $11, $12)
+`
+
+func (w *worker) handleGitHubForkDrift(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var ghToken string
+	if ghToken, err = w.githubToken(ctx, j); err != nil {
+		return err
+	}
+
+	var owner, name string
+	if owner, name, err = helper.GetRepoOwnerAndRepoName(j.Repo); err != nil {
+		return err
+	}
+
+	var client = github.NewClient(githubHTTPClient(ctx, ghToken))
+
+	repo, resp, err := client.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		return fmt.Errorf("get repo: %w", err)
+	}
+	helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+	// the arguments of insertForkDrift, if the repo is a fork
+	var drift []interface{}
+	if parent := repo.GetParent(); repo.GetFork() && parent != nil {
+		var upstreamOwner, upstreamName = parent.GetOwner().GetLogin(), parent.GetName()
+
+		// compared in the upstream, the head (of the fork) is ahead by the commits the upstream doesn't have
+		var status, aheadBy, behindBy, mergeBaseHash, mergeBaseCommittedAt interface{}
+		comparison, resp, err := client.Repositories.CompareCommits(ctx, upstreamOwner, upstreamName,
+			parent.GetDefaultBranch(), owner+":"+repo.GetDefaultBranch(), &github.ListOptions{PerPage: 1})
+		switch {
+		case err == nil:
+			helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+			status, aheadBy, behindBy = comparison.GetStatus(), comparison.GetAheadBy(), comparison.GetBehindBy()
+			if mb := comparison.GetMergeBaseCommit(); mb != nil {
+				mergeBaseHash = nullIfEmpty(mb.GetSHA())
+				mergeBaseCommittedAt = nullIfZero(mb.GetCommit().GetCommitter().GetDate().Time)
+			}
+		case resp != nil && resp.StatusCode == http.StatusNotFound:
+			// the branches don't share any history (or the upstream isn't visible), the upstream is still recorded
+			w.warnForJob(ctx, j, fmt.Sprintf("could not compare %s with its upstream %s/%s: %v", j.Repo, upstreamOwner, upstreamName, err),
+				logDetails{"upstream": upstreamOwner + "/" + upstreamName, "error": err.Error()})
+		default:
+			return fmt.Errorf("compare with upstream: %w", err)
+		}
+
+		l.Info().Msgf("fork of %s/%s is %v ahead and %v behind", upstreamOwner, upstreamName, aheadBy, behindBy)
+
+		drift = []interface{}{j.RepoID, time.Now(), upstreamOwner, upstreamName, parent.GetHTMLURL(), repo.GetDefaultBranch(),
+			parent.GetDefaultBranch(), status, aheadBy, behindBy, mergeBaseHash, mergeBaseCommittedAt}
+	} else {
+		l.Info().Msg("repo is not a fork")
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	var inserted int
+	if drift != nil {
+		if _, err := tx.Exec(ctx, insertForkDrift, drift...); err != nil {
+			return fmt.Errorf("insert fork drift: %w", err)
+		}
+		inserted = 1
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_fork_drift", inserted),
+		Details:         rowDetails("inserted", "github_fork_drift", int64(inserted)),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,212 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/queries"
+	"golang.org/x/oauth2"
+)
+
+// githubDiscussion is the rows of the comment threads of a repo's issues (or pull requests),
+// with the reaction rollups of each item and comment, and the history of their labels
+type githubDiscussion struct {
+	reactions   [][]interface{}
+	comments    [][]interface{}
+	labelEvents [][]interface{}
+}
+
+// reactionRollup returns the total number of reactions and, encoded as json, the number by content
+func reactionRollup(r *github.Reactions) (int, []byte) {
+	var counts = make(map[string]int)
+	if r == nil {
+		return 0, []byte("{}")
+	}
+
+	for content, count := range map[string]*int{
+		"+1": r.PlusOne, "-1": r.MinusOne, "laugh": r.Laugh, "confused": r.Confused,
+		"heart": r.Heart, "hooray": r.Hooray, "rocket": r.Rocket, "eyes": r.Eyes,
+	} {
+		if count != nil && *count > 0 {
+			counts[content] = *count
+		}
+	}
+
+	var encoded, _ = json.Marshal(counts)
+	return r.GetTotalCount(), encoded
+}
+
+// numberFromURL returns the number of the issue (or pull request) an api url ends with
+func numberFromURL(u string) (int, bool) {
+	var n, err = strconv.Atoi(u[strings.LastIndex(u, "/")+1:])
+	return n, err == nil
+}
+
+// fetchGitHubDiscussion lists the issues (or, if pulls is set, the pull requests) of a repo with their reactions,
+// and the comments and label events of all of them. For pull requests, review comments (on the diff) are listed as well.
+func (w *worker) fetchGitHubDiscussion(ctx context.Context, token string, repo uuid.UUID, owner, name string, pulls bool) (*githubDiscussion, error) {
+	var client = github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	var d = &githubDiscussion{}
+	var numbers = make(map[int]bool)
+
+	var issueOpts = &github.IssueListByRepoOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := client.Issues.ListByRepo(ctx, owner, name, issueOpts)
+		if err != nil {
+			return nil, fmt.Errorf("list issues: %w", err)
+		}
+		helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+		// the issues api lists pull requests as well
+		for _, issue := range page {
+			if issue.IsPullRequest() != pulls {
+				continue
+			}
+
+			numbers[issue.GetNumber()] = true
+			var total, reactions = reactionRollup(issue.Reactions)
+			d.reactions = append(d.reactions, []interface{}{repo, issue.GetNumber(), pulls, total, reactions})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		issueOpts.Page = resp.NextPage
+	}
+
+	var commentOpts = &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := client.Issues.ListComments(ctx, owner, name, 0, commentOpts)
+		if err != nil {
+			return nil, fmt.Errorf("list comments: %w", err)
+		}
+		helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+		for _, c := range page {
+			var number, ok = numberFromURL(c.GetIssueURL())
+			if !ok || !numbers[number] {
+				continue
+			}
+
+			var total, reactions = reactionRollup(c.Reactions)
+			d.comments = append(d.comments, []interface{}{repo, c.GetID(), "ISSUE_COMMENT", number, pulls,
+				c.GetUser().GetLogin(), c.GetAuthorAssociation(), c.GetBody(), nil, nil,
+				c.GetCreatedAt().Time, c.GetUpdatedAt().Time, c.GetHTMLURL(), total, reactions})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		commentOpts.Page = resp.NextPage
+	}
+
+	var eventOpts = &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := client.Issues.ListRepositoryEvents(ctx, owner, name, eventOpts)
+		if err != nil {
+			return nil, fmt.Errorf("list issue events: %w", err)
+		}
+		helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+		for _, e := range page {
+			if (e.GetEvent() != "labeled" && e.GetEvent() != "unlabeled") || e.Label == nil || !numbers[e.GetIssue().GetNumber()] {
+				continue
+			}
+
+			d.labelEvents = append(d.labelEvents, []interface{}{repo, e.GetID(), e.GetIssue().GetNumber(), pulls,
+				e.GetEvent(), e.GetLabel().GetName(), e.GetActor().GetLogin(), e.GetCreatedAt().Time})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		eventOpts.Page = resp.NextPage
+	}
+
+	if !pulls {
+		return d, nil
+	}
+
+	var reviewOpts = &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := client.PullRequests.ListComments(ctx, owner, name, 0, reviewOpts)
+		if err != nil {
+			return nil, fmt.Errorf("list review comments: %w", err)
+		}
+		helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+		for _, c := range page {
+			var number, ok = numberFromURL(c.GetPullRequestURL())
+			if !ok {
+				continue
+			}
+
+			var inReplyTo interface{}
+			if c.InReplyTo != nil {
+				inReplyTo = c.GetInReplyTo()
+			}
+
+			var total, reactions = reactionRollup(c.Reactions)
+			d.comments = append(d.comments, []interface{}{repo, c.GetID(), "REVIEW_COMMENT", number, true,
+				c.GetUser().GetLogin(), c.GetAuthorAssociation(), c.GetBody(), c.GetPath(), inReplyTo,
+				c.GetCreatedAt().Time, c.GetUpdatedAt().Time, c.GetHTMLURL(), total, reactions})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		reviewOpts.Page = resp.NextPage
+	}
+
+	return d, nil
+}
+
+// sendBatchGitHubDiscussion replaces the repo's rows, for issues (or pull requests), in github_issue_comments,
+// github_issue_reactions and github_issue_label_events with the fetched ones
+func (w *worker) sendBatchGitHubDiscussion(ctx context.Context, tx pgx.Tx, j *db.DequeueSyncJobRow, d *githubDiscussion, pulls bool) error {
+	var tables = []struct {
+		name    string
+		columns []string
+		rows    [][]interface{}
+	}{
+		{"github_issue_reactions", []string{"repo_id", "issue_number", "is_pull_request", "reactions_total", "reactions"}, d.reactions},
+		{"github_issue_comments", []string{"repo_id", "id", "kind", "issue_number", "is_pull_request", "author_login", "author_association",
+			"body", "path", "in_reply_to_id", "created_at", "updated_at", "url", "reactions_total", "reactions"}, d.comments},
+		{"github_issue_label_events", []string{"repo_id", "id", "issue_number", "is_pull_request", "event", "label", "actor_login", "created_at"}, d.labelEvents},
+	}
+
+	for _, table := range tables {
+		r, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE repo_id = $1 AND is_pull_request = $2;", table.name), j.RepoID, pulls)
+		if err != nil {
+			return fmt.Errorf("delete rows: %w", err)
+		}
+
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{table.name}, table.columns, pgx.CopyFromRows(table.rows)); err != nil {
+			return fmt.Errorf("tx copy from: %w", err)
+		}
+
+		if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+			Type:            SyncLogTypeInfo,
+			RepoSyncQueueID: j.ID,
+			Message:         fmt.Sprintf("removed %d row(s) from %s", r.RowsAffected(), table.name),
+			Details:         rowDetails("removed", table.name, r.RowsAffected()),
+		}, {
+			Type:            SyncLogTypeInfo,
+			RepoSyncQueueID: j.ID,
+			Message:         fmt.Sprintf("inserted %d row(s) into %s", len(table.rows), table.name),
+			Details:         rowDetails("inserted", table.name, int64(len(table.rows))),
+		}}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// handleGitHubIssueResponseTimes derives the response times and label durations of the repo's issues and pull requests,
+// from their comments, reviews and label events
+func (w *worker) handleGitHubIssueResponseTimes(ctx context.Context, j *db.DequeueSyncJobRow) error {
+	return w.handleDerived(ctx, j, "derive_issue_response_times", "github_issue_response_times")
+}
@@ -0,0 +1,184 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/queries"
+	"golang.org/x/oauth2"
+)
+
+// insertAuditLogEntry appends an event to the audit log, events synced already (eg. by the sync of another repo of the org) are skipped
+const insertAuditLogEntry = `
+INSERT INTO github_org_audit_log (org, document_id, action, category, actor, user_login, repo, created_at, entry)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT DO NOTHING
+`
+
+// advanceAuditLogCheckpoint moves the checkpoint of the org forward (never backward, when syncs of several repos of the org overlap)
+const advanceAuditLogCheckpoint = `
+INSERT INTO mergestat.github_audit_log_checkpoints (org, last_event_at) VALUES ($1, $2)
+ON CONFLICT (org) DO UPDATE SET last_event_at = GREATEST(github_audit_log_checkpoints.last_event_at, EXCLUDED.last_event_at), updated_at = now()
+`
+
+// fetchGitHubAuditLog lists the audit log events of the org that occurred at or after since (all of the retained ones, if zero),
+// oldest first, paging through the log with its cursors. It returns ok = false if the org has no audit log api (it isn't on
+// GitHub Enterprise Cloud) or the token may not read it.
+func (w *worker) fetchGitHubAuditLog(ctx context.Context, client *github.Client, org string, since time.Time) (_ []*github.AuditEntry, ok bool, _ error) {
+	var opts = &github.GetAuditLogOptions{
+		Include:           github.String("all"),
+		Order:             github.String("asc"),
+		ListCursorOptions: github.ListCursorOptions{PerPage: 100},
+	}
+
+	if !since.IsZero() {
+		opts.Phrase = github.String("created:>=" + since.UTC().Format(time.RFC3339))
+	}
+
+	var entries []*github.AuditEntry
+	for {
+		page, resp, err := client.Organizations.GetAuditLog(ctx, org, opts)
+		if err != nil {
+			var errResponse *github.ErrorResponse
+			if errors.As(err, &errResponse) && (errResponse.Response.StatusCode == http.StatusNotFound || errResponse.Response.StatusCode == http.StatusForbidden) {
+				return nil, false, nil
+			}
+			return nil, false, fmt.Errorf("get audit log: %w", err)
+		}
+		helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+		entries = append(entries, page...)
+
+		if resp.After == "" {
+			break
+		}
+		opts.After = resp.After
+	}
+
+	return entries, true, nil
+}
+
+func (w *worker) handleGitHubOrgAuditLog(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var ghToken string
+	if _, ghToken, err = w.fetchCredentials(ctx, j); err != nil {
+		return err
+	}
+
+	if len(ghToken) <= 0 {
+		return errGitHubTokenRequired
+	}
+
+	var org string
+	if org, _, err = helper.GetRepoOwnerAndRepoName(j.Repo); err != nil {
+		return err
+	}
+
+	var since time.Time
+	if err = w.pool.QueryRow(ctx, "SELECT last_event_at FROM mergestat.github_audit_log_checkpoints WHERE org = $1", org).Scan(&since); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("read audit log checkpoint: %w", err)
+	}
+
+	var client = github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken})))
+
+	var entries []*github.AuditEntry
+	var ok bool
+	if entries, ok, err = w.fetchGitHubAuditLog(ctx, client, org, since); err != nil {
+		return err
+	}
+
+	if !ok {
+		l.Warn().Msgf("audit log of %s is not available (it requires GitHub Enterprise Cloud and the read:audit_log scope), skipping", org)
+	} else {
+		l.Info().Msgf("retrieved audit log events: %d", len(entries))
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("rollback transaction: %v", err)
+			}
+		}
+	}()
+
+	var batch = &pgx.Batch{}
+	var latest = since
+	for _, e := range entries {
+		var encoded []byte
+		if encoded, err = json.Marshal(e); err != nil {
+			return fmt.Errorf("encode audit log event: %w", err)
+		}
+
+		var createdAt interface{}
+		if e.Timestamp != nil {
+			createdAt = e.GetTimestamp().Time
+			if e.GetTimestamp().After(latest) {
+				latest = e.GetTimestamp().Time
+			}
+		}
+
+		var category, _, _ = strings.Cut(e.GetAction(), ".")
+		batch.Queue(insertAuditLogEntry, org, e.GetDocumentID(), e.GetAction(), category, e.GetActor(), e.GetUser(), e.GetRepo(), createdAt, encoded)
+	}
+
+	if !latest.IsZero() {
+		batch.Queue(advanceAuditLogCheckpoint, org, latest)
+	}
+
+	var inserted int64
+	var results = tx.SendBatch(ctx, batch)
+	for range entries {
+		r, err := results.Exec()
+		if err != nil {
+			_ = results.Close()
+			return fmt.Errorf("insert audit log event: %w", err)
+		}
+		inserted += r.RowsAffected()
+	}
+	if err := results.Close(); err != nil {
+		return fmt.Errorf("advance audit log checkpoint: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_org_audit_log", inserted),
+		Details:         rowDetails("inserted", "github_org_audit_log", inserted),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
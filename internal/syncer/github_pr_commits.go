@@ -118,7 +118,7 @@ func (w *worker) handleGitHubPRCommits(ctx context.Context, j *db.DequeueSyncJob
 	l.Info().Msgf("retrieved PR commits: %d", len(commits))
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -138,6 +138,7 @@ func (w *worker) handleGitHubPRCommits(ctx context.Context, j *db.DequeueSyncJob
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from github_pull_request_commits", r.RowsAffected()),
+		Details:         rowDetails("removed", "github_pull_request_commits", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -150,6 +151,7 @@ func (w *worker) handleGitHubPRCommits(ctx context.Context, j *db.DequeueSyncJob
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into github_pull_request_commits", len(commits)),
+		Details:         rowDetails("inserted", "github_pull_request_commits", int64(len(commits))),
 	}}); err != nil {
 		return err
 	}
@@ -165,5 +167,11 @@ func (w *worker) handleGitHubPRCommits(ctx context.Context, j *db.DequeueSyncJob
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	return tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "github_pull_request_commits", len(commits))
+
+	return nil
 }
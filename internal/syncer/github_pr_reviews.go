@@ -128,7 +128,7 @@ func (w *worker) handleGitHubPRReviews(ctx context.Context, j *db.DequeueSyncJob
 	}
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -148,6 +148,7 @@ func (w *worker) handleGitHubPRReviews(ctx context.Context, j *db.DequeueSyncJob
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from github_pull_request_reviews", r.RowsAffected()),
+		Details:         rowDetails("removed", "github_pull_request_reviews", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -162,6 +163,7 @@ func (w *worker) handleGitHubPRReviews(ctx context.Context, j *db.DequeueSyncJob
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into github_pull_request_reviews", len(reviews)),
+		Details:         rowDetails("inserted", "github_pull_request_reviews", int64(len(reviews))),
 	}}); err != nil {
 		return err
 	}
@@ -177,5 +179,11 @@ func (w *worker) handleGitHubPRReviews(ctx context.Context, j *db.DequeueSyncJob
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	return tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "github_pull_request_reviews", len(reviews))
+
+	return nil
 }
@@ -0,0 +1,348 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
+)
+
+// githubProjectFieldName is the name of the field a project item field value belongs to
+type githubProjectFieldName struct {
+	Common struct {
+		Name string
+	} `graphql:"... on ProjectV2FieldCommon"`
+}
+
+// githubProjectsQuery lists the projects linked to a repo, and their fields
+type githubProjectsQuery struct {
+	Repository struct {
+		ProjectsV2 struct {
+			Nodes []struct {
+				ID        string
+				Number    int
+				Title     string
+				URL       string
+				Closed    bool
+				CreatedAt time.Time
+				UpdatedAt time.Time
+				Fields    struct {
+					Nodes []struct {
+						Common struct {
+							ID       string
+							Name     string
+							DataType string
+						} `graphql:"... on ProjectV2FieldCommon"`
+						SingleSelect struct {
+							Options []struct {
+								ID   string `json:"id"`
+								Name string `json:"name"`
+							}
+						} `graphql:"... on ProjectV2SingleSelectField"`
+						Iteration struct {
+							Configuration struct {
+								Iterations []struct {
+									ID        string `json:"id"`
+									Title     string `json:"title"`
+									StartDate string `json:"start_date"`
+									Duration  int    `json:"duration"`
+								}
+							}
+						} `graphql:"... on ProjectV2IterationField"`
+					}
+				} `graphql:"fields(first: 100)"`
+			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   githubv4.String
+			}
+		} `graphql:"projectsV2(first: 20, after: $cursor)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// githubProjectItemsQuery lists the items of a project, with their content and field values
+type githubProjectItemsQuery struct {
+	Node struct {
+		Project struct {
+			Items struct {
+				Nodes []struct {
+					ID         string
+					Type       string
+					IsArchived bool
+					CreatedAt  time.Time
+					UpdatedAt  time.Time
+					Content    struct {
+						Typename string `graphql:"__typename"`
+						Issue    struct {
+							Number     int
+							URL        string
+							Title      string
+							Repository struct{ NameWithOwner string }
+						} `graphql:"... on Issue"`
+						PullRequest struct {
+							Number     int
+							URL        string
+							Title      string
+							Repository struct{ NameWithOwner string }
+						} `graphql:"... on PullRequest"`
+						DraftIssue struct {
+							Title string
+						} `graphql:"... on DraftIssue"`
+					}
+					FieldValues struct {
+						Nodes []struct {
+							Typename string `graphql:"__typename"`
+							Text     struct {
+								Text      string
+								UpdatedAt time.Time
+								Field     githubProjectFieldName
+							} `graphql:"... on ProjectV2ItemFieldTextValue"`
+							Number struct {
+								Number    float64
+								UpdatedAt time.Time
+								Field     githubProjectFieldName
+							} `graphql:"... on ProjectV2ItemFieldNumberValue"`
+							Date struct {
+								Date      string
+								UpdatedAt time.Time
+								Field     githubProjectFieldName
+							} `graphql:"... on ProjectV2ItemFieldDateValue"`
+							SingleSelect struct {
+								Name      string
+								UpdatedAt time.Time
+								Field     githubProjectFieldName
+							} `graphql:"... on ProjectV2ItemFieldSingleSelectValue"`
+							Iteration struct {
+								Title     string
+								StartDate string
+								Duration  int
+								UpdatedAt time.Time
+								Field     githubProjectFieldName
+							} `graphql:"... on ProjectV2ItemFieldIterationValue"`
+						}
+					} `graphql:"fieldValues(first: 50)"`
+				}
+				PageInfo struct {
+					HasNextPage bool
+					EndCursor   githubv4.String
+				}
+			} `graphql:"items(first: 100, after: $cursor)"`
+		} `graphql:"... on ProjectV2"`
+	} `graphql:"node(id: $id)"`
+}
+
+// githubProjectRows are the rows of the projects linked to a repo, for each of the github_project* tables
+type githubProjectRows struct {
+	projects, fields, items, values [][]interface{}
+}
+
+// parseProjectDate parses a date (without a time) returned by the GitHub api, nil if empty or invalid
+func parseProjectDate(s string) interface{} {
+	var t, err = time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+// fetchGitHubProjects lists the projects (Projects v2) linked to the repo, with their fields, items and item field values
+func (w *worker) fetchGitHubProjects(ctx context.Context, client *githubv4.Client, j *db.DequeueSyncJobRow, owner, name string) (*githubProjectRows, error) {
+	var rows = &githubProjectRows{}
+	var projectIDs []string
+
+	var vars = map[string]interface{}{"owner": githubv4.String(owner), "name": githubv4.String(name), "cursor": (*githubv4.String)(nil)}
+	for {
+		var q githubProjectsQuery
+		if err := client.Query(ctx, &q, vars); err != nil {
+			return nil, fmt.Errorf("query projects: %w", err)
+		}
+
+		for _, p := range q.Repository.ProjectsV2.Nodes {
+			projectIDs = append(projectIDs, p.ID)
+			rows.projects = append(rows.projects, []interface{}{j.RepoID, p.ID, p.Number, p.Title, p.URL, p.Closed, p.CreatedAt, p.UpdatedAt})
+
+			for _, f := range p.Fields.Nodes {
+				var options interface{} = []struct{}{}
+				switch f.Common.DataType {
+				case "SINGLE_SELECT":
+					options = f.SingleSelect.Options
+				case "ITERATION":
+					options = f.Iteration.Configuration.Iterations
+				}
+
+				var encoded, err = json.Marshal(options)
+				if err != nil {
+					return nil, fmt.Errorf("marshal field options: %w", err)
+				}
+				rows.fields = append(rows.fields, []interface{}{j.RepoID, p.ID, f.Common.ID, f.Common.Name, f.Common.DataType, encoded})
+			}
+		}
+
+		if !q.Repository.ProjectsV2.PageInfo.HasNextPage {
+			break
+		}
+		vars["cursor"] = githubv4.NewString(q.Repository.ProjectsV2.PageInfo.EndCursor)
+	}
+
+	for _, projectID := range projectIDs {
+		var vars = map[string]interface{}{"id": githubv4.ID(projectID), "cursor": (*githubv4.String)(nil)}
+		for {
+			var q githubProjectItemsQuery
+			if err := client.Query(ctx, &q, vars); err != nil {
+				return nil, fmt.Errorf("query items of project %s: %w", projectID, err)
+			}
+
+			for _, item := range q.Node.Project.Items.Nodes {
+				var repository, url, title interface{}
+				var number interface{}
+				switch item.Content.Typename {
+				case "Issue":
+					var c = item.Content.Issue
+					repository, number, url, title = c.Repository.NameWithOwner, c.Number, c.URL, c.Title
+				case "PullRequest":
+					var c = item.Content.PullRequest
+					repository, number, url, title = c.Repository.NameWithOwner, c.Number, c.URL, c.Title
+				case "DraftIssue":
+					title = item.Content.DraftIssue.Title
+				}
+
+				rows.items = append(rows.items, []interface{}{j.RepoID, projectID, item.ID, item.Type, repository, number, url, title,
+					item.IsArchived, item.CreatedAt, item.UpdatedAt})
+
+				for _, v := range item.FieldValues.Nodes {
+					var row []interface{}
+					switch v.Typename {
+					case "ProjectV2ItemFieldTextValue":
+						row = []interface{}{v.Text.Field.Common.Name, "TEXT", v.Text.Text, nil, nil, nil, v.Text.UpdatedAt}
+					case "ProjectV2ItemFieldNumberValue":
+						row = []interface{}{v.Number.Field.Common.Name, "NUMBER", nil, v.Number.Number, nil, nil, v.Number.UpdatedAt}
+					case "ProjectV2ItemFieldDateValue":
+						row = []interface{}{v.Date.Field.Common.Name, "DATE", nil, nil, parseProjectDate(v.Date.Date), nil, v.Date.UpdatedAt}
+					case "ProjectV2ItemFieldSingleSelectValue":
+						row = []interface{}{v.SingleSelect.Field.Common.Name, "SINGLE_SELECT", v.SingleSelect.Name, nil, nil, nil, v.SingleSelect.UpdatedAt}
+					case "ProjectV2ItemFieldIterationValue":
+						row = []interface{}{v.Iteration.Field.Common.Name, "ITERATION", v.Iteration.Title, nil,
+							parseProjectDate(v.Iteration.StartDate), v.Iteration.Duration, v.Iteration.UpdatedAt}
+					default:
+						// built-in fields (title, assignees, labels...) duplicate the content of the item
+						continue
+					}
+					rows.values = append(rows.values, append([]interface{}{j.RepoID, projectID, item.ID}, row...))
+				}
+			}
+
+			w.logger.Info().Msgf("fetched page of GitHub project items")
+
+			if !q.Node.Project.Items.PageInfo.HasNextPage {
+				break
+			}
+			vars["cursor"] = githubv4.NewString(q.Node.Project.Items.PageInfo.EndCursor)
+		}
+	}
+
+	return rows, nil
+}
+
+func (w *worker) handleGitHubProjects(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var ghToken string
+	if _, ghToken, err = w.fetchCredentials(ctx, j); err != nil {
+		return err
+	}
+
+	if len(ghToken) <= 0 {
+		return errGitHubTokenRequired
+	}
+
+	var owner, name string
+	if owner, name, err = helper.GetRepoOwnerAndRepoName(j.Repo); err != nil {
+		return err
+	}
+
+	var client = githubv4.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken})))
+
+	var rows *githubProjectRows
+	if rows, err = w.fetchGitHubProjects(ctx, client, j, owner, name); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("retrieved projects: %d", len(rows.projects))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("rollback transaction: %v", err)
+			}
+		}
+	}()
+
+	var tables = []struct {
+		name    string
+		columns []string
+		rows    [][]interface{}
+	}{
+		{"github_projects", []string{"repo_id", "project_id", "number", "title", "url", "closed", "created_at", "updated_at"}, rows.projects},
+		{"github_project_fields", []string{"repo_id", "project_id", "field_id", "name", "data_type", "options"}, rows.fields},
+		{"github_project_items", []string{"repo_id", "project_id", "item_id", "type", "content_repository", "content_number", "content_url",
+			"title", "is_archived", "created_at", "updated_at"}, rows.items},
+		{"github_project_item_field_values", []string{"repo_id", "project_id", "item_id", "field_name", "field_type", "text_value",
+			"number_value", "date_value", "iteration_duration", "updated_at"}, rows.values},
+	}
+
+	for _, table := range tables {
+		r, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE repo_id = $1;", table.name), j.RepoID)
+		if err != nil {
+			return fmt.Errorf("exec delete: %w", err)
+		}
+
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{table.name}, table.columns, pgx.CopyFromRows(table.rows)); err != nil {
+			return fmt.Errorf("tx copy from: %w", err)
+		}
+
+		if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+			Type:            SyncLogTypeInfo,
+			RepoSyncQueueID: j.ID,
+			Message:         fmt.Sprintf("removed %d row(s) from %s", r.RowsAffected(), table.name),
+			Details:         rowDetails("removed", table.name, r.RowsAffected()),
+		}, {
+			Type:            SyncLogTypeInfo,
+			RepoSyncQueueID: j.ID,
+			Message:         fmt.Sprintf("inserted %d row(s) into %s", len(table.rows), table.name),
+			Details:         rowDetails("inserted", table.name, int64(len(table.rows))),
+		}}); err != nil {
+			return err
+		}
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -17,7 +17,6 @@ import (
 	"github.com/mergestat/mergestat/internal/helper"
 	"github.com/mergestat/mergestat/queries"
 	uuid "github.com/satori/go.uuid"
-	"golang.org/x/oauth2"
 )
 
 func (w *worker) handleGitHubRepoPRsAndCommits(ctx context.Context, j *db.DequeueSyncJobRow) error {
@@ -32,14 +31,10 @@ func (w *worker) handleGitHubRepoPRsAndCommits(ctx context.Context, j *db.Dequeu
 	}
 
 	var ghToken string
-	if _, ghToken, err = w.fetchCredentials(ctx, j); err != nil {
+	if ghToken, err = w.githubToken(ctx, j); err != nil {
 		return err
 	}
 
-	if len(ghToken) <= 0 {
-		return errGitHubTokenRequired
-	}
-
 	id, err := uuid.FromString(j.RepoID.String())
 	if err != nil {
 		return fmt.Errorf("parse uuid: %w", err)
@@ -54,16 +49,7 @@ func (w *worker) handleGitHubRepoPRsAndCommits(ctx context.Context, j *db.Dequeu
 
 	prsToInsert := make([]*githubRepoPR, 0)
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: ghToken},
-	)
-
-	tc := oauth2.NewClient(ctx, ts)
-	if len(ghToken) <= 0 {
-		tc = nil
-	}
-
-	client := github.NewClient(tc)
+	client := github.NewClient(githubHTTPClient(ctx, ghToken))
 
 	var perPage = 50 // match the default used by mergestat-lite
 	if perPageEnv := os.Getenv("GITHUB_PER_PAGE"); perPageEnv != "" {
@@ -229,7 +215,7 @@ func (w *worker) handleGitHubRepoPRsAndCommits(ctx context.Context, j *db.Dequeu
 	}
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -250,6 +236,7 @@ func (w *worker) handleGitHubRepoPRsAndCommits(ctx context.Context, j *db.Dequeu
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from github_pull_requests", r.RowsAffected()),
+		Details:         rowDetails("removed", "github_pull_requests", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -263,6 +250,7 @@ func (w *worker) handleGitHubRepoPRsAndCommits(ctx context.Context, j *db.Dequeu
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from github_pull_request_commits", r.RowsAffected()),
+		Details:         rowDetails("removed", "github_pull_request_commits", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -278,6 +266,7 @@ func (w *worker) handleGitHubRepoPRsAndCommits(ctx context.Context, j *db.Dequeu
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into github_pull_requests", len(prsToInsert)),
+		Details:         rowDetails("inserted", "github_pull_requests", int64(len(prsToInsert))),
 	}}); err != nil {
 		return err
 	}
@@ -290,6 +279,7 @@ func (w *worker) handleGitHubRepoPRsAndCommits(ctx context.Context, j *db.Dequeu
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into github_pull_request_commits", len(allPRCommitsToInsert)),
+		Details:         rowDetails("inserted", "github_pull_request_commits", int64(len(allPRCommitsToInsert))),
 	}}); err != nil {
 		return err
 	}
@@ -305,5 +295,12 @@ func (w *worker) handleGitHubRepoPRsAndCommits(ctx context.Context, j *db.Dequeu
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	return tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "github_pull_requests", len(prsToInsert))
+	w.reconcileRowCount(ctx, j, "github_pull_request_commits", len(allPRCommitsToInsert))
+
+	return nil
 }
@@ -152,8 +152,13 @@ func (w *worker) handleGitHubRepoIssues(ctx context.Context, j *db.DequeueSyncJo
 		return fmt.Errorf("mergestat query: %w", err)
 	}
 
+	discussion, err := w.fetchGitHubDiscussion(ctx, ghToken, j.RepoID, repoOwner, repoName, false)
+	if err != nil {
+		return fmt.Errorf("fetch comments and reactions: %w", err)
+	}
+
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -173,6 +178,7 @@ func (w *worker) handleGitHubRepoIssues(ctx context.Context, j *db.DequeueSyncJo
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from github_issues", r.RowsAffected()),
+		Details:         rowDetails("removed", "github_issues", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -187,10 +193,15 @@ func (w *worker) handleGitHubRepoIssues(ctx context.Context, j *db.DequeueSyncJo
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into github_issues", len(issues)),
+		Details:         rowDetails("inserted", "github_issues", int64(len(issues))),
 	}}); err != nil {
 		return err
 	}
 
+	if err := w.sendBatchGitHubDiscussion(ctx, tx, j, discussion, false); err != nil {
+		return fmt.Errorf("insert comments and reactions: %w", err)
+	}
+
 	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
 		return fmt.Errorf("sync job done: %w", err)
 	}
@@ -202,5 +213,11 @@ func (w *worker) handleGitHubRepoIssues(ctx context.Context, j *db.DequeueSyncJo
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	return tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "github_issues", len(issues))
+
+	return nil
 }
@@ -14,7 +14,6 @@ import (
 	"github.com/mergestat/mergestat/internal/db"
 	"github.com/mergestat/mergestat/internal/helper"
 	"github.com/mergestat/mergestat/queries"
-	"golang.org/x/oauth2"
 )
 
 func (w *worker) handleGitHubRepoMetadata(ctx context.Context, j *db.DequeueSyncJobRow) error {
@@ -29,14 +28,10 @@ func (w *worker) handleGitHubRepoMetadata(ctx context.Context, j *db.DequeueSync
 	}
 
 	var ghToken string
-	if _, ghToken, err = w.fetchCredentials(ctx, j); err != nil {
+	if ghToken, err = w.githubToken(ctx, j); err != nil {
 		return err
 	}
 
-	if len(ghToken) <= 0 {
-		return errGitHubTokenRequired
-	}
-
 	var u *url.URL
 	if u, err = url.Parse(j.Repo); err != nil {
 		return fmt.Errorf("could not parse repo: %v", err)
@@ -59,7 +54,7 @@ func (w *worker) handleGitHubRepoMetadata(ctx context.Context, j *db.DequeueSync
 	l.Info().Msgf("retrieved repo info as JSON")
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return err
 	}
 	defer func() {
@@ -226,16 +221,7 @@ func (w *worker) getRepositoryInfo(ctx context.Context, ghToken string, currentR
 		resp          *github.Response
 	)
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: ghToken},
-	)
-
-	tc := oauth2.NewClient(ctx, ts)
-	if len(ghToken) <= 0 {
-		tc = nil
-	}
-
-	client := github.NewClient(tc)
+	client := github.NewClient(githubHTTPClient(ctx, ghToken))
 
 	if len(ghToken) > 0 {
 		// we check the rate limit before any call to the GitHub API
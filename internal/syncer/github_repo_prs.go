@@ -203,8 +203,13 @@ func (w *worker) handleGitHubRepoPRs(ctx context.Context, j *db.DequeueSyncJobRo
 		return fmt.Errorf("mergestat query: %w", err)
 	}
 
+	discussion, err := w.fetchGitHubDiscussion(ctx, ghToken, j.RepoID, repoOwner, repoName, true)
+	if err != nil {
+		return fmt.Errorf("fetch comments and reactions: %w", err)
+	}
+
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -224,6 +229,7 @@ func (w *worker) handleGitHubRepoPRs(ctx context.Context, j *db.DequeueSyncJobRo
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from github_pull_requests", r.RowsAffected()),
+		Details:         rowDetails("removed", "github_pull_requests", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
@@ -238,10 +244,15 @@ func (w *worker) handleGitHubRepoPRs(ctx context.Context, j *db.DequeueSyncJobRo
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("inserted %d row(s) into github_pull_requests", len(prs)),
+		Details:         rowDetails("inserted", "github_pull_requests", int64(len(prs))),
 	}}); err != nil {
 		return err
 	}
 
+	if err := w.sendBatchGitHubDiscussion(ctx, tx, j, discussion, true); err != nil {
+		return fmt.Errorf("insert comments and reactions: %w", err)
+	}
+
 	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
 		return fmt.Errorf("sync job done: %w", err)
 	}
@@ -253,5 +264,11 @@ func (w *worker) handleGitHubRepoPRs(ctx context.Context, j *db.DequeueSyncJobRo
 		return fmt.Errorf("send batch log messages: %w", err)
 	}
 
-	return tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "github_pull_requests", len(prs))
+
+	return nil
 }
@@ -0,0 +1,174 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"golang.org/x/oauth2"
+)
+
+// githubRepoTeam is a GitHub team with access to a repo, and its members
+type githubRepoTeam struct {
+	team    *github.Team
+	members []string
+}
+
+// fetchGitHubRepoTeams lists the teams with access to the repo, and the members of each team
+func fetchGitHubRepoTeams(ctx context.Context, client *github.Client, owner, name string) ([]*githubRepoTeam, error) {
+	var teams []*githubRepoTeam
+
+	var opts = &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := client.Repositories.ListTeams(ctx, owner, name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("list teams: %w", err)
+		}
+
+		for _, team := range page {
+			teams = append(teams, &githubRepoTeam{team: team})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	for _, t := range teams {
+		var memberOpts = &github.TeamListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			page, resp, err := client.Teams.ListTeamMembersBySlug(ctx, t.team.GetOrganization().GetLogin(), t.team.GetSlug(), memberOpts)
+			if err != nil {
+				return nil, fmt.Errorf("list members of team %s: %w", t.team.GetSlug(), err)
+			}
+
+			for _, member := range page {
+				t.members = append(t.members, member.GetLogin())
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			memberOpts.Page = resp.NextPage
+		}
+	}
+
+	return teams, nil
+}
+
+func (w *worker) handleGitHubRepoTeams(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var ghToken string
+	if _, ghToken, err = w.fetchCredentials(ctx, j); err != nil {
+		return err
+	}
+
+	if len(ghToken) <= 0 {
+		return errGitHubTokenRequired
+	}
+
+	var owner, name string
+	if owner, name, err = helper.GetRepoOwnerAndRepoName(j.Repo); err != nil {
+		return err
+	}
+
+	var client = github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken})))
+
+	var teams []*githubRepoTeam
+	if teams, err = fetchGitHubRepoTeams(ctx, client, owner, name); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("retrieved repo teams: %d", len(teams))
+
+	var teamRows, memberRows [][]interface{}
+	for _, t := range teams {
+		teamRows = append(teamRows, []interface{}{j.RepoID, t.team.GetID(), t.team.GetOrganization().GetLogin(), t.team.GetSlug(), t.team.GetName(), t.team.GetPermission()})
+		for _, login := range t.members {
+			memberRows = append(memberRows, []interface{}{j.RepoID, t.team.GetID(), login})
+		}
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("rollback transaction: %v", err)
+			}
+		}
+	}()
+
+	for _, table := range []string{"github_repo_team_members", "github_repo_teams"} {
+		r, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE repo_id = $1;", table), j.RepoID)
+		if err != nil {
+			return fmt.Errorf("exec delete: %w", err)
+		}
+
+		if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+			Type:            SyncLogTypeInfo,
+			RepoSyncQueueID: j.ID,
+			Message:         fmt.Sprintf("removed %d row(s) from %s", r.RowsAffected(), table),
+			Details:         rowDetails("removed", table, r.RowsAffected()),
+		}}); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"github_repo_teams"}, []string{"repo_id", "team_id", "org", "slug", "name", "permission"}, pgx.CopyFromRows(teamRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"github_repo_team_members"}, []string{"repo_id", "team_id", "login"}, pgx.CopyFromRows(memberRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_repo_teams", len(teamRows)),
+		Details:         rowDetails("inserted", "github_repo_teams", int64(len(teamRows))),
+	}, {
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_repo_team_members", len(memberRows)),
+		Details:         rowDetails("inserted", "github_repo_team_members", int64(len(memberRows))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "github_repo_teams", len(teamRows))
+	w.reconcileRowCount(ctx, j, "github_repo_team_members", len(memberRows))
+
+	return nil
+}
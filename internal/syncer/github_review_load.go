@@ -0,0 +1,12 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// handleGitHubReviewLoad derives the weekly review counts, response latency and review depth of each reviewer of the repo
+func (w *worker) handleGitHubReviewLoad(ctx context.Context, j *db.DequeueSyncJobRow) error {
+	return w.handleDerived(ctx, j, "derive_review_load", "github_reviewer_weekly_load")
+}
@@ -0,0 +1,175 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/queries"
+	"golang.org/x/oauth2"
+)
+
+// githubRunner is a self-hosted runner available to a repo, and the scope (REPO or ORG) it's registered at
+type githubRunner struct {
+	scope  string
+	runner *github.Runner
+}
+
+// fetchGitHubRunners lists the self-hosted runners registered to the repo and, if the token may list them, to the repo's organization
+func (w *worker) fetchGitHubRunners(ctx context.Context, client *github.Client, owner, name string) ([]*githubRunner, error) {
+	var runners []*githubRunner
+
+	var list = func(scope string, fn func(*github.ListOptions) (*github.Runners, *github.Response, error)) error {
+		var opts = &github.ListOptions{PerPage: 100}
+		for {
+			page, resp, err := fn(opts)
+			if err != nil {
+				return err
+			}
+			helper.RestRatelimitHandler(ctx, resp, w.logger, queries.NewQuerier(w.db), true)
+
+			for _, r := range page.Runners {
+				runners = append(runners, &githubRunner{scope: scope, runner: r})
+			}
+
+			if resp.NextPage == 0 {
+				return nil
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+
+	if err := list("REPO", func(opts *github.ListOptions) (*github.Runners, *github.Response, error) {
+		return client.Actions.ListRunners(ctx, owner, name, opts)
+	}); err != nil {
+		return nil, fmt.Errorf("list repo runners: %w", err)
+	}
+
+	// the owner may be a user (which has no runners of its own) or the token may lack the admin:org scope
+	if err := list("ORG", func(opts *github.ListOptions) (*github.Runners, *github.Response, error) {
+		return client.Actions.ListOrganizationRunners(ctx, owner, opts)
+	}); err != nil {
+		var errResponse *github.ErrorResponse
+		if !errors.As(err, &errResponse) || (errResponse.Response.StatusCode != http.StatusNotFound && errResponse.Response.StatusCode != http.StatusForbidden) {
+			return nil, fmt.Errorf("list organization runners: %w", err)
+		}
+		w.logger.Warn().Msgf("could not list runners of organization %s, skipping: %v", owner, err)
+	}
+
+	return runners, nil
+}
+
+func (w *worker) handleGitHubRunners(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var ghToken string
+	if _, ghToken, err = w.fetchCredentials(ctx, j); err != nil {
+		return err
+	}
+
+	if len(ghToken) <= 0 {
+		return errGitHubTokenRequired
+	}
+
+	var owner, name string
+	if owner, name, err = helper.GetRepoOwnerAndRepoName(j.Repo); err != nil {
+		return err
+	}
+
+	var client = github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken})))
+
+	var runners []*githubRunner
+	if runners, err = w.fetchGitHubRunners(ctx, client, owner, name); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("retrieved runners: %d", len(runners))
+
+	var observedAt = time.Now()
+	var runnerRows, snapshotRows [][]interface{}
+	for _, r := range runners {
+		var labels = make([]string, 0, len(r.runner.Labels))
+		for _, label := range r.runner.Labels {
+			labels = append(labels, label.GetName())
+		}
+
+		runnerRows = append(runnerRows, []interface{}{j.RepoID, r.scope, owner, r.runner.GetID(), r.runner.GetName(), r.runner.GetOS(), r.runner.GetStatus(), r.runner.GetBusy(), labels})
+		snapshotRows = append(snapshotRows, []interface{}{j.RepoID, r.scope, r.runner.GetID(), observedAt, r.runner.GetName(), r.runner.GetStatus(), r.runner.GetBusy(), labels})
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("rollback transaction: %v", err)
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM github_runners WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"github_runners"}, []string{"repo_id", "scope", "owner", "runner_id", "name", "os", "status", "busy", "labels"}, pgx.CopyFromRows(runnerRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"github_runner_snapshots"}, []string{"repo_id", "scope", "runner_id", "observed_at", "name", "status", "busy", "labels"}, pgx.CopyFromRows(snapshotRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from github_runners", r.RowsAffected()),
+		Details:         rowDetails("removed", "github_runners", r.RowsAffected()),
+	}, {
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_runners", len(runnerRows)),
+		Details:         rowDetails("inserted", "github_runners", int64(len(runnerRows))),
+	}, {
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into github_runner_snapshots", len(snapshotRows)),
+		Details:         rowDetails("inserted", "github_runner_snapshots", int64(len(snapshotRows))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "github_runners", len(runnerRows))
+
+	return nil
+}
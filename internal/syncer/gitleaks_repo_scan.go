@@ -55,7 +55,7 @@ func (w *worker) handleGitleaksRepoScan(ctx context.Context, j *db.DequeueSyncJo
 	}
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -75,6 +75,7 @@ func (w *worker) handleGitleaksRepoScan(ctx context.Context, j *db.DequeueSyncJo
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from gitleaks_repo_scans", r.RowsAffected()),
+		Details:         rowDetails("removed", "gitleaks_repo_scans", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
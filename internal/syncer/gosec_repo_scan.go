@@ -87,7 +87,7 @@ func (w *worker) handleGosecRepoScan(ctx context.Context, j *db.DequeueSyncJobRo
 	}
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -98,7 +98,7 @@ func (w *worker) handleGosecRepoScan(ctx context.Context, j *db.DequeueSyncJobRo
 		}
 	}()
 
-	r, err := tx.Exec(ctx, "DELETE FROM public.gosec_repo_scans WHERE repo_id = $1;", j.RepoID.String())
+	r, err := tx.Exec(ctx, "DELETE FROM gosec_repo_scans WHERE repo_id = $1;", j.RepoID.String())
 	if err != nil {
 		return fmt.Errorf("exec delete: %w", err)
 	}
@@ -107,11 +107,12 @@ func (w *worker) handleGosecRepoScan(ctx context.Context, j *db.DequeueSyncJobRo
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from gosec_repo_scans", r.RowsAffected()),
+		Details:         rowDetails("removed", "gosec_repo_scans", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
 
-	if _, err := tx.Exec(ctx, "INSERT INTO public.gosec_repo_scans (repo_id, issues) VALUES ($1, $2)", j.RepoID, stdout.Bytes()); err != nil {
+	if _, err := tx.Exec(ctx, "INSERT INTO gosec_repo_scans (repo_id, issues) VALUES ($1, $2)", j.RepoID, stdout.Bytes()); err != nil {
 		return fmt.Errorf("inserting gosec results: %w", err)
 	}
 
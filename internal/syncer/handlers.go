@@ -2,9 +2,12 @@ package syncer
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"time"
 
-	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgconn"
+
 	"github.com/mergestat/mergestat/internal/db"
 	"github.com/rs/zerolog"
 )
@@ -26,30 +29,84 @@ const (
 
 	// LogFormatErrorWarningMessage is for formatting a warning message when an error was encountered during a repo sync
 	LogFormatErrorWarningMessage = "warning: %s (%v)"
+
+	// LogFormatSuppressedLogs is the message summarizing the similar logs of a job that were suppressed (see WithLogSampling)
+	LogFormatSuppressedLogs = "suppressed %d more log(s) like: %s"
 )
 
+// logDetails are the structured fields of a sync log (stored as jsonb in repo_sync_logs.details),
+// so that logs can be queried without parsing their message
+type logDetails map[string]interface{}
+
 type syncLog struct {
 	Type            syncLogType
 	Message         string
 	RepoSyncQueueID int64
+	Details         logDetails
+
+	// Kind, if set, groups logs whose messages differ (eg. the per-file warnings of a sync) for sampling
+	Kind string
 }
 
-// sendBatchLogMessages uses the pg COPY protocol to send a batch of sync logs
-func (w *worker) sendBatchLogMessages(ctx context.Context, batch []*syncLog) error {
-	inputs := make([][]interface{}, 0, len(batch))
-	for _, l := range batch {
-		input := []interface{}{l.Type, l.Message, l.RepoSyncQueueID}
-		inputs = append(inputs, input)
+// rowDetails returns the details of a log of rows written to (or removed from) a table
+func rowDetails(operation, table string, rows int64) logDetails {
+	return logDetails{"operation": operation, "table": table, "rows": rows}
+}
+
+// errorDetails returns the details of a log of an error, with the code of the error
+// when it's one returned by postgres
+func errorDetails(err error) logDetails {
+	var details = logDetails{"error": err.Error()}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		details["error_code"] = pgErr.Code
+	}
+	return details
+}
+
+// encode returns the details encoded as json, or nil if there are none
+func (d logDetails) encode() []byte {
+	if len(d) == 0 {
+		return nil
 	}
 
-	if _, err := w.pool.CopyFrom(ctx, pgx.Identifier{"mergestat", "repo_sync_logs"}, []string{"log_type", "message", "repo_sync_queue_id"}, pgx.CopyFromRows(inputs)); err != nil {
-		return err
+	var encoded, err = json.Marshal(d)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+// sendBatchLogMessages buffers a batch of sync logs, which are written (using the pg COPY protocol) in the background
+func (w *worker) sendBatchLogMessages(ctx context.Context, batch []*syncLog) error {
+	// when running outside the queue (see Doctor) there is no repo_sync_queue row
+	// to attach the logs to, so we only send them to the worker log
+	if w.localLogs {
+		for _, l := range batch {
+			w.logger.Info().Str("log-type", string(l.Type)).Fields(map[string]interface{}(l.Details)).Msg(l.Message)
+		}
+		return nil
 	}
+
+	w.logs.add(batch)
 	return nil
 }
 
+// WithLogSampling sets, by log type (INFO, WARNING or ERROR), the number of similar logs a job writes before
+// the following ones are suppressed. Suppressed logs are summarized by a single entry, with their count, once
+// the job ends. A limit of zero (or less) never suppresses logs of the type.
+func (w *worker) WithLogSampling(limits map[string]int) *worker {
+	var sampling = make(map[syncLogType]int)
+	for logType, limit := range limits {
+		sampling[syncLogType(logType)] = limit
+	}
+	w.logs.sampling = sampling
+	return w
+}
+
 func (w *worker) loggerForJob(j *db.DequeueSyncJobRow) *zerolog.Logger {
-	l := w.logger.With().Str("job-type", j.SyncType).Str("repo", j.Repo).Logger()
+	l := w.logger.With().Int64("job-id", j.ID).Str("job-type", j.SyncType).Str("repo", j.Repo).Str("repo-id", j.RepoID.String()).Logger()
 	return &l
 }
 
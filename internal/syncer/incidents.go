@@ -0,0 +1,329 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+const (
+	// incidentsLookback is how far back the first INCIDENTS sync of a repo imports incidents from
+	incidentsLookback = 365 * 24 * time.Hour
+
+	// pagerDutyMaxRange is the longest range of time PagerDuty lists incidents for at once
+	pagerDutyMaxRange = 180 * 24 * time.Hour
+
+	// incidentsPageSize is the number of incidents fetched per request, from either provider
+	incidentsPageSize = 100
+
+	// defaultOpsgenieURL is the url of the Opsgenie api, unless set with the OPSGENIE_API_URL env var (eg. for the EU instance)
+	defaultOpsgenieURL = "https://api.opsgenie.com"
+)
+
+// incident is an incident fetched from PagerDuty or Opsgenie
+type incident struct {
+	id, number, title, status, severity string
+	serviceID, serviceName, url         string
+	createdAt                           time.Time
+	resolvedAt                          *time.Time
+}
+
+// selectIncidentsSince returns the time incidents of the repo should be fetched from: the oldest incident that
+// isn't resolved yet (so that its resolution is picked up), or else the latest incident
+const selectIncidentsSince = `
+SELECT COALESCE(MIN(created_at) FILTER (WHERE resolved_at IS NULL), MAX(created_at)) FROM incidents
+WHERE repo_id = $1 AND provider = $2
+`
+
+const upsertIncident = `
+INSERT INTO incidents (repo_id, provider, incident_id, number, title, status, severity, service_id, service_name, url, created_at, resolved_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+ON CONFLICT (repo_id, provider, incident_id) DO UPDATE SET number = EXCLUDED.number, title = EXCLUDED.title,
+    status = EXCLUDED.status, severity = EXCLUDED.severity, service_id = EXCLUDED.service_id, service_name = EXCLUDED.service_name,
+    url = EXCLUDED.url, resolved_at = EXCLUDED.resolved_at, _mergestat_synced_at = now()`
+
+// getIncidentsJSON decodes the response of a GET request to an incident provider's api
+func getIncidentsJSON(ctx context.Context, u string, header http.Header, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// fetchPagerDutyIncidents fetches the incidents of the given PagerDuty services opened since the given time,
+// authenticating with the PAGERDUTY_API_TOKEN env var
+func fetchPagerDutyIncidents(ctx context.Context, services []string, since time.Time) ([]*incident, error) {
+	var token = os.Getenv("PAGERDUTY_API_TOKEN")
+	if token == "" {
+		return nil, errors.New("the PAGERDUTY_API_TOKEN env var is required to import incidents from PagerDuty")
+	}
+
+	var header = http.Header{}
+	header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+	header.Set("Authorization", "Token token="+token)
+
+	var incidents []*incident
+	for _, window := range helper.SplitTimeRange(since, time.Now(), pagerDutyMaxRange) {
+		for offset := 0; ; offset += incidentsPageSize {
+			var query = url.Values{}
+			query["service_ids[]"] = services
+			query.Set("since", window.Since.UTC().Format(time.RFC3339))
+			query.Set("until", window.Until.UTC().Format(time.RFC3339))
+			query.Set("limit", fmt.Sprint(incidentsPageSize))
+			query.Set("offset", fmt.Sprint(offset))
+			query.Set("time_zone", "UTC")
+
+			var result struct {
+				Incidents []struct {
+					ID                 string     `json:"id"`
+					Number             int        `json:"incident_number"`
+					Title              string     `json:"title"`
+					Status             string     `json:"status"`
+					Urgency            string     `json:"urgency"`
+					HTMLURL            string     `json:"html_url"`
+					CreatedAt          time.Time  `json:"created_at"`
+					ResolvedAt         *time.Time `json:"resolved_at"`
+					LastStatusChangeAt *time.Time `json:"last_status_change_at"`
+					Service            struct {
+						ID      string `json:"id"`
+						Summary string `json:"summary"`
+					} `json:"service"`
+				} `json:"incidents"`
+				More bool `json:"more"`
+			}
+			if err := getIncidentsJSON(ctx, "https://api.pagerduty.com/incidents?"+query.Encode(), header, &result); err != nil {
+				return nil, err
+			}
+
+			for _, i := range result.Incidents {
+				var status = helper.IncidentStatus(i.Status)
+
+				// the status of a resolved incident last changed when it was resolved
+				var resolvedAt = i.ResolvedAt
+				if status == "RESOLVED" && resolvedAt == nil {
+					resolvedAt = i.LastStatusChangeAt
+				} else if status != "RESOLVED" {
+					resolvedAt = nil
+				}
+
+				incidents = append(incidents, &incident{id: i.ID, number: fmt.Sprint(i.Number), title: i.Title, status: status,
+					severity: i.Urgency, serviceID: i.Service.ID, serviceName: i.Service.Summary, url: i.HTMLURL,
+					createdAt: i.CreatedAt, resolvedAt: resolvedAt})
+			}
+
+			if !result.More {
+				break
+			}
+		}
+	}
+
+	return incidents, nil
+}
+
+// fetchOpsgenieIncidents fetches the incidents impacting the given Opsgenie services opened since the given time,
+// authenticating with the OPSGENIE_API_KEY env var. Opsgenie doesn't report when an incident was resolved, so the
+// last update of a resolved (or closed) incident is taken as its resolution.
+func fetchOpsgenieIncidents(ctx context.Context, services []string, since time.Time) ([]*incident, error) {
+	var key = os.Getenv("OPSGENIE_API_KEY")
+	if key == "" {
+		return nil, errors.New("the OPSGENIE_API_KEY env var is required to import incidents from Opsgenie")
+	}
+
+	var baseURL = defaultOpsgenieURL
+	if u := os.Getenv("OPSGENIE_API_URL"); u != "" {
+		baseURL = strings.TrimSuffix(u, "/")
+	}
+
+	var header = http.Header{}
+	header.Set("Accept", "application/json")
+	header.Set("Authorization", "GenieKey "+key)
+
+	var wanted = make(map[string]bool, len(services))
+	for _, s := range services {
+		wanted[s] = true
+	}
+
+	// incidents are listed most recent first, until they're older than since
+	var incidents []*incident
+	for offset := 0; ; offset += incidentsPageSize {
+		var query = url.Values{}
+		query.Set("sort", "createdAt")
+		query.Set("order", "desc")
+		query.Set("limit", fmt.Sprint(incidentsPageSize))
+		query.Set("offset", fmt.Sprint(offset))
+
+		var result struct {
+			Data []struct {
+				ID               string    `json:"id"`
+				TinyID           string    `json:"tinyId"`
+				Message          string    `json:"message"`
+				Status           string    `json:"status"`
+				Priority         string    `json:"priority"`
+				CreatedAt        time.Time `json:"createdAt"`
+				UpdatedAt        time.Time `json:"updatedAt"`
+				ImpactedServices []string  `json:"impactedServices"`
+				Links            struct {
+					Web string `json:"web"`
+				} `json:"links"`
+			} `json:"data"`
+		}
+		if err := getIncidentsJSON(ctx, baseURL+"/v1/incidents?"+query.Encode(), header, &result); err != nil {
+			return nil, err
+		}
+
+		for _, i := range result.Data {
+			if i.CreatedAt.Before(since) {
+				return incidents, nil
+			}
+
+			var service string
+			for _, s := range i.ImpactedServices {
+				if wanted[s] {
+					service = s
+					break
+				}
+			}
+			if service == "" {
+				continue
+			}
+
+			var status = helper.IncidentStatus(i.Status)
+			var resolvedAt *time.Time
+			if status == "RESOLVED" {
+				var updatedAt = i.UpdatedAt
+				resolvedAt = &updatedAt
+			}
+
+			incidents = append(incidents, &incident{id: i.ID, number: i.TinyID, title: i.Message, status: status,
+				severity: i.Priority, serviceID: service, url: i.Links.Web, createdAt: i.CreatedAt, resolvedAt: resolvedAt})
+		}
+
+		if len(result.Data) < incidentsPageSize {
+			return incidents, nil
+		}
+	}
+}
+
+func (w *worker) handleIncidents(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	if len(settings.IncidentServices) == 0 {
+		return errors.New("the incidentServices setting is required to import incidents")
+	}
+
+	var provider = strings.ToUpper(settings.IncidentProvider)
+
+	var since *time.Time
+	if err = w.pool.QueryRow(ctx, selectIncidentsSince, j.RepoID, provider).Scan(&since); err != nil {
+		return fmt.Errorf("query latest incident: %w", err)
+	}
+	if since == nil {
+		var start = time.Now().Add(-incidentsLookback)
+		since = &start
+	}
+
+	var incidents []*incident
+	switch provider {
+	case "PAGERDUTY":
+		incidents, err = fetchPagerDutyIncidents(ctx, settings.IncidentServices, *since)
+	case "OPSGENIE":
+		incidents, err = fetchOpsgenieIncidents(ctx, settings.IncidentServices, *since)
+	default:
+		return fmt.Errorf("unknown incident provider %q, expected pagerduty or opsgenie", settings.IncidentProvider)
+	}
+	if err != nil {
+		return fmt.Errorf("fetch incidents: %w", err)
+	}
+
+	l.Info().Msgf("found %d incident(s) since %s", len(incidents), since.Format(time.RFC3339))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	// incidents older than since are kept, the ones fetched again (eg. now resolved) are updated
+	var batch = &pgx.Batch{}
+	for _, i := range incidents {
+		batch.Queue(upsertIncident, j.RepoID, provider, i.id, nullIfEmpty(i.number), nullIfEmpty(i.title), i.status, nullIfEmpty(i.severity),
+			nullIfEmpty(i.serviceID), nullIfEmpty(i.serviceName), nullIfEmpty(i.url), i.createdAt, i.resolvedAt)
+	}
+
+	var results = tx.SendBatch(ctx, batch)
+	for range incidents {
+		if _, err := results.Exec(); err != nil {
+			_ = results.Close()
+			return fmt.Errorf("upsert incident: %w", err)
+		}
+	}
+	if err := results.Close(); err != nil {
+		return fmt.Errorf("upsert incidents: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("upserted %d row(s) into incidents", len(incidents)),
+		Details:         rowDetails("upserted", "incidents", int64(len(incidents))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
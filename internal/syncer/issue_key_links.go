@@ -0,0 +1,301 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// defaultIssueKeyPattern matches Jira style issue keys, eg. ABC-123
+const defaultIssueKeyPattern = `\b[A-Z][A-Z0-9]+-[0-9]+\b`
+
+// jiraBatchSize is the number of issues fetched from Jira per request
+const jiraBatchSize = 100
+
+// issueKeyLink is an issue key found in a commit message or a pull request
+type issueKeyLink struct {
+	source   string
+	commit   *string
+	pr       *int32
+	issueKey string
+}
+
+// jiraIssue is an issue fetched from the Jira API
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary string `json:"summary"`
+		Status  struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		IssueType struct {
+			Name string `json:"name"`
+		} `json:"issuetype"`
+	} `json:"fields"`
+}
+
+// extractIssueKeys returns the distinct issue keys matched by pattern in text, in order of appearance
+func extractIssueKeys(pattern *regexp.Regexp, text string) []string {
+	var keys []string
+	var seen = make(map[string]struct{})
+	for _, key := range pattern.FindAllString(text, -1) {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// fetchIssueKeyLinks scans the (already synced) commits and pull requests of the repo for issue keys
+func (w *worker) fetchIssueKeyLinks(ctx context.Context, j *db.DequeueSyncJobRow, pattern *regexp.Regexp) (_ []*issueKeyLink, err error) {
+	var links []*issueKeyLink
+
+	var d *decrypter
+	if d, err = w.decrypter(ctx); err != nil {
+		return nil, err
+	}
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, "SELECT hash, COALESCE(message, '') FROM git_commits WHERE repo_id = $1", j.RepoID); err != nil {
+		return nil, fmt.Errorf("query commits: %w", err)
+	}
+	for rows.Next() {
+		var hash, message string
+		if err = rows.Scan(&hash, &message); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if err = d.decrypt("git_commits", "message", &message); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		for _, key := range extractIssueKeys(pattern, message) {
+			var hash = hash
+			links = append(links, &issueKeyLink{source: "COMMIT", commit: &hash, issueKey: key})
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	const selectPRs = "SELECT number, COALESCE(title, ''), COALESCE(body, '') FROM github_pull_requests WHERE repo_id = $1"
+	if rows, err = w.pool.Query(ctx, selectPRs, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query pull requests: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var number int32
+		var title, body string
+		if err = rows.Scan(&number, &title, &body); err != nil {
+			return nil, err
+		}
+		if err = d.decrypt("github_pull_requests", "title", &title); err != nil {
+			return nil, err
+		}
+		if err = d.decrypt("github_pull_requests", "body", &body); err != nil {
+			return nil, err
+		}
+		for _, key := range extractIssueKeys(pattern, title+"\n"+body) {
+			var number = number
+			links = append(links, &issueKeyLink{source: "PULL_REQUEST", pr: &number, issueKey: key})
+		}
+	}
+
+	return links, rows.Err()
+}
+
+// fetchJiraIssues fetches the issues with the given keys from the Jira instance at baseURL, authenticating
+// with the JIRA_EMAIL and JIRA_API_TOKEN env vars (if set). Keys that don't exist (or aren't visible) are skipped.
+// The url is configured with the JIRA_URL env var, next to the credentials, rather than in the (user editable)
+// settings of the sync, which could send the credentials anywhere.
+func fetchJiraIssues(ctx context.Context, baseURL string, keys []string) ([]*jiraIssue, error) {
+	var issues []*jiraIssue
+	for start := 0; start < len(keys); start += jiraBatchSize {
+		var end = start + jiraBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		var batch = keys[start:end]
+
+		var query = url.Values{}
+		query.Set("jql", fmt.Sprintf("key in (%s)", strings.Join(batch, ",")))
+		query.Set("fields", "summary,status,issuetype")
+		query.Set("maxResults", fmt.Sprint(jiraBatchSize))
+		query.Set("validateQuery", "warn")
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/rest/api/2/search?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if email, token := os.Getenv("JIRA_EMAIL"), os.Getenv("JIRA_API_TOKEN"); token != "" {
+			req.SetBasicAuth(email, token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Issues []*jiraIssue `json:"issues"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("jira search returned %s", resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode jira response: %w", err)
+		}
+
+		issues = append(issues, result.Issues...)
+	}
+
+	return issues, nil
+}
+
+func (w *worker) handleIssueKeyLinks(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	var expr = defaultIssueKeyPattern
+	if settings.IssueKeyPattern != "" {
+		expr = settings.IssueKeyPattern
+	}
+
+	var pattern *regexp.Regexp
+	if pattern, err = regexp.Compile(expr); err != nil {
+		return fmt.Errorf("invalid issue key pattern: %w", err)
+	}
+
+	var links []*issueKeyLink
+	if links, err = w.fetchIssueKeyLinks(ctx, j, pattern); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("found %d issue key link(s)", len(links))
+
+	var issues []*jiraIssue
+	if jiraURL := os.Getenv("JIRA_URL"); jiraURL != "" {
+		var keys []string
+		var seen = make(map[string]struct{})
+		for _, link := range links {
+			if _, ok := seen[link.issueKey]; !ok {
+				seen[link.issueKey] = struct{}{}
+				keys = append(keys, link.issueKey)
+			}
+		}
+
+		// failing to enrich the links is not fatal, the links themselves are still synced
+		if issues, err = fetchJiraIssues(ctx, jiraURL, keys); err != nil {
+			w.warnForJob(ctx, j, fmt.Sprintf(LogFormatErrorWarningMessage, "could not fetch issues from Jira", err), errorDetails(err))
+			issues = nil
+		}
+	}
+
+	var inputs = make([][]interface{}, 0, len(links))
+	for _, link := range links {
+		inputs = append(inputs, []interface{}{j.RepoID, link.source, link.commit, link.pr, link.issueKey})
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM issue_key_links WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from issue_key_links", r.RowsAffected()),
+		Details:         rowDetails("removed", "issue_key_links", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"issue_key_links"}, []string{"repo_id", "source", "commit_hash", "pull_request_number", "issue_key"}, pgx.CopyFromRows(inputs)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	var batch = []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into issue_key_links", len(inputs)),
+		Details:         rowDetails("inserted", "issue_key_links", int64(len(inputs))),
+	}}
+
+	// issues aren't specific to a repo, so they're upserted rather than replaced
+	const upsertIssue = `
+INSERT INTO jira_issues (issue_key, summary, status, issue_type) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (issue_key) DO UPDATE SET summary = EXCLUDED.summary, status = EXCLUDED.status,
+		issue_type = EXCLUDED.issue_type, _mergestat_synced_at = now()`
+	for _, issue := range issues {
+		if _, err := tx.Exec(ctx, upsertIssue, issue.Key, issue.Fields.Summary, issue.Fields.Status.Name, issue.Fields.IssueType.Name); err != nil {
+			return fmt.Errorf("upsert jira issue: %w", err)
+		}
+	}
+	if len(issues) > 0 {
+		batch = append(batch, &syncLog{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+			Message: fmt.Sprintf("upserted %d row(s) into jira_issues", len(issues)),
+			Details: rowDetails("upserted", "jira_issues", int64(len(issues))),
+		})
+	}
+
+	if err := w.sendBatchLogMessages(ctx, batch); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "issue_key_links", len(inputs))
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LLMOptions configures the model LLM_SUMMARIES syncs generate summaries with (see WithLLM)
+type LLMOptions struct {
+	// Provider is the api the model is served with: openai (the OpenAI api, or any server compatible with its chat
+	// completions endpoint, eg. vLLM or LocalAI) or ollama (a local Ollama server)
+	Provider string
+
+	// URL is the base url of the api, defaults to https://api.openai.com/v1 (openai) or http://localhost:11434 (ollama)
+	URL string
+
+	// Model is the name of the model, eg. gpt-4o-mini or llama3
+	Model string
+
+	// APIKey, if set, authenticates the calls (as a bearer token)
+	APIKey string
+
+	// MaxTokens caps the length of the summaries, in tokens
+	MaxTokens int
+
+	// MaxInputBytes is the size prompts are cut to, to fit the context of the model (and bound the cost of a call)
+	MaxInputBytes int
+}
+
+// errLLMNotConfigured is returned by LLM_SUMMARIES syncs run by a worker without an LLM
+var errLLMNotConfigured = errors.New("in order to run this syncer, an LLM must be configured (see the LLM_PROVIDER and LLM_MODEL env vars)")
+
+// WithLLM sets the model LLM_SUMMARIES syncs generate summaries with. Options left zero take defaults.
+func (w *worker) WithLLM(opts LLMOptions) *worker {
+	if opts.Provider == "" {
+		opts.Provider = "openai"
+	}
+	if opts.URL == "" {
+		switch opts.Provider {
+		case "openai":
+			opts.URL = "https://api.openai.com/v1"
+		case "ollama":
+			opts.URL = "http://localhost:11434"
+		}
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = 300
+	}
+	if opts.MaxInputBytes <= 0 {
+		opts.MaxInputBytes = 24 << 10
+	}
+
+	w.llm = &opts
+	return w
+}
+
+// llmClient calls the chat api of the configured LLM
+type llmClient struct {
+	opts   *LLMOptions
+	client *http.Client
+}
+
+func newLLMClient(opts *LLMOptions) (*llmClient, error) {
+	if opts == nil || opts.Model == "" {
+		return nil, errLLMNotConfigured
+	}
+	if opts.Provider != "openai" && opts.Provider != "ollama" {
+		return nil, fmt.Errorf("unknown LLM provider %q, expected openai or ollama", opts.Provider)
+	}
+	return &llmClient{opts: opts, client: &http.Client{Timeout: 5 * time.Minute}}, nil
+}
+
+// complete returns the answer of the model to the prompt, following the instructions, and the model (version) that
+// generated it, as reported by the endpoint
+func (c *llmClient) complete(ctx context.Context, instructions, prompt string) (answer, model string, err error) {
+	var messages = []map[string]string{{"role": "system", "content": instructions}, {"role": "user", "content": prompt}}
+
+	var endpoint = strings.TrimSuffix(c.opts.URL, "/") + "/chat/completions"
+	var request = map[string]interface{}{"model": c.opts.Model, "messages": messages, "max_tokens": c.opts.MaxTokens}
+	if c.opts.Provider == "ollama" {
+		endpoint = strings.TrimSuffix(c.opts.URL, "/") + "/api/chat"
+		request = map[string]interface{}{"model": c.opts.Model, "messages": messages, "stream": false,
+			"options": map[string]interface{}{"num_predict": c.opts.MaxTokens}}
+	}
+
+	var body []byte
+	if body, err = json.Marshal(request); err != nil {
+		return "", "", err
+	}
+
+	var response struct {
+		Model string `json:"model"`
+
+		// openai
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+
+		// ollama
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	}
+	if err = postModelAPI(ctx, c.client, endpoint, c.opts.APIKey, body, &response); err != nil {
+		return "", "", err
+	}
+
+	answer = response.Message.Content
+	if len(response.Choices) > 0 {
+		answer = response.Choices[0].Message.Content
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return "", "", fmt.Errorf("%s returned an empty answer", endpoint)
+	}
+	return answer, response.Model, nil
+}
@@ -0,0 +1,352 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+const (
+	// summaryPromptVersion is the version of the instructions (and prompts) summaries are generated with, it must be
+	// incremented when they change, so that summaries are generated again
+	summaryPromptVersion = 1
+
+	repoSummaryInstructions = "You summarize software repositories for an engineering portfolio overview. In 2 to 4 sentences " +
+		"of plain text, say what the repository is, what it is used for, and what its recent work focused on. " +
+		"Only use the information given, do not invent details."
+
+	pullRequestSummaryInstructions = "You summarize large pull requests for engineering managers. In 2 to 3 sentences of " +
+		"plain text, say what the pull request changes and why. Only use the information given, do not invent details."
+
+	// defaultSummaryRecentCommits, defaultSummaryPRMinChanges and defaultSummaryMaxPRs are the defaults of the
+	// settings of LLM_SUMMARIES syncs (see syncSettings)
+	defaultSummaryRecentCommits = 30
+	defaultSummaryPRMinChanges  = 500
+	defaultSummaryMaxPRs        = 20
+)
+
+const upsertRepoSummary = `
+INSERT INTO repo_summaries (repo_id, model, model_version, prompt_version, summary, input_hash) VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (repo_id, model) DO UPDATE
+    SET model_version = excluded.model_version, prompt_version = excluded.prompt_version, summary = excluded.summary,
+        input_hash = excluded.input_hash, generated_at = now(), _mergestat_synced_at = now()
+`
+
+const upsertPullRequestSummary = `
+INSERT INTO github_pull_request_summaries (repo_id, pr_number, model, model_version, prompt_version, summary, input_hash) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (repo_id, pr_number, model) DO UPDATE
+    SET model_version = excluded.model_version, prompt_version = excluded.prompt_version, summary = excluded.summary,
+        input_hash = excluded.input_hash, generated_at = now(), _mergestat_synced_at = now()
+`
+
+// summaryInputHash identifies the inputs of a summary: its prompt, and the version of the instructions
+func summaryInputHash(prompt string) string {
+	var sum = sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s", summaryPromptVersion, prompt)))
+	return hex.EncodeToString(sum[:])
+}
+
+// summaryInput is a summary to generate, of the repo (number is zero) or of one of its pull requests
+type summaryInput struct {
+	number int
+	prompt string
+	hash   string
+}
+
+// repoSummaryInput returns the input of the summary of the repo, from its (already synced) README and recent commits,
+// or nil if it has neither
+func (w *worker) repoSummaryInput(ctx context.Context, j *db.DequeueSyncJobRow, recentCommits, maxBytes int) (_ *summaryInput, err error) {
+	const readme = `
+SELECT contents FROM git_files WHERE repo_id = $1 AND contents IS NOT NULL AND path ~* '^readme(\.[a-z]+)?$'
+ORDER BY length(path) LIMIT 1`
+
+	var d *decrypter
+	if d, err = w.decrypter(ctx); err != nil {
+		return nil, err
+	}
+
+	var contents string
+	if err = w.pool.QueryRow(ctx, readme, j.RepoID).Scan(&contents); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("query readme: %w", err)
+	}
+	if err = d.decrypt("git_files", "contents", &contents); err != nil {
+		return nil, err
+	}
+
+	var commits []string
+	if commits, err = helper.CollectStrings(w.pool.Query(ctx, "SELECT COALESCE(message, '') FROM git_commits WHERE repo_id = $1 ORDER BY committer_when DESC LIMIT $2", j.RepoID, recentCommits)); err != nil {
+		return nil, fmt.Errorf("query commits: %w", err)
+	}
+	for i := range commits {
+		if err = d.decrypt("git_commits", "message", &commits[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if contents == "" && len(commits) == 0 {
+		return nil, nil
+	}
+
+	var prompt = helper.RepoSummaryPrompt(j.Repo, contents, commits, maxBytes)
+	return &summaryInput{prompt: prompt, hash: summaryInputHash(prompt)}, nil
+}
+
+// pullRequestSummaryInputs returns the inputs of the summaries of the (already synced) pull requests of the repo
+// changing at least minChanges lines, latest first
+func (w *worker) pullRequestSummaryInputs(ctx context.Context, j *db.DequeueSyncJobRow, minChanges, maxBytes int) (_ []*summaryInput, err error) {
+	const listPullRequests = `
+SELECT number, COALESCE(title, ''), COALESCE(body, ''), COALESCE(additions, 0), COALESCE(deletions, 0), COALESCE(changed_files, 0)
+FROM github_pull_requests WHERE repo_id = $1 AND COALESCE(additions, 0) + COALESCE(deletions, 0) >= $2
+ORDER BY created_at DESC`
+
+	type pullRequest struct {
+		number, additions, deletions, changedFiles int
+		title, body                                string
+	}
+
+	var d *decrypter
+	if d, err = w.decrypter(ctx); err != nil {
+		return nil, err
+	}
+
+	var prs []*pullRequest
+	var numbers []int
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, listPullRequests, j.RepoID, minChanges); err != nil {
+		return nil, fmt.Errorf("query pull requests: %w", err)
+	}
+	for rows.Next() {
+		var pr pullRequest
+		if err = rows.Scan(&pr.number, &pr.title, &pr.body, &pr.additions, &pr.deletions, &pr.changedFiles); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if err = d.decrypt("github_pull_requests", "title", &pr.title); err == nil {
+			err = d.decrypt("github_pull_requests", "body", &pr.body)
+		}
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		prs, numbers = append(prs, &pr), append(numbers, pr.number)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var commits = make(map[int][]string)
+	if rows, err = w.pool.Query(ctx, "SELECT pr_number, COALESCE(message, '') FROM github_pull_request_commits WHERE repo_id = $1 AND pr_number = ANY($2) ORDER BY author_when", j.RepoID, numbers); err != nil {
+		return nil, fmt.Errorf("query pull request commits: %w", err)
+	}
+	for rows.Next() {
+		var number int
+		var message string
+		if err = rows.Scan(&number, &message); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if err = d.decrypt("github_pull_request_commits", "message", &message); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		commits[number] = append(commits[number], message)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var inputs = make([]*summaryInput, len(prs))
+	for i, pr := range prs {
+		var prompt = helper.PullRequestSummaryPrompt(pr.title, pr.body, pr.additions, pr.deletions, pr.changedFiles, commits[pr.number], maxBytes)
+		inputs[i] = &summaryInput{number: pr.number, prompt: prompt, hash: summaryInputHash(prompt)}
+	}
+	return inputs, nil
+}
+
+func (w *worker) handleLLMSummaries(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var client *llmClient
+	if client, err = newLLMClient(w.llm); err != nil {
+		return err
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	var recentCommits, minChanges, maxPRs = settings.SummaryRecentCommits, settings.SummaryPRMinChanges, settings.SummaryMaxPRs
+	if recentCommits <= 0 {
+		recentCommits = defaultSummaryRecentCommits
+	}
+	if minChanges <= 0 {
+		minChanges = defaultSummaryPRMinChanges
+	}
+	if maxPRs <= 0 {
+		maxPRs = defaultSummaryMaxPRs
+	}
+
+	var model = w.llm.Model
+
+	var repoInput *summaryInput
+	if repoInput, err = w.repoSummaryInput(ctx, j, recentCommits, w.llm.MaxInputBytes); err != nil {
+		return err
+	}
+
+	var prInputs []*summaryInput
+	if prInputs, err = w.pullRequestSummaryInputs(ctx, j, minChanges, w.llm.MaxInputBytes); err != nil {
+		return err
+	}
+
+	// only the summaries whose inputs changed since they were generated are generated again (calls to the model aren't free)
+	var repoHash string
+	if err = w.pool.QueryRow(ctx, "SELECT input_hash FROM repo_summaries WHERE repo_id = $1 AND model = $2", j.RepoID, model).Scan(&repoHash); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("query repo summary: %w", err)
+	}
+	if repoInput != nil && repoInput.hash == repoHash {
+		repoInput = nil
+	}
+
+	var prHashes = make(map[int]string)
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, "SELECT pr_number, input_hash FROM github_pull_request_summaries WHERE repo_id = $1 AND model = $2", j.RepoID, model); err != nil {
+		return fmt.Errorf("query pull request summaries: %w", err)
+	}
+	for rows.Next() {
+		var number int
+		var hash string
+		if err = rows.Scan(&number, &hash); err != nil {
+			rows.Close()
+			return err
+		}
+		prHashes[number] = hash
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	var pending []*summaryInput
+	for _, input := range prInputs {
+		if prHashes[input.number] != input.hash {
+			pending = append(pending, input)
+		}
+	}
+	if len(pending) > maxPRs {
+		w.warnForJob(ctx, j, fmt.Sprintf("%d pull request(s) to summarize, only the latest %d are summarized by this sync (see summaryMaxPrs)", len(pending), maxPRs),
+			logDetails{"pending": len(pending), "maxPRs": maxPRs})
+		pending = pending[:maxPRs]
+	}
+	if repoInput != nil {
+		pending = append([]*summaryInput{repoInput}, pending...)
+	}
+
+	// a summary that can't be generated is skipped (and attempted again by the next sync), unless none can be
+	var batch = &pgx.Batch{}
+	var generated, failed int
+	var lastErr error
+	for _, input := range pending {
+		var instructions = pullRequestSummaryInstructions
+		if input.number == 0 {
+			instructions = repoSummaryInstructions
+		}
+
+		var summary, version string
+		if summary, version, err = client.complete(ctx, instructions, input.prompt); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var subject = "the repo"
+			if input.number != 0 {
+				subject = fmt.Sprintf("pull request #%d", input.number)
+			}
+			w.warnForJob(ctx, j, fmt.Sprintf("could not summarize %s: %v", subject, err), logDetails{"pr_number": input.number, "error": err.Error()})
+			failed, lastErr = failed+1, err
+			continue
+		}
+
+		if input.number == 0 {
+			batch.Queue(upsertRepoSummary, j.RepoID, model, nullIfEmpty(version), summaryPromptVersion, summary, input.hash)
+		} else {
+			batch.Queue(upsertPullRequestSummary, j.RepoID, input.number, model, nullIfEmpty(version), summaryPromptVersion, summary, input.hash)
+		}
+		generated++
+	}
+
+	if failed > 0 && generated == 0 {
+		return fmt.Errorf("summarize: %w", lastErr)
+	}
+
+	l.Info().Msgf("generated %d summar(ies) with %s", generated, model)
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	// summaries of pull requests no longer synced are removed
+	const remove = `
+DELETE FROM github_pull_request_summaries s WHERE s.repo_id = $1
+    AND NOT EXISTS (SELECT 1 FROM github_pull_requests p WHERE p.repo_id = s.repo_id AND p.number = s.pr_number)`
+	r, err := tx.Exec(ctx, remove, j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from github_pull_request_summaries", r.RowsAffected()),
+		Details:         rowDetails("removed", "github_pull_request_summaries", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("upsert summaries: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("upserted %d summar(ies) into repo_summaries and github_pull_request_summaries", generated),
+		Details:         rowDetails("upserted", "github_pull_request_summaries", int64(generated)),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,101 @@
+package syncer
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// loadSampleInterval is how often the database load is sampled
+	loadSampleInterval = 15 * time.Second
+
+	// maxAcquireWait is the average time spent waiting for a pool connection, over a sample
+	// interval, above which the database is considered saturated
+	maxAcquireWait = 500 * time.Millisecond
+
+	// maxConnectionsRatio is the ratio of max_connections in use above which the database is considered saturated
+	maxConnectionsRatio = 0.9
+)
+
+// limiter sheds the worker's concurrency while Postgres is saturated (eg. during a large COPY) and restores it,
+// one slot at a time, once the load goes back down. Exec loops with a slot above the current limit don't dequeue.
+type limiter struct {
+	logger *zerolog.Logger
+	pool   *pgxpool.Pool
+	max    int32
+	limit  int32
+
+	// statActivity is set if pg_stat_activity can be used to sample the load
+	statActivity bool
+
+	// pool statistics at the previous sample
+	acquireCount    int64
+	acquireDuration time.Duration
+}
+
+func newLimiter(logger *zerolog.Logger, pool *pgxpool.Pool, max int) *limiter {
+	return &limiter{logger: logger, pool: pool, max: int32(max), limit: int32(max), statActivity: true}
+}
+
+// allows reports whether the exec loop with the given (zero-based) slot may dequeue a job
+func (l *limiter) allows(slot int) bool {
+	return int32(slot) < atomic.LoadInt32(&l.limit)
+}
+
+// monitor samples the database load until the context is canceled, adjusting the limit
+func (l *limiter) monitor(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(loadSampleInterval):
+			saturated, err := l.saturated(ctx)
+			if err != nil {
+				l.logger.Warn().AnErr("error", err).Msg("could not sample database load")
+				continue
+			}
+
+			var limit = atomic.LoadInt32(&l.limit)
+			switch {
+			case saturated && limit > 1:
+				limit = limit / 2
+				l.logger.Warn().Msgf("database is saturated, reducing sync concurrency to %d", limit)
+			case !saturated && limit < l.max:
+				limit++
+				l.logger.Info().Msgf("database load is back to normal, raising sync concurrency to %d", limit)
+			default:
+				continue
+			}
+			atomic.StoreInt32(&l.limit, limit)
+		}
+	}
+}
+
+// saturated reports whether connections took too long to acquire from the pool since the last sample,
+// or too many of the server's max_connections are in use
+func (l *limiter) saturated(ctx context.Context) (bool, error) {
+	var stat = l.pool.Stat()
+	var acquires, duration = stat.AcquireCount() - l.acquireCount, stat.AcquireDuration() - l.acquireDuration
+	l.acquireCount, l.acquireDuration = stat.AcquireCount(), stat.AcquireDuration()
+
+	if acquires > 0 && duration/time.Duration(acquires) > maxAcquireWait {
+		return true, nil
+	}
+
+	if !l.statActivity {
+		return false, nil
+	}
+
+	const connections = `SELECT COUNT(*), current_setting('max_connections')::INTEGER FROM pg_stat_activity`
+
+	var inUse, max int
+	if err := l.pool.QueryRow(ctx, connections).Scan(&inUse, &max); err != nil {
+		return false, err
+	}
+
+	return max > 0 && float64(inUse) > maxConnectionsRatio*float64(max), nil
+}
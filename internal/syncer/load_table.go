@@ -0,0 +1,86 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// copier is implemented by anything rows can be COPY'd through (a transaction or a connection)
+type copier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// loadTable is a temporary (and so unlogged) copy of a table, used by full-refresh syncs to COPY rows into
+// outside of any transaction. The rows are then moved into the table in a short transaction (see swap),
+// which reduces WAL and the window where readers see the table without the repo's rows.
+type loadTable struct {
+	conn   *pgxpool.Conn
+	copier copier
+	table  string
+}
+
+// newLoadTable acquires a connection and creates an empty temporary copy of the given table on it.
+// Callers must close the returned loadTable once done.
+func (w *worker) newLoadTable(ctx context.Context, j *db.DequeueSyncJobRow, table string) (_ *loadTable, err error) {
+	var conn *pgxpool.Conn
+	if conn, err = w.pool.Acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	var l = &loadTable{conn: conn, table: table}
+	var stmt = fmt.Sprintf("DROP TABLE IF EXISTS %[1]s; CREATE TEMPORARY TABLE %[1]s (LIKE %[2]s INCLUDING DEFAULTS)",
+		l.Identifier().Sanitize(), pgx.Identifier{table}.Sanitize())
+	if _, err = conn.Exec(ctx, stmt); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("create load table: %w", err)
+	}
+
+	if l.copier, err = w.withTransforms(ctx, j, conn); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("load transforms: %w", err)
+	}
+
+	return l, nil
+}
+
+// Identifier is the name of the temporary table rows should be copied into
+func (l *loadTable) Identifier() pgx.Identifier {
+	return pgx.Identifier{"pg_temp", "load_" + l.table}
+}
+
+// Conn is the connection the temporary table lives on; the transaction passed to swap must be started on it
+func (l *loadTable) Conn() *pgxpool.Conn { return l.conn }
+
+// Copier is what rows should be copied through, it applies the job's transforms (if any) to them
+func (l *loadTable) Copier() copier { return l.copier }
+
+// swap moves the given columns of all loaded rows into the table, using the given transaction
+func (l *loadTable) swap(ctx context.Context, tx pgx.Tx, columns []string) (int64, error) {
+	var cols = make([]string, len(columns))
+	for i, c := range columns {
+		cols[i] = pgx.Identifier{c}.Sanitize()
+	}
+	var list = strings.Join(cols, ", ")
+
+	var stmt = fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+		pgx.Identifier{l.table}.Sanitize(), list, list, l.Identifier().Sanitize())
+
+	r, err := tx.Exec(ctx, stmt)
+	if err != nil {
+		return 0, fmt.Errorf("swap load table: %w", err)
+	}
+
+	return r.RowsAffected(), nil
+}
+
+// close drops the temporary table and releases the connection
+func (l *loadTable) close(ctx context.Context) error {
+	defer l.conn.Release()
+	_, err := l.conn.Exec(ctx, "DROP TABLE IF EXISTS "+l.Identifier().Sanitize())
+	return err
+}
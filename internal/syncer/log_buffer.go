@@ -0,0 +1,168 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// logFlushInterval is how often buffered sync logs are written to mergestat.repo_sync_logs
+	logFlushInterval = time.Second
+
+	// logFlushSize is the number of buffered sync logs that triggers a write before the interval elapses
+	logFlushSize = 500
+)
+
+// defaultLogSampling is the number of similar logs, by log type, a job writes before the following ones are
+// suppressed (see WithLogSampling). Types that aren't listed are never suppressed.
+var defaultLogSampling = map[syncLogType]int{SyncLogTypeWarn: 25}
+
+// suppressedLogs are the logs of a kind a job sent after reaching the sampling limit of their type
+type suppressedLogs struct {
+	count int
+	last  *syncLog
+}
+
+// logBuffer collects the sync logs of the worker's jobs and writes them to mergestat.repo_sync_logs in batches,
+// in the background (see run), so that sending a log doesn't cost a round trip to the database. Logs keep the
+// time they were sent at, and the logs of a job are flushed when it ends (see worker.handle).
+type logBuffer struct {
+	pool   *pgxpool.Pool
+	logger *zerolog.Logger
+
+	mu      sync.Mutex
+	pending [][]interface{}
+	full    chan struct{}
+
+	// sampling is the number of similar logs, by type, a job writes before the following ones are suppressed.
+	// seen counts, by job, the logs of each kind, and suppressed collects the ones that were dropped.
+	sampling   map[syncLogType]int
+	seen       map[int64]map[string]int
+	suppressed map[int64]map[string]*suppressedLogs
+
+	// flushMu serializes flushes, so that logs are written in the order they were sent in
+	flushMu sync.Mutex
+}
+
+func newLogBuffer(pool *pgxpool.Pool, logger *zerolog.Logger) *logBuffer {
+	return &logBuffer{pool: pool, logger: logger, full: make(chan struct{}, 1), sampling: defaultLogSampling,
+		seen: make(map[int64]map[string]int), suppressed: make(map[int64]map[string]*suppressedLogs)}
+}
+
+// kind returns what makes logs similar: their kind, if set, or else their type and message
+func (l *syncLog) kind() string {
+	if l.Kind != "" {
+		return l.Kind
+	}
+	return string(l.Type) + ":" + l.Message
+}
+
+// add buffers the given logs, waking up the background flush if the buffer is full. Logs of a kind the job already
+// sent as many of as the sampling limit of their type are suppressed, and summarized once the job ends (see finish).
+func (b *logBuffer) add(batch []*syncLog) {
+	var now = time.Now()
+
+	b.mu.Lock()
+	for _, l := range batch {
+		if limit, ok := b.sampling[l.Type]; ok && limit > 0 {
+			var seen = b.seen[l.RepoSyncQueueID]
+			if seen == nil {
+				seen = make(map[string]int)
+				b.seen[l.RepoSyncQueueID] = seen
+			}
+
+			var kind = l.kind()
+			if seen[kind]++; seen[kind] > limit {
+				var suppressed = b.suppressed[l.RepoSyncQueueID]
+				if suppressed == nil {
+					suppressed = make(map[string]*suppressedLogs)
+					b.suppressed[l.RepoSyncQueueID] = suppressed
+				}
+				if suppressed[kind] == nil {
+					suppressed[kind] = &suppressedLogs{}
+				}
+				suppressed[kind].count++
+				suppressed[kind].last = l
+				continue
+			}
+		}
+
+		b.pending = append(b.pending, []interface{}{now, l.Type, l.Message, l.RepoSyncQueueID, l.Details.encode()})
+	}
+	var full = len(b.pending) >= logFlushSize
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush writes all buffered logs. Logs that can't be written are dropped (and reported to the worker log),
+// failing to write logs never fails a job.
+func (b *logBuffer) flush(ctx context.Context) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	var rows = b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if len(rows) == 0 {
+		return
+	}
+
+	if _, err := b.pool.CopyFrom(ctx, pgx.Identifier{"mergestat", "repo_sync_logs"}, []string{"created_at", "log_type", "message", "repo_sync_queue_id", "details"}, pgx.CopyFromRows(rows)); err != nil {
+		b.logger.Err(err).Msgf("could not write %d sync log(s)", len(rows))
+	}
+}
+
+// finish writes, for each kind of log the job had suppressed, a single entry with the number of suppressed logs
+// and the last of them (as a sample), then flushes the buffer
+func (b *logBuffer) finish(ctx context.Context, job int64) {
+	var now = time.Now()
+
+	b.mu.Lock()
+	for _, s := range b.suppressed[job] {
+		var details = logDetails{"suppressed": s.count, "sample": s.last.Message}
+		for k, v := range s.last.Details {
+			details[k] = v
+		}
+
+		var message = fmt.Sprintf(LogFormatSuppressedLogs, s.count, s.last.Message)
+		b.pending = append(b.pending, []interface{}{now, s.last.Type, message, job, details.encode()})
+	}
+	delete(b.seen, job)
+	delete(b.suppressed, job)
+	b.mu.Unlock()
+
+	b.flush(ctx)
+}
+
+// run flushes the buffer every logFlushInterval (or sooner, when it fills up) until the context is done,
+// then flushes it one last time
+func (b *logBuffer) run(ctx context.Context) {
+	var ticker = time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.flush(context.Background())
+			return
+		case <-ticker.C:
+			b.flush(ctx)
+		case <-b.full:
+			b.flush(ctx)
+		}
+	}
+}
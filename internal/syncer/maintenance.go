@@ -0,0 +1,93 @@
+package syncer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// minMaintenanceInterval is the minimum time between two maintenance runs of the same table by a worker,
+// so that many syncs of the same type completing together don't analyze the table over and over
+const minMaintenanceInterval = 5 * time.Minute
+
+// bulkTables are the tables each sync type bulk loads rows into
+var bulkTables = map[string][]string{
+	syncTypeGitCommits:               {"git_commits"},
+	syncTypeGitCommitStats:           {"git_commit_stats"},
+	syncTypeGitFiles:                 {"git_files", "git_file_changes"},
+	syncTypeGitBlame:                 {"git_blame", "git_file_ownership"},
+	syncTypeGitRefs:                  {"git_refs"},
+	syncTypeGitHubRepoIssues:         {"github_issues", "github_issue_comments", "github_issue_label_events"},
+	syncTypeGitHubRepoPRs:            {"github_pull_requests", "github_issue_comments", "github_issue_label_events"},
+	syncTypeGitHubPRReviews:          {"github_pull_request_reviews"},
+	syncTypeGitHubPRCommits:          {"github_pull_request_commits"},
+	syncTypeGitHubPRsAndCommits:      {"github_pull_requests", "github_pull_request_commits"},
+	syncTypeGitHubRepoStars:          {"github_stargazers"},
+	syncTypeGitHubRepoTeams:          {"github_repo_teams", "github_repo_team_members"},
+	syncTypeGitCommitMetrics:         {"git_commit_metrics"},
+	syncTypeGitCommitPatches:         {"git_commit_patches"},
+	syncTypeGitSymbols:               {"git_symbols"},
+	syncTypeGitHubActionsUsage:       {"github_actions_workflow_run_usage", "github_actions_workflow_job_usage"},
+	syncTypeGitHubActionsTestResults: {"github_actions_test_results"},
+	syncTypeGitHubRunners:            {"github_runner_snapshots"},
+}
+
+// maintenance keeps track of when tables were last maintained by the worker
+type maintenance struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// due reports whether the table should be maintained now, and if so records it as maintained
+func (m *maintenance) due(table string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.last == nil {
+		m.last = make(map[string]time.Time)
+	}
+
+	if time.Since(m.last[table]) < minMaintenanceInterval {
+		return false
+	}
+
+	m.last[table] = time.Now()
+	return true
+}
+
+// maintainTables analyzes (and, if configured for the sync type, reindexes) the tables the job bulk loaded rows into,
+// so that queries following a sync aren't planned against stale statistics. Failures are only logged.
+func (w *worker) maintainTables(ctx context.Context, j *db.DequeueSyncJobRow) {
+	var tables, ok = bulkTables[j.SyncType]
+	if !ok {
+		return
+	}
+
+	var logger = w.loggerForJob(j)
+
+	settings, err := w.db.GetSyncTypeMaintenance(ctx, j.SyncType)
+	if err != nil {
+		logger.Warn().AnErr("error", err).Msg("could not fetch maintenance settings of sync type")
+		return
+	}
+
+	for _, table := range tables {
+		if !w.maintenance.due(table) {
+			continue
+		}
+
+		if settings.AnalyzeAfterSync {
+			if _, err = w.pool.Exec(ctx, "ANALYZE "+w.tables.Table(table).Sanitize()); err != nil {
+				logger.Warn().AnErr("error", err).Msgf("could not analyze %s", table)
+			}
+		}
+
+		if settings.ReindexAfterSync && w.dialect.ReindexConcurrently {
+			if _, err = w.pool.Exec(ctx, "REINDEX TABLE CONCURRENTLY "+w.tables.Table(table).Sanitize()); err != nil {
+				logger.Warn().AnErr("error", err).Msgf("could not reindex %s", table)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	stdhttp "net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maintenanceModePollInterval is how often the worker checks whether the maintenance mode is enabled
+const maintenanceModePollInterval = 5 * time.Second
+
+// maintenanceMode is the state of the maintenance mode (see mergestat.maintenance_mode) as last seen by the worker,
+// and the number of jobs it has in flight
+type maintenanceMode struct {
+	enabled  int32
+	inFlight int32
+
+	mu     sync.Mutex
+	reason string
+	since  time.Time
+}
+
+// active reports whether the maintenance mode is enabled, in which case exec loops don't dequeue
+func (m *maintenanceMode) active() bool {
+	return atomic.LoadInt32(&m.enabled) == 1
+}
+
+// watchMaintenanceMode polls the maintenance mode until the context is canceled, logging its transitions and
+// when the jobs in flight are done
+func (w *worker) watchMaintenanceMode(ctx context.Context) {
+	var drained bool
+	for {
+		if mode, err := w.db.GetMaintenanceMode(ctx); err != nil {
+			if ctx.Err() == nil {
+				w.logger.Warn().AnErr("error", err).Msg("could not check maintenance mode")
+			}
+		} else {
+			w.paused.mu.Lock()
+			w.paused.reason, w.paused.since = mode.Reason.String, mode.EnabledAt.Time
+			w.paused.mu.Unlock()
+
+			var inFlight = atomic.LoadInt32(&w.paused.inFlight)
+			switch {
+			case mode.Enabled && atomic.SwapInt32(&w.paused.enabled, 1) == 0:
+				w.logger.Warn().Str("reason", mode.Reason.String).Msgf("maintenance mode enabled, finishing %d job(s) in flight and pausing", inFlight)
+				drained = false
+			case !mode.Enabled && atomic.SwapInt32(&w.paused.enabled, 0) == 1:
+				w.logger.Info().Msg("maintenance mode disabled, resuming")
+			}
+
+			if mode.Enabled && inFlight == 0 && !drained {
+				w.logger.Info().Msg("maintenance mode: no job in flight, worker is paused")
+				drained = true
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(maintenanceModePollInterval):
+		}
+	}
+}
+
+// Health is the state of the worker, as reported by its health endpoints
+type Health struct {
+	// Status is ok, or maintenance while the maintenance mode is enabled
+	Status string `json:"status"`
+
+	Maintenance      bool       `json:"maintenance"`
+	Reason           string     `json:"reason,omitempty"`
+	MaintenanceSince *time.Time `json:"maintenance_since,omitempty"`
+
+	// InFlight is the number of jobs the worker is running, and Drained is set once none is left in maintenance mode
+	InFlight int32 `json:"in_flight"`
+	Drained  bool  `json:"drained"`
+}
+
+// Health returns the state of the worker
+func (w *worker) Health() Health {
+	var h = Health{Status: "ok", InFlight: atomic.LoadInt32(&w.paused.inFlight)}
+	if w.paused.active() {
+		w.paused.mu.Lock()
+		h.Status, h.Maintenance, h.Reason = "maintenance", true, w.paused.reason
+		if since := w.paused.since; !since.IsZero() {
+			h.MaintenanceSince = &since
+		}
+		w.paused.mu.Unlock()
+		h.Drained = h.InFlight == 0
+	}
+	return h
+}
+
+// HealthHandler serves the state of the worker as json. If ready is set (for a readiness probe), it responds with
+// 503 Service Unavailable while the maintenance mode is enabled.
+func (w *worker) HealthHandler(ready bool) stdhttp.Handler {
+	return stdhttp.HandlerFunc(func(rw stdhttp.ResponseWriter, _ *stdhttp.Request) {
+		var h = w.Health()
+
+		rw.Header().Set("Content-Type", "application/json")
+		if ready && h.Maintenance {
+			rw.WriteHeader(stdhttp.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(rw).Encode(h)
+	})
+}
@@ -0,0 +1,34 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/namespace"
+)
+
+// WithTableRegistry sets the registry of the synced tables relocated out of public (see namespace.Apply). Syncs
+// reference tables by their own name, which the search_path of the pool resolves to updatable views, the registry
+// resolves the tables themselves, for what doesn't go through views (COPY, ANALYZE, REINDEX...).
+func (w *worker) WithTableRegistry(r namespace.Registry) *worker {
+	w.tables = r
+	return w
+}
+
+// namespacedTx is a transaction COPY'ing rows into the synced tables where they live
+type namespacedTx struct {
+	pgx.Tx
+	tables namespace.Registry
+}
+
+func (tx *namespacedTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return tx.Tx.CopyFrom(ctx, tx.tables.Identifier(tableName), columnNames, rowSrc)
+}
+
+// withNamespace returns tx wrapped so that the rows COPY'd through it go into the relocated tables, if any
+func (w *worker) withNamespace(tx pgx.Tx) pgx.Tx {
+	if len(w.tables) == 0 {
+		return tx
+	}
+	return &namespacedTx{Tx: tx, tables: w.tables}
+}
@@ -45,7 +45,7 @@ func (w *worker) handleOSSFScorecardScan(ctx context.Context, j *db.DequeueSyncJ
 	}
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -56,7 +56,7 @@ func (w *worker) handleOSSFScorecardScan(ctx context.Context, j *db.DequeueSyncJ
 		}
 	}()
 
-	r, err := tx.Exec(ctx, "DELETE FROM public.ossf_scorecard_repo_scans WHERE repo_id = $1;", j.RepoID.String())
+	r, err := tx.Exec(ctx, "DELETE FROM ossf_scorecard_repo_scans WHERE repo_id = $1;", j.RepoID.String())
 	if err != nil {
 		return fmt.Errorf("exec delete: %w", err)
 	}
@@ -65,11 +65,12 @@ func (w *worker) handleOSSFScorecardScan(ctx context.Context, j *db.DequeueSyncJ
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from ossf_scorecard_repo_scans", r.RowsAffected()),
+		Details:         rowDetails("removed", "ossf_scorecard_repo_scans", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
 
-	if _, err := tx.Exec(ctx, "INSERT INTO public.ossf_scorecard_repo_scans (repo_id, results) VALUES ($1, $2)", j.RepoID, stdout.Bytes()); err != nil {
+	if _, err := tx.Exec(ctx, "INSERT INTO ossf_scorecard_repo_scans (repo_id, results) VALUES ($1, $2)", j.RepoID, stdout.Bytes()); err != nil {
 		return fmt.Errorf("inserting scorecard results: %w", err)
 	}
 
@@ -0,0 +1,189 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/vendors/registry/client"
+)
+
+var (
+	// registryPackagesColumns are the columns of registry_packages a sync writes
+	registryPackagesColumns = []string{"repo_id", "registry", "name", "manifest_path", "latest_version", "latest_released_at", "version_count", "downloads_last_month"}
+
+	// registryPackageVersionsColumns are the columns of registry_package_versions a sync writes
+	registryPackageVersionsColumns = []string{"repo_id", "registry", "name", "version", "released_at"}
+)
+
+var (
+	goModulePattern    = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+	pyprojectPattern   = regexp.MustCompile(`(?ms)^\[(?:project|tool\.poetry)\]\s*$.*?^name\s*=\s*["']([^"']+)["']`)
+	setupCfgPattern    = regexp.MustCompile(`(?ms)^\[metadata\]\s*$.*?^name\s*=\s*(\S+)`)
+	packageManifestSQL = `
+SELECT path, COALESCE(contents, '') FROM git_files
+	WHERE repo_id = $1 AND path !~ '(^|/)(node_modules|vendor|testdata)/'
+		AND path ~ '(^|/)(package\.json|pyproject\.toml|setup\.cfg|go\.mod)$'`
+)
+
+// publishedPackage is a package declared by a manifest of the repo
+type publishedPackage struct {
+	registry, name, manifest string
+}
+
+// parseManifest returns the package declared by the manifest at p, if it declares a publishable one
+func parseManifest(p, contents string) (*publishedPackage, bool) {
+	var pkg = &publishedPackage{manifest: p}
+	switch path.Base(p) {
+	case "package.json":
+		var manifest struct {
+			Name    string `json:"name"`
+			Private bool   `json:"private"`
+		}
+		if err := json.Unmarshal([]byte(contents), &manifest); err != nil || manifest.Private || manifest.Name == "" {
+			return nil, false
+		}
+		pkg.registry, pkg.name = "npm", manifest.Name
+	case "pyproject.toml", "setup.cfg":
+		var pattern = pyprojectPattern
+		if path.Base(p) == "setup.cfg" {
+			pattern = setupCfgPattern
+		}
+		var m = pattern.FindStringSubmatch(contents)
+		if m == nil {
+			return nil, false
+		}
+		pkg.registry, pkg.name = "pypi", m[1]
+	case "go.mod":
+		var m = goModulePattern.FindStringSubmatch(contents)
+		if m == nil {
+			return nil, false
+		}
+		pkg.registry, pkg.name = "go", m[1]
+	default:
+		return nil, false
+	}
+	return pkg, true
+}
+
+// publishedPackages returns the packages declared by the (already synced) manifests of the repo
+func (w *worker) publishedPackages(ctx context.Context, j *db.DequeueSyncJobRow) (_ []*publishedPackage, err error) {
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, packageManifestSQL, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query files: %w", err)
+	}
+	defer rows.Close()
+
+	var seen = make(map[publishedPackage]bool)
+	var packages []*publishedPackage
+	for rows.Next() {
+		var p, contents string
+		if err = rows.Scan(&p, &contents); err != nil {
+			return nil, err
+		}
+
+		// the same package may be declared by more than one manifest (eg. pyproject.toml and setup.cfg)
+		if pkg, ok := parseManifest(p, contents); ok && !seen[publishedPackage{registry: pkg.registry, name: pkg.name}] {
+			seen[publishedPackage{registry: pkg.registry, name: pkg.name}] = true
+			packages = append(packages, pkg)
+		}
+	}
+
+	return packages, rows.Err()
+}
+
+func (w *worker) handlePackageRegistries(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var packages []*publishedPackage
+	if packages, err = w.publishedPackages(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("found %d package manifest(s)", len(packages))
+
+	var registry = client.New(&http.Client{Timeout: time.Minute})
+
+	var pkgInputs, versionInputs [][]interface{}
+	for _, p := range packages {
+		var pkg *client.Package
+		if pkg, err = registry.Lookup(ctx, p.registry, p.name); err != nil {
+			if !errors.Is(err, client.ErrNotFound) {
+				w.warnForJob(ctx, j, fmt.Sprintf("could not fetch %s package %s: %v", p.registry, p.name, err),
+					logDetails{"registry": p.registry, "package": p.name, "error": err.Error()})
+			}
+			continue
+		}
+
+		var latest time.Time
+		for _, v := range pkg.Versions {
+			versionInputs = append(versionInputs, []interface{}{j.RepoID, pkg.Registry, pkg.Name, v.Version, nullIfZero(v.ReleasedAt)})
+			if v.ReleasedAt.After(latest) {
+				latest = v.ReleasedAt
+			}
+		}
+
+		var downloads interface{}
+		if pkg.DownloadsLastMonth >= 0 {
+			downloads = pkg.DownloadsLastMonth
+		}
+
+		pkgInputs = append(pkgInputs, []interface{}{j.RepoID, pkg.Registry, pkg.Name, p.manifest, nullIfEmpty(pkg.Latest),
+			nullIfZero(latest), len(pkg.Versions), downloads})
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	if err = w.replaceRows(ctx, tx, j, "registry_packages", registryPackagesColumns, pkgInputs); err != nil {
+		return err
+	}
+
+	if err = w.replaceRows(ctx, tx, j, "registry_package_versions", registryPackageVersionsColumns, versionInputs); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// nullIfZero returns nil for a zero time, so that it's stored as NULL
+func nullIfZero(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,154 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/plugins"
+)
+
+// WithPlugins configures the worker to execute syncs of the given types (in addition to the built-in ones)
+// with external plugins (see package plugins)
+func (w *worker) WithPlugins(p map[string]*plugins.Plugin) *worker {
+	w.plugins = p
+	return w
+}
+
+// pluginTable are the rows a plugin sent for a table
+type pluginTable struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+// handlePlugin executes the job with the given plugin. The rows the plugin sends replace the rows of the repo
+// in their tables, in a single transaction, once (and only if) the plugin completes the sync successfully.
+func (w *worker) handlePlugin(ctx context.Context, j *db.DequeueSyncJobRow, p *plugins.Plugin) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var req = &plugins.Request{RepoID: j.RepoID, Repo: j.Repo, Ref: j.Ref.String, SyncType: j.SyncType}
+	if len(j.Settings.Bytes) > 0 {
+		req.Settings = j.Settings.Bytes
+	}
+
+	if p.Clone {
+		tmpPath, cleanup, err := helper.CreateTempDir(os.Getenv("GIT_CLONE_PATH"), fmt.Sprintf("mergestat-repo-%s-*", j.RepoID.String()))
+		if err != nil {
+			return fmt.Errorf("temp dir: %w", err)
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				l.Err(err).Msgf("error cleaning up repo at: %s, %v", tmpPath, err)
+			}
+		}()
+
+		if err = w.clone(ctx, tmpPath, j); err != nil {
+			return fmt.Errorf("git clone: %w", err)
+		}
+		req.Path = tmpPath
+	}
+
+	var tables = make(map[string]*pluginTable)
+	var order []string
+	err = p.Run(ctx, req, func(msg *plugins.Message) error {
+		switch msg.Type {
+		case "log":
+			var logType = SyncLogTypeInfo
+			switch strings.ToUpper(msg.Level) {
+			case "WARN", "WARNING":
+				logType = SyncLogTypeWarn
+			case "ERROR":
+				logType = SyncLogTypeError
+			}
+			return w.sendBatchLogMessages(ctx, []*syncLog{{Type: logType, RepoSyncQueueID: j.ID, Message: msg.Message, Details: msg.Details}})
+		case "rows":
+			if !p.Allows(msg.Table) {
+				return fmt.Errorf("plugin is not allowed to write into %s", msg.Table)
+			}
+
+			// the repo_id of the rows is the worker's to set
+			for _, column := range msg.Columns {
+				if column == "repo_id" {
+					return fmt.Errorf("plugin sent rows for %s with a repo_id column", msg.Table)
+				}
+			}
+
+			var t, found = tables[msg.Table]
+			if !found {
+				t = &pluginTable{columns: append([]string{"repo_id"}, msg.Columns...)}
+				tables[msg.Table], order = t, append(order, msg.Table)
+			} else if !sameColumns(t.columns[1:], msg.Columns) {
+				return fmt.Errorf("plugin sent rows for %s with different columns", msg.Table)
+			}
+
+			for _, row := range msg.Rows {
+				if len(row) != len(msg.Columns) {
+					return fmt.Errorf("plugin sent a row for %s with %d value(s), expected %d", msg.Table, len(row), len(msg.Columns))
+				}
+				t.rows = append(t.rows, append([]interface{}{j.RepoID}, row...))
+			}
+			return nil
+		default:
+			return fmt.Errorf("unknown plugin message type: %q", msg.Type)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	for _, table := range order {
+		if err = w.replaceRows(ctx, tx, j, table, tables[table].columns, tables[table].rows); err != nil {
+			return err
+		}
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// sameColumns reports whether both lists have the same columns, in the same order
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
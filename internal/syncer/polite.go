@@ -0,0 +1,257 @@
+package syncer
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PoliteOptions configures the polite mode of the worker (see WithPoliteMode), meant for scanning many public repos
+// (eg. thousands of OSS GitHub repos, for research) without tripping the abuse detection of the provider
+type PoliteOptions struct {
+	// Concurrency caps the number of jobs the worker runs at once
+	Concurrency int
+
+	// UserAgent identifies the worker in its api calls, it should say who runs it and how to reach them
+	UserAgent string
+
+	// MinInterval is the least time between two api calls of the worker (across its jobs)
+	MinInterval time.Duration
+
+	// MaxCallsPerHour is the hard ceiling of api calls the worker makes per (clock) hour, past which calls fail
+	// and no job is dequeued until the next hour
+	MaxCallsPerHour int
+
+	// CacheSize is the total size (in bytes) of the responses kept to make conditional requests with
+	CacheSize int64
+}
+
+// errPoliteCeiling is returned by the api calls made once the polite mode's ceiling for the hour is reached
+var errPoliteCeiling = errors.New("polite mode: the ceiling of api calls for this hour is reached")
+
+// maxCachedResponse is the size of the largest response kept to make conditional requests with
+const maxCachedResponse = 1 << 20
+
+// WithPoliteMode enables the polite mode: low concurrency, paced api calls with an identifiable User-Agent,
+// conditional requests (GitHub doesn't count 304 Not Modified responses against the rate limit), honoring of
+// Retry-After, and a hard ceiling of api calls per hour. Options left zero take conservative defaults.
+func (w *worker) WithPoliteMode(opts PoliteOptions) *worker {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 2
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "mergestat-worker (polite mode)"
+	}
+	if opts.MinInterval <= 0 {
+		opts.MinInterval = time.Second
+	}
+	if opts.MaxCallsPerHour <= 0 {
+		opts.MaxCallsPerHour = 2000
+	}
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = 64 << 20
+	}
+
+	if opts.Concurrency < w.concurrency {
+		w.concurrency = opts.Concurrency
+		w.limiter.max, w.limiter.limit = int32(opts.Concurrency), int32(opts.Concurrency)
+	}
+
+	w.polite = &polite{opts: opts, entries: make(map[string]*list.Element), lru: list.New()}
+	return w
+}
+
+// polite is the state of the polite mode, shared by the api calls of all the jobs of the worker
+type polite struct {
+	opts PoliteOptions
+
+	mu sync.Mutex
+
+	// next is the earliest time the next api call may be made
+	next time.Time
+
+	// hour is the (clock) hour calls are counted in
+	hour  time.Time
+	calls int
+
+	// cache holds the latest response (with an ETag or Last-Modified) of GET requests, least recently used first
+	entries   map[string]*list.Element
+	lru       *list.List
+	cacheSize int64
+}
+
+// cachedResponse is a response kept to make a conditional request with
+type cachedResponse struct {
+	key          string
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// exhausted reports whether the ceiling of api calls is reached for the current hour
+func (p *polite) exhausted() bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.hour.Equal(time.Now().Truncate(time.Hour)) && p.calls >= p.opts.MaxCallsPerHour
+}
+
+// wait blocks until the next api call may be made (and counts it), or fails if the ceiling for the hour is reached
+func (p *polite) wait(ctx context.Context) error {
+	p.mu.Lock()
+	var now = time.Now()
+	if hour := now.Truncate(time.Hour); !p.hour.Equal(hour) {
+		p.hour, p.calls = hour, 0
+	}
+	if p.calls >= p.opts.MaxCallsPerHour {
+		p.mu.Unlock()
+		return errPoliteCeiling
+	}
+	p.calls++
+
+	var at = p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.opts.MinInterval)
+	p.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(at)):
+		return nil
+	}
+}
+
+// backOff delays the next api calls by the Retry-After of the response, if the provider asked to slow down
+func (p *polite) backOff(resp *http.Response) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+
+	var seconds, err = strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := time.Now().Add(time.Duration(seconds) * time.Second); until.After(p.next) {
+		p.next = until
+	}
+}
+
+func (p *polite) lookup(key string) *cachedResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.entries[key]; ok {
+		p.lru.MoveToBack(e)
+		return e.Value.(*cachedResponse)
+	}
+	return nil
+}
+
+func (p *polite) store(c *cachedResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.entries[c.key]; ok {
+		p.cacheSize -= int64(len(e.Value.(*cachedResponse).body))
+		p.lru.Remove(e)
+	}
+
+	p.entries[c.key] = p.lru.PushBack(c)
+	p.cacheSize += int64(len(c.body))
+
+	for p.cacheSize > p.opts.CacheSize {
+		var oldest = p.lru.Remove(p.lru.Front()).(*cachedResponse)
+		delete(p.entries, oldest.key)
+		p.cacheSize -= int64(len(oldest.body))
+	}
+}
+
+// politeTransport is an http.RoundTripper making the api calls of a job in polite mode
+type politeTransport struct {
+	*polite
+	base http.RoundTripper
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.opts.UserAgent)
+
+	// responses are cached per credential, which the visibility of private resources depends on
+	var key string
+	var cached *cachedResponse
+	if req.Method == http.MethodGet && req.Header.Get("Range") == "" {
+		key = fmt.Sprintf("%s:%x", req.URL.String(), sha256.Sum256([]byte(req.Header.Get("Authorization"))))
+		if cached = t.lookup(key); cached != nil {
+			if cached.etag != "" {
+				req.Header.Set("If-None-Match", cached.etag)
+			} else {
+				req.Header.Set("If-Modified-Since", cached.lastModified)
+			}
+		}
+	}
+
+	var resp, err = t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	t.backOff(resp)
+
+	// the cached response is replayed, with the (rate limit) headers of the 304 response
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		_ = resp.Body.Close()
+
+		var header = cached.header.Clone()
+		for name, values := range resp.Header {
+			header[name] = values
+		}
+		resp.StatusCode, resp.Status, resp.Header = http.StatusOK, "200 OK", header
+		resp.Body, resp.ContentLength = io.NopCloser(bytes.NewReader(cached.body)), int64(len(cached.body))
+		return resp, nil
+	}
+
+	var etag, lastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if key == "" || resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") || resp.ContentLength > maxCachedResponse {
+		return resp, nil
+	}
+
+	// the length of (eg. compressed) responses may be unknown, larger ones are passed through as they're read
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(resp.Body, maxCachedResponse+1)); err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxCachedResponse {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.store(&cachedResponse{key: key, etag: etag, lastModified: lastModified, header: resp.Header.Clone(), body: body})
+	return resp, nil
+}
@@ -0,0 +1,428 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// privacySettings are the deployment wide data minimization settings (see mergestat.privacy_settings),
+// applied to the rows every sync writes
+type privacySettings struct {
+	authorEmails        string // KEEP, HASH or DROP
+	commitMessageBodies string // KEEP or DROP
+	fileContents        string // KEEP or DROP (contents and patches)
+	hashSalt            string
+
+	// erasures are the modes (PSEUDONYMIZE or DELETE) of the erased authors (see mergestat.author_erasures),
+	// by hash of their email or login
+	erasures map[string]string
+
+	// identities are the data types (text, ARRAY or jsonb) of the columns of the owned tables identifying authors
+	// (the email and login columns, and those listed in mergestat.identity_columns), by table and column, loaded
+	// when there are erasures
+	identities map[string]map[string]string
+
+	// columns are the text columns of the owned tables the settings redact, by table, and keys those of them that
+	// are part of a key, whose dropped emails are hashed rather than emptied (as rows would collide)
+	columns map[string][]string
+	keys    map[string]map[string]bool
+}
+
+// privacySettings fetches the current privacy settings
+func (w *worker) privacySettings(ctx context.Context) (*privacySettings, error) {
+	const query = `
+SELECT author_emails, commit_message_bodies, file_contents, hash_salt FROM mergestat.privacy_settings WHERE id`
+
+	var p privacySettings
+	if err := w.pool.QueryRow(ctx, query).Scan(&p.authorEmails, &p.commitMessageBodies, &p.fileContents, &p.hashSalt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &privacySettings{authorEmails: "KEEP", commitMessageBodies: "KEEP", fileContents: "KEEP"}, nil
+		}
+		return nil, err
+	}
+
+	if p.redacts("email") || p.redacts("message") || p.redacts("contents") {
+		if err := p.loadColumns(ctx, w); err != nil {
+			return nil, err
+		}
+	}
+
+	const erasures = `
+SELECT email_hash, mode FROM mergestat.author_erasures WHERE email_hash IS NOT NULL
+UNION ALL
+SELECT login_hash, mode FROM mergestat.author_erasures WHERE login_hash IS NOT NULL`
+
+	var rows, err = w.pool.Query(ctx, erasures)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash, mode string
+		if err = rows.Scan(&hash, &mode); err != nil {
+			return nil, err
+		}
+		if p.erasures == nil {
+			p.erasures = make(map[string]string)
+		}
+		// a deletion supersedes a pseudonymization of the same author
+		if p.erasures[hash] != "DELETE" {
+			p.erasures[hash] = mode
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(p.erasures) > 0 {
+		if err = p.loadIdentities(ctx, w); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// loadColumns loads the text columns of the owned tables (see mergestat.owned_tables) the settings redact
+func (p *privacySettings) loadColumns(ctx context.Context, w *worker) error {
+	const query = `
+SELECT table_name, column_name, mergestat.is_key_column(to_regclass(format('%I.%I', table_schema, physical_name)), column_name)
+FROM mergestat.owned_table_columns WHERE data_type = 'text'`
+
+	var rows, err = w.pool.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	p.columns, p.keys = make(map[string][]string), make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		var key bool
+		if err = rows.Scan(&table, &column, &key); err != nil {
+			return err
+		}
+		if !p.redacts(column) {
+			continue
+		}
+		p.columns[table] = append(p.columns[table], column)
+		if key {
+			if p.keys[table] == nil {
+				p.keys[table] = make(map[string]bool)
+			}
+			p.keys[table][column] = true
+		}
+	}
+	return rows.Err()
+}
+
+// loadIdentities loads the columns of the owned tables identifying authors
+func (p *privacySettings) loadIdentities(ctx context.Context, w *worker) error {
+	const query = `
+SELECT c.table_name, c.column_name, c.data_type, i.column_name IS NOT NULL FROM mergestat.owned_table_columns c
+    LEFT JOIN mergestat.identity_columns i ON i.table_name = c.table_name AND i.column_name = c.column_name`
+
+	var rows, err = w.pool.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	p.identities = make(map[string]map[string]string)
+	for rows.Next() {
+		var table, column, dataType string
+		var listed bool
+		if err = rows.Scan(&table, &column, &dataType, &listed); err != nil {
+			return err
+		}
+		if !listed && (dataType != "text" || !isEmailColumn(column) && !isLoginColumn(column)) {
+			continue
+		}
+		if p.identities[table] == nil {
+			p.identities[table] = make(map[string]string)
+		}
+		p.identities[table][column] = dataType
+	}
+	return rows.Err()
+}
+
+// isEmailColumn reports whether the column holds email addresses
+func isEmailColumn(column string) bool {
+	return column == "email" || strings.HasSuffix(column, "_email")
+}
+
+// isLoginColumn reports whether the column holds logins (eg. on GitHub)
+func isLoginColumn(column string) bool {
+	return column == "login" || strings.HasSuffix(column, "_login")
+}
+
+// redacts reports whether the settings change the values of the given column
+func (p *privacySettings) redacts(column string) bool {
+	switch {
+	case isEmailColumn(column):
+		return p.authorEmails != "KEEP"
+	case column == "message":
+		return p.commitMessageBodies != "KEEP"
+	case column == "contents" || column == "patch":
+		return p.fileContents != "KEEP"
+	default:
+		return false
+	}
+}
+
+// active reports whether the settings redact anything
+func (p *privacySettings) active() bool {
+	return p.redacts("email") || p.redacts("message") || p.redacts("contents") || len(p.erasures) > 0
+}
+
+// hash hashes the value like mergestat.privacy_hash does
+func (p *privacySettings) hash(value string) string {
+	var sum = sha256.Sum256([]byte(p.hashSalt + strings.ToLower(value)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// textValue returns the value as a string, if it's a (non-null) text value
+func textValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case *string:
+		if v == nil {
+			return "", false
+		}
+		return *v, true
+	case sql.NullString:
+		return v.String, v.Valid
+	default:
+		return "", false
+	}
+}
+
+// redact returns the value of the column of the table, redacted following the settings
+func (p *privacySettings) redact(table, column string, value interface{}) interface{} {
+	var s, ok = textValue(value)
+	if !ok {
+		return value
+	}
+
+	switch {
+	case isEmailColumn(column) && s != "" && p.authorEmails == "HASH":
+		return p.hash(s)
+	case isEmailColumn(column) && p.authorEmails == "DROP" && s != "" && p.keys[table][column]:
+		return p.hash(s)
+	case isEmailColumn(column) && p.authorEmails == "DROP":
+		return ""
+	case column == "message" && p.commitMessageBodies == "DROP":
+		subject, _, _ := strings.Cut(s, "\n")
+		return subject
+	case column == "contents" && p.fileContents == "DROP":
+		return nil
+	case column == "patch" && p.fileContents == "DROP":
+		// patches (see git_commit_patches) aren't nullable, they're emptied
+		return ""
+	default:
+		return value
+	}
+}
+
+// erases reports whether the rows with the given columns of the table may identify an erased author
+func (p *privacySettings) erases(table string, columns []string) bool {
+	if len(p.erasures) == 0 {
+		return false
+	}
+	for _, column := range columns {
+		if isEmailColumn(column) || isLoginColumn(column) || p.identities[table][column] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// erasure returns the hash and the mode of the erasure of the value, if it's the identity of an erased author
+func (p *privacySettings) erasure(value string) (hash, mode string, erased bool) {
+	if value == "" {
+		return "", "", false
+	}
+	if hash = value; !strings.HasPrefix(value, "sha256:") {
+		hash = p.hash(value)
+	}
+	mode, erased = p.erasures[hash]
+	return hash, mode, erased
+}
+
+// pseudonym returns the pseudonym of the erased author with the given hash (see mergestat.erasure_pseudonym)
+func pseudonym(hash string, email bool) string {
+	if email {
+		return "erased-" + hash[7:19] + "@erased.invalid"
+	}
+	return "erased-" + hash[7:19]
+}
+
+// erase applies the erasures to the row of the table, like mergestat.erase_author does to the rows already synced:
+// the identity of an erased author (and the name next to it) is replaced with their pseudonym, in arrays and JSON
+// values too. It reports false if the row must be dropped instead (an erased author is removed from arrays rather).
+// Values are matched before or after being hashed by the settings.
+func (p *privacySettings) erase(table string, columns []string, row []interface{}) bool {
+	for i, column := range columns {
+		var conventional = isEmailColumn(column) || isLoginColumn(column)
+
+		switch p.identities[table][column] {
+		case "ARRAY":
+			row[i] = p.eraseArray(row[i])
+			continue
+		case "jsonb":
+			var value, keep = p.eraseJSON(row[i])
+			if !keep {
+				return false
+			}
+			row[i] = value
+			continue
+		case "":
+			if !conventional {
+				continue
+			}
+		}
+
+		var s, ok = textValue(row[i])
+		if !ok {
+			continue
+		}
+		var hash, mode, erased = p.erasure(s)
+		if !erased {
+			continue
+		}
+		if mode == "DELETE" {
+			return false
+		}
+
+		row[i] = pseudonym(hash, isEmailColumn(column) || !conventional && strings.Contains(s, "@"))
+		if !conventional {
+			continue
+		}
+
+		var name = strings.TrimSuffix(strings.TrimSuffix(column, "email"), "login") + "name"
+		for j := range columns {
+			if columns[j] == name {
+				row[j] = pseudonym(hash, false)
+			}
+		}
+	}
+	return true
+}
+
+// eraseArray removes the erased authors from an array of identities, or replaces them with their pseudonyms
+func (p *privacySettings) eraseArray(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []string:
+		var erased = make([]string, 0, len(v))
+		for _, s := range v {
+			var hash, mode, ok = p.erasure(s)
+			switch {
+			case !ok:
+				erased = append(erased, s)
+			case mode != "DELETE":
+				erased = append(erased, pseudonym(hash, strings.Contains(s, "@")))
+			}
+		}
+		return erased
+	case []interface{}:
+		var erased = make([]interface{}, 0, len(v))
+		for _, e := range v {
+			var s, text = textValue(e)
+			var hash, mode, ok = p.erasure(s)
+			switch {
+			case !text || !ok:
+				erased = append(erased, e)
+			case mode != "DELETE":
+				erased = append(erased, pseudonym(hash, strings.Contains(s, "@")))
+			}
+		}
+		return erased
+	default:
+		return value
+	}
+}
+
+// eraseJSON replaces the strings of a JSON value (encoded, or not) identifying erased authors with their pseudonyms.
+// It reports false if one of them is to be deleted.
+func (p *privacySettings) eraseJSON(value interface{}) (_ interface{}, keep bool) {
+	var decoded interface{}
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case []byte:
+		if json.Unmarshal(v, &decoded) != nil {
+			return value, true
+		}
+	case string:
+		if json.Unmarshal([]byte(v), &decoded) != nil {
+			return value, true
+		}
+	default:
+		decoded = v
+	}
+
+	var changed bool
+	var walk func(interface{}) (interface{}, bool)
+	walk = func(v interface{}) (interface{}, bool) {
+		switch v := v.(type) {
+		case string:
+			var hash, mode, erased = p.erasure(v)
+			if !erased {
+				return v, true
+			}
+			if mode == "DELETE" {
+				return nil, false
+			}
+			changed = true
+			return pseudonym(hash, strings.Contains(v, "@")), true
+		case map[string]interface{}:
+			for key, e := range v {
+				var erased, keep = walk(e)
+				if !keep {
+					return nil, false
+				}
+				v[key] = erased
+			}
+		case []interface{}:
+			for i, e := range v {
+				var erased, keep = walk(e)
+				if !keep {
+					return nil, false
+				}
+				v[i] = erased
+			}
+		}
+		return v, true
+	}
+
+	if decoded, keep = walk(decoded); !keep {
+		return nil, false
+	}
+	if !changed {
+		return value, true
+	}
+
+	switch value.(type) {
+	case []byte, string:
+		var encoded, err = json.Marshal(decoded)
+		if err != nil {
+			return value, true
+		}
+		if _, ok := value.(string); ok {
+			return string(encoded), true
+		}
+		return encoded, true
+	default:
+		return decoded, true
+	}
+}
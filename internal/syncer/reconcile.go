@@ -0,0 +1,38 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// LogFormatRowCountMismatch is the message for a discrepancy between rows a sync expected to write and rows found
+const LogFormatRowCountMismatch = "row count mismatch in %s: expected %d row(s) but found %d"
+
+// reconcileRowCount compares the number of rows found in table for the job's repo against the number of
+// rows the sync expected to write, once the sync's transaction has committed. Any discrepancy (eg. a COPY batch
+// that was partially lost) is logged as a warning. It never fails the job, since the data is already committed.
+func (w *worker) reconcileRowCount(ctx context.Context, j *db.DequeueSyncJobRow, table string, expected int) {
+	var actual int64
+	var query = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE repo_id = $1", pgx.Identifier{table}.Sanitize())
+	if err := w.reader(ctx).QueryRow(ctx, query, j.RepoID).Scan(&actual); err != nil {
+		w.loggerForJob(j).Err(err).Msgf("could not count rows in %s", table)
+		return
+	}
+
+	if actual != int64(expected) {
+		w.warnForJob(ctx, j, fmt.Sprintf(LogFormatRowCountMismatch, table, expected, actual),
+			logDetails{"table": table, "expected_rows": expected, "rows": actual})
+	}
+}
+
+// warnForJob logs a warning both to the worker log and to the job's sync logs
+func (w *worker) warnForJob(ctx context.Context, j *db.DequeueSyncJobRow, message string, details logDetails) {
+	w.loggerForJob(j).Warn().Fields(map[string]interface{}(details)).Msg(message)
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeWarn, RepoSyncQueueID: j.ID, Message: message, Details: details}}); err != nil {
+		w.logger.Err(err).Msgf("error sending log warning message: %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// handleReleaseChangelogs derives the releases of the repo, and their changelogs, from its tags, commits and pull requests
+func (w *worker) handleReleaseChangelogs(ctx context.Context, j *db.DequeueSyncJobRow) error {
+	return w.handleDerived(ctx, j, "derive_release_changelogs", "git_releases")
+}
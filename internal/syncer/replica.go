@@ -0,0 +1,58 @@
+package syncer
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// maxReplicaWait is how long to wait for the read replica to catch up with the primary, before reading from the primary
+const maxReplicaWait = 10 * time.Second
+
+// WithReadReplica configures the worker to run heavy read queries (eg. row count reconciliation) against
+// the given read-only pool instead of the primary, which is then only used for the queue and writes.
+func (w *worker) WithReadReplica(replica *pgxpool.Pool) *worker {
+	w.replica = replica
+	return w
+}
+
+// reader returns the pool to run read queries against. If a read replica is configured, it's returned once
+// it has replayed everything committed on the primary so far (so that reads see the rows a sync just wrote).
+// The primary is returned if there's no replica, or it doesn't catch up in time.
+func (w *worker) reader(ctx context.Context) *pgxpool.Pool {
+	if w.replica == nil || !w.dialect.WALFunctions {
+		return w.pool
+	}
+
+	var lsn string
+	if err := w.pool.QueryRow(ctx, "SELECT pg_current_wal_lsn()::TEXT").Scan(&lsn); err != nil {
+		w.logger.Warn().AnErr("error", err).Msg("could not fetch primary wal position, reading from primary")
+		return w.pool
+	}
+
+	var deadline = time.Now().Add(maxReplicaWait)
+	for {
+		var caughtUp bool
+		const replayed = "SELECT COALESCE(pg_last_wal_replay_lsn() >= $1::PG_LSN, FALSE)"
+		if err := w.replica.QueryRow(ctx, replayed, lsn).Scan(&caughtUp); err != nil {
+			w.logger.Warn().AnErr("error", err).Msg("could not fetch replica wal position, reading from primary")
+			return w.pool
+		}
+
+		if caughtUp {
+			return w.replica
+		}
+
+		if time.Now().After(deadline) {
+			w.logger.Warn().Msgf("read replica is lagging behind the primary by more than %s, reading from primary", maxReplicaWait)
+			return w.pool
+		}
+
+		select {
+		case <-ctx.Done():
+			return w.pool
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
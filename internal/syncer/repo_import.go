@@ -0,0 +1,104 @@
+package syncer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// runGit runs git with the given args (and stdin, if any) in the given directory, and returns its output
+func runGit(ctx context.Context, dir string, stdin io.Reader, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir, cmd.Stdin, cmd.Stdout, cmd.Stderr = dir, stdin, &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// importRepo creates the repository (and its checkout) at path from the git bundle or fast-export stream at source,
+// for repos with no remote to clone from (eg. from a decommissioned server). It requires the git executable.
+func importRepo(ctx context.Context, path string, kind helper.RepoImportKind, source string) (err error) {
+	if _, err = os.Stat(source); err != nil {
+		return fmt.Errorf("import source: %w", err)
+	}
+
+	// a bundle is cloned from like any remote, and its HEAD (if it has one) is checked out
+	if kind == helper.RepoImportBundle {
+		if _, err = runGit(ctx, path, nil, "clone", "--quiet", source, "."); err != nil {
+			return err
+		}
+		if _, err = runGit(ctx, path, nil, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+			return nil
+		}
+	} else {
+		var stream io.ReadCloser
+		if stream, err = os.Open(source); err != nil {
+			return err
+		}
+		defer stream.Close()
+
+		if strings.HasSuffix(source, ".gz") {
+			var gz *gzip.Reader
+			if gz, err = gzip.NewReader(stream); err != nil {
+				return fmt.Errorf("decompress stream: %w", err)
+			}
+			defer gz.Close()
+			stream = gz
+		}
+
+		if _, err = runGit(ctx, path, nil, "init", "--quiet"); err != nil {
+			return err
+		}
+		if _, err = runGit(ctx, path, stream, "fast-import", "--quiet"); err != nil {
+			return err
+		}
+	}
+
+	// a fast-export stream (or a bundle without HEAD) has no HEAD, one of its branches is checked out
+	var branches string
+	if branches, err = runGit(ctx, path, nil, "for-each-ref", "--format=%(refname)", "refs/heads/", "refs/remotes/origin/"); err != nil {
+		return err
+	}
+
+	// the branches of a cloned bundle are remote-tracking branches, they're checked out as local branches
+	var refs = make(map[string]string)
+	var names []string
+	for _, ref := range strings.Fields(branches) {
+		var name = "refs/heads/" + strings.TrimPrefix(strings.TrimPrefix(ref, "refs/heads/"), "refs/remotes/origin/")
+		if _, seen := refs[name]; !seen {
+			refs[name], names = ref, append(names, name)
+		}
+	}
+
+	var head = helper.DefaultImportBranch(names)
+	if head == "" {
+		return fmt.Errorf("%s contains no branches", source)
+	}
+
+	if refs[head] != head {
+		_, err = runGit(ctx, path, nil, "checkout", "--quiet", "-B", strings.TrimPrefix(head, "refs/heads/"), refs[head])
+	} else if _, err = runGit(ctx, path, nil, "symbolic-ref", "HEAD", head); err == nil {
+		_, err = runGit(ctx, path, nil, "reset", "--quiet", "--hard")
+	}
+
+	return err
+}
+
+// importChecksum identifies the state of the file a repo is imported from, by its size and modification time
+func importChecksum(source string) (string, error) {
+	var info, err = os.Stat(source)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("import:%d:%d", info.Size(), info.ModTime().Unix()), nil
+}
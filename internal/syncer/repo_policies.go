@@ -0,0 +1,12 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// handleRepoPolicies checks the repo against the enabled rules in mergestat.repo_policy_rules
+func (w *worker) handleRepoPolicies(ctx context.Context, j *db.DequeueSyncJobRow) error {
+	return w.handleDerived(ctx, j, "evaluate_repo_policies", "repo_policy_results")
+}
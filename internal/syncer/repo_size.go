@@ -0,0 +1,97 @@
+package syncer
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// headOnlySyncTypes are the sync types that only read the files at HEAD, whose clone may be shallow (see helper.SyncStrategy)
+var headOnlySyncTypes = map[string]bool{
+	syncTypeGitFiles:                  true,
+	syncTypeGitSymbols:                true,
+	syncTypeGosecRepoScan:             true,
+	syncTypeGrypeScan:                 true,
+	syncTypeYelpDetectSecretsRepoScan: true,
+}
+
+// refSyncTypes are the sync types that read the refs of the repo (its tags included), whose clone always fetches
+// the tags (see helper.SyncStrategy)
+var refSyncTypes = map[string]bool{
+	syncTypeGitRefs:   true,
+	syncTypeGitMirror: true,
+}
+
+type syncStrategyKey struct{}
+
+// withSyncStrategy returns a context carrying the strategy of the job, chosen by the size tier of its repo: the sizeTier
+// repo setting if set, else the tier of the largest of its disk usage (as reported by GitHub) and the size of its last clone
+func (w *worker) withSyncStrategy(ctx context.Context, j *db.DequeueSyncJobRow) context.Context {
+	const repoSize = `
+SELECT GREATEST(
+    COALESCE((SELECT MAX(gri.size)::BIGINT * 1024 FROM github_repo_info gri WHERE gri.repo_id = r.id), 0),
+    COALESCE((SELECT rs.clone_bytes FROM mergestat.repo_sizes rs WHERE rs.repo_id = r.id), 0)
+), COALESCE(r.settings->>'sizeTier', '')
+FROM public.repos r WHERE r.id = $1`
+
+	var size int64
+	var override string
+	if err := w.pool.QueryRow(ctx, repoSize, j.RepoID).Scan(&size, &override); err != nil {
+		w.loggerForJob(j).Warn().AnErr("error", err).Msg("could not load size of repo, using the default sync strategy")
+		return context.WithValue(ctx, syncStrategyKey{}, helper.SyncStrategyFor(helper.RepoSizeMedium))
+	}
+
+	var tier, ok = helper.ParseRepoSizeTier(override)
+	if !ok {
+		tier = helper.ClassifyRepoSize(size)
+	}
+
+	w.loggerForJob(j).Debug().Msgf("repo is %s (%d bytes), syncing with its strategy", tier, size)
+	return context.WithValue(ctx, syncStrategyKey{}, helper.SyncStrategyFor(tier))
+}
+
+// syncStrategy returns the strategy of the job the context belongs to (see withSyncStrategy)
+func syncStrategy(ctx context.Context) helper.SyncStrategy {
+	if s, ok := ctx.Value(syncStrategyKey{}).(helper.SyncStrategy); ok {
+		return s
+	}
+	return helper.SyncStrategyFor(helper.RepoSizeMedium)
+}
+
+// withTags returns a context whose sync strategy fetches the tags of the repo, whatever its size (eg. to blame at a tag)
+func withTags(ctx context.Context) context.Context {
+	var s = syncStrategy(ctx)
+	s.NoTags = false
+	return context.WithValue(ctx, syncStrategyKey{}, s)
+}
+
+// recordRepoSize measures the clone of the job's repo at path, and records its size and tier
+func (w *worker) recordRepoSize(ctx context.Context, j *db.DequeueSyncJobRow, path string) {
+	var size int64
+	var err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		var info fs.FileInfo
+		if info, err = d.Info(); err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		w.loggerForJob(j).Warn().AnErr("error", err).Msg("could not measure size of clone")
+		return
+	}
+
+	const record = `
+INSERT INTO mergestat.repo_sizes (repo_id, clone_bytes, tier) VALUES ($1, $2, $3)
+ON CONFLICT (repo_id) DO UPDATE SET clone_bytes = excluded.clone_bytes, tier = excluded.tier, measured_at = now()`
+
+	if _, err = w.pool.Exec(ctx, record, j.RepoID, size, string(helper.ClassifyRepoSize(size))); err != nil {
+		w.loggerForJob(j).Warn().AnErr("error", err).Msg("could not record size of repo")
+	}
+}
@@ -0,0 +1,200 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/rs/zerolog"
+)
+
+// searchIndex is a search index configured in mergestat.search_indexes
+type searchIndex struct {
+	helper.SearchIndex
+	enabled   bool
+	indexName *string
+
+	// encrypted is set if the column is encrypted (see mergestat.encrypted_columns), which can't be indexed
+	encrypted bool
+}
+
+// maintainSearchIndexes creates the search indexes enabled (see mergestat.search_indexes) on the tables the job bulk
+// loaded rows into, and drops those disabled (or removed), so that search queries are fast without manual work.
+// Indexes are built concurrently, and only by one worker at a time per table. Failures are only logged, and recorded.
+func (w *worker) maintainSearchIndexes(ctx context.Context, j *db.DequeueSyncJobRow) {
+	var tables, ok = bulkTables[j.SyncType]
+	if !ok || !w.dialect.TextSearch {
+		return
+	}
+
+	var logger = w.loggerForJob(j)
+
+	const listSearchIndexes = `
+SELECT table_name, column_name, method, config::TEXT, enabled, index_name FROM mergestat.search_indexes
+WHERE table_name = ANY($1::TEXT[]) ORDER BY table_name, column_name`
+
+	var encrypted, err = encryptedColumns(ctx, w.pool)
+	if err != nil {
+		logger.Warn().AnErr("error", err).Msg("could not fetch encrypted columns")
+		return
+	}
+
+	var indexes = make(map[string][]searchIndex)
+	var rows pgx.Rows
+	rows, err = w.pool.Query(ctx, listSearchIndexes, tables)
+	if err != nil {
+		logger.Warn().AnErr("error", err).Msg("could not fetch search indexes")
+		return
+	}
+	for rows.Next() {
+		var s searchIndex
+		var method string
+		if err = rows.Scan(&s.Table, &s.Column, &method, &s.Config, &s.enabled, &s.indexName); err != nil {
+			rows.Close()
+			logger.Warn().AnErr("error", err).Msg("could not fetch search indexes")
+			return
+		}
+		s.Method = helper.SearchIndexMethod(method)
+		s.encrypted = encrypted[s.Table][s.Column]
+		indexes[s.Table] = append(indexes[s.Table], s)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		logger.Warn().AnErr("error", err).Msg("could not fetch search indexes")
+		return
+	}
+
+	for _, table := range tables {
+		if err = w.maintainSearchIndexesOf(ctx, logger, table, indexes[table]); err != nil {
+			logger.Warn().AnErr("error", err).Msgf("could not maintain search indexes of %s", table)
+		}
+	}
+}
+
+// maintainSearchIndexesOf brings the search indexes of the table in line with their configuration
+func (w *worker) maintainSearchIndexesOf(ctx context.Context, logger *zerolog.Logger, table string, indexes []searchIndex) (err error) {
+	// a session lock is held on a dedicated connection, as indexes can't be built concurrently in a transaction
+	var conn *pgxpool.Conn
+	if conn, err = w.pool.Acquire(ctx); err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if w.dialect.AdvisoryLocks {
+		var locked bool
+		if err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock(hashtext('mergestat.search_indexes'), hashtext($1))", table).Scan(&locked); err != nil {
+			return err
+		}
+		if !locked {
+			return nil // another worker is maintaining the indexes of the table
+		}
+		defer func() {
+			_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock(hashtext('mergestat.search_indexes'), hashtext($1))", table)
+		}()
+	}
+
+	// the indexes live along with the table, in its schema (see WithTableRegistry)
+	var id = w.tables.Identifier(pgx.Identifier{"public", table})
+
+	// existing maps the search indexes of the table (by name) to whether they're valid, ie. their build completed
+	const listExisting = `
+SELECT c.relname, i.indisvalid FROM pg_catalog.pg_index i
+    INNER JOIN pg_catalog.pg_class c ON c.oid = i.indexrelid
+    INNER JOIN pg_catalog.pg_class t ON t.oid = i.indrelid
+    INNER JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname = $1 AND t.relname = $2 AND starts_with(c.relname, $3)`
+
+	var existing = make(map[string]bool)
+	var rows pgx.Rows
+	if rows, err = conn.Query(ctx, listExisting, id[0], id[1], helper.SearchIndexPrefix); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var name string
+		var valid bool
+		if err = rows.Scan(&name, &valid); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = valid
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	var wanted = make(map[string]bool)
+	for _, s := range indexes {
+		if s.enabled && !s.encrypted {
+			wanted[s.Name()] = true
+		}
+	}
+
+	// indexes disabled, reconfigured (under another name), or whose build failed, are dropped
+	for name, valid := range existing {
+		if wanted[name] && valid {
+			continue
+		}
+		if _, err = conn.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+pgx.Identifier{id[0], name}.Sanitize()); err != nil {
+			return err
+		}
+		delete(existing, name)
+		logger.Info().Msgf("dropped search index %s", name)
+	}
+
+	const record = `
+UPDATE mergestat.search_indexes SET index_name = $3, last_error = $4, indexed_at = CASE WHEN $5::BOOLEAN THEN now() ELSE indexed_at END
+WHERE table_name = $1 AND column_name = $2`
+
+	for _, s := range indexes {
+		var name = s.Name()
+		var indexName *string
+		var lastError *string
+		var changed bool
+
+		switch _, exists := existing[name]; {
+		case s.enabled && s.encrypted:
+			var message = fmt.Sprintf("%s.%s is encrypted (see mergestat.encrypted_columns), and can't be indexed", s.Table, s.Column)
+			lastError, changed = &message, s.indexName != nil
+		case !s.enabled:
+			changed = s.indexName != nil
+		case exists:
+			indexName = &name
+			changed = s.indexName == nil || *s.indexName != name
+		default:
+			if err = w.createSearchIndex(ctx, conn, id, s.SearchIndex); err != nil {
+				logger.Warn().AnErr("error", err).Msgf("could not create search index %s", name)
+				var message = err.Error()
+				lastError = &message
+			} else {
+				indexName = &name
+				logger.Info().Msgf("created search index %s", name)
+			}
+			changed = err == nil
+		}
+
+		if _, err = conn.Exec(ctx, record, s.Table, s.Column, indexName, lastError, changed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createSearchIndex builds the index on the table concurrently, dropping what's left of it if the build fails
+func (w *worker) createSearchIndex(ctx context.Context, conn *pgxpool.Conn, table pgx.Identifier, s helper.SearchIndex) (err error) {
+	var definition string
+	if definition, err = s.Definition(table); err != nil {
+		return err
+	}
+
+	if _, err = conn.Exec(ctx, definition); err != nil {
+		// a failed concurrent build leaves an invalid index behind, it's dropped so that it's attempted again
+		_, _ = conn.Exec(context.Background(), "DROP INDEX CONCURRENTLY IF EXISTS "+pgx.Identifier{table[0], s.Name()}.Sanitize())
+		return err
+	}
+	return nil
+}
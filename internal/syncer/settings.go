@@ -0,0 +1,99 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// syncSettings are the user-provided settings of a repo sync (see mergestat.repo_syncs.settings)
+type syncSettings struct {
+	// DryRun, if set, executes the sync against the tables in mergestat_staging instead of public
+	DryRun bool `json:"dryRun"`
+
+	// DisableChangeDetection, if set, always executes the sync even if the source hasn't changed since the last
+	// successful sync (see worker.checksum)
+	DisableChangeDetection bool `json:"disableChangeDetection"`
+
+	// IssueKeyPattern is the regular expression ISSUE_KEY_LINKS syncs match issue keys with (see defaultIssueKeyPattern)
+	IssueKeyPattern string `json:"issueKeyPattern"`
+
+	// BusFactorCoverage is the percentage of the contributions GIT_BUS_FACTOR syncs require the authors to cover (defaults to 50)
+	BusFactorCoverage float64 `json:"busFactorCoverage"`
+
+	// BusFactorDepth is how many directory levels deep GIT_BUS_FACTOR syncs compute metrics for (defaults to 2)
+	BusFactorDepth int `json:"busFactorDepth"`
+
+	// LargeFileThreshold is the size (in bytes) above which GIT_LARGE_FILES syncs record a file (defaults to 1 MiB)
+	LargeFileThreshold int64 `json:"largeFileThreshold"`
+
+	// DurationRegressionThreshold is the percentage by which the p95 duration of a workflow must exceed its baseline
+	// for CI_DURATION_REGRESSIONS syncs to record (and notify of) a regression (defaults to 25)
+	DurationRegressionThreshold float64 `json:"durationRegressionThreshold"`
+
+	// BlamePaths, if set, are the globs (eg. src/** or **/*.go) of the files GIT_BLAME syncs blame, other files are skipped
+	BlamePaths []string `json:"blamePaths"`
+
+	// BlameRef and BlameAsOf, if set, have GIT_BLAME syncs blame the repo at the ref (eg. a tag), or at the last commit
+	// (of the ref, or HEAD) before the date (eg. 2023-01-01), into git_blame_snapshots instead of git_blame
+	BlameRef  string `json:"blameRef"`
+	BlameAsOf string `json:"blameAsOf"`
+
+	// BlameAggregatesOnly, if set, has GIT_BLAME syncs only write the rollups of the blame (git_file_ownership and
+	// git_file_line_ages), and remove the lines of the repo from git_blame instead of writing them
+	BlameAggregatesOnly bool `json:"blameAggregatesOnly"`
+
+	// PatchMaxFileBytes and PatchMaxCommitBytes cap the size of the patches GIT_COMMIT_PATCHES syncs store, of each
+	// file and of each commit in total (default to 64 KiB and 1 MiB)
+	PatchMaxFileBytes   int `json:"patchMaxFileBytes"`
+	PatchMaxCommitBytes int `json:"patchMaxCommitBytes"`
+
+	// TestRatioDepth is how many directory levels deep TEST_RATIOS syncs compute ratios for (defaults to 2)
+	TestRatioDepth int `json:"testRatioDepth"`
+
+	// JUnitArtifacts are the globs of the (lowercased) names of the artifacts GITHUB_ACTIONS_TEST_RESULTS syncs download
+	// as JUnit reports (defaults to names containing junit, test-result or test-report)
+	JUnitArtifacts []string `json:"junitArtifacts"`
+
+	// SonarQubeProjectKey is the key of the project the repo is analyzed as (eg. mergestat_mergestat) on the SonarQube
+	// (or SonarCloud) server SONARQUBE syncs import from (see defaultSonarQubeURL)
+	SonarQubeProjectKey string `json:"sonarQubeProjectKey"`
+
+	// IncidentProvider and IncidentServices are the incident management tool INCIDENTS syncs import from (pagerduty
+	// or opsgenie), and the ids of the services of the tool the repo is mapped to
+	IncidentProvider string   `json:"incidentProvider"`
+	IncidentServices []string `json:"incidentServices"`
+
+	// EmbeddingPaths are the globs of the files GIT_EMBEDDINGS syncs embed (defaults to READMEs and documentation, see
+	// helper.DefaultEmbeddingPaths), and EmbeddingMaxItems the most texts a sync embeds (defaults to 5000)
+	EmbeddingPaths    []string `json:"embeddingPaths"`
+	EmbeddingMaxItems int      `json:"embeddingMaxItems"`
+
+	// SummaryRecentCommits is the number of recent commits LLM_SUMMARIES syncs summarize the repo from (defaults to 30),
+	// SummaryPRMinChanges the lines (added and deleted) a pull request must change to be summarized (defaults to 500),
+	// and SummaryMaxPRs the most pull requests a sync summarizes (defaults to 20)
+	SummaryRecentCommits int `json:"summaryRecentCommits"`
+	SummaryPRMinChanges  int `json:"summaryPrMinChanges"`
+	SummaryMaxPRs        int `json:"summaryMaxPrs"`
+}
+
+// settingsForJob decodes the settings of the repo sync the given job belongs to, overridden by the parameters
+// of the job (see mergestat.enqueue_repo_sync)
+func settingsForJob(j *db.DequeueSyncJobRow) (*syncSettings, error) {
+	var settings syncSettings
+	if j.Settings.Status == pgtype.Present && len(j.Settings.Bytes) > 0 {
+		if err := json.Unmarshal(j.Settings.Bytes, &settings); err != nil {
+			return nil, fmt.Errorf("invalid sync settings: %w", err)
+		}
+	}
+
+	if j.Parameters.Status == pgtype.Present && len(j.Parameters.Bytes) > 0 {
+		if err := json.Unmarshal(j.Parameters.Bytes, &settings); err != nil {
+			return nil, fmt.Errorf("invalid job parameters: %w", err)
+		}
+	}
+
+	return &settings, nil
+}
@@ -0,0 +1,325 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+const (
+	// sonarQubePageSize is the number of issues fetched from SonarQube per request (the most the api allows)
+	sonarQubePageSize = 500
+
+	// sonarQubeMaxIssues is the most issues the SonarQube api returns for a search, whatever the page
+	sonarQubeMaxIssues = 10000
+
+	// sonarQubeTimeLayout is the layout of the dates returned by the SonarQube api, eg. 2023-01-02T15:04:05+0000
+	sonarQubeTimeLayout = "2006-01-02T15:04:05-0700"
+
+	// defaultSonarQubeURL is the url of the server, unless set with the SONARQUBE_URL env var (eg. for a self-hosted
+	// SonarQube). It's configured next to SONARQUBE_TOKEN rather than in the (user editable) settings of the sync,
+	// which could send the token anywhere.
+	defaultSonarQubeURL = "https://sonarcloud.io"
+)
+
+// sonarQubeMetrics are the metrics of the project SONARQUBE syncs import
+var sonarQubeMetrics = []string{
+	"alert_status", "bugs", "vulnerabilities", "code_smells", "security_hotspots", "coverage", "duplicated_lines_density",
+	"ncloc", "sqale_index", "sqale_rating", "reliability_rating", "security_rating",
+}
+
+// sonarQubeIssuesColumns are the columns of sonarqube_issues a sync writes
+var sonarQubeIssuesColumns = []string{"repo_id", "project_key", "issue_key", "rule", "type", "severity", "status", "path", "line", "message", "effort", "tags", "created_at", "updated_at"}
+
+// sonarQubeIssue is an issue returned by the SonarQube api (see /api/issues/search)
+type sonarQubeIssue struct {
+	Key          string   `json:"key"`
+	Rule         string   `json:"rule"`
+	Type         string   `json:"type"`
+	Severity     string   `json:"severity"`
+	Status       string   `json:"status"`
+	Component    string   `json:"component"`
+	Line         *int     `json:"line"`
+	Message      string   `json:"message"`
+	Effort       string   `json:"effort"`
+	Tags         []string `json:"tags"`
+	CreationDate string   `json:"creationDate"`
+	UpdateDate   string   `json:"updateDate"`
+}
+
+// sonarQubeClient calls the web api of a SonarQube (or SonarCloud) server, authenticating with the SONARQUBE_TOKEN env var (if set)
+type sonarQubeClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newSonarQubeClient() *sonarQubeClient {
+	var baseURL = defaultSonarQubeURL
+	if u := os.Getenv("SONARQUBE_URL"); u != "" {
+		baseURL = u
+	}
+	return &sonarQubeClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   os.Getenv("SONARQUBE_TOKEN"),
+		client:  &http.Client{Timeout: time.Minute},
+	}
+}
+
+// get decodes the response of the api endpoint at path, called with the given query
+func (c *sonarQubeClient) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		// tokens are passed as the user name, with an empty password
+		req.SetBasicAuth(c.token, "")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sonarqube %s returned %s", path, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode sonarqube response: %w", err)
+	}
+	return nil
+}
+
+// issues returns the unresolved issues of the project, and whether there were more than the api returns
+func (c *sonarQubeClient) issues(ctx context.Context, projectKey string) ([]*sonarQubeIssue, bool, error) {
+	var issues []*sonarQubeIssue
+	for page := 1; ; page++ {
+		var query = url.Values{}
+		query.Set("componentKeys", projectKey)
+		query.Set("resolved", "false")
+		query.Set("ps", fmt.Sprint(sonarQubePageSize))
+		query.Set("p", fmt.Sprint(page))
+
+		var result struct {
+			Issues []*sonarQubeIssue `json:"issues"`
+			Paging struct {
+				Total int `json:"total"`
+			} `json:"paging"`
+		}
+		if err := c.get(ctx, "/api/issues/search", query, &result); err != nil {
+			return nil, false, err
+		}
+
+		issues = append(issues, result.Issues...)
+		if len(result.Issues) == 0 || len(issues) >= result.Paging.Total {
+			return issues, false, nil
+		}
+		if page*sonarQubePageSize >= sonarQubeMaxIssues {
+			return issues, true, nil
+		}
+	}
+}
+
+// measures returns the value of the given metrics of the project, by metric (metrics without a value are left out)
+func (c *sonarQubeClient) measures(ctx context.Context, projectKey string, metrics []string) (map[string]string, error) {
+	var query = url.Values{}
+	query.Set("component", projectKey)
+	query.Set("metricKeys", strings.Join(metrics, ","))
+
+	var result struct {
+		Component struct {
+			Measures []struct {
+				Metric string `json:"metric"`
+				Value  string `json:"value"`
+			} `json:"measures"`
+		} `json:"component"`
+	}
+	if err := c.get(ctx, "/api/measures/component", query, &result); err != nil {
+		return nil, err
+	}
+
+	var measures = make(map[string]string, len(result.Component.Measures))
+	for _, m := range result.Component.Measures {
+		measures[m.Metric] = m.Value
+	}
+	return measures, nil
+}
+
+// qualityGate returns the status of the quality gate of the project, and its (encoded) conditions
+func (c *sonarQubeClient) qualityGate(ctx context.Context, projectKey string) (string, json.RawMessage, error) {
+	var query = url.Values{}
+	query.Set("projectKey", projectKey)
+
+	var result struct {
+		ProjectStatus struct {
+			Status     string          `json:"status"`
+			Conditions json.RawMessage `json:"conditions"`
+		} `json:"projectStatus"`
+	}
+	if err := c.get(ctx, "/api/qualitygates/project_status", query, &result); err != nil {
+		return "", nil, err
+	}
+	return result.ProjectStatus.Status, result.ProjectStatus.Conditions, nil
+}
+
+// sonarQubeTime parses a date returned by the SonarQube api, returning nil if it's missing or malformed
+func sonarQubeTime(s string) interface{} {
+	if t, err := time.Parse(sonarQubeTimeLayout, s); err == nil {
+		return t
+	}
+	return nil
+}
+
+func (w *worker) handleSonarQube(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	if settings.SonarQubeProjectKey == "" {
+		return errors.New("the sonarQubeProjectKey setting is required to sync from SonarQube")
+	}
+
+	var projectKey = settings.SonarQubeProjectKey
+	var client = newSonarQubeClient()
+
+	var issues []*sonarQubeIssue
+	var truncated bool
+	if issues, truncated, err = client.issues(ctx, projectKey); err != nil {
+		return fmt.Errorf("fetch sonarqube issues: %w", err)
+	}
+	if truncated {
+		w.warnForJob(ctx, j, fmt.Sprintf("SonarQube returns at most %d issues, only the first %d of project %s were imported", sonarQubeMaxIssues, len(issues), projectKey),
+			logDetails{"projectKey": projectKey, "issues": len(issues)})
+	}
+
+	var measures map[string]string
+	if measures, err = client.measures(ctx, projectKey, sonarQubeMetrics); err != nil {
+		return fmt.Errorf("fetch sonarqube measures: %w", err)
+	}
+
+	var gateStatus string
+	var gateConditions json.RawMessage
+	if gateStatus, gateConditions, err = client.qualityGate(ctx, projectKey); err != nil {
+		return fmt.Errorf("fetch sonarqube quality gate: %w", err)
+	}
+
+	l.Info().Msgf("found %d issue(s) and %d measure(s) of project %s, quality gate is %s", len(issues), len(measures), projectKey, gateStatus)
+
+	// issues are reported against components, eg. project:path/to/file.go, the path of which is relative to the repo
+	var issueRows = make([][]interface{}, 0, len(issues))
+	for _, issue := range issues {
+		var path interface{}
+		if p := strings.TrimPrefix(issue.Component, projectKey+":"); p != issue.Component && p != "" {
+			path = p
+		}
+		issueRows = append(issueRows, []interface{}{j.RepoID, projectKey, issue.Key, issue.Rule, nullIfEmpty(issue.Type), nullIfEmpty(issue.Severity),
+			nullIfEmpty(issue.Status), path, issue.Line, nullIfEmpty(issue.Message), nullIfEmpty(issue.Effort), issue.Tags,
+			sonarQubeTime(issue.CreationDate), sonarQubeTime(issue.UpdateDate)})
+	}
+
+	var computedAt = time.Now()
+	var measureRows = make([][]interface{}, 0, len(measures))
+	for metric, value := range measures {
+		measureRows = append(measureRows, []interface{}{j.RepoID, computedAt, projectKey, metric, nullIfEmpty(value)})
+	}
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	r, err := tx.Exec(ctx, "DELETE FROM sonarqube_issues WHERE repo_id = $1;", j.RepoID)
+	if err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("removed %d row(s) from sonarqube_issues", r.RowsAffected()),
+		Details:         rowDetails("removed", "sonarqube_issues", r.RowsAffected()),
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"sonarqube_issues"}, sonarQubeIssuesColumns, pgx.CopyFromRows(issueRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	// previous measures and gate statuses are kept, so that they can be trended over time
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"sonarqube_measures"}, []string{"repo_id", "computed_at", "project_key", "metric", "value"}, pgx.CopyFromRows(measureRows)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	var conditions interface{}
+	if len(gateConditions) > 0 {
+		conditions = string(gateConditions)
+	}
+	const insertQualityGate = `INSERT INTO sonarqube_quality_gates (repo_id, computed_at, project_key, status, conditions) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.Exec(ctx, insertQualityGate, j.RepoID, computedAt, projectKey, gateStatus, conditions); err != nil {
+		return fmt.Errorf("insert quality gate: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into sonarqube_issues", len(issueRows)),
+		Details:         rowDetails("inserted", "sonarqube_issues", int64(len(issueRows))),
+	}, {
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into sonarqube_measures", len(measureRows)),
+		Details:         rowDetails("inserted", "sonarqube_measures", int64(len(measureRows))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	w.reconcileRowCount(ctx, j, "sonarqube_issues", len(issueRows))
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/rs/zerolog"
+)
+
+// stagingSchema is the schema that dry-run syncs write into, in place of public
+const stagingSchema = "mergestat_staging"
+
+// cloneTables creates (if it doesn't already exist) an empty copy of every table in public (except repos), and of
+// every synced table relocated out of it (see namespace.Apply), under the given schema. Syncers reference their tables without qualifying the schema, so a connection with
+// the given schema at the front of its search_path writes into the copies instead. Copies whose columns no longer
+// match those of their table (after a migration) are recreated, along with the rows they held.
+func cloneTables(ctx context.Context, pool *pgxpool.Pool, schema string) (err error) {
+	const listTables = `
+SELECT table_name, table_schema, table_name FROM information_schema.tables
+	WHERE table_schema = 'public' AND table_type = 'BASE TABLE' AND table_name <> 'repos'
+UNION ALL
+SELECT table_name, schema_name, physical_name FROM mergestat.synced_tables`
+
+	var tx pgx.Tx
+	if tx, err = pool.Begin(ctx); err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if _, err = tx.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{schema}.Sanitize()); err != nil {
+		return err
+	}
+
+	// copies are named after the tables, the tables relocated out of public are copied from where they live
+	var tables = make(map[string]pgx.Identifier)
+	var rows pgx.Rows
+	if rows, err = tx.Query(ctx, listTables); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var table, source, name string
+		if err = rows.Scan(&table, &source, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables[table] = pgx.Identifier{source, name}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// aligned reports whether the copy ($1) exists and has the columns of its table ($2), in order, with their types
+	const aligned = `
+WITH columns AS (
+	SELECT attrelid, array_agg(attname::TEXT || ' ' || format_type(atttypid, atttypmod) ORDER BY attnum) AS columns
+	FROM pg_attribute WHERE attrelid IN (to_regclass($1), to_regclass($2)) AND attnum > 0 AND NOT attisdropped
+	GROUP BY attrelid
+)
+SELECT to_regclass($1) IS NOT NULL AND
+	(SELECT columns FROM columns WHERE attrelid = to_regclass($1)) = (SELECT columns FROM columns WHERE attrelid = to_regclass($2))`
+
+	for table, source := range tables {
+		var copied = pgx.Identifier{schema, table}.Sanitize()
+
+		var ok bool
+		if err = tx.QueryRow(ctx, aligned, copied, source.Sanitize()).Scan(&ok); err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+
+		if _, err = tx.Exec(ctx, "DROP TABLE IF EXISTS "+copied); err != nil {
+			return err
+		}
+		var stmt = fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING INDEXES)", copied, source.Sanitize())
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// connectWithSchema returns a new pool, with the same configuration as the given one,
+// whose connections resolve unqualified table names to the given schema first.
+func connectWithSchema(ctx context.Context, pool *pgxpool.Pool, schema string, maxConns int32) (*pgxpool.Pool, error) {
+	var config = pool.Config()
+	config.MaxConns = maxConns
+	config.ConnConfig.RuntimeParams["search_path"] = pgx.Identifier{schema}.Sanitize() + ", public"
+
+	return pgxpool.ConnectConfig(ctx, config)
+}
+
+// stagingWorker returns a copy of the worker that writes into the mergestat_staging schema.
+// It's created on first use and is used to execute dry-run syncs.
+func (w *worker) stagingWorker(ctx context.Context) (_ *worker, err error) {
+	w.stagingMu.Lock()
+	defer w.stagingMu.Unlock()
+
+	if w.staging != nil {
+		return w.staging, nil
+	}
+
+	if err = cloneTables(ctx, w.pool, stagingSchema); err != nil {
+		return nil, fmt.Errorf("staging tables: %w", err)
+	}
+
+	var pool *pgxpool.Pool
+	if pool, err = connectWithSchema(ctx, w.pool, stagingSchema, int32(w.concurrency)+1); err != nil {
+		return nil, fmt.Errorf("staging pool: %w", err)
+	}
+
+	var staging = New(pool, w.mergestat, w.logger, w.concurrency, w.pollInterval)
+	staging.localLogs = w.localLogs
+	staging.logs = w.logs
+	staging.plugins = w.plugins
+	staging.cipher = w.cipher
+	staging.WithDialect(w.dialect)
+	staging.embeddings = w.embeddings
+	staging.llm = w.llm
+	// the copies of relocated tables are named after the tables themselves, so the staging worker has no registry
+
+	w.staging = staging
+	return w.staging, nil
+}
+
+// lockStagingRepo serializes the dry runs of the repo on the worker, so that the rows of the repo in mergestat_staging
+// are those of a single dry run at a time. It returns the function unlocking the repo.
+func (w *worker) lockStagingRepo(repo uuid.UUID) func() {
+	w.stagingMu.Lock()
+	if w.stagingRepos == nil {
+		w.stagingRepos = make(map[uuid.UUID]*sync.Mutex)
+	}
+	var mu, ok = w.stagingRepos[repo]
+	if !ok {
+		mu = &sync.Mutex{}
+		w.stagingRepos[repo] = mu
+	}
+	w.stagingMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// repoTables returns the tables (with a repo_id column) of the given schema
+func repoTables(ctx context.Context, pool *pgxpool.Pool, schema string) ([]string, error) {
+	const listTables = `
+SELECT table_name FROM information_schema.columns
+	WHERE table_schema = $1 AND column_name = 'repo_id' ORDER BY table_name`
+
+	return helper.CollectStrings(pool.Query(ctx, listTables, schema))
+}
+
+// clearRows deletes the rows for the given repo from each table (with a repo_id column) of the given schema
+func clearRows(ctx context.Context, pool *pgxpool.Pool, schema string, repo uuid.UUID) (err error) {
+	var tables []string
+	if tables, err = repoTables(ctx, pool, schema); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err = pool.Exec(ctx, "DELETE FROM "+pgx.Identifier{schema, table}.Sanitize()+" WHERE repo_id = $1", repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableRowCount is the number of rows a table contains for a given repo
+type tableRowCount struct {
+	Table string
+	Rows  int64
+}
+
+// countRows returns the number of rows for the given repo in each table (with a repo_id column) of the given schema.
+// Tables without any rows for the repo are omitted.
+func countRows(ctx context.Context, pool *pgxpool.Pool, schema string, repo uuid.UUID) (_ []tableRowCount, err error) {
+	var tables []string
+	if tables, err = repoTables(ctx, pool, schema); err != nil {
+		return nil, err
+	}
+
+	var counts []tableRowCount
+	for _, table := range tables {
+		var count int64
+		var query = "SELECT COUNT(*) FROM " + pgx.Identifier{schema, table}.Sanitize() + " WHERE repo_id = $1"
+		if err = pool.QueryRow(ctx, query, repo).Scan(&count); err != nil {
+			return nil, err
+		}
+
+		if count > 0 {
+			counts = append(counts, tableRowCount{Table: table, Rows: count})
+		}
+	}
+
+	return counts, nil
+}
+
+// logRowCounts sends the result of countRows to the given logger
+func logRowCounts(logger *zerolog.Logger, schema string, counts []tableRowCount) {
+	for _, c := range counts {
+		logger.Info().Str("table", c.Table).Int64("rows", c.Rows).Msgf("%d row(s) in %s.%s", c.Rows, schema, c.Table)
+	}
+}
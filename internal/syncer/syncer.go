@@ -4,7 +4,11 @@ package syncer
 import (
 	"context"
 	"fmt"
+	stdhttp "net/http"
+	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-git/go-billy/v5/osfs"
@@ -16,12 +20,18 @@ import (
 	"github.com/go-git/go-git/v5/storage/filesystem"
 	"github.com/pkg/errors"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 	_ "github.com/mergestat/mergestat-lite/pkg/sqlite"
 	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/dialect"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/namespace"
+	"github.com/mergestat/mergestat/internal/plugins"
 	"github.com/rs/zerolog"
 )
 
@@ -47,6 +57,40 @@ const (
 	syncTypeGosecRepoScan             = "GOSEC_REPO_SCAN"
 	syncTypeOSSFScorecardRepoScan     = "OSSF_SCORECARD_REPO_SCAN"
 	syncTypeGrypeScan                 = "GRYPE_REPO_SCAN"
+	syncTypeGitHubRepoTeams           = "GITHUB_REPO_TEAMS"
+	syncTypeIssueKeyLinks             = "ISSUE_KEY_LINKS"
+	syncTypeGitCommitConventions      = "GIT_COMMIT_CONVENTIONS"
+	syncTypeReleaseChangelogs         = "RELEASE_CHANGELOGS"
+	syncTypeGitBusFactor              = "GIT_BUS_FACTOR"
+	syncTypeGitCommitMetrics          = "GIT_COMMIT_METRICS"
+	syncTypeGitLargeFiles             = "GIT_LARGE_FILES"
+	syncTypeRepoPolicies              = "REPO_POLICIES"
+	syncTypeContainerImages           = "CONTAINER_IMAGES"
+	syncTypeTerraformInventory        = "TERRAFORM_INVENTORY"
+	syncTypeCIInventory               = "CI_INVENTORY"
+	syncTypePackageRegistries         = "PACKAGE_REGISTRIES"
+	syncTypeGitHubProjects            = "GITHUB_PROJECTS"
+	syncTypeGitCommitPullRequests     = "GIT_COMMIT_PULL_REQUESTS"
+	syncTypeGitHubIssueResponseTimes  = "GITHUB_ISSUE_RESPONSE_TIMES"
+	syncTypeGitHubReviewLoad          = "GITHUB_REVIEW_LOAD"
+	syncTypeGitHubActionsUsage        = "GITHUB_ACTIONS_USAGE"
+	syncTypeGitHubActionsTestResults  = "GITHUB_ACTIONS_TEST_RESULTS"
+	syncTypeCIFlakyJobs               = "CI_FLAKY_JOBS"
+	syncTypeCIDurationRegressions     = "CI_DURATION_REGRESSIONS"
+	syncTypeGitHubRunners             = "GITHUB_RUNNERS"
+	syncTypeGitHubOrgAuditLog         = "GITHUB_ORG_AUDIT_LOG"
+	syncTypeGitMirror                 = "GIT_MIRROR"
+	syncTypeGitCommitPatches          = "GIT_COMMIT_PATCHES"
+	syncTypeGitSymbols                = "GIT_SYMBOLS"
+	syncTypeCodeImports               = "CODE_IMPORTS"
+	syncTypeTestRatios                = "TEST_RATIOS"
+	syncTypeSonarQube                 = "SONARQUBE"
+	syncTypeIncidents                 = "INCIDENTS"
+	syncTypeChangeFailures            = "CHANGE_FAILURES"
+	syncTypeDependencyUpdateLag       = "DEPENDENCY_UPDATE_LAG"
+	syncTypeGitHubForkDrift           = "GITHUB_FORK_DRIFT"
+	syncTypeGitEmbeddings             = "GIT_EMBEDDINGS"
+	syncTypeLLMSummaries              = "LLM_SUMMARIES"
 )
 
 var errGitHubTokenRequired = errors.New("in order to run this syncer, a GitHub authentication token must be present")
@@ -58,6 +102,72 @@ type worker struct {
 	db           *db.Queries
 	concurrency  int
 	pollInterval time.Duration
+
+	// dialect is the backend the worker is connected to (see WithDialect)
+	dialect *dialect.Dialect
+
+	// replica, if set, is a read-only pool heavy read queries are run against (see reader)
+	replica *pgxpool.Pool
+
+	// limiter adjusts the number of exec loops that dequeue jobs to the database load
+	limiter *limiter
+
+	// plugins are the external binaries implementing additional sync types (see WithPlugins)
+	plugins map[string]*plugins.Plugin
+
+	// maintenance tracks when the tables syncs write into were last analyzed (see maintainTables)
+	maintenance maintenance
+
+	// paused is the state of the maintenance mode, which pauses dequeuing (see watchMaintenanceMode)
+	paused maintenanceMode
+
+	// weights caps the total weight of the jobs the worker runs at once (see WithMaxWeight)
+	weights weights
+
+	// statementTimeout and lockTimeout, if set, are the timeouts those of the transactions of syncs are derived from
+	// (see WithStatementTimeout and WithLockTimeout)
+	statementTimeout *time.Duration
+	lockTimeout      *time.Duration
+
+	// localLogs, if set, sends sync logs to the worker logger instead of mergestat.repo_sync_logs
+	localLogs bool
+
+	// chaos, if set, enables the chaos test mode (see WithChaos)
+	chaos *ChaosOptions
+
+	// transport, if set, is the http transport api calls are made through instead of http.DefaultTransport (see WithTransport)
+	transport stdhttp.RoundTripper
+
+	// providerUsage counts the api calls made through provider transports (see ProviderTransport)
+	providerUsage providerUsage
+
+	// anonymous, if set, lets syncs call the GitHub api without a credential (see WithAnonymousAccess)
+	anonymous bool
+
+	// polite, if set, paces and caps the api calls of the worker (see WithPoliteMode)
+	polite *polite
+
+	// cipher, if set, encrypts the columns listed in mergestat.encrypted_columns (see WithColumnEncryption)
+	cipher *helper.ColumnCipher
+
+	// embeddings, if set, is the model GIT_EMBEDDINGS syncs compute embeddings with (see WithEmbeddings)
+	embeddings *EmbeddingOptions
+
+	// llm, if set, is the model LLM_SUMMARIES syncs generate summaries with (see WithLLM)
+	llm *LLMOptions
+
+	// tables resolves the synced tables relocated out of public to where they live (see WithTableRegistry)
+	tables namespace.Registry
+
+	// logs buffers the sync logs of jobs until they're written to mergestat.repo_sync_logs
+	logs *logBuffer
+
+	// staging is a copy of this worker that writes into mergestat_staging (see stagingWorker)
+	staging   *worker
+	stagingMu sync.Mutex
+
+	// stagingRepos are the locks serializing the dry runs of each repo (see lockStagingRepo)
+	stagingRepos map[uuid.UUID]*sync.Mutex
 }
 
 func New(pool *pgxpool.Pool, mergestat *sqlx.DB, logger *zerolog.Logger, concurrency int, pollInterval time.Duration) *worker {
@@ -68,6 +178,9 @@ func New(pool *pgxpool.Pool, mergestat *sqlx.DB, logger *zerolog.Logger, concurr
 		db:           db.New(pool),
 		concurrency:  concurrency,
 		pollInterval: pollInterval,
+		limiter:      newLimiter(logger, pool, concurrency),
+		dialect:      dialect.Postgres,
+		logs:         newLogBuffer(pool, logger),
 	}
 }
 
@@ -83,7 +196,20 @@ func (w *worker) dequeue(ctx context.Context) (*db.DequeueSyncJobRow, error) {
 		case <-time.After(w.pollInterval):
 			var job db.DequeueSyncJobRow
 			var err error
-			if job, err = w.db.DequeueSyncJob(ctx); err != nil {
+
+			// the weight of the job is taken while dequeues are serialized, and released once it's done (see exec)
+			w.weights.mu.Lock()
+			var available = w.weights.available()
+			if available <= 0 {
+				w.weights.mu.Unlock()
+				continue
+			}
+			if job, err = w.db.DequeueSyncJob(ctx, available); err == nil {
+				w.weights.inFlight += job.Weight
+			}
+			w.weights.mu.Unlock()
+
+			if err != nil {
 				if errors.Is(err, pgx.ErrNoRows) {
 					continue
 				}
@@ -96,7 +222,9 @@ func (w *worker) dequeue(ctx context.Context) (*db.DequeueSyncJobRow, error) {
 }
 
 // exec loops until the context is canceled, executing a sync.
-func (w *worker) exec(ctx context.Context, id string) {
+// The loop only dequeues jobs while its slot is allowed by the worker's limiter.
+func (w *worker) exec(ctx context.Context, slot int) {
+	var id = strconv.Itoa(slot)
 	w.logger.Info().Msgf("starting exec loop: %s", id)
 	for {
 		select {
@@ -106,6 +234,14 @@ func (w *worker) exec(ctx context.Context, id string) {
 				return
 			}
 		default:
+			if !w.limiter.allows(slot) || w.paused.active() || w.polite.exhausted() {
+				select {
+				case <-ctx.Done():
+				case <-time.After(w.pollInterval):
+				}
+				continue
+			}
+
 			j, err := w.dequeue(ctx)
 			if err != nil {
 				// if error is a context cancellation, go to next tick of loop where
@@ -120,14 +256,23 @@ func (w *worker) exec(ctx context.Context, id string) {
 
 			w.loggerForJob(j).Info().Msg("dequeued job")
 
-			if err := w.handle(ctx, j); err != nil {
+			var started = time.Now()
+			atomic.AddInt32(&w.paused.inFlight, 1)
+			err = w.handle(ctx, j)
+			atomic.AddInt32(&w.paused.inFlight, -1)
+			w.weights.release(j.Weight)
+			if err != nil {
 				if !errors.Is(err, context.Canceled) {
-					w.logger.Warn().AnErr("error", err).Msgf("error handling job: %v", j)
+					w.loggerForJob(j).Warn().AnErr("error", err).Msg("error handling job")
+
+					var details = errorDetails(err)
+					details["duration_ms"] = time.Since(started).Milliseconds()
 
 					if err := w.db.InsertSyncJobLog(context.TODO(), db.InsertSyncJobLogParams{
 						LogType:         string(SyncLogTypeError),
 						Message:         err.Error(),
 						RepoSyncQueueID: j.ID,
+						Details:         pgtype.JSONB{Bytes: details.encode(), Status: pgtype.Present},
 					}); err != nil {
 						w.logger.Err(err).Msgf("error sending log error message: %v", err)
 					}
@@ -153,13 +298,128 @@ func (w *worker) exec(ctx context.Context, id string) {
 	}
 }
 
-// handle maps jobs to the right handler (see handlers.go)
+// handle executes the job, honouring the settings of the repo sync it belongs to
 func (w *worker) handle(ctx context.Context, j *db.DequeueSyncJobRow) error {
 	w.loggerForJob(j).Info().Msg("handling job")
 
 	done := w.startKeepAlives(j, 30*time.Second)
 	defer done()
 
+	defer w.injectChaos(j)()
+
+	// the job's logs are all written by the time it's handled (and, on error, before its error is logged)
+	defer w.logs.finish(context.Background(), j.ID)
+
+	ctx, recordAPIUsage := w.trackAPIUsage(ctx, j)
+	defer recordAPIUsage()
+
+	settings, err := settingsForJob(j)
+	if err != nil {
+		return err
+	}
+
+	if settings.DryRun {
+		return w.handleDryRun(ctx, j)
+	}
+
+	// clones, batch sizes and timeouts are adapted to the size of the repo
+	ctx = w.withSyncStrategy(ctx, j)
+
+	// a job with parameters is a one-off run (eg. a blame at a point in time), it's never skipped nor sets the checksum
+	var checksum string
+	if !settings.DisableChangeDetection && j.Parameters.Status != pgtype.Present {
+		if checksum, err = w.checksum(ctx, j); err != nil {
+			// failing to detect changes is never fatal, we just run the sync
+			w.loggerForJob(j).Warn().AnErr("error", err).Msg("could not compute checksum, skipping change detection")
+			checksum = ""
+		}
+
+		if checksum != "" && j.LastCompletedChecksum.Valid && j.LastCompletedChecksum.String == checksum {
+			return w.skipUnchanged(ctx, j, checksum)
+		}
+	}
+
+	if err = w.run(ctx, j); err != nil {
+		return err
+	}
+
+	w.populateTableVersions(ctx, j)
+	w.maintainTables(ctx, j)
+	w.maintainSearchIndexes(ctx, j)
+	w.enqueueFollowUps(ctx, j)
+	w.recordVersion(ctx, j)
+
+	if checksum != "" {
+		if err = w.db.SetSyncChecksum(ctx, db.SetSyncChecksumParams{Checksum: checksum, ID: j.RepoSyncID}); err != nil {
+			w.loggerForJob(j).Err(err).Msg("could not record checksum of sync")
+		}
+	}
+
+	return nil
+}
+
+// skipUnchanged marks the job as done without executing it, since its source hasn't changed since the last successful sync
+func (w *worker) skipUnchanged(ctx context.Context, j *db.DequeueSyncJobRow, checksum string) error {
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf("no changes since the last successful sync (%s), skipping", checksum),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	if err := w.db.SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	return nil
+}
+
+// handleDryRun executes the job using the staging worker, so that none of the production tables are modified,
+// and reports the number of rows the sync wrote into mergestat_staging. The rows of the repo left there by earlier
+// dry runs are deleted first, so that only the tables the sync writes into are reported.
+func (w *worker) handleDryRun(ctx context.Context, j *db.DequeueSyncJobRow) error {
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf("dry run: writing into %s instead of public", stagingSchema),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	staging, err := w.stagingWorker(ctx)
+	if err != nil {
+		return err
+	}
+
+	var unlock = w.lockStagingRepo(j.RepoID)
+	defer unlock()
+
+	if err = clearRows(ctx, staging.pool, stagingSchema, j.RepoID); err != nil {
+		return fmt.Errorf("clear staging rows: %w", err)
+	}
+
+	if err = staging.run(ctx, j); err != nil {
+		return err
+	}
+
+	counts, err := countRows(ctx, staging.pool, stagingSchema, j.RepoID)
+	if err != nil {
+		return fmt.Errorf("count staging rows: %w", err)
+	}
+
+	var batch = make([]*syncLog, 0, len(counts))
+	for _, c := range counts {
+		batch = append(batch, &syncLog{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+			Message: fmt.Sprintf("dry run: %d row(s) in %s.%s", c.Rows, stagingSchema, c.Table),
+		})
+	}
+
+	return w.sendBatchLogMessages(ctx, batch)
+}
+
+// run maps jobs to the right handler (see handlers.go)
+func (w *worker) run(ctx context.Context, j *db.DequeueSyncJobRow) error {
+	if err := w.checkDialect(j); err != nil {
+		return err
+	}
+
 	switch j.SyncType {
 	case syncTypeGitCommits:
 		return w.handleGitCommits(ctx, j)
@@ -203,18 +463,96 @@ func (w *worker) handle(ctx context.Context, j *db.DequeueSyncJobRow) error {
 		return w.handleOSSFScorecardScan(ctx, j)
 	case syncTypeGrypeScan:
 		return w.handleGrypeRepoScan(ctx, j)
+	case syncTypeGitHubRepoTeams:
+		return w.handleGitHubRepoTeams(ctx, j)
+	case syncTypeIssueKeyLinks:
+		return w.handleIssueKeyLinks(ctx, j)
+	case syncTypeGitCommitConventions:
+		return w.handleGitCommitConventions(ctx, j)
+	case syncTypeReleaseChangelogs:
+		return w.handleReleaseChangelogs(ctx, j)
+	case syncTypeGitBusFactor:
+		return w.handleGitBusFactor(ctx, j)
+	case syncTypeGitCommitPatches:
+		return w.handleGitCommitPatches(ctx, j)
+	case syncTypeGitSymbols:
+		return w.handleGitSymbols(ctx, j)
+	case syncTypeGitCommitMetrics:
+		return w.handleGitCommitMetrics(ctx, j)
+	case syncTypeGitLargeFiles:
+		return w.handleGitLargeFiles(ctx, j)
+	case syncTypeRepoPolicies:
+		return w.handleRepoPolicies(ctx, j)
+	case syncTypeContainerImages:
+		return w.handleContainerImages(ctx, j)
+	case syncTypeTerraformInventory:
+		return w.handleTerraformInventory(ctx, j)
+	case syncTypeCIInventory:
+		return w.handleCIInventory(ctx, j)
+	case syncTypeCodeImports:
+		return w.handleCodeImports(ctx, j)
+	case syncTypeTestRatios:
+		return w.handleTestRatios(ctx, j)
+	case syncTypePackageRegistries:
+		return w.handlePackageRegistries(ctx, j)
+	case syncTypeGitHubProjects:
+		return w.handleGitHubProjects(ctx, j)
+	case syncTypeGitCommitPullRequests:
+		return w.handleGitCommitPullRequests(ctx, j)
+	case syncTypeGitHubIssueResponseTimes:
+		return w.handleGitHubIssueResponseTimes(ctx, j)
+	case syncTypeGitHubReviewLoad:
+		return w.handleGitHubReviewLoad(ctx, j)
+	case syncTypeGitHubActionsUsage:
+		return w.handleGitHubActionsUsage(ctx, j)
+	case syncTypeGitHubActionsTestResults:
+		return w.handleGitHubActionsTestResults(ctx, j)
+	case syncTypeCIFlakyJobs:
+		return w.handleCIFlakyJobs(ctx, j)
+	case syncTypeCIDurationRegressions:
+		return w.handleCIDurationRegressions(ctx, j)
+	case syncTypeGitHubRunners:
+		return w.handleGitHubRunners(ctx, j)
+	case syncTypeGitHubOrgAuditLog:
+		return w.handleGitHubOrgAuditLog(ctx, j)
+	case syncTypeGitMirror:
+		return w.handleGitMirror(ctx, j)
+	case syncTypeSonarQube:
+		return w.handleSonarQube(ctx, j)
+	case syncTypeIncidents:
+		return w.handleIncidents(ctx, j)
+	case syncTypeChangeFailures:
+		return w.handleChangeFailures(ctx, j)
+	case syncTypeDependencyUpdateLag:
+		return w.handleDependencyUpdateLag(ctx, j)
+	case syncTypeGitHubForkDrift:
+		return w.handleGitHubForkDrift(ctx, j)
+	case syncTypeGitEmbeddings:
+		return w.handleGitEmbeddings(ctx, j)
+	case syncTypeLLMSummaries:
+		return w.handleLLMSummaries(ctx, j)
 	default:
+		if p, ok := w.plugins[j.SyncType]; ok {
+			return w.handlePlugin(ctx, j, p)
+		}
 		return fmt.Errorf("unknown sync type: %s for job ID: %d", j.SyncType, j.ID)
 	}
 }
 
 // Start starts running the workers until the ctx is canceled.
 func (w *worker) Start(ctx context.Context) {
+	go w.limiter.monitor(ctx)
+	go w.logs.run(ctx)
+	go w.watchMaintenanceMode(ctx)
+	go w.recordProviderUsage(ctx)
+
+	w.scheduleBackfills(ctx)
+
 	g := &sync.WaitGroup{}
 	g.Add(w.concurrency)
 	for i := 0; i < w.concurrency; i++ {
 		go func(i int) {
-			w.exec(ctx, fmt.Sprintf("%d", i))
+			w.exec(ctx, i)
 			g.Done()
 		}(i)
 	}
@@ -222,11 +560,29 @@ func (w *worker) Start(ctx context.Context) {
 }
 
 func (w *worker) fetchCredentials(ctx context.Context, job *db.DequeueSyncJobRow) (_, _ string, err error) {
+	// without pgcrypto there are no stored credentials, only the GITHUB_TOKEN env var (or none, in anonymous mode)
+	if !w.dialect.PGCrypto {
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" || w.anonymous {
+			return "", token, nil
+		}
+		return "", "", fmt.Errorf("stored credentials require pgcrypto, which is not supported by %s", w.dialect.Name)
+	}
+
 	var repo db.Repo
 	if repo, err = w.db.GetRepoById(ctx, job.RepoID); err != nil {
 		return "", "", err
 	}
 
+	return w.repoCredentials(ctx, repo)
+}
+
+// repoCredentials returns the username and token configured for the repository's provider or, if there's none
+// (or no pgcrypto to decrypt it with), the GITHUB_TOKEN env var, which may be empty (eg. to clone public repos)
+func (w *worker) repoCredentials(ctx context.Context, repo db.Repo) (_, _ string, err error) {
+	if !w.dialect.PGCrypto {
+		return "", os.Getenv("GITHUB_TOKEN"), nil
+	}
+
 	var username, token string
 	if username, token, err = w.db.FetchCredential(ctx, repo.Provider); err != nil {
 		return "", "", err
@@ -253,19 +609,95 @@ func (w *worker) clone(ctx context.Context, path string, job *db.DequeueSyncJobR
 		return err
 	}
 
+	// repos with no remote (eg. from a decommissioned server) are imported from a git bundle or a fast-export stream
+	var strategy = syncStrategy(ctx)
+	var shallow = strategy.Shallow && headOnlySyncTypes[job.SyncType]
+	if refSyncTypes[job.SyncType] {
+		strategy.NoTags = false
+	}
+	var kind helper.RepoImportKind
+	var source string
+	if kind, source, err = helper.RepoImportSource(repo.Repo, os.Getenv("REPO_IMPORT_DIR")); err != nil {
+		return err
+	}
+	if kind != "" {
+		var importCtx, cancel = context.WithTimeout(ctx, strategy.CloneTimeout)
+		defer cancel()
+		if err = importRepo(importCtx, path, kind, source); err != nil {
+			return errors.Wrapf(err, "failed to import repository from %s", kind)
+		}
+		shallow = false
+	} else if err = w.cloneRemote(ctx, path, repo, strategy, shallow); err != nil {
+		return err
+	}
+
+	logger.Info().Msgf("finished git repository clone: %s", repo.Repo)
+
+	// a shallow clone is smaller than the repo, it isn't measured
+	if !shallow {
+		w.recordRepoSize(ctx, job, path)
+	}
+
+	if err = w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: job.ID,
+		Message:         "finished git clone successfully: " + repo.Repo,
+	}}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// cloneRemote clones the repository from its remote into the given path, with the given strategy
+func (w *worker) cloneRemote(ctx context.Context, path string, repo db.Repo, strategy helper.SyncStrategy, shallow bool) (err error) {
+	var endpoint *transport.Endpoint
+	var auth transport.AuthMethod
+	if endpoint, auth, err = w.authForRepo(ctx, repo); err != nil {
+		return err
+	}
+
+	// fs and target are different! target is a subdirectory of fs. target stores git objects (like commits, etc.)
+	// whereas fs contains the working directory (a local checkout) of the cloned repository.
+	var fs = osfs.New(path)
+	var dotgit, _ = fs.Chroot(".git")
+	var target = filesystem.NewStorage(dotgit, cache.NewObjectLRUDefault())
+
+	// large repos are cloned without their tags (for syncs that don't read refs), shallow (for syncs that only read
+	// HEAD), and given more time
+	var opts = &git.CloneOptions{URL: endpoint.String(), Auth: auth}
+	if strategy.NoTags {
+		opts.Tags = git.NoTags
+	}
+	if shallow {
+		opts.Depth = 1
+	}
+
+	var cloneCtx, cancel = context.WithTimeout(ctx, strategy.CloneTimeout)
+	defer cancel()
+	if _, err = git.CloneContext(cloneCtx, target, fs, opts); err != nil {
+		return errors.Wrapf(err, "failed to clone repository")
+	}
+
+	return nil
+}
+
+// authForRepo returns the endpoint of the given repository along with the method to authenticate against it,
+// using the credentials configured for the repository's provider.
+func (w *worker) authForRepo(ctx context.Context, repo db.Repo) (_ *transport.Endpoint, _ transport.AuthMethod, err error) {
 	// TODO(@riyaz): we can improve this by first detecting the kind of url
 	// 		and then fetching the appropriate type of credential for it.
 	// 		This still involves couple of challenges (differentiating between different provider tokens etc.)
 
 	// fetch the username and token for the provider
 	var username, token string
-	if username, token, err = w.db.FetchCredential(ctx, repo.Provider); err != nil {
-		return err
+	if username, token, err = w.repoCredentials(ctx, repo); err != nil {
+		return nil, nil, err
 	}
 
 	var endpoint *transport.Endpoint
 	if endpoint, err = transport.NewEndpoint(repo.Repo); err != nil {
-		return errors.Wrapf(err, "failed to parse url")
+		return nil, nil, errors.Wrapf(err, "failed to parse url")
 	}
 
 	var auth transport.AuthMethod
@@ -275,7 +707,7 @@ func (w *worker) clone(ctx context.Context, path string, job *db.DequeueSyncJobR
 		}
 
 		if auth, err = ssh.NewPublicKeys(username, []byte(token), ""); err != nil {
-			return errors.Wrapf(err, "failed to parse ssh key")
+			return nil, nil, errors.Wrapf(err, "failed to parse ssh key")
 		}
 	} else if endpoint.Protocol == "http" || endpoint.Protocol == "https" || endpoint.Protocol == "git" {
 		if username == "" {
@@ -287,26 +719,5 @@ func (w *worker) clone(ctx context.Context, path string, job *db.DequeueSyncJobR
 		}
 	}
 
-	// fs and target are different! target is a subdirectory of fs. target stores git objects (like commits, etc.)
-	// whereas fs contains the working directory (a local checkout) of the cloned repository.
-	var fs = osfs.New(path)
-	var dotgit, _ = fs.Chroot(".git")
-	var target = filesystem.NewStorage(dotgit, cache.NewObjectLRUDefault())
-
-	var opts = &git.CloneOptions{URL: endpoint.String(), Auth: auth}
-	if _, err = git.CloneContext(ctx, target, fs, opts); err != nil {
-		return errors.Wrapf(err, "failed to clone repository")
-	}
-
-	logger.Info().Msgf("finished git repository clone: %s", repo.Repo)
-
-	if err = w.sendBatchLogMessages(ctx, []*syncLog{{
-		Type:            SyncLogTypeInfo,
-		RepoSyncQueueID: job.ID,
-		Message:         "finished git clone successfully: " + repo.Repo,
-	}}); err != nil {
-		return err
-	}
-
-	return nil
+	return endpoint, auth, nil
 }
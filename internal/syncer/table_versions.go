@@ -0,0 +1,22 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+// populateTableVersions populates, for the job's repo, the new versions of the tables the sync writes that are
+// being populated alongside the active version, ahead of a cut over (see mergestat.table_versions). Failures are
+// only logged, they never fail the sync: readers are still on the active version.
+func (w *worker) populateTableVersions(ctx context.Context, j *db.DequeueSyncJobRow) {
+	var rows int64
+	if err := w.pool.QueryRow(ctx, "SELECT mergestat.populate_table_versions($1, $2)", j.SyncType, j.RepoID).Scan(&rows); err != nil {
+		w.loggerForJob(j).Err(err).Msg("could not populate new table versions")
+		return
+	}
+
+	if rows > 0 {
+		w.loggerForJob(j).Info().Msgf("wrote %d row(s) into new table versions", rows)
+	}
+}
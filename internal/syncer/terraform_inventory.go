@@ -0,0 +1,91 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// terraformColumns are the columns of terraform_modules and terraform_providers a sync writes
+var terraformColumns = []string{"repo_id", "path", "line", "name", "source", "version"}
+
+// terraformInventory parses the module calls and provider requirements of the (already synced) .tf files of the repo
+func (w *worker) terraformInventory(ctx context.Context, j *db.DequeueSyncJobRow) (modules, providers [][]interface{}, err error) {
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, "SELECT path, contents FROM git_files WHERE repo_id = $1 AND contents IS NOT NULL AND path LIKE '%.tf'", j.RepoID); err != nil {
+		return nil, nil, fmt.Errorf("query files: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var path, contents string
+		if err = rows.Scan(&path, &contents); err != nil {
+			return nil, nil, err
+		}
+
+		var m, p = helper.ParseTerraform(contents)
+		for _, module := range m {
+			modules = append(modules, []interface{}{j.RepoID, path, module.Line, module.Name, nullIfEmpty(module.Source), nullIfEmpty(module.Version)})
+		}
+		for _, provider := range p {
+			providers = append(providers, []interface{}{j.RepoID, path, provider.Line, provider.Name, nullIfEmpty(provider.Source), nullIfEmpty(provider.Version)})
+		}
+	}
+
+	return modules, providers, rows.Err()
+}
+
+func (w *worker) handleTerraformInventory(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var modules, providers [][]interface{}
+	if modules, providers, err = w.terraformInventory(ctx, j); err != nil {
+		return err
+	}
+
+	l.Info().Msgf("found %d module(s) and %d provider(s)", len(modules), len(providers))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	if err = w.replaceRows(ctx, tx, j, "terraform_modules", terraformColumns, modules); err != nil {
+		return err
+	}
+
+	if err = w.replaceRows(ctx, tx, j, "terraform_providers", terraformColumns, providers); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,123 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/helper"
+)
+
+// defaultTestRatioDepth is the number of directory levels ratios are computed for, unless set in the sync settings
+const defaultTestRatioDepth = 2
+
+// testRatiosColumns are the columns of test_ratios a sync writes
+var testRatiosColumns = []string{"repo_id", "computed_at", "directory", "source_files", "source_lines", "test_files", "test_lines", "file_ratio", "line_ratio"}
+
+// sourceFiles returns the (already synced) files of the repo with their number of lines, counted in the database
+// so that the contents of the files aren't transferred
+func (w *worker) sourceFiles(ctx context.Context, j *db.DequeueSyncJobRow) (_ []helper.SourceFile, err error) {
+	const query = `
+SELECT path, length(contents) - length(replace(contents, E'\n', '')) + CASE WHEN contents = '' OR right(contents, 1) = E'\n' THEN 0 ELSE 1 END
+FROM git_files WHERE repo_id = $1 AND contents IS NOT NULL`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, query, j.RepoID); err != nil {
+		return nil, fmt.Errorf("query files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []helper.SourceFile
+	for rows.Next() {
+		var f helper.SourceFile
+		if err = rows.Scan(&f.Path, &f.Lines); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+func (w *worker) handleTestRatios(ctx context.Context, j *db.DequeueSyncJobRow) (err error) {
+	l := w.loggerForJob(j)
+
+	// indicate that we're starting query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatStartingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	var settings *syncSettings
+	if settings, err = settingsForJob(j); err != nil {
+		return err
+	}
+
+	var depth = settings.TestRatioDepth
+	if depth <= 0 {
+		depth = defaultTestRatioDepth
+	}
+
+	var files []helper.SourceFile
+	if files, err = w.sourceFiles(ctx, j); err != nil {
+		return err
+	}
+
+	var computedAt = time.Now()
+	var ratios = helper.ComputeTestRatios(files, depth)
+	var inputs = make([][]interface{}, 0, len(ratios))
+	for _, r := range ratios {
+		var fileRatio, lineRatio interface{}
+		if ratio, ok := r.FileRatio(); ok {
+			fileRatio = ratio
+		}
+		if ratio, ok := r.LineRatio(); ok {
+			lineRatio = ratio
+		}
+		inputs = append(inputs, []interface{}{j.RepoID, computedAt, r.Directory, r.SourceFiles, r.SourceLines, r.TestFiles, r.TestLines, fileRatio, lineRatio})
+	}
+
+	l.Info().Msgf("computed test ratios of %d directories", len(inputs))
+
+	var tx pgx.Tx
+	if tx, err = w.beginTx(ctx, j); err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			if !errors.Is(err, pgx.ErrTxClosed) {
+				w.logger.Err(err).Msgf("could not rollback transaction")
+			}
+		}
+	}()
+
+	// previous rows are kept, so that the ratios can be trended over time
+	if _, err = tx.CopyFrom(ctx, pgx.Identifier{"test_ratios"}, testRatiosColumns, pgx.CopyFromRows(inputs)); err != nil {
+		return fmt.Errorf("tx copy from: %w", err)
+	}
+
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{
+		Type:            SyncLogTypeInfo,
+		RepoSyncQueueID: j.ID,
+		Message:         fmt.Sprintf("inserted %d row(s) into test_ratios", len(inputs)),
+		Details:         rowDetails("inserted", "test_ratios", int64(len(inputs))),
+	}}); err != nil {
+		return err
+	}
+
+	if err := w.db.WithTx(tx).SetSyncJobStatus(ctx, db.SetSyncJobStatusParams{Status: "DONE", ID: j.ID}); err != nil {
+		return fmt.Errorf("update status done: %w", err)
+	}
+
+	// indicate that we're finishing query execution
+	if err := w.sendBatchLogMessages(ctx, []*syncLog{{Type: SyncLogTypeInfo, RepoSyncQueueID: j.ID,
+		Message: fmt.Sprintf(LogFormatFinishingSync, j.SyncType, j.Repo),
+	}}); err != nil {
+		return fmt.Errorf("send batch log messages: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
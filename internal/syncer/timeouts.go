@@ -0,0 +1,93 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+)
+
+const (
+	// defaultStatementTimeout is the statement timeout applied to the transaction of a sync
+	defaultStatementTimeout = 10 * time.Minute
+
+	// defaultLockTimeout is the lock timeout applied to the transaction of a sync, so that a sync blocked
+	// on another's lock (eg. a DELETE FROM git_blame) fails instead of holding its transaction open indefinitely
+	defaultLockTimeout = 2 * time.Minute
+)
+
+// statementTimeouts overrides defaultStatementTimeout for the sync types that write large amounts of rows
+var statementTimeouts = map[string]time.Duration{
+	syncTypeGitBlame:         time.Hour,
+	syncTypeGitCommitStats:   time.Hour,
+	syncTypeGitCommitPatches: time.Hour,
+	syncTypeGitFiles:         30 * time.Minute,
+	syncTypeGitSymbols:       30 * time.Minute,
+	syncTypeGitCommits:       30 * time.Minute,
+}
+
+// WithStatementTimeout sets the statement timeout the ones of the transactions of syncs are derived from (eg. the one
+// of the connections, see DB_STATEMENT_TIMEOUT) instead of defaultStatementTimeout: the timeouts of the sync types
+// (see statementTimeouts) are scaled by the same ratio. Zero disables them, as in Postgres.
+func (w *worker) WithStatementTimeout(timeout time.Duration) *worker {
+	w.statementTimeout = &timeout
+	return w
+}
+
+// WithLockTimeout sets the lock timeout of the transactions of syncs (eg. the one of the connections, see
+// DB_LOCK_TIMEOUT) instead of defaultLockTimeout. Zero disables it, as in Postgres.
+func (w *worker) WithLockTimeout(timeout time.Duration) *worker {
+	w.lockTimeout = &timeout
+	return w
+}
+
+// txBeginner is implemented by anything a transaction can be started on (a pool or a connection)
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// beginTx starts the transaction a sync writes its rows in, with the statement and lock timeouts for the job's sync type.
+// Rows COPY'd through the transaction go through the job's transforms and the privacy settings (see withTransforms).
+func (w *worker) beginTx(ctx context.Context, j *db.DequeueSyncJobRow) (pgx.Tx, error) {
+	return w.beginTxOn(ctx, w.pool, j)
+}
+
+// beginTxOn is like beginTx, but starts the transaction on the given pool or connection
+func (w *worker) beginTxOn(ctx context.Context, b txBeginner, j *db.DequeueSyncJobRow) (_ pgx.Tx, err error) {
+	var statementTimeout = defaultStatementTimeout
+	if timeout, ok := statementTimeouts[j.SyncType]; ok {
+		statementTimeout = timeout
+	}
+	if w.statementTimeout != nil {
+		statementTimeout = time.Duration(float64(statementTimeout) * float64(*w.statementTimeout) / float64(defaultStatementTimeout))
+	}
+	statementTimeout *= time.Duration(syncStrategy(ctx).TimeoutFactor)
+
+	var lockTimeout = defaultLockTimeout
+	if w.lockTimeout != nil {
+		lockTimeout = *w.lockTimeout
+	}
+
+	var tx pgx.Tx
+	if tx, err = b.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+		return nil, err
+	}
+
+	var stmt = fmt.Sprintf("SET LOCAL statement_timeout = %d; SET LOCAL lock_timeout = %d",
+		statementTimeout.Milliseconds(), lockTimeout.Milliseconds())
+	if _, err = tx.Exec(ctx, stmt); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, fmt.Errorf("set timeouts: %w", err)
+	}
+
+	// transforms see the tables by their own name, whether or not they're relocated (see withNamespace)
+	var c copier
+	if c, err = w.withTransforms(ctx, j, w.withNamespace(tx)); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, fmt.Errorf("load transforms: %w", err)
+	}
+
+	return c.(pgx.Tx), nil
+}
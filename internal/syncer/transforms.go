@@ -0,0 +1,397 @@
+package syncer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/mergestat/mergestat/internal/db"
+	"github.com/mergestat/mergestat/internal/dialect"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/namespace"
+	satori "github.com/satori/go.uuid"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	// transformTimeout is how long a transform can take to process a batch of rows, past which its module is stopped
+	transformTimeout = 10 * time.Minute
+
+	// transformMemoryLimitPages caps the memory of a transform's module, in pages of 64KiB (ie. 256MiB)
+	transformMemoryLimitPages = 4096
+
+	// transformBatchSize is the number of rows a transform (and the privacy settings, and encryption) is applied to
+	// at once, as they're COPY'd
+	transformBatchSize = 10000
+)
+
+// transformCache caches the compiled modules of the transforms, across jobs
+var transformCache = wazero.NewCompilationCache()
+
+// transform is a WASM (WASI) module that transforms or filters the rows of a table before they are written.
+// The module is executed by the worker's embedded WASI runtime (wazero), without any access to the filesystem
+// or network, within transformMemoryLimitPages of memory and transformTimeout. It's executed once per batch of
+// (at most transformBatchSize) rows: it reads a header line ({"table": ..., "columns": [...]}) followed by one JSON
+// array per row on stdin, and writes one JSON array per row to keep on stdout (omitting dropped rows).
+type transform struct {
+	name   string
+	table  string
+	module []byte
+	url    string
+}
+
+// transformsForJob returns the enabled transforms of the job's sync type and repo, keyed by table
+func (w *worker) transformsForJob(ctx context.Context, j *db.DequeueSyncJobRow) (_ map[string][]*transform, err error) {
+	const query = `
+SELECT name, table_name, module, COALESCE(module_url, '') FROM mergestat.sync_transforms
+	WHERE enabled AND sync_type = $1 AND (repo_id IS NULL OR repo_id = $2)
+ORDER BY position, created_at`
+
+	var rows pgx.Rows
+	if rows, err = w.pool.Query(ctx, query, j.SyncType, j.RepoID); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transforms = make(map[string][]*transform)
+	for rows.Next() {
+		var t transform
+		if err = rows.Scan(&t.name, &t.table, &t.module, &t.url); err != nil {
+			return nil, err
+		}
+		transforms[t.table] = append(transforms[t.table], &t)
+	}
+
+	return transforms, rows.Err()
+}
+
+// fetchModule fetches the transform's module, unless it's stored in the database (or was fetched already)
+func (t *transform) fetchModule(ctx context.Context) (err error) {
+	if t.module != nil {
+		return nil
+	}
+
+	var request *http.Request
+	if request, err = http.NewRequestWithContext(ctx, http.MethodGet, t.url, http.NoBody); err != nil {
+		return fmt.Errorf("fetch module: %w", err)
+	}
+
+	var response *http.Response
+	if response, err = http.DefaultClient.Do(request); err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch module: unexpected status %d", response.StatusCode)
+	}
+
+	t.module, err = io.ReadAll(response.Body)
+	return err
+}
+
+// apply runs the rows through the transform's module
+func (t *transform) apply(ctx context.Context, columns []string, rows [][]interface{}) (_ [][]interface{}, err error) {
+	if err = t.fetchModule(ctx); err != nil {
+		return nil, fmt.Errorf("transform %s: %w", t.name, err)
+	}
+
+	var input bytes.Buffer
+	var encoder = json.NewEncoder(&input)
+	if err = encoder.Encode(map[string]interface{}{"table": t.table, "columns": columns}); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err = encoder.Encode(row); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, transformTimeout)
+	defer cancel()
+
+	var config = wazero.NewRuntimeConfig().
+		WithCompilationCache(transformCache).
+		WithMemoryLimitPages(transformMemoryLimitPages).
+		WithCloseOnContextDone(true)
+
+	var runtime = wazero.NewRuntimeWithConfig(ctx, config)
+	defer runtime.Close(context.Background())
+
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	var compiled wazero.CompiledModule
+	if compiled, err = runtime.CompileModule(ctx, t.module); err != nil {
+		return nil, fmt.Errorf("transform %s: invalid module: %w", t.name, err)
+	}
+
+	// the module is given stdio only: no filesystem, environment, clock or randomness of the host
+	var stdout, stderr bytes.Buffer
+	var module = wazero.NewModuleConfig().WithStdin(&input).WithStdout(&stdout).WithStderr(&stderr)
+	if _, err = runtime.InstantiateModule(ctx, compiled, module); err != nil {
+		return nil, fmt.Errorf("transform %s: %w: %s", t.name, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var result [][]interface{}
+	var scanner = bufio.NewScanner(&stdout)
+	scanner.Buffer(make([]byte, bufio.MaxScanTokenSize), 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var decoder = json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+
+		var row []interface{}
+		if err = decoder.Decode(&row); err != nil {
+			return nil, fmt.Errorf("transform %s: invalid row: %w", t.name, err)
+		}
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("transform %s: row has %d value(s), expected %d", t.name, len(row), len(columns))
+		}
+		result = append(result, row)
+	}
+
+	return result, scanner.Err()
+}
+
+// restoreTypes converts the JSON decoded values of the rows back to the types of the sample values
+// (the first non-nil value of each column before the transform), so that they can be COPY'd
+func restoreTypes(samples []interface{}, rows [][]interface{}) error {
+	for _, row := range rows {
+		for i, v := range row {
+			var s, ok = v.(string)
+			var n, isNumber = v.(json.Number)
+			if !ok && !isNumber {
+				continue
+			}
+
+			var err error
+			switch samples[i].(type) {
+			case time.Time, *time.Time:
+				row[i], err = time.Parse(time.RFC3339Nano, s)
+			case uuid.UUID:
+				row[i], err = uuid.Parse(s)
+			case satori.UUID:
+				row[i], err = satori.FromString(s)
+			case int, int32, int64, *int:
+				row[i], err = n.Int64()
+			case float32, float64:
+				row[i], err = n.Float64()
+			default:
+				if isNumber {
+					row[i] = n.String()
+				}
+			}
+
+			if err != nil {
+				return fmt.Errorf("column %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// transformingCopier applies the job's transforms, then the privacy settings (and author erasures), to the rows
+// COPY'd through it, and encrypts the encrypted columns
+type transformingCopier struct {
+	copier
+	transforms map[string][]*transform
+	privacy    *privacySettings
+
+	// encrypted are the columns to encrypt with cipher, by table (see mergestat.encrypted_columns)
+	encrypted map[string]map[string]bool
+	cipher    *helper.ColumnCipher
+
+	// tables, repoID and dialect are used to find the rows written by statements (see processWritten)
+	tables  namespace.Registry
+	repoID  uuid.UUID
+	dialect *dialect.Dialect
+}
+
+// transformingTx is a transaction applying the job's transforms to the rows COPY'd through it, and redacting and
+// encrypting the rows it wrote with statements before it commits (see processWritten)
+type transformingTx struct {
+	pgx.Tx
+	c *transformingCopier
+}
+
+func (tx *transformingTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return tx.c.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (c *transformingCopier) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (_ int64, err error) {
+	// rows copied into a load table (see loadTable) are transformed as the rows of the table it's loading
+	var table = tableName[len(tableName)-1]
+	if len(tableName) == 2 && tableName[0] == "pg_temp" {
+		table = strings.TrimPrefix(table, "load_")
+	}
+
+	var redacted []int
+	for i, column := range columnNames {
+		if c.privacy.redacts(column) {
+			redacted = append(redacted, i)
+		}
+	}
+
+	var encrypted []int
+	for i, column := range columnNames {
+		if c.encrypted[table][column] {
+			encrypted = append(encrypted, i)
+		}
+	}
+	if len(encrypted) > 0 && c.cipher == nil {
+		return 0, errNoEncryptionKey
+	}
+
+	var transforms = c.transforms[table]
+	var erasing = c.privacy.erases(table, columnNames)
+	if len(transforms) == 0 && len(redacted) == 0 && !erasing && len(encrypted) == 0 {
+		return c.copier.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	}
+
+	// rows are read, transformed and COPY'd a batch at a time, rather than all at once
+	var src = &transformingSource{c: c, ctx: ctx, table: table, columns: columnNames, rows: rowSrc,
+		transforms: transforms, redacted: redacted, erasing: erasing, encrypted: encrypted, samples: make([]interface{}, len(columnNames))}
+	return c.copier.CopyFrom(ctx, tableName, columnNames, src)
+}
+
+// transformingSource is a pgx.CopyFromSource reading the rows of another, and applying the transforms, the privacy
+// settings (and author erasures) and encryption to them, a batch (of transformBatchSize rows) at a time
+type transformingSource struct {
+	c       *transformingCopier
+	ctx     context.Context
+	table   string
+	columns []string
+	rows    pgx.CopyFromSource
+
+	transforms []*transform
+	redacted   []int
+	erasing    bool
+	encrypted  []int
+
+	// samples are the first non-nil value of each column (see restoreTypes)
+	samples []interface{}
+
+	batch [][]interface{}
+	pos   int
+	done  bool
+	err   error
+}
+
+func (s *transformingSource) Next() bool {
+	for s.pos+1 >= len(s.batch) {
+		if s.done || s.err != nil {
+			return false
+		}
+		if s.err = s.nextBatch(); s.err != nil {
+			return false
+		}
+	}
+	s.pos++
+	return true
+}
+
+func (s *transformingSource) Values() ([]interface{}, error) {
+	return s.batch[s.pos], nil
+}
+
+func (s *transformingSource) Err() error {
+	return s.err
+}
+
+// nextBatch reads (and transforms) the next batch of rows
+func (s *transformingSource) nextBatch() (err error) {
+	var rows = make([][]interface{}, 0, transformBatchSize)
+	for len(rows) < transformBatchSize {
+		if !s.rows.Next() {
+			s.done = true
+			break
+		}
+
+		var values []interface{}
+		if values, err = s.rows.Values(); err != nil {
+			return err
+		}
+		for i, v := range values {
+			if s.samples[i] == nil {
+				s.samples[i] = v
+			}
+		}
+		rows = append(rows, values)
+	}
+	if err = s.rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range s.transforms {
+		if len(rows) == 0 {
+			break
+		}
+		if rows, err = t.apply(s.ctx, s.columns, rows); err != nil {
+			return err
+		}
+		if err = restoreTypes(s.samples, rows); err != nil {
+			return fmt.Errorf("transform %s: %w", t.name, err)
+		}
+	}
+
+	var kept = rows[:0]
+	for _, row := range rows {
+		for _, i := range s.redacted {
+			row[i] = s.c.privacy.redact(s.table, s.columns[i], row[i])
+		}
+		if s.erasing && !s.c.privacy.erase(s.table, s.columns, row) {
+			continue
+		}
+		for _, i := range s.encrypted {
+			if row[i], err = encryptValue(s.c.cipher, row[i]); err != nil {
+				return err
+			}
+		}
+		kept = append(kept, row)
+	}
+
+	s.batch, s.pos = kept, -1
+	return nil
+}
+
+// withTransforms returns c wrapped so that the job's transforms (if any), and the privacy settings, are applied
+// to the rows COPY'd through it
+func (w *worker) withTransforms(ctx context.Context, j *db.DequeueSyncJobRow, c copier) (_ copier, err error) {
+	var transforms map[string][]*transform
+	if transforms, err = w.transformsForJob(ctx, j); err != nil {
+		return nil, err
+	}
+
+	var privacy *privacySettings
+	if privacy, err = w.privacySettings(ctx); err != nil {
+		return nil, err
+	}
+
+	var encrypted map[string]map[string]bool
+	if encrypted, err = encryptedColumns(ctx, w.pool); err != nil {
+		return nil, err
+	}
+
+	if len(transforms) == 0 && !privacy.active() && len(encrypted) == 0 {
+		return c, nil
+	}
+
+	var tc = &transformingCopier{copier: c, transforms: transforms, privacy: privacy, encrypted: encrypted, cipher: w.cipher,
+		tables: w.tables, repoID: j.RepoID, dialect: w.dialect}
+	if tx, ok := c.(pgx.Tx); ok {
+		return &transformingTx{Tx: tx, c: tc}, nil
+	}
+	return tc, nil
+}
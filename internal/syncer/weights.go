@@ -0,0 +1,37 @@
+package syncer
+
+import (
+	"math"
+	"sync"
+)
+
+// weights tracks the total weight of the jobs the worker runs (see mergestat.repo_sync_types.weight), capped at max,
+// so that heavy syncs (eg. GIT_BLAME of a monorepo) don't run together on the same worker, whatever its free slots
+type weights struct {
+	// mu serializes dequeues, so that exec loops don't take the same weight left
+	mu       sync.Mutex
+	max      int32
+	inFlight int32
+}
+
+// WithMaxWeight caps the total weight of the jobs the worker runs at once, if max is positive
+func (w *worker) WithMaxWeight(max int) *worker {
+	w.weights.max = int32(max)
+	return w
+}
+
+// available returns the weight left for a job to dequeue. A worker without any job in flight takes a job of any
+// weight, so that syncs heavier than the cap still run (alone).
+func (ws *weights) available() int32 {
+	if ws.max <= 0 || ws.inFlight == 0 {
+		return math.MaxInt32
+	}
+	return ws.max - ws.inFlight
+}
+
+// release returns the weight of a job done
+func (ws *weights) release(weight int32) {
+	ws.mu.Lock()
+	ws.inFlight -= weight
+	ws.mu.Unlock()
+}
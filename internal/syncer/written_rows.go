@@ -0,0 +1,303 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Rows written by statements (upserts, and the INSERT ... SELECT of derived syncs and load tables) rather than COPY'd
+// don't go through the transformingCopier of the transaction: they're redacted (see privacySettings) and encrypted in
+// place before it commits. The
+// rows the transaction wrote are told by their xmin, and addressed by their ctid, in the tables themselves (where
+// they live, as views of relocated tables have neither), and only in the tables it inserted or updated rows in.
+
+// writtenByTx matches the rows written by the current transaction
+const writtenByTx = "xmin::TEXT::BIGINT = txid_current() % 4294967296"
+
+// Commit processes the rows the transaction wrote with statements (see processWritten), then commits it
+func (tx *transformingTx) Commit(ctx context.Context) error {
+	if err := tx.c.processWritten(ctx, tx.Tx); err != nil {
+		_ = tx.Tx.Rollback(ctx)
+		return err
+	}
+	return tx.Tx.Commit(ctx)
+}
+
+// processWritten redacts the values the transaction wrote following the privacy settings, applies the author
+// erasures to them, then encrypts those it wrote into encrypted columns that aren't encrypted yet (as COPY does)
+func (c *transformingCopier) processWritten(ctx context.Context, tx pgx.Tx) (err error) {
+	var tables = make(map[string]bool)
+	for table := range c.privacy.columns {
+		tables[table] = true
+	}
+	for table := range c.privacy.identities {
+		tables[table] = true
+	}
+	for table := range c.encrypted {
+		tables[table] = true
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	if !c.dialect.SystemColumns {
+		return fmt.Errorf("privacy settings and encrypted columns require the xmin and ctid system columns, which are not supported by %s", c.dialect.Name)
+	}
+
+	for table := range tables {
+		var written *writtenRows
+		if written, err = c.writtenRows(ctx, tx, table); err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+		for _, column := range c.privacy.columns[table] {
+			if !written.columns[column] {
+				continue
+			}
+			if err = written.redact(ctx, tx, c.privacy, table, column); err != nil {
+				return fmt.Errorf("redact %s.%s: %w", table, column, err)
+			}
+		}
+		if len(c.privacy.erasures) > 0 {
+			if err = written.erase(ctx, tx, c.privacy, table); err != nil {
+				return fmt.Errorf("erase %s: %w", table, err)
+			}
+		}
+		for column := range c.encrypted[table] {
+			if !written.columns[column] {
+				continue
+			}
+			if err = written.encrypt(ctx, tx, c, column); err != nil {
+				return fmt.Errorf("encrypt %s.%s: %w", table, column, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// writtenRows are the rows of a table written by the current transaction
+type writtenRows struct {
+	// identifier is the (sanitized) identifier of the table, where it lives
+	identifier string
+
+	// columns are the columns of the table, empty if it doesn't exist or the transaction didn't write into it
+	columns map[string]bool
+
+	// where matches the rows written by the transaction (for the repo, if the table has a repo_id), with args
+	where string
+	args  []interface{}
+}
+
+// writtenRows returns the rows of the table written by the transaction
+func (c *transformingCopier) writtenRows(ctx context.Context, tx pgx.Tx, table string) (_ *writtenRows, err error) {
+	var r = &writtenRows{identifier: c.tables.Table(table).Sanitize(), columns: make(map[string]bool)}
+
+	const listColumns = `
+SELECT attname::TEXT FROM pg_attribute WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped
+    AND EXISTS (SELECT 1 FROM pg_stat_xact_user_tables WHERE relid = to_regclass($1) AND n_tup_ins + n_tup_upd > 0)`
+
+	var rows pgx.Rows
+	if rows, err = tx.Query(ctx, listColumns, r.identifier); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var column string
+		if err = rows.Scan(&column); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.columns[column] = true
+	}
+	rows.Close()
+
+	r.where = writtenByTx
+	if r.columns["repo_id"] {
+		r.where, r.args = "repo_id = $1 AND "+r.where, []interface{}{c.repoID}
+	}
+	return r, rows.Err()
+}
+
+// redact redacts the values of the column written by the transaction following the settings, like
+// mergestat.apply_privacy_settings does (and privacySettings.redact, for the rows COPY'd)
+func (r *writtenRows) redact(ctx context.Context, tx pgx.Tx, p *privacySettings, table, column string) (err error) {
+	var col = pgx.Identifier{column}.Sanitize()
+	var args = r.args
+
+	var set, where string
+	switch {
+	case column == "message" && p.commitMessageBodies == "DROP":
+		set, where = fmt.Sprintf(`split_part(%s, E'\n', 1)`, col), fmt.Sprintf(`strpos(%s, E'\n') > 0`, col)
+	case column == "contents" && p.fileContents == "DROP":
+		set, where = "NULL", col+" IS NOT NULL"
+	case column == "patch" && p.fileContents == "DROP":
+		set, where = "''", col+" <> ''"
+	case isEmailColumn(column) && (p.authorEmails == "HASH" || p.authorEmails == "DROP" && p.keys[table][column]):
+		args = append(args[:len(args):len(args)], p.hashSalt)
+		set = fmt.Sprintf("mergestat.privacy_hash(%s, $%d)", col, len(args))
+		where = fmt.Sprintf("%s <> '' AND %s NOT LIKE 'sha256:%%'", col, col)
+	case isEmailColumn(column) && p.authorEmails == "DROP":
+		set, where = "''", col+" <> ''"
+	default:
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s AND %s", r.identifier, col, set, r.where, where), args...)
+	return err
+}
+
+// erase applies the author erasures to the rows written by the transaction (see privacySettings.erase): the values
+// of their identity columns (and the name columns next to them) are read as JSON, and the rows updated or deleted
+func (r *writtenRows) erase(ctx context.Context, tx pgx.Tx, p *privacySettings, table string) (err error) {
+	var columns []string
+	for column := range p.identities[table] {
+		if !r.columns[column] {
+			continue
+		}
+		columns = append(columns, column)
+		if name := strings.TrimSuffix(strings.TrimSuffix(column, "email"), "login") + "name"; name != column+"name" && r.columns[name] {
+			if _, listed := p.identities[table][name]; !listed {
+				columns = append(columns, name)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	sort.Strings(columns)
+
+	var selected = make([]string, len(columns))
+	for i, column := range columns {
+		selected[i] = fmt.Sprintf("to_jsonb(%s)::TEXT", pgx.Identifier{column}.Sanitize())
+	}
+	var query = fmt.Sprintf("SELECT ctid::TEXT, %s FROM %s WHERE %s", strings.Join(selected, ", "), r.identifier, r.where)
+
+	var rows pgx.Rows
+	if rows, err = tx.Query(ctx, query, r.args...); err != nil {
+		return err
+	}
+
+	var b = &pgx.Batch{}
+	for rows.Next() {
+		var ctid string
+		var raw = make([]*string, len(columns))
+		var dest = []interface{}{&ctid}
+		for i := range raw {
+			dest = append(dest, &raw[i])
+		}
+		if err = rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+
+		var row, before = make([]interface{}, len(columns)), make([]string, len(columns))
+		for i, value := range raw {
+			if value == nil {
+				continue
+			}
+			if p.identities[table][columns[i]] == "jsonb" {
+				row[i] = []byte(*value)
+			} else if err = json.Unmarshal([]byte(*value), &row[i]); err != nil {
+				rows.Close()
+				return err
+			}
+			before[i] = jsonText(row[i])
+		}
+
+		if !p.erase(table, columns, row) {
+			b.Queue(fmt.Sprintf("DELETE FROM %s WHERE ctid = $1::TID", r.identifier), ctid)
+			continue
+		}
+
+		var set, args = []string{}, []interface{}{ctid}
+		for i, column := range columns {
+			var after = jsonText(row[i])
+			if raw[i] == nil || after == before[i] {
+				continue
+			}
+			args = append(args, after)
+			var col = pgx.Identifier{column}.Sanitize()
+			switch p.identities[table][column] {
+			case "ARRAY":
+				set = append(set, fmt.Sprintf("%s = ARRAY(SELECT jsonb_array_elements_text($%d::JSONB))", col, len(args)))
+			case "jsonb":
+				set = append(set, fmt.Sprintf("%s = $%d::JSONB", col, len(args)))
+			default:
+				set = append(set, fmt.Sprintf("%s = $%d::JSONB #>> '{}'", col, len(args)))
+			}
+		}
+		if len(set) > 0 {
+			b.Queue(fmt.Sprintf("UPDATE %s SET %s WHERE ctid = $1::TID", r.identifier, strings.Join(set, ", ")), args...)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if b.Len() == 0 {
+		return nil
+	}
+	return tx.SendBatch(ctx, b).Close()
+}
+
+// jsonText returns the JSON encoding of a value read (and erased) by writtenRows.erase
+func jsonText(value interface{}) string {
+	if v, ok := value.([]byte); ok {
+		var decoded interface{}
+		if json.Unmarshal(v, &decoded) == nil {
+			value = decoded
+		}
+	}
+	var encoded, _ = json.Marshal(value)
+	return string(encoded)
+}
+
+// encrypt encrypts the values of the column written by the transaction that don't authenticate with the key
+// (ie. those written by statements, as those COPY'd are encrypted already)
+func (r *writtenRows) encrypt(ctx context.Context, tx pgx.Tx, c *transformingCopier, column string) (err error) {
+	var col = pgx.Identifier{column}.Sanitize()
+	var query = fmt.Sprintf("SELECT ctid::TEXT, %s FROM %s WHERE %s AND %s IS NOT NULL", col, r.identifier, r.where, col)
+
+	var rows pgx.Rows
+	if rows, err = tx.Query(ctx, query, r.args...); err != nil {
+		return err
+	}
+
+	var plain [][2]string
+	for rows.Next() {
+		var ctid, value string
+		if err = rows.Scan(&ctid, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		if c.cipher == nil || !c.cipher.IsEncrypted(value) {
+			plain = append(plain, [2]string{ctid, value})
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(plain) == 0 {
+		return nil
+	}
+	if c.cipher == nil {
+		return errNoEncryptionKey
+	}
+
+	var update = fmt.Sprintf("UPDATE %s SET %s = $2 WHERE ctid = $1::TID", r.identifier, col)
+	var b = &pgx.Batch{}
+	for _, row := range plain {
+		var encrypted string
+		if encrypted, err = c.cipher.Encrypt(row[1]); err != nil {
+			return err
+		}
+		b.Queue(update, row[0], encrypted)
+	}
+	return tx.SendBatch(ctx, b).Close()
+}
@@ -50,7 +50,7 @@ func (w *worker) handleYelpDetectSecretsRepoScan(ctx context.Context, j *db.Dequ
 	}
 
 	var tx pgx.Tx
-	if tx, err = w.pool.BeginTx(ctx, pgx.TxOptions{}); err != nil {
+	if tx, err = w.beginTx(ctx, j); err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer func() {
@@ -70,6 +70,7 @@ func (w *worker) handleYelpDetectSecretsRepoScan(ctx context.Context, j *db.Dequ
 		Type:            SyncLogTypeInfo,
 		RepoSyncQueueID: j.ID,
 		Message:         fmt.Sprintf("removed %d row(s) from yelp_detect_secrets_repo_scans", r.RowsAffected()),
+		Details:         rowDetails("removed", "yelp_detect_secrets_repo_scans", r.RowsAffected()),
 	}}); err != nil {
 		return err
 	}
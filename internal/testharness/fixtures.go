@@ -0,0 +1,77 @@
+package testharness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Commit is a commit of a fixture repository. Files are written (a nil content removes the file) relative to the
+// root of the repository, before the commit is made.
+type Commit struct {
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+	When        time.Time
+	Files       map[string]*string
+}
+
+// Content returns a pointer to the given file content, for use in Commit.Files
+func Content(s string) *string { return &s }
+
+// Repo builds a git repository, with the given commits on its default branch, in a directory removed once
+// the test completes, and returns its path (which can be added as the url of a repo, see Database.AddRepo)
+func Repo(t testing.TB, commits ...Commit) string {
+	t.Helper()
+
+	var dir = t.TempDir()
+	var repo, err = git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("could not init fixture repo: %v", err)
+	}
+
+	var worktree *git.Worktree
+	if worktree, err = repo.Worktree(); err != nil {
+		t.Fatalf("could not open worktree of fixture repo: %v", err)
+	}
+
+	for i, c := range commits {
+		for path, content := range c.Files {
+			var full = filepath.Join(dir, path)
+			if content == nil {
+				if _, err = worktree.Remove(path); err != nil {
+					t.Fatalf("commit %d: could not remove %s: %v", i, path, err)
+				}
+				continue
+			}
+
+			if err = os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+				t.Fatalf("commit %d: could not create directory of %s: %v", i, path, err)
+			}
+			if err = os.WriteFile(full, []byte(*content), 0o644); err != nil {
+				t.Fatalf("commit %d: could not write %s: %v", i, path, err)
+			}
+			if _, err = worktree.Add(path); err != nil {
+				t.Fatalf("commit %d: could not add %s: %v", i, path, err)
+			}
+		}
+
+		var author = &object.Signature{Name: c.AuthorName, Email: c.AuthorEmail, When: c.When}
+		if author.Name == "" {
+			author.Name, author.Email = "Fixture Author", "fixture@example.com"
+		}
+		if author.When.IsZero() {
+			author.When = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Hour)
+		}
+
+		if _, err = worktree.Commit(c.Message, &git.CommitOptions{Author: author, AllowEmptyCommits: true}); err != nil {
+			t.Fatalf("commit %d: could not commit: %v", i, err)
+		}
+	}
+
+	return dir
+}
@@ -0,0 +1,182 @@
+// Package testharness provides what end-to-end tests of the syncs need: a Postgres database with every migration
+// applied, and git repositories built from fixtures. Tests using it are behind the integration build tag, eg.
+//
+//	go test -tags=integration,static,system_libgit2 ./internal/syncer/...
+//
+// The database is the one MERGESTAT_TEST_POSTGRES points to (a connection string) if it's set, or else a throwaway
+// Postgres container started with the docker cli. Tests are skipped if neither is available.
+package testharness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// postgresImage is the image of the throwaway container, the same version docker-compose.yaml runs
+const postgresImage = "postgres:14"
+
+// Database is a migrated Postgres database
+type Database struct {
+	ConnString string
+	Pool       *pgxpool.Pool
+}
+
+// Postgres returns a database with every migration applied. The database (and the container, if one was started)
+// is removed once the test completes.
+func Postgres(t testing.TB) *Database {
+	t.Helper()
+
+	var connString = os.Getenv("MERGESTAT_TEST_POSTGRES")
+	if connString == "" {
+		connString = startContainer(t)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var pool, err = waitForPostgres(ctx, connString)
+	if err != nil {
+		t.Fatalf("could not connect to postgres: %v", err)
+	}
+
+	// each test gets its own database, so that tests sharing a server don't see each other's rows
+	var name = "mergestat_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err = pool.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("could not create database: %v", err)
+	}
+
+	var config = pool.Config().ConnConfig.Copy()
+	config.Database = name
+	var testConnString = fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", config.User, config.Password, config.Host, config.Port, name)
+
+	t.Cleanup(func() {
+		if _, err := pool.Exec(context.Background(), "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)"); err != nil {
+			t.Logf("could not drop database %s: %v", name, err)
+		}
+		pool.Close()
+	})
+
+	if err = applyMigrations(testConnString); err != nil {
+		t.Fatalf("could not apply migrations: %v", err)
+	}
+
+	var testPool *pgxpool.Pool
+	if testPool, err = pgxpool.Connect(ctx, testConnString); err != nil {
+		t.Fatalf("could not connect to test database: %v", err)
+	}
+	t.Cleanup(testPool.Close)
+
+	return &Database{ConnString: testConnString, Pool: testPool}
+}
+
+// startContainer starts a throwaway Postgres container, and returns the connection string of its server
+func startContainer(t testing.TB) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("neither MERGESTAT_TEST_POSTGRES nor docker is available")
+	}
+
+	var out, err = exec.Command("docker", "run", "--rm", "--detach", "--env", "POSTGRES_PASSWORD=postgres",
+		"--publish", "127.0.0.1::5432", postgresImage).Output()
+	if err != nil {
+		t.Skipf("could not start postgres container: %v", err)
+	}
+
+	var container = strings.TrimSpace(string(out))
+	t.Cleanup(func() {
+		if err := exec.Command("docker", "rm", "--force", container).Run(); err != nil {
+			t.Logf("could not remove container %s: %v", container, err)
+		}
+	})
+
+	if out, err = exec.Command("docker", "port", container, "5432/tcp").Output(); err != nil {
+		t.Fatalf("could not find the port of container %s: %v", container, err)
+	}
+
+	// docker port lists one address per line (eg. 127.0.0.1:49153)
+	var address = strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	return fmt.Sprintf("postgres://postgres:postgres@%s/postgres?sslmode=disable", address)
+}
+
+// waitForPostgres connects to the server, retrying until it accepts connections or the context is done
+func waitForPostgres(ctx context.Context, connString string) (*pgxpool.Pool, error) {
+	for {
+		var pool, err = pgxpool.Connect(ctx, connString)
+		if err == nil {
+			if err = pool.Ping(ctx); err == nil {
+				return pool, nil
+			}
+			pool.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// applyMigrations applies the migrations of the repository (see migrationsDir) to the database
+func applyMigrations(connString string) error {
+	var m, err = migrate.New("file://"+migrationsDir(), connString)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// migrationsDir returns the path of the migrations directory at the root of the repository
+func migrationsDir() string {
+	var _, file, _, _ = runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
+
+// AddRepo adds the repository at the given url (or path) to the database, under a generic git provider,
+// and returns its id
+func (d *Database) AddRepo(t testing.TB, url string) uuid.UUID {
+	t.Helper()
+
+	const query = `
+WITH provider AS (
+    INSERT INTO mergestat.providers (name, vendor, settings) VALUES ('test-' || gen_random_uuid(), 'git', '{}') RETURNING id
+)
+INSERT INTO public.repos (repo, provider) SELECT $1, id FROM provider RETURNING id`
+
+	var id uuid.UUID
+	if err := d.Pool.QueryRow(context.Background(), query, url).Scan(&id); err != nil {
+		t.Fatalf("could not add repo %s: %v", url, err)
+	}
+	return id
+}
+
+// Count returns the number of rows of the table for the repo
+func (d *Database) Count(t testing.TB, table string, repo uuid.UUID) int64 {
+	t.Helper()
+
+	var count int64
+	if err := d.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+table+" WHERE repo_id = $1", repo).Scan(&count); err != nil {
+		t.Fatalf("could not count rows of %s: %v", table, err)
+	}
+	return count
+}
@@ -0,0 +1,410 @@
+// Package tuning maintains the generated (ie. computed) columns and extra indexes configured on the synced tables (see
+// mergestat.generated_columns and mergestat.table_indexes), so that the common query patterns of an install are fast
+// without a DBA tuning each of them by hand. Columns are added (or dropped) first, as indexes may depend on them.
+package tuning
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/mergestat/mergestat/internal/helper"
+	"github.com/mergestat/mergestat/internal/namespace"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// lockTimeout is how long adding (or dropping) a column waits for the lock of its table, eg. while a sync writes
+// into it, before it's attempted again on the next run
+const lockTimeout = 10 * time.Second
+
+// backfillBlocks is the number of pages of a table whose rows are backfilled (see backfillColumn) at once
+const backfillBlocks = 1000
+
+// tuner periodically brings the generated columns and extra indexes of the synced tables in line with their configuration
+type tuner struct {
+	logger *zerolog.Logger
+	pool   *pgxpool.Pool
+	tables namespace.Registry
+}
+
+func New(logger *zerolog.Logger, pool *pgxpool.Pool, tables namespace.Registry) *tuner {
+	return &tuner{
+		logger: logger,
+		pool:   pool,
+		tables: tables,
+	}
+}
+
+func (t *tuner) Start(ctx context.Context, interval time.Duration) {
+	t.logger.Info().Msg("starting table tuning routine")
+	exec := func() {
+		if err := t.Apply(ctx); err != nil {
+			t.logger.Err(err).Msg("encountered error tuning tables")
+		}
+	}
+	exec()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.logger.Info().Msg("stopping table tuning routine")
+			return
+		case <-time.After(interval):
+			exec()
+		}
+	}
+}
+
+// Apply adds (or drops) the generated columns, then creates (or drops) the indexes, as configured. Only one worker
+// at a time tunes the tables. Failures of a column or an index are logged and recorded, and don't stop the others.
+func (t *tuner) Apply(ctx context.Context) (err error) {
+	var conn *pgxpool.Conn
+	if conn, err = t.pool.Acquire(ctx); err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock(hashtext('mergestat.table_tuning'))").Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		t.logger.Debug().Msg("tables are already being tuned by another worker, skipping")
+		return nil
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock(hashtext('mergestat.table_tuning'))")
+	}()
+
+	if err = t.applyColumns(ctx, conn); err != nil {
+		return errors.Wrapf(err, "failed to apply generated columns")
+	}
+	if err = t.applyIndexes(ctx, conn); err != nil {
+		return errors.Wrapf(err, "failed to apply table indexes")
+	}
+	return nil
+}
+
+// table returns the (qualified) identifier of the table where it lives, in public unless it's relocated
+func (t *tuner) table(name string) pgx.Identifier {
+	return t.tables.Identifier(pgx.Identifier{"public", name})
+}
+
+// generatedColumn is a generated column configured in mergestat.generated_columns
+type generatedColumn struct {
+	helper.GeneratedColumn
+	enabled bool
+	applied *string
+}
+
+func (t *tuner) applyColumns(ctx context.Context, conn *pgxpool.Conn) (err error) {
+	const listColumns = `
+SELECT table_name, column_name, type, expression, enabled, applied_definition FROM mergestat.generated_columns
+ORDER BY table_name, column_name`
+
+	var columns []generatedColumn
+	var rows pgx.Rows
+	if rows, err = conn.Query(ctx, listColumns); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var c generatedColumn
+		if err = rows.Scan(&c.Table, &c.Column, &c.Type, &c.Expression, &c.enabled, &c.applied); err != nil {
+			rows.Close()
+			return err
+		}
+		columns = append(columns, c)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	const record = `
+UPDATE mergestat.generated_columns SET applied_definition = $3, last_error = $4, applied_at = CASE WHEN $5::BOOLEAN THEN now() ELSE applied_at END
+WHERE table_name = $1 AND column_name = $2`
+
+	for _, c := range columns {
+		var definition, _ = c.Definition()
+		if c.enabled && c.applied != nil && *c.applied == definition {
+			continue
+		}
+		if !c.enabled && c.applied == nil {
+			continue
+		}
+
+		var applied = c.applied
+		var lastError *string
+		if err = t.applyColumn(ctx, conn, c); err != nil {
+			t.logger.Warn().AnErr("error", err).Msgf("could not apply generated column %s.%s", c.Table, c.Column)
+			var message = err.Error()
+			lastError = &message
+		} else if c.enabled {
+			applied = &definition
+			t.logger.Info().Msgf("added generated column %s.%s", c.Table, c.Column)
+		} else {
+			applied = nil
+			t.logger.Info().Msgf("dropped generated column %s.%s", c.Table, c.Column)
+		}
+
+		if _, err = conn.Exec(ctx, record, c.Table, c.Column, applied, lastError, lastError == nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyColumn drops the column, if it was added with another definition (or is disabled), and adds it, if enabled.
+// The column is a plain one, computed by a trigger (see helper.GeneratedColumn): adding (or dropping) it only takes
+// the lock of the table briefly, rather than for a rewrite of the table. It's then backfilled, in batches.
+// The view resolving the name of a relocated table (see namespace.Apply) is recreated, to have the same columns.
+func (t *tuner) applyColumn(ctx context.Context, conn *pgxpool.Conn, c generatedColumn) (err error) {
+	var function string
+	if c.enabled {
+		if function, err = c.TriggerFunction(); err != nil {
+			return err
+		}
+	}
+
+	var tx pgx.Tx
+	if tx, err = conn.Begin(ctx); err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if _, err = tx.Exec(ctx, fmt.Sprintf("SET LOCAL lock_timeout = %d", lockTimeout.Milliseconds())); err != nil {
+		return err
+	}
+
+	var table = t.table(c.Table)
+	var view = pgx.Identifier{"mergestat_tables", c.Table}.Sanitize()
+	var _, relocated = t.tables[c.Table]
+	var column = pgx.Identifier{c.Column}.Sanitize()
+	var trigger = pgx.Identifier{c.TriggerName()}.Sanitize()
+	var triggerFunction = pgx.Identifier{"mergestat", c.TriggerName()}.Sanitize()
+
+	// a column whose backfill failed has its trigger, but wasn't recorded as applied
+	var added = c.applied != nil
+	if !added {
+		const triggered = "SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_trigger WHERE tgrelid = $1::REGCLASS AND tgname = $2)"
+		if err = tx.QueryRow(ctx, triggered, table.Sanitize(), c.TriggerName()).Scan(&added); err != nil {
+			return err
+		}
+	}
+
+	var stmts []string
+	if relocated {
+		stmts = append(stmts, "DROP VIEW IF EXISTS "+view)
+	}
+	if added {
+		stmts = append(stmts,
+			"DROP TRIGGER IF EXISTS "+trigger+" ON "+table.Sanitize(),
+			"DROP FUNCTION IF EXISTS "+triggerFunction+"()",
+			"ALTER TABLE "+table.Sanitize()+" DROP COLUMN IF EXISTS "+column)
+	}
+	if c.enabled {
+		stmts = append(stmts,
+			"ALTER TABLE "+table.Sanitize()+" ADD COLUMN "+column+" "+strings.TrimSpace(c.Type),
+			function,
+			"CREATE TRIGGER "+trigger+" BEFORE INSERT OR UPDATE ON "+table.Sanitize()+" FOR EACH ROW EXECUTE FUNCTION "+triggerFunction+"()")
+	}
+	if relocated {
+		stmts = append(stmts, "CREATE VIEW "+view+" AS SELECT * FROM "+table.Sanitize())
+	}
+
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if c.enabled {
+		return t.backfillColumn(ctx, conn, table, c)
+	}
+	return nil
+}
+
+// backfillColumn computes the column of the rows written before it was added, backfillBlocks pages of the table at a
+// time (each in its own transaction, so that syncs writing into the table are only blocked by the rows of a batch):
+// updating the rows fires the trigger computing it. The rows written meanwhile are computed by the trigger already.
+func (t *tuner) backfillColumn(ctx context.Context, conn *pgxpool.Conn, table pgx.Identifier, c generatedColumn) (err error) {
+	var blocks int64
+	if err = conn.QueryRow(ctx, "SELECT pg_relation_size($1::REGCLASS) / current_setting('block_size')::BIGINT", table.Sanitize()).Scan(&blocks); err != nil {
+		return err
+	}
+
+	var column = pgx.Identifier{c.Column}.Sanitize()
+	var backfill = fmt.Sprintf("UPDATE %s SET %s = %s WHERE ctid >= format('(%%s,0)', $1::BIGINT)::TID AND ctid < format('(%%s,0)', $2::BIGINT)::TID",
+		table.Sanitize(), column, column)
+
+	for start := int64(0); start < blocks; start += backfillBlocks {
+		var tx pgx.Tx
+		if tx, err = conn.Begin(ctx); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, fmt.Sprintf("SET LOCAL lock_timeout = %d", lockTimeout.Milliseconds())); err == nil {
+			_, err = tx.Exec(ctx, backfill, start, start+backfillBlocks)
+		}
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return errors.Wrapf(err, "failed to backfill pages %d to %d", start, start+backfillBlocks)
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tableIndex is an index configured in mergestat.table_indexes
+type tableIndex struct {
+	helper.TableIndex
+	enabled   bool
+	indexName *string
+}
+
+func (t *tuner) applyIndexes(ctx context.Context, conn *pgxpool.Conn) (err error) {
+	const listIndexes = `
+SELECT table_name, name, method, columns, COALESCE(predicate, ''), enabled, index_name FROM mergestat.table_indexes
+ORDER BY table_name, name`
+
+	var indexes = make(map[string][]tableIndex)
+	var tables []string
+	var rows pgx.Rows
+	if rows, err = conn.Query(ctx, listIndexes); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var i tableIndex
+		if err = rows.Scan(&i.Table, &i.Name, &i.Method, &i.Columns, &i.Predicate, &i.enabled, &i.indexName); err != nil {
+			rows.Close()
+			return err
+		}
+		if _, ok := indexes[i.Table]; !ok {
+			tables = append(tables, i.Table)
+		}
+		indexes[i.Table] = append(indexes[i.Table], i)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if err = t.applyIndexesOf(ctx, conn, table, indexes[table]); err != nil {
+			t.logger.Warn().AnErr("error", err).Msgf("could not apply indexes of %s", table)
+		}
+	}
+	return nil
+}
+
+// applyIndexesOf brings the (configured) indexes of the table in line with their configuration
+func (t *tuner) applyIndexesOf(ctx context.Context, conn *pgxpool.Conn, name string, indexes []tableIndex) (err error) {
+	var table = t.table(name)
+	var schema, relation = table[0], table[1]
+
+	// existing maps the configured indexes of the table (by name) to whether they're valid, ie. their build completed
+	const listExisting = `
+SELECT c.relname, i.indisvalid FROM pg_catalog.pg_index i
+    INNER JOIN pg_catalog.pg_class c ON c.oid = i.indexrelid
+    INNER JOIN pg_catalog.pg_class t ON t.oid = i.indrelid
+    INNER JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname = $1 AND t.relname = $2 AND starts_with(c.relname, $3)`
+
+	var existing = make(map[string]bool)
+	var rows pgx.Rows
+	if rows, err = conn.Query(ctx, listExisting, schema, relation, helper.TableIndexPrefix); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var index string
+		var valid bool
+		if err = rows.Scan(&index, &valid); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[index] = valid
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	var wanted = make(map[string]bool)
+	for _, i := range indexes {
+		if i.enabled {
+			wanted[i.IndexName()] = true
+		}
+	}
+
+	// indexes disabled, reconfigured (under another name), or whose build failed, are dropped
+	for index, valid := range existing {
+		if wanted[index] && valid {
+			continue
+		}
+		if _, err = conn.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+pgx.Identifier{schema, index}.Sanitize()); err != nil {
+			return err
+		}
+		delete(existing, index)
+		t.logger.Info().Msgf("dropped index %s of %s", index, name)
+	}
+
+	const record = `
+UPDATE mergestat.table_indexes SET index_name = $3, last_error = $4, indexed_at = CASE WHEN $5::BOOLEAN THEN now() ELSE indexed_at END
+WHERE table_name = $1 AND name = $2`
+
+	for _, i := range indexes {
+		var index = i.IndexName()
+		var indexName *string
+		var lastError *string
+		var changed bool
+
+		switch _, exists := existing[index]; {
+		case !i.enabled:
+			changed = i.indexName != nil
+		case exists:
+			indexName = &index
+			changed = i.indexName == nil || *i.indexName != index
+		default:
+			if err = createIndex(ctx, conn, table, schema, i.TableIndex); err != nil {
+				t.logger.Warn().AnErr("error", err).Msgf("could not create index %s of %s", index, name)
+				var message = err.Error()
+				lastError = &message
+			} else {
+				indexName = &index
+				t.logger.Info().Msgf("created index %s of %s", index, name)
+			}
+			changed = err == nil
+		}
+
+		if _, err = conn.Exec(ctx, record, i.Table, i.Name, indexName, lastError, changed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createIndex builds the index concurrently, dropping what's left of it if the build fails
+func createIndex(ctx context.Context, conn *pgxpool.Conn, table pgx.Identifier, schema string, i helper.TableIndex) (err error) {
+	var definition string
+	if definition, err = i.Definition(table); err != nil {
+		return err
+	}
+
+	if _, err = conn.Exec(ctx, definition); err != nil {
+		// a failed concurrent build leaves an invalid index behind, it's dropped so that it's attempted again
+		_, _ = conn.Exec(context.Background(), "DROP INDEX CONCURRENTLY IF EXISTS "+pgx.Identifier{schema, i.IndexName()}.Sanitize())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// escapeModulePath escapes a module path for the module proxy protocol, where upper case letters are
+// replaced by an exclamation mark followed by the letter's lower case
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// GoProxy fetches the module from proxy.golang.org. The proxy doesn't report downloads.
+func (c *Client) GoProxy(ctx context.Context, module string) (_ *Package, err error) {
+	var base = "https://proxy.golang.org/" + escapeModulePath(module) + "/@v/"
+
+	var request, _ = http.NewRequest(http.MethodGet, base+"list", http.NoBody)
+	request = request.WithContext(ctx)
+
+	var response *http.Response
+	if response, err = c.client.Do(request); err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+		return nil, ErrNotFound
+	case response.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %d from %s", response.StatusCode, base+"list")
+	}
+
+	var versions []string
+	var scanner = bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		if v := strings.TrimSpace(scanner.Text()); v != "" {
+			versions = append(versions, v)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var pkg = &Package{Registry: "go", Name: module, DownloadsLastMonth: -1}
+	for _, v := range versions {
+		var info struct {
+			Version string    `json:"Version"`
+			Time    time.Time `json:"Time"`
+		}
+		if err = c.getJSON(ctx, base+escapeModulePath(v)+".info", &info); err != nil {
+			return nil, err
+		}
+		pkg.Versions = append(pkg.Versions, &Version{Version: info.Version, ReleasedAt: info.Time})
+	}
+
+	var latest struct {
+		Version string `json:"Version"`
+	}
+	if err = c.getJSON(ctx, "https://proxy.golang.org/"+escapeModulePath(module)+"/@latest", &latest); err == nil {
+		pkg.Latest = latest.Version
+	}
+
+	return pkg, nil
+}
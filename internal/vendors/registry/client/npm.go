@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NPM fetches the package from registry.npmjs.org, and its downloads from api.npmjs.org
+func (c *Client) NPM(ctx context.Context, name string) (_ *Package, err error) {
+	// scoped packages (@scope/name) keep the @ but escape the slash
+	var escaped = strings.Replace(url.PathEscape(name), "%40", "@", 1)
+
+	var doc struct {
+		DistTags map[string]string    `json:"dist-tags"`
+		Time     map[string]time.Time `json:"time"`
+	}
+	if err = c.getJSON(ctx, "https://registry.npmjs.org/"+escaped, &doc); err != nil {
+		return nil, err
+	}
+
+	var pkg = &Package{Registry: "npm", Name: name, Latest: doc.DistTags["latest"], DownloadsLastMonth: -1}
+	for version, released := range doc.Time {
+		if version == "created" || version == "modified" {
+			continue
+		}
+		pkg.Versions = append(pkg.Versions, &Version{Version: version, ReleasedAt: released})
+	}
+
+	var downloads struct {
+		Downloads int64 `json:"downloads"`
+	}
+	if err = c.getJSON(ctx, "https://api.npmjs.org/downloads/point/last-month/"+escaped, &downloads); err == nil {
+		pkg.DownloadsLastMonth = downloads.Downloads
+	}
+
+	return pkg, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// PyPI fetches the package from pypi.org, and its downloads from pypistats.org
+func (c *Client) PyPI(ctx context.Context, name string) (_ *Package, err error) {
+	var doc struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Releases map[string][]struct {
+			UploadTime string `json:"upload_time_iso_8601"`
+		} `json:"releases"`
+	}
+	if err = c.getJSON(ctx, "https://pypi.org/pypi/"+url.PathEscape(name)+"/json", &doc); err != nil {
+		return nil, err
+	}
+
+	var pkg = &Package{Registry: "pypi", Name: name, Latest: doc.Info.Version, DownloadsLastMonth: -1}
+	for version, files := range doc.Releases {
+		// a release is published when its first file is uploaded
+		var released time.Time
+		for _, f := range files {
+			if t, err := time.Parse(time.RFC3339, f.UploadTime); err == nil && (released.IsZero() || t.Before(released)) {
+				released = t
+			}
+		}
+		pkg.Versions = append(pkg.Versions, &Version{Version: version, ReleasedAt: released})
+	}
+
+	var stats struct {
+		Data struct {
+			LastMonth int64 `json:"last_month"`
+		} `json:"data"`
+	}
+	if err = c.getJSON(ctx, "https://pypistats.org/api/packages/"+url.PathEscape(name)+"/recent", &stats); err == nil {
+		pkg.DownloadsLastMonth = stats.Data.LastMonth
+	}
+
+	return pkg, nil
+}
@@ -0,0 +1,86 @@
+// Package client provides a minimal client for the public package registries (npm, PyPI and the Go module proxy)
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrNotFound is returned when the package isn't published in the registry
+var ErrNotFound = errors.New("package not found")
+
+// HttpClient is a shim over the default http.Client object
+type HttpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client looks up packages in the public registries
+type Client struct {
+	client HttpClient
+}
+
+// New creates a new instance of the registry client.
+func New(client HttpClient) *Client {
+	return &Client{client: client}
+}
+
+// Version is a published version of a package
+type Version struct {
+	Version    string
+	ReleasedAt time.Time
+}
+
+// Package is a package published in a registry
+type Package struct {
+	Registry string
+	Name     string
+
+	// Versions are the published versions, in no particular order
+	Versions []*Version
+
+	// Latest is the version the registry considers the latest one, if any
+	Latest string
+
+	// DownloadsLastMonth is the number of downloads in the last month, or -1 if the registry doesn't report it
+	DownloadsLastMonth int64
+}
+
+// getJSON fetches the target url and decodes the json response into result
+func (c *Client) getJSON(ctx context.Context, target string, result interface{}) (err error) {
+	var request, _ = http.NewRequest(http.MethodGet, target, http.NoBody)
+	request = request.WithContext(ctx)
+	request.Header.Set("Accept", "application/json")
+
+	var response *http.Response
+	if response, err = c.client.Do(request); err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+		return ErrNotFound
+	case response.StatusCode != http.StatusOK:
+		return fmt.Errorf("unexpected status %d from %s", response.StatusCode, target)
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// Lookup fetches the package from the given registry (npm, pypi or go)
+func (c *Client) Lookup(ctx context.Context, registry, name string) (*Package, error) {
+	switch registry {
+	case "npm":
+		return c.NPM(ctx, name)
+	case "pypi":
+		return c.PyPI(ctx, name)
+	case "go":
+		return c.GoProxy(ctx, name)
+	default:
+		return nil, fmt.Errorf("unknown registry %q", registry)
+	}
+}
@@ -21,6 +21,7 @@ const (
 	startingProcess    jobStatus = "starting"
 	insertedProcess    jobStatus = "inserted"
 	unexpectedBehavior jobStatus = "unexpected behavior"
+	rowCountMismatch   jobStatus = "row count mismatch"
 )
 
 type syncLog struct {
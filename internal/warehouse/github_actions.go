@@ -115,6 +115,8 @@ func (w *warehouse) handleWorkflowRuns(ctx context.Context, owner, repo string,
 	repoID := job.RepoID
 	runsCount := 0
 	jobsCount := 0
+	// expectedRuns is the total_count of runs reported by the API, used to detect runs that went missing
+	expectedRuns := 0
 	pagination, err := w.getPaginationOpt("GITHUB_WORKFLOW_RUNS_PER_PAGE")
 	if err != nil {
 		return err
@@ -177,6 +179,10 @@ func (w *warehouse) handleWorkflowRuns(ctx context.Context, owner, repo string,
 				continue
 			}
 
+			if opt.Page == 0 && workflowRunsPage.TotalCount != nil {
+				expectedRuns = *workflowRunsPage.TotalCount
+			}
+
 			if *workflowRunsPage.TotalCount > 0 && len(workflowRunsPage.WorkflowRuns) > 0 {
 
 				if err := w.handleWorkflowRunsUpsert(ctx, workflowRunsPage.WorkflowRuns, repoID); err != nil {
@@ -204,9 +210,17 @@ func (w *warehouse) handleWorkflowRuns(ctx context.Context, owner, repo string,
 			return err
 		}
 
+		if runsCount != expectedRuns {
+			operation := fmt.Sprintf("for workflow %s: the API reported %d runs but %d were synced", *workflow.Name, expectedRuns, runsCount)
+			if err := w.batchProcessLogMessages(ctx, SyncLogTypeWarning, job, rowCountMismatch, operation); err != nil {
+				return err
+			}
+		}
+
 		opt.Page = 0
 		runsCount = 0
 		jobsCount = 0
+		expectedRuns = 0
 		w.logger.Info().Msgf("finished getting all github actions from workflow %s", *workflow.Name)
 	}
 
@@ -0,0 +1,15 @@
+BEGIN;
+
+-- mergestat_staging holds copies of the public tables that dry-run syncs write into.
+-- The tables themselves are created (empty) by the worker the first time it executes a dry-run sync, and recreated
+-- when their columns no longer match those of the tables in public. A dry run first deletes the rows of its repo
+-- left by earlier ones. To reset, drop the schema.
+CREATE SCHEMA IF NOT EXISTS mergestat_staging;
+
+COMMENT ON SCHEMA mergestat_staging IS 'tables written into by dry-run syncs, in place of the ones in public';
+COMMENT ON COLUMN mergestat.repo_syncs.settings IS 'JSON settings for the repo sync, set {"dryRun": true} to write into mergestat_staging instead of public';
+
+GRANT USAGE ON SCHEMA mergestat_staging TO readaccess;
+ALTER DEFAULT PRIVILEGES IN SCHEMA mergestat_staging GRANT SELECT ON TABLES TO readaccess;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE mergestat.repo_syncs
+ADD COLUMN IF NOT EXISTS last_completed_checksum TEXT;
+
+COMMENT ON COLUMN mergestat.repo_syncs.last_completed_checksum IS 'checksum (HEAD sha or provider content hash) of the source at the last successful sync, with a hash of its settings';
+
+COMMIT;
@@ -0,0 +1,88 @@
+BEGIN;
+
+-- retention policies for finished repo sync jobs, by outcome of the job
+CREATE TABLE IF NOT EXISTS mergestat.repo_sync_queue_retention_policies (
+    outcome TEXT PRIMARY KEY CHECK (outcome IN ('SUCCESS', 'ERROR')),
+    retention_days INTEGER NOT NULL CHECK (retention_days > 0),
+    archive BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+COMMENT ON TABLE mergestat.repo_sync_queue_retention_policies IS 'retention policies for finished repo sync jobs (and their logs), by outcome';
+COMMENT ON COLUMN mergestat.repo_sync_queue_retention_policies.outcome IS 'outcome of the job the policy applies to: SUCCESS or ERROR (a job with at least one ERROR log)';
+COMMENT ON COLUMN mergestat.repo_sync_queue_retention_policies.retention_days IS 'number of days to keep a finished job for';
+COMMENT ON COLUMN mergestat.repo_sync_queue_retention_policies.archive IS 'if true, jobs (and their logs) are copied into mergestat.repo_sync_queue_archive before they are deleted';
+
+INSERT INTO mergestat.repo_sync_queue_retention_policies (outcome, retention_days, archive)
+VALUES
+('SUCCESS', 30, FALSE),
+('ERROR', 90, FALSE)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS mergestat.repo_sync_queue_archive (
+    id BIGINT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    repo_sync_id UUID NOT NULL,
+    status TEXT NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE,
+    done_at TIMESTAMP WITH TIME ZONE,
+    priority INTEGER NOT NULL,
+    type_group TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    logs JSONB NOT NULL DEFAULT '[]'::JSONB,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- logs are stored out of line and compressed (TOAST), they're seldom read
+ALTER TABLE mergestat.repo_sync_queue_archive ALTER COLUMN logs SET STORAGE EXTENDED;
+
+CREATE INDEX IF NOT EXISTS idx_repo_sync_queue_archive_repo_sync_id ON mergestat.repo_sync_queue_archive (repo_sync_id);
+
+COMMENT ON TABLE mergestat.repo_sync_queue_archive IS 'archive of repo sync jobs (and their logs) removed by mergestat.repo_sync_queue_cleanup()';
+COMMENT ON COLUMN mergestat.repo_sync_queue_archive.id IS 'id of the job in mergestat.repo_sync_queue';
+COMMENT ON COLUMN mergestat.repo_sync_queue_archive.outcome IS 'outcome of the job: SUCCESS or ERROR';
+COMMENT ON COLUMN mergestat.repo_sync_queue_archive.logs IS 'array of the job logs, each with created_at, log_type and message';
+COMMENT ON COLUMN mergestat.repo_sync_queue_archive.archived_at IS 'timestamp of when the job was archived';
+
+-- repo_sync_queue_cleanup removes finished jobs according to mergestat.repo_sync_queue_retention_policies,
+-- archiving them first where configured. Only one caller at a time does any work (the "leader"), concurrent
+-- callers return NULL immediately.
+CREATE OR REPLACE FUNCTION mergestat.repo_sync_queue_cleanup()
+RETURNS INTEGER
+AS
+$$
+DECLARE _rows_deleted INTEGER := 0;
+DECLARE _count INTEGER;
+DECLARE _policy RECORD;
+BEGIN
+    IF NOT pg_try_advisory_xact_lock(hashtext('mergestat.repo_sync_queue_cleanup')) THEN
+        RETURN NULL;
+    END IF;
+
+    FOR _policy IN SELECT * FROM mergestat.repo_sync_queue_retention_policies LOOP
+        CREATE TEMPORARY TABLE _expired ON COMMIT DROP AS
+            SELECT q.* FROM mergestat.repo_sync_queue q
+            WHERE q.status = 'DONE'
+                AND q.created_at < CURRENT_DATE - _policy.retention_days
+                AND mergestat.repo_sync_queue_has_error(q) = (_policy.outcome = 'ERROR');
+
+        IF _policy.archive THEN
+            INSERT INTO mergestat.repo_sync_queue_archive (id, created_at, repo_sync_id, status, started_at, done_at, priority, type_group, outcome, logs)
+            SELECT e.id, e.created_at, e.repo_sync_id, e.status, e.started_at, e.done_at, e.priority, e.type_group, _policy.outcome,
+                COALESCE((SELECT jsonb_agg(jsonb_build_object('created_at', l.created_at, 'log_type', l.log_type, 'message', l.message) ORDER BY l.id)
+                    FROM mergestat.repo_sync_logs l WHERE l.repo_sync_queue_id = e.id), '[]'::JSONB)
+            FROM _expired e
+            ON CONFLICT (id) DO NOTHING;
+        END IF;
+
+        DELETE FROM mergestat.repo_sync_queue WHERE id IN (SELECT id FROM _expired);
+        GET DIAGNOSTICS _count = ROW_COUNT;
+        _rows_deleted := _rows_deleted + _count;
+
+        DROP TABLE _expired;
+    END LOOP;
+
+    RETURN _rows_deleted;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,82 @@
+BEGIN;
+
+-- number of rows a job wrote, as reported by its "inserted %d row(s) into <table>" logs
+CREATE OR REPLACE FUNCTION mergestat.repo_sync_queue_rows_written(job mergestat.repo_sync_queue)
+RETURNS BIGINT
+LANGUAGE SQL STABLE
+AS $$
+    SELECT COALESCE(SUM((regexp_match(message, '^inserted (\d+) row\(s\)'))[1]::BIGINT), 0)
+    FROM mergestat.repo_sync_logs
+    WHERE repo_sync_queue_id = job.id AND log_type = 'INFO' AND message LIKE 'inserted %';
+$$;
+
+COMMENT ON FUNCTION mergestat.repo_sync_queue_rows_written(mergestat.repo_sync_queue) IS 'number of rows written by the job, as reported in its logs';
+
+--https://www.graphile.org/postgraphile/custom-queries/
+CREATE OR REPLACE FUNCTION mergestat.repo_sync_history(repo_sync_id UUID, run_limit INTEGER DEFAULT 20)
+RETURNS TABLE (
+    job_id BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    done_at TIMESTAMP WITH TIME ZONE,
+    duration_seconds DOUBLE PRECISION,
+    rows_written BIGINT,
+    outcome TEXT
+)
+LANGUAGE SQL STABLE
+AS $$
+    SELECT
+        q.id,
+        q.created_at,
+        q.started_at,
+        q.done_at,
+        EXTRACT(EPOCH FROM q.done_at - q.started_at)::DOUBLE PRECISION,
+        mergestat.repo_sync_queue_rows_written(q),
+        CASE
+            WHEN q.status <> 'DONE' THEN q.status
+            WHEN mergestat.repo_sync_queue_has_error(q) THEN 'ERROR'
+            ELSE 'SUCCESS'
+        END
+    FROM mergestat.repo_sync_queue q
+    WHERE q.repo_sync_id = repo_sync_history.repo_sync_id
+    ORDER BY q.created_at DESC
+    LIMIT run_limit;
+$$;
+
+COMMENT ON FUNCTION mergestat.repo_sync_history(UUID, INTEGER) IS 'last N runs of a repo sync with their duration, rows written and outcome (SUCCESS, ERROR or the status of an unfinished job)';
+
+-- percentile durations of successful runs, by sync type, in the given window
+CREATE OR REPLACE FUNCTION mergestat.repo_sync_type_duration_percentiles(since_days INTEGER DEFAULT 30)
+RETURNS TABLE (
+    sync_type TEXT,
+    runs BIGINT,
+    p50_seconds DOUBLE PRECISION,
+    p90_seconds DOUBLE PRECISION,
+    p99_seconds DOUBLE PRECISION,
+    max_seconds DOUBLE PRECISION
+)
+LANGUAGE SQL STABLE
+AS $$
+    WITH durations AS (
+        SELECT s.sync_type, EXTRACT(EPOCH FROM q.done_at - q.started_at)::DOUBLE PRECISION AS seconds
+        FROM mergestat.repo_sync_queue q
+        INNER JOIN mergestat.repo_syncs s ON s.id = q.repo_sync_id
+        WHERE q.status = 'DONE' AND q.started_at IS NOT NULL AND q.done_at IS NOT NULL
+            AND q.created_at > now() - make_interval(days => since_days)
+            AND NOT mergestat.repo_sync_queue_has_error(q)
+    )
+    SELECT
+        sync_type,
+        COUNT(*),
+        percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds),
+        percentile_cont(0.9) WITHIN GROUP (ORDER BY seconds),
+        percentile_cont(0.99) WITHIN GROUP (ORDER BY seconds),
+        MAX(seconds)
+    FROM durations
+    GROUP BY sync_type
+    ORDER BY sync_type;
+$$;
+
+COMMENT ON FUNCTION mergestat.repo_sync_type_duration_percentiles(INTEGER) IS 'p50, p90 and p99 durations of successful runs, by sync type, over the last N days';
+
+COMMIT;
@@ -0,0 +1,115 @@
+BEGIN;
+
+-- recent duration of a repo sync: the average of its last 5 successful runs (NULL if there are none)
+CREATE OR REPLACE FUNCTION mergestat.repo_sync_recent_duration(_repo_sync_id UUID)
+RETURNS INTERVAL
+LANGUAGE SQL STABLE
+AS $$
+    SELECT AVG(h.done_at - h.started_at) FROM (
+        SELECT q.done_at, q.started_at FROM mergestat.repo_sync_queue q
+        WHERE q.repo_sync_id = _repo_sync_id AND q.status = 'DONE' AND q.started_at IS NOT NULL AND q.done_at IS NOT NULL
+            AND NOT mergestat.repo_sync_queue_has_error(q)
+        ORDER BY q.created_at DESC LIMIT 5
+    ) h;
+$$;
+
+COMMENT ON FUNCTION mergestat.repo_sync_recent_duration(UUID) IS 'average duration of the last 5 successful runs of the repo sync';
+
+-- estimated duration of a job: the average of the last 5 successful runs of the same repo sync, falling back
+-- to the median of successful runs of the same sync type in the last 30 days (and to a minute if there's no history)
+CREATE OR REPLACE FUNCTION mergestat.repo_sync_queue_estimated_duration(job mergestat.repo_sync_queue)
+RETURNS INTERVAL
+LANGUAGE SQL STABLE
+AS $$
+    SELECT COALESCE(
+        mergestat.repo_sync_recent_duration(job.repo_sync_id),
+        (
+            SELECT make_interval(secs => p.p50_seconds)
+            FROM mergestat.repo_sync_type_duration_percentiles(30) p
+            INNER JOIN mergestat.repo_syncs s ON s.sync_type = p.sync_type
+            WHERE s.id = job.repo_sync_id
+        ),
+        INTERVAL '1 minute'
+    );
+$$;
+
+COMMENT ON FUNCTION mergestat.repo_sync_queue_estimated_duration(mergestat.repo_sync_queue) IS 'estimated duration of the job, based on the durations of previous runs';
+
+-- queue_eta estimates when each queued or running job will finish. Jobs of a type group are assumed to run
+-- concurrent_syncs at a time (i.e. that there are enough workers), in the order they are dequeued in.
+-- Jobs are estimated like mergestat.repo_sync_queue_estimated_duration does, the percentiles of the sync types
+-- computed once (rather than once per job).
+CREATE OR REPLACE VIEW mergestat.queue_eta AS (
+    WITH type_durations AS (
+        SELECT p.sync_type, make_interval(secs => p.p50_seconds) AS p50 FROM mergestat.repo_sync_type_duration_percentiles(30) p
+    ), estimated AS (
+        SELECT q.*, COALESCE(mergestat.repo_sync_recent_duration(q.repo_sync_id), d.p50, INTERVAL '1 minute') AS estimated_duration
+        FROM mergestat.repo_sync_queue q
+        INNER JOIN mergestat.repo_syncs s ON s.id = q.repo_sync_id
+        LEFT JOIN type_durations d ON d.sync_type = s.sync_type
+        WHERE q.status IN ('QUEUED', 'RUNNING')
+    ), pending AS (
+        SELECT
+            q.id,
+            q.repo_sync_id,
+            q.status,
+            q.type_group,
+            q.priority,
+            q.created_at,
+            g.concurrent_syncs,
+            CASE
+                WHEN q.status = 'RUNNING' THEN GREATEST(q.estimated_duration - (now() - q.started_at), INTERVAL '0')
+                ELSE q.estimated_duration
+            END AS remaining
+        FROM estimated q
+        INNER JOIN mergestat.repo_sync_type_groups g ON g.group = q.type_group
+    ), ordered AS (
+        SELECT
+            pending.*,
+            COALESCE(SUM(remaining) OVER (
+                PARTITION BY type_group
+                ORDER BY status = 'QUEUED', priority ASC, created_at ASC, id ASC
+                ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+            ), INTERVAL '0') AS ahead
+        FROM pending
+    )
+    SELECT
+        id AS job_id,
+        repo_sync_id,
+        status,
+        type_group,
+        remaining AS estimated_remaining,
+        CASE WHEN status = 'RUNNING' THEN NULL ELSE now() + ahead / GREATEST(concurrent_syncs, 1) END AS estimated_start_at,
+        CASE WHEN status = 'RUNNING' THEN now() + remaining ELSE now() + ahead / GREATEST(concurrent_syncs, 1) + remaining END AS estimated_done_at
+    FROM ordered
+);
+
+COMMENT ON VIEW mergestat.queue_eta IS 'estimated start and completion times of queued and running repo sync jobs';
+COMMENT ON COLUMN mergestat.queue_eta.estimated_remaining IS 'estimated time left for the job to complete, once started';
+COMMENT ON COLUMN mergestat.queue_eta.estimated_start_at IS 'estimated time the job will be started at (NULL if already running)';
+COMMENT ON COLUMN mergestat.queue_eta.estimated_done_at IS 'estimated time the job will be completed at';
+
+-- queue_cycle_eta estimates when all currently queued and running jobs of each type group will be completed
+CREATE OR REPLACE VIEW mergestat.queue_cycle_eta AS (
+    SELECT
+        type_group,
+        COUNT(*) FILTER (WHERE status = 'QUEUED') AS queued,
+        COUNT(*) FILTER (WHERE status = 'RUNNING') AS running,
+        MAX(estimated_done_at) AS estimated_done_at
+    FROM mergestat.queue_eta
+    GROUP BY type_group
+);
+
+COMMENT ON VIEW mergestat.queue_cycle_eta IS 'estimated completion time of the current sync cycle, by type group';
+
+--https://www.graphile.org/postgraphile/computed-columns/
+CREATE OR REPLACE FUNCTION mergestat.repo_sync_queue_estimated_done_at(job mergestat.repo_sync_queue)
+RETURNS TIMESTAMP WITH TIME ZONE
+LANGUAGE SQL STABLE
+AS $$
+    SELECT estimated_done_at FROM mergestat.queue_eta WHERE job_id = job.id;
+$$;
+
+COMMENT ON FUNCTION mergestat.repo_sync_queue_estimated_done_at(mergestat.repo_sync_queue) IS 'estimated completion time of a queued or running job';
+
+COMMIT;
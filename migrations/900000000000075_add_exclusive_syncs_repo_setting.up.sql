@@ -0,0 +1,28 @@
+BEGIN;
+
+COMMENT ON COLUMN public.repos.settings IS 'JSON settings for the repo. Set exclusiveSyncs to true to never run more than one sync (of any type) at a time for the repo, so that it is only cloned once at a time';
+
+-- mergestat.try_exclusive_repo_sync is checked when dequeuing a job of a repo with the exclusiveSyncs setting: it locks
+-- the repo until the dequeuing transaction commits, so that dequeues of any type group serialize on the repo (row locks
+-- only serialize dequeues within a group), and then checks that none of the syncs of the repo is running. The function
+-- is volatile, so the check runs with a snapshot taken once the lock is held, and sees the job a concurrent dequeue just
+-- started (its lock is released once it commits).
+CREATE OR REPLACE FUNCTION mergestat.try_exclusive_repo_sync(_repo_id UUID)
+RETURNS BOOLEAN
+AS $$
+BEGIN
+    IF NOT pg_try_advisory_xact_lock(hashtext('mergestat.exclusive_syncs'), hashtext(_repo_id::TEXT)) THEN
+        RETURN FALSE;
+    END IF;
+
+    RETURN NOT EXISTS (
+        SELECT 1 FROM mergestat.repo_sync_queue q
+        INNER JOIN mergestat.repo_syncs rs ON rs.id = q.repo_sync_id
+        WHERE rs.repo_id = _repo_id AND q.status = 'RUNNING'
+    );
+END;
+$$ LANGUAGE PLPGSQL VOLATILE;
+
+COMMENT ON FUNCTION mergestat.try_exclusive_repo_sync(UUID) IS 'locks the repo (until the transaction commits) and returns true if none of its syncs is running, false if it is locked or one is running';
+
+COMMIT;
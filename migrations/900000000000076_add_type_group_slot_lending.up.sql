@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE mergestat.repo_sync_type_groups
+ADD COLUMN IF NOT EXISTS lend_idle_slots BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS max_borrowed_syncs INTEGER NOT NULL DEFAULT 0 CHECK (max_borrowed_syncs >= 0);
+
+COMMENT ON COLUMN mergestat.repo_sync_type_groups.lend_idle_slots IS 'if true, the slots of the group can be used by other groups when it has no queued syncs';
+COMMENT ON COLUMN mergestat.repo_sync_type_groups.max_borrowed_syncs IS 'maximum number of syncs of the group that can run in slots borrowed from idle groups (0 to never borrow)';
+
+COMMIT;
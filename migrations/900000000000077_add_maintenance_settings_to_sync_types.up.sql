@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE mergestat.repo_sync_types
+ADD COLUMN IF NOT EXISTS analyze_after_sync BOOLEAN NOT NULL DEFAULT TRUE,
+ADD COLUMN IF NOT EXISTS reindex_after_sync BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN mergestat.repo_sync_types.analyze_after_sync IS 'if true, the tables written by the sync are analyzed after a successful sync';
+COMMENT ON COLUMN mergestat.repo_sync_types.reindex_after_sync IS 'if true, the indexes of the tables written by the sync are rebuilt after a successful sync';
+
+COMMIT;
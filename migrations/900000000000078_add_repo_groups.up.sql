@@ -0,0 +1,70 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS mergestat.repo_groups (
+    id UUID PRIMARY KEY DEFAULT public.gen_random_uuid() NOT NULL,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    tag TEXT,
+    sync_interval INTERVAL,
+    last_enqueued_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE mergestat.repo_groups IS 'groups of repos (eg. the repos of a project or platform), whose syncs are scheduled together';
+COMMENT ON COLUMN mergestat.repo_groups.name IS 'unique name of the group';
+COMMENT ON COLUMN mergestat.repo_groups.tag IS 'if set, every repo with this tag is a member of the group (in addition to explicit members)';
+COMMENT ON COLUMN mergestat.repo_groups.sync_interval IS 'if set, the enabled syncs of the repos in the group are enqueued on this interval (eg. 1 hour)';
+COMMENT ON COLUMN mergestat.repo_groups.last_enqueued_at IS 'timestamp of when the syncs of the group were last enqueued';
+
+CREATE TABLE IF NOT EXISTS mergestat.repo_group_members (
+    group_id UUID NOT NULL REFERENCES mergestat.repo_groups(id) ON DELETE CASCADE,
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, repo_id)
+);
+
+COMMENT ON TABLE mergestat.repo_group_members IS 'explicit membership of repos in groups';
+
+-- repo_group_repos resolves the members of each group, both explicit and by tag
+CREATE OR REPLACE VIEW mergestat.repo_group_repos AS (
+    SELECT m.group_id, m.repo_id FROM mergestat.repo_group_members m
+    UNION
+    SELECT g.id AS group_id, r.id AS repo_id
+    FROM mergestat.repo_groups g
+    INNER JOIN public.repos r ON r.tags ? g.tag
+    WHERE g.tag IS NOT NULL
+);
+
+COMMENT ON VIEW mergestat.repo_group_repos IS 'repos of each group, explicit members and repos with the tag of the group';
+
+-- enqueue_repo_group_syncs enqueues the enabled syncs of the repos in every group whose sync interval has elapsed,
+-- skipping syncs that are already queued or running. It returns the number of jobs enqueued.
+CREATE OR REPLACE FUNCTION mergestat.enqueue_repo_group_syncs()
+RETURNS INTEGER
+AS
+$$
+DECLARE _enqueued INTEGER;
+BEGIN
+    WITH due AS (
+        UPDATE mergestat.repo_groups SET last_enqueued_at = now()
+        WHERE sync_interval IS NOT NULL AND (last_enqueued_at IS NULL OR last_enqueued_at + sync_interval <= now())
+        RETURNING id
+    ), enqueued AS (
+        INSERT INTO mergestat.repo_sync_queue (repo_sync_id, status, priority, type_group)
+        SELECT DISTINCT rs.id, 'QUEUED', rs.priority, rst.type_group
+        FROM due
+        INNER JOIN mergestat.repo_group_repos grr ON grr.group_id = due.id
+        INNER JOIN mergestat.repo_syncs rs ON rs.repo_id = grr.repo_id
+        INNER JOIN mergestat.repo_sync_types rst ON rs.sync_type = rst.type
+        WHERE rs.schedule_enabled
+            AND rs.id NOT IN (SELECT repo_sync_id FROM mergestat.repo_sync_queue WHERE status IN ('QUEUED', 'RUNNING'))
+        RETURNING 1
+    )
+    SELECT COUNT(*) INTO _enqueued FROM enqueued;
+
+    RETURN _enqueued;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.enqueue_repo_group_syncs() IS 'enqueues the syncs of the repo groups whose sync interval has elapsed';
+
+COMMIT;
@@ -0,0 +1,130 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority, type_group)
+VALUES ('GITHUB_REPO_TEAMS', 'Retrieves the GitHub teams with access to a repo, and their members', 'GitHub Repo Teams', 2, 'GITHUB')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.github_repo_teams (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    team_id BIGINT NOT NULL,
+    org TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    name TEXT,
+    permission TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, team_id)
+);
+
+COMMENT ON TABLE public.github_repo_teams IS 'GitHub teams with access to a repo';
+COMMENT ON COLUMN public.github_repo_teams.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_repo_teams.team_id IS 'GitHub id of the team';
+COMMENT ON COLUMN public.github_repo_teams.org IS 'login of the organization the team belongs to';
+COMMENT ON COLUMN public.github_repo_teams.slug IS 'slug of the team, as used in CODEOWNERS (@org/slug)';
+COMMENT ON COLUMN public.github_repo_teams.permission IS 'permission of the team on the repo (pull, triage, push, maintain or admin)';
+COMMENT ON COLUMN public.github_repo_teams._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_repo_team_members (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    team_id BIGINT NOT NULL,
+    login TEXT NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, team_id, login)
+);
+
+COMMENT ON TABLE public.github_repo_team_members IS 'members of the GitHub teams with access to a repo';
+COMMENT ON COLUMN public.github_repo_team_members.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_repo_team_members.team_id IS 'GitHub id of the team';
+COMMENT ON COLUMN public.github_repo_team_members.login IS 'login of the member';
+COMMENT ON COLUMN public.github_repo_team_members._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- teams, as defined by users (optionally linked to a GitHub team)
+CREATE TABLE IF NOT EXISTS mergestat.teams (
+    id UUID PRIMARY KEY DEFAULT public.gen_random_uuid() NOT NULL,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    github_org TEXT,
+    github_slug TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE mergestat.teams IS 'teams repos (and paths within repos) are owned by';
+COMMENT ON COLUMN mergestat.teams.github_org IS 'if set (with github_slug), the GitHub team the team is enriched from';
+COMMENT ON COLUMN mergestat.teams.github_slug IS 'slug of the GitHub team the team is enriched from';
+
+CREATE TABLE IF NOT EXISTS mergestat.team_repos (
+    team_id UUID NOT NULL REFERENCES mergestat.teams(id) ON DELETE CASCADE,
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, repo_id)
+);
+
+COMMENT ON TABLE mergestat.team_repos IS 'repos explicitly owned by a team';
+
+CREATE TABLE IF NOT EXISTS mergestat.team_path_patterns (
+    team_id UUID NOT NULL REFERENCES mergestat.teams(id) ON DELETE CASCADE,
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    pattern TEXT NOT NULL,
+    PRIMARY KEY (team_id, repo_id, pattern)
+);
+
+COMMENT ON TABLE mergestat.team_path_patterns IS 'paths (CODEOWNERS style patterns) within repos owned by a team';
+
+-- codeowners_rules parses the CODEOWNERS files synced by GIT_FILES into one row per (rule, owner)
+CREATE OR REPLACE VIEW public.codeowners_rules AS (
+    WITH files AS (
+        SELECT repo_id, path AS codeowners_path, regexp_split_to_array(contents, '\n') AS lines
+        FROM public.git_files
+        WHERE path IN ('CODEOWNERS', '.github/CODEOWNERS', 'docs/CODEOWNERS')
+    ), numbered AS (
+        SELECT repo_id, codeowners_path, lines, generate_subscripts(lines, 1) AS line_no
+        FROM files
+    ), rules AS (
+        SELECT repo_id, codeowners_path, line_no, regexp_split_to_array(trim(regexp_replace(lines[line_no], '#.*$', '')), '\s+') AS fields
+        FROM numbered
+    )
+    SELECT repo_id, codeowners_path, line_no, fields[1] AS pattern, unnest(fields[2:]) AS owner
+    FROM rules
+    WHERE fields[1] <> ''
+);
+
+COMMENT ON VIEW public.codeowners_rules IS 'rules of the CODEOWNERS files of repos (requires GIT_FILES), one row per owner of a rule';
+COMMENT ON COLUMN public.codeowners_rules.codeowners_path IS 'path of the CODEOWNERS file';
+COMMENT ON COLUMN public.codeowners_rules.line_no IS 'line of the rule in the CODEOWNERS file (later rules take precedence)';
+COMMENT ON COLUMN public.codeowners_rules.pattern IS 'path pattern of the rule';
+COMMENT ON COLUMN public.codeowners_rules.owner IS 'owner of the paths matching the pattern: @user, @org/team or an email';
+
+-- team_ownership resolves the repos (and path patterns) each team owns, from explicit mappings, GitHub teams
+-- (the teams with admin or maintain permission on a repo) and CODEOWNERS rules naming the team's GitHub team
+CREATE OR REPLACE VIEW mergestat.team_ownership AS (
+    SELECT t.id AS team_id, tr.repo_id, NULL::TEXT AS pattern, 'EXPLICIT' AS source
+    FROM mergestat.teams t
+    INNER JOIN mergestat.team_repos tr ON tr.team_id = t.id
+    UNION
+    SELECT t.id AS team_id, tp.repo_id, tp.pattern, 'EXPLICIT' AS source
+    FROM mergestat.teams t
+    INNER JOIN mergestat.team_path_patterns tp ON tp.team_id = t.id
+    UNION
+    SELECT t.id AS team_id, gt.repo_id, NULL::TEXT AS pattern, 'GITHUB_TEAM' AS source
+    FROM mergestat.teams t
+    INNER JOIN public.github_repo_teams gt ON gt.org = t.github_org AND gt.slug = t.github_slug
+    WHERE gt.permission IN ('admin', 'maintain')
+    UNION
+    SELECT t.id AS team_id, c.repo_id, c.pattern, 'CODEOWNERS' AS source
+    FROM mergestat.teams t
+    INNER JOIN public.codeowners_rules c ON lower(c.owner) = lower('@' || t.github_org || '/' || t.github_slug)
+);
+
+COMMENT ON VIEW mergestat.team_ownership IS 'repos (and path patterns within them, NULL for the whole repo) owned by each team, and the source of the mapping';
+
+--https://www.graphile.org/postgraphile/computed-columns/
+CREATE OR REPLACE FUNCTION public.repos_teams(repos REPOS)
+RETURNS SETOF mergestat.teams
+LANGUAGE SQL STABLE
+AS $$
+    SELECT DISTINCT t.* FROM mergestat.teams t
+    INNER JOIN mergestat.team_ownership o ON o.team_id = t.id
+    WHERE o.repo_id = repos.id;
+$$;
+
+COMMENT ON FUNCTION public.repos_teams(REPOS) IS 'teams owning (part of) the repo';
+
+COMMIT;
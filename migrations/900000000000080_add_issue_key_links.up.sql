@@ -0,0 +1,42 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('ISSUE_KEY_LINKS', 'Extracts issue keys (eg. Jira ABC-123) from commit messages and pull requests, requires GIT_COMMITS and/or GITHUB_REPO_PRS', 'Issue Key Links', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.issue_key_links (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    source TEXT NOT NULL CHECK (source IN ('COMMIT', 'PULL_REQUEST')),
+    commit_hash TEXT,
+    pull_request_number INTEGER,
+    issue_key TEXT NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_issue_key_links_repo_id ON public.issue_key_links (repo_id);
+CREATE INDEX IF NOT EXISTS idx_issue_key_links_issue_key ON public.issue_key_links (issue_key);
+
+COMMENT ON TABLE public.issue_key_links IS 'issue keys referenced by the commits and pull requests of a repo';
+COMMENT ON COLUMN public.issue_key_links.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.issue_key_links.source IS 'where the key was found: COMMIT (message) or PULL_REQUEST (title or body)';
+COMMENT ON COLUMN public.issue_key_links.commit_hash IS 'hash of the commit referencing the issue (for COMMIT links)';
+COMMENT ON COLUMN public.issue_key_links.pull_request_number IS 'number of the pull request referencing the issue (for PULL_REQUEST links)';
+COMMENT ON COLUMN public.issue_key_links.issue_key IS 'the issue key, eg. ABC-123';
+COMMENT ON COLUMN public.issue_key_links._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.jira_issues (
+    issue_key TEXT PRIMARY KEY,
+    summary TEXT,
+    status TEXT,
+    issue_type TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL
+);
+
+COMMENT ON TABLE public.jira_issues IS 'Jira issues referenced in public.issue_key_links (when the JIRA_URL of the Jira instance is configured on the worker)';
+COMMENT ON COLUMN public.jira_issues.issue_key IS 'key of the issue, eg. ABC-123';
+COMMENT ON COLUMN public.jira_issues.summary IS 'summary (title) of the issue';
+COMMENT ON COLUMN public.jira_issues.status IS 'name of the status of the issue';
+COMMENT ON COLUMN public.jira_issues.issue_type IS 'name of the type of the issue';
+COMMENT ON COLUMN public.jira_issues._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
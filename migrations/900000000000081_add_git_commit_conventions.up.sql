@@ -0,0 +1,31 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GIT_COMMIT_CONVENTIONS', 'Parses commit messages following the Conventional Commits specification, requires GIT_COMMITS', 'Git Commit Conventions', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_commit_conventions (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    commit_hash TEXT NOT NULL,
+    type TEXT NOT NULL,
+    scope TEXT,
+    breaking BOOLEAN NOT NULL,
+    description TEXT NOT NULL,
+    ticket_refs TEXT[] NOT NULL DEFAULT '{}',
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, commit_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_commit_conventions_type ON public.git_commit_conventions (repo_id, type);
+
+COMMENT ON TABLE public.git_commit_conventions IS 'conventional commit structure of the commit messages of a repo (commits not following the convention are omitted)';
+COMMENT ON COLUMN public.git_commit_conventions.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_commit_conventions.commit_hash IS 'hash of the commit';
+COMMENT ON COLUMN public.git_commit_conventions.type IS 'type of the change, lower cased, eg. feat or fix';
+COMMENT ON COLUMN public.git_commit_conventions.scope IS 'scope of the change, if any, eg. parser';
+COMMENT ON COLUMN public.git_commit_conventions.breaking IS 'true if the commit is marked as a breaking change (! or a BREAKING CHANGE footer)';
+COMMENT ON COLUMN public.git_commit_conventions.description IS 'description of the change, from the first line of the message';
+COMMENT ON COLUMN public.git_commit_conventions.ticket_refs IS 'ticket references found in the message, eg. #123 or ABC-123';
+COMMENT ON COLUMN public.git_commit_conventions._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
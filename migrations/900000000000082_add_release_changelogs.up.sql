@@ -0,0 +1,124 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('RELEASE_CHANGELOGS', 'Derives per-release changelogs and lead times from tags, commits and merged pull requests, runs after GIT_REFS and pull request syncs', 'Release Changelogs', 4)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_releases (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    commit_hash TEXT NOT NULL,
+    released_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    previous_tag TEXT,
+    commit_count INTEGER NOT NULL,
+    pull_request_count INTEGER NOT NULL,
+    feature_count INTEGER NOT NULL,
+    fix_count INTEGER NOT NULL,
+    breaking_count INTEGER NOT NULL,
+    median_lead_time INTERVAL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, tag)
+);
+
+COMMENT ON TABLE public.git_releases IS 'releases (tags) of a repo, with a summary of their changes';
+COMMENT ON COLUMN public.git_releases.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_releases.tag IS 'name of the tag of the release';
+COMMENT ON COLUMN public.git_releases.commit_hash IS 'hash of the commit the tag points to';
+COMMENT ON COLUMN public.git_releases.released_at IS 'commit time of the tagged commit';
+COMMENT ON COLUMN public.git_releases.previous_tag IS 'tag of the previous release';
+COMMENT ON COLUMN public.git_releases.commit_count IS 'number of commits since the previous release';
+COMMENT ON COLUMN public.git_releases.pull_request_count IS 'number of pull requests merged since the previous release';
+COMMENT ON COLUMN public.git_releases.feature_count IS 'number of feat changes (conventional commits and pull request titles)';
+COMMENT ON COLUMN public.git_releases.fix_count IS 'number of fix changes (conventional commits and pull request titles)';
+COMMENT ON COLUMN public.git_releases.breaking_count IS 'number of breaking changes';
+COMMENT ON COLUMN public.git_releases.median_lead_time IS 'median time from a change being authored (or its pull request opened) to its release';
+
+CREATE TABLE IF NOT EXISTS public.git_release_changes (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    source TEXT NOT NULL CHECK (source IN ('COMMIT', 'PULL_REQUEST')),
+    commit_hash TEXT,
+    pull_request_number INTEGER,
+    type TEXT,
+    scope TEXT,
+    breaking BOOLEAN NOT NULL DEFAULT FALSE,
+    description TEXT,
+    lead_time INTERVAL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_release_changes_repo_id_tag ON public.git_release_changes (repo_id, tag);
+
+COMMENT ON TABLE public.git_release_changes IS 'changelog of each release of a repo: the commits and merged pull requests since the previous release';
+COMMENT ON COLUMN public.git_release_changes.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_release_changes.tag IS 'tag of the release the change shipped in';
+COMMENT ON COLUMN public.git_release_changes.source IS 'COMMIT or PULL_REQUEST';
+COMMENT ON COLUMN public.git_release_changes.type IS 'conventional commit type of the change (from GIT_COMMIT_CONVENTIONS, or the pull request title)';
+COMMENT ON COLUMN public.git_release_changes.description IS 'commit subject or pull request title';
+COMMENT ON COLUMN public.git_release_changes.lead_time IS 'time from the change being authored (or its pull request opened) to its release';
+
+-- derive_release_changelogs (re-)materializes git_releases and git_release_changes for a repo. A change belongs to the
+-- first release tagged after it was committed (or merged). Tables are left unqualified so that the function writes
+-- into the schema the calling session resolves them to (see dry runs).
+CREATE OR REPLACE FUNCTION mergestat.derive_release_changelogs(_repo_id UUID)
+RETURNS INTEGER
+AS
+$$
+DECLARE _count INTEGER;
+BEGIN
+    DELETE FROM git_release_changes WHERE repo_id = _repo_id;
+    DELETE FROM git_releases WHERE repo_id = _repo_id;
+
+    CREATE TEMPORARY TABLE _releases ON COMMIT DROP AS
+    WITH tags AS (
+        SELECT DISTINCT ON (r.name) r.name AS tag, c.hash AS commit_hash, c.committer_when AS released_at
+        FROM git_refs r
+        INNER JOIN git_commits c ON c.repo_id = r.repo_id AND c.hash = COALESCE(r.tag_commit_hash, r.hash)
+        WHERE r.repo_id = _repo_id AND r.type = 'tag'
+    )
+    SELECT tag, commit_hash, released_at,
+        LAG(tag) OVER (ORDER BY released_at, tag) AS previous_tag,
+        LAG(released_at) OVER (ORDER BY released_at, tag) AS previous_released_at
+    FROM tags;
+
+    INSERT INTO git_release_changes (repo_id, tag, source, commit_hash, type, scope, breaking, description, lead_time)
+    SELECT _repo_id, r.tag, 'COMMIT', c.hash, cc.type, cc.scope, COALESCE(cc.breaking, FALSE),
+        split_part(c.message, E'\n', 1), r.released_at - c.author_when
+    FROM _releases r
+    INNER JOIN git_commits c ON c.repo_id = _repo_id
+        AND c.committer_when <= r.released_at AND (r.previous_released_at IS NULL OR c.committer_when > r.previous_released_at)
+    LEFT JOIN git_commit_conventions cc ON cc.repo_id = c.repo_id AND cc.commit_hash = c.hash;
+
+    INSERT INTO git_release_changes (repo_id, tag, source, pull_request_number, type, scope, breaking, description, lead_time)
+    SELECT _repo_id, r.tag, 'PULL_REQUEST', pr.number,
+        lower(substring(pr.title FROM '^([a-zA-Z]+)(?:\([^)]*\))?!?: ')),
+        substring(pr.title FROM '^[a-zA-Z]+\(([^)]*)\)!?: '),
+        pr.title ~ '^[a-zA-Z]+(\([^)]*\))?!: ',
+        pr.title, r.released_at - pr.created_at
+    FROM _releases r
+    INNER JOIN github_pull_requests pr ON pr.repo_id = _repo_id AND pr.merged
+        AND pr.merged_at <= r.released_at AND (r.previous_released_at IS NULL OR pr.merged_at > r.previous_released_at);
+
+    INSERT INTO git_releases (repo_id, tag, commit_hash, released_at, previous_tag, commit_count, pull_request_count,
+        feature_count, fix_count, breaking_count, median_lead_time)
+    SELECT _repo_id, r.tag, r.commit_hash, r.released_at, r.previous_tag,
+        COUNT(ch.*) FILTER (WHERE ch.source = 'COMMIT'),
+        COUNT(ch.*) FILTER (WHERE ch.source = 'PULL_REQUEST'),
+        COUNT(ch.*) FILTER (WHERE ch.type = 'feat'),
+        COUNT(ch.*) FILTER (WHERE ch.type = 'fix'),
+        COUNT(ch.*) FILTER (WHERE ch.breaking),
+        percentile_cont(0.5) WITHIN GROUP (ORDER BY ch.lead_time)
+    FROM _releases r
+    LEFT JOIN git_release_changes ch ON ch.repo_id = _repo_id AND ch.tag = r.tag
+    GROUP BY r.tag, r.commit_hash, r.released_at, r.previous_tag;
+
+    GET DIAGNOSTICS _count = ROW_COUNT;
+    DROP TABLE _releases;
+
+    RETURN _count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.derive_release_changelogs(UUID) IS 'materializes the releases and changelogs of a repo into git_releases and git_release_changes';
+
+COMMIT;
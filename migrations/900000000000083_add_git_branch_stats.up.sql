@@ -0,0 +1,40 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS public.git_branch_stats (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    first_commit_at TIMESTAMP WITH TIME ZONE,
+    last_commit_at TIMESTAMP WITH TIME ZONE,
+    last_commit_author_email TEXT,
+    ahead INTEGER NOT NULL,
+    behind INTEGER NOT NULL,
+    merged BOOLEAN NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_branch_stats_last_commit_at ON public.git_branch_stats (repo_id, last_commit_at);
+
+COMMENT ON TABLE public.git_branch_stats IS 'age, activity and divergence from the default branch of the branches of a repo, refreshed on each GIT_REFS sync';
+COMMENT ON COLUMN public.git_branch_stats.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_branch_stats.name IS 'name of the branch, without the remote prefix';
+COMMENT ON COLUMN public.git_branch_stats.hash IS 'hash of the commit the branch points to';
+COMMENT ON COLUMN public.git_branch_stats.is_default IS 'true if this is the default branch of the repo';
+COMMENT ON COLUMN public.git_branch_stats.first_commit_at IS 'author date of the oldest commit of the branch not in the default branch, null if there is none';
+COMMENT ON COLUMN public.git_branch_stats.last_commit_at IS 'committer date of the commit the branch points to';
+COMMENT ON COLUMN public.git_branch_stats.last_commit_author_email IS 'author email of the commit the branch points to';
+COMMENT ON COLUMN public.git_branch_stats.ahead IS 'number of commits of the branch not in the default branch';
+COMMENT ON COLUMN public.git_branch_stats.behind IS 'number of commits of the default branch not in the branch';
+COMMENT ON COLUMN public.git_branch_stats.merged IS 'true if every commit of the branch is in the default branch';
+COMMENT ON COLUMN public.git_branch_stats._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE OR REPLACE VIEW public.stale_branches AS
+    SELECT s.*, date_part('day', now() - s.last_commit_at)::INTEGER AS days_inactive
+        FROM public.git_branch_stats s
+    WHERE NOT s.is_default AND s.last_commit_at < now() - INTERVAL '90 days';
+
+COMMENT ON VIEW public.stale_branches IS 'branches (other than the default one) without a new commit in the last 90 days';
+
+COMMIT;
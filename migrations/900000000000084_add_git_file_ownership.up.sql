@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS public.git_file_ownership (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    author_email TEXT NOT NULL,
+    author_name TEXT,
+    lines INTEGER NOT NULL,
+    total_lines INTEGER NOT NULL,
+    ownership DOUBLE PRECISION NOT NULL,
+    last_touched_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path, author_email)
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_file_ownership_author_email ON public.git_file_ownership (repo_id, author_email);
+
+COMMENT ON TABLE public.git_file_ownership IS 'per file, per author rollup of git_blame, maintained by GIT_BLAME syncs';
+COMMENT ON COLUMN public.git_file_ownership.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_file_ownership.path IS 'path of the file';
+COMMENT ON COLUMN public.git_file_ownership.author_email IS 'email of the author the lines are attributed to';
+COMMENT ON COLUMN public.git_file_ownership.author_name IS 'name of the author the lines are attributed to';
+COMMENT ON COLUMN public.git_file_ownership.lines IS 'number of lines of the file attributed to the author';
+COMMENT ON COLUMN public.git_file_ownership.total_lines IS 'number of lines of the file';
+COMMENT ON COLUMN public.git_file_ownership.ownership IS 'percentage (0 to 100) of the lines of the file attributed to the author';
+COMMENT ON COLUMN public.git_file_ownership.last_touched_at IS 'author date of the most recent commit of the author still present in the file';
+COMMENT ON COLUMN public.git_file_ownership._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
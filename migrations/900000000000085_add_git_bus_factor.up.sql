@@ -0,0 +1,42 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GIT_BUS_FACTOR', 'Computes bus factor metrics per repo and directory, requires GIT_BLAME and GIT_COMMIT_STATS', 'Git Bus Factor', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_bus_factor (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    directory TEXT NOT NULL,
+    metric TEXT NOT NULL,
+    coverage DOUBLE PRECISION NOT NULL,
+    bus_factor INTEGER NOT NULL,
+    authors TEXT[] NOT NULL,
+    total_authors INTEGER NOT NULL,
+    total BIGINT NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, computed_at, directory, metric)
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_bus_factor_directory ON public.git_bus_factor (repo_id, directory, metric, computed_at);
+
+COMMENT ON TABLE public.git_bus_factor IS 'bus factor of a repo and its directories, one set of rows per GIT_BUS_FACTOR sync (kept for trending)';
+COMMENT ON COLUMN public.git_bus_factor.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_bus_factor.computed_at IS 'time the metrics were computed, shared by all the rows of a sync';
+COMMENT ON COLUMN public.git_bus_factor.directory IS 'directory the metric covers, empty for the whole repo';
+COMMENT ON COLUMN public.git_bus_factor.metric IS 'what contributions are counted, lines (from git_file_ownership) or commits (from git_commit_stats)';
+COMMENT ON COLUMN public.git_bus_factor.coverage IS 'percentage of the contributions the authors must cover';
+COMMENT ON COLUMN public.git_bus_factor.bus_factor IS 'smallest number of authors covering the given percentage of the contributions';
+COMMENT ON COLUMN public.git_bus_factor.authors IS 'emails of the authors counted in the bus factor, largest contributor first';
+COMMENT ON COLUMN public.git_bus_factor.total_authors IS 'number of authors with contributions to the directory';
+COMMENT ON COLUMN public.git_bus_factor.total IS 'number of contributions (lines or commits) to the directory';
+COMMENT ON COLUMN public.git_bus_factor._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE OR REPLACE VIEW public.git_bus_factor_latest AS
+    SELECT DISTINCT ON (repo_id, directory, metric) *
+        FROM public.git_bus_factor
+    ORDER BY repo_id, directory, metric, computed_at DESC;
+
+COMMENT ON VIEW public.git_bus_factor_latest IS 'most recently computed bus factor of each repo and directory';
+
+COMMIT;
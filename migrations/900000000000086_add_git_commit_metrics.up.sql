@@ -0,0 +1,37 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GIT_COMMIT_METRICS', 'Records message quality and size metrics of commits, requires GIT_COMMITS and GIT_COMMIT_STATS', 'Git Commit Metrics', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_commit_metrics (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    commit_hash TEXT NOT NULL,
+    message_length INTEGER NOT NULL,
+    subject_length INTEGER NOT NULL,
+    subject_conforms BOOLEAN NOT NULL,
+    has_body BOOLEAN NOT NULL,
+    files_changed INTEGER NOT NULL,
+    additions INTEGER NOT NULL,
+    deletions INTEGER NOT NULL,
+    size_bucket TEXT NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, commit_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_commit_metrics_size_bucket ON public.git_commit_metrics (repo_id, size_bucket);
+
+COMMENT ON TABLE public.git_commit_metrics IS 'message quality and size metrics of the commits of a repo';
+COMMENT ON COLUMN public.git_commit_metrics.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_commit_metrics.commit_hash IS 'hash of the commit';
+COMMENT ON COLUMN public.git_commit_metrics.message_length IS 'number of characters of the (trimmed) commit message';
+COMMENT ON COLUMN public.git_commit_metrics.subject_length IS 'number of characters of the first line of the commit message';
+COMMENT ON COLUMN public.git_commit_metrics.subject_conforms IS 'true if the subject is non-empty, at most 72 characters, and does not end with a period';
+COMMENT ON COLUMN public.git_commit_metrics.has_body IS 'true if the message has a body, separated from the subject by a blank line';
+COMMENT ON COLUMN public.git_commit_metrics.files_changed IS 'number of files the commit changes';
+COMMENT ON COLUMN public.git_commit_metrics.additions IS 'number of lines the commit adds';
+COMMENT ON COLUMN public.git_commit_metrics.deletions IS 'number of lines the commit removes';
+COMMENT ON COLUMN public.git_commit_metrics.size_bucket IS 'size of the commit by lines changed: XS (< 10), S (< 50), M (< 250), L (< 1000) or XL';
+COMMENT ON COLUMN public.git_commit_metrics._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
@@ -0,0 +1,33 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GIT_LARGE_FILES', 'Inventory of the binary files, and files above a size threshold, at the HEAD of a repo', 'Git Binary & Large Files', 2)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_large_files (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    blob_hash TEXT NOT NULL,
+    is_binary BOOLEAN NOT NULL,
+    large BOOLEAN NOT NULL,
+    last_commit_hash TEXT,
+    last_commit_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_large_files_size ON public.git_large_files (repo_id, size DESC);
+
+COMMENT ON TABLE public.git_large_files IS 'binary files, and files above a size threshold (see the largeFileThreshold sync setting), at the HEAD of a repo';
+COMMENT ON COLUMN public.git_large_files.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_large_files.path IS 'path of the file';
+COMMENT ON COLUMN public.git_large_files.size IS 'size of the file in bytes';
+COMMENT ON COLUMN public.git_large_files.blob_hash IS 'hash of the blob of the file';
+COMMENT ON COLUMN public.git_large_files.is_binary IS 'true if the file is detected as binary';
+COMMENT ON COLUMN public.git_large_files.large IS 'true if the file is above the size threshold';
+COMMENT ON COLUMN public.git_large_files.last_commit_hash IS 'hash of the most recent commit modifying the file';
+COMMENT ON COLUMN public.git_large_files.last_commit_at IS 'committer date of the most recent commit modifying the file';
+COMMENT ON COLUMN public.git_large_files._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
@@ -0,0 +1,82 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('REPO_POLICIES', 'Checks a repo against the rules in mergestat.repo_policy_rules, requires GIT_FILES (and GIT_REFS for branch naming rules)', 'Repo Policies', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS mergestat.repo_policy_rules (
+    name TEXT PRIMARY KEY,
+    description TEXT,
+    kind TEXT NOT NULL CHECK (kind IN ('FILE_EXISTS', 'FILE_ABSENT', 'BRANCH_NAMES')),
+    pattern TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE mergestat.repo_policy_rules IS 'rules REPO_POLICIES syncs check repos against';
+COMMENT ON COLUMN mergestat.repo_policy_rules.name IS 'name of the rule, eg. has-license';
+COMMENT ON COLUMN mergestat.repo_policy_rules.description IS 'description of the rule';
+COMMENT ON COLUMN mergestat.repo_policy_rules.kind IS 'FILE_EXISTS (a file matching pattern exists), FILE_ABSENT (no file matches pattern) or BRANCH_NAMES (every branch name matches pattern)';
+COMMENT ON COLUMN mergestat.repo_policy_rules.pattern IS 'regular expression matched against file paths or branch names';
+COMMENT ON COLUMN mergestat.repo_policy_rules.enabled IS 'only enabled rules are checked';
+
+INSERT INTO mergestat.repo_policy_rules (name, description, kind, pattern, enabled) VALUES
+    ('has-license', 'repo has a LICENSE file', 'FILE_EXISTS', '^(LICENSE|LICENCE|COPYING)(\.[a-zA-Z]+)?$', TRUE),
+    ('has-codeowners', 'repo has a CODEOWNERS file', 'FILE_EXISTS', '^(\.github/|docs/)?CODEOWNERS$', TRUE),
+    ('has-workflows', 'repo has GitHub Actions workflows', 'FILE_EXISTS', '^\.github/workflows/[^/]+\.ya?ml$', TRUE),
+    ('has-gitignore', 'repo has a .gitignore file', 'FILE_EXISTS', '^\.gitignore$', TRUE),
+    ('has-gitattributes', 'repo has a .gitattributes file', 'FILE_EXISTS', '^\.gitattributes$', FALSE),
+    ('has-editorconfig', 'repo has an .editorconfig file', 'FILE_EXISTS', '^\.editorconfig$', FALSE),
+    ('branch-naming', 'branch names follow the naming convention', 'BRANCH_NAMES', '^(main|master|develop|(feature|fix|hotfix|release|chore|dependabot|renovate)/.+)$', FALSE)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.repo_policy_results (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    rule TEXT NOT NULL,
+    passed BOOLEAN NOT NULL,
+    details TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, rule)
+);
+
+COMMENT ON TABLE public.repo_policy_results IS 'result of checking a repo against each enabled rule in mergestat.repo_policy_rules';
+COMMENT ON COLUMN public.repo_policy_results.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.repo_policy_results.rule IS 'name of the rule, see mergestat.repo_policy_rules';
+COMMENT ON COLUMN public.repo_policy_results.passed IS 'true if the repo complies with the rule';
+COMMENT ON COLUMN public.repo_policy_results.details IS 'what failed the rule, eg. the offending file paths or branch names';
+COMMENT ON COLUMN public.repo_policy_results._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE OR REPLACE FUNCTION mergestat.evaluate_repo_policies(_repo_id UUID)
+RETURNS INTEGER
+AS
+$$
+DECLARE _count INTEGER;
+BEGIN
+    DELETE FROM repo_policy_results WHERE repo_id = _repo_id;
+
+    INSERT INTO repo_policy_results (repo_id, rule, passed, details)
+    SELECT _repo_id, r.name, check_result.passed, check_result.details
+    FROM mergestat.repo_policy_rules r,
+    LATERAL (
+        SELECT COUNT(*) > 0 AS passed, NULL::TEXT AS details
+            FROM git_files f WHERE r.kind = 'FILE_EXISTS' AND f.repo_id = _repo_id AND f.path ~ r.pattern
+        HAVING r.kind = 'FILE_EXISTS'
+        UNION ALL
+        SELECT COUNT(*) = 0, string_agg(f.path, ', ' ORDER BY f.path)
+            FROM git_files f WHERE r.kind = 'FILE_ABSENT' AND f.repo_id = _repo_id AND f.path ~ r.pattern
+        HAVING r.kind = 'FILE_ABSENT'
+        UNION ALL
+        SELECT COUNT(*) = 0, string_agg(b.name, ', ' ORDER BY b.name)
+            FROM git_branch_stats b WHERE r.kind = 'BRANCH_NAMES' AND b.repo_id = _repo_id AND b.name !~ r.pattern
+        HAVING r.kind = 'BRANCH_NAMES'
+    ) check_result
+    WHERE r.enabled;
+
+    GET DIAGNOSTICS _count = ROW_COUNT;
+    RETURN _count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.evaluate_repo_policies(UUID) IS 'checks a repo against the enabled rules in mergestat.repo_policy_rules, writing the results into repo_policy_results';
+
+COMMIT;
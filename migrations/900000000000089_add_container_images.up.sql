@@ -0,0 +1,37 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('CONTAINER_IMAGES', 'Inventory of the base images of the Dockerfiles, and the images of the compose files, of a repo, requires GIT_FILES', 'Container Images', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.container_images (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    image TEXT NOT NULL,
+    registry TEXT NOT NULL,
+    repository TEXT NOT NULL,
+    tag TEXT,
+    digest TEXT,
+    stage TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path, line)
+);
+
+CREATE INDEX IF NOT EXISTS idx_container_images_repository ON public.container_images (repository, tag);
+
+COMMENT ON TABLE public.container_images IS 'container images referenced by the Dockerfiles and compose files of a repo';
+COMMENT ON COLUMN public.container_images.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.container_images.path IS 'path of the file referencing the image';
+COMMENT ON COLUMN public.container_images.line IS 'line of the file the image is referenced at';
+COMMENT ON COLUMN public.container_images.source IS 'DOCKERFILE (a FROM instruction) or COMPOSE (a service image)';
+COMMENT ON COLUMN public.container_images.image IS 'image reference, as written in the file (with build args substituted)';
+COMMENT ON COLUMN public.container_images.registry IS 'registry of the image, docker.io if not specified';
+COMMENT ON COLUMN public.container_images.repository IS 'repository of the image in the registry, eg. library/golang';
+COMMENT ON COLUMN public.container_images.tag IS 'tag of the image, if any';
+COMMENT ON COLUMN public.container_images.digest IS 'digest the image is pinned to, if any';
+COMMENT ON COLUMN public.container_images.stage IS 'name of the build stage (FROM ... AS stage) of Dockerfile images, if any';
+COMMENT ON COLUMN public.container_images._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
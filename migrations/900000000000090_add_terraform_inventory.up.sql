@@ -0,0 +1,51 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('TERRAFORM_INVENTORY', 'Inventory of the Terraform module sources and provider version constraints of a repo, requires GIT_FILES', 'Terraform Inventory', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.terraform_modules (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    source TEXT,
+    version TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path, line)
+);
+
+CREATE INDEX IF NOT EXISTS idx_terraform_modules_source ON public.terraform_modules (source);
+
+COMMENT ON TABLE public.terraform_modules IS 'module calls of the Terraform files of a repo';
+COMMENT ON COLUMN public.terraform_modules.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.terraform_modules.path IS 'path of the .tf file';
+COMMENT ON COLUMN public.terraform_modules.line IS 'line of the file the module block starts at';
+COMMENT ON COLUMN public.terraform_modules.name IS 'name of the module call';
+COMMENT ON COLUMN public.terraform_modules.source IS 'source of the module, eg. terraform-aws-modules/vpc/aws or ./modules/network';
+COMMENT ON COLUMN public.terraform_modules.version IS 'version constraint of the module, if any';
+COMMENT ON COLUMN public.terraform_modules._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.terraform_providers (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    source TEXT,
+    version TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path, line)
+);
+
+CREATE INDEX IF NOT EXISTS idx_terraform_providers_source ON public.terraform_providers (source);
+
+COMMENT ON TABLE public.terraform_providers IS 'provider requirements of the Terraform files of a repo';
+COMMENT ON COLUMN public.terraform_providers.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.terraform_providers.path IS 'path of the .tf file';
+COMMENT ON COLUMN public.terraform_providers.line IS 'line of the file the requirement is declared at';
+COMMENT ON COLUMN public.terraform_providers.name IS 'local name of the provider, eg. aws';
+COMMENT ON COLUMN public.terraform_providers.source IS 'source address of the provider, eg. hashicorp/aws';
+COMMENT ON COLUMN public.terraform_providers.version IS 'version constraint of the provider, if any';
+COMMENT ON COLUMN public.terraform_providers._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
@@ -0,0 +1,69 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('CI_INVENTORY', 'Inventory of the CI configurations of a repo (GitHub Actions workflows, GitLab CI and Jenkinsfiles), requires GIT_FILES', 'CI Inventory', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.ci_configs (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    name TEXT,
+    triggers TEXT[] NOT NULL DEFAULT '{}',
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path)
+);
+
+COMMENT ON TABLE public.ci_configs IS 'CI configuration files of a repo';
+COMMENT ON COLUMN public.ci_configs.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.ci_configs.path IS 'path of the configuration file';
+COMMENT ON COLUMN public.ci_configs.provider IS 'CI provider the file configures, GITHUB_ACTIONS, GITLAB_CI or JENKINS';
+COMMENT ON COLUMN public.ci_configs.name IS 'name of the workflow, if any';
+COMMENT ON COLUMN public.ci_configs.triggers IS 'events triggering the workflow, eg. push or pull_request';
+COMMENT ON COLUMN public.ci_configs._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.ci_jobs (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    job TEXT NOT NULL,
+    runs_on TEXT[] NOT NULL DEFAULT '{}',
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path, job)
+);
+
+COMMENT ON TABLE public.ci_jobs IS 'jobs of the CI configurations of a repo';
+COMMENT ON COLUMN public.ci_jobs.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.ci_jobs.path IS 'path of the configuration file';
+COMMENT ON COLUMN public.ci_jobs.job IS 'id of the job';
+COMMENT ON COLUMN public.ci_jobs.runs_on IS 'labels of the runners the job runs on (runs-on in GitHub Actions, tags in GitLab CI)';
+COMMENT ON COLUMN public.ci_jobs._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.ci_actions (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    job TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    uses TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    action TEXT NOT NULL,
+    ref TEXT,
+    pinned BOOLEAN NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path, job, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ci_actions_action ON public.ci_actions (action, ref);
+
+COMMENT ON TABLE public.ci_actions IS 'actions (and reusable workflows) used by the GitHub Actions workflows of a repo';
+COMMENT ON COLUMN public.ci_actions.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.ci_actions.path IS 'path of the workflow';
+COMMENT ON COLUMN public.ci_actions.job IS 'id of the job using the action';
+COMMENT ON COLUMN public.ci_actions.position IS 'position of the action among the ones the job uses';
+COMMENT ON COLUMN public.ci_actions.uses IS 'reference to the action, as written in the workflow';
+COMMENT ON COLUMN public.ci_actions.kind IS 'REMOTE (an action of another repository), LOCAL (a path of the repository) or DOCKER (a docker:// image)';
+COMMENT ON COLUMN public.ci_actions.action IS 'action without its ref, eg. actions/checkout';
+COMMENT ON COLUMN public.ci_actions.ref IS 'git ref of remote actions (a tag, branch or commit sha)';
+COMMENT ON COLUMN public.ci_actions.pinned IS 'true if the action is pinned to a full commit sha (or image digest)';
+COMMENT ON COLUMN public.ci_actions._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
@@ -0,0 +1,60 @@
+BEGIN;
+
+ALTER TABLE mergestat.repo_policy_rules ADD COLUMN IF NOT EXISTS severity TEXT NOT NULL DEFAULT 'MEDIUM'
+    CHECK (severity IN ('LOW', 'MEDIUM', 'HIGH', 'CRITICAL'));
+COMMENT ON COLUMN mergestat.repo_policy_rules.severity IS 'severity of a failure of the rule, LOW, MEDIUM, HIGH or CRITICAL';
+
+ALTER TABLE mergestat.repo_policy_rules DROP CONSTRAINT IF EXISTS repo_policy_rules_kind_check;
+ALTER TABLE mergestat.repo_policy_rules ADD CONSTRAINT repo_policy_rules_kind_check
+    CHECK (kind IN ('FILE_EXISTS', 'FILE_ABSENT', 'BRANCH_NAMES', 'ACTIONS_PINNED'));
+COMMENT ON COLUMN mergestat.repo_policy_rules.kind IS 'FILE_EXISTS (a file matching pattern exists), FILE_ABSENT (no file matches pattern), BRANCH_NAMES (every branch name matches pattern) or ACTIONS_PINNED (every workflow action matching pattern is pinned to a commit sha, requires CI_INVENTORY)';
+
+ALTER TABLE public.repo_policy_results ADD COLUMN IF NOT EXISTS severity TEXT;
+COMMENT ON COLUMN public.repo_policy_results.severity IS 'severity of the rule, see mergestat.repo_policy_rules.severity';
+
+INSERT INTO mergestat.repo_policy_rules (name, description, kind, pattern, severity, enabled) VALUES
+    ('third-party-actions-pinned', 'third-party GitHub Actions are pinned to a commit sha, not a mutable tag or branch', 'ACTIONS_PINNED', '^(?!(actions|github)/)', 'HIGH', TRUE),
+    ('first-party-actions-pinned', 'GitHub owned actions (actions/* and github/*) are pinned to a commit sha', 'ACTIONS_PINNED', '^(actions|github)/', 'LOW', TRUE)
+ON CONFLICT DO NOTHING;
+
+UPDATE mergestat.repo_sync_types
+SET description = 'Checks a repo against the rules in mergestat.repo_policy_rules, requires GIT_FILES (GIT_REFS for branch naming rules and CI_INVENTORY for action pinning rules)'
+WHERE type = 'REPO_POLICIES';
+
+CREATE OR REPLACE FUNCTION mergestat.evaluate_repo_policies(_repo_id UUID)
+RETURNS INTEGER
+AS
+$$
+DECLARE _count INTEGER;
+BEGIN
+    DELETE FROM repo_policy_results WHERE repo_id = _repo_id;
+
+    INSERT INTO repo_policy_results (repo_id, rule, passed, details, severity)
+    SELECT _repo_id, r.name, check_result.passed, check_result.details, r.severity
+    FROM mergestat.repo_policy_rules r,
+    LATERAL (
+        SELECT COUNT(*) > 0 AS passed, NULL::TEXT AS details
+            FROM git_files f WHERE r.kind = 'FILE_EXISTS' AND f.repo_id = _repo_id AND f.path ~ r.pattern
+        HAVING r.kind = 'FILE_EXISTS'
+        UNION ALL
+        SELECT COUNT(*) = 0, string_agg(f.path, ', ' ORDER BY f.path)
+            FROM git_files f WHERE r.kind = 'FILE_ABSENT' AND f.repo_id = _repo_id AND f.path ~ r.pattern
+        HAVING r.kind = 'FILE_ABSENT'
+        UNION ALL
+        SELECT COUNT(*) = 0, string_agg(b.name, ', ' ORDER BY b.name)
+            FROM git_branch_stats b WHERE r.kind = 'BRANCH_NAMES' AND b.repo_id = _repo_id AND b.name !~ r.pattern
+        HAVING r.kind = 'BRANCH_NAMES'
+        UNION ALL
+        SELECT COUNT(*) = 0, string_agg(DISTINCT a.path || ': ' || a.uses, ', ')
+            FROM ci_actions a WHERE r.kind = 'ACTIONS_PINNED' AND a.repo_id = _repo_id
+                AND a.kind <> 'LOCAL' AND NOT a.pinned AND a.action ~ r.pattern
+        HAVING r.kind = 'ACTIONS_PINNED'
+    ) check_result
+    WHERE r.enabled;
+
+    GET DIAGNOSTICS _count = ROW_COUNT;
+    RETURN _count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,49 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('PACKAGE_REGISTRIES', 'Fetches the published versions and downloads of the npm, PyPI and Go packages a repo publishes, requires GIT_FILES', 'Package Registries', 4)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.registry_packages (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    registry TEXT NOT NULL,
+    name TEXT NOT NULL,
+    manifest_path TEXT NOT NULL,
+    latest_version TEXT,
+    latest_released_at TIMESTAMP WITH TIME ZONE,
+    version_count INTEGER NOT NULL,
+    downloads_last_month BIGINT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, registry, name)
+);
+
+COMMENT ON TABLE public.registry_packages IS 'packages a repo publishes to npm, PyPI or the Go module proxy, as reported by the registry';
+COMMENT ON COLUMN public.registry_packages.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.registry_packages.registry IS 'registry the package is published to, npm, pypi or go';
+COMMENT ON COLUMN public.registry_packages.name IS 'name of the package (or path of the Go module)';
+COMMENT ON COLUMN public.registry_packages.manifest_path IS 'path of the manifest declaring the package, eg. package.json';
+COMMENT ON COLUMN public.registry_packages.latest_version IS 'version the registry considers the latest';
+COMMENT ON COLUMN public.registry_packages.latest_released_at IS 'time the most recent version was published';
+COMMENT ON COLUMN public.registry_packages.version_count IS 'number of published versions';
+COMMENT ON COLUMN public.registry_packages.downloads_last_month IS 'number of downloads in the last month, null if the registry does not report it';
+COMMENT ON COLUMN public.registry_packages._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.registry_package_versions (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    registry TEXT NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    released_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, registry, name, version)
+);
+
+COMMENT ON TABLE public.registry_package_versions IS 'published versions of the packages in registry_packages';
+COMMENT ON COLUMN public.registry_package_versions.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.registry_package_versions.registry IS 'registry the package is published to, npm, pypi or go';
+COMMENT ON COLUMN public.registry_package_versions.name IS 'name of the package (or path of the Go module)';
+COMMENT ON COLUMN public.registry_package_versions.version IS 'published version';
+COMMENT ON COLUMN public.registry_package_versions.released_at IS 'time the version was published';
+COMMENT ON COLUMN public.registry_package_versions._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
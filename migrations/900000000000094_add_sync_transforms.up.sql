@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS mergestat.sync_transforms (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    sync_type TEXT NOT NULL REFERENCES mergestat.repo_sync_types(type) ON DELETE CASCADE,
+    repo_id UUID REFERENCES public.repos(id) ON DELETE CASCADE,
+    table_name TEXT NOT NULL,
+    module BYTEA,
+    module_url TEXT,
+    position INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK ((module IS NULL) <> (module_url IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_transforms_sync_type ON mergestat.sync_transforms (sync_type);
+
+COMMENT ON TABLE mergestat.sync_transforms IS 'WASM (WASI) modules that transform or filter the rows of a table before a sync writes them';
+COMMENT ON COLUMN mergestat.sync_transforms.name IS 'name of the transform, eg. redact-emails';
+COMMENT ON COLUMN mergestat.sync_transforms.sync_type IS 'type of the syncs the transform applies to';
+COMMENT ON COLUMN mergestat.sync_transforms.repo_id IS 'repo the transform applies to, null for all repos';
+COMMENT ON COLUMN mergestat.sync_transforms.table_name IS 'table whose rows are transformed, eg. git_commits';
+COMMENT ON COLUMN mergestat.sync_transforms.module IS 'the compiled WASM module, unless it is fetched from module_url';
+COMMENT ON COLUMN mergestat.sync_transforms.module_url IS 'url (eg. an object storage url) the WASM module is fetched from, unless it is stored in module';
+COMMENT ON COLUMN mergestat.sync_transforms.position IS 'transforms of the same table are applied in ascending position';
+COMMENT ON COLUMN mergestat.sync_transforms.enabled IS 'only enabled transforms are applied';
+
+COMMIT;
@@ -0,0 +1,89 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS mergestat.privacy_settings (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    author_emails TEXT NOT NULL DEFAULT 'KEEP' CHECK (author_emails IN ('KEEP', 'HASH', 'DROP')),
+    commit_message_bodies TEXT NOT NULL DEFAULT 'KEEP' CHECK (commit_message_bodies IN ('KEEP', 'DROP')),
+    file_contents TEXT NOT NULL DEFAULT 'KEEP' CHECK (file_contents IN ('KEEP', 'DROP')),
+    hash_salt TEXT NOT NULL DEFAULT md5(random()::TEXT || clock_timestamp()::TEXT),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+INSERT INTO mergestat.privacy_settings (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE mergestat.privacy_settings IS 'deployment wide data minimization settings, applied by every sync as rows are written (a single row)';
+COMMENT ON COLUMN mergestat.privacy_settings.author_emails IS 'KEEP, HASH (replace with a salted sha256 hash, prefixed with sha256:) or DROP (replace with an empty string, or with the hash in the columns of keys, whose values must stay distinct) email columns';
+COMMENT ON COLUMN mergestat.privacy_settings.commit_message_bodies IS 'KEEP or DROP (keep only the subject line of) message columns';
+COMMENT ON COLUMN mergestat.privacy_settings.file_contents IS 'KEEP or DROP (replace with null) contents columns, and empty patch columns';
+COMMENT ON COLUMN mergestat.privacy_settings.hash_salt IS 'salt of hashed emails, so that hashes are consistent across tables but not reversible with a dictionary of known emails';
+
+-- mergestat.privacy_hash hashes a value the way the worker does when author_emails is HASH
+CREATE OR REPLACE FUNCTION mergestat.privacy_hash(_value TEXT, _salt TEXT)
+RETURNS TEXT
+AS $$
+    SELECT 'sha256:' || encode(sha256(convert_to(_salt || lower(_value), 'UTF8')), 'hex')
+$$ LANGUAGE SQL IMMUTABLE;
+
+COMMENT ON FUNCTION mergestat.privacy_hash(TEXT, TEXT) IS 'hashes an email the way syncs do when mergestat.privacy_settings.author_emails is HASH';
+
+-- mergestat.is_key_column reports whether the column is part of the primary key (or of a unique index) of the table
+CREATE OR REPLACE FUNCTION mergestat.is_key_column(_table REGCLASS, _column TEXT)
+RETURNS BOOLEAN
+AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM pg_index i JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+        WHERE i.indrelid = _table AND (i.indisprimary OR i.indisunique) AND a.attname = _column
+    )
+$$ LANGUAGE SQL STABLE;
+
+COMMENT ON FUNCTION mergestat.is_key_column(REGCLASS, TEXT) IS 'whether the column is part of the primary key (or of a unique index) of the table';
+
+-- mergestat.apply_privacy_settings re-redacts the rows already synced into the public tables, following the current settings
+CREATE OR REPLACE FUNCTION mergestat.apply_privacy_settings()
+RETURNS BIGINT
+AS
+$$
+DECLARE
+    _settings mergestat.privacy_settings;
+    _column RECORD;
+    _count BIGINT;
+    _total BIGINT := 0;
+BEGIN
+    SELECT * INTO _settings FROM mergestat.privacy_settings WHERE id;
+
+    FOR _column IN
+        SELECT c.table_name, c.column_name FROM information_schema.columns c
+            INNER JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+        WHERE c.table_schema = 'public' AND t.table_type = 'BASE TABLE' AND c.data_type = 'text'
+            AND (c.column_name = 'email' OR c.column_name LIKE '%\_email' OR c.column_name IN ('message', 'contents', 'patch'))
+    LOOP
+        IF _column.column_name = 'message' AND _settings.commit_message_bodies = 'DROP' THEN
+            EXECUTE format('UPDATE public.%I SET %2$I = split_part(%2$I, E''\n'', 1) WHERE strpos(%2$I, E''\n'') > 0',
+                _column.table_name, _column.column_name);
+        ELSIF _column.column_name = 'contents' AND _settings.file_contents = 'DROP' THEN
+            EXECUTE format('UPDATE public.%I SET %2$I = NULL WHERE %2$I IS NOT NULL', _column.table_name, _column.column_name);
+        ELSIF _column.column_name = 'patch' AND _settings.file_contents = 'DROP' THEN
+            -- patches (see git_commit_patches) aren't nullable, they're emptied
+            EXECUTE format('UPDATE public.%I SET %2$I = '''' WHERE %2$I <> ''''', _column.table_name, _column.column_name);
+        ELSIF _column.column_name NOT IN ('message', 'contents', 'patch') AND (_settings.author_emails = 'HASH' OR _settings.author_emails = 'DROP'
+            AND mergestat.is_key_column(format('public.%I', _column.table_name)::REGCLASS, _column.column_name)) THEN
+            -- dropped emails of keys are hashed, as emptying them would make rows collide
+            EXECUTE format('UPDATE public.%I SET %2$I = mergestat.privacy_hash(%2$I, $1) WHERE %2$I <> '''' AND %2$I NOT LIKE ''sha256:%%''',
+                _column.table_name, _column.column_name) USING _settings.hash_salt;
+        ELSIF _column.column_name NOT IN ('message', 'contents', 'patch') AND _settings.author_emails = 'DROP' THEN
+            EXECUTE format('UPDATE public.%I SET %2$I = '''' WHERE %2$I <> ''''', _column.table_name, _column.column_name);
+        ELSE
+            CONTINUE;
+        END IF;
+
+        GET DIAGNOSTICS _count = ROW_COUNT;
+        _total := _total + _count;
+    END LOOP;
+
+    RETURN _total;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.apply_privacy_settings() IS 'redacts the rows already synced into the public tables following mergestat.privacy_settings, returns the number of updated rows';
+
+COMMIT;
@@ -0,0 +1,78 @@
+BEGIN;
+
+-- retention policies for rows of the synced (public) tables, so that tables that accumulate history
+-- (such as workflow runs) don't grow without bound. A policy with a NULL repo_id is the default for the table,
+-- a policy with a repo_id overrides the default for that repo only.
+CREATE TABLE IF NOT EXISTS mergestat.data_retention_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    table_name TEXT NOT NULL,
+    repo_id UUID REFERENCES public.repos(id) ON DELETE CASCADE,
+    timestamp_column TEXT NOT NULL,
+    max_age INTERVAL CHECK (max_age > INTERVAL '0'),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_retention_policies_default ON mergestat.data_retention_policies (table_name) WHERE repo_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_retention_policies_repo ON mergestat.data_retention_policies (table_name, repo_id) WHERE repo_id IS NOT NULL;
+
+COMMENT ON TABLE mergestat.data_retention_policies IS 'retention policies for rows of synced tables, enforced periodically by mergestat.enforce_data_retention()';
+COMMENT ON COLUMN mergestat.data_retention_policies.table_name IS 'name of the table (in the public schema) the policy applies to';
+COMMENT ON COLUMN mergestat.data_retention_policies.repo_id IS 'repo the policy applies to, NULL for the default policy of the table (a per-repo policy takes precedence over the default)';
+COMMENT ON COLUMN mergestat.data_retention_policies.timestamp_column IS 'column of the table that determines the age of a row';
+COMMENT ON COLUMN mergestat.data_retention_policies.max_age IS 'rows older than this are removed, NULL to keep rows forever';
+COMMENT ON COLUMN mergestat.data_retention_policies.enabled IS 'if false the policy is not enforced, a disabled per-repo policy keeps all rows of the repo';
+
+-- only workflow runs (and the jobs of the runs) accumulate history indefinitely by default, other synced tables
+-- hold a snapshot of the repo (eg. git_blame only holds the blame of HEAD of the default branch)
+INSERT INTO mergestat.data_retention_policies (table_name, timestamp_column, max_age, enabled)
+VALUES
+('github_actions_workflow_runs', 'created_at', '18 months', FALSE),
+('github_actions_workflow_run_jobs', 'started_at', '18 months', FALSE),
+('git_bus_factor', 'computed_at', '2 years', FALSE)
+ON CONFLICT DO NOTHING;
+
+-- enforce_data_retention removes rows of synced tables that are past their retention period and returns
+-- the total number of rows removed. Only one caller at a time does any work, concurrent callers return NULL immediately.
+CREATE OR REPLACE FUNCTION mergestat.enforce_data_retention()
+RETURNS BIGINT
+AS
+$$
+DECLARE _rows_deleted BIGINT := 0;
+DECLARE _count BIGINT;
+DECLARE _policy RECORD;
+BEGIN
+    IF NOT pg_try_advisory_xact_lock(hashtext('mergestat.enforce_data_retention')) THEN
+        RETURN NULL;
+    END IF;
+
+    FOR _policy IN SELECT * FROM mergestat.data_retention_policies
+        WHERE enabled AND max_age IS NOT NULL ORDER BY table_name, repo_id NULLS FIRST
+    LOOP
+        -- skip policies for tables that don't exist (anymore)
+        IF to_regclass(format('public.%I', _policy.table_name)) IS NULL THEN
+            RAISE WARNING 'skipping retention policy for unknown table public.%', _policy.table_name;
+            CONTINUE;
+        END IF;
+
+        IF _policy.repo_id IS NULL THEN
+            EXECUTE format(
+                'DELETE FROM public.%I t WHERE t.%I < now() - $1 AND NOT EXISTS ' ||
+                '(SELECT 1 FROM mergestat.data_retention_policies o WHERE o.table_name = $2 AND o.repo_id = t.repo_id)',
+                _policy.table_name, _policy.timestamp_column) USING _policy.max_age, _policy.table_name;
+        ELSE
+            EXECUTE format('DELETE FROM public.%I WHERE repo_id = $1 AND %I < now() - $2', _policy.table_name, _policy.timestamp_column)
+                USING _policy.repo_id, _policy.max_age;
+        END IF;
+
+        GET DIAGNOSTICS _count = ROW_COUNT;
+        _rows_deleted := _rows_deleted + _count;
+    END LOOP;
+
+    RETURN _rows_deleted;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.enforce_data_retention() IS 'removes rows of synced tables past the retention period of mergestat.data_retention_policies, returns NULL if already running elsewhere';
+
+COMMIT;
@@ -0,0 +1,48 @@
+BEGIN;
+
+-- version of the handler that performed the last successful sync of each repo sync. Handlers bump their
+-- version when their output changes (because of a schema or logic change), syncs performed by older versions
+-- are then re-synced (backfilled) by mergestat.backfill_repo_syncs()
+CREATE TABLE IF NOT EXISTS mergestat.repo_sync_versions (
+    repo_sync_id UUID PRIMARY KEY REFERENCES mergestat.repo_syncs(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE mergestat.repo_sync_versions IS 'version of the sync handler that performed the last successful sync of each repo sync';
+COMMENT ON COLUMN mergestat.repo_sync_versions.repo_sync_id IS 'foreign key for mergestat.repo_syncs.id';
+COMMENT ON COLUMN mergestat.repo_sync_versions.version IS 'version of the handler, repo syncs without a recorded version were performed by version 1';
+COMMENT ON COLUMN mergestat.repo_sync_versions.updated_at IS 'timestamp of the last successful sync';
+
+-- backfill_repo_syncs enqueues a full re-sync, at the given (low) priority, of every enabled repo sync of the type
+-- last synced by a handler older than _version (or of all of them, if _version is NULL), except those already
+-- queued or running. The checksum of the last sync is cleared so that change detection doesn't skip the re-sync.
+-- Returns the number of enqueued jobs.
+CREATE OR REPLACE FUNCTION mergestat.backfill_repo_syncs(_sync_type TEXT, _version INTEGER, _priority INTEGER)
+RETURNS INTEGER
+AS
+$$
+DECLARE _enqueued INTEGER;
+BEGIN
+    -- workers call this when they start, serialize them so that a repo sync is only enqueued once
+    PERFORM pg_advisory_xact_lock(hashtext('mergestat.backfill_repo_syncs'));
+
+    WITH outdated AS (
+        UPDATE mergestat.repo_syncs rs SET last_completed_checksum = NULL
+        WHERE rs.sync_type = _sync_type AND rs.schedule_enabled
+            AND (_version IS NULL OR COALESCE((SELECT v.version FROM mergestat.repo_sync_versions v WHERE v.repo_sync_id = rs.id), 1) < _version)
+            AND rs.id NOT IN (SELECT repo_sync_id FROM mergestat.repo_sync_queue WHERE status = 'RUNNING' OR status = 'QUEUED')
+        RETURNING rs.id
+    )
+    INSERT INTO mergestat.repo_sync_queue (repo_sync_id, status, priority, type_group)
+    SELECT outdated.id, 'QUEUED', _priority, rst.type_group
+    FROM outdated, mergestat.repo_sync_types rst WHERE rst.type = _sync_type;
+
+    GET DIAGNOSTICS _enqueued = ROW_COUNT;
+    RETURN _enqueued;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.backfill_repo_syncs(TEXT, INTEGER, INTEGER) IS 'enqueues a full re-sync of the repo syncs of a type last synced by a handler older than the given version (or all, if NULL)';
+
+COMMIT;
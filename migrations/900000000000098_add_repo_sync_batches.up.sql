@@ -0,0 +1,36 @@
+BEGIN;
+
+-- provenance of the rows written by each successful sync job (a batch): the version of the handler and of
+-- the worker's code that produced them. After a bug fix, it identifies the repos whose data was produced by
+-- the buggy version (and needs a backfill, see mergestat.backfill_repo_syncs()).
+CREATE TABLE IF NOT EXISTS mergestat.repo_sync_batches (
+    repo_sync_queue_id BIGINT PRIMARY KEY,
+    repo_sync_id UUID NOT NULL REFERENCES mergestat.repo_syncs(id) ON DELETE CASCADE,
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    sync_type TEXT NOT NULL,
+    handler_version INTEGER NOT NULL,
+    code_version TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_repo_sync_batches_repo_sync_id ON mergestat.repo_sync_batches (repo_sync_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_repo_sync_batches_version ON mergestat.repo_sync_batches (sync_type, handler_version);
+CREATE INDEX IF NOT EXISTS idx_repo_sync_batches_code_version ON mergestat.repo_sync_batches (code_version);
+
+COMMENT ON TABLE mergestat.repo_sync_batches IS 'provenance of the rows written by each successful sync job';
+COMMENT ON COLUMN mergestat.repo_sync_batches.repo_sync_queue_id IS 'id of the job in mergestat.repo_sync_queue (jobs are eventually removed from the queue, batches are kept)';
+COMMENT ON COLUMN mergestat.repo_sync_batches.repo_sync_id IS 'foreign key for mergestat.repo_syncs.id';
+COMMENT ON COLUMN mergestat.repo_sync_batches.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN mergestat.repo_sync_batches.sync_type IS 'type of the sync';
+COMMENT ON COLUMN mergestat.repo_sync_batches.handler_version IS 'version of the sync handler (see mergestat.repo_sync_versions)';
+COMMENT ON COLUMN mergestat.repo_sync_batches.code_version IS 'version of the worker code, the vcs revision the worker was built from (suffixed with -dirty if modified) or unknown';
+COMMENT ON COLUMN mergestat.repo_sync_batches.created_at IS 'timestamp when the batch was written';
+
+-- the batch that produced the rows currently synced for each repo sync
+CREATE OR REPLACE VIEW mergestat.latest_repo_sync_batches AS
+    SELECT DISTINCT ON (repo_sync_id) * FROM mergestat.repo_sync_batches
+    ORDER BY repo_sync_id, created_at DESC;
+
+COMMENT ON VIEW mergestat.latest_repo_sync_batches IS 'latest batch of each repo sync, ie. the provenance of the rows currently synced';
+
+COMMIT;
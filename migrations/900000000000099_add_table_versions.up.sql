@@ -0,0 +1,121 @@
+BEGIN;
+
+-- new versions of synced tables, for breaking changes to a table (eg. git_blame_v2 with different columns).
+-- A new version is created (as public.<table>_v<version>) and registered by a migration, and is populated by
+-- the worker, alongside the active version, after every sync of the sync type writing the table. Once populated,
+-- mergestat.cut_over_table_version() swaps it in place of the active version atomically, readers stay on the
+-- active version until then.
+CREATE TABLE IF NOT EXISTS mergestat.table_versions (
+    table_name TEXT NOT NULL,
+    version INTEGER NOT NULL CHECK (version > 1),
+    sync_type TEXT NOT NULL REFERENCES mergestat.repo_sync_types(type) ON DELETE CASCADE,
+    populate_function TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'POPULATING' CHECK (status IN ('POPULATING', 'ACTIVE', 'RETIRED')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    cut_over_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (table_name, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_table_versions_active ON mergestat.table_versions (table_name) WHERE status = 'ACTIVE';
+
+COMMENT ON TABLE mergestat.table_versions IS 'versions of synced tables populated in parallel with the active version, see mergestat.cut_over_table_version()';
+COMMENT ON COLUMN mergestat.table_versions.table_name IS 'name of the table (in the public schema), the version is public.<table_name>_v<version> until it is cut over';
+COMMENT ON COLUMN mergestat.table_versions.version IS 'version of the table, tables without an ACTIVE version are at version 1';
+COMMENT ON COLUMN mergestat.table_versions.sync_type IS 'sync type writing the table, the version is populated after each of its syncs';
+COMMENT ON COLUMN mergestat.table_versions.populate_function IS 'function (taking the id of a repo and returning the number of rows written) replacing the rows of the repo in the version';
+COMMENT ON COLUMN mergestat.table_versions.status IS 'POPULATING until cut over, ACTIVE once cut over and RETIRED once a newer version is cut over';
+COMMENT ON COLUMN mergestat.table_versions.cut_over_at IS 'timestamp of the cut over';
+
+-- populate_table_versions populates the versions being populated of the tables the sync type writes, for the repo.
+-- Returns the number of rows written.
+CREATE OR REPLACE FUNCTION mergestat.populate_table_versions(_sync_type TEXT, _repo_id UUID)
+RETURNS BIGINT
+AS
+$$
+DECLARE _rows BIGINT := 0;
+DECLARE _count BIGINT;
+DECLARE _version RECORD;
+BEGIN
+    FOR _version IN SELECT * FROM mergestat.table_versions WHERE sync_type = _sync_type AND status = 'POPULATING' LOOP
+        EXECUTE format('SELECT %s($1)', _version.populate_function::REGPROC) INTO _count USING _repo_id;
+        _rows := _rows + COALESCE(_count, 0);
+    END LOOP;
+
+    RETURN _rows;
+END;
+$$ LANGUAGE plpgsql;
+
+-- populate_table_version populates a version of a table for every repo (eg. right after it's created, instead of
+-- waiting for every repo to be synced again). Returns the number of rows written.
+CREATE OR REPLACE FUNCTION mergestat.populate_table_version(_table TEXT, _version INTEGER)
+RETURNS BIGINT
+AS
+$$
+DECLARE _rows BIGINT := 0;
+DECLARE _count BIGINT;
+DECLARE _function TEXT;
+DECLARE _repo_id UUID;
+BEGIN
+    SELECT populate_function INTO _function FROM mergestat.table_versions WHERE table_name = _table AND version = _version AND status = 'POPULATING';
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'version % of % is not being populated', _version, _table;
+    END IF;
+
+    FOR _repo_id IN SELECT id FROM public.repos LOOP
+        EXECUTE format('SELECT %s($1)', _function::REGPROC) INTO _count USING _repo_id;
+        _rows := _rows + COALESCE(_count, 0);
+    END LOOP;
+
+    RETURN _rows;
+END;
+$$ LANGUAGE plpgsql;
+
+-- cut_over_table_version swaps a version of a table in place of the active version: public.<table> is renamed to
+-- public.<table>_v<active version> and public.<table>_v<version> to public.<table>. Call it from the migration
+-- shipped with the worker that writes the new version. Views are bound to the tables they select from, so views
+-- depending on the table must be dropped before (and recreated after) the cut over, in the same transaction.
+CREATE OR REPLACE FUNCTION mergestat.cut_over_table_version(_table TEXT, _version INTEGER)
+RETURNS VOID
+AS
+$$
+DECLARE _active INTEGER;
+DECLARE _views TEXT;
+BEGIN
+    PERFORM 1 FROM mergestat.table_versions WHERE table_name = _table AND version = _version AND status = 'POPULATING' FOR UPDATE;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'version % of % is not being populated', _version, _table;
+    END IF;
+
+    IF to_regclass(format('public.%I', _table || '_v' || _version)) IS NULL THEN
+        RAISE EXCEPTION 'table public.% does not exist', _table || '_v' || _version;
+    END IF;
+
+    SELECT string_agg(DISTINCT v.oid::REGCLASS::TEXT, ', ') INTO _views
+    FROM pg_depend d
+        INNER JOIN pg_rewrite r ON r.oid = d.objid
+        INNER JOIN pg_class v ON v.oid = r.ev_class
+    WHERE d.classid = 'pg_rewrite'::REGCLASS AND d.refobjid = format('public.%I', _table)::REGCLASS AND v.oid <> d.refobjid;
+
+    IF _views IS NOT NULL THEN
+        RAISE EXCEPTION 'views % depend on public.%, drop them before (and recreate them after) the cut over', _views, _table;
+    END IF;
+
+    _active := COALESCE((SELECT version FROM mergestat.table_versions WHERE table_name = _table AND status = 'ACTIVE'), 1);
+
+    EXECUTE format('LOCK TABLE public.%I, public.%I IN ACCESS EXCLUSIVE MODE', _table, _table || '_v' || _version);
+    EXECUTE format('ALTER TABLE public.%I RENAME TO %I', _table, _table || '_v' || _active);
+    EXECUTE format('ALTER TABLE public.%I RENAME TO %I', _table || '_v' || _version, _table);
+
+    -- copies of the table used by dry-run syncs are recreated (with the new columns) on first use
+    EXECUTE format('DROP TABLE IF EXISTS mergestat_staging.%I', _table);
+
+    UPDATE mergestat.table_versions SET status = 'RETIRED' WHERE table_name = _table AND status = 'ACTIVE';
+    UPDATE mergestat.table_versions SET status = 'ACTIVE', cut_over_at = now() WHERE table_name = _table AND version = _version;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.populate_table_versions(TEXT, UUID) IS 'populates, for the repo, the versions being populated of the tables the sync type writes';
+COMMENT ON FUNCTION mergestat.populate_table_version(TEXT, INTEGER) IS 'populates a version of a table for every repo';
+COMMENT ON FUNCTION mergestat.cut_over_table_version(TEXT, INTEGER) IS 'atomically swaps a version of a table in place of the active version';
+
+COMMIT;
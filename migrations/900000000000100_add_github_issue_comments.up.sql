@@ -0,0 +1,64 @@
+BEGIN;
+
+UPDATE mergestat.repo_sync_types SET description = 'Retrieves all the issues of a GitHub repo, with their comments and reactions' WHERE type = 'GITHUB_REPO_ISSUES';
+UPDATE mergestat.repo_sync_types SET description = 'Retrieves all the pull requests of a GitHub repo, with their comments, review comments and reactions' WHERE type = 'GITHUB_REPO_PRS';
+
+CREATE TABLE IF NOT EXISTS public.github_issue_comments (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    id BIGINT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('ISSUE_COMMENT', 'REVIEW_COMMENT')),
+    issue_number INTEGER NOT NULL,
+    is_pull_request BOOLEAN NOT NULL,
+    author_login TEXT,
+    author_association TEXT,
+    body TEXT,
+    path TEXT,
+    in_reply_to_id BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    url TEXT,
+    reactions_total INTEGER NOT NULL DEFAULT 0,
+    reactions JSONB NOT NULL DEFAULT '{}'::JSONB,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, kind, id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_issue_comments_issue ON public.github_issue_comments (repo_id, issue_number);
+
+COMMENT ON TABLE public.github_issue_comments IS 'comments of GitHub issues and pull requests (including pull request review comments)';
+COMMENT ON COLUMN public.github_issue_comments.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_issue_comments.id IS 'GitHub id of the comment';
+COMMENT ON COLUMN public.github_issue_comments.kind IS 'ISSUE_COMMENT for comments of the conversation of an issue or pull request, REVIEW_COMMENT for comments on the diff of a pull request';
+COMMENT ON COLUMN public.github_issue_comments.issue_number IS 'number of the issue or pull request';
+COMMENT ON COLUMN public.github_issue_comments.is_pull_request IS 'whether the comment belongs to a pull request';
+COMMENT ON COLUMN public.github_issue_comments.author_login IS 'login of the author of the comment';
+COMMENT ON COLUMN public.github_issue_comments.author_association IS 'relationship of the author to the repo, eg. MEMBER or CONTRIBUTOR';
+COMMENT ON COLUMN public.github_issue_comments.body IS 'body of the comment';
+COMMENT ON COLUMN public.github_issue_comments.path IS 'path of the file a review comment is on';
+COMMENT ON COLUMN public.github_issue_comments.in_reply_to_id IS 'id of the review comment a review comment replies to';
+COMMENT ON COLUMN public.github_issue_comments.created_at IS 'timestamp when the comment was created';
+COMMENT ON COLUMN public.github_issue_comments.updated_at IS 'timestamp when the comment was last updated';
+COMMENT ON COLUMN public.github_issue_comments.url IS 'url of the comment';
+COMMENT ON COLUMN public.github_issue_comments.reactions_total IS 'total number of reactions to the comment';
+COMMENT ON COLUMN public.github_issue_comments.reactions IS 'number of reactions to the comment by content, eg. {"+1": 2, "heart": 1}';
+COMMENT ON COLUMN public.github_issue_comments._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_issue_reactions (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    issue_number INTEGER NOT NULL,
+    is_pull_request BOOLEAN NOT NULL,
+    reactions_total INTEGER NOT NULL DEFAULT 0,
+    reactions JSONB NOT NULL DEFAULT '{}'::JSONB,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, issue_number)
+);
+
+COMMENT ON TABLE public.github_issue_reactions IS 'rollup of the reactions to GitHub issues and pull requests (reactions to their comments are in github_issue_comments)';
+COMMENT ON COLUMN public.github_issue_reactions.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_issue_reactions.issue_number IS 'number of the issue or pull request';
+COMMENT ON COLUMN public.github_issue_reactions.is_pull_request IS 'whether the item is a pull request';
+COMMENT ON COLUMN public.github_issue_reactions.reactions_total IS 'total number of reactions to the item';
+COMMENT ON COLUMN public.github_issue_reactions.reactions IS 'number of reactions to the item by content, eg. {"+1": 2, "heart": 1}';
+COMMENT ON COLUMN public.github_issue_reactions._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
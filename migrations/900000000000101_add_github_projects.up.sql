@@ -0,0 +1,111 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority, type_group)
+VALUES ('GITHUB_PROJECTS', 'Retrieves the GitHub projects (Projects v2) linked to a repo, with their fields, items and item field values', 'GitHub Projects', 2, 'GITHUB')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.github_projects (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    project_id TEXT NOT NULL,
+    number INTEGER,
+    title TEXT,
+    url TEXT,
+    closed BOOLEAN,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, project_id)
+);
+
+COMMENT ON TABLE public.github_projects IS 'GitHub projects (Projects v2) linked to a repo';
+COMMENT ON COLUMN public.github_projects.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_projects.project_id IS 'GraphQL node id of the project';
+COMMENT ON COLUMN public.github_projects.number IS 'number of the project (unique within its owner)';
+COMMENT ON COLUMN public.github_projects.title IS 'title of the project';
+COMMENT ON COLUMN public.github_projects.url IS 'url of the project';
+COMMENT ON COLUMN public.github_projects.closed IS 'whether the project is closed';
+COMMENT ON COLUMN public.github_projects.created_at IS 'timestamp when the project was created';
+COMMENT ON COLUMN public.github_projects.updated_at IS 'timestamp when the project was last updated';
+COMMENT ON COLUMN public.github_projects._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_project_fields (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    project_id TEXT NOT NULL,
+    field_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    data_type TEXT,
+    options JSONB NOT NULL DEFAULT '[]'::JSONB,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, project_id, field_id)
+);
+
+COMMENT ON TABLE public.github_project_fields IS 'fields (built-in and custom) of GitHub projects';
+COMMENT ON COLUMN public.github_project_fields.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_project_fields.project_id IS 'GraphQL node id of the project';
+COMMENT ON COLUMN public.github_project_fields.field_id IS 'GraphQL node id of the field';
+COMMENT ON COLUMN public.github_project_fields.name IS 'name of the field, eg. Status';
+COMMENT ON COLUMN public.github_project_fields.data_type IS 'data type of the field, eg. SINGLE_SELECT, ITERATION, TEXT, NUMBER or DATE';
+COMMENT ON COLUMN public.github_project_fields.options IS 'options of a single select field, or iterations of an iteration field';
+COMMENT ON COLUMN public.github_project_fields._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_project_items (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    project_id TEXT NOT NULL,
+    item_id TEXT NOT NULL,
+    type TEXT,
+    content_repository TEXT,
+    content_number INTEGER,
+    content_url TEXT,
+    title TEXT,
+    is_archived BOOLEAN,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, project_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_project_items_content ON public.github_project_items (content_repository, content_number);
+
+COMMENT ON TABLE public.github_project_items IS 'items of GitHub projects, and the issue, pull request or draft issue they hold';
+COMMENT ON COLUMN public.github_project_items.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_project_items.project_id IS 'GraphQL node id of the project';
+COMMENT ON COLUMN public.github_project_items.item_id IS 'GraphQL node id of the item';
+COMMENT ON COLUMN public.github_project_items.type IS 'type of the content of the item: ISSUE, PULL_REQUEST, DRAFT_ISSUE or REDACTED';
+COMMENT ON COLUMN public.github_project_items.content_repository IS 'repository (owner/name) of the issue or pull request, items of a project may belong to any repo';
+COMMENT ON COLUMN public.github_project_items.content_number IS 'number of the issue or pull request, joins with github_issues.number and github_pull_requests.number';
+COMMENT ON COLUMN public.github_project_items.content_url IS 'url of the issue or pull request';
+COMMENT ON COLUMN public.github_project_items.title IS 'title of the content of the item';
+COMMENT ON COLUMN public.github_project_items.is_archived IS 'whether the item is archived';
+COMMENT ON COLUMN public.github_project_items.created_at IS 'timestamp when the item was added to the project';
+COMMENT ON COLUMN public.github_project_items.updated_at IS 'timestamp when the item was last updated';
+COMMENT ON COLUMN public.github_project_items._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_project_item_field_values (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    project_id TEXT NOT NULL,
+    item_id TEXT NOT NULL,
+    field_name TEXT NOT NULL,
+    field_type TEXT NOT NULL,
+    text_value TEXT,
+    number_value DOUBLE PRECISION,
+    date_value DATE,
+    iteration_duration INTEGER,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, project_id, item_id, field_name)
+);
+
+COMMENT ON TABLE public.github_project_item_field_values IS 'values of the fields (status, iteration, custom fields...) of GitHub project items';
+COMMENT ON COLUMN public.github_project_item_field_values.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_project_item_field_values.project_id IS 'GraphQL node id of the project';
+COMMENT ON COLUMN public.github_project_item_field_values.item_id IS 'GraphQL node id of the item';
+COMMENT ON COLUMN public.github_project_item_field_values.field_name IS 'name of the field, eg. Status';
+COMMENT ON COLUMN public.github_project_item_field_values.field_type IS 'type of the value: SINGLE_SELECT, ITERATION, TEXT, NUMBER or DATE';
+COMMENT ON COLUMN public.github_project_item_field_values.text_value IS 'value of a text field, name of the option of a single select field or title of the iteration of an iteration field';
+COMMENT ON COLUMN public.github_project_item_field_values.number_value IS 'value of a number field';
+COMMENT ON COLUMN public.github_project_item_field_values.date_value IS 'value of a date field or start date of the iteration of an iteration field';
+COMMENT ON COLUMN public.github_project_item_field_values.iteration_duration IS 'duration, in days, of the iteration of an iteration field';
+COMMENT ON COLUMN public.github_project_item_field_values.updated_at IS 'timestamp when the value was last updated';
+COMMENT ON COLUMN public.github_project_item_field_values._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
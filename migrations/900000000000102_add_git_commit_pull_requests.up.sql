@@ -0,0 +1,73 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GIT_COMMIT_PULL_REQUESTS', 'Derives which commits belong to which pull request (including merge and squash commits), runs after GIT_COMMITS and pull request syncs', 'Commit Pull Requests', 4)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_commit_pull_requests (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    commit_hash TEXT NOT NULL,
+    pull_request_number INTEGER NOT NULL,
+    association TEXT NOT NULL CHECK (association IN ('PR_COMMIT', 'MERGE_COMMIT', 'SQUASH_COMMIT')),
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, commit_hash, pull_request_number, association)
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_commit_pull_requests_pr ON public.git_commit_pull_requests (repo_id, pull_request_number);
+
+COMMENT ON TABLE public.git_commit_pull_requests IS 'commits of a repo and the pull requests they belong to';
+COMMENT ON COLUMN public.git_commit_pull_requests.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_commit_pull_requests.commit_hash IS 'hash of the commit';
+COMMENT ON COLUMN public.git_commit_pull_requests.pull_request_number IS 'number of the pull request';
+COMMENT ON COLUMN public.git_commit_pull_requests.association IS 'PR_COMMIT for commits of the pull request (from github_pull_request_commits), MERGE_COMMIT for the merge commit of the pull request and SQUASH_COMMIT for the commit a pull request was squashed into (both from git_commits)';
+COMMENT ON COLUMN public.git_commit_pull_requests._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- derive_commit_pull_requests (re-)materializes git_commit_pull_requests for a repo. Merge and squash commits are
+-- recognized by the messages GitHub generates: "Merge pull request #123 from ..." and "<title> (#123)". If pull
+-- requests are synced for the repo, they must also reference a merged pull request. Tables are left unqualified so
+-- that the function writes into the schema the calling session resolves them to (see dry runs).
+CREATE OR REPLACE FUNCTION mergestat.derive_commit_pull_requests(_repo_id UUID)
+RETURNS INTEGER
+AS
+$$
+DECLARE _count INTEGER;
+BEGIN
+    DELETE FROM git_commit_pull_requests WHERE repo_id = _repo_id;
+
+    INSERT INTO git_commit_pull_requests (repo_id, commit_hash, pull_request_number, association)
+    WITH merged AS (
+        SELECT c.hash, substring(c.message FROM '^Merge pull request #([0-9]+) ')::INTEGER AS number, 'MERGE_COMMIT' AS association
+        FROM git_commits c
+        WHERE c.repo_id = _repo_id AND c.parents > 1 AND c.message ~ '^Merge pull request #[0-9]+ '
+        UNION ALL
+        SELECT c.hash, substring(split_part(c.message, E'\n', 1) FROM '\(#([0-9]+)\)\s*$')::INTEGER, 'SQUASH_COMMIT'
+        FROM git_commits c
+        WHERE c.repo_id = _repo_id AND c.parents = 1 AND split_part(c.message, E'\n', 1) ~ '\(#[0-9]+\)\s*$'
+    )
+    SELECT DISTINCT _repo_id, prc.hash, prc.pr_number, 'PR_COMMIT'
+    FROM github_pull_request_commits prc
+    WHERE prc.repo_id = _repo_id AND prc.hash IS NOT NULL
+    UNION
+    SELECT _repo_id, m.hash, m.number, m.association
+    FROM merged m
+    WHERE NOT EXISTS (SELECT 1 FROM github_pull_requests pr WHERE pr.repo_id = _repo_id)
+        OR EXISTS (SELECT 1 FROM github_pull_requests pr WHERE pr.repo_id = _repo_id AND pr.number = m.number AND pr.merged);
+
+    GET DIAGNOSTICS _count = ROW_COUNT;
+    RETURN _count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.derive_commit_pull_requests(UUID) IS 'materializes the pull requests of the commits of a repo into git_commit_pull_requests';
+
+-- time from each commit of a merged pull request being authored to the pull request being merged
+CREATE OR REPLACE VIEW public.git_commit_lead_times AS
+    SELECT cpr.repo_id, cpr.commit_hash, cpr.pull_request_number, prc.author_when, pr.merged_at, pr.merged_at - prc.author_when AS lead_time
+    FROM public.git_commit_pull_requests cpr
+    INNER JOIN public.github_pull_request_commits prc ON prc.repo_id = cpr.repo_id AND prc.pr_number = cpr.pull_request_number AND prc.hash = cpr.commit_hash
+    INNER JOIN public.github_pull_requests pr ON pr.repo_id = cpr.repo_id AND pr.number = cpr.pull_request_number
+    WHERE cpr.association = 'PR_COMMIT' AND pr.merged;
+
+COMMENT ON VIEW public.git_commit_lead_times IS 'lead time (from being authored to being merged) of each commit of a merged pull request';
+
+COMMIT;
@@ -0,0 +1,154 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GITHUB_ISSUE_RESPONSE_TIMES', 'Derives time to first response, time to first review and time in label of issues and pull requests, runs after issue, pull request and review syncs', 'Issue Response Times', 4)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.github_issue_label_events (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    id BIGINT NOT NULL,
+    issue_number INTEGER NOT NULL,
+    is_pull_request BOOLEAN NOT NULL,
+    event TEXT NOT NULL CHECK (event IN ('labeled', 'unlabeled')),
+    label TEXT NOT NULL,
+    actor_login TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_issue_label_events_issue ON public.github_issue_label_events (repo_id, issue_number, label, created_at);
+
+COMMENT ON TABLE public.github_issue_label_events IS 'labels added to and removed from GitHub issues and pull requests';
+COMMENT ON COLUMN public.github_issue_label_events.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_issue_label_events.id IS 'GitHub id of the event';
+COMMENT ON COLUMN public.github_issue_label_events.issue_number IS 'number of the issue or pull request';
+COMMENT ON COLUMN public.github_issue_label_events.is_pull_request IS 'whether the event belongs to a pull request';
+COMMENT ON COLUMN public.github_issue_label_events.event IS 'labeled or unlabeled';
+COMMENT ON COLUMN public.github_issue_label_events.label IS 'name of the label';
+COMMENT ON COLUMN public.github_issue_label_events.actor_login IS 'login of the user who added or removed the label';
+COMMENT ON COLUMN public.github_issue_label_events.created_at IS 'timestamp of the event';
+COMMENT ON COLUMN public.github_issue_label_events._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_issue_response_times (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    issue_number INTEGER NOT NULL,
+    is_pull_request BOOLEAN NOT NULL,
+    author_login TEXT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    first_response_at TIMESTAMP WITH TIME ZONE,
+    first_responder_login TEXT,
+    time_to_first_response INTERVAL,
+    first_review_at TIMESTAMP WITH TIME ZONE,
+    time_to_first_review INTERVAL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, is_pull_request, issue_number)
+);
+
+COMMENT ON TABLE public.github_issue_response_times IS 'time to the first response (and, for pull requests, to the first review) of GitHub issues and pull requests';
+COMMENT ON COLUMN public.github_issue_response_times.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_issue_response_times.issue_number IS 'number of the issue or pull request';
+COMMENT ON COLUMN public.github_issue_response_times.is_pull_request IS 'whether the item is a pull request';
+COMMENT ON COLUMN public.github_issue_response_times.author_login IS 'login of the author of the item';
+COMMENT ON COLUMN public.github_issue_response_times.created_at IS 'timestamp when the item was created';
+COMMENT ON COLUMN public.github_issue_response_times.first_response_at IS 'timestamp of the first comment (or review) by someone other than the author (and other than a bot), NULL if none yet';
+COMMENT ON COLUMN public.github_issue_response_times.first_responder_login IS 'login of the author of the first response';
+COMMENT ON COLUMN public.github_issue_response_times.time_to_first_response IS 'time from the item being created to the first response';
+COMMENT ON COLUMN public.github_issue_response_times.first_review_at IS 'timestamp of the first review of a pull request by someone other than the author, NULL if none yet';
+COMMENT ON COLUMN public.github_issue_response_times.time_to_first_review IS 'time from the pull request being created to the first review';
+COMMENT ON COLUMN public.github_issue_response_times._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_issue_label_durations (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    issue_number INTEGER NOT NULL,
+    is_pull_request BOOLEAN NOT NULL,
+    label TEXT NOT NULL,
+    labeled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    unlabeled_at TIMESTAMP WITH TIME ZONE,
+    duration INTERVAL NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, is_pull_request, issue_number, label, labeled_at)
+);
+
+COMMENT ON TABLE public.github_issue_label_durations IS 'periods GitHub issues and pull requests spent with a label (eg. in a triage state)';
+COMMENT ON COLUMN public.github_issue_label_durations.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_issue_label_durations.issue_number IS 'number of the issue or pull request';
+COMMENT ON COLUMN public.github_issue_label_durations.is_pull_request IS 'whether the item is a pull request';
+COMMENT ON COLUMN public.github_issue_label_durations.label IS 'name of the label';
+COMMENT ON COLUMN public.github_issue_label_durations.labeled_at IS 'timestamp when the label was added';
+COMMENT ON COLUMN public.github_issue_label_durations.unlabeled_at IS 'timestamp when the label was removed, NULL if it still is labeled';
+COMMENT ON COLUMN public.github_issue_label_durations.duration IS 'time spent with the label, until it was removed, the item closed or (if neither) the time of the sync';
+COMMENT ON COLUMN public.github_issue_label_durations._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- derive_issue_response_times (re-)materializes github_issue_response_times and github_issue_label_durations for a repo.
+-- Tables are left unqualified so that the function writes into the schema the calling session resolves them to (see dry runs).
+CREATE OR REPLACE FUNCTION mergestat.derive_issue_response_times(_repo_id UUID)
+RETURNS INTEGER
+AS
+$$
+DECLARE _count INTEGER;
+BEGIN
+    DELETE FROM github_issue_response_times WHERE repo_id = _repo_id;
+    DELETE FROM github_issue_label_durations WHERE repo_id = _repo_id;
+
+    CREATE TEMPORARY TABLE _items ON COMMIT DROP AS
+        SELECT number, FALSE AS is_pull_request, author_login, created_at, closed_at FROM github_issues WHERE repo_id = _repo_id AND number IS NOT NULL
+        UNION ALL
+        SELECT number, TRUE, author_login, created_at, closed_at FROM github_pull_requests WHERE repo_id = _repo_id AND number IS NOT NULL;
+
+    INSERT INTO github_issue_response_times (repo_id, issue_number, is_pull_request, author_login, created_at,
+        first_response_at, first_responder_login, time_to_first_response, first_review_at, time_to_first_review)
+    WITH responses AS (
+        SELECT c.issue_number AS number, c.is_pull_request, c.author_login, c.created_at, FALSE AS review
+        FROM github_issue_comments c WHERE c.repo_id = _repo_id
+        UNION ALL
+        SELECT r.pr_number, TRUE, r.author_login, COALESCE(r.submitted_at, r.created_at), TRUE
+        FROM github_pull_request_reviews r WHERE r.repo_id = _repo_id
+    ),
+    -- responses of anyone but the author of the item (and other than a bot)
+    others AS (
+        SELECT r.* FROM responses r
+        INNER JOIN _items i ON i.number = r.number AND i.is_pull_request = r.is_pull_request
+        WHERE r.author_login IS DISTINCT FROM i.author_login AND r.author_login NOT LIKE '%[bot]' AND r.created_at IS NOT NULL
+    ),
+    first_responses AS (
+        SELECT DISTINCT ON (number, is_pull_request) number, is_pull_request, author_login, created_at
+        FROM others ORDER BY number, is_pull_request, created_at
+    ),
+    first_reviews AS (
+        SELECT number, MIN(created_at) AS created_at FROM others WHERE review GROUP BY number
+    )
+    SELECT _repo_id, i.number, i.is_pull_request, i.author_login, i.created_at,
+        fr.created_at, fr.author_login, fr.created_at - i.created_at,
+        rv.created_at, rv.created_at - i.created_at
+    FROM _items i
+    LEFT JOIN first_responses fr ON fr.number = i.number AND fr.is_pull_request = i.is_pull_request
+    LEFT JOIN first_reviews rv ON i.is_pull_request AND rv.number = i.number
+    ON CONFLICT DO NOTHING;
+
+    GET DIAGNOSTICS _count = ROW_COUNT;
+
+    INSERT INTO github_issue_label_durations (repo_id, issue_number, is_pull_request, label, labeled_at, unlabeled_at, duration)
+    WITH events AS (
+        SELECT issue_number, is_pull_request, label, event, created_at,
+            LEAD(event) OVER w AS next_event, LEAD(created_at) OVER w AS next_at
+        FROM github_issue_label_events WHERE repo_id = _repo_id
+        WINDOW w AS (PARTITION BY issue_number, is_pull_request, label ORDER BY created_at, id)
+    )
+    SELECT _repo_id, e.issue_number, e.is_pull_request, e.label, e.created_at,
+        CASE WHEN e.next_event = 'unlabeled' THEN e.next_at END,
+        GREATEST(COALESCE(e.next_at, i.closed_at, now()) - e.created_at, INTERVAL '0')
+    FROM events e
+    LEFT JOIN _items i ON i.number = e.issue_number AND i.is_pull_request = e.is_pull_request
+    WHERE e.event = 'labeled'
+    ON CONFLICT DO NOTHING;
+
+    DROP TABLE _items;
+
+    RETURN _count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.derive_issue_response_times(UUID) IS 'materializes the response times and label durations of the issues and pull requests of a repo into github_issue_response_times and github_issue_label_durations';
+
+COMMIT;
@@ -0,0 +1,94 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GITHUB_REVIEW_LOAD', 'Derives weekly review counts, response latency and review depth per reviewer, runs after pull request and review syncs', 'Review Load', 4)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.github_reviewer_weekly_load (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    reviewer_login TEXT NOT NULL,
+    week DATE NOT NULL,
+    reviews INTEGER NOT NULL,
+    pull_requests_reviewed INTEGER NOT NULL,
+    approvals INTEGER NOT NULL,
+    changes_requested INTEGER NOT NULL,
+    review_comments INTEGER NOT NULL,
+    comments_per_review NUMERIC NOT NULL,
+    avg_response_latency INTERVAL,
+    median_response_latency INTERVAL,
+    share_of_reviews NUMERIC NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, reviewer_login, week)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_reviewer_weekly_load_week ON public.github_reviewer_weekly_load (week);
+
+COMMENT ON TABLE public.github_reviewer_weekly_load IS 'weekly rollup of the pull request reviews of each reviewer of a GitHub repo';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.reviewer_login IS 'login of the reviewer';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.week IS 'first day (monday) of the week the reviews were submitted in';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.reviews IS 'number of reviews submitted';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.pull_requests_reviewed IS 'number of distinct pull requests reviewed';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.approvals IS 'number of reviews that approved';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.changes_requested IS 'number of reviews that requested changes';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.review_comments IS 'number of comments left with the reviews';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.comments_per_review IS 'review depth: average number of comments per review';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.avg_response_latency IS 'average time from a pull request being opened to the reviewer''s first review of it';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.median_response_latency IS 'median time from a pull request being opened to the reviewer''s first review of it';
+COMMENT ON COLUMN public.github_reviewer_weekly_load.share_of_reviews IS 'share (0 to 1) of the repo''s reviews of the week submitted by the reviewer';
+COMMENT ON COLUMN public.github_reviewer_weekly_load._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- derive_review_load (re-)materializes github_reviewer_weekly_load for a repo.
+-- Tables are left unqualified so that the function writes into the schema the calling session resolves them to (see dry runs).
+CREATE OR REPLACE FUNCTION mergestat.derive_review_load(_repo_id UUID)
+RETURNS INTEGER
+AS
+$$
+DECLARE _count INTEGER;
+BEGIN
+    DELETE FROM github_reviewer_weekly_load WHERE repo_id = _repo_id;
+
+    INSERT INTO github_reviewer_weekly_load (repo_id, reviewer_login, week, reviews, pull_requests_reviewed, approvals,
+        changes_requested, review_comments, comments_per_review, avg_response_latency, median_response_latency, share_of_reviews)
+    WITH reviews AS (
+        -- pending reviews (not yet submitted) and reviews of a pull request by its own author are not review load
+        SELECT r.author_login, r.pr_number, r.state, COALESCE(r.comment_count, 0) AS comment_count,
+            COALESCE(r.submitted_at, r.created_at) AS submitted_at, pr.created_at AS opened_at
+        FROM github_pull_request_reviews r
+        LEFT JOIN github_pull_requests pr ON pr.repo_id = r.repo_id AND pr.number = r.pr_number
+        WHERE r.repo_id = _repo_id AND r.author_login IS NOT NULL AND r.state IS DISTINCT FROM 'PENDING'
+            AND COALESCE(r.submitted_at, r.created_at) IS NOT NULL AND r.author_login IS DISTINCT FROM pr.author_login
+    ),
+    first_reviews AS (
+        SELECT author_login, pr_number, MIN(submitted_at) AS submitted_at, MIN(submitted_at) - MIN(opened_at) AS latency
+        FROM reviews GROUP BY author_login, pr_number
+    ),
+    latencies AS (
+        SELECT author_login, date_trunc('week', submitted_at)::DATE AS week,
+            AVG(latency) AS avg_latency,
+            percentile_cont(0.5) WITHIN GROUP (ORDER BY latency) AS median_latency
+        FROM first_reviews WHERE latency IS NOT NULL GROUP BY 1, 2
+    ),
+    weekly AS (
+        SELECT author_login, date_trunc('week', submitted_at)::DATE AS week,
+            COUNT(*) AS reviews,
+            COUNT(DISTINCT pr_number) AS pull_requests_reviewed,
+            COUNT(*) FILTER (WHERE state = 'APPROVED') AS approvals,
+            COUNT(*) FILTER (WHERE state = 'CHANGES_REQUESTED') AS changes_requested,
+            SUM(comment_count) AS review_comments
+        FROM reviews GROUP BY 1, 2
+    )
+    SELECT _repo_id, w.author_login, w.week, w.reviews, w.pull_requests_reviewed, w.approvals, w.changes_requested,
+        w.review_comments, ROUND(w.review_comments::NUMERIC / w.reviews, 2), l.avg_latency, l.median_latency,
+        ROUND(w.reviews::NUMERIC / SUM(w.reviews) OVER (PARTITION BY w.week), 4)
+    FROM weekly w
+    LEFT JOIN latencies l ON l.author_login = w.author_login AND l.week = w.week;
+
+    GET DIAGNOSTICS _count = ROW_COUNT;
+    RETURN _count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.derive_review_load(UUID) IS 'materializes the weekly review load of each reviewer of a repo into github_reviewer_weekly_load';
+
+COMMIT;
@@ -0,0 +1,66 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority, type_group)
+VALUES ('GITHUB_ACTIONS_USAGE', 'Retrieves the timing and billable minutes (by runner operating system) of the completed GitHub Actions workflow runs and jobs of a repo, runs after the GitHub Actions sync', 'GitHub Actions Usage', 3, 'GITHUB')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.github_actions_workflow_run_usage (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    run_id BIGINT NOT NULL,
+    run_duration_ms BIGINT,
+    billable_ms BIGINT NOT NULL,
+    billable_minutes BIGINT NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, run_id)
+);
+
+COMMENT ON TABLE public.github_actions_workflow_run_usage IS 'timing and billable time of completed GitHub Actions workflow runs';
+COMMENT ON COLUMN public.github_actions_workflow_run_usage.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_actions_workflow_run_usage.run_id IS 'id of the workflow run (see github_actions_workflow_runs.id)';
+COMMENT ON COLUMN public.github_actions_workflow_run_usage.run_duration_ms IS 'wall clock duration of the run in milliseconds';
+COMMENT ON COLUMN public.github_actions_workflow_run_usage.billable_ms IS 'time the jobs of the run spent on GitHub hosted runners in milliseconds (0 for self-hosted runners and public repos)';
+COMMENT ON COLUMN public.github_actions_workflow_run_usage.billable_minutes IS 'minutes GitHub bills for the run: each job rounded up to the minute and multiplied by the rate of its runner operating system';
+COMMENT ON COLUMN public.github_actions_workflow_run_usage._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_actions_workflow_job_usage (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    run_id BIGINT NOT NULL,
+    job_id BIGINT NOT NULL,
+    runner_os TEXT NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    billable_minutes BIGINT NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, job_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_actions_workflow_job_usage_run ON public.github_actions_workflow_job_usage (repo_id, run_id);
+
+COMMENT ON TABLE public.github_actions_workflow_job_usage IS 'billable time of the jobs of completed GitHub Actions workflow runs, by runner operating system';
+COMMENT ON COLUMN public.github_actions_workflow_job_usage.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_actions_workflow_job_usage.run_id IS 'id of the workflow run (see github_actions_workflow_runs.id)';
+COMMENT ON COLUMN public.github_actions_workflow_job_usage.job_id IS 'id of the job (see github_actions_workflow_run_jobs.id)';
+COMMENT ON COLUMN public.github_actions_workflow_job_usage.runner_os IS 'operating system of the GitHub hosted runner, eg. UBUNTU, WINDOWS or MACOS';
+COMMENT ON COLUMN public.github_actions_workflow_job_usage.duration_ms IS 'billable time of the job in milliseconds';
+COMMENT ON COLUMN public.github_actions_workflow_job_usage.billable_minutes IS 'minutes GitHub bills for the job: rounded up to the minute and multiplied by the rate of the runner operating system';
+COMMENT ON COLUMN public.github_actions_workflow_job_usage._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE OR REPLACE VIEW public.github_actions_monthly_usage AS
+SELECT u.repo_id, r.repo, date_trunc('month', wr.created_at)::DATE AS month, u.runner_os,
+    COUNT(DISTINCT u.run_id) AS runs, COUNT(*) AS jobs, SUM(u.duration_ms) AS duration_ms, SUM(u.billable_minutes) AS billable_minutes
+FROM public.github_actions_workflow_job_usage u
+INNER JOIN public.repos r ON r.id = u.repo_id
+INNER JOIN public.github_actions_workflow_runs wr ON wr.repo_id = u.repo_id AND wr.id = u.run_id
+GROUP BY u.repo_id, r.repo, 3, u.runner_os;
+
+COMMENT ON VIEW public.github_actions_monthly_usage IS 'billable GitHub Actions minutes of each repo by month (of the run) and runner operating system';
+
+CREATE OR REPLACE VIEW public.github_actions_team_monthly_usage AS
+SELECT t.org, t.slug AS team, m.month, m.runner_os,
+    COUNT(DISTINCT m.repo_id) AS repos, SUM(m.runs) AS runs, SUM(m.jobs) AS jobs, SUM(m.billable_minutes) AS billable_minutes
+FROM public.github_actions_monthly_usage m
+INNER JOIN public.github_repo_teams t ON t.repo_id = m.repo_id AND t.permission IN ('admin', 'maintain')
+GROUP BY t.org, t.slug, m.month, m.runner_os;
+
+COMMENT ON VIEW public.github_actions_team_monthly_usage IS 'billable GitHub Actions minutes by team (the teams with admin or maintain permission on a repo, in full to each of them), month and runner operating system';
+
+COMMIT;
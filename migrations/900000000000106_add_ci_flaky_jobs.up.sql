@@ -0,0 +1,107 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('CI_FLAKY_JOBS', 'Detects flaky GitHub Actions jobs (failing and passing on the same commit, or retried) of the last 90 days, runs after the GitHub Actions sync', 'Flaky CI Jobs', 4)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.ci_flaky_jobs (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    workflow_id BIGINT NOT NULL,
+    workflow_name TEXT,
+    job_name TEXT NOT NULL,
+    executions INTEGER NOT NULL,
+    commits INTEGER NOT NULL,
+    flaky_commits INTEGER NOT NULL,
+    runs INTEGER NOT NULL,
+    retried_runs INTEGER NOT NULL,
+    flaky_commit_rate NUMERIC NOT NULL,
+    retry_rate NUMERIC NOT NULL,
+    flakiness_score NUMERIC NOT NULL,
+    last_flaky_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, workflow_id, job_name)
+);
+
+COMMENT ON TABLE public.ci_flaky_jobs IS 'GitHub Actions jobs that failed and passed on the same commit, or were retried, in the last 90 days';
+COMMENT ON COLUMN public.ci_flaky_jobs.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.ci_flaky_jobs.workflow_id IS 'id of the workflow of the job (see github_actions_workflows.id)';
+COMMENT ON COLUMN public.ci_flaky_jobs.workflow_name IS 'name of the workflow of the job';
+COMMENT ON COLUMN public.ci_flaky_jobs.job_name IS 'name of the job';
+COMMENT ON COLUMN public.ci_flaky_jobs.executions IS 'number of completed executions of the job (including retries)';
+COMMENT ON COLUMN public.ci_flaky_jobs.commits IS 'number of distinct commits the job completed on';
+COMMENT ON COLUMN public.ci_flaky_jobs.flaky_commits IS 'number of commits the job both failed and succeeded on';
+COMMENT ON COLUMN public.ci_flaky_jobs.runs IS 'number of workflow runs the job completed in';
+COMMENT ON COLUMN public.ci_flaky_jobs.retried_runs IS 'number of workflow runs the job was executed more than once in';
+COMMENT ON COLUMN public.ci_flaky_jobs.flaky_commit_rate IS 'share (0 to 1) of the commits the job both failed and succeeded on';
+COMMENT ON COLUMN public.ci_flaky_jobs.retry_rate IS 'share (0 to 1) of the runs the job was retried in';
+COMMENT ON COLUMN public.ci_flaky_jobs.flakiness_score IS 'the greater of flaky_commit_rate and retry_rate';
+COMMENT ON COLUMN public.ci_flaky_jobs.last_flaky_at IS 'timestamp of the latest execution on a flaky commit, or of a retried run';
+COMMENT ON COLUMN public.ci_flaky_jobs._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- derive_ci_flaky_jobs (re-)materializes ci_flaky_jobs for a repo.
+-- Tables are left unqualified so that the function writes into the schema the calling session resolves them to (see dry runs).
+CREATE OR REPLACE FUNCTION mergestat.derive_ci_flaky_jobs(_repo_id UUID)
+RETURNS INTEGER
+AS
+$$
+DECLARE _count INTEGER;
+BEGIN
+    DELETE FROM ci_flaky_jobs WHERE repo_id = _repo_id;
+
+    INSERT INTO ci_flaky_jobs (repo_id, workflow_id, workflow_name, job_name, executions, commits, flaky_commits, runs,
+        retried_runs, flaky_commit_rate, retry_rate, flakiness_score, last_flaky_at)
+    WITH executions AS (
+        -- the jobs table names the job in its workflow_name column
+        SELECT r.workflow_id, r.name AS workflow_name, j.workflow_name AS job_name, j.run_id, j.head_sha, j.conclusion,
+            COALESCE(j.completed_at, j.started_at) AS completed_at
+        FROM github_actions_workflow_run_jobs j
+        INNER JOIN github_actions_workflow_runs r ON r.repo_id = j.repo_id AND r.id = j.run_id
+        WHERE j.repo_id = _repo_id AND j.workflow_name IS NOT NULL AND j.status = 'completed'
+            AND j.conclusion IN ('success', 'failure') AND j.started_at >= now() - INTERVAL '90 days'
+    ),
+    by_commit AS (
+        SELECT workflow_id, job_name, head_sha,
+            bool_or(conclusion = 'success') AND bool_or(conclusion = 'failure') AS flaky, MAX(completed_at) AS completed_at
+        FROM executions WHERE head_sha IS NOT NULL GROUP BY 1, 2, 3
+    ),
+    by_run AS (
+        SELECT workflow_id, job_name, run_id, COUNT(*) > 1 AS retried, MAX(completed_at) AS completed_at
+        FROM executions GROUP BY 1, 2, 3
+    ),
+    commits AS (
+        SELECT workflow_id, job_name, COUNT(*) AS commits, COUNT(*) FILTER (WHERE flaky) AS flaky_commits,
+            MAX(completed_at) FILTER (WHERE flaky) AS last_flaky_at
+        FROM by_commit GROUP BY 1, 2
+    ),
+    runs AS (
+        SELECT workflow_id, job_name, COUNT(*) AS runs, COUNT(*) FILTER (WHERE retried) AS retried_runs,
+            MAX(completed_at) FILTER (WHERE retried) AS last_retried_at
+        FROM by_run GROUP BY 1, 2
+    ),
+    jobs AS (
+        SELECT workflow_id, MAX(workflow_name) AS workflow_name, job_name, COUNT(*) AS executions
+        FROM executions GROUP BY workflow_id, job_name
+    ),
+    rates AS (
+        SELECT j.workflow_id, j.workflow_name, j.job_name, j.executions,
+            COALESCE(c.commits, 0) AS commits, COALESCE(c.flaky_commits, 0) AS flaky_commits, r.runs, r.retried_runs,
+            CASE WHEN c.commits > 0 THEN ROUND(c.flaky_commits::NUMERIC / c.commits, 4) ELSE 0 END AS flaky_commit_rate,
+            ROUND(r.retried_runs::NUMERIC / r.runs, 4) AS retry_rate,
+            GREATEST(c.last_flaky_at, r.last_retried_at) AS last_flaky_at
+        FROM jobs j
+        INNER JOIN runs r ON r.workflow_id = j.workflow_id AND r.job_name = j.job_name
+        LEFT JOIN commits c ON c.workflow_id = j.workflow_id AND c.job_name = j.job_name
+    )
+    SELECT _repo_id, workflow_id, workflow_name, job_name, executions, commits, flaky_commits, runs, retried_runs,
+        flaky_commit_rate, retry_rate, GREATEST(flaky_commit_rate, retry_rate), last_flaky_at
+    FROM rates
+    WHERE flaky_commits > 0 OR retried_runs > 0;
+
+    GET DIAGNOSTICS _count = ROW_COUNT;
+    RETURN _count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.derive_ci_flaky_jobs(UUID) IS 'materializes the flaky GitHub Actions jobs of a repo into ci_flaky_jobs';
+
+COMMIT;
@@ -0,0 +1,153 @@
+BEGIN;
+
+-- mergestat.notifications is the outbox of the notifications the worker (or the database) raises, eg. of CI duration
+-- regressions. Each notification is stored once per (kind, repo_id, key), and announced on the mergestat_notifications
+-- channel (see LISTEN) when it's first raised, so that integrations (chat, email, etc.) can relay it. The worker relays
+-- them to the webhook at NOTIFICATIONS_WEBHOOK_URL, if set (see internal/notifications), and acknowledges them.
+CREATE TABLE IF NOT EXISTS mergestat.notifications (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    repo_id UUID REFERENCES public.repos(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}'::JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (kind, repo_id, key)
+);
+
+COMMENT ON TABLE mergestat.notifications IS 'notifications raised by syncs, relayed by the worker to the webhook at NOTIFICATIONS_WEBHOOK_URL (if set) or by integrations listening on the mergestat_notifications channel';
+COMMENT ON COLUMN mergestat.notifications.kind IS 'kind of the notification, eg. CI_DURATION_REGRESSION';
+COMMENT ON COLUMN mergestat.notifications.repo_id IS 'repo the notification is about, if any';
+COMMENT ON COLUMN mergestat.notifications.key IS 'identifies the notification among the ones of its kind and repo, so that it is only raised once';
+COMMENT ON COLUMN mergestat.notifications.subject IS 'human readable summary of the notification';
+COMMENT ON COLUMN mergestat.notifications.payload IS 'details of the notification';
+COMMENT ON COLUMN mergestat.notifications.acknowledged_at IS 'timestamp when the notification was acknowledged (eg. relayed), NULL if it was not yet';
+
+-- notify raises a notification, unless it was raised already, and announces it on the mergestat_notifications channel
+CREATE OR REPLACE FUNCTION mergestat.notify(_kind TEXT, _repo_id UUID, _key TEXT, _subject TEXT, _payload JSONB)
+RETURNS BOOLEAN
+AS
+$$
+DECLARE _id BIGINT;
+BEGIN
+    INSERT INTO mergestat.notifications (kind, repo_id, key, subject, payload)
+    VALUES (_kind, _repo_id, _key, _subject, COALESCE(_payload, '{}'::JSONB))
+    ON CONFLICT DO NOTHING
+    RETURNING id INTO _id;
+
+    IF _id IS NULL THEN
+        RETURN FALSE;
+    END IF;
+
+    PERFORM pg_notify('mergestat_notifications', json_build_object('id', _id, 'kind', _kind, 'repo_id', _repo_id, 'subject', _subject)::TEXT);
+    RETURN TRUE;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.notify(TEXT, UUID, TEXT, TEXT, JSONB) IS 'raises a notification (once per kind, repo and key), returns whether it was raised';
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('CI_DURATION_REGRESSIONS', 'Tracks the weekly p50/p95 durations of GitHub Actions workflows and notifies of regressions beyond a threshold (durationRegressionThreshold setting, percent), runs after the GitHub Actions sync', 'CI Duration Regressions', 4)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.ci_workflow_durations (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    workflow_id BIGINT NOT NULL,
+    workflow_name TEXT,
+    week DATE NOT NULL,
+    runs INTEGER NOT NULL,
+    p50_duration INTERVAL NOT NULL,
+    p95_duration INTERVAL NOT NULL,
+    baseline_p50_duration INTERVAL,
+    baseline_p95_duration INTERVAL,
+    p95_change_percent NUMERIC,
+    regressed BOOLEAN NOT NULL DEFAULT FALSE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, workflow_id, week)
+);
+
+COMMENT ON TABLE public.ci_workflow_durations IS 'weekly durations of the successful runs of GitHub Actions workflows, compared to the preceding four weeks';
+COMMENT ON COLUMN public.ci_workflow_durations.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.ci_workflow_durations.workflow_id IS 'id of the workflow (see github_actions_workflows.id)';
+COMMENT ON COLUMN public.ci_workflow_durations.workflow_name IS 'name of the workflow';
+COMMENT ON COLUMN public.ci_workflow_durations.week IS 'first day (monday) of the week the runs started in';
+COMMENT ON COLUMN public.ci_workflow_durations.runs IS 'number of successful runs';
+COMMENT ON COLUMN public.ci_workflow_durations.p50_duration IS 'median duration of the runs';
+COMMENT ON COLUMN public.ci_workflow_durations.p95_duration IS '95th percentile duration of the runs';
+COMMENT ON COLUMN public.ci_workflow_durations.baseline_p50_duration IS 'median duration of the runs of the preceding four weeks';
+COMMENT ON COLUMN public.ci_workflow_durations.baseline_p95_duration IS '95th percentile duration of the runs of the preceding four weeks';
+COMMENT ON COLUMN public.ci_workflow_durations.p95_change_percent IS 'change of the 95th percentile duration compared to the baseline, in percent';
+COMMENT ON COLUMN public.ci_workflow_durations.regressed IS 'whether the 95th percentile duration exceeded the baseline by more than the threshold';
+COMMENT ON COLUMN public.ci_workflow_durations._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- derive_ci_duration_regressions (re-)materializes ci_workflow_durations for a repo, and raises a notification for
+-- each regression of the current or the previous week (older ones aren't news, eg. on the first sync).
+-- Tables are left unqualified so that the function writes into the schema the calling session resolves them to (see dry runs).
+CREATE OR REPLACE FUNCTION mergestat.derive_ci_duration_regressions(_repo_id UUID, _threshold DOUBLE PRECISION)
+RETURNS INTEGER
+AS
+$$
+DECLARE _count INTEGER;
+DECLARE _regression RECORD;
+BEGIN
+    DELETE FROM ci_workflow_durations WHERE repo_id = _repo_id;
+
+    CREATE TEMPORARY TABLE _runs ON COMMIT DROP AS
+        SELECT workflow_id, name AS workflow_name, date_trunc('week', run_started_at)::DATE AS week,
+            updated_at - run_started_at AS duration
+        FROM github_actions_workflow_runs
+        WHERE repo_id = _repo_id AND status = 'completed' AND conclusion = 'success'
+            AND run_started_at IS NOT NULL AND updated_at >= run_started_at;
+
+    INSERT INTO ci_workflow_durations (repo_id, workflow_id, workflow_name, week, runs, p50_duration, p95_duration,
+        baseline_p50_duration, baseline_p95_duration, p95_change_percent, regressed)
+    WITH weeks AS (
+        SELECT workflow_id, MAX(workflow_name) AS workflow_name, week, COUNT(*) AS runs,
+            percentile_cont(0.5) WITHIN GROUP (ORDER BY duration) AS p50,
+            percentile_cont(0.95) WITHIN GROUP (ORDER BY duration) AS p95
+        FROM _runs GROUP BY workflow_id, week
+    ),
+    baselines AS (
+        SELECT w.workflow_id, w.week, COUNT(r.*) AS runs,
+            percentile_cont(0.5) WITHIN GROUP (ORDER BY r.duration) AS p50,
+            percentile_cont(0.95) WITHIN GROUP (ORDER BY r.duration) AS p95
+        FROM weeks w
+        INNER JOIN _runs r ON r.workflow_id = w.workflow_id AND r.week >= w.week - 28 AND r.week < w.week
+        GROUP BY w.workflow_id, w.week
+    ),
+    changes AS (
+        SELECT w.*, b.p50 AS baseline_p50, b.p95 AS baseline_p95, b.runs AS baseline_runs,
+            CASE WHEN EXTRACT(EPOCH FROM b.p95) > 0
+                THEN ROUND(((EXTRACT(EPOCH FROM w.p95) / EXTRACT(EPOCH FROM b.p95) - 1) * 100)::NUMERIC, 2)
+            END AS change
+        FROM weeks w
+        LEFT JOIN baselines b ON b.workflow_id = w.workflow_id AND b.week = w.week
+    )
+    -- too few runs make for noisy percentiles, so they're never a regression
+    SELECT _repo_id, workflow_id, workflow_name, week, runs, p50, p95, baseline_p50, baseline_p95, change,
+        COALESCE(runs >= 5 AND baseline_runs >= 5 AND change > _threshold, FALSE)
+    FROM changes;
+
+    GET DIAGNOSTICS _count = ROW_COUNT;
+
+    FOR _regression IN
+        SELECT * FROM ci_workflow_durations
+        WHERE repo_id = _repo_id AND regressed AND week >= date_trunc('week', now())::DATE - 7
+    LOOP
+        PERFORM mergestat.notify('CI_DURATION_REGRESSION', _repo_id, _regression.workflow_id || '/' || _regression.week,
+            format('p95 duration of workflow %s regressed by %s%% in the week of %s', COALESCE(_regression.workflow_name, _regression.workflow_id::TEXT), _regression.p95_change_percent, _regression.week),
+            json_build_object('workflow_id', _regression.workflow_id, 'workflow_name', _regression.workflow_name, 'week', _regression.week,
+                'p95_duration', EXTRACT(EPOCH FROM _regression.p95_duration), 'baseline_p95_duration', EXTRACT(EPOCH FROM _regression.baseline_p95_duration),
+                'p95_change_percent', _regression.p95_change_percent)::JSONB);
+    END LOOP;
+
+    DROP TABLE _runs;
+
+    RETURN _count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.derive_ci_duration_regressions(UUID, DOUBLE PRECISION) IS 'materializes the weekly durations of the workflows of a repo into ci_workflow_durations and notifies of regressions beyond the threshold (percent)';
+
+COMMIT;
@@ -0,0 +1,63 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority, type_group)
+VALUES ('GITHUB_RUNNERS', 'Retrieves the self-hosted GitHub Actions runners registered to a repo (and to its organization), and records their status over time', 'GitHub Runners', 2, 'GITHUB')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.github_runners (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL CHECK (scope IN ('REPO', 'ORG')),
+    owner TEXT NOT NULL,
+    runner_id BIGINT NOT NULL,
+    name TEXT,
+    os TEXT,
+    status TEXT,
+    busy BOOLEAN,
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, scope, runner_id)
+);
+
+COMMENT ON TABLE public.github_runners IS 'self-hosted GitHub Actions runners available to a repo, registered to the repo or to its organization';
+COMMENT ON COLUMN public.github_runners.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_runners.scope IS 'REPO if the runner is registered to the repo, ORG if to its organization';
+COMMENT ON COLUMN public.github_runners.owner IS 'login of the owner (user or organization) of the repo';
+COMMENT ON COLUMN public.github_runners.runner_id IS 'GitHub id of the runner';
+COMMENT ON COLUMN public.github_runners.name IS 'name of the runner';
+COMMENT ON COLUMN public.github_runners.os IS 'operating system of the runner';
+COMMENT ON COLUMN public.github_runners.status IS 'online or offline';
+COMMENT ON COLUMN public.github_runners.busy IS 'whether the runner was executing a job';
+COMMENT ON COLUMN public.github_runners.labels IS 'labels of the runner, that jobs select runners by';
+COMMENT ON COLUMN public.github_runners._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_runner_snapshots (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL CHECK (scope IN ('REPO', 'ORG')),
+    runner_id BIGINT NOT NULL,
+    observed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    name TEXT,
+    status TEXT,
+    busy BOOLEAN,
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, scope, runner_id, observed_at)
+);
+
+COMMENT ON TABLE public.github_runner_snapshots IS 'status of the self-hosted GitHub Actions runners available to a repo, as observed by each sync';
+COMMENT ON COLUMN public.github_runner_snapshots.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_runner_snapshots.scope IS 'REPO if the runner is registered to the repo, ORG if to its organization';
+COMMENT ON COLUMN public.github_runner_snapshots.runner_id IS 'GitHub id of the runner';
+COMMENT ON COLUMN public.github_runner_snapshots.observed_at IS 'timestamp of the sync that observed the runner';
+COMMENT ON COLUMN public.github_runner_snapshots.name IS 'name of the runner';
+COMMENT ON COLUMN public.github_runner_snapshots.status IS 'online or offline';
+COMMENT ON COLUMN public.github_runner_snapshots.busy IS 'whether the runner was executing a job';
+COMMENT ON COLUMN public.github_runner_snapshots.labels IS 'labels of the runner';
+COMMENT ON COLUMN public.github_runner_snapshots._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- runner snapshots accumulate a row per runner and sync
+INSERT INTO mergestat.data_retention_policies (table_name, timestamp_column, max_age, enabled)
+VALUES
+('github_runner_snapshots', 'observed_at', '6 months', FALSE)
+ON CONFLICT DO NOTHING;
+
+COMMIT;
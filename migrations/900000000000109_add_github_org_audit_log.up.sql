@@ -0,0 +1,48 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority, type_group)
+VALUES ('GITHUB_ORG_AUDIT_LOG', 'Retrieves the new audit log events (git events, settings and member changes) of the GitHub Enterprise Cloud organization that owns a repo', 'GitHub Org Audit Log', 3, 'GITHUB')
+ON CONFLICT DO NOTHING;
+
+-- audit log events belong to an organization rather than to a repo, and are only ever appended: events of
+-- an organization are synced once, by whichever of its repos' syncs gets to them first
+CREATE TABLE IF NOT EXISTS public.github_org_audit_log (
+    org TEXT NOT NULL,
+    document_id TEXT NOT NULL,
+    action TEXT,
+    category TEXT,
+    actor TEXT,
+    user_login TEXT,
+    repo TEXT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    entry JSONB NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (org, document_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_org_audit_log_created_at ON public.github_org_audit_log (org, created_at);
+
+COMMENT ON TABLE public.github_org_audit_log IS 'audit log events of GitHub Enterprise Cloud organizations (append-only)';
+COMMENT ON COLUMN public.github_org_audit_log.org IS 'login of the organization';
+COMMENT ON COLUMN public.github_org_audit_log.document_id IS 'GitHub id of the event';
+COMMENT ON COLUMN public.github_org_audit_log.action IS 'action performed, eg. repo.create, org.add_member or git.push';
+COMMENT ON COLUMN public.github_org_audit_log.category IS 'category of the action (the part of action before the first dot), eg. repo, org or git';
+COMMENT ON COLUMN public.github_org_audit_log.actor IS 'login of the user who performed the action';
+COMMENT ON COLUMN public.github_org_audit_log.user_login IS 'login of the user affected by the action, if any';
+COMMENT ON COLUMN public.github_org_audit_log.repo IS 'repo (owner/name) affected by the action, if any';
+COMMENT ON COLUMN public.github_org_audit_log.created_at IS 'timestamp of the event';
+COMMENT ON COLUMN public.github_org_audit_log.entry IS 'the event, as returned by the audit log api';
+COMMENT ON COLUMN public.github_org_audit_log._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS mergestat.github_audit_log_checkpoints (
+    org TEXT PRIMARY KEY,
+    last_event_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE mergestat.github_audit_log_checkpoints IS 'position up to which the audit log of each organization was synced';
+COMMENT ON COLUMN mergestat.github_audit_log_checkpoints.org IS 'login of the organization';
+COMMENT ON COLUMN mergestat.github_audit_log_checkpoints.last_event_at IS 'timestamp of the latest synced event, the next sync resumes from it';
+COMMENT ON COLUMN mergestat.github_audit_log_checkpoints.updated_at IS 'timestamp when the checkpoint last moved';
+
+COMMIT;
@@ -0,0 +1,112 @@
+BEGIN;
+
+ALTER TABLE mergestat.repo_syncs
+ADD COLUMN IF NOT EXISTS sync_interval INTERVAL;
+
+COMMENT ON COLUMN mergestat.repo_syncs.sync_interval IS 'if set, the scheduler enqueues the sync at most once per interval (eg. 1 month), otherwise as soon as the previous run completes';
+
+-- repo_lifecycle_policy is the (single row) policy that flags dormant repos and, if auto_downgrade is set,
+-- downgrades the schedule of their syncs (see mergestat.apply_repo_lifecycle_policy). It's disabled by default.
+CREATE TABLE IF NOT EXISTS mergestat.repo_lifecycle_policy (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    inactive_after INTERVAL NOT NULL DEFAULT '6 months' CHECK (inactive_after > INTERVAL '0'),
+    include_archived BOOLEAN NOT NULL DEFAULT TRUE,
+    auto_downgrade BOOLEAN NOT NULL DEFAULT FALSE,
+    downgraded_sync_interval INTERVAL NOT NULL DEFAULT '1 month' CHECK (downgraded_sync_interval > INTERVAL '0'),
+    last_applied_at TIMESTAMP WITH TIME ZONE
+);
+
+COMMENT ON TABLE mergestat.repo_lifecycle_policy IS 'policy for flagging dormant repos (and downgrading the schedule of their syncs)';
+COMMENT ON COLUMN mergestat.repo_lifecycle_policy.enabled IS 'whether the policy is applied at all';
+COMMENT ON COLUMN mergestat.repo_lifecycle_policy.inactive_after IS 'repos without commits for this long are dormant';
+COMMENT ON COLUMN mergestat.repo_lifecycle_policy.include_archived IS 'whether repos archived upstream (see github_repo_info.is_archived) are dormant';
+COMMENT ON COLUMN mergestat.repo_lifecycle_policy.auto_downgrade IS 'whether the syncs of dormant repos are downgraded to downgraded_sync_interval (and restored once they are active again)';
+COMMENT ON COLUMN mergestat.repo_lifecycle_policy.downgraded_sync_interval IS 'sync interval of the syncs of dormant repos';
+COMMENT ON COLUMN mergestat.repo_lifecycle_policy.last_applied_at IS 'timestamp when the policy was last applied';
+
+INSERT INTO mergestat.repo_lifecycle_policy (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS mergestat.dormant_repos (
+    repo_id UUID PRIMARY KEY REFERENCES public.repos(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason IN ('ARCHIVED', 'INACTIVE')),
+    last_commit_at TIMESTAMP WITH TIME ZONE,
+    flagged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    downgraded_at TIMESTAMP WITH TIME ZONE
+);
+
+COMMENT ON TABLE mergestat.dormant_repos IS 'repos flagged as dormant by the lifecycle policy';
+COMMENT ON COLUMN mergestat.dormant_repos.reason IS 'ARCHIVED if the repo is archived upstream, INACTIVE if it had no commits for the inactive_after period of the policy';
+COMMENT ON COLUMN mergestat.dormant_repos.last_commit_at IS 'timestamp of the latest commit of the repo, NULL if it has no synced commits';
+COMMENT ON COLUMN mergestat.dormant_repos.flagged_at IS 'timestamp when the repo was flagged as dormant';
+COMMENT ON COLUMN mergestat.dormant_repos.downgraded_at IS 'timestamp when the syncs of the repo were downgraded, NULL if they were not';
+
+-- apply_repo_lifecycle_policy flags dormant repos, unflags repos that became active again and (if the policy says so)
+-- downgrades (and restores) the sync interval of their syncs. Only syncs without an interval of their own are downgraded,
+-- and only the ones at the downgraded interval are restored, so intervals set by users are left alone.
+-- It returns the number of repos newly flagged or unflagged, NULL if the policy is disabled.
+CREATE OR REPLACE FUNCTION mergestat.apply_repo_lifecycle_policy()
+RETURNS INTEGER
+AS
+$$
+DECLARE _policy mergestat.repo_lifecycle_policy;
+DECLARE _flagged INTEGER;
+DECLARE _unflagged INTEGER;
+BEGIN
+    SELECT * INTO _policy FROM mergestat.repo_lifecycle_policy WHERE enabled FOR UPDATE SKIP LOCKED;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    CREATE TEMPORARY TABLE _dormant ON COMMIT DROP AS
+    WITH last_commits AS (
+        SELECT repo_id, MAX(committer_when) AS last_commit_at FROM public.git_commits GROUP BY repo_id
+    )
+    SELECT r.id AS repo_id,
+        CASE WHEN _policy.include_archived AND COALESCE(i.is_archived, FALSE) THEN 'ARCHIVED' ELSE 'INACTIVE' END AS reason,
+        c.last_commit_at
+    FROM public.repos r
+    LEFT JOIN public.github_repo_info i ON i.repo_id = r.id
+    LEFT JOIN last_commits c ON c.repo_id = r.id
+    -- repos without synced commits can't be told apart from repos that were never synced, so they're not dormant
+    WHERE (_policy.include_archived AND COALESCE(i.is_archived, FALSE))
+        OR c.last_commit_at < now() - _policy.inactive_after;
+
+    -- repos that are active again
+    WITH active AS (
+        DELETE FROM mergestat.dormant_repos d WHERE NOT EXISTS (SELECT 1 FROM _dormant WHERE _dormant.repo_id = d.repo_id)
+        RETURNING d.repo_id, d.downgraded_at
+    ), restored AS (
+        UPDATE mergestat.repo_syncs rs SET sync_interval = NULL
+        FROM active WHERE active.downgraded_at IS NOT NULL AND rs.repo_id = active.repo_id
+            AND rs.sync_interval = _policy.downgraded_sync_interval
+        RETURNING 1
+    )
+    SELECT COUNT(*) INTO _unflagged FROM active;
+
+    UPDATE mergestat.dormant_repos d SET reason = _dormant.reason, last_commit_at = _dormant.last_commit_at
+    FROM _dormant WHERE _dormant.repo_id = d.repo_id;
+
+    INSERT INTO mergestat.dormant_repos (repo_id, reason, last_commit_at)
+    SELECT repo_id, reason, last_commit_at FROM _dormant
+    ON CONFLICT (repo_id) DO NOTHING;
+
+    GET DIAGNOSTICS _flagged = ROW_COUNT;
+
+    IF _policy.auto_downgrade THEN
+        UPDATE mergestat.repo_syncs rs SET sync_interval = _policy.downgraded_sync_interval
+        FROM mergestat.dormant_repos d WHERE d.repo_id = rs.repo_id AND rs.sync_interval IS NULL;
+
+        UPDATE mergestat.dormant_repos SET downgraded_at = now() WHERE downgraded_at IS NULL;
+    END IF;
+
+    UPDATE mergestat.repo_lifecycle_policy SET last_applied_at = now();
+    DROP TABLE _dormant;
+
+    RETURN _flagged + _unflagged;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.apply_repo_lifecycle_policy() IS 'flags dormant repos (and downgrades the schedule of their syncs) per mergestat.repo_lifecycle_policy';
+
+COMMIT;
@@ -0,0 +1,74 @@
+BEGIN;
+
+ALTER TABLE mergestat.repo_sync_type_groups
+ADD COLUMN IF NOT EXISTS priority_aging_interval INTERVAL DEFAULT '1 hour' CHECK (priority_aging_interval > INTERVAL '0'),
+ADD COLUMN IF NOT EXISTS priority_aging_max_boost INTEGER NOT NULL DEFAULT 10 CHECK (priority_aging_max_boost >= 0);
+
+COMMENT ON COLUMN mergestat.repo_sync_type_groups.priority_aging_interval IS 'queued syncs of the group gain one level of priority for every interval they wait, NULL to never age';
+COMMENT ON COLUMN mergestat.repo_sync_type_groups.priority_aging_max_boost IS 'maximum number of levels of priority queued syncs of the group gain by waiting (0 to never age)';
+
+-- effective priority of a job: its priority, less a level for every aging interval of its group it's been waiting for
+-- (up to the max boost of the group), so that a steady stream of higher priority jobs doesn't starve lower priority ones
+CREATE OR REPLACE FUNCTION mergestat.repo_sync_queue_effective_priority(job mergestat.repo_sync_queue)
+RETURNS INTEGER
+LANGUAGE SQL STABLE
+AS $$
+    SELECT job.priority - COALESCE((
+        SELECT LEAST(FLOOR(EXTRACT(EPOCH FROM now() - job.created_at) / EXTRACT(EPOCH FROM g.priority_aging_interval))::INTEGER, g.priority_aging_max_boost)
+        FROM mergestat.repo_sync_type_groups g
+        WHERE g.group = job.type_group AND job.status = 'QUEUED'
+    ), 0);
+$$;
+
+COMMENT ON FUNCTION mergestat.repo_sync_queue_effective_priority(mergestat.repo_sync_queue) IS 'priority the job is dequeued by, its priority raised by the time it has been waiting, a PostGraphile computed column of repo_sync_queue';
+
+-- queue_eta orders queued jobs by their effective priority, like the dequeue does.
+-- Jobs are estimated like mergestat.repo_sync_queue_estimated_duration does, the percentiles of the sync types
+-- computed once (rather than once per job).
+CREATE OR REPLACE VIEW mergestat.queue_eta AS (
+    WITH type_durations AS (
+        SELECT p.sync_type, make_interval(secs => p.p50_seconds) AS p50 FROM mergestat.repo_sync_type_duration_percentiles(30) p
+    ), estimated AS (
+        SELECT q.*, COALESCE(mergestat.repo_sync_recent_duration(q.repo_sync_id), d.p50, INTERVAL '1 minute') AS estimated_duration,
+            mergestat.repo_sync_queue_effective_priority(q) AS effective_priority
+        FROM mergestat.repo_sync_queue q
+        INNER JOIN mergestat.repo_syncs s ON s.id = q.repo_sync_id
+        LEFT JOIN type_durations d ON d.sync_type = s.sync_type
+        WHERE q.status IN ('QUEUED', 'RUNNING')
+    ), pending AS (
+        SELECT
+            q.id,
+            q.repo_sync_id,
+            q.status,
+            q.type_group,
+            q.effective_priority AS priority,
+            q.created_at,
+            g.concurrent_syncs,
+            CASE
+                WHEN q.status = 'RUNNING' THEN GREATEST(q.estimated_duration - (now() - q.started_at), INTERVAL '0')
+                ELSE q.estimated_duration
+            END AS remaining
+        FROM estimated q
+        INNER JOIN mergestat.repo_sync_type_groups g ON g.group = q.type_group
+    ), ordered AS (
+        SELECT
+            pending.*,
+            COALESCE(SUM(remaining) OVER (
+                PARTITION BY type_group
+                ORDER BY status = 'QUEUED', priority ASC, created_at ASC, id ASC
+                ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+            ), INTERVAL '0') AS ahead
+        FROM pending
+    )
+    SELECT
+        id AS job_id,
+        repo_sync_id,
+        status,
+        type_group,
+        remaining AS estimated_remaining,
+        CASE WHEN status = 'RUNNING' THEN NULL ELSE now() + ahead / GREATEST(concurrent_syncs, 1) END AS estimated_start_at,
+        CASE WHEN status = 'RUNNING' THEN now() + remaining ELSE now() + ahead / GREATEST(concurrent_syncs, 1) + remaining END AS estimated_done_at
+    FROM ordered
+);
+
+COMMIT;
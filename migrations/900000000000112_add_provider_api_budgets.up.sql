@@ -0,0 +1,59 @@
+BEGIN;
+
+-- provider_api_budgets caps the api calls the workers make with the credential of a provider, so that they leave
+-- headroom (of the same PAT's rate limit) for other consumers. Syncs that call the api are deferred (stay queued)
+-- while the budget of their repo's provider is exhausted.
+CREATE TABLE IF NOT EXISTS mergestat.provider_api_budgets (
+    provider_id UUID PRIMARY KEY REFERENCES mergestat.providers(id) ON DELETE CASCADE,
+    max_calls_per_hour INTEGER CHECK (max_calls_per_hour > 0),
+    reserved_remaining INTEGER NOT NULL DEFAULT 0 CHECK (reserved_remaining >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE mergestat.provider_api_budgets IS 'api call budgets of providers, api-backed syncs are deferred while the budget of their provider is exhausted';
+COMMENT ON COLUMN mergestat.provider_api_budgets.provider_id IS 'provider (and so credential) the budget applies to';
+COMMENT ON COLUMN mergestat.provider_api_budgets.max_calls_per_hour IS 'maximum number of api calls the workers make per (clock) hour, NULL for no maximum';
+COMMENT ON COLUMN mergestat.provider_api_budgets.reserved_remaining IS 'api calls of the rate limit of the credential left for other consumers: syncs are deferred while the remaining rate limit (as last reported by the api) is below this, until the rate limit resets';
+
+CREATE TABLE IF NOT EXISTS mergestat.provider_api_usage (
+    provider_id UUID NOT NULL REFERENCES mergestat.providers(id) ON DELETE CASCADE,
+    hour TIMESTAMP WITH TIME ZONE NOT NULL,
+    calls BIGINT NOT NULL DEFAULT 0,
+    last_remaining INTEGER,
+    rate_limit_reset_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider_id, hour)
+);
+
+COMMENT ON TABLE mergestat.provider_api_usage IS 'api calls made by the workers with the credential of each provider, by hour';
+COMMENT ON COLUMN mergestat.provider_api_usage.hour IS 'start of the (clock) hour the calls were made in';
+COMMENT ON COLUMN mergestat.provider_api_usage.calls IS 'number of api calls made';
+COMMENT ON COLUMN mergestat.provider_api_usage.last_remaining IS 'remaining rate limit of the credential, as last reported by the api';
+COMMENT ON COLUMN mergestat.provider_api_usage.rate_limit_reset_at IS 'when the rate limit of the credential resets, as last reported by the api';
+
+ALTER TABLE mergestat.repo_sync_types
+ADD COLUMN IF NOT EXISTS uses_provider_api BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN mergestat.repo_sync_types.uses_provider_api IS 'whether syncs of the type call the api of the repo''s provider (and so are subject to its api budget)';
+
+UPDATE mergestat.repo_sync_types SET uses_provider_api = TRUE
+WHERE type LIKE 'GITHUB\_%' AND type NOT IN ('GITHUB_ISSUE_RESPONSE_TIMES', 'GITHUB_REVIEW_LOAD');
+
+-- provider_api_budget_exhausted returns whether api-backed syncs of repos of the provider should be deferred
+CREATE OR REPLACE FUNCTION mergestat.provider_api_budget_exhausted(_provider_id UUID)
+RETURNS BOOLEAN
+LANGUAGE SQL STABLE
+AS $$
+    SELECT COALESCE((
+        SELECT
+            (b.max_calls_per_hour IS NOT NULL AND COALESCE(u.calls, 0) >= b.max_calls_per_hour)
+            OR (b.reserved_remaining > 0 AND u.last_remaining < b.reserved_remaining AND COALESCE(u.rate_limit_reset_at > now(), TRUE))
+        FROM mergestat.provider_api_budgets b
+        LEFT JOIN mergestat.provider_api_usage u ON u.provider_id = b.provider_id AND u.hour = date_trunc('hour', now())
+        WHERE b.provider_id = _provider_id
+    ), FALSE);
+$$;
+
+COMMENT ON FUNCTION mergestat.provider_api_budget_exhausted(UUID) IS 'whether the api budget of the provider is exhausted for the current hour';
+
+COMMIT;
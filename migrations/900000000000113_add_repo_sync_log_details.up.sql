@@ -0,0 +1,11 @@
+BEGIN;
+
+-- details holds the structured fields of a sync log (eg. the table and number of rows of a write, the path of a file
+-- that couldn't be processed, the code of an error), so that logs can be analyzed without parsing their message
+ALTER TABLE mergestat.repo_sync_logs ADD COLUMN IF NOT EXISTS details JSONB;
+
+COMMENT ON COLUMN mergestat.repo_sync_logs.details IS 'structured fields of the log (eg. table, rows, path, error, error_code, duration_ms), NULL if it has none';
+
+CREATE INDEX IF NOT EXISTS idx_repo_sync_logs_details ON mergestat.repo_sync_logs USING GIN (details jsonb_path_ops);
+
+COMMIT;
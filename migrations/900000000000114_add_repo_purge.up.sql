@@ -0,0 +1,88 @@
+BEGIN;
+
+-- repo_data_tables is the registry of the tables (and their provenance column) holding rows produced for a repo:
+-- every column referencing public.repos(id), and the repo_id columns of the synced tables that don't declare the
+-- reference (eg. the ones created before it was standard, or the copies dry-run syncs write into in mergestat_staging).
+-- It's what purging a repo deletes from.
+-- repo_purges (below) keeps the id of purged repos, and is left out.
+CREATE OR REPLACE VIEW mergestat.repo_data_tables AS
+SELECT DISTINCT ON (cols.table_schema, cols.table_name) cols.table_schema, cols.table_name, cols.column_name, cols.is_foreign_key
+FROM (
+    SELECT ns.nspname::TEXT AS table_schema, cl.relname::TEXT AS table_name, att.attname::TEXT AS column_name, TRUE AS is_foreign_key
+    FROM pg_catalog.pg_constraint con
+    INNER JOIN pg_catalog.pg_class cl ON cl.oid = con.conrelid
+    INNER JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+    INNER JOIN pg_catalog.pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = con.conkey[1]
+    WHERE con.contype = 'f' AND con.confrelid = 'public.repos'::regclass AND array_length(con.conkey, 1) = 1
+    UNION ALL
+    SELECT c.table_schema::TEXT, c.table_name::TEXT, c.column_name::TEXT, FALSE
+    FROM information_schema.columns c
+    INNER JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+    WHERE c.table_schema IN ('public', 'mergestat', 'mergestat_staging') AND c.column_name = 'repo_id' AND c.data_type = 'uuid' AND t.table_type = 'BASE TABLE'
+) AS cols
+WHERE NOT (cols.table_schema = 'public' AND cols.table_name = 'repos') AND NOT (cols.table_schema = 'mergestat' AND cols.table_name = 'repo_purges')
+ORDER BY cols.table_schema, cols.table_name, cols.is_foreign_key DESC;
+
+COMMENT ON VIEW mergestat.repo_data_tables IS 'registry of the tables (and their provenance column) holding rows produced for a repo, see mergestat.purge_repo_rows';
+
+-- purge_repo_rows deletes (at most) _batch_size rows of the repo from a table of mergestat.repo_data_tables,
+-- and returns the number of rows deleted. Purging a repo calls it until it returns less than _batch_size,
+-- so that no single transaction (and no cascade) deletes (and locks) all the rows of the repo at once.
+CREATE OR REPLACE FUNCTION mergestat.purge_repo_rows(_repo_id UUID, _schema TEXT, _table TEXT, _column TEXT, _batch_size INTEGER)
+RETURNS INTEGER
+AS
+$$
+DECLARE _deleted INTEGER;
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM mergestat.repo_data_tables WHERE table_schema = _schema AND table_name = _table AND column_name = _column) THEN
+        RAISE EXCEPTION '%.%.% is not a registered repo data column', _schema, _table, _column;
+    END IF;
+
+    EXECUTE format('DELETE FROM %1$I.%2$I WHERE ctid IN (SELECT ctid FROM %1$I.%2$I WHERE %3$I = $1 LIMIT $2)', _schema, _table, _column)
+        USING _repo_id, _batch_size;
+    GET DIAGNOSTICS _deleted = ROW_COUNT;
+
+    RETURN _deleted;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.purge_repo_rows(UUID, TEXT, TEXT, TEXT, INTEGER) IS 'deletes a batch of the rows of a repo from a table of mergestat.repo_data_tables, returns the number of rows deleted';
+
+-- purge_repo_sync_logs deletes (at most) _batch_size sync logs of the repo. Logs reference their job rather than
+-- the repo, and are usually the largest share of the rows of a repo in the mergestat schema.
+CREATE OR REPLACE FUNCTION mergestat.purge_repo_sync_logs(_repo_id UUID, _batch_size INTEGER)
+RETURNS INTEGER
+AS
+$$
+DECLARE _deleted INTEGER;
+BEGIN
+    DELETE FROM mergestat.repo_sync_logs WHERE id IN (
+        SELECT l.id FROM mergestat.repo_sync_logs l
+        INNER JOIN mergestat.repo_sync_queue q ON q.id = l.repo_sync_queue_id
+        INNER JOIN mergestat.repo_syncs rs ON rs.id = q.repo_sync_id
+        WHERE rs.repo_id = _repo_id
+        LIMIT _batch_size
+    );
+    GET DIAGNOSTICS _deleted = ROW_COUNT;
+
+    RETURN _deleted;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.purge_repo_sync_logs(UUID, INTEGER) IS 'deletes a batch of the sync logs of a repo, returns the number of logs deleted';
+
+-- repo_purges records the repos that were purged (and how many rows were deleted), as evidence of offboarding
+CREATE TABLE IF NOT EXISTS mergestat.repo_purges (
+    repo_id UUID NOT NULL,
+    repo TEXT NOT NULL,
+    rows_deleted BIGINT NOT NULL,
+    purged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE mergestat.repo_purges IS 'repos purged, with every row produced for them, by `worker purge`';
+COMMENT ON COLUMN mergestat.repo_purges.repo_id IS 'id the purged repo had (it no longer references public.repos)';
+COMMENT ON COLUMN mergestat.repo_purges.repo IS 'url of the purged repo';
+COMMENT ON COLUMN mergestat.repo_purges.rows_deleted IS 'number of rows deleted across all the tables of mergestat.repo_data_tables, and sync logs';
+COMMENT ON COLUMN mergestat.repo_purges.purged_at IS 'timestamp when the purge completed';
+
+COMMIT;
@@ -0,0 +1,190 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS mergestat.author_erasures (
+    id SERIAL PRIMARY KEY,
+    email_hash TEXT,
+    login_hash TEXT,
+    mode TEXT NOT NULL CHECK (mode IN ('PSEUDONYMIZE', 'DELETE')),
+    rows_affected BIGINT NOT NULL,
+    erased_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK (email_hash IS NOT NULL OR login_hash IS NOT NULL)
+);
+
+COMMENT ON TABLE mergestat.author_erasures IS 'authors whose data was erased (right-to-erasure requests), also applied by every sync to the rows it writes';
+COMMENT ON COLUMN mergestat.author_erasures.email_hash IS 'email of the author, hashed with mergestat.privacy_hash (the email itself is not retained)';
+COMMENT ON COLUMN mergestat.author_erasures.login_hash IS 'login (eg. on GitHub) of the author, hashed with mergestat.privacy_hash';
+COMMENT ON COLUMN mergestat.author_erasures.mode IS 'PSEUDONYMIZE (replace the identity, and the name next to it, with a stable pseudonym) or DELETE (delete the rows)';
+COMMENT ON COLUMN mergestat.author_erasures.rows_affected IS 'number of rows pseudonymized or deleted by the erasure';
+COMMENT ON COLUMN mergestat.author_erasures.erased_at IS 'timestamp when the erasure was applied';
+
+-- identities in columns other than the text email and login columns are listed explicitly
+CREATE TABLE IF NOT EXISTS mergestat.identity_columns (
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    PRIMARY KEY (table_name, column_name)
+);
+
+COMMENT ON TABLE mergestat.identity_columns IS 'columns identifying authors other than the text email, login, *_email and *_login columns (eg. arrays of emails, or raw JSON events), erased by mergestat.erase_author and by syncs';
+COMMENT ON COLUMN mergestat.identity_columns.table_name IS 'name of the table, in the public schema';
+COMMENT ON COLUMN mergestat.identity_columns.column_name IS 'name of the column: a text column holding an email or a login, an array of them, or a jsonb value any string of which may be one';
+
+INSERT INTO mergestat.identity_columns (table_name, column_name) VALUES
+('git_bus_factor', 'authors'),
+('github_org_audit_log', 'actor'),
+('github_org_audit_log', 'entry')
+ON CONFLICT DO NOTHING;
+
+-- mergestat.erasure_pseudonym is what the identity with the given hash (see mergestat.privacy_hash) is replaced with
+CREATE OR REPLACE FUNCTION mergestat.erasure_pseudonym(_hash TEXT)
+RETURNS TEXT
+AS $$
+    SELECT 'erased-' || substr(_hash, 8, 12)
+$$ LANGUAGE SQL IMMUTABLE;
+
+COMMENT ON FUNCTION mergestat.erasure_pseudonym(TEXT) IS 'pseudonym of an erased author, given the hash of their email or login';
+
+-- mergestat.erase_identity pseudonymizes (or deletes) the identity (an email or a login, given as-is and hashed) in a
+-- column listed in mergestat.identity_columns of the (qualified) table: text values are replaced (or their rows
+-- deleted), the matching elements of arrays are replaced (or removed), and so are the matching strings of jsonb values
+-- (or their rows deleted)
+CREATE OR REPLACE FUNCTION mergestat.erase_identity(_table TEXT, _column TEXT, _data_type TEXT, _value TEXT, _hash TEXT, _replacement TEXT, _mode TEXT)
+RETURNS BIGINT
+AS
+$$
+DECLARE
+    _count BIGINT;
+BEGIN
+    IF _data_type = 'ARRAY' AND _mode = 'DELETE' THEN
+        EXECUTE format('UPDATE %1$s SET %2$I = ARRAY(SELECT a FROM unnest(%2$I) WITH ORDINALITY u(a, i) WHERE (lower(a) = lower($1) OR a = $2) IS NOT TRUE ORDER BY i)
+            WHERE EXISTS (SELECT 1 FROM unnest(%2$I) a WHERE lower(a) = lower($1) OR a = $2)', _table, _column) USING _value, _hash;
+    ELSIF _data_type = 'ARRAY' THEN
+        EXECUTE format('UPDATE %1$s SET %2$I = ARRAY(SELECT CASE WHEN lower(a) = lower($1) OR a = $2 THEN $3 ELSE a END FROM unnest(%2$I) WITH ORDINALITY u(a, i) ORDER BY i)
+            WHERE EXISTS (SELECT 1 FROM unnest(%2$I) a WHERE lower(a) = lower($1) OR a = $2)', _table, _column) USING _value, _hash, _replacement;
+    ELSIF _data_type = 'jsonb' AND _mode = 'DELETE' THEN
+        EXECUTE format('DELETE FROM %1$s WHERE EXISTS (SELECT 1 FROM jsonb_path_query(%2$I, ''strict $.** ? (@.type() == "string")'') v
+            WHERE lower(v #>> ''{}'') = lower($1) OR v #>> ''{}'' = $2)', _table, _column) USING _value, _hash;
+    ELSIF _data_type = 'jsonb' THEN
+        -- the strings are matched as a whole, case insensitively, in the text of the value
+        EXECUTE format('UPDATE %1$s SET %2$I = regexp_replace(%2$I::TEXT, $3, $4, ''gi'')::JSONB WHERE EXISTS (SELECT 1
+            FROM jsonb_path_query(%2$I, ''strict $.** ? (@.type() == "string")'') v WHERE lower(v #>> ''{}'') = lower($1) OR v #>> ''{}'' = $2)', _table, _column)
+            USING _value, _hash, '"' || regexp_replace(_value, '(\W)', '\\\1', 'g') || '"', '"' || _replacement || '"';
+    ELSIF _mode = 'DELETE' THEN
+        EXECUTE format('DELETE FROM %1$s WHERE lower(%2$I) = lower($1) OR %2$I = $2', _table, _column) USING _value, _hash;
+    ELSE
+        EXECUTE format('UPDATE %1$s SET %2$I = $3 WHERE lower(%2$I) = lower($1) OR %2$I = $2', _table, _column) USING _value, _hash, _replacement;
+    END IF;
+
+    GET DIAGNOSTICS _count = ROW_COUNT;
+    RETURN _count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.erase_identity(TEXT, TEXT, TEXT, TEXT, TEXT, TEXT, TEXT) IS 'pseudonymizes (or deletes) an identity in a column listed in mergestat.identity_columns, returns the number of rows affected';
+
+-- mergestat.erase_author pseudonymizes (or deletes) every row of the public tables identifying the author, by email
+-- (email and *_email columns) or by login (login and *_login columns), and records the erasure so that syncs apply it
+-- to the rows they write from then on. Values hashed by the privacy settings are matched too. When deleting, the rows
+-- keyed by the author's commits (eg. git_commit_stats, which carry no identity) are deleted as well. Identities in
+-- other columns (see mergestat.identity_columns) are erased by mergestat.erase_identity.
+CREATE OR REPLACE FUNCTION mergestat.erase_author(_email TEXT, _login TEXT, _mode TEXT)
+RETURNS BIGINT
+AS
+$$
+DECLARE
+    _salt TEXT;
+    _email_hash TEXT;
+    _login_hash TEXT;
+    _value TEXT;
+    _hash TEXT;
+    _replacement TEXT;
+    _column RECORD;
+    _count BIGINT;
+    _total BIGINT := 0;
+BEGIN
+    IF _mode NOT IN ('PSEUDONYMIZE', 'DELETE') THEN
+        RAISE EXCEPTION 'unknown erasure mode %, expected PSEUDONYMIZE or DELETE', _mode;
+    END IF;
+
+    SELECT hash_salt INTO _salt FROM mergestat.privacy_settings WHERE id;
+    IF coalesce(_email, '') <> '' THEN
+        _email_hash := mergestat.privacy_hash(_email, coalesce(_salt, ''));
+    END IF;
+    IF coalesce(_login, '') <> '' THEN
+        _login_hash := mergestat.privacy_hash(_login, coalesce(_salt, ''));
+    END IF;
+    IF _email_hash IS NULL AND _login_hash IS NULL THEN
+        RAISE EXCEPTION 'an email or a login is required';
+    END IF;
+
+    IF _mode = 'DELETE' AND _email_hash IS NOT NULL THEN
+        FOR _column IN
+            SELECT c.table_name FROM information_schema.columns c
+                INNER JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+            WHERE c.table_schema = 'public' AND t.table_type = 'BASE TABLE' AND c.column_name = 'commit_hash'
+                AND EXISTS (SELECT 1 FROM information_schema.columns r WHERE r.table_schema = 'public' AND r.table_name = c.table_name AND r.column_name = 'repo_id')
+        LOOP
+            EXECUTE format('DELETE FROM public.%I t USING public.git_commits c WHERE c.repo_id = t.repo_id AND c.hash = t.commit_hash AND (lower(c.author_email) = lower($1) OR c.author_email = $2)',
+                _column.table_name) USING _email, _email_hash;
+
+            GET DIAGNOSTICS _count = ROW_COUNT;
+            _total := _total + _count;
+        END LOOP;
+    END IF;
+
+    FOR _column IN
+        SELECT c.table_name, c.column_name, (c.column_name = 'login' OR c.column_name LIKE '%\_login') AS is_login, n.column_name AS name_column
+            FROM information_schema.columns c
+            INNER JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+            LEFT JOIN information_schema.columns n ON n.table_schema = c.table_schema AND n.table_name = c.table_name
+                AND n.column_name = regexp_replace(c.column_name, '(email|login)$', 'name') AND n.data_type = 'text'
+        WHERE c.table_schema = 'public' AND t.table_type = 'BASE TABLE' AND c.data_type = 'text'
+            AND (c.column_name IN ('email', 'login') OR c.column_name LIKE '%\_email' OR c.column_name LIKE '%\_login')
+    LOOP
+        IF _column.is_login THEN
+            _value := _login; _hash := _login_hash; _replacement := mergestat.erasure_pseudonym(_login_hash);
+        ELSE
+            _value := _email; _hash := _email_hash; _replacement := mergestat.erasure_pseudonym(_email_hash) || '@erased.invalid';
+        END IF;
+
+        IF _hash IS NULL THEN
+            CONTINUE;
+        END IF;
+
+        IF _mode = 'DELETE' THEN
+            EXECUTE format('DELETE FROM public.%I WHERE lower(%2$I) = lower($1) OR %2$I = $2', _column.table_name, _column.column_name)
+                USING _value, _hash;
+        ELSIF _column.name_column IS NOT NULL THEN
+            EXECUTE format('UPDATE public.%I SET %2$I = $3, %3$I = $4 WHERE lower(%2$I) = lower($1) OR %2$I = $2', _column.table_name, _column.column_name, _column.name_column)
+                USING _value, _hash, _replacement, mergestat.erasure_pseudonym(_hash);
+        ELSE
+            EXECUTE format('UPDATE public.%I SET %2$I = $3 WHERE lower(%2$I) = lower($1) OR %2$I = $2', _column.table_name, _column.column_name)
+                USING _value, _hash, _replacement;
+        END IF;
+
+        GET DIAGNOSTICS _count = ROW_COUNT;
+        _total := _total + _count;
+    END LOOP;
+
+    FOR _column IN
+        SELECT i.table_name, i.column_name, c.data_type FROM mergestat.identity_columns i
+            INNER JOIN information_schema.columns c ON c.table_schema = 'public' AND c.table_name = i.table_name AND c.column_name = i.column_name
+    LOOP
+        IF _email_hash IS NOT NULL THEN
+            _total := _total + mergestat.erase_identity(format('public.%I', _column.table_name), _column.column_name, _column.data_type,
+                _email, _email_hash, mergestat.erasure_pseudonym(_email_hash) || '@erased.invalid', _mode);
+        END IF;
+        IF _login_hash IS NOT NULL THEN
+            _total := _total + mergestat.erase_identity(format('public.%I', _column.table_name), _column.column_name, _column.data_type,
+                _login, _login_hash, mergestat.erasure_pseudonym(_login_hash), _mode);
+        END IF;
+    END LOOP;
+
+    INSERT INTO mergestat.author_erasures (email_hash, login_hash, mode, rows_affected) VALUES (_email_hash, _login_hash, _mode, _total);
+
+    RETURN _total;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.erase_author(TEXT, TEXT, TEXT) IS 'pseudonymizes (or deletes) the rows of the public tables identifying an author by email or login, returns the number of rows affected';
+
+COMMIT;
@@ -0,0 +1,37 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS mergestat.encrypted_columns (
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (table_name, column_name),
+    -- identities (emails and logins) are matched by value by erasures (see mergestat.erase_author), which encrypted
+    -- values (with a random nonce) never match: hash them with the privacy settings instead
+    CONSTRAINT encrypted_columns_not_identity CHECK (column_name NOT IN ('email', 'login')
+        AND column_name NOT LIKE '%\_email' AND column_name NOT LIKE '%\_login')
+);
+
+-- columns of a key (primary key or unique index) can't be encrypted either: encrypting the same value twice gives
+-- different results, so upserts (ON CONFLICT) would never match the rows synced before
+CREATE OR REPLACE FUNCTION mergestat.check_encrypted_column() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM pg_index i JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+        WHERE i.indrelid = to_regclass(format('public.%I', NEW.table_name)) AND (i.indisprimary OR i.indisunique)
+            AND a.attname = NEW.column_name
+    ) THEN
+        RAISE EXCEPTION 'column % of % is part of a key of the table, and can''t be encrypted', NEW.column_name, NEW.table_name;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER check_encrypted_column BEFORE INSERT OR UPDATE ON mergestat.encrypted_columns
+    FOR EACH ROW EXECUTE FUNCTION mergestat.check_encrypted_column();
+
+COMMENT ON TABLE mergestat.encrypted_columns IS 'text columns of the public tables the worker encrypts at rest (AES-256-GCM, with the key in COLUMN_ENCRYPTION_KEY) as rows are synced';
+COMMENT ON COLUMN mergestat.encrypted_columns.table_name IS 'name of the table, in the public schema';
+COMMENT ON COLUMN mergestat.encrypted_columns.column_name IS 'name of the (text) column, whose values are stored prefixed with enc:v1:, identity columns (email, login, *_email, *_login) and the columns of keys can''t be encrypted';
+COMMENT ON COLUMN mergestat.encrypted_columns.created_at IS 'timestamp when the column was configured to be encrypted, rows synced before are encrypted by `worker encrypt`';
+
+COMMIT;
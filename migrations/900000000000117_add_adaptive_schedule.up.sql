@@ -0,0 +1,98 @@
+BEGIN;
+
+ALTER TABLE mergestat.repo_syncs
+ADD COLUMN IF NOT EXISTS adaptive_sync_interval INTERVAL;
+
+COMMENT ON COLUMN mergestat.repo_syncs.adaptive_sync_interval IS 'interval set by the adaptive schedule (see mergestat.apply_adaptive_schedule), used when sync_interval is not set';
+
+-- adaptive_schedule is the (single row) policy that sets the interval of syncs by the activity of their repo:
+-- syncs of repos pushed to recently run more often, the ones of dormant repos less often. It's disabled by default.
+CREATE TABLE IF NOT EXISTS mergestat.adaptive_schedule (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    active_within INTERVAL NOT NULL DEFAULT '1 day' CHECK (active_within > INTERVAL '0'),
+    active_interval INTERVAL NOT NULL DEFAULT '1 hour' CHECK (active_interval > INTERVAL '0'),
+    default_interval INTERVAL NOT NULL DEFAULT '6 hours' CHECK (default_interval > INTERVAL '0'),
+    dormant_after INTERVAL NOT NULL DEFAULT '30 days' CHECK (dormant_after > INTERVAL '0'),
+    dormant_interval INTERVAL NOT NULL DEFAULT '1 week' CHECK (dormant_interval > INTERVAL '0'),
+    probe_sync_types TEXT[] NOT NULL DEFAULT '{GITHUB_REPO_METADATA}',
+    apply_every INTERVAL NOT NULL DEFAULT '15 minutes' CHECK (apply_every >= INTERVAL '0'),
+    last_applied_at TIMESTAMP WITH TIME ZONE
+);
+
+COMMENT ON TABLE mergestat.adaptive_schedule IS 'policy setting the interval of syncs by the activity (latest push) of their repo';
+COMMENT ON COLUMN mergestat.adaptive_schedule.enabled IS 'whether the policy is applied at all, the adaptive intervals of syncs are cleared when it is disabled';
+COMMENT ON COLUMN mergestat.adaptive_schedule.active_within IS 'repos pushed to within this period are active';
+COMMENT ON COLUMN mergestat.adaptive_schedule.active_interval IS 'sync interval of the syncs of active repos';
+COMMENT ON COLUMN mergestat.adaptive_schedule.default_interval IS 'sync interval of the syncs of repos neither active nor dormant (and of repos whose activity is unknown)';
+COMMENT ON COLUMN mergestat.adaptive_schedule.dormant_after IS 'repos not pushed to for this long are dormant';
+COMMENT ON COLUMN mergestat.adaptive_schedule.dormant_interval IS 'sync interval of the syncs of dormant repos';
+COMMENT ON COLUMN mergestat.adaptive_schedule.probe_sync_types IS 'sync types that detect pushes (eg. GITHUB_REPO_METADATA, which syncs pushed_at), never scheduled less often than default_interval';
+COMMENT ON COLUMN mergestat.adaptive_schedule.apply_every IS 'the policy is applied at most this often, rather than on every tick of the scheduler, as the activity of repos changes slowly';
+COMMENT ON COLUMN mergestat.adaptive_schedule.last_applied_at IS 'timestamp when the policy was last applied';
+
+INSERT INTO mergestat.adaptive_schedule (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+-- repo_activity is the latest push to each repo: the pushed_at reported by GitHub (see GITHUB_REPO_METADATA)
+-- or, for repos without it, the latest synced commit (looked up by repo, in the index below, rather than aggregated
+-- over all the commits)
+CREATE INDEX IF NOT EXISTS idx_git_commits_repo_id_committer_when ON public.git_commits (repo_id, committer_when);
+
+CREATE OR REPLACE VIEW mergestat.repo_activity AS
+SELECT r.id AS repo_id, GREATEST(i.pushed_at, c.last_commit_at) AS last_pushed_at
+FROM public.repos r
+LEFT JOIN public.github_repo_info i ON i.repo_id = r.id
+LEFT JOIN LATERAL (SELECT MAX(gc.committer_when) AS last_commit_at FROM public.git_commits gc WHERE gc.repo_id = r.id) c ON TRUE;
+
+COMMENT ON VIEW mergestat.repo_activity IS 'latest push to each repo, from GitHub metadata or the latest synced commit (NULL if unknown)';
+
+-- apply_adaptive_schedule sets the adaptive interval of every sync by the activity of its repo, per
+-- mergestat.adaptive_schedule. Intervals set by users (sync_interval) take precedence and are left alone.
+-- It's applied at most every apply_every. It returns the number of syncs whose interval changed, NULL if the
+-- policy is disabled.
+CREATE OR REPLACE FUNCTION mergestat.apply_adaptive_schedule()
+RETURNS INTEGER
+AS
+$$
+DECLARE _policy mergestat.adaptive_schedule;
+DECLARE _changed INTEGER;
+BEGIN
+    SELECT * INTO _policy FROM mergestat.adaptive_schedule WHERE enabled FOR UPDATE SKIP LOCKED;
+    IF NOT FOUND THEN
+        -- a disabled policy no longer drives the schedule
+        IF NOT EXISTS (SELECT 1 FROM mergestat.adaptive_schedule WHERE enabled) THEN
+            UPDATE mergestat.repo_syncs SET adaptive_sync_interval = NULL WHERE adaptive_sync_interval IS NOT NULL;
+        END IF;
+        RETURN NULL;
+    END IF;
+
+    IF _policy.last_applied_at > now() - _policy.apply_every THEN
+        RETURN 0;
+    END IF;
+
+    WITH intervals AS (
+        SELECT rs.id,
+            CASE
+                WHEN a.last_pushed_at >= now() - _policy.active_within THEN _policy.active_interval
+                WHEN a.last_pushed_at < now() - _policy.dormant_after AND rs.sync_type = ANY(_policy.probe_sync_types)
+                    THEN LEAST(_policy.dormant_interval, _policy.default_interval)
+                WHEN a.last_pushed_at < now() - _policy.dormant_after THEN _policy.dormant_interval
+                ELSE _policy.default_interval
+            END AS adaptive_sync_interval
+        FROM mergestat.repo_syncs rs
+        INNER JOIN mergestat.repo_activity a ON a.repo_id = rs.repo_id
+    )
+    UPDATE mergestat.repo_syncs rs SET adaptive_sync_interval = intervals.adaptive_sync_interval
+    FROM intervals WHERE intervals.id = rs.id AND rs.adaptive_sync_interval IS DISTINCT FROM intervals.adaptive_sync_interval;
+
+    GET DIAGNOSTICS _changed = ROW_COUNT;
+
+    UPDATE mergestat.adaptive_schedule SET last_applied_at = now();
+
+    RETURN _changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.apply_adaptive_schedule() IS 'sets the adaptive interval of syncs by the activity of their repo per mergestat.adaptive_schedule';
+
+COMMIT;
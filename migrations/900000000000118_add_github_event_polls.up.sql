@@ -0,0 +1,81 @@
+BEGIN;
+
+-- github_event_polls is the state of the polling of the GitHub Events API, by owner (organization or user) of synced repos: the events
+-- the authenticated user receives of organizations they're a member of, or else those of each synced repo of the owner
+CREATE TABLE IF NOT EXISTS mergestat.github_event_polls (
+    provider UUID NOT NULL REFERENCES mergestat.providers(id) ON DELETE CASCADE,
+    owner TEXT NOT NULL,
+    etag TEXT,
+    last_event_id BIGINT,
+    poll_interval INTEGER NOT NULL DEFAULT 60,
+    polled_at TIMESTAMP WITH TIME ZONE,
+    events_seen BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    PRIMARY KEY (provider, owner)
+);
+
+COMMENT ON TABLE mergestat.github_event_polls IS 'state of the polling of the GitHub Events API, by owner of synced repos (see mergestat.github_event_sync_types)';
+COMMENT ON COLUMN mergestat.github_event_polls.owner IS 'organization or user whose events are polled';
+COMMENT ON COLUMN mergestat.github_event_polls.etag IS 'etag of the latest response (of the events of an organization), requests with it do not count against the rate limit when there are no new events';
+COMMENT ON COLUMN mergestat.github_event_polls.last_event_id IS 'id of the latest event handled';
+COMMENT ON COLUMN mergestat.github_event_polls.poll_interval IS 'seconds between polls, as requested by GitHub (X-Poll-Interval)';
+COMMENT ON COLUMN mergestat.github_event_polls.polled_at IS 'timestamp of the latest poll';
+COMMENT ON COLUMN mergestat.github_event_polls.events_seen IS 'number of events handled';
+COMMENT ON COLUMN mergestat.github_event_polls.last_error IS 'error of the latest poll, NULL if it succeeded';
+
+-- github_event_sync_types maps the types of GitHub events to the syncs they make stale
+CREATE TABLE IF NOT EXISTS mergestat.github_event_sync_types (
+    event_type TEXT NOT NULL,
+    sync_type TEXT NOT NULL REFERENCES mergestat.repo_sync_types(type) ON DELETE CASCADE,
+    PRIMARY KEY (event_type, sync_type)
+);
+
+COMMENT ON TABLE mergestat.github_event_sync_types IS 'syncs enqueued (with a high priority) when an event of the type is seen on a repo';
+COMMENT ON COLUMN mergestat.github_event_sync_types.event_type IS 'type of the event, eg. PushEvent (see https://docs.github.com/en/rest/using-the-rest-api/github-event-types)';
+COMMENT ON COLUMN mergestat.github_event_sync_types.sync_type IS 'type of the sync to enqueue, if enabled for the repo';
+
+INSERT INTO mergestat.github_event_sync_types (event_type, sync_type)
+SELECT e.event_type, e.sync_type FROM (VALUES
+    ('PushEvent', 'GIT_COMMITS'),
+    ('PushEvent', 'GIT_COMMIT_STATS'),
+    ('PushEvent', 'GIT_FILES'),
+    ('PushEvent', 'GIT_REFS'),
+    ('PushEvent', 'GIT_BLAME'),
+    ('CreateEvent', 'GIT_REFS'),
+    ('DeleteEvent', 'GIT_REFS'),
+    ('PullRequestEvent', 'GITHUB_REPO_PRS'),
+    ('PullRequestEvent', 'GITHUB_PR_COMMITS'),
+    ('PullRequestReviewEvent', 'GITHUB_PR_REVIEWS'),
+    ('IssuesEvent', 'GITHUB_REPO_ISSUES'),
+    ('IssueCommentEvent', 'GITHUB_REPO_ISSUES'),
+    ('ReleaseEvent', 'RELEASE_CHANGELOGS'),
+    ('WatchEvent', 'GITHUB_REPO_STARS')
+) AS e(event_type, sync_type)
+INNER JOIN mergestat.repo_sync_types t ON t.type = e.sync_type
+ON CONFLICT DO NOTHING;
+
+-- enqueue_github_event_syncs enqueues, with the highest priority, the enabled syncs of the repo made stale by an event
+-- of the given type (see mergestat.github_event_sync_types) that aren't already queued or running.
+-- It returns the number of syncs enqueued.
+CREATE OR REPLACE FUNCTION mergestat.enqueue_github_event_syncs(_repo_id UUID, _event_type TEXT)
+RETURNS INTEGER
+AS
+$$
+DECLARE _enqueued INTEGER;
+BEGIN
+    INSERT INTO mergestat.repo_sync_queue (repo_sync_id, status, priority, type_group)
+    SELECT rs.id, 'QUEUED', 0, rst.type_group
+    FROM mergestat.repo_syncs rs
+    INNER JOIN mergestat.repo_sync_types rst ON rst.type = rs.sync_type
+    INNER JOIN mergestat.github_event_sync_types e ON e.sync_type = rs.sync_type AND e.event_type = _event_type
+    WHERE rs.repo_id = _repo_id AND rs.schedule_enabled
+        AND NOT EXISTS (SELECT 1 FROM mergestat.repo_sync_queue q WHERE q.repo_sync_id = rs.id AND q.status IN ('QUEUED', 'RUNNING'));
+
+    GET DIAGNOSTICS _enqueued = ROW_COUNT;
+    RETURN _enqueued;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.enqueue_github_event_syncs(UUID, TEXT) IS 'enqueues, with the highest priority, the enabled syncs of a repo made stale by a GitHub event of the given type';
+
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GIT_MIRROR', 'Full bare mirror of a repo, kept up to date in the storage location of the workers (GIT_MIRROR_PATH)', 'Git Mirror', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_mirrors (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    head_commit_hash TEXT,
+    refs INTEGER NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id)
+);
+
+COMMENT ON TABLE public.git_mirrors IS 'bare mirrors (every ref) of repos, maintained by GIT_MIRROR syncs for offline analysis';
+COMMENT ON COLUMN public.git_mirrors.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_mirrors.path IS 'path of the mirror, on the storage of the worker (under GIT_MIRROR_PATH)';
+COMMENT ON COLUMN public.git_mirrors.head_commit_hash IS 'hash of the commit HEAD of the mirror points to';
+COMMENT ON COLUMN public.git_mirrors.refs IS 'number of refs of the mirror';
+COMMENT ON COLUMN public.git_mirrors.size IS 'size of the mirror on disk in bytes';
+COMMENT ON COLUMN public.git_mirrors.created_at IS 'timestamp when the mirror was cloned';
+COMMENT ON COLUMN public.git_mirrors.fetched_at IS 'timestamp when the mirror was last fetched into';
+COMMENT ON COLUMN public.git_mirrors._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
@@ -0,0 +1,27 @@
+BEGIN;
+
+-- query_packs are the maintained libraries of SQL views shipped with the worker (see internal/querypacks), each
+-- installed in its own schema (pack_<name>), upgraded when the worker ships a newer version, and optionally
+-- refreshed into tables (<view>_snapshot) on a schedule. Packs are registered by the workers, and enabled by default.
+CREATE TABLE IF NOT EXISTS mergestat.query_packs (
+    name TEXT PRIMARY KEY,
+    description TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    version INTEGER,
+    refresh_interval INTERVAL CHECK (refresh_interval > INTERVAL '0'),
+    installed_at TIMESTAMP WITH TIME ZONE,
+    last_refreshed_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT
+);
+
+COMMENT ON TABLE mergestat.query_packs IS 'query packs (libraries of views, eg. DORA metrics) shipped with the worker, and their installation in the database';
+COMMENT ON COLUMN mergestat.query_packs.name IS 'name of the pack, installed in the pack_<name> schema';
+COMMENT ON COLUMN mergestat.query_packs.description IS 'description of the pack';
+COMMENT ON COLUMN mergestat.query_packs.enabled IS 'whether the pack is installed, its schema (and everything depending on it) is dropped when it is disabled';
+COMMENT ON COLUMN mergestat.query_packs.version IS 'installed version of the pack (NULL when not installed), the pack is reinstalled when a worker ships a newer version';
+COMMENT ON COLUMN mergestat.query_packs.refresh_interval IS 'interval the views of the pack are refreshed into snapshot tables (<view>_snapshot) at, NULL to never refresh them';
+COMMENT ON COLUMN mergestat.query_packs.installed_at IS 'timestamp when the installed version of the pack was installed';
+COMMENT ON COLUMN mergestat.query_packs.last_refreshed_at IS 'timestamp when the snapshot tables of the pack were last refreshed';
+COMMENT ON COLUMN mergestat.query_packs.last_error IS 'error of the last install or refresh of the pack, NULL if it succeeded';
+
+COMMIT;
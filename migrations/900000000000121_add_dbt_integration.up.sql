@@ -0,0 +1,34 @@
+BEGIN;
+
+-- dbt_integration is the (single row) configuration of the coordination with dbt: once a sync cycle completes (the
+-- queue drained after some syncs ran), the workers write the dbt source declaring the synced tables (DBT_SOURCES_PATH)
+-- and run the configured dbt Cloud job (DBT_CLOUD_API_TOKEN). It's disabled by default.
+CREATE TABLE IF NOT EXISTS mergestat.dbt_integration (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    source_name TEXT NOT NULL DEFAULT 'mergestat',
+    warn_after INTERVAL NOT NULL DEFAULT '12 hours' CHECK (warn_after > INTERVAL '0'),
+    error_after INTERVAL NOT NULL DEFAULT '24 hours' CHECK (error_after > INTERVAL '0'),
+    cloud_url TEXT NOT NULL DEFAULT 'https://cloud.getdbt.com',
+    cloud_account_id BIGINT,
+    cloud_job_id BIGINT,
+    last_cycle_at TIMESTAMP WITH TIME ZONE,
+    last_run_id BIGINT,
+    last_error TEXT
+);
+
+COMMENT ON TABLE mergestat.dbt_integration IS 'coordination of dbt with the syncs: the dbt source written, and the dbt Cloud job run, once a sync cycle completes';
+COMMENT ON COLUMN mergestat.dbt_integration.enabled IS 'whether the integration runs at all';
+COMMENT ON COLUMN mergestat.dbt_integration.source_name IS 'name of the dbt source declaring the synced tables, as used in source() by dbt models';
+COMMENT ON COLUMN mergestat.dbt_integration.warn_after IS 'dbt warns about a synced table when its rows were all synced longer ago than this (freshness)';
+COMMENT ON COLUMN mergestat.dbt_integration.error_after IS 'dbt errors about a synced table when its rows were all synced longer ago than this (freshness)';
+COMMENT ON COLUMN mergestat.dbt_integration.cloud_url IS 'base url of the dbt Cloud API';
+COMMENT ON COLUMN mergestat.dbt_integration.cloud_account_id IS 'id of the dbt Cloud account of the job';
+COMMENT ON COLUMN mergestat.dbt_integration.cloud_job_id IS 'id of the dbt Cloud job run after each sync cycle, NULL to not run any';
+COMMENT ON COLUMN mergestat.dbt_integration.last_cycle_at IS 'timestamp when the last sync cycle completed';
+COMMENT ON COLUMN mergestat.dbt_integration.last_run_id IS 'id of the dbt Cloud run triggered after the last sync cycle';
+COMMENT ON COLUMN mergestat.dbt_integration.last_error IS 'error of the integration after the last sync cycle, NULL if it succeeded';
+
+INSERT INTO mergestat.dbt_integration (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,65 @@
+BEGIN;
+
+-- maintenance_mode is the (single row) switch pausing all the workers, eg. for a maintenance window of the database:
+-- while it's enabled, workers don't dequeue jobs (and finish the ones in flight) and the scheduler doesn't enqueue any.
+CREATE TABLE IF NOT EXISTS mergestat.maintenance_mode (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    reason TEXT,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    enabled_by TEXT
+);
+
+COMMENT ON TABLE mergestat.maintenance_mode IS 'switch pausing all the workers (no job is dequeued nor enqueued while it is enabled), eg. for a maintenance window of the database';
+COMMENT ON COLUMN mergestat.maintenance_mode.enabled IS 'whether the maintenance mode is enabled';
+COMMENT ON COLUMN mergestat.maintenance_mode.reason IS 'why the maintenance mode is enabled, reported by the health endpoints of the workers';
+COMMENT ON COLUMN mergestat.maintenance_mode.enabled_at IS 'timestamp when the maintenance mode was enabled';
+COMMENT ON COLUMN mergestat.maintenance_mode.enabled_by IS 'database user who enabled the maintenance mode';
+
+INSERT INTO mergestat.maintenance_mode (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+CREATE OR REPLACE FUNCTION mergestat.maintenance_mode_enabled()
+RETURNS BOOLEAN
+LANGUAGE SQL STABLE
+AS $$
+    SELECT COALESCE((SELECT enabled FROM mergestat.maintenance_mode), FALSE);
+$$;
+
+COMMENT ON FUNCTION mergestat.maintenance_mode_enabled() IS 'whether the maintenance mode is enabled (and so jobs are not dequeued)';
+
+CREATE OR REPLACE FUNCTION mergestat.enable_maintenance_mode(reason TEXT DEFAULT NULL)
+RETURNS mergestat.maintenance_mode
+LANGUAGE SQL VOLATILE
+AS $$
+    UPDATE mergestat.maintenance_mode
+    SET enabled = TRUE, reason = enable_maintenance_mode.reason, enabled_at = COALESCE(CASE WHEN enabled THEN enabled_at END, now()), enabled_by = current_user
+    RETURNING *;
+$$;
+
+COMMENT ON FUNCTION mergestat.enable_maintenance_mode(TEXT) IS 'enables the maintenance mode: workers finish the jobs in flight, and stop dequeuing';
+
+CREATE OR REPLACE FUNCTION mergestat.disable_maintenance_mode()
+RETURNS mergestat.maintenance_mode
+LANGUAGE SQL VOLATILE
+AS $$
+    UPDATE mergestat.maintenance_mode SET enabled = FALSE, reason = NULL, enabled_at = NULL, enabled_by = NULL RETURNING *;
+$$;
+
+COMMENT ON FUNCTION mergestat.disable_maintenance_mode() IS 'disables the maintenance mode: workers resume dequeuing';
+
+-- only admins toggle the maintenance mode (through the api, the functions are mutations)
+REVOKE EXECUTE ON FUNCTION mergestat.enable_maintenance_mode(TEXT) FROM PUBLIC;
+REVOKE EXECUTE ON FUNCTION mergestat.disable_maintenance_mode() FROM PUBLIC;
+GRANT EXECUTE ON FUNCTION mergestat.enable_maintenance_mode(TEXT) TO mergestat_role_admin;
+GRANT EXECUTE ON FUNCTION mergestat.disable_maintenance_mode() TO mergestat_role_admin;
+
+-- maintenance_status is the state of the maintenance mode, and whether the workers are done with the jobs in flight
+CREATE OR REPLACE VIEW mergestat.maintenance_status AS
+SELECT m.enabled, m.reason, m.enabled_at, m.enabled_by,
+    (SELECT COUNT(*) FROM mergestat.repo_sync_queue WHERE status = 'RUNNING') AS running_jobs,
+    m.enabled AND NOT EXISTS (SELECT 1 FROM mergestat.repo_sync_queue WHERE status = 'RUNNING') AS drained
+FROM mergestat.maintenance_mode m;
+
+COMMENT ON VIEW mergestat.maintenance_status IS 'state of the maintenance mode, and whether the jobs in flight are all finished (drained)';
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+-- weight is the cost of a sync of the type on the worker running it: a worker only dequeues a sync whose weight fits
+-- in the weight it has left (MAX_WEIGHT minus the weight of the syncs it's running), whatever its free slots
+ALTER TABLE mergestat.repo_sync_types ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 1 CHECK (weight > 0);
+
+COMMENT ON COLUMN mergestat.repo_sync_types.weight IS 'cost of a sync of the type on the worker running it, workers cap the total weight of the syncs they run at once (MAX_WEIGHT)';
+
+-- blaming every line of a repo is by far the heaviest sync (memory and cpu)
+UPDATE mergestat.repo_sync_types SET weight = 4 WHERE type = 'GIT_BLAME';
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+-- repo_sizes are the measured sizes of repos, and the size tier their syncs are run with (see helper.SyncStrategyFor)
+CREATE TABLE IF NOT EXISTS mergestat.repo_sizes (
+    repo_id     UUID PRIMARY KEY REFERENCES public.repos (id) ON DELETE CASCADE,
+    clone_bytes BIGINT NOT NULL,
+    tier        TEXT NOT NULL CHECK (tier IN ('small', 'medium', 'large', 'huge')),
+    measured_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE mergestat.repo_sizes IS 'measured sizes of repos, and the size tier their syncs are run with';
+COMMENT ON COLUMN mergestat.repo_sizes.clone_bytes IS 'size (in bytes) of the last clone of the repo';
+COMMENT ON COLUMN mergestat.repo_sizes.tier IS 'size tier of the repo (small, medium, large or huge), which sets how it''s cloned, its batch sizes and timeouts. The sizeTier repo setting overrides it';
+COMMENT ON COLUMN mergestat.repo_sizes.measured_at IS 'timestamp of when the clone of the repo was measured';
+
+COMMIT;
@@ -0,0 +1,67 @@
+BEGIN;
+
+-- parameters of a job (eg. enqueued manually), which override the settings of its repo sync for that run only
+ALTER TABLE mergestat.repo_sync_queue ADD COLUMN IF NOT EXISTS parameters JSONB;
+COMMENT ON COLUMN mergestat.repo_sync_queue.parameters IS 'settings of the job, overriding the settings of its repo sync for this run only (eg. the blameRef of a GIT_BLAME sync)';
+
+-- enqueue_repo_sync enqueues a run of the repo sync, with parameters overriding its settings for that run
+CREATE OR REPLACE FUNCTION mergestat.enqueue_repo_sync(repo_sync_id UUID, parameters JSONB DEFAULT NULL)
+RETURNS mergestat.repo_sync_queue
+LANGUAGE SQL VOLATILE
+AS $$
+    INSERT INTO mergestat.repo_sync_queue (repo_sync_id, status, priority, type_group, parameters)
+    SELECT rs.id, 'QUEUED', rs.priority, rst.type_group, enqueue_repo_sync.parameters
+    FROM mergestat.repo_syncs rs
+    INNER JOIN mergestat.repo_sync_types rst ON rst.type = rs.sync_type
+    WHERE rs.id = enqueue_repo_sync.repo_sync_id
+    RETURNING *;
+$$;
+
+COMMENT ON FUNCTION mergestat.enqueue_repo_sync(UUID, JSONB) IS 'enqueues a run of the repo sync, with parameters overriding its settings for that run (eg. {"blameRef": "v1.2.0"})';
+
+-- git_blame_snapshots is the blame of repos at a point in time (a ref, or the last commit before a date), written by
+-- GIT_BLAME syncs with the blameRef or blameAsOf setting (or parameter). The blame at HEAD stays in git_blame.
+CREATE TABLE IF NOT EXISTS public.git_blame_snapshots (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    ref TEXT NOT NULL,
+    as_of TIMESTAMP WITH TIME ZONE,
+    revision TEXT NOT NULL,
+    author_email TEXT,
+    author_name TEXT,
+    author_when TIMESTAMP WITH TIME ZONE,
+    commit_hash TEXT,
+    line_no INTEGER NOT NULL,
+    line TEXT,
+    path TEXT NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_git_blame_snapshots_key ON public.git_blame_snapshots (repo_id, ref, COALESCE(as_of, '-infinity'), path, line_no);
+
+COMMENT ON TABLE public.git_blame_snapshots IS 'git blame of all lines of a repo at a point in time (a ref, or the last commit before a date)';
+COMMENT ON COLUMN public.git_blame_snapshots.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_blame_snapshots.ref IS 'ref the blame was taken at (HEAD if only a date was given)';
+COMMENT ON COLUMN public.git_blame_snapshots.as_of IS 'date the blame was taken as of, the revision is the last commit of the ref before it (null if none was given)';
+COMMENT ON COLUMN public.git_blame_snapshots.revision IS 'hash of the commit the blame was taken at';
+COMMENT ON COLUMN public.git_blame_snapshots.author_email IS 'email of the author who last modified the line';
+COMMENT ON COLUMN public.git_blame_snapshots.author_name IS 'name of the author who last modified the line';
+COMMENT ON COLUMN public.git_blame_snapshots.author_when IS 'timestamp of when the line was last modified';
+COMMENT ON COLUMN public.git_blame_snapshots.commit_hash IS 'hash of the commit the line was last modified in';
+COMMENT ON COLUMN public.git_blame_snapshots.line_no IS 'line number';
+COMMENT ON COLUMN public.git_blame_snapshots.line IS 'content of the line';
+COMMENT ON COLUMN public.git_blame_snapshots.path IS 'path of the file';
+COMMENT ON COLUMN public.git_blame_snapshots._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- git_file_ownership_snapshots is the per file, per author rollup of the blame snapshots (as git_file_ownership is of git_blame)
+CREATE OR REPLACE VIEW public.git_file_ownership_snapshots AS
+SELECT repo_id, ref, as_of, revision, path, author_email, MAX(author_name) AS author_name, COUNT(*) AS lines,
+    SUM(COUNT(*)) OVER (PARTITION BY repo_id, ref, as_of, path) AS total_lines,
+    COUNT(*)::DOUBLE PRECISION / SUM(COUNT(*)) OVER (PARTITION BY repo_id, ref, as_of, path) AS ownership,
+    MAX(author_when) AS last_touched_at
+FROM public.git_blame_snapshots
+WHERE author_email IS NOT NULL
+GROUP BY repo_id, ref, as_of, revision, path, author_email;
+
+COMMENT ON VIEW public.git_file_ownership_snapshots IS 'per file, per author rollup of git_blame_snapshots, ie. the ownership of files at a point in time';
+
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS public.git_file_line_ages (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    lines INTEGER NOT NULL,
+    older_than_1y DOUBLE PRECISION NOT NULL,
+    older_than_2y DOUBLE PRECISION NOT NULL,
+    older_than_3y DOUBLE PRECISION NOT NULL,
+    median_age_days INTEGER NOT NULL,
+    oldest_line_at TIMESTAMP WITH TIME ZONE,
+    newest_line_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path)
+);
+
+COMMENT ON TABLE public.git_file_line_ages IS 'per file age histogram of the lines of git_blame (code decay), maintained by GIT_BLAME syncs';
+COMMENT ON COLUMN public.git_file_line_ages.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_file_line_ages.path IS 'path of the file';
+COMMENT ON COLUMN public.git_file_line_ages.lines IS 'number of lines of the file';
+COMMENT ON COLUMN public.git_file_line_ages.older_than_1y IS 'percentage (0 to 100) of the lines of the file last modified more than a year before the sync';
+COMMENT ON COLUMN public.git_file_line_ages.older_than_2y IS 'percentage (0 to 100) of the lines of the file last modified more than 2 years before the sync';
+COMMENT ON COLUMN public.git_file_line_ages.older_than_3y IS 'percentage (0 to 100) of the lines of the file last modified more than 3 years before the sync';
+COMMENT ON COLUMN public.git_file_line_ages.median_age_days IS 'median age (in days) of the lines of the file, by the author date of the commit that last modified them';
+COMMENT ON COLUMN public.git_file_line_ages.oldest_line_at IS 'author date of the oldest line of the file';
+COMMENT ON COLUMN public.git_file_line_ages.newest_line_at IS 'author date of the newest line of the file';
+COMMENT ON COLUMN public.git_file_line_ages._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
@@ -0,0 +1,34 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GIT_COMMIT_PATCHES', 'Retrieves the unified diff of the files changed by each commit, truncated per file and per commit (patchMaxFileBytes and patchMaxCommitBytes settings)', 'Git Commit Patches', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_commit_patches (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    commit_hash TEXT NOT NULL,
+    file_path TEXT NOT NULL,
+    old_file_path TEXT,
+    patch TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    truncated BOOLEAN NOT NULL,
+    is_binary BOOLEAN NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, commit_hash, file_path)
+);
+
+-- searching over changes, eg. WHERE to_tsvector('simple', patch) @@ websearch_to_tsquery('simple', 'InsecureSkipVerify')
+CREATE INDEX IF NOT EXISTS idx_git_commit_patches_search ON public.git_commit_patches USING GIN (to_tsvector('simple', patch));
+
+COMMENT ON TABLE public.git_commit_patches IS 'unified diff of the files changed by the commits of a repo, truncated per file and per commit';
+COMMENT ON COLUMN public.git_commit_patches.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_commit_patches.commit_hash IS 'hash of the commit';
+COMMENT ON COLUMN public.git_commit_patches.file_path IS 'path of the file changed';
+COMMENT ON COLUMN public.git_commit_patches.old_file_path IS 'path of the file before the commit (differs from file_path if it was renamed)';
+COMMENT ON COLUMN public.git_commit_patches.patch IS 'unified diff of the file, possibly truncated (empty for binary files)';
+COMMENT ON COLUMN public.git_commit_patches.size IS 'size (in bytes) of the whole unified diff of the file';
+COMMENT ON COLUMN public.git_commit_patches.truncated IS 'true if the patch holds only part of the unified diff (see the patchMaxFileBytes and patchMaxCommitBytes sync settings)';
+COMMENT ON COLUMN public.git_commit_patches.is_binary IS 'true if the file is binary';
+COMMENT ON COLUMN public.git_commit_patches._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
@@ -0,0 +1,35 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS mergestat.search_indexes (
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    method TEXT NOT NULL DEFAULT 'tsvector' CHECK (method IN ('tsvector', 'trgm')),
+    config REGCONFIG NOT NULL DEFAULT 'simple',
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    index_name TEXT,
+    indexed_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (table_name, column_name)
+);
+
+COMMENT ON TABLE mergestat.search_indexes IS 'search indexes of text columns of the synced tables, created (or dropped) by the workers once a sync writing into the table completes';
+COMMENT ON COLUMN mergestat.search_indexes.table_name IS 'name of the table (of the public schema) the column belongs to';
+COMMENT ON COLUMN mergestat.search_indexes.column_name IS 'name of the text column indexed';
+COMMENT ON COLUMN mergestat.search_indexes.method IS 'tsvector for a GIN index over to_tsvector(config, column), used by full-text search (@@), or trgm for a GIN index with pg_trgm operators, used by LIKE, ILIKE and regular expressions';
+COMMENT ON COLUMN mergestat.search_indexes.config IS 'text search configuration of tsvector indexes, queries must use the same one to use the index, eg. to_tsvector(''simple'', message) @@ websearch_to_tsquery(''simple'', ''fix race'')';
+COMMENT ON COLUMN mergestat.search_indexes.enabled IS 'if true the index is created, if false it is dropped';
+COMMENT ON COLUMN mergestat.search_indexes.index_name IS 'name of the index, once created';
+COMMENT ON COLUMN mergestat.search_indexes.indexed_at IS 'timestamp when the index was last created (or dropped)';
+COMMENT ON COLUMN mergestat.search_indexes.last_error IS 'error of the last attempt to create (or drop) the index, if it failed';
+COMMENT ON COLUMN mergestat.search_indexes.created_at IS 'timestamp when the search index was configured';
+
+-- the usual searches, disabled as the indexes of large tables (notably of file contents) take a lot of space
+INSERT INTO mergestat.search_indexes (table_name, column_name, method, config) VALUES
+    ('git_commits', 'message', 'tsvector', 'simple'),
+    ('github_issues', 'body', 'tsvector', 'simple'),
+    ('github_pull_requests', 'body', 'tsvector', 'simple'),
+    ('git_files', 'contents', 'trgm', 'simple')
+ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,35 @@
+BEGIN;
+
+ALTER TABLE public.git_files ADD COLUMN IF NOT EXISTS content_hash TEXT;
+
+COMMENT ON COLUMN public.git_files.content_hash IS 'SHA-256 (hex encoded) of the contents of the file, binary files included (NULL if synced before content hashes were recorded)';
+
+CREATE TABLE IF NOT EXISTS public.git_file_changes (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    sync_id BIGINT NOT NULL,
+    path TEXT NOT NULL,
+    change TEXT NOT NULL CHECK (change IN ('added', 'modified', 'deleted')),
+    old_content_hash TEXT,
+    new_content_hash TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, sync_id, path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_file_changes_synced_at ON public.git_file_changes (_mergestat_synced_at);
+
+COMMENT ON TABLE public.git_file_changes IS 'files added, modified or deleted since the previous GIT_FILES sync of a repo, so that consumers can process deltas rather than all of git_files';
+COMMENT ON COLUMN public.git_file_changes.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_file_changes.sync_id IS 'id of the sync job (in mergestat.repo_sync_queue) that observed the change, increasing from one sync to the next';
+COMMENT ON COLUMN public.git_file_changes.path IS 'path of the file';
+COMMENT ON COLUMN public.git_file_changes.change IS 'added, modified or deleted (files whose previous content hash is unknown are never reported as modified)';
+COMMENT ON COLUMN public.git_file_changes.old_content_hash IS 'content hash of the file as of the previous sync (NULL if added)';
+COMMENT ON COLUMN public.git_file_changes.new_content_hash IS 'content hash of the file as of the sync (NULL if deleted)';
+COMMENT ON COLUMN public.git_file_changes._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- changes accumulate a row per changed file and sync
+INSERT INTO mergestat.data_retention_policies (table_name, timestamp_column, max_age, enabled)
+VALUES
+('git_file_changes', '_mergestat_synced_at', '3 months', FALSE)
+ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,35 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GIT_SYMBOLS', 'Extracts the symbols (functions, types, variables...) defined in the files of the repo at HEAD with universal-ctags', 'Git Symbols', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_symbols (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    language TEXT NOT NULL,
+    scope TEXT,
+    scope_kind TEXT,
+    signature TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_symbols_repo_id_path ON public.git_symbols (repo_id, path);
+CREATE INDEX IF NOT EXISTS idx_git_symbols_name ON public.git_symbols (name);
+
+COMMENT ON TABLE public.git_symbols IS 'symbols defined in the files of a repo at HEAD, as extracted by universal-ctags';
+COMMENT ON COLUMN public.git_symbols.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_symbols.path IS 'path of the file defining the symbol';
+COMMENT ON COLUMN public.git_symbols.name IS 'name of the symbol';
+COMMENT ON COLUMN public.git_symbols.kind IS 'kind of the symbol, as named by ctags for the language (eg. func, struct, method, class, variable)';
+COMMENT ON COLUMN public.git_symbols.line IS 'line of the file the symbol is defined at';
+COMMENT ON COLUMN public.git_symbols.language IS 'language of the file, as detected by ctags';
+COMMENT ON COLUMN public.git_symbols.scope IS 'name of the symbol enclosing the symbol (eg. the class of a method), if any';
+COMMENT ON COLUMN public.git_symbols.scope_kind IS 'kind of the enclosing symbol, if any';
+COMMENT ON COLUMN public.git_symbols.signature IS 'signature of functions and methods, for the languages ctags reports it for';
+COMMENT ON COLUMN public.git_symbols._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
@@ -0,0 +1,63 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('CODE_IMPORTS', 'Parses the imports of the Go and JavaScript/TypeScript files of the repo (requires GIT_FILES) into an import graph', 'Code Imports', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.code_imports (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    language TEXT NOT NULL,
+    module TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    resolved_path TEXT,
+    internal BOOLEAN NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path, line, module)
+);
+
+CREATE INDEX IF NOT EXISTS idx_code_imports_module ON public.code_imports (module);
+
+COMMENT ON TABLE public.code_imports IS 'imports of the Go and JavaScript/TypeScript files of a repo (file to imported module), parsed from git_files';
+COMMENT ON COLUMN public.code_imports.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.code_imports.path IS 'path of the importing file';
+COMMENT ON COLUMN public.code_imports.line IS 'line of the import';
+COMMENT ON COLUMN public.code_imports.language IS 'language of the importing file (Go, JavaScript or TypeScript)';
+COMMENT ON COLUMN public.code_imports.module IS 'module imported, as written (eg. github.com/jackc/pgx/v4, react or ./utils)';
+COMMENT ON COLUMN public.code_imports.kind IS 'import, require (require() calls) or dynamic (import() calls)';
+COMMENT ON COLUMN public.code_imports.resolved_path IS 'path (within the repo) of the directory or file imported, for internal imports';
+COMMENT ON COLUMN public.code_imports.internal IS 'true if the import refers to the repo itself: a relative import, or a package of a Go module of the repo';
+COMMENT ON COLUMN public.code_imports._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.code_modules (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    language TEXT NOT NULL,
+    name TEXT NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_code_modules_name ON public.code_modules (name);
+
+COMMENT ON TABLE public.code_modules IS 'modules declared by a repo (by go.mod and package.json files), which imports of other repos may refer to';
+COMMENT ON COLUMN public.code_modules.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.code_modules.path IS 'path of the go.mod or package.json file declaring the module';
+COMMENT ON COLUMN public.code_modules.language IS 'Go for go.mod files, JavaScript for package.json files';
+COMMENT ON COLUMN public.code_modules.name IS 'module path (go.mod) or package name (package.json)';
+COMMENT ON COLUMN public.code_modules._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+-- imports resolved to the repos declaring the module imported (or one of its parents, for Go packages and JS subpaths)
+CREATE OR REPLACE VIEW public.code_import_edges AS
+SELECT i.repo_id, i.path, i.line, i.language, i.module, i.internal, m.repo_id AS target_repo_id, m.name AS target_module
+FROM public.code_imports i
+INNER JOIN public.code_modules m ON (m.language = 'Go') = (i.language = 'Go')
+    AND (i.module = m.name OR starts_with(i.module, m.name || '/'))
+WHERE NOT i.internal OR m.repo_id <> i.repo_id;
+
+COMMENT ON VIEW public.code_import_edges IS 'imports resolved to the repos declaring the module they import, for dependency graphs across repos';
+COMMENT ON COLUMN public.code_import_edges.target_repo_id IS 'repo declaring the module imported';
+COMMENT ON COLUMN public.code_import_edges.target_module IS 'module (declared by the target repo) the import refers to';
+
+COMMIT;
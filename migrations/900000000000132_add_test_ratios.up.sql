@@ -0,0 +1,47 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('TEST_RATIOS', 'Computes the ratio of test files (and lines) to source files per repo and directory, requires GIT_FILES', 'Test Ratios', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.test_ratios (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    directory TEXT NOT NULL,
+    source_files INTEGER NOT NULL,
+    source_lines BIGINT NOT NULL,
+    test_files INTEGER NOT NULL,
+    test_lines BIGINT NOT NULL,
+    file_ratio DOUBLE PRECISION,
+    line_ratio DOUBLE PRECISION,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, computed_at, directory)
+);
+
+CREATE INDEX IF NOT EXISTS idx_test_ratios_directory ON public.test_ratios (repo_id, directory, computed_at);
+
+COMMENT ON TABLE public.test_ratios IS 'number of test and source files (and their lines) of a repo and its directories, one set of rows per TEST_RATIOS sync (kept for trending)';
+COMMENT ON COLUMN public.test_ratios.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.test_ratios.computed_at IS 'time the ratios were computed, shared by all the rows of a sync';
+COMMENT ON COLUMN public.test_ratios.directory IS 'directory the ratios cover, empty for the whole repo';
+COMMENT ON COLUMN public.test_ratios.source_files IS 'number of source files that are not tests';
+COMMENT ON COLUMN public.test_ratios.source_lines IS 'number of lines of the source files that are not tests';
+COMMENT ON COLUMN public.test_ratios.test_files IS 'number of test files, by the naming conventions of their language (eg. _test.go, *.spec.ts, test_*.py) or in a test directory (eg. tests/)';
+COMMENT ON COLUMN public.test_ratios.test_lines IS 'number of lines of the test files';
+COMMENT ON COLUMN public.test_ratios.file_ratio IS 'test files per source file, NULL without source files';
+COMMENT ON COLUMN public.test_ratios.line_ratio IS 'test lines per source line, NULL without source lines';
+COMMENT ON COLUMN public.test_ratios._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE OR REPLACE VIEW public.test_ratios_latest AS
+    SELECT DISTINCT ON (repo_id, directory) *
+        FROM public.test_ratios
+    ORDER BY repo_id, directory, computed_at DESC;
+
+COMMENT ON VIEW public.test_ratios_latest IS 'most recently computed test ratios of each repo and directory';
+
+INSERT INTO mergestat.data_retention_policies (table_name, timestamp_column, max_age, enabled)
+VALUES
+('test_ratios', 'computed_at', '2 years', FALSE)
+ON CONFLICT DO NOTHING;
+
+COMMIT;
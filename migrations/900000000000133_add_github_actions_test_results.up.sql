@@ -0,0 +1,89 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority, type_group)
+VALUES ('GITHUB_ACTIONS_TEST_RESULTS', 'Retrieves the JUnit XML reports uploaded as artifacts by the completed GitHub Actions workflow runs of a repo, and records the results of their test cases, runs after the GitHub Actions sync', 'GitHub Actions Test Results', 3, 'GITHUB')
+ON CONFLICT DO NOTHING;
+
+UPDATE mergestat.repo_sync_types SET uses_provider_api = TRUE WHERE type = 'GITHUB_ACTIONS_TEST_RESULTS';
+
+CREATE TABLE IF NOT EXISTS public.github_actions_test_result_runs (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    run_id BIGINT NOT NULL,
+    artifacts INTEGER NOT NULL,
+    test_cases INTEGER NOT NULL,
+    scanned_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, run_id)
+);
+
+COMMENT ON TABLE public.github_actions_test_result_runs IS 'completed GitHub Actions workflow runs the artifacts of which were scanned for JUnit reports (including runs without any), so that they are only scanned once';
+COMMENT ON COLUMN public.github_actions_test_result_runs.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_actions_test_result_runs.run_id IS 'id of the workflow run (see github_actions_workflow_runs.id)';
+COMMENT ON COLUMN public.github_actions_test_result_runs.artifacts IS 'number of artifacts of the run that were downloaded as JUnit reports';
+COMMENT ON COLUMN public.github_actions_test_result_runs.test_cases IS 'number of test cases found in the reports of the run';
+COMMENT ON COLUMN public.github_actions_test_result_runs.scanned_at IS 'time the artifacts of the run were scanned';
+COMMENT ON COLUMN public.github_actions_test_result_runs._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_actions_test_results (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    run_id BIGINT NOT NULL,
+    artifact_id BIGINT NOT NULL,
+    artifact_name TEXT NOT NULL,
+    file TEXT NOT NULL,
+    suite TEXT NOT NULL,
+    classname TEXT NOT NULL,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL,
+    duration_seconds DOUBLE PRECISION NOT NULL,
+    message TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_actions_test_results_run ON public.github_actions_test_results (repo_id, run_id);
+CREATE INDEX IF NOT EXISTS idx_github_actions_test_results_test ON public.github_actions_test_results (repo_id, suite, classname, name);
+
+COMMENT ON TABLE public.github_actions_test_results IS 'results of the test cases of the JUnit XML reports uploaded as artifacts by GitHub Actions workflow runs';
+COMMENT ON COLUMN public.github_actions_test_results.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_actions_test_results.run_id IS 'id of the workflow run (see github_actions_workflow_runs.id)';
+COMMENT ON COLUMN public.github_actions_test_results.artifact_id IS 'id of the artifact the report was found in';
+COMMENT ON COLUMN public.github_actions_test_results.artifact_name IS 'name of the artifact the report was found in';
+COMMENT ON COLUMN public.github_actions_test_results.file IS 'path of the report in the artifact';
+COMMENT ON COLUMN public.github_actions_test_results.suite IS 'name of the (innermost named) test suite of the test case';
+COMMENT ON COLUMN public.github_actions_test_results.classname IS 'class name of the test case, as reported by the test runner';
+COMMENT ON COLUMN public.github_actions_test_results.name IS 'name of the test case';
+COMMENT ON COLUMN public.github_actions_test_results.status IS 'result of the test case: passed, failed, error or skipped';
+COMMENT ON COLUMN public.github_actions_test_results.duration_seconds IS 'duration of the test case in seconds';
+COMMENT ON COLUMN public.github_actions_test_results.message IS 'message of the failure, error or skip, truncated to 4 KiB';
+COMMENT ON COLUMN public.github_actions_test_results._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE OR REPLACE VIEW public.github_actions_test_cases AS
+SELECT t.repo_id, t.run_id, wr.workflow_id, wr.name AS workflow_name, wr.head_branch, wr.head_commit->>'id' AS commit_hash,
+    wr.run_attempt, wr.created_at AS run_created_at, t.suite, t.classname, t.name, t.status, t.duration_seconds, t.message
+FROM public.github_actions_test_results t
+INNER JOIN public.github_actions_workflow_runs wr ON wr.repo_id = t.repo_id AND wr.id = t.run_id;
+
+COMMENT ON VIEW public.github_actions_test_cases IS 'results of test cases with the workflow, branch and commit of the run that reported them';
+
+CREATE OR REPLACE VIEW public.github_actions_flaky_tests AS
+WITH outcomes AS (
+    SELECT repo_id, workflow_id, commit_hash, suite, classname, name,
+        bool_or(status = 'passed') AS passed, bool_or(status IN ('failed', 'error')) AS failed
+    FROM public.github_actions_test_cases
+    WHERE commit_hash IS NOT NULL
+    GROUP BY repo_id, workflow_id, commit_hash, suite, classname, name
+)
+SELECT repo_id, workflow_id, suite, classname, name,
+    COUNT(*) AS commits, COUNT(*) FILTER (WHERE passed AND failed) AS flaky_commits,
+    COUNT(*) FILTER (WHERE passed AND failed)::DOUBLE PRECISION / COUNT(*) AS flaky_rate
+FROM outcomes
+GROUP BY repo_id, workflow_id, suite, classname, name
+HAVING COUNT(*) FILTER (WHERE passed AND failed) > 0;
+
+COMMENT ON VIEW public.github_actions_flaky_tests IS 'test cases that both passed and failed (eg. on a re-run) on the same commit in the same workflow, with the share of commits they did so on';
+
+INSERT INTO mergestat.data_retention_policies (table_name, timestamp_column, max_age, enabled)
+VALUES
+('github_actions_test_results', '_mergestat_synced_at', '18 months', FALSE)
+ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,96 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('SONARQUBE', 'Imports the open issues, metrics and quality gate status of the SonarQube (or SonarCloud) project of a repo, requires the sonarQubeProjectKey setting (the server is SonarCloud, unless SONARQUBE_URL is set on the worker)', 'SonarQube', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.sonarqube_issues (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    project_key TEXT NOT NULL,
+    issue_key TEXT NOT NULL,
+    rule TEXT NOT NULL,
+    type TEXT,
+    severity TEXT,
+    status TEXT,
+    path TEXT,
+    line INTEGER,
+    message TEXT,
+    effort TEXT,
+    tags TEXT[],
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, issue_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sonarqube_issues_path ON public.sonarqube_issues (repo_id, path);
+
+COMMENT ON TABLE public.sonarqube_issues IS 'open issues reported by SonarQube for the project of a repo';
+COMMENT ON COLUMN public.sonarqube_issues.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.sonarqube_issues.project_key IS 'key of the SonarQube project the repo is mapped to';
+COMMENT ON COLUMN public.sonarqube_issues.issue_key IS 'key of the issue in SonarQube';
+COMMENT ON COLUMN public.sonarqube_issues.rule IS 'rule that raised the issue, eg. go:S1192';
+COMMENT ON COLUMN public.sonarqube_issues.type IS 'type of the issue: BUG, VULNERABILITY or CODE_SMELL';
+COMMENT ON COLUMN public.sonarqube_issues.severity IS 'severity of the issue: BLOCKER, CRITICAL, MAJOR, MINOR or INFO';
+COMMENT ON COLUMN public.sonarqube_issues.status IS 'status of the issue, eg. OPEN, CONFIRMED or REOPENED';
+COMMENT ON COLUMN public.sonarqube_issues.path IS 'path of the file of the issue in the repo, NULL for issues of the project';
+COMMENT ON COLUMN public.sonarqube_issues.line IS 'line of the issue in the file';
+COMMENT ON COLUMN public.sonarqube_issues.message IS 'description of the issue';
+COMMENT ON COLUMN public.sonarqube_issues.effort IS 'estimated effort to fix the issue, eg. 10min';
+COMMENT ON COLUMN public.sonarqube_issues.tags IS 'tags of the issue';
+COMMENT ON COLUMN public.sonarqube_issues.created_at IS 'time the issue was first reported';
+COMMENT ON COLUMN public.sonarqube_issues.updated_at IS 'time the issue was last updated';
+COMMENT ON COLUMN public.sonarqube_issues._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.sonarqube_measures (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    project_key TEXT NOT NULL,
+    metric TEXT NOT NULL,
+    value TEXT,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, computed_at, metric)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sonarqube_measures_metric ON public.sonarqube_measures (repo_id, metric, computed_at);
+
+COMMENT ON TABLE public.sonarqube_measures IS 'metrics of the SonarQube project of a repo (eg. coverage, bugs or ncloc), one set of rows per SONARQUBE sync (kept for trending)';
+COMMENT ON COLUMN public.sonarqube_measures.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.sonarqube_measures.computed_at IS 'time the metrics were imported, shared by all the rows of a sync';
+COMMENT ON COLUMN public.sonarqube_measures.project_key IS 'key of the SonarQube project the repo is mapped to';
+COMMENT ON COLUMN public.sonarqube_measures.metric IS 'key of the metric, eg. coverage';
+COMMENT ON COLUMN public.sonarqube_measures.value IS 'value of the metric, as reported by SonarQube (numeric for most metrics, eg. 81.5, or a rating, eg. 1.0 for A)';
+COMMENT ON COLUMN public.sonarqube_measures._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE OR REPLACE VIEW public.sonarqube_measures_latest AS
+    SELECT DISTINCT ON (repo_id, metric) *
+        FROM public.sonarqube_measures
+    ORDER BY repo_id, metric, computed_at DESC;
+
+COMMENT ON VIEW public.sonarqube_measures_latest IS 'most recently imported value of each SonarQube metric of each repo';
+
+CREATE TABLE IF NOT EXISTS public.sonarqube_quality_gates (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    project_key TEXT NOT NULL,
+    status TEXT NOT NULL,
+    conditions JSONB,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, computed_at)
+);
+
+COMMENT ON TABLE public.sonarqube_quality_gates IS 'quality gate status of the SonarQube project of a repo, one row per SONARQUBE sync (kept for trending)';
+COMMENT ON COLUMN public.sonarqube_quality_gates.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.sonarqube_quality_gates.computed_at IS 'time the status was imported';
+COMMENT ON COLUMN public.sonarqube_quality_gates.project_key IS 'key of the SonarQube project the repo is mapped to';
+COMMENT ON COLUMN public.sonarqube_quality_gates.status IS 'status of the quality gate: OK, WARN, ERROR or NONE (no gate or no analysis)';
+COMMENT ON COLUMN public.sonarqube_quality_gates.conditions IS 'conditions of the quality gate, with their thresholds, actual values and statuses';
+COMMENT ON COLUMN public.sonarqube_quality_gates._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+INSERT INTO mergestat.data_retention_policies (table_name, timestamp_column, max_age, enabled)
+VALUES
+('sonarqube_measures', 'computed_at', '2 years', FALSE),
+('sonarqube_quality_gates', 'computed_at', '2 years', FALSE)
+ON CONFLICT DO NOTHING;
+
+COMMIT;
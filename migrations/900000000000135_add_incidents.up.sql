@@ -0,0 +1,41 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('INCIDENTS', 'Imports the incidents of the PagerDuty or Opsgenie services a repo is mapped to (in the incidentProvider and incidentServices settings), used by the DORA query pack for change failure rate and time to restore', 'Incidents', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.incidents (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL CHECK (provider IN ('PAGERDUTY', 'OPSGENIE')),
+    incident_id TEXT NOT NULL,
+    number TEXT,
+    title TEXT,
+    status TEXT NOT NULL CHECK (status IN ('OPEN', 'ACKNOWLEDGED', 'RESOLVED')),
+    severity TEXT,
+    service_id TEXT,
+    service_name TEXT,
+    url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, provider, incident_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_created_at ON public.incidents (repo_id, created_at);
+
+COMMENT ON TABLE public.incidents IS 'incidents of the PagerDuty or Opsgenie services a repo is mapped to';
+COMMENT ON COLUMN public.incidents.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.incidents.provider IS 'incident management tool the incident was imported from: PAGERDUTY or OPSGENIE';
+COMMENT ON COLUMN public.incidents.incident_id IS 'id of the incident in the provider';
+COMMENT ON COLUMN public.incidents.number IS 'human readable number of the incident, eg. 1234';
+COMMENT ON COLUMN public.incidents.title IS 'title of the incident';
+COMMENT ON COLUMN public.incidents.status IS 'status of the incident: OPEN, ACKNOWLEDGED or RESOLVED (including closed incidents)';
+COMMENT ON COLUMN public.incidents.severity IS 'urgency (PagerDuty, eg. high) or priority (Opsgenie, eg. P1) of the incident';
+COMMENT ON COLUMN public.incidents.service_id IS 'id of the (first impacted) service of the incident';
+COMMENT ON COLUMN public.incidents.service_name IS 'name of the service of the incident, if reported by the provider';
+COMMENT ON COLUMN public.incidents.url IS 'url of the incident in the provider';
+COMMENT ON COLUMN public.incidents.created_at IS 'time the incident was opened';
+COMMENT ON COLUMN public.incidents.resolved_at IS 'time the incident was resolved, NULL while it''s not';
+COMMENT ON COLUMN public.incidents._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
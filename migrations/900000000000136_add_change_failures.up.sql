@@ -0,0 +1,36 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('CHANGE_FAILURES', 'Detects the revert commits and the hotfix branches, commits and pull requests of a repo, used by the DORA query pack as change failures when no incidents are imported, requires GIT_COMMITS and/or GITHUB_REPO_PRS', 'Change Failures', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.change_failures (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('REVERT', 'HOTFIX')),
+    source TEXT NOT NULL CHECK (source IN ('COMMIT', 'PULL_REQUEST')),
+    commit_hash TEXT,
+    pull_request_number INTEGER,
+    reverted_commit_hash TEXT,
+    branch TEXT,
+    title TEXT,
+    failed_at TIMESTAMP WITH TIME ZONE,
+    restored_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_change_failures_restored_at ON public.change_failures (repo_id, restored_at);
+
+COMMENT ON TABLE public.change_failures IS 'reverts and hotfixes of a repo, each a signal of a change that failed (in production)';
+COMMENT ON COLUMN public.change_failures.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.change_failures.kind IS 'REVERT (a commit reverting others) or HOTFIX (a hotfix branch, commit or pull request)';
+COMMENT ON COLUMN public.change_failures.source IS 'where the failure was detected: COMMIT or PULL_REQUEST';
+COMMENT ON COLUMN public.change_failures.commit_hash IS 'hash of the revert or hotfix commit (for COMMIT failures)';
+COMMENT ON COLUMN public.change_failures.pull_request_number IS 'number of the hotfix pull request (for PULL_REQUEST failures)';
+COMMENT ON COLUMN public.change_failures.reverted_commit_hash IS 'hash of the commit reverted (for REVERT failures that state it)';
+COMMENT ON COLUMN public.change_failures.branch IS 'hotfix branch, merged by the commit or head of the pull request';
+COMMENT ON COLUMN public.change_failures.title IS 'subject of the commit, or title of the pull request';
+COMMENT ON COLUMN public.change_failures.failed_at IS 'time the failure was introduced (the reverted commit was committed) or noticed (the hotfix pull request was opened), if known';
+COMMENT ON COLUMN public.change_failures.restored_at IS 'time the failure was restored: the revert or hotfix was committed, or the hotfix pull request merged';
+COMMENT ON COLUMN public.change_failures._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+COMMIT;
@@ -0,0 +1,89 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('DEPENDENCY_UPDATE_LAG', 'Measures how long the Dependabot and Renovate pull requests of a repo stay open, and how far behind the latest release (in versions and libyears) each of its npm, PyPI and Go dependencies is, requires SYFT_REPO_SCAN and/or GITHUB_REPO_PRS', 'Dependency Update Lag', 4)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.dependency_update_prs (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL,
+    bot TEXT NOT NULL,
+    dependency TEXT,
+    from_version TEXT,
+    to_version TEXT,
+    state TEXT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    merged_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, number)
+);
+
+COMMENT ON TABLE public.dependency_update_prs IS 'pull requests of a repo opened by dependency update bots (Dependabot or Renovate)';
+COMMENT ON COLUMN public.dependency_update_prs.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.dependency_update_prs.number IS 'number of the pull request (see github_pull_requests.number)';
+COMMENT ON COLUMN public.dependency_update_prs.bot IS 'bot that opened the pull request: dependabot or renovate';
+COMMENT ON COLUMN public.dependency_update_prs.dependency IS 'name of the dependency updated, as stated by the title of the pull request (NULL for grouped updates)';
+COMMENT ON COLUMN public.dependency_update_prs.from_version IS 'version the dependency is updated from, if stated';
+COMMENT ON COLUMN public.dependency_update_prs.to_version IS 'version the dependency is updated to, if stated';
+COMMENT ON COLUMN public.dependency_update_prs.state IS 'state of the pull request: OPEN, CLOSED or MERGED';
+COMMENT ON COLUMN public.dependency_update_prs.created_at IS 'time the pull request was opened';
+COMMENT ON COLUMN public.dependency_update_prs.closed_at IS 'time the pull request was closed (or merged)';
+COMMENT ON COLUMN public.dependency_update_prs.merged_at IS 'time the pull request was merged';
+COMMENT ON COLUMN public.dependency_update_prs._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.dependency_lag (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    registry TEXT NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    version_released_at TIMESTAMP WITH TIME ZONE,
+    latest_version TEXT,
+    latest_released_at TIMESTAMP WITH TIME ZONE,
+    versions_behind INTEGER,
+    libyears DOUBLE PRECISION,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, registry, name, version)
+);
+
+COMMENT ON TABLE public.dependency_lag IS 'npm, PyPI and Go dependencies of a repo (as found by its Syft scan), and how far behind the latest release of their registry they are';
+COMMENT ON COLUMN public.dependency_lag.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.dependency_lag.registry IS 'registry the dependency is published to, npm, pypi or go';
+COMMENT ON COLUMN public.dependency_lag.name IS 'name of the dependency (or path of the Go module)';
+COMMENT ON COLUMN public.dependency_lag.version IS 'version of the dependency the repo depends on';
+COMMENT ON COLUMN public.dependency_lag.version_released_at IS 'time the version the repo depends on was published, NULL if the registry does not know it';
+COMMENT ON COLUMN public.dependency_lag.latest_version IS 'latest (non-prerelease) version of the dependency';
+COMMENT ON COLUMN public.dependency_lag.latest_released_at IS 'time the latest version was published';
+COMMENT ON COLUMN public.dependency_lag.versions_behind IS 'number of (non-prerelease) versions published after the version the repo depends on, NULL if unknown';
+COMMENT ON COLUMN public.dependency_lag.libyears IS 'years between the release of the version the repo depends on and that of the latest version, NULL if unknown';
+COMMENT ON COLUMN public.dependency_lag._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE OR REPLACE VIEW public.dependency_update_lag_scores AS
+WITH deps AS (
+    SELECT repo_id, COUNT(*) AS dependencies,
+        COUNT(*) FILTER (WHERE versions_behind > 0) AS outdated_dependencies,
+        SUM(libyears) AS libyears,
+        AVG(COALESCE(libyears, 0)) FILTER (WHERE version_released_at IS NOT NULL) AS libyears_per_dependency
+    FROM public.dependency_lag
+    GROUP BY repo_id
+), prs AS (
+    SELECT repo_id,
+        COUNT(*) FILTER (WHERE state = 'OPEN') AS open_update_prs,
+        MAX(now() - created_at) FILTER (WHERE state = 'OPEN') AS oldest_open_update_pr_age,
+        percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM merged_at - created_at)) FILTER (WHERE merged_at IS NOT NULL) * INTERVAL '1 second' AS median_time_to_merge,
+        COUNT(*) FILTER (WHERE state = 'MERGED')::DOUBLE PRECISION / NULLIF(COUNT(*) FILTER (WHERE state <> 'OPEN'), 0) AS merge_rate
+    FROM public.dependency_update_prs
+    GROUP BY repo_id
+)
+SELECT r.id AS repo_id, r.repo,
+    COALESCE(d.dependencies, 0) AS dependencies, COALESCE(d.outdated_dependencies, 0) AS outdated_dependencies,
+    d.libyears, d.libyears_per_dependency,
+    COALESCE(p.open_update_prs, 0) AS open_update_prs, p.oldest_open_update_pr_age, p.median_time_to_merge, p.merge_rate
+FROM public.repos r
+LEFT JOIN deps d ON d.repo_id = r.id
+LEFT JOIN prs p ON p.repo_id = r.id
+WHERE d.repo_id IS NOT NULL OR p.repo_id IS NOT NULL;
+
+COMMENT ON VIEW public.dependency_update_lag_scores IS 'update lag of each repo: how outdated its dependencies are (in total libyears, and per dependency as its score, lower is better), and how its dependency update pull requests are handled';
+
+COMMIT;
@@ -0,0 +1,53 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority, type_group)
+VALUES ('GITHUB_FORK_DRIFT', 'Records the upstream (parent) repo of a fork, and how many commits the default branch of the fork is ahead of and behind the default branch of its upstream, over time', 'GitHub Fork Drift', 3, 'GITHUB')
+ON CONFLICT DO NOTHING;
+
+UPDATE mergestat.repo_sync_types SET uses_provider_api = TRUE WHERE type = 'GITHUB_FORK_DRIFT';
+
+CREATE TABLE IF NOT EXISTS public.github_fork_drift (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    upstream_owner TEXT NOT NULL,
+    upstream_name TEXT NOT NULL,
+    upstream_repo_id UUID REFERENCES public.repos(id) ON DELETE SET NULL,
+    default_branch TEXT NOT NULL,
+    upstream_default_branch TEXT NOT NULL,
+    status TEXT,
+    ahead_by INTEGER,
+    behind_by INTEGER,
+    merge_base_hash TEXT,
+    merge_base_committed_at TIMESTAMP WITH TIME ZONE,
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, computed_at)
+);
+
+COMMENT ON TABLE public.github_fork_drift IS 'upstream of a fork, and how far its default branch diverged from that of the upstream, one row per GITHUB_FORK_DRIFT sync (kept for trending)';
+COMMENT ON COLUMN public.github_fork_drift.repo_id IS 'foreign key for public.repos.id (of the fork)';
+COMMENT ON COLUMN public.github_fork_drift.computed_at IS 'time the drift was computed';
+COMMENT ON COLUMN public.github_fork_drift.upstream_owner IS 'owner of the repo the fork was forked from (its parent)';
+COMMENT ON COLUMN public.github_fork_drift.upstream_name IS 'name of the repo the fork was forked from';
+COMMENT ON COLUMN public.github_fork_drift.upstream_repo_id IS 'id of the upstream in public.repos, if it''s synced too';
+COMMENT ON COLUMN public.github_fork_drift.default_branch IS 'default branch of the fork';
+COMMENT ON COLUMN public.github_fork_drift.upstream_default_branch IS 'default branch of the upstream';
+COMMENT ON COLUMN public.github_fork_drift.status IS 'status of the fork versus its upstream: identical, ahead, behind or diverged, NULL if they could not be compared (eg. unrelated histories)';
+COMMENT ON COLUMN public.github_fork_drift.ahead_by IS 'number of commits of the fork''s default branch not in the upstream''s';
+COMMENT ON COLUMN public.github_fork_drift.behind_by IS 'number of commits of the upstream''s default branch not in the fork''s';
+COMMENT ON COLUMN public.github_fork_drift.merge_base_hash IS 'hash of the latest commit the two branches share';
+COMMENT ON COLUMN public.github_fork_drift.merge_base_committed_at IS 'time the latest commit the two branches share was committed, ie. since when they diverged';
+COMMENT ON COLUMN public.github_fork_drift._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE OR REPLACE VIEW public.github_fork_drift_latest AS
+    SELECT DISTINCT ON (repo_id) *
+        FROM public.github_fork_drift
+    ORDER BY repo_id, computed_at DESC;
+
+COMMENT ON VIEW public.github_fork_drift_latest IS 'most recently computed drift of each fork from its upstream';
+
+INSERT INTO mergestat.data_retention_policies (table_name, timestamp_column, max_age, enabled)
+VALUES
+('github_fork_drift', 'computed_at', '2 years', FALSE)
+ON CONFLICT DO NOTHING;
+
+COMMIT;
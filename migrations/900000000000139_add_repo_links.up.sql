@@ -0,0 +1,111 @@
+BEGIN;
+
+-- repos declared to be the same logical project as another (canonical) repo, eg. a GitLab mirror of a GitHub repo or
+-- the Bitbucket repo a project was migrated away from, so that their metrics and history can be unified
+CREATE TABLE IF NOT EXISTS mergestat.repo_links (
+    repo_id UUID PRIMARY KEY REFERENCES public.repos(id) ON DELETE CASCADE,
+    canonical_repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    relation TEXT NOT NULL CHECK (relation IN ('MIRROR', 'PREDECESSOR')),
+    migrated_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK (repo_id <> canonical_repo_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_repo_links_canonical_repo_id ON mergestat.repo_links(canonical_repo_id);
+
+COMMENT ON TABLE mergestat.repo_links IS 'repos that are the same logical project as another (canonical) repo, eg. on another provider';
+COMMENT ON COLUMN mergestat.repo_links.repo_id IS 'foreign key for public.repos.id of the linked repo';
+COMMENT ON COLUMN mergestat.repo_links.canonical_repo_id IS 'foreign key for public.repos.id of the repo the project is identified by (itself never linked to another)';
+COMMENT ON COLUMN mergestat.repo_links.relation IS 'MIRROR (a copy of the canonical repo, eg. on GitLab) or PREDECESSOR (where the project lived before it was migrated to the canonical repo)';
+COMMENT ON COLUMN mergestat.repo_links.migrated_at IS 'for a PREDECESSOR, when the project was migrated to the canonical repo: the data of the predecessor is used before, that of the canonical repo after';
+COMMENT ON COLUMN mergestat.repo_links.created_at IS 'timestamp when the link was declared';
+
+-- links are a single level deep: a canonical repo is never linked itself (and a linked repo is never canonical), so
+-- that resolving the canonical repo of any repo is a single lookup
+CREATE OR REPLACE FUNCTION mergestat.check_repo_link()
+RETURNS TRIGGER
+AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM mergestat.repo_links WHERE repo_id = NEW.canonical_repo_id) THEN
+        RAISE EXCEPTION 'repo % is itself linked to another repo, link to that one instead', NEW.canonical_repo_id;
+    END IF;
+    IF EXISTS (SELECT 1 FROM mergestat.repo_links WHERE canonical_repo_id = NEW.repo_id) THEN
+        RAISE EXCEPTION 'repo % is the canonical repo of other repos, it cannot be linked', NEW.repo_id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE PLPGSQL;
+
+DROP TRIGGER IF EXISTS check_repo_link ON mergestat.repo_links;
+CREATE TRIGGER check_repo_link BEFORE INSERT OR UPDATE ON mergestat.repo_links
+    FOR EACH ROW EXECUTE FUNCTION mergestat.check_repo_link();
+
+-- mergestat.resolve_repo returns the id of a repo, given either its id or its url
+CREATE OR REPLACE FUNCTION mergestat.resolve_repo(_repo TEXT)
+RETURNS UUID
+AS $$
+DECLARE
+    _id UUID;
+BEGIN
+    SELECT id INTO _id FROM public.repos WHERE id::TEXT = _repo OR repo = _repo;
+    IF _id IS NULL THEN
+        RAISE EXCEPTION 'repo % not found', _repo;
+    END IF;
+    RETURN _id;
+END;
+$$ LANGUAGE PLPGSQL STABLE;
+
+COMMENT ON FUNCTION mergestat.resolve_repo(TEXT) IS 'id of the repo with the given id or url';
+
+-- mergestat.link_repos declares (or updates) that the repo is the same project as the canonical repo, both given by id or url
+CREATE OR REPLACE FUNCTION mergestat.link_repos(_repo TEXT, _canonical TEXT, _relation TEXT, _migrated_at TIMESTAMP WITH TIME ZONE)
+RETURNS VOID
+AS $$
+    INSERT INTO mergestat.repo_links (repo_id, canonical_repo_id, relation, migrated_at)
+    VALUES (mergestat.resolve_repo(_repo), mergestat.resolve_repo(_canonical), upper(_relation), _migrated_at)
+    ON CONFLICT (repo_id) DO UPDATE SET canonical_repo_id = EXCLUDED.canonical_repo_id, relation = EXCLUDED.relation,
+        migrated_at = EXCLUDED.migrated_at;
+$$ LANGUAGE SQL;
+
+COMMENT ON FUNCTION mergestat.link_repos(TEXT, TEXT, TEXT, TIMESTAMP WITH TIME ZONE) IS 'links a repo (by id or url) to the canonical repo of its project, as a MIRROR or a PREDECESSOR';
+
+-- mergestat.repo_identities maps every repo to the canonical repo of its project (itself, if it isn't linked)
+CREATE OR REPLACE VIEW mergestat.repo_identities AS (
+    SELECT r.id AS repo_id, COALESCE(l.canonical_repo_id, r.id) AS canonical_repo_id, l.relation, l.migrated_at
+    FROM public.repos r
+    LEFT JOIN mergestat.repo_links l ON l.repo_id = r.id
+);
+
+COMMENT ON VIEW mergestat.repo_identities IS 'canonical repo of the project of every repo (the repo itself, if not linked)';
+COMMENT ON COLUMN mergestat.repo_identities.relation IS 'relation of the repo to its canonical repo (MIRROR or PREDECESSOR), NULL for canonical (and unlinked) repos';
+
+-- public.project_git_commits unifies the commits of linked repos under their canonical repo: a commit found in more than
+-- one of them (eg. in a mirror, or carried over by a migration) is kept once, preferably from the canonical repo, and
+-- the commits of a predecessor are only kept up to its migration
+CREATE OR REPLACE VIEW public.project_git_commits AS (
+    SELECT DISTINCT ON (i.canonical_repo_id, c.hash) i.canonical_repo_id, c.repo_id AS source_repo_id, c.hash, c.message,
+        c.author_name, c.author_email, c.author_when, c.committer_name, c.committer_email, c.committer_when, c.parents
+    FROM public.git_commits c
+    INNER JOIN mergestat.repo_identities i ON i.repo_id = c.repo_id
+    WHERE i.relation IS DISTINCT FROM 'PREDECESSOR' OR i.migrated_at IS NULL OR c.committer_when < i.migrated_at
+    ORDER BY i.canonical_repo_id, c.hash, (c.repo_id = i.canonical_repo_id) DESC, c.repo_id
+);
+
+COMMENT ON VIEW public.project_git_commits IS 'commits of every project (the canonical repo and the repos linked to it), deduplicated by hash';
+COMMENT ON COLUMN public.project_git_commits.canonical_repo_id IS 'id of the canonical repo of the project (see mergestat.repo_links)';
+COMMENT ON COLUMN public.project_git_commits.source_repo_id IS 'id of the repo the commit was synced from';
+
+--https://www.graphile.org/postgraphile/computed-columns/
+CREATE OR REPLACE FUNCTION public.repos_linked_repos(repos REPOS)
+RETURNS SETOF public.repos
+LANGUAGE SQL STABLE
+AS $$
+    SELECT r.* FROM public.repos r
+    INNER JOIN mergestat.repo_identities i ON i.repo_id = r.id
+    INNER JOIN mergestat.repo_identities self ON self.repo_id = repos.id
+    WHERE i.canonical_repo_id = self.canonical_repo_id AND r.id <> repos.id;
+$$;
+
+COMMENT ON FUNCTION public.repos_linked_repos(REPOS) IS 'other repos of the same project as the repo (its canonical repo, mirrors and predecessors)';
+
+COMMIT;
@@ -0,0 +1,531 @@
+BEGIN;
+
+-- owned_tables is the registry of the synced tables mergestat owns: the only tables a deployment relocates (see
+-- apply_table_namespace), and the ones the functions redacting, erasing or expiring synced rows act on. Migrations
+-- creating a synced table register it here.
+CREATE TABLE IF NOT EXISTS mergestat.owned_tables (
+    table_name TEXT PRIMARY KEY
+);
+
+COMMENT ON TABLE mergestat.owned_tables IS 'synced tables owned by mergestat (created in public by migrations), other tables of the database are never moved nor rewritten';
+COMMENT ON COLUMN mergestat.owned_tables.table_name IS 'name of the table, as created by the migrations (and referenced by syncs)';
+
+INSERT INTO mergestat.owned_tables (table_name)
+VALUES
+('change_failures'), ('ci_actions'), ('ci_configs'), ('ci_flaky_jobs'), ('ci_jobs'), ('ci_workflow_durations'),
+('code_imports'), ('code_modules'), ('container_images'), ('dependency_lag'), ('dependency_update_prs'),
+('git_blame'), ('git_blame_snapshots'), ('git_branch_stats'), ('git_bus_factor'), ('git_commit_conventions'),
+('git_commit_metrics'), ('git_commit_patches'), ('git_commit_pull_requests'), ('git_commit_stats'), ('git_commits'),
+('git_file_changes'), ('git_file_line_ages'), ('git_file_ownership'), ('git_files'), ('git_large_files'),
+('git_mirrors'), ('git_refs'), ('git_release_changes'), ('git_releases'), ('git_remotes'), ('git_symbols'),
+('github_actions_test_result_runs'), ('github_actions_test_results'), ('github_actions_workflow_job_usage'),
+('github_actions_workflow_run_jobs'), ('github_actions_workflow_run_usage'), ('github_actions_workflow_runs'),
+('github_actions_workflows'), ('github_fork_drift'), ('github_issue_comments'), ('github_issue_label_durations'),
+('github_issue_label_events'), ('github_issue_reactions'), ('github_issue_response_times'), ('github_issues'),
+('github_org_audit_log'), ('github_project_fields'), ('github_project_item_field_values'), ('github_project_items'),
+('github_projects'), ('github_pull_request_commits'), ('github_pull_request_reviews'), ('github_pull_requests'),
+('github_repo_info'), ('github_repo_team_members'), ('github_repo_teams'), ('github_reviewer_weekly_load'),
+('github_runner_snapshots'), ('github_runners'), ('github_stargazers'), ('gitleaks_repo_scans'), ('gosec_repo_scans'),
+('grype_repo_scans'), ('incidents'), ('issue_key_links'), ('jira_issues'), ('ossf_scorecard_repo_scans'),
+('registry_package_versions'), ('registry_packages'), ('repo_policy_results'), ('sonarqube_issues'),
+('sonarqube_measures'), ('sonarqube_quality_gates'), ('syft_repo_scans'), ('terraform_modules'),
+('terraform_providers'), ('test_ratios'), ('trivy_repo_scans'), ('yelp_detect_secrets_repo_scans')
+ON CONFLICT DO NOTHING;
+
+-- synced_tables records where the synced tables live when a deployment relocates them out of public (eg. into a schema
+-- of their own, with a prefix) to coexist with other applications in a shared database. Tables are created in public
+-- by migrations, and moved to their namespace (see apply_table_namespace) once the migrations have run.
+CREATE TABLE IF NOT EXISTS mergestat.synced_tables (
+    table_name TEXT PRIMARY KEY,
+    schema_name TEXT NOT NULL,
+    physical_name TEXT NOT NULL,
+    relocated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (schema_name, physical_name)
+);
+
+COMMENT ON TABLE mergestat.synced_tables IS 'synced tables relocated out of public, and where they live (tables not listed are in public, under their own name)';
+COMMENT ON COLUMN mergestat.synced_tables.table_name IS 'name of the table, as created by the migrations (and referenced by syncs)';
+COMMENT ON COLUMN mergestat.synced_tables.schema_name IS 'schema the table was moved into';
+COMMENT ON COLUMN mergestat.synced_tables.physical_name IS 'name of the table in its schema, ie. its name with the configured prefix';
+COMMENT ON COLUMN mergestat.synced_tables.relocated_at IS 'timestamp when the table was moved';
+
+-- mergestat.synced_table returns the (quoted, qualified) name of a synced table where it lives, for functions
+-- building statements on the synced tables
+CREATE OR REPLACE FUNCTION mergestat.synced_table(_table TEXT)
+RETURNS TEXT
+AS $$
+    SELECT coalesce((SELECT format('%I.%I', s.schema_name, s.physical_name) FROM mergestat.synced_tables s WHERE s.table_name = _table),
+        format('public.%I', _table))
+$$ LANGUAGE SQL STABLE;
+
+COMMENT ON FUNCTION mergestat.synced_table(TEXT) IS 'qualified name of a synced table where it lives, in public or relocated';
+
+-- owned_table_columns lists the columns of the owned tables, where the tables live
+CREATE OR REPLACE VIEW mergestat.owned_table_columns AS
+SELECT o.table_name, c.table_schema::TEXT AS table_schema, c.table_name::TEXT AS physical_name, c.column_name::TEXT AS column_name,
+    c.data_type::TEXT AS data_type, c.udt_name::TEXT AS udt_name
+FROM mergestat.owned_tables o
+LEFT JOIN mergestat.synced_tables s ON s.table_name = o.table_name
+INNER JOIN information_schema.columns c ON c.table_schema = coalesce(s.schema_name, 'public') AND c.table_name = coalesce(s.physical_name, o.table_name);
+
+COMMENT ON VIEW mergestat.owned_table_columns IS 'columns of the synced tables owned by mergestat, in the schema (and under the name) the tables live';
+
+-- the unqualified names of relocated tables resolve to views of the same name in mergestat_tables (which the workers
+-- put first in their search_path). The views are simple, and so updatable, only COPY has to target the table itself.
+CREATE SCHEMA IF NOT EXISTS mergestat_tables;
+
+COMMENT ON SCHEMA mergestat_tables IS 'views resolving the names of the synced tables relocated out of public (see mergestat.synced_tables)';
+
+-- mergestat.restore_table_namespace moves the relocated tables back into public, under their own name, so that
+-- migrations (which reference public) can run. Moving tables only changes the catalog, views and foreign keys follow.
+-- It locks the tables (and drops the views resolving their names), workers are paused while it runs.
+CREATE OR REPLACE FUNCTION mergestat.restore_table_namespace()
+RETURNS INTEGER
+AS $$
+DECLARE
+    _table RECORD;
+    _count INTEGER := 0;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('mergestat.synced_tables'));
+
+    FOR _table IN SELECT * FROM mergestat.synced_tables LOOP
+        EXECUTE format('DROP VIEW IF EXISTS mergestat_tables.%I', _table.table_name);
+        IF to_regclass(format('%I.%I', _table.schema_name, _table.physical_name)) IS NOT NULL THEN
+            IF _table.physical_name <> _table.table_name THEN
+                EXECUTE format('ALTER TABLE %I.%I RENAME TO %I', _table.schema_name, _table.physical_name, _table.table_name);
+            END IF;
+            IF _table.schema_name <> 'public' THEN
+                EXECUTE format('ALTER TABLE %I.%I SET SCHEMA public', _table.schema_name, _table.table_name);
+            END IF;
+            _count := _count + 1;
+        END IF;
+        DELETE FROM mergestat.synced_tables WHERE table_name = _table.table_name;
+    END LOOP;
+    RETURN _count;
+END;
+$$ LANGUAGE PLPGSQL;
+
+COMMENT ON FUNCTION mergestat.restore_table_namespace() IS 'moves the synced tables relocated out of public back into it, returns the number of tables moved';
+
+-- mergestat.table_namespace_pending reports whether apply_table_namespace has tables to move (or views to create)
+-- for the given schema and prefix, so that workers only pause for it when it does
+CREATE OR REPLACE FUNCTION mergestat.table_namespace_pending(_schema TEXT, _prefix TEXT)
+RETURNS BOOLEAN
+AS $$
+    SELECT EXISTS (SELECT 1 FROM mergestat.synced_tables s
+            WHERE s.schema_name <> coalesce(nullif(_schema, ''), 'public') OR s.physical_name <> coalesce(_prefix, '') || s.table_name
+                OR to_regclass(format('mergestat_tables.%I', s.table_name)) IS NULL)
+        OR ((coalesce(nullif(_schema, ''), 'public') <> 'public' OR coalesce(_prefix, '') <> '')
+            AND EXISTS (SELECT 1 FROM mergestat.owned_tables o
+                WHERE to_regclass(format('public.%I', o.table_name)) IS NOT NULL
+                    AND NOT EXISTS (SELECT 1 FROM mergestat.synced_tables s WHERE s.table_name = o.table_name)))
+$$ LANGUAGE SQL STABLE;
+
+COMMENT ON FUNCTION mergestat.table_namespace_pending(TEXT, TEXT) IS 'whether apply_table_namespace has tables to move for the given schema and prefix';
+
+-- mergestat.apply_table_namespace moves the owned tables (see mergestat.owned_tables) into the given schema, with the
+-- given prefix, and (re)creates the views resolving their names. Tables already relocated elsewhere are restored first.
+-- With public and no prefix, it only restores the tables. Workers apply it on every start, paused (see
+-- table_namespace_pending) when there's anything to move, it's a no-op when the tables already live where they should.
+CREATE OR REPLACE FUNCTION mergestat.apply_table_namespace(_schema TEXT, _prefix TEXT)
+RETURNS INTEGER
+AS $$
+DECLARE
+    _table TEXT;
+    _count INTEGER := 0;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('mergestat.synced_tables'));
+
+    _schema := coalesce(nullif(_schema, ''), 'public');
+    _prefix := coalesce(_prefix, '');
+
+    IF EXISTS (SELECT 1 FROM mergestat.synced_tables WHERE schema_name <> _schema OR physical_name <> _prefix || table_name) THEN
+        PERFORM mergestat.restore_table_namespace();
+    END IF;
+
+    IF _schema = 'public' AND _prefix = '' THEN
+        RETURN 0;
+    END IF;
+
+    EXECUTE format('CREATE SCHEMA IF NOT EXISTS %I', _schema);
+
+    FOR _table IN
+        SELECT o.table_name FROM mergestat.owned_tables o
+        WHERE to_regclass(format('public.%I', o.table_name)) IS NOT NULL
+            AND NOT EXISTS (SELECT 1 FROM mergestat.synced_tables s WHERE s.table_name = o.table_name)
+        ORDER BY o.table_name
+    LOOP
+        IF _prefix <> '' THEN
+            EXECUTE format('ALTER TABLE public.%I RENAME TO %I', _table, _prefix || _table);
+        END IF;
+        IF _schema <> 'public' THEN
+            EXECUTE format('ALTER TABLE public.%I SET SCHEMA %I', _prefix || _table, _schema);
+        END IF;
+        INSERT INTO mergestat.synced_tables (table_name, schema_name, physical_name) VALUES (_table, _schema, _prefix || _table);
+        _count := _count + 1;
+    END LOOP;
+
+    -- views are dropped with restore_table_namespace (as migrations may change the columns of their tables)
+    FOR _table IN SELECT s.table_name FROM mergestat.synced_tables s
+        WHERE to_regclass(format('mergestat_tables.%I', s.table_name)) IS NULL
+    LOOP
+        EXECUTE format('CREATE VIEW mergestat_tables.%I AS SELECT * FROM %I.%I', _table, _schema, _prefix || _table);
+    END LOOP;
+
+    RETURN _count;
+END;
+$$ LANGUAGE PLPGSQL;
+
+COMMENT ON FUNCTION mergestat.apply_table_namespace(TEXT, TEXT) IS 'moves the owned synced tables into the given schema, with the given prefix, returns the number of tables moved';
+
+-- repo_data_tables (see 114) is redefined to also list the tables relocated out of public, which don't all declare
+-- their reference to public.repos
+CREATE OR REPLACE VIEW mergestat.repo_data_tables AS
+SELECT DISTINCT ON (cols.table_schema, cols.table_name) cols.table_schema, cols.table_name, cols.column_name, cols.is_foreign_key
+FROM (
+    SELECT ns.nspname::TEXT AS table_schema, cl.relname::TEXT AS table_name, att.attname::TEXT AS column_name, TRUE AS is_foreign_key
+    FROM pg_catalog.pg_constraint con
+    INNER JOIN pg_catalog.pg_class cl ON cl.oid = con.conrelid
+    INNER JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+    INNER JOIN pg_catalog.pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = con.conkey[1]
+    WHERE con.contype = 'f' AND con.confrelid = 'public.repos'::regclass AND array_length(con.conkey, 1) = 1
+    UNION ALL
+    SELECT c.table_schema::TEXT, c.table_name::TEXT, c.column_name::TEXT, FALSE
+    FROM information_schema.columns c
+    INNER JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+    WHERE (c.table_schema IN ('public', 'mergestat', 'mergestat_staging')
+            OR (c.table_schema::TEXT, c.table_name::TEXT) IN (SELECT s.schema_name, s.physical_name FROM mergestat.synced_tables s))
+        AND c.column_name = 'repo_id' AND c.data_type = 'uuid' AND t.table_type = 'BASE TABLE'
+) AS cols
+WHERE NOT (cols.table_schema = 'public' AND cols.table_name = 'repos') AND NOT (cols.table_schema = 'mergestat' AND cols.table_name = 'repo_purges')
+ORDER BY cols.table_schema, cols.table_name, cols.is_foreign_key DESC;
+
+-- erase_author (see 115) is redefined to only erase the rows of the owned tables, where they live
+CREATE OR REPLACE FUNCTION mergestat.erase_author(_email TEXT, _login TEXT, _mode TEXT)
+RETURNS BIGINT
+AS
+$$
+DECLARE
+    _salt TEXT;
+    _email_hash TEXT;
+    _login_hash TEXT;
+    _value TEXT;
+    _hash TEXT;
+    _replacement TEXT;
+    _commits TEXT;
+    _column RECORD;
+    _count BIGINT;
+    _total BIGINT := 0;
+BEGIN
+    IF _mode NOT IN ('PSEUDONYMIZE', 'DELETE') THEN
+        RAISE EXCEPTION 'unknown erasure mode %, expected PSEUDONYMIZE or DELETE', _mode;
+    END IF;
+
+    SELECT hash_salt INTO _salt FROM mergestat.privacy_settings WHERE id;
+    IF coalesce(_email, '') <> '' THEN
+        _email_hash := mergestat.privacy_hash(_email, coalesce(_salt, ''));
+    END IF;
+    IF coalesce(_login, '') <> '' THEN
+        _login_hash := mergestat.privacy_hash(_login, coalesce(_salt, ''));
+    END IF;
+    IF _email_hash IS NULL AND _login_hash IS NULL THEN
+        RAISE EXCEPTION 'an email or a login is required';
+    END IF;
+
+    _commits := mergestat.synced_table('git_commits');
+
+    IF _mode = 'DELETE' AND _email_hash IS NOT NULL THEN
+        FOR _column IN
+            SELECT c.table_schema, c.physical_name FROM mergestat.owned_table_columns c
+            WHERE c.column_name = 'commit_hash'
+                AND EXISTS (SELECT 1 FROM mergestat.owned_table_columns r WHERE r.table_name = c.table_name AND r.column_name = 'repo_id')
+        LOOP
+            EXECUTE format('DELETE FROM %I.%I t USING %s c WHERE c.repo_id = t.repo_id AND c.hash = t.commit_hash AND (lower(c.author_email) = lower($1) OR c.author_email = $2)',
+                _column.table_schema, _column.physical_name, _commits) USING _email, _email_hash;
+
+            GET DIAGNOSTICS _count = ROW_COUNT;
+            _total := _total + _count;
+        END LOOP;
+    END IF;
+
+    FOR _column IN
+        SELECT c.table_schema, c.physical_name, c.column_name, (c.column_name = 'login' OR c.column_name LIKE '%\_login') AS is_login, n.column_name AS name_column
+            FROM mergestat.owned_table_columns c
+            LEFT JOIN mergestat.owned_table_columns n ON n.table_name = c.table_name
+                AND n.column_name = regexp_replace(c.column_name, '(email|login)$', 'name') AND n.data_type = 'text'
+        WHERE c.data_type = 'text'
+            AND (c.column_name IN ('email', 'login') OR c.column_name LIKE '%\_email' OR c.column_name LIKE '%\_login')
+    LOOP
+        IF _column.is_login THEN
+            _value := _login; _hash := _login_hash; _replacement := mergestat.erasure_pseudonym(_login_hash);
+        ELSE
+            _value := _email; _hash := _email_hash; _replacement := mergestat.erasure_pseudonym(_email_hash) || '@erased.invalid';
+        END IF;
+
+        IF _hash IS NULL THEN
+            CONTINUE;
+        END IF;
+
+        IF _mode = 'DELETE' THEN
+            EXECUTE format('DELETE FROM %I.%I WHERE lower(%3$I) = lower($1) OR %3$I = $2', _column.table_schema, _column.physical_name, _column.column_name)
+                USING _value, _hash;
+        ELSIF _column.name_column IS NOT NULL THEN
+            EXECUTE format('UPDATE %I.%I SET %3$I = $3, %4$I = $4 WHERE lower(%3$I) = lower($1) OR %3$I = $2', _column.table_schema, _column.physical_name, _column.column_name, _column.name_column)
+                USING _value, _hash, _replacement, mergestat.erasure_pseudonym(_hash);
+        ELSE
+            EXECUTE format('UPDATE %I.%I SET %3$I = $3 WHERE lower(%3$I) = lower($1) OR %3$I = $2', _column.table_schema, _column.physical_name, _column.column_name)
+                USING _value, _hash, _replacement;
+        END IF;
+
+        GET DIAGNOSTICS _count = ROW_COUNT;
+        _total := _total + _count;
+    END LOOP;
+
+    FOR _column IN
+        SELECT c.table_schema, c.physical_name, c.column_name, c.data_type FROM mergestat.identity_columns i
+            INNER JOIN mergestat.owned_table_columns c ON c.table_name = i.table_name AND c.column_name = i.column_name
+    LOOP
+        IF _email_hash IS NOT NULL THEN
+            _total := _total + mergestat.erase_identity(format('%I.%I', _column.table_schema, _column.physical_name), _column.column_name, _column.data_type,
+                _email, _email_hash, mergestat.erasure_pseudonym(_email_hash) || '@erased.invalid', _mode);
+        END IF;
+        IF _login_hash IS NOT NULL THEN
+            _total := _total + mergestat.erase_identity(format('%I.%I', _column.table_schema, _column.physical_name), _column.column_name, _column.data_type,
+                _login, _login_hash, mergestat.erasure_pseudonym(_login_hash), _mode);
+        END IF;
+    END LOOP;
+
+    INSERT INTO mergestat.author_erasures (email_hash, login_hash, mode, rows_affected) VALUES (_email_hash, _login_hash, _mode, _total);
+
+    RETURN _total;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.erase_author(TEXT, TEXT, TEXT) IS 'pseudonymizes (or deletes) the rows of the owned synced tables (in public, or relocated) identifying an author by email or login, returns the number of rows affected';
+
+-- apply_privacy_settings (see 095) is redefined to only redact the owned tables, where they live
+CREATE OR REPLACE FUNCTION mergestat.apply_privacy_settings()
+RETURNS BIGINT
+AS
+$$
+DECLARE
+    _settings mergestat.privacy_settings;
+    _column RECORD;
+    _count BIGINT;
+    _total BIGINT := 0;
+BEGIN
+    SELECT * INTO _settings FROM mergestat.privacy_settings WHERE id;
+
+    FOR _column IN
+        SELECT c.table_schema, c.physical_name, c.column_name FROM mergestat.owned_table_columns c
+        WHERE c.data_type = 'text' AND (c.column_name = 'email' OR c.column_name LIKE '%\_email' OR c.column_name IN ('message', 'contents', 'patch'))
+    LOOP
+        IF _column.column_name = 'message' AND _settings.commit_message_bodies = 'DROP' THEN
+            EXECUTE format('UPDATE %I.%I SET %3$I = split_part(%3$I, E''\n'', 1) WHERE strpos(%3$I, E''\n'') > 0',
+                _column.table_schema, _column.physical_name, _column.column_name);
+        ELSIF _column.column_name = 'contents' AND _settings.file_contents = 'DROP' THEN
+            EXECUTE format('UPDATE %I.%I SET %3$I = NULL WHERE %3$I IS NOT NULL', _column.table_schema, _column.physical_name, _column.column_name);
+        ELSIF _column.column_name = 'patch' AND _settings.file_contents = 'DROP' THEN
+            -- patches (see git_commit_patches) aren't nullable, they're emptied
+            EXECUTE format('UPDATE %I.%I SET %3$I = '''' WHERE %3$I <> ''''', _column.table_schema, _column.physical_name, _column.column_name);
+        ELSIF _column.column_name NOT IN ('message', 'contents', 'patch') AND (_settings.author_emails = 'HASH' OR _settings.author_emails = 'DROP'
+            AND mergestat.is_key_column(format('%I.%I', _column.table_schema, _column.physical_name)::REGCLASS, _column.column_name)) THEN
+            -- dropped emails of keys are hashed, as emptying them would make rows collide
+            EXECUTE format('UPDATE %I.%I SET %3$I = mergestat.privacy_hash(%3$I, $1) WHERE %3$I <> '''' AND %3$I NOT LIKE ''sha256:%%''',
+                _column.table_schema, _column.physical_name, _column.column_name) USING _settings.hash_salt;
+        ELSIF _column.column_name NOT IN ('message', 'contents', 'patch') AND _settings.author_emails = 'DROP' THEN
+            EXECUTE format('UPDATE %I.%I SET %3$I = '''' WHERE %3$I <> ''''', _column.table_schema, _column.physical_name, _column.column_name);
+        ELSE
+            CONTINUE;
+        END IF;
+
+        GET DIAGNOSTICS _count = ROW_COUNT;
+        _total := _total + _count;
+    END LOOP;
+
+    RETURN _total;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION mergestat.apply_privacy_settings() IS 'redacts the rows already synced into the owned tables (in public, or relocated) following mergestat.privacy_settings, returns the number of updated rows';
+
+-- enforce_data_retention (see 096) is redefined to expire the rows of the synced tables where they live
+CREATE OR REPLACE FUNCTION mergestat.enforce_data_retention()
+RETURNS BIGINT
+AS
+$$
+DECLARE _rows_deleted BIGINT := 0;
+DECLARE _count BIGINT;
+DECLARE _policy RECORD;
+DECLARE _table TEXT;
+BEGIN
+    IF NOT pg_try_advisory_xact_lock(hashtext('mergestat.enforce_data_retention')) THEN
+        RETURN NULL;
+    END IF;
+
+    FOR _policy IN SELECT * FROM mergestat.data_retention_policies
+        WHERE enabled AND max_age IS NOT NULL ORDER BY table_name, repo_id NULLS FIRST
+    LOOP
+        _table := mergestat.synced_table(_policy.table_name);
+
+        -- skip policies for tables that don't exist (anymore)
+        IF to_regclass(_table) IS NULL THEN
+            RAISE WARNING 'skipping retention policy for unknown table %', _table;
+            CONTINUE;
+        END IF;
+
+        IF _policy.repo_id IS NULL THEN
+            EXECUTE format(
+                'DELETE FROM %s t WHERE t.%I < now() - $1 AND NOT EXISTS ' ||
+                '(SELECT 1 FROM mergestat.data_retention_policies o WHERE o.table_name = $2 AND o.repo_id = t.repo_id)',
+                _table, _policy.timestamp_column) USING _policy.max_age, _policy.table_name;
+        ELSE
+            EXECUTE format('DELETE FROM %s WHERE repo_id = $1 AND %I < now() - $2', _table, _policy.timestamp_column)
+                USING _policy.repo_id, _policy.max_age;
+        END IF;
+
+        GET DIAGNOSTICS _count = ROW_COUNT;
+        _rows_deleted := _rows_deleted + _count;
+    END LOOP;
+
+    RETURN _rows_deleted;
+END;
+$$ LANGUAGE plpgsql;
+
+-- cut_over_table_version (see 099) is redefined to swap the version in place of the active table where it lives.
+-- Versions are created in public by migrations: a relocated table is renamed to <physical name>_v<active version>
+-- in its schema, and the version moved next to it, under the physical name. The view resolving the name of the
+-- table is recreated over the version.
+CREATE OR REPLACE FUNCTION mergestat.cut_over_table_version(_table TEXT, _version INTEGER)
+RETURNS VOID
+AS
+$$
+DECLARE _active INTEGER;
+DECLARE _views TEXT;
+DECLARE _schema TEXT;
+DECLARE _name TEXT;
+BEGIN
+    PERFORM 1 FROM mergestat.table_versions WHERE table_name = _table AND version = _version AND status = 'POPULATING' FOR UPDATE;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'version % of % is not being populated', _version, _table;
+    END IF;
+
+    IF to_regclass(format('public.%I', _table || '_v' || _version)) IS NULL THEN
+        RAISE EXCEPTION 'table public.% does not exist', _table || '_v' || _version;
+    END IF;
+
+    _schema := coalesce((SELECT schema_name FROM mergestat.synced_tables WHERE table_name = _table), 'public');
+    _name := coalesce((SELECT physical_name FROM mergestat.synced_tables WHERE table_name = _table), _table);
+
+    SELECT string_agg(DISTINCT v.oid::REGCLASS::TEXT, ', ') INTO _views
+    FROM pg_depend d
+        INNER JOIN pg_rewrite r ON r.oid = d.objid
+        INNER JOIN pg_class v ON v.oid = r.ev_class
+    WHERE d.classid = 'pg_rewrite'::REGCLASS AND d.refobjid = format('%I.%I', _schema, _name)::REGCLASS AND v.oid <> d.refobjid
+        AND v.relnamespace <> 'mergestat_tables'::REGNAMESPACE;
+
+    IF _views IS NOT NULL THEN
+        RAISE EXCEPTION 'views % depend on %, drop them before (and recreate them after) the cut over', _views, mergestat.synced_table(_table);
+    END IF;
+
+    _active := COALESCE((SELECT version FROM mergestat.table_versions WHERE table_name = _table AND status = 'ACTIVE'), 1);
+
+    EXECUTE format('LOCK TABLE %I.%I, public.%I IN ACCESS EXCLUSIVE MODE', _schema, _name, _table || '_v' || _version);
+    EXECUTE format('DROP VIEW IF EXISTS mergestat_tables.%I', _table);
+    EXECUTE format('ALTER TABLE %I.%I RENAME TO %I', _schema, _name, _name || '_v' || _active);
+    IF _schema <> 'public' THEN
+        EXECUTE format('ALTER TABLE public.%I SET SCHEMA %I', _table || '_v' || _version, _schema);
+    END IF;
+    EXECUTE format('ALTER TABLE %I.%I RENAME TO %I', _schema, _table || '_v' || _version, _name);
+    IF EXISTS (SELECT 1 FROM mergestat.synced_tables WHERE table_name = _table) THEN
+        EXECUTE format('CREATE VIEW mergestat_tables.%I AS SELECT * FROM %I.%I', _table, _schema, _name);
+    END IF;
+
+    -- copies of the table used by dry-run syncs are recreated (with the new columns) on first use
+    EXECUTE format('DROP TABLE IF EXISTS mergestat_staging.%I', _table);
+
+    UPDATE mergestat.table_versions SET status = 'RETIRED' WHERE table_name = _table AND status = 'ACTIVE';
+    UPDATE mergestat.table_versions SET status = 'ACTIVE', cut_over_at = now() WHERE table_name = _table AND version = _version;
+END;
+$$ LANGUAGE plpgsql;
+
+-- check_encrypted_column (see 116) is redefined to check the keys of the table where it lives
+CREATE OR REPLACE FUNCTION mergestat.check_encrypted_column() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM pg_index i JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+        WHERE i.indrelid = to_regclass(mergestat.synced_table(NEW.table_name)) AND (i.indisprimary OR i.indisunique)
+            AND a.attname = NEW.column_name
+    ) THEN
+        RAISE EXCEPTION 'column % of % is part of a key of the table, and can''t be encrypted', NEW.column_name, NEW.table_name;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- apply_repo_lifecycle_policy (see 110) is redefined to read the commits and repo metadata where they live
+CREATE OR REPLACE FUNCTION mergestat.apply_repo_lifecycle_policy()
+RETURNS INTEGER
+AS
+$$
+DECLARE _policy mergestat.repo_lifecycle_policy;
+DECLARE _flagged INTEGER;
+DECLARE _unflagged INTEGER;
+BEGIN
+    SELECT * INTO _policy FROM mergestat.repo_lifecycle_policy WHERE enabled FOR UPDATE SKIP LOCKED;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    EXECUTE format($sql$
+        CREATE TEMPORARY TABLE _dormant ON COMMIT DROP AS
+        WITH last_commits AS (
+            SELECT repo_id, MAX(committer_when) AS last_commit_at FROM %1$s GROUP BY repo_id
+        )
+        SELECT r.id AS repo_id,
+            CASE WHEN %3$L::BOOLEAN AND COALESCE(i.is_archived, FALSE) THEN 'ARCHIVED' ELSE 'INACTIVE' END AS reason,
+            c.last_commit_at
+        FROM public.repos r
+        LEFT JOIN %2$s i ON i.repo_id = r.id
+        LEFT JOIN last_commits c ON c.repo_id = r.id
+        -- repos without synced commits can't be told apart from repos that were never synced, so they're not dormant
+        WHERE (%3$L::BOOLEAN AND COALESCE(i.is_archived, FALSE))
+            OR c.last_commit_at < now() - %4$L::INTERVAL
+    $sql$, mergestat.synced_table('git_commits'), mergestat.synced_table('github_repo_info'), _policy.include_archived, _policy.inactive_after);
+
+    -- repos that are active again
+    WITH active AS (
+        DELETE FROM mergestat.dormant_repos d WHERE NOT EXISTS (SELECT 1 FROM _dormant WHERE _dormant.repo_id = d.repo_id)
+        RETURNING d.repo_id, d.downgraded_at
+    ), restored AS (
+        UPDATE mergestat.repo_syncs rs SET sync_interval = NULL
+        FROM active WHERE active.downgraded_at IS NOT NULL AND rs.repo_id = active.repo_id
+            AND rs.sync_interval = _policy.downgraded_sync_interval
+        RETURNING 1
+    )
+    SELECT COUNT(*) INTO _unflagged FROM active;
+
+    UPDATE mergestat.dormant_repos d SET reason = _dormant.reason, last_commit_at = _dormant.last_commit_at
+    FROM _dormant WHERE _dormant.repo_id = d.repo_id;
+
+    INSERT INTO mergestat.dormant_repos (repo_id, reason, last_commit_at)
+    SELECT repo_id, reason, last_commit_at FROM _dormant
+    ON CONFLICT (repo_id) DO NOTHING;
+
+    GET DIAGNOSTICS _flagged = ROW_COUNT;
+
+    IF _policy.auto_downgrade THEN
+        UPDATE mergestat.repo_syncs rs SET sync_interval = _policy.downgraded_sync_interval
+        FROM mergestat.dormant_repos d WHERE d.repo_id = rs.repo_id AND rs.sync_interval IS NULL;
+
+        UPDATE mergestat.dormant_repos SET downgraded_at = now() WHERE downgraded_at IS NULL;
+    END IF;
+
+    UPDATE mergestat.repo_lifecycle_policy SET last_applied_at = now();
+    DROP TABLE _dormant;
+
+    RETURN _flagged + _unflagged;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,66 @@
+BEGIN;
+
+-- generated (ie. computed) columns and indexes declared here are added to (and dropped from) the synced tables by the workers, after
+-- migrations, so that the common query patterns of an install are fast without a DBA tuning each of them by hand.
+-- Expressions are SQL, as-is: only administrators should be allowed to write into these tables.
+CREATE TABLE IF NOT EXISTS mergestat.generated_columns (
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    expression TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    applied_definition TEXT,
+    applied_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (table_name, column_name)
+);
+
+COMMENT ON TABLE mergestat.generated_columns IS 'computed columns of the synced tables, added (or dropped) by the workers after migrations: plain columns kept up to date by a trigger, and backfilled in batches';
+COMMENT ON COLUMN mergestat.generated_columns.table_name IS 'name of the synced table the column is added to';
+COMMENT ON COLUMN mergestat.generated_columns.column_name IS 'name of the generated column';
+COMMENT ON COLUMN mergestat.generated_columns.type IS 'type of the generated column, eg. TIMESTAMP';
+COMMENT ON COLUMN mergestat.generated_columns.expression IS 'expression the column is generated with, of the other columns of the row, it must be immutable (eg. date_trunc(''month'', author_when AT TIME ZONE ''UTC''))';
+COMMENT ON COLUMN mergestat.generated_columns.enabled IS 'if true the column is added, if false it is dropped (the table is only locked briefly, the rows are then backfilled in batches of pages)';
+COMMENT ON COLUMN mergestat.generated_columns.applied_definition IS 'definition (type and expression) of the column as added and backfilled by a worker, null if it is not added';
+COMMENT ON COLUMN mergestat.generated_columns.applied_at IS 'timestamp when the column was last added (or dropped)';
+COMMENT ON COLUMN mergestat.generated_columns.last_error IS 'error of the last attempt to add (or drop) the column, if it failed';
+COMMENT ON COLUMN mergestat.generated_columns.created_at IS 'timestamp when the generated column was configured';
+
+CREATE TABLE IF NOT EXISTS mergestat.table_indexes (
+    table_name TEXT NOT NULL,
+    name TEXT NOT NULL,
+    method TEXT NOT NULL DEFAULT 'btree' CHECK (method IN ('btree', 'hash', 'gin', 'gist', 'brin')),
+    columns TEXT NOT NULL,
+    predicate TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    index_name TEXT,
+    indexed_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (table_name, name)
+);
+
+COMMENT ON TABLE mergestat.table_indexes IS 'extra indexes of the synced tables, created (or dropped) concurrently by the workers after migrations';
+COMMENT ON COLUMN mergestat.table_indexes.table_name IS 'name of the synced table the index is created on';
+COMMENT ON COLUMN mergestat.table_indexes.name IS 'name of the index, unique by table (the name of the index itself is derived from it and its definition)';
+COMMENT ON COLUMN mergestat.table_indexes.method IS 'index method: btree, hash, gin, gist or brin';
+COMMENT ON COLUMN mergestat.table_indexes.columns IS 'columns (or expressions) indexed, as in CREATE INDEX, eg. repo_id, author_when DESC';
+COMMENT ON COLUMN mergestat.table_indexes.predicate IS 'predicate of a partial index, eg. merged_at IS NOT NULL';
+COMMENT ON COLUMN mergestat.table_indexes.enabled IS 'if true the index is created, if false it is dropped';
+COMMENT ON COLUMN mergestat.table_indexes.index_name IS 'name of the index, once created';
+COMMENT ON COLUMN mergestat.table_indexes.indexed_at IS 'timestamp when the index was last created (or dropped)';
+COMMENT ON COLUMN mergestat.table_indexes.last_error IS 'error of the last attempt to create (or drop) the index, if it failed';
+COMMENT ON COLUMN mergestat.table_indexes.created_at IS 'timestamp when the index was configured';
+
+-- common patterns, disabled until an install needs them: commits by month (eg. for activity charts), merged PRs by date
+INSERT INTO mergestat.generated_columns (table_name, column_name, type, expression) VALUES
+    ('git_commits', 'author_month', 'TIMESTAMP', 'date_trunc(''month'', author_when AT TIME ZONE ''UTC'')')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO mergestat.table_indexes (table_name, name, method, columns, predicate) VALUES
+    ('git_commits', 'author_month', 'btree', 'repo_id, author_month', NULL),
+    ('github_pull_requests', 'merged_at', 'btree', 'repo_id, merged_at', 'merged_at IS NOT NULL')
+ON CONFLICT DO NOTHING;
+
+COMMIT;
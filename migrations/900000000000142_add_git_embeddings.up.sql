@@ -0,0 +1,53 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('GIT_EMBEDDINGS', 'Computes embeddings of commit messages and README/doc files, with the model the worker is configured with (see the EMBEDDINGS_* env vars), for semantic search and clustering, requires GIT_COMMITS and GIT_FILES', 'Git Embeddings', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.git_embeddings (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('commit_message', 'file')),
+    key TEXT NOT NULL,
+    model TEXT NOT NULL,
+    content_hash TEXT NOT NULL,
+    dimensions INTEGER NOT NULL,
+    embedding REAL[] NOT NULL,
+    embedded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, kind, key, model)
+);
+
+-- pgvector is optional: the extension is created if it's available (and the role is allowed to), and embeddings are
+-- then stored as vectors, otherwise they're stored as arrays, and the column can be converted once the extension is
+-- installed, with:
+--   ALTER TABLE public.git_embeddings ALTER COLUMN embedding TYPE vector USING embedding::vector
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+        BEGIN
+            CREATE EXTENSION IF NOT EXISTS vector;
+        EXCEPTION WHEN insufficient_privilege THEN
+            RAISE NOTICE 'pgvector is available but could not be created, embeddings are stored as REAL[]';
+        END;
+    END IF;
+
+    IF to_regtype('vector') IS NOT NULL THEN
+        EXECUTE 'ALTER TABLE public.git_embeddings ALTER COLUMN embedding TYPE vector USING embedding::vector';
+    END IF;
+END;
+$$;
+
+COMMENT ON TABLE public.git_embeddings IS 'embeddings of the commit messages and README/doc files of a repo, for semantic search (eg. ORDER BY embedding <=> $1::vector with pgvector) and clustering';
+COMMENT ON COLUMN public.git_embeddings.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.git_embeddings.kind IS 'what is embedded: commit_message or file';
+COMMENT ON COLUMN public.git_embeddings.key IS 'hash of the commit (commit_message), or path of the file (file)';
+COMMENT ON COLUMN public.git_embeddings.model IS 'model the embedding was computed with, embeddings of different models must not be compared';
+COMMENT ON COLUMN public.git_embeddings.content_hash IS 'SHA-256 of the text embedded, so that only texts that changed are embedded again';
+COMMENT ON COLUMN public.git_embeddings.dimensions IS 'number of dimensions of the embedding';
+COMMENT ON COLUMN public.git_embeddings.embedding IS 'embedding of the text (a pgvector vector, or REAL[] without pgvector)';
+COMMENT ON COLUMN public.git_embeddings.embedded_at IS 'timestamp when the embedding was computed';
+COMMENT ON COLUMN public.git_embeddings._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+INSERT INTO mergestat.owned_tables (table_name) VALUES ('git_embeddings') ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,64 @@
+BEGIN;
+
+INSERT INTO mergestat.repo_sync_types (type, description, short_name, priority)
+VALUES ('LLM_SUMMARIES', 'Summarizes the repo (from its README and recent commits) and its large pull requests, with the LLM the worker is configured with (see the LLM_* env vars), requires GIT_FILES, GIT_COMMITS and GITHUB_REPO_PRS', 'LLM Summaries', 3)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.repo_summaries (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    model TEXT NOT NULL,
+    model_version TEXT,
+    prompt_version INTEGER NOT NULL,
+    summary TEXT NOT NULL,
+    input_hash TEXT NOT NULL,
+    generated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, model)
+);
+
+COMMENT ON TABLE public.repo_summaries IS 'summary of a repo generated by an LLM, from its README and recent commits, for portfolio overviews';
+COMMENT ON COLUMN public.repo_summaries.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.repo_summaries.model IS 'model the summary was requested from, as configured';
+COMMENT ON COLUMN public.repo_summaries.model_version IS 'model (version) that generated the summary, as reported by the endpoint, eg. gpt-4o-mini-2024-07-18';
+COMMENT ON COLUMN public.repo_summaries.prompt_version IS 'version of the prompt the summary was generated with, summaries are generated again when it changes';
+COMMENT ON COLUMN public.repo_summaries.summary IS 'summary of the repo, generated (and not reviewed), it may be inaccurate';
+COMMENT ON COLUMN public.repo_summaries.input_hash IS 'SHA-256 of the prompt, so that the summary is only generated again when its inputs change';
+COMMENT ON COLUMN public.repo_summaries.generated_at IS 'timestamp when the summary was generated';
+COMMENT ON COLUMN public.repo_summaries._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+CREATE TABLE IF NOT EXISTS public.github_pull_request_summaries (
+    repo_id UUID NOT NULL REFERENCES public.repos(id) ON DELETE CASCADE,
+    pr_number INTEGER NOT NULL,
+    model TEXT NOT NULL,
+    model_version TEXT,
+    prompt_version INTEGER NOT NULL,
+    summary TEXT NOT NULL,
+    input_hash TEXT NOT NULL,
+    generated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    _mergestat_synced_at TIMESTAMP(6) WITH TIME ZONE DEFAULT now() NOT NULL,
+    PRIMARY KEY (repo_id, pr_number, model)
+);
+
+COMMENT ON TABLE public.github_pull_request_summaries IS 'summary of a large pull request generated by an LLM, from its description, size and commits';
+COMMENT ON COLUMN public.github_pull_request_summaries.repo_id IS 'foreign key for public.repos.id';
+COMMENT ON COLUMN public.github_pull_request_summaries.pr_number IS 'number of the pull request';
+COMMENT ON COLUMN public.github_pull_request_summaries.model IS 'model the summary was requested from, as configured';
+COMMENT ON COLUMN public.github_pull_request_summaries.model_version IS 'model (version) that generated the summary, as reported by the endpoint';
+COMMENT ON COLUMN public.github_pull_request_summaries.prompt_version IS 'version of the prompt the summary was generated with, summaries are generated again when it changes';
+COMMENT ON COLUMN public.github_pull_request_summaries.summary IS 'summary of the pull request, generated (and not reviewed), it may be inaccurate';
+COMMENT ON COLUMN public.github_pull_request_summaries.input_hash IS 'SHA-256 of the prompt, so that the summary is only generated again when its inputs change';
+COMMENT ON COLUMN public.github_pull_request_summaries.generated_at IS 'timestamp when the summary was generated';
+COMMENT ON COLUMN public.github_pull_request_summaries._mergestat_synced_at IS 'timestamp when record was synced into the MergeStat database';
+
+INSERT INTO mergestat.owned_tables (table_name) VALUES ('repo_summaries'), ('github_pull_request_summaries') ON CONFLICT DO NOTHING;
+
+-- the latest summary of each repo, whatever the model, next to the repo (for portfolio-overview dashboards)
+CREATE OR REPLACE VIEW public.repo_summaries_latest AS
+    SELECT DISTINCT ON (r.id) r.id AS repo_id, r.repo, s.summary, s.model, s.model_version, s.generated_at
+        FROM public.repos r
+        INNER JOIN public.repo_summaries s ON s.repo_id = r.id
+    ORDER BY r.id, s.generated_at DESC;
+
+COMMENT ON VIEW public.repo_summaries_latest IS 'latest generated summary of each repo';
+
+COMMIT;